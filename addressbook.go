@@ -0,0 +1,280 @@
+package bitswap
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+var (
+	// addressBookPeers and addressBookHints are the datastore namespaces of
+	// peer records, by peer ID, and of the hints of their databases, by
+	// digest.
+	addressBookPeers = datastore.NewKey("/bitswap-peers")
+	addressBookHints = datastore.NewKey("/bitswap-hints")
+)
+
+// AddressBook remembers the peers which answered private retrievals in a
+// datastore, so that clients restarted with it skip rediscovering them and
+// renegotiating with them: their addresses, the PIR parameters and hints
+// last negotiated with them, and their scores. A datastore on disk, such as
+// a leveldb or flatfs one, keeps them across restarts. It may be shared
+// between clients, and is safe for concurrent use.
+//
+// Parameters restored are used as if just negotiated: a peer whose
+// databases changed since fails the first round with ErrStaleParams, and is
+// negotiated with afresh, and presence filters older than FilterMaxAge are
+// not trusted.
+type AddressBook struct {
+	ds datastore.Datastore
+	// mtx serialises the updates of records.
+	mtx sync.Mutex
+}
+
+// NewAddressBook creates an address book keeping its records in ds.
+func NewAddressBook(ds datastore.Datastore) *AddressBook {
+	return &AddressBook{ds: ds}
+}
+
+// PeerRecord is what an AddressBook remembers of a peer.
+type PeerRecord struct {
+	Addrs []multiaddr.Multiaddr
+	// Scheme and Epoch are those of the parameters last negotiated with the
+	// peer, at Negotiated. Scheme is empty if none were.
+	Scheme     string
+	Epoch      uint64
+	Negotiated time.Time
+	// Score is the peer's score when last saved, at Scored.
+	Score  float64
+	Scored time.Time
+}
+
+// bookRecord is a peer record as kept in the datastore.
+type bookRecord struct {
+	Addrs []string `json:",omitempty"`
+	// Handshake is the peer's reply to the handshake last run with it,
+	// offering Group, at Received.
+	Handshake []byte    `json:",omitempty"`
+	Group     []byte    `json:",omitempty"`
+	Received  time.Time `json:",omitempty"`
+	Score     float64   `json:",omitempty"`
+	ScoredAt  time.Time `json:",omitempty"`
+}
+
+func bookKey(p peer.ID) datastore.Key {
+	return addressBookPeers.ChildString(p.String())
+}
+
+func bookHintKey(digest []byte) datastore.Key {
+	return addressBookHints.ChildString(hex.EncodeToString(digest))
+}
+
+// Peers returns the peers the address book has records of.
+func (ab *AddressBook) Peers(ctx context.Context) ([]peer.ID, error) {
+	res, err := ab.ds.Query(ctx, query.Query{Prefix: addressBookPeers.String(), KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return nil, err
+	}
+	var out []peer.ID
+	for _, e := range entries {
+		p, err := peer.Decode(datastore.RawKey(e.Key).BaseNamespace())
+		if err != nil {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Get returns the record of p, or datastore.ErrNotFound if there is none.
+func (ab *AddressBook) Get(ctx context.Context, p peer.ID) (PeerRecord, error) {
+	rec, err := ab.get(ctx, p)
+	if err != nil {
+		return PeerRecord{}, err
+	}
+	out := PeerRecord{Score: rec.Score, Scored: rec.ScoredAt, Negotiated: rec.Received}
+	for _, s := range rec.Addrs {
+		if a, err := multiaddr.NewMultiaddr(s); err == nil {
+			out.Addrs = append(out.Addrs, a)
+		}
+	}
+	if rec.Handshake != nil {
+		hs := bitswap_message_pb.Message_PIRHandshake{}
+		if err := hs.Unmarshal(rec.Handshake); err == nil {
+			out.Scheme, out.Epoch = hs.Index.Scheme, hs.Epoch
+		}
+	}
+	return out, nil
+}
+
+// Forget drops the record of p. The hints of its databases are kept, as
+// other peers may hold the same.
+func (ab *AddressBook) Forget(ctx context.Context, p peer.ID) error {
+	ab.mtx.Lock()
+	defer ab.mtx.Unlock()
+	return ab.ds.Delete(ctx, bookKey(p))
+}
+
+func (ab *AddressBook) get(ctx context.Context, p peer.ID) (bookRecord, error) {
+	var rec bookRecord
+	data, err := ab.ds.Get(ctx, bookKey(p))
+	if err != nil {
+		return rec, err
+	}
+	return rec, json.Unmarshal(data, &rec)
+}
+
+// update applies f to the record of p, or to an empty one if there is none,
+// and saves it.
+func (ab *AddressBook) update(ctx context.Context, p peer.ID, f func(*bookRecord)) error {
+	ab.mtx.Lock()
+	defer ab.mtx.Unlock()
+	rec, err := ab.get(ctx, p)
+	if err != nil && err != datastore.ErrNotFound {
+		return err
+	}
+	f(&rec)
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return ab.ds.Put(ctx, bookKey(p), data)
+}
+
+// restore adds the addresses of the peers recorded to ps, noting those
+// negotiated with as answering private retrievals, and their scores to sc.
+func (ab *AddressBook) restore(ctx context.Context, ps peerstore.Peerstore, sc *Scores) error {
+	peers, err := ab.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range peers {
+		rec, err := ab.get(ctx, p)
+		if err != nil {
+			logger.Debugw("failed to read address book record", "peer", p, "err", err)
+			continue
+		}
+		if !rec.ScoredAt.IsZero() {
+			sc.set(p, rec.Score, rec.ScoredAt)
+		}
+		if ps == nil {
+			continue
+		}
+		var addrs []multiaddr.Multiaddr
+		for _, s := range rec.Addrs {
+			if a, err := multiaddr.NewMultiaddr(s); err == nil {
+				addrs = append(addrs, a)
+			}
+		}
+		ps.AddAddrs(p, addrs, peerstore.AddressTTL)
+		if rec.Handshake != nil {
+			if err := ps.AddProtocols(p, ProtocolPrivate); err != nil {
+				logger.Debugw("failed to restore peer protocols", "peer", p, "err", err)
+			}
+		}
+	}
+	return nil
+}
+
+// saveScore records the current score of p in sc.
+func (ab *AddressBook) saveScore(ctx context.Context, p peer.ID, sc *Scores) error {
+	v := sc.Score(p)
+	return ab.update(ctx, p, func(rec *bookRecord) {
+		rec.Score, rec.ScoredAt = v, time.Now()
+	})
+}
+
+// saveParams records the peer's handshake reply hs, and the addresses and
+// hints it was negotiated with, if the session has an address book.
+// Failures are logged: they only cost a handshake after a restart.
+func (s *Session) saveParams(ctx context.Context, hs []byte, pp PeerParams) {
+	if s.book == nil {
+		return
+	}
+	var addrs []string
+	if s.Host != nil {
+		for _, a := range s.Peerstore().Addrs(s.peer) {
+			addrs = append(addrs, a.String())
+		}
+	}
+	for _, params := range []pir.Params{pp.Index, pp.Blocks} {
+		if params.Hint == nil {
+			continue
+		}
+		if err := s.book.ds.Put(ctx, bookHintKey(params.HintDigest), params.Hint); err != nil {
+			logger.Debugw("failed to save hint", "peer", s.peer, "err", err)
+		}
+	}
+	err := s.book.update(ctx, s.peer, func(rec *bookRecord) {
+		rec.Handshake, rec.Received = hs, pp.received
+		rec.Group = nil
+		if pp.offered.Defined() {
+			rec.Group = pp.offered.Bytes()
+		}
+		if len(addrs) > 0 {
+			rec.Addrs = addrs
+		}
+	})
+	if err != nil {
+		logger.Debugw("failed to save peer parameters", "peer", s.peer, "err", err)
+	}
+}
+
+// restoreParams returns the parameters last negotiated with the peer, if
+// the session has an address book holding them, and they were negotiated
+// for the session's schemes, limits and group. Their hints are read from
+// the address book, or fetched as after a handshake if it lacks them.
+func (s *Session) restoreParams(ctx context.Context) (PeerParams, bool) {
+	if s.book == nil {
+		return PeerParams{}, false
+	}
+	rec, err := s.book.get(ctx, s.peer)
+	if err != nil || rec.Handshake == nil {
+		return PeerParams{}, false
+	}
+	group := cid.Undef
+	if len(rec.Group) > 0 {
+		if group, err = cid.Cast(rec.Group); err != nil {
+			return PeerParams{}, false
+		}
+	}
+	if group != s.group {
+		return PeerParams{}, false
+	}
+	pp, err := s.paramsOf(rec.Handshake)
+	if err != nil {
+		logger.Debugw("not restoring peer parameters", "peer", s.peer, "err", err)
+		return PeerParams{}, false
+	}
+	pp.received = rec.Received
+	for _, params := range []*pir.Params{&pp.Index, &pp.Blocks} {
+		if len(params.HintDigest) == 0 {
+			continue
+		}
+		if hint, err := s.book.ds.Get(ctx, bookHintKey(params.HintDigest)); err == nil && pir.CheckHint(*params, hint) {
+			params.Hint = hint
+		}
+	}
+	if err := s.fetchHints(ctx, &pp); err != nil {
+		logger.Debugw("failed to fetch hints of restored parameters", "peer", s.peer, "err", err)
+		return PeerParams{}, false
+	}
+	s.params.Put(s.peer, pp)
+	s.metrics.Add("params_restored", 1)
+	return pp, true
+}
@@ -0,0 +1,91 @@
+package bitswap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/multiformats/go-multiaddr"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+func TestAddressBook(t *testing.T) {
+	ctx := context.Background()
+	priv, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab := NewAddressBook(dssync.MutexWrap(datastore.NewMapDatastore()))
+	if _, err := ab.Get(ctx, p); err != datastore.ErrNotFound {
+		t.Fatalf("record of unknown peer read with %v", err)
+	}
+
+	hs := bitswap_message_pb.Message_PIRHandshake{Epoch: 3}
+	hs.Index.Scheme = "simplepir"
+	data, err := hs.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	negotiated := time.Now().Add(-time.Minute).Round(0)
+	err = ab.update(ctx, p, func(rec *bookRecord) {
+		rec.Handshake, rec.Received = data, negotiated
+		rec.Addrs = []string{"/ip4/127.0.0.1/tcp/4001"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewScores(ScoreParams{Success: 1})
+	sc.add(p, 2)
+	if err := ab.saveScore(ctx, p, sc); err != nil {
+		t.Fatal(err)
+	}
+
+	// a record keeps the parameters negotiated alongside the score.
+	rec, err := ab.Get(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Scheme != "simplepir" || rec.Epoch != 3 || !rec.Negotiated.Equal(negotiated) || rec.Score != 2 || len(rec.Addrs) != 1 {
+		t.Fatalf("record read as %+v", rec)
+	}
+	if peers, err := ab.Peers(ctx); err != nil || len(peers) != 1 || peers[0] != p {
+		t.Fatalf("peers listed as %v, %v", peers, err)
+	}
+
+	// restoring it gives a fresh client the peer's address, protocol and
+	// score.
+	ps, err := pstoremem.NewPeerstore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+	restored := NewScores(ScoreParams{Success: 1})
+	if err := ab.restore(ctx, ps, restored); err != nil {
+		t.Fatal(err)
+	}
+	if addrs := ps.Addrs(p); len(addrs) != 1 || !addrs[0].Equal(multiaddr.StringCast("/ip4/127.0.0.1/tcp/4001")) {
+		t.Fatalf("addresses restored as %v", addrs)
+	}
+	if protos, err := ps.SupportsProtocols(p, ProtocolPrivate); err != nil || len(protos) != 1 {
+		t.Fatalf("protocols restored as %v, %v", protos, err)
+	}
+	if restored.Score(p) != 2 {
+		t.Fatalf("score restored as %v", restored.Score(p))
+	}
+
+	if err := ab.Forget(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if peers, err := ab.Peers(ctx); err != nil || len(peers) != 0 {
+		t.Fatalf("peers listed after forgetting as %v, %v", peers, err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package announce publishes provider records for the blocks a server
+// holds, so clients looking up the providers of a CID find the server.
+//
+// Records are published to any libp2p content router, such as the Kademlia
+// DHT, and refreshed on a schedule before they expire. Blocks put into a
+// MutableBlockstore are announced as they arrive, rather than at the next
+// refresh.
+package announce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+)
+
+const (
+	// DefaultInterval is how often all records are published again when
+	// Options does not say otherwise. DHT records expire after 48 hours.
+	DefaultInterval = 22 * time.Hour
+	// DefaultConcurrency is the number of records published at once when
+	// Options does not say otherwise.
+	DefaultConcurrency = 8
+)
+
+var logger = logging.Logger("bitswap-announce")
+
+// Router publishes provider records. A libp2p ContentRouting, such as the
+// Kademlia DHT, is a Router.
+type Router interface {
+	Provide(ctx context.Context, c cid.Cid, announce bool) error
+}
+
+// Flusher is implemented by routers which batch the records given to them,
+// such as ipni.Publisher, publishing them when flushed.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+type Options struct {
+	// Interval is how often all records are published again. Defaults to
+	// DefaultInterval.
+	Interval time.Duration
+	// Concurrency bounds the records published at once. Defaults to
+	// DefaultConcurrency.
+	Concurrency int
+	// Timeout bounds the publishing of each record to each router. If
+	// zero, only the context of the pass bounds it.
+	Timeout time.Duration
+}
+
+// Announcer publishes the provider records of the blocks of a blockstore.
+type Announcer struct {
+	bs      bitswapserver.EnumerableBlockstore
+	routers []Router
+	opts    Options
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates an announcer publishing the blocks of bs to routers.
+func New(bs bitswapserver.EnumerableBlockstore, opts Options, routers ...Router) *Announcer {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	return &Announcer{bs: bs, routers: routers, opts: opts}
+}
+
+// provide publishes the record of c to every router, returning the last
+// error.
+func (a *Announcer) provide(ctx context.Context, c cid.Cid) error {
+	var lastErr error
+	for _, r := range a.routers {
+		rctx, cancel := ctx, context.CancelFunc(func() {})
+		if a.opts.Timeout > 0 {
+			rctx, cancel = context.WithTimeout(ctx, a.opts.Timeout)
+		}
+		if err := r.Provide(rctx, c, true); err != nil {
+			lastErr = err
+		}
+		cancel()
+	}
+	return lastErr
+}
+
+// Announce publishes the records of every block of the blockstore once,
+// returning how many were published to every router. Blocks whose records
+// fail to publish are skipped, and the last error returned once all were
+// tried.
+func (a *Announcer) Announce(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keys, err := a.bs.AllKeysChan(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		mtx     sync.Mutex
+		n       int
+		lastErr error
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < a.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range keys {
+				err := a.provide(ctx, c)
+				mtx.Lock()
+				if err != nil {
+					lastErr = err
+				} else {
+					n++
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return n, err
+	}
+	if err := a.flush(ctx); err != nil {
+		lastErr = err
+	}
+	return n, lastErr
+}
+
+// flush flushes the routers which batch records, returning the last error.
+func (a *Announcer) flush(ctx context.Context) error {
+	var lastErr error
+	for _, r := range a.routers {
+		if f, ok := r.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
+// Start announces every block now and then every Interval, and blocks put
+// into a MutableBlockstore as they arrive, until Close.
+func (a *Announcer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+	if mbs, ok := a.bs.(bitswapserver.MutableBlockstore); ok {
+		unsubscribe := mbs.Subscribe(func(c cid.Cid, data []byte) {
+			if data == nil {
+				return
+			}
+			go func() {
+				err := a.provide(ctx, c)
+				if ferr := a.flush(ctx); ferr != nil {
+					err = ferr
+				}
+				if err != nil && !errors.Is(err, context.Canceled) {
+					logger.Warnw("failed to announce block", "cid", c, "err", err)
+				}
+			}()
+		})
+		go func() {
+			<-ctx.Done()
+			unsubscribe()
+		}()
+	}
+	go a.run(ctx)
+}
+
+func (a *Announcer) run(ctx context.Context) {
+	defer close(a.done)
+	t := time.NewTicker(a.opts.Interval)
+	defer t.Stop()
+	for {
+		n, err := a.Announce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warnw("failed to announce some blocks", "announced", n, "err", err)
+		} else {
+			logger.Infow("announced blocks", "announced", n)
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close stops announcing, waiting for the current pass to stop.
+func (a *Announcer) Close() error {
+	if a.cancel == nil {
+		return nil
+	}
+	a.cancel()
+	<-a.done
+	return nil
+}
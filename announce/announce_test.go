@@ -0,0 +1,75 @@
+package announce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/announce"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// router records the CIDs provided, failing for those in fail.
+type router struct {
+	mtx      sync.Mutex
+	provided map[cid.Cid]int
+	fail     map[cid.Cid]bool
+}
+
+func newRouter() *router {
+	return &router{provided: make(map[cid.Cid]int), fail: make(map[cid.Cid]bool)}
+}
+
+func (r *router) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.fail[c] {
+		return errors.New("unreachable")
+	}
+	r.provided[c]++
+	return nil
+}
+
+func (r *router) count(c cid.Cid) int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.provided[c]
+}
+
+func TestAnnounce(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	a := util.Add(bs, []byte("hello world"))
+	b := util.Add(bs, []byte("hello world 2"))
+	r1, r2 := newRouter(), newRouter()
+	r2.fail[b] = true
+
+	n, err := announce.New(bs, announce.Options{Concurrency: 2}, r1, r2).Announce(context.Background())
+	if err == nil || n != 1 {
+		t.Fatalf("announced %d blocks to every router: %v", n, err)
+	}
+	if r1.count(a) != 1 || r1.count(b) != 1 || r2.count(a) != 1 {
+		t.Fatal("every record should be published to every router")
+	}
+}
+
+func TestStart(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	a := util.Add(bs, []byte("hello world"))
+	r := newRouter()
+	an := announce.New(bs, announce.Options{Interval: 10 * time.Millisecond}, r)
+	an.Start()
+	b := util.Add(bs, []byte("hello world 2"))
+	deadline := time.Now().Add(5 * time.Second)
+	for r.count(a) < 2 || r.count(b) < 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("published %d and %d records", r.count(a), r.count(b))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := an.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,304 @@
+package ipni
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/record"
+	"github.com/multiformats/go-multihash"
+)
+
+var ErrBadSignature = errors.New("advertisement signature does not verify")
+
+// Advertisement announces to indexers that Provider serves the multihashes
+// of a chain of entry chunks, under ContextID, with the protocols its
+// Metadata declares. Advertisements link to the one published before, so
+// indexers walk the chain back to the last they ingested.
+type Advertisement struct {
+	PreviousID cid.Cid
+	Provider   peer.ID
+	Addresses  []string
+	Signature  []byte
+	Entries    cid.Cid
+	ContextID  []byte
+	Metadata   []byte
+	IsRm       bool
+}
+
+// EntryChunk is a link of the chain of multihashes an advertisement
+// announces.
+type EntryChunk struct {
+	Entries []multihash.Multihash
+	Next    cid.Cid
+}
+
+// encode builds a dag-json block of n, returning its CID and data.
+func encode(n datamodel.Node) (cid.Cid, []byte, error) {
+	var buf bytes.Buffer
+	if err := dagjson.Encode(n, &buf); err != nil {
+		return cid.Undef, nil, err
+	}
+	h, err := multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return cid.NewCidV1(cid.DagJSON, h), buf.Bytes(), nil
+}
+
+func decode(data []byte) (datamodel.Node, error) {
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagjson.Decode(nb, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// Encode returns the CID and dag-json block of the chunk.
+func (ec *EntryChunk) Encode() (cid.Cid, []byte, error) {
+	fields := int64(1)
+	if ec.Next.Defined() {
+		fields++
+	}
+	n, err := qp.BuildMap(basicnode.Prototype.Any, fields, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "Entries", qp.List(int64(len(ec.Entries)), func(la datamodel.ListAssembler) {
+			for _, mh := range ec.Entries {
+				qp.ListEntry(la, qp.Bytes(mh))
+			}
+		}))
+		if ec.Next.Defined() {
+			qp.MapEntry(ma, "Next", qp.Link(cidlink.Link{Cid: ec.Next}))
+		}
+	})
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return encode(n)
+}
+
+// DecodeEntryChunk parses a chunk from its dag-json block.
+func DecodeEntryChunk(data []byte) (*EntryChunk, error) {
+	n, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	ec := &EntryChunk{}
+	entries, err := n.LookupByString("Entries")
+	if err != nil {
+		return nil, err
+	}
+	for it := entries.ListIterator(); it != nil && !it.Done(); {
+		_, v, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		mh, err := v.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		ec.Entries = append(ec.Entries, mh)
+	}
+	if ec.Next, err = optionalLink(n, "Next"); err != nil {
+		return nil, err
+	}
+	return ec, nil
+}
+
+func optionalLink(n datamodel.Node, key string) (cid.Cid, error) {
+	v, err := n.LookupByString(key)
+	if err != nil {
+		return cid.Undef, nil
+	}
+	l, err := v.AsLink()
+	if err != nil {
+		return cid.Undef, err
+	}
+	cl, ok := l.(cidlink.Link)
+	if !ok {
+		return cid.Undef, fmt.Errorf("%s is not a CID", key)
+	}
+	return cl.Cid, nil
+}
+
+// Encode returns the CID and dag-json block of the advertisement.
+func (ad *Advertisement) Encode() (cid.Cid, []byte, error) {
+	fields := int64(7)
+	if ad.PreviousID.Defined() {
+		fields++
+	}
+	n, err := qp.BuildMap(basicnode.Prototype.Any, fields, func(ma datamodel.MapAssembler) {
+		if ad.PreviousID.Defined() {
+			qp.MapEntry(ma, "PreviousID", qp.Link(cidlink.Link{Cid: ad.PreviousID}))
+		}
+		qp.MapEntry(ma, "Provider", qp.String(ad.Provider.String()))
+		qp.MapEntry(ma, "Addresses", qp.List(int64(len(ad.Addresses)), func(la datamodel.ListAssembler) {
+			for _, a := range ad.Addresses {
+				qp.ListEntry(la, qp.String(a))
+			}
+		}))
+		qp.MapEntry(ma, "Signature", qp.Bytes(ad.Signature))
+		qp.MapEntry(ma, "Entries", qp.Link(cidlink.Link{Cid: ad.Entries}))
+		qp.MapEntry(ma, "ContextID", qp.Bytes(ad.ContextID))
+		qp.MapEntry(ma, "Metadata", qp.Bytes(ad.Metadata))
+		qp.MapEntry(ma, "IsRm", qp.Bool(ad.IsRm))
+	})
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return encode(n)
+}
+
+// DecodeAdvertisement parses an advertisement from its dag-json block.
+func DecodeAdvertisement(data []byte) (*Advertisement, error) {
+	n, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	ad := &Advertisement{}
+	str := func(key string) (string, error) {
+		v, err := n.LookupByString(key)
+		if err != nil {
+			return "", err
+		}
+		return v.AsString()
+	}
+	byts := func(key string) ([]byte, error) {
+		v, err := n.LookupByString(key)
+		if err != nil {
+			return nil, err
+		}
+		return v.AsBytes()
+	}
+	if ad.PreviousID, err = optionalLink(n, "PreviousID"); err != nil {
+		return nil, err
+	}
+	provider, err := str("Provider")
+	if err != nil {
+		return nil, err
+	}
+	if ad.Provider, err = peer.Decode(provider); err != nil {
+		return nil, err
+	}
+	addrs, err := n.LookupByString("Addresses")
+	if err != nil {
+		return nil, err
+	}
+	for it := addrs.ListIterator(); it != nil && !it.Done(); {
+		_, v, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		a, err := v.AsString()
+		if err != nil {
+			return nil, err
+		}
+		ad.Addresses = append(ad.Addresses, a)
+	}
+	if ad.Signature, err = byts("Signature"); err != nil {
+		return nil, err
+	}
+	if ad.Entries, err = optionalLink(n, "Entries"); err != nil || !ad.Entries.Defined() {
+		return nil, fmt.Errorf("advertisement without entries: %v", err)
+	}
+	if ad.ContextID, err = byts("ContextID"); err != nil {
+		return nil, err
+	}
+	if ad.Metadata, err = byts("Metadata"); err != nil {
+		return nil, err
+	}
+	isRm, err := n.LookupByString("IsRm")
+	if err != nil {
+		return nil, err
+	}
+	if ad.IsRm, err = isRm.AsBool(); err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
+// signedFields is the digest of the fields of ad covered by its signature.
+func (ad *Advertisement) signedFields() ([]byte, error) {
+	var buf bytes.Buffer
+	if ad.PreviousID.Defined() {
+		buf.Write(ad.PreviousID.Bytes())
+	}
+	buf.Write(ad.Entries.Bytes())
+	buf.WriteString(ad.Provider.String())
+	for _, a := range ad.Addresses {
+		buf.WriteString(a)
+	}
+	buf.Write(ad.ContextID)
+	buf.Write(ad.Metadata)
+	if ad.IsRm {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	return multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+}
+
+// signatureRecord is the record sealed in the envelope of an advertisement
+// signature.
+type signatureRecord struct {
+	digest []byte
+}
+
+func (r *signatureRecord) Domain() string {
+	return "indexer"
+}
+
+func (r *signatureRecord) Codec() []byte {
+	return []byte("/indexer/ingest/adSignature")
+}
+
+func (r *signatureRecord) MarshalRecord() ([]byte, error) {
+	return r.digest, nil
+}
+
+func (r *signatureRecord) UnmarshalRecord(data []byte) error {
+	r.digest = data
+	return nil
+}
+
+// Sign signs ad as its provider, whose key is key.
+func (ad *Advertisement) Sign(key crypto.PrivKey) error {
+	digest, err := ad.signedFields()
+	if err != nil {
+		return err
+	}
+	env, err := record.Seal(&signatureRecord{digest}, key)
+	if err != nil {
+		return err
+	}
+	ad.Signature, err = env.Marshal()
+	return err
+}
+
+// Verify checks that ad is signed by its provider.
+func (ad *Advertisement) Verify() error {
+	var rec signatureRecord
+	env, err := record.ConsumeTypedEnvelope(ad.Signature, &rec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	signer, err := peer.IDFromPublicKey(env.PublicKey)
+	if err != nil || signer != ad.Provider {
+		return ErrBadSignature
+	}
+	digest, err := ad.signedFields()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(digest, rec.digest) {
+		return ErrBadSignature
+	}
+	return nil
+}
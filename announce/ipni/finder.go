@@ -0,0 +1,124 @@
+package ipni
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// findResponse is the reply of an indexer to a multihash lookup.
+type findResponse struct {
+	MultihashResults []struct {
+		ProviderResults []struct {
+			ContextID []byte
+			Metadata  []byte
+			Provider  peer.AddrInfo
+		}
+	}
+}
+
+// Finder looks up on an indexer the providers which advertised private
+// retrieval, and is a routing.Finder. The indexer learns which CID was
+// looked up, as a DHT server would.
+type Finder struct {
+	url     string
+	client  *http.Client
+	schemes []string
+}
+
+// NewFinder creates a finder querying the indexer at the base URL url for
+// providers advertising PIR, with one of schemes if any are given.
+func NewFinder(url string, schemes ...string) *Finder {
+	return &Finder{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{Timeout: time.Minute},
+		schemes: schemes,
+	}
+}
+
+// accepts reports whether metadata declares private retrieval with one of
+// the schemes of the finder.
+func (f *Finder) accepts(metadata []byte) bool {
+	_, schemes, err := ParseMetadata(metadata)
+	if err != nil || len(schemes) == 0 {
+		return false
+	}
+	if len(f.schemes) == 0 {
+		return true
+	}
+	for _, s := range schemes {
+		for _, want := range f.schemes {
+			if s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Find returns the providers of c advertising PIR.
+func (f *Finder) Find(ctx context.Context, c cid.Cid) ([]peer.AddrInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url+"/multihash/"+c.Hash().B58String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("indexer replied %s", resp.Status)
+	}
+	var found findResponse
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, err
+	}
+	var out []peer.AddrInfo
+	seen := make(map[peer.ID]bool)
+	for _, mr := range found.MultihashResults {
+		for _, pr := range mr.ProviderResults {
+			if seen[pr.Provider.ID] || !f.accepts(pr.Metadata) {
+				continue
+			}
+			seen[pr.Provider.ID] = true
+			out = append(out, pr.Provider)
+		}
+	}
+	return out, nil
+}
+
+// FindProvidersAsync returns up to count of the providers Find returns, or
+// all of them if count is 0. Failed lookups find none.
+func (f *Finder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		found, err := f.Find(ctx, c)
+		if err != nil {
+			logger.Warnw("indexer lookup failed", "indexer", f.url, "cid", c, "err", err)
+			return
+		}
+		for i, ai := range found {
+			if count > 0 && i >= count {
+				return
+			}
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
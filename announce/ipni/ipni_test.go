@@ -0,0 +1,201 @@
+package ipni_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/willscott/go-selfish-bitswap-client/announce"
+	"github.com/willscott/go-selfish-bitswap-client/announce/ipni"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestMetadata(t *testing.T) {
+	md, err := ipni.Metadata("fastpir-lwe1024/v1", "spiral/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitswap, schemes, err := ipni.ParseMetadata(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bitswap || len(schemes) != 2 || schemes[0] != "fastpir-lwe1024/v1" || schemes[1] != "spiral/v1" {
+		t.Fatalf("parsed bitswap %v, schemes %v", bitswap, schemes)
+	}
+	md, err = ipni.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bitswap, schemes, err = ipni.ParseMetadata(md); err != nil || !bitswap || len(schemes) != 0 {
+		t.Fatalf("plain bitswap metadata parsed as %v %v: %v", bitswap, schemes, err)
+	}
+}
+
+func get(t *testing.T, url string) []byte {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("fetching %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// entries walks the chain of entry chunks from first.
+func entries(t *testing.T, base string, first cid.Cid) int {
+	n := 0
+	for c := first; c.Defined(); {
+		chunk, err := ipni.DecodeEntryChunk(get(t, base+ipni.AdPath+c.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += len(chunk.Entries)
+		c = chunk.Next
+	}
+	return n
+}
+
+func TestPublish(t *testing.T) {
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	announced := make(chan []byte, 4)
+	indexer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/announce" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		announced <- body
+	}))
+	defer indexer.Close()
+
+	addr := ma.StringCast("/ip4/127.0.0.1/tcp/4001")
+	pub, err := ipni.New(key, ipni.Options{
+		Schemes:        []string{"fastpir-lwe1024/v1"},
+		Addrs:          []ma.Multiaddr{addr},
+		PublisherAddrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/3104/http")},
+		Indexers:       []string{indexer.URL},
+		ChunkSize:      2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(pub)
+	defer srv.Close()
+
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	for _, b := range []string{"a", "b", "c"} {
+		util.Add(bs, []byte(b))
+	}
+	an := announce.New(bs, announce.Options{}, pub)
+	if n, err := an.Announce(context.Background()); err != nil || n != 3 {
+		t.Fatalf("announced %d blocks: %v", n, err)
+	}
+	if len(announced) != 1 {
+		t.Fatalf("indexer told of %d heads", len(announced))
+	}
+	head := pub.Head()
+	get(t, srv.URL+ipni.AdPath+"head")
+	ad, err := ipni.DecodeAdvertisement(get(t, srv.URL+ipni.AdPath+head.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ad.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if ad.PreviousID.Defined() || len(ad.Addresses) != 1 || ad.Addresses[0] != addr.String() {
+		t.Fatalf("unexpected advertisement %+v", ad)
+	}
+	if _, schemes, err := ipni.ParseMetadata(ad.Metadata); err != nil || len(schemes) != 1 {
+		t.Fatalf("advertised schemes %v: %v", schemes, err)
+	}
+	if n := entries(t, srv.URL, ad.Entries); n != 3 {
+		t.Fatalf("advertised %d multihashes", n)
+	}
+
+	// blocks advertised already are not advertised again.
+	if _, err := an.Announce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Head().Equals(head) {
+		t.Fatal("nothing new should be advertised")
+	}
+	util.Add(bs, []byte("d"))
+	if _, err := an.Announce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	next, err := ipni.DecodeAdvertisement(get(t, srv.URL+ipni.AdPath+pub.Head().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.PreviousID.Equals(head) || entries(t, srv.URL, next.Entries) != 1 {
+		t.Fatalf("unexpected advertisement %+v", next)
+	}
+
+	ad.Metadata = nil
+	if err := ad.Verify(); err == nil {
+		t.Fatal("tampered advertisement should not verify")
+	}
+}
+
+func TestFinder(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(bs, []byte("a"))
+	plain, err := ipni.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := ipni.Metadata("fastpir-lwe1024/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const privatePeer = "12D3KooWD3eckifWpRn9wQpMG9R9hX3sD158z7EqHWmweQAJU5SA"
+	const plainPeer = "12D3KooWJWoaqZhDaoEFshF7Rh1bpY9ohihFhzcW6d69Lr2NASuq"
+	indexer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/multihash/"+c.Hash().B58String() {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"MultihashResults": []interface{}{map[string]interface{}{
+				"Multihash": []byte(c.Hash()),
+				"ProviderResults": []interface{}{
+					map[string]interface{}{"Metadata": plain, "Provider": map[string]interface{}{"ID": plainPeer, "Addrs": []string{"/ip4/127.0.0.1/tcp/4001"}}},
+					map[string]interface{}{"Metadata": private, "Provider": map[string]interface{}{"ID": privatePeer, "Addrs": []string{"/ip4/127.0.0.1/tcp/4002"}}},
+				},
+			}},
+		})
+	}))
+	defer indexer.Close()
+
+	var found []peer.AddrInfo
+	for ai := range ipni.NewFinder(indexer.URL).FindProvidersAsync(context.Background(), c, 0) {
+		found = append(found, ai)
+	}
+	if len(found) != 1 || found[0].ID.String() != privatePeer || len(found[0].Addrs) != 1 {
+		t.Fatalf("found %v", found)
+	}
+	if found, err := ipni.NewFinder(indexer.URL, "spiral/v1").Find(context.Background(), c); err != nil || len(found) != 0 {
+		t.Fatalf("found %v with no scheme in common: %v", found, err)
+	}
+	other := util.Add(bs, []byte("b"))
+	if found, err := ipni.NewFinder(indexer.URL).Find(context.Background(), other); err != nil || len(found) != 0 {
+		t.Fatalf("found %v for an unknown CID: %v", found, err)
+	}
+}
@@ -0,0 +1,94 @@
+package ipni
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multicodec"
+)
+
+// TransportPIR is the metadata protocol code declaring that a provider
+// answers private retrievals. Its payload is the dag-cbor list of the
+// schemes it answers with, preferred first. The code is in the multicodec
+// private use range until one is registered.
+const TransportPIR multicodec.Code = 0x300900
+
+var ErrMalformedMetadata = errors.New("malformed advertisement metadata")
+
+// Metadata declares retrieval over bitswap and, if any schemes are given,
+// private retrieval with them. Protocols are listed in the order of their
+// codes, as indexers expect, so those unaware of TransportPIR still read the
+// bitswap entry.
+func Metadata(schemes ...string) ([]byte, error) {
+	out := appendUvarint(nil, uint64(multicodec.TransportBitswap))
+	if len(schemes) == 0 {
+		return out, nil
+	}
+	n, err := qp.BuildList(basicnode.Prototype.Any, int64(len(schemes)), func(la datamodel.ListAssembler) {
+		for _, s := range schemes {
+			qp.ListEntry(la, qp.String(s))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	var payload bytes.Buffer
+	if err := dagcbor.Encode(n, &payload); err != nil {
+		return nil, err
+	}
+	out = appendUvarint(out, uint64(TransportPIR))
+	return append(out, payload.Bytes()...), nil
+}
+
+// ParseMetadata reads metadata written by Metadata, reporting whether it
+// declares bitswap retrieval and the PIR schemes it declares. Reading stops
+// at the first protocol other than these, whose payload cannot be skipped.
+func ParseMetadata(b []byte) (bitswap bool, schemes []string, err error) {
+	for len(b) > 0 {
+		code, n := binary.Uvarint(b)
+		if n <= 0 {
+			return false, nil, ErrMalformedMetadata
+		}
+		b = b[n:]
+		switch multicodec.Code(code) {
+		case multicodec.TransportBitswap:
+			bitswap = true
+		case TransportPIR:
+			nb := basicnode.Prototype.Any.NewBuilder()
+			r := bytes.NewReader(b)
+			if err := dagcbor.Decode(nb, r); err != nil {
+				return false, nil, fmt.Errorf("%w: %v", ErrMalformedMetadata, err)
+			}
+			b = b[len(b)-r.Len():]
+			it := nb.Build().ListIterator()
+			if it == nil {
+				return false, nil, ErrMalformedMetadata
+			}
+			for !it.Done() {
+				_, v, err := it.Next()
+				if err != nil {
+					return false, nil, err
+				}
+				s, err := v.AsString()
+				if err != nil {
+					return false, nil, fmt.Errorf("%w: %v", ErrMalformedMetadata, err)
+				}
+				schemes = append(schemes, s)
+			}
+		default:
+			return bitswap, schemes, nil
+		}
+	}
+	return bitswap, schemes, nil
+}
+
+func appendUvarint(dst []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(dst, buf[:binary.PutUvarint(buf[:], x)]...)
+}
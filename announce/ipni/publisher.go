@@ -0,0 +1,313 @@
+// Package ipni publishes advertisements to network indexers, so clients
+// find providers with a single indexer lookup rather than a DHT walk.
+// Advertisements declare, in their metadata, bitswap retrieval and the PIR
+// schemes the provider answers with, so clients can pick out the providers
+// they can retrieve from privately.
+//
+// The Publisher is an announce.Router: the CIDs it is given are queued, and
+// published in one advertisement when the announcer flushes it. The chain
+// of advertisements is served over HTTP, for indexers to fetch once told of
+// a new head.
+package ipni
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+)
+
+const (
+	// DefaultChunkSize is the number of multihashes per entry chunk when
+	// Options does not say otherwise.
+	DefaultChunkSize = 16384
+	// DefaultTopic is the topic the head is signed for when Options does
+	// not say otherwise.
+	DefaultTopic = "/indexer/ingest/mainnet"
+	// AdPath is the path under which the Publisher serves advertisements
+	// and entry chunks by CID, and the signed head at AdPath+"head".
+	AdPath = "/ipni/v1/ad/"
+)
+
+// ContextID groups the advertisements of a Publisher, so each adds to the
+// multihashes of the last, and its metadata applies to all of them.
+var ContextID = []byte("pirbitswap")
+
+var logger = logging.Logger("bitswap-ipni")
+
+var ErrNoHead = errors.New("nothing published yet")
+
+type Options struct {
+	// Schemes are the PIR schemes declared in the metadata, preferred
+	// first. Without any, only bitswap retrieval is declared.
+	Schemes []string
+	// Addrs are the addresses the provider serves bitswap on.
+	Addrs []ma.Multiaddr
+	// PublisherAddrs are the HTTP addresses the Publisher is served on, as
+	// given to indexers in announcements.
+	PublisherAddrs []ma.Multiaddr
+	// Indexers are the base URLs of the indexers told of each new head.
+	Indexers []string
+	// ChunkSize bounds the multihashes per entry chunk. Defaults to
+	// DefaultChunkSize.
+	ChunkSize int
+	// Topic is the topic the head is signed for. Defaults to DefaultTopic.
+	Topic string
+	// Client sends announcements. Defaults to a client with a timeout of
+	// a minute.
+	Client *http.Client
+}
+
+// Publisher publishes the advertisements of a provider.
+type Publisher struct {
+	key      crypto.PrivKey
+	id       peer.ID
+	opts     Options
+	metadata []byte
+
+	mtx       sync.Mutex
+	blocks    map[cid.Cid][]byte
+	head      cid.Cid
+	published map[string]bool
+	pending   []multihash.Multihash
+}
+
+// New creates a publisher advertising as the provider whose key is key.
+func New(key crypto.PrivKey, opts Options) (*Publisher, error) {
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := Metadata(opts.Schemes...)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Topic == "" {
+		opts.Topic = DefaultTopic
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: time.Minute}
+	}
+	return &Publisher{
+		key:       key,
+		id:        id,
+		opts:      opts,
+		metadata:  metadata,
+		blocks:    make(map[cid.Cid][]byte),
+		published: make(map[string]bool),
+	}, nil
+}
+
+// Provide queues the multihash of c for the next advertisement, unless it
+// was published already.
+func (p *Publisher) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	mh := c.Hash()
+	if !p.published[string(mh)] {
+		p.published[string(mh)] = true
+		p.pending = append(p.pending, mh)
+	}
+	return nil
+}
+
+// Flush publishes the queued multihashes in a new advertisement, and tells
+// the indexers of it.
+func (p *Publisher) Flush(ctx context.Context) error {
+	head, err := p.advertise()
+	if err != nil || !head.Defined() {
+		return err
+	}
+	return p.announce(ctx, head)
+}
+
+// advertise links the queued multihashes into a new advertisement,
+// returning its CID, or cid.Undef if none were queued.
+func (p *Publisher) advertise() (cid.Cid, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if len(p.pending) == 0 {
+		return cid.Undef, nil
+	}
+	// the chain is built from its end, so the first chunk holds the first
+	// multihashes.
+	var next cid.Cid
+	for end := len(p.pending); end > 0; end -= p.opts.ChunkSize {
+		start := end - p.opts.ChunkSize
+		if start < 0 {
+			start = 0
+		}
+		chunk := &EntryChunk{Entries: p.pending[start:end], Next: next}
+		c, data, err := chunk.Encode()
+		if err != nil {
+			return cid.Undef, err
+		}
+		p.blocks[c] = data
+		next = c
+	}
+	ad := &Advertisement{
+		PreviousID: p.head,
+		Provider:   p.id,
+		Entries:    next,
+		ContextID:  ContextID,
+		Metadata:   p.metadata,
+	}
+	for _, a := range p.opts.Addrs {
+		ad.Addresses = append(ad.Addresses, a.String())
+	}
+	if err := ad.Sign(p.key); err != nil {
+		return cid.Undef, err
+	}
+	c, data, err := ad.Encode()
+	if err != nil {
+		return cid.Undef, err
+	}
+	p.blocks[c] = data
+	p.head = c
+	p.pending = nil
+	return c, nil
+}
+
+// announce tells the indexers of a new head, returning the last error.
+func (p *Publisher) announce(ctx context.Context, head cid.Cid) error {
+	if len(p.opts.Indexers) == 0 {
+		return nil
+	}
+	self, err := ma.NewComponent("p2p", p.id.String())
+	if err != nil {
+		return err
+	}
+	n, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(m datamodel.MapAssembler) {
+		qp.MapEntry(m, "Cid", qp.Link(cidlink.Link{Cid: head}))
+		qp.MapEntry(m, "Addrs", qp.List(int64(len(p.opts.PublisherAddrs)), func(la datamodel.ListAssembler) {
+			for _, a := range p.opts.PublisherAddrs {
+				qp.ListEntry(la, qp.Bytes(a.Encapsulate(self).Bytes()))
+			}
+		}))
+	})
+	if err != nil {
+		return err
+	}
+	_, msg, err := encode(n)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, indexer := range p.opts.Indexers {
+		if err := p.put(ctx, strings.TrimSuffix(indexer, "/")+"/announce", msg); err != nil {
+			logger.Warnw("failed to announce to indexer", "indexer", indexer, "err", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (p *Publisher) put(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("indexer replied %s", resp.Status)
+	}
+	return nil
+}
+
+// Head returns the CID of the last advertisement published.
+func (p *Publisher) Head() cid.Cid {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.head
+}
+
+// SignedHead returns the dag-json signed head, naming the last
+// advertisement published and signed by the provider with its topic.
+func (p *Publisher) SignedHead() ([]byte, error) {
+	head := p.Head()
+	if !head.Defined() {
+		return nil, ErrNoHead
+	}
+	pub, err := crypto.MarshalPublicKey(p.key.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := p.key.Sign(append(head.Bytes(), p.opts.Topic...))
+	if err != nil {
+		return nil, err
+	}
+	n, err := qp.BuildMap(basicnode.Prototype.Any, 4, func(m datamodel.MapAssembler) {
+		qp.MapEntry(m, "head", qp.Link(cidlink.Link{Cid: head}))
+		qp.MapEntry(m, "topic", qp.String(p.opts.Topic))
+		qp.MapEntry(m, "pubkey", qp.Bytes(pub))
+		qp.MapEntry(m, "sig", qp.Bytes(sig))
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, data, err := encode(n)
+	return data, err
+}
+
+// ServeHTTP serves the signed head at AdPath+"head", and the blocks of the
+// chain by CID under AdPath.
+func (p *Publisher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, AdPath)
+	if name == r.URL.Path || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	if name == "head" {
+		data, err := p.SignedHead()
+		if errors.Is(err, ErrNoHead) {
+			http.Error(w, err.Error(), http.StatusNoContent)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+		return
+	}
+	c, err := cid.Decode(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mtx.Lock()
+	data, ok := p.blocks[c]
+	p.mtx.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,105 @@
+package bitswap
+
+import (
+	"fmt"
+	"hash"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// MaxAnswerSize bounds the PIR answers sessions reassemble, for schemes
+// which cannot decode answers as they arrive. Answers which are decoded as
+// they arrive are not bounded, since they are not held.
+const MaxAnswerSize = 256 << 20
+
+// answerStream receives one PIR answer, which peers may split across
+// messages, passing it to dec as it arrives if the scheme can decode it so,
+// and reassembling it otherwise.
+type answerStream struct {
+	// id is that of the request answered.
+	id       uint64
+	dec      pir.Decoder
+	answer   []byte
+	received uint64
+	total    uint64
+	epoch    uint64
+	// digest, if set, hashes the answer as it arrives, for its receipt.
+	digest hash.Hash
+}
+
+// add adds the piece of the answer in r, reporting whether the whole
+// answer has arrived. Pieces must arrive in order.
+func (as *answerStream) add(r bitswap_message_pb.Message_PIRResponse) (bool, error) {
+	n := uint64(len(r.Answer))
+	total := r.Total
+	if total == 0 && r.Offset == 0 {
+		// answers which were not split arrive whole.
+		total = n
+	}
+	if as.received == 0 {
+		if as.dec == nil && total > MaxAnswerSize {
+			return false, fmt.Errorf("%w: answer of %d bytes exceeds %d", pir.ErrMalformed, total, MaxAnswerSize)
+		}
+		as.total, as.epoch = total, r.Epoch
+	}
+	if total != as.total || r.Epoch != as.epoch || r.Offset != as.received || n > as.total-as.received {
+		return false, fmt.Errorf("%w: inconsistent answer piece at %d of %d", pir.ErrMalformed, r.Offset, total)
+	}
+	if as.digest != nil {
+		as.digest.Write(r.Answer)
+	}
+	if as.dec != nil {
+		if _, err := as.dec.Write(r.Answer); err != nil {
+			return false, err
+		}
+	} else {
+		as.answer = append(as.answer, r.Answer...)
+	}
+	as.received += n
+	return as.received == as.total, nil
+}
+
+// onResponse delivers a PIR response, or a piece of one, to the round
+// awaiting it. Pieces extend the round's response timeout as progress
+// reports do; responses no round registered a stream for are delivered
+// as they are. Responses naming another request than the one awaited, such
+// as late answers to an abandoned round, are dropped; peers which do not
+// echo request ids are trusted to answer in kind.
+func (s *Session) onResponse(r bitswap_message_pb.Message_PIRResponse) error {
+	key := pirInterest(r.Session, r.Round, r.Part)
+	s.interestMtx.Lock()
+	as, ok := s.answers[key]
+	progressed := s.progress[progressInterest(r.Session, r.Round)]
+	s.interestMtx.Unlock()
+	if !ok {
+		resp, err := r.Marshal()
+		if err != nil {
+			return err
+		}
+		return s.resolveKey(key, resp)
+	}
+	if r.Id != 0 && r.Id != as.id {
+		return fmt.Errorf("response to request %d, awaiting %d", r.Id, as.id)
+	}
+
+	complete, err := as.add(r)
+	if err != nil {
+		return s.deliver(key, nil, err)
+	}
+	if !complete {
+		if progressed != nil {
+			select {
+			case progressed <- struct{}{}:
+			default:
+			}
+		}
+		return nil
+	}
+	r.Answer, r.Offset, r.Total = as.answer, 0, 0
+	resp, err := r.Marshal()
+	if err != nil {
+		return s.deliver(key, nil, err)
+	}
+	return s.resolveKey(key, resp)
+}
@@ -0,0 +1,120 @@
+package bitswap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+)
+
+// awaiting waits until a round awaits the response under key.
+func awaiting(s *Session, key string) {
+	for {
+		s.interestMtx.Lock()
+		_, ok := s.interests[key]
+		s.interestMtx.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAnswerPieces(t *testing.T) {
+	s := New(nil, "p", Options{ResponseTimeout: time.Second})
+	s.private = sinkStream{}
+	scheme := fastpir.New()
+	db := pirtest.RandomDatabase(17, 45)
+	enc, err := scheme.Setup(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	round := bitswap_message_pb.Message_BlockRound
+	q, sec, err := scheme.Query(enc.Params, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer, err := scheme.Answer(enc, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ask := func(session uint64) (pir.Decoder, chan error) {
+		d, err := scheme.NewDecoder(enc.Params, sec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.ask(context.Background(), session, round, 0, enc.Params, [][]byte{q}, []pir.Decoder{d})
+			done <- err
+		}()
+		awaiting(s, pirInterest(session, round, 0))
+		return d, done
+	}
+
+	d, done := ask(1)
+	for off := 0; off < len(answer); off += 100 {
+		end := off + 100
+		if end > len(answer) {
+			end = len(answer)
+		}
+		deliver(t, s, bitswap_message_pb.Message{PirResponses: []bitswap_message_pb.Message_PIRResponse{{
+			Session: 1, Round: round, Offset: uint64(off), Total: uint64(len(answer)), Answer: answer[off:end],
+		}}})
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Element()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, db.Elements[9]) {
+		t.Fatal("element decoded wrongly from the pieces of its answer")
+	}
+
+	// pieces out of order fail the round.
+	_, done = ask(2)
+	deliver(t, s, bitswap_message_pb.Message{PirResponses: []bitswap_message_pb.Message_PIRResponse{{
+		Session: 2, Round: round, Offset: 100, Total: uint64(len(answer)), Answer: answer[100:200],
+	}}})
+	if err := <-done; !errors.Is(err, pir.ErrMalformed) {
+		t.Fatalf("out of order piece: got %v", err)
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	s := New(nil, "p", Options{ResponseTimeout: time.Second})
+	s.private = sinkStream{}
+	round := bitswap_message_pb.Message_BlockRound
+	params := pir.Params{Scheme: "test"}
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.ask(context.Background(), 1, round, 0, params, [][]byte{nil}, nil)
+		done <- err
+	}()
+	key := pirInterest(1, round, 0)
+	awaiting(s, key)
+	s.interestMtx.Lock()
+	id := s.answers[key].id
+	s.interestMtx.Unlock()
+
+	// answers naming another request are dropped,
+	if err := s.onResponse(bitswap_message_pb.Message_PIRResponse{Session: 1, Round: round, Answer: []byte("late"), Id: id + 1}); err == nil {
+		t.Fatal("answer to another request accepted")
+	}
+	// and those naming the one awaited delivered.
+	deliver(t, s, bitswap_message_pb.Message{PirResponses: []bitswap_message_pb.Message_PIRResponse{{
+		Session: 1, Round: round, Answer: []byte("answer"), Id: id,
+	}}})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
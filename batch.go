@@ -0,0 +1,231 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ipfs/go-cid"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PrivateGetBatch retrieves several blocks without revealing to the peer
+// which CIDs were requested. The result holds the block named by each of
+// cids, or nil where the peer does not hold it.
+//
+// If the peer lays its databases out for batches, the CIDs are retrieved in
+// batches of the peer's batch size. Each round of a batch sends one query per
+// bucket of the batched layout, which the peer answers for about
+// batch.NumHashes passes over its database rather than one per CID. CIDs
+// which do not fit in a round, because their buckets were all taken, are
+// retrieved in a further round; the peer learns that such rounds were
+// needed, but not for which CIDs. Peers without batched layouts are asked for
+// each CID with PrivateGet. CIDs which the peer's presence filter shows it
+// lacks, or which the session's cache holds, are not retrieved at all. Up to
+// the session's Pipeline batches, or CIDs, are retrieved at once on the one
+// stream. Peers supporting none of the session's schemes are asked in
+// plaintext, if the session allows it, as PrivateGet.
+func (s *Session) PrivateGetBatch(ctx context.Context, cids []cid.Cid) (_ [][]byte, err error) {
+	ctx, span := tracer.Start(ctx, "PrivateGetBatch", trace.WithAttributes(
+		attribute.String("peer", s.peer.String()),
+		attribute.Int("cids", len(cids)),
+	))
+	defer func() { endSpan(span, err) }()
+	if len(s.schemes) == 0 {
+		return nil, ErrNoScheme
+	}
+	pp, err := s.privateParams(ctx)
+	out := make([][]byte, len(cids))
+	if s.plaintext(err) {
+		span.SetAttributes(attribute.Bool("plaintext", true))
+		reason := err
+		for i, c := range cids {
+			if blk, ok, err := s.cached(ctx, c); ok || err != nil {
+				if err != nil {
+					return nil, err
+				}
+				out[i] = blk
+				continue
+			}
+			blk, err := s.getPlaintext(ctx, c, reason)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			out[i] = blk
+		}
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if pp.IndexBatch == nil || pp.BlocksBatch == nil {
+		err := s.pipeline(ctx, len(cids), func(ctx context.Context, i int) error {
+			blk, err := s.PrivateGet(ctx, cids[i])
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			out[i] = blk
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	// CIDs held in the cache, or which the peer's presence filter shows it
+	// lacks, are left out of the batches.
+	var todo []int
+	var wanted []cid.Cid
+	for i, c := range cids {
+		blk, ok, err := s.cached(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out[i] = blk
+		} else if !s.lacks(pp, c.Hash()) {
+			todo = append(todo, i)
+			wanted = append(wanted, c)
+		}
+	}
+	got := make([][]byte, len(wanted))
+	size := int(pp.BlocksBatch.BatchSize)
+	if size < 1 {
+		size = 1
+	}
+	batches := (len(wanted) + size - 1) / size
+	err = s.pipeline(ctx, batches, func(ctx context.Context, b int) error {
+		start, end := b*size, (b+1)*size
+		if end > len(wanted) {
+			end = len(wanted)
+		}
+		return s.privateGetBatch(ctx, pp, wanted[start:end], got[start:end])
+	})
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range todo {
+		out[i] = got[j]
+	}
+	return out, nil
+}
+
+// pipeline runs get for each of n retrievals, keeping up to the session's
+// Pipeline of them outstanding on its private stream at once. Their answers
+// may arrive in any order, and are told apart by request id. The first
+// retrieval to fail abandons the rest and fails the whole.
+func (s *Session) pipeline(ctx context.Context, n int, get func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	slots := make(chan struct{}, s.pipelineDepth)
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for i := 0; i < n; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := get(ctx, i); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// privateGetBatch retrieves one batch of cids into out. As with PrivateGet,
+// the block round runs even if none of the CIDs were found.
+func (s *Session) privateGetBatch(ctx context.Context, pp PeerParams, cids []cid.Cid, out [][]byte) error {
+	session := atomic.AddUint64(&s.pirSession, 1)
+	s.sendDecoys()
+
+	var slots []uint64
+	for _, c := range cids {
+		sl := keyword.Slots(c.Hash(), pp.Index.NumElements)
+		slots = append(slots, sl[:]...)
+	}
+	found, err := s.batchQuery(ctx, session, bitswap_message_pb.Message_BatchIndexRound, pp.Epoch, *pp.IndexBatch, slots)
+	if err != nil {
+		return err
+	}
+	positions := make(map[int]uint64, len(cids))
+	var wanted []uint64
+	for i, c := range cids {
+		key := []byte(c.Hash())
+		for _, slot := range keyword.Slots(key, pp.Index.NumElements) {
+			if pos, ok := keyword.Find(found[slot], key); ok && pos < pp.Blocks.NumElements {
+				positions[i] = pos
+				wanted = append(wanted, pos)
+				break
+			}
+		}
+	}
+
+	elements, err := s.batchQuery(ctx, session, bitswap_message_pb.Message_BatchBlockRound, pp.Epoch, *pp.BlocksBatch, wanted)
+	if err != nil {
+		return err
+	}
+	for i, pos := range positions {
+		blk, err := open(pp, cids[i], elements[pos])
+		if err != nil {
+			return err
+		}
+		if err := s.record(ctx, cids[i], blk); err != nil {
+			return err
+		}
+		out[i] = blk
+	}
+	return nil
+}
+
+// batchQuery retrieves the elements at indices from a batched database,
+// running rounds until all of them have been assigned to a bucket. At least
+// one round is always run.
+func (s *Session) batchQuery(ctx context.Context, session uint64, round bitswap_message_pb.Message_PIRRound, epoch uint64, params batch.Params, indices []uint64) (map[uint64][]byte, error) {
+	out := make(map[uint64][]byte, len(indices))
+	for first := true; first || len(indices) > 0; first = false {
+		queries, rest := batch.Plan(params, indices)
+		positions := make([]uint64, len(queries))
+		for b, q := range queries {
+			positions[b] = q.Position
+		}
+		elements, err := s.query(ctx, session, round, epoch, params.Bucket, positions...)
+		if err != nil {
+			return nil, err
+		}
+		for b, q := range queries {
+			if !q.Dummy {
+				out[q.Index] = elements[b]
+			}
+		}
+		indices = rest
+	}
+	return out, nil
+}
@@ -1,15 +1,30 @@
 package bitswap_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
+	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
 	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multihash"
 	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
 	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
 	"github.com/willscott/go-selfish-bitswap-client/server/util"
 )
@@ -102,30 +117,653 @@ func TestAskMultiple(t *testing.T) {
 	}
 }
 
-func TestBadAsksClose(t *testing.T) {
+func TestBadAsksDontHave(t *testing.T) {
 	serverHost, _ := libp2p.New()
 	clientHost, _ := libp2p.New()
 	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
 
 	store := util.NewMemStore(make(map[cid.Cid][]byte))
-	_ = util.Add(store, []byte("hello world"))
+	c := util.Add(store, []byte("hello world"))
 	otherStore := util.NewMemStore(make(map[cid.Cid][]byte))
 	badC := util.Add(otherStore, []byte("not a number"))
 	bitswapserver.AttachBitswapServer(serverHost, store)
 
 	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{})
 	_, err := session.Get(context.Background(), badC)
-	if err == nil {
-		t.Fatalf("should fail to get a cid not on server")
+	if err != bitswap.ErrNotFound {
+		t.Fatalf("expected not found for a cid not on server, got %v", err)
+	}
+
+	// the stream stays up for the rest of the wantlist.
+	blk, err := session.Get(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blk, []byte("hello world")) {
+		t.Fatal("unexpected block")
+	}
+}
+
+// gatedStore holds up reads of the block gated until gate is closed, and
+// records the blocks the server reads and sizes.
+type gatedStore struct {
+	bitswapserver.Blockstore
+	gated   cid.Cid
+	gate    chan struct{}
+	started chan struct{}
+	// sized receives the CIDs sized, as the server does on scheduling them.
+	sized chan cid.Cid
+
+	mtx     sync.Mutex
+	fetched []cid.Cid
+}
+
+func (s *gatedStore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	s.mtx.Lock()
+	s.fetched = append(s.fetched, c)
+	s.mtx.Unlock()
+	if c.Equals(s.gated) {
+		s.started <- struct{}{}
+		<-s.gate
+	}
+	return s.Blockstore.Get(ctx, c)
+}
+
+func (s *gatedStore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	s.sized <- c
+	blk, err := s.Blockstore.Get(ctx, c)
+	if err != nil {
+		return -1, err
+	}
+	return len(blk.RawData()), nil
+}
+
+func TestCancel(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	mem := util.NewMemStore(make(map[cid.Cid][]byte))
+	slow := util.Add(mem, []byte("held up"))
+	c := util.Add(mem, []byte("cancelled"))
+	after := util.Add(mem, []byte("wanted after"))
+	store := &gatedStore{Blockstore: mem, gated: slow, gate: make(chan struct{}), started: make(chan struct{}, 1), sized: make(chan cid.Cid, 3)}
+	// one worker, held up serving slow, leaves the want of c queued.
+	bitswapserver.AttachBitswapServer(serverHost, store, bitswapserver.WithWorkers(1))
+
+	metrics := &counters{}
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Metrics: metrics})
+	defer session.Close()
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := session.Get(context.Background(), slow)
+		slowDone <- err
+	}()
+	<-store.started
+
+	ctx, cncl := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cncl()
+	if _, err := session.Get(ctx, c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the get to time out, got %v", err)
+	}
+	// the want of after follows the cancel on the stream, so the cancel is
+	// read once after is scheduled.
+	afterDone := make(chan error, 1)
+	go func() {
+		blk, err := session.Get(context.Background(), after)
+		if err == nil && string(blk) != "wanted after" {
+			err = fmt.Errorf("unexpected block %q", blk)
+		}
+		afterDone <- err
+	}()
+	for sized := range store.sized {
+		if sized.Equals(after) {
+			break
+		}
+	}
+	close(store.gate)
+	if err := <-slowDone; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-afterDone; err != nil {
+		t.Fatal(err)
+	}
+
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	for _, f := range store.fetched {
+		if f.Equals(c) {
+			t.Fatal("server served a cancelled want")
+		}
 	}
+	if n := metrics.get("blocks_received"); n != 2 {
+		t.Fatalf("received %v blocks, want the 2 not cancelled", n)
+	}
+}
 
-	conns := clientHost.Network().ConnsToPeer(serverHost.ID())
-	for _, c := range conns {
-		strs := c.GetStreams()
-		for _, s := range strs {
-			if s.Protocol() == bitswap.ProtocolBitswap {
-				t.Fatal("after failure there shouldn't be an open bitswap conn")
+func TestPrivateRoundtrip(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(store, []byte("hello world"))
+	_ = util.Add(store, []byte("hello world 2"))
+	otherStore := util.NewMemStore(make(map[cid.Cid][]byte))
+	missing := util.Add(otherStore, []byte("not a number"))
+
+	scheme := fastpir.New()
+	db, err := bitswapserver.NewPIRStore(store, scheme, pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitswapserver.AttachBitswapServer(serverHost, store, bitswapserver.WithPIRStore(db))
+
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme})
+	blk, err := session.PrivateGet(context.Background(), c1)
+	if err != nil {
+		t.Fatalf("should get block, got %v", err)
+	}
+	if string(blk) != "hello world" {
+		t.Fatalf("private get didn't succeed")
+	}
+	if _, err := session.PrivateGet(context.Background(), missing); err != bitswap.ErrNotFound {
+		t.Fatalf("should not find a cid not on server, got %v", err)
+	}
+}
+
+func TestPrivateGetBatch(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	var cids []cid.Cid
+	for i := 0; i < 10; i++ {
+		cids = append(cids, util.Add(store, []byte(fmt.Sprintf("block %d", i))))
+	}
+	otherStore := util.NewMemStore(make(map[cid.Cid][]byte))
+	missing := util.Add(otherStore, []byte("not a number"))
+	cids = append(cids, missing)
+
+	scheme := fastpir.New()
+	opts := bitswapserver.WithPIRScheme(scheme, pirstore.Options{BatchSize: 4})
+	if err := bitswapserver.AttachBitswapServer(serverHost, store, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme})
+	blks, err := session.PrivateGetBatch(context.Background(), cids)
+	if err != nil {
+		t.Fatalf("should get blocks, got %v", err)
+	}
+	for i, blk := range blks[:10] {
+		if string(blk) != fmt.Sprintf("block %d", i) {
+			t.Fatalf("block %d retrieved as %q", i, blk)
+		}
+	}
+	if blks[10] != nil {
+		t.Fatal("should not find a cid not on server")
+	}
+}
+
+func TestPipelinedGetBatch(t *testing.T) {
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	var cids []cid.Cid
+	for i := 0; i < 10; i++ {
+		cids = append(cids, util.Add(store, []byte(fmt.Sprintf("block %d", i))))
+	}
+	otherStore := util.NewMemStore(make(map[cid.Cid][]byte))
+	cids = append(cids, util.Add(otherStore, []byte("not a number")))
+
+	// retrievals of single blocks, and of batches, answered out of order.
+	for _, batchSize := range []int{0, 2} {
+		serverHost, _ := libp2p.New()
+		clientHost, _ := libp2p.New()
+		clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+		scheme := fastpir.New()
+		opts := bitswapserver.WithPIRScheme(scheme, pirstore.Options{BatchSize: batchSize})
+		if err := bitswapserver.AttachBitswapServer(serverHost, store, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Pipeline: 4})
+		blks, err := session.PrivateGetBatch(context.Background(), cids)
+		if err != nil {
+			t.Fatalf("batch size %d: should get blocks, got %v", batchSize, err)
+		}
+		for i, blk := range blks[:10] {
+			if string(blk) != fmt.Sprintf("block %d", i) {
+				t.Fatalf("batch size %d: block %d retrieved as %q", batchSize, i, blk)
 			}
 		}
+		if blks[10] != nil {
+			t.Fatalf("batch size %d: should not find a cid not on server", batchSize)
+		}
+		_ = session.Close()
+	}
+}
+
+// counters is a MetricsSink counting what sessions and servers report.
+type counters struct {
+	mtx sync.Mutex
+	n   map[string]float64
+}
+
+func (c *counters) Add(name string, v float64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.n == nil {
+		c.n = make(map[string]float64)
+	}
+	c.n[name] += v
+}
+
+func (c *counters) Observe(string, float64) {}
+
+func (c *counters) get(name string) float64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.n[name]
+}
+
+func TestPresenceFilter(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(store, []byte("hello world"))
+	missing := util.Add(util.NewMemStore(make(map[cid.Cid][]byte)), []byte("not on the server"))
+	served := &counters{}
+	scheme := fastpir.New()
+	if err := bitswapserver.AttachBitswapServer(serverHost, store,
+		bitswapserver.WithPIRScheme(scheme, pirstore.Options{FilterRate: 0.0001}),
+		bitswapserver.WithMetrics(served)); err != nil {
+		t.Fatal(err)
+	}
+
+	// sessions skip the CIDs the filter shows the peer lacks,
+	metrics := &counters{}
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Metrics: metrics})
+	defer session.Close()
+	if blk, err := session.PrivateGet(context.Background(), c); err != nil || string(blk) != "hello world" {
+		t.Fatalf("should get block, got %q %v", blk, err)
+	}
+	queries := served.get("pir_queries")
+	if _, err := session.PrivateGet(context.Background(), missing); !errors.Is(err, bitswap.ErrNotFound) {
+		t.Fatalf("should not find a cid not on server, got %v", err)
+	}
+	if metrics.get("filter_skips") != 1 || served.get("pir_queries") != queries {
+		t.Fatalf("%v retrievals skipped, %v queries sent", metrics.get("filter_skips"), served.get("pir_queries")-queries)
+	}
+
+	// unless they do not trust filters.
+	untrusting := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, FilterMaxAge: -1})
+	defer untrusting.Close()
+	if _, err := untrusting.PrivateGet(context.Background(), missing); !errors.Is(err, bitswap.ErrNotFound) {
+		t.Fatalf("should not find a cid not on server, got %v", err)
+	}
+	if served.get("pir_queries") == queries {
+		t.Fatal("retrieval skipped by a session not trusting filters")
+	}
+}
+
+func TestCachedRetrieval(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(store, []byte("hello world"))
+	served := &counters{}
+	scheme := fastpir.New()
+	if err := bitswapserver.AttachBitswapServer(serverHost, store,
+		bitswapserver.WithPIRScheme(scheme, pirstore.Options{}),
+		bitswapserver.WithMetrics(served)); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := bitswap.NewBlockCache(0)
+	metrics := &counters{}
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Cache: cache, Metrics: metrics})
+	defer session.Close()
+	if blk, err := session.PrivateGet(context.Background(), c); err != nil || string(blk) != "hello world" {
+		t.Fatalf("should get block, got %q %v", blk, err)
+	}
+	queries := served.get("pir_queries")
+
+	// blocks retrieved before are returned from the cache, without a round,
+	for i := 0; i < 2; i++ {
+		if blk, err := session.PrivateGet(context.Background(), c); err != nil || string(blk) != "hello world" {
+			t.Fatalf("should get cached block, got %q %v", blk, err)
+		}
+	}
+	if blk, err := session.Get(context.Background(), c); err != nil || string(blk) != "hello world" {
+		t.Fatalf("should get cached block in plaintext, got %q %v", blk, err)
+	}
+	if metrics.get("block_cache_hits") != 3 || served.get("pir_queries") != queries {
+		t.Fatalf("%v cache hits, %v queries sent", metrics.get("block_cache_hits"), served.get("pir_queries")-queries)
+	}
+
+	// and to other sessions sharing the cache.
+	other := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Cache: cache})
+	defer other.Close()
+	if blks, err := other.PrivateGetBatch(context.Background(), []cid.Cid{c}); err != nil || string(blks[0]) != "hello world" {
+		t.Fatalf("should get cached block in a batch, got %q %v", blks, err)
+	}
+	if served.get("pir_queries") != queries {
+		t.Fatal("cached block retrieved again by another session")
+	}
+}
+
+func TestPrivateGroup(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	// a raw block is a DAG of its own.
+	root := util.Add(store, []byte("hello world"))
+	other := util.Add(store, []byte("elsewhere"))
+	scheme := fastpir.New()
+	if err := bitswapserver.AttachBitswapServer(serverHost, store,
+		bitswapserver.WithPIRScheme(scheme, pirstore.Options{}),
+		bitswapserver.WithPIRGroups(root)); err != nil {
+		t.Fatal(err)
+	}
+
+	params := bitswap.NewParamCache()
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Group: root, Params: params})
+	defer session.Close()
+	if blk, err := session.PrivateGet(context.Background(), root); err != nil || string(blk) != "hello world" {
+		t.Fatalf("should get block of the group, got %q %v", blk, err)
+	}
+	pp, ok := params.Get(serverHost.ID())
+	if !ok || pp.Group != root || len(pp.Groups) != 1 || pp.Groups[0] != root {
+		t.Fatalf("retrieved from group %v of %v", pp.Group, pp.Groups)
+	}
+	// the group's databases hold only its blocks.
+	if _, err := session.PrivateGet(context.Background(), other); !errors.Is(err, bitswap.ErrNotFound) {
+		t.Fatalf("should not find a block outside the group, got %v", err)
+	}
+
+	// sessions sharing the cache without the group negotiate again.
+	whole := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Params: params})
+	defer whole.Close()
+	if blk, err := whole.PrivateGet(context.Background(), other); err != nil || string(blk) != "elsewhere" {
+		t.Fatalf("should get block of the whole store, got %q %v", blk, err)
+	}
+}
+
+func TestPrivateGroupProof(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	ctx := context.Background()
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	leaf := util.Add(store, []byte("leaf"))
+	root := dagNode(t, store, dagNode(t, store, leaf))
+	// another group lays the leaf out with the proof of a DAG it is not
+	// part of.
+	forged := dagNode(t, store, util.Add(store, []byte("other leaf")))
+	blk, err := store.Get(ctx, forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheme := fastpir.New()
+	bad := pirstore.New(scheme, pirstore.Options{Group: forged})
+	if err := bad.Add(leaf, dag.AppendProof(nil, []byte("leaf"), [][]byte{blk.RawData()})); err != nil {
+		t.Fatal(err)
+	}
+	if err := bitswapserver.AttachBitswapServer(serverHost, store,
+		bitswapserver.WithPIRScheme(scheme, pirstore.Options{}),
+		bitswapserver.WithPIRGroups(root),
+		bitswapserver.WithPIRStore(bad)); err != nil {
+		t.Fatal(err)
+	}
+
+	// leaves are proven part of the group's DAG by the blocks linking them
+	// to its root,
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Group: root})
+	defer session.Close()
+	if data, err := session.PrivateGet(ctx, leaf); err != nil || string(data) != "leaf" {
+		t.Fatalf("should get leaf of the group, got %q %v", data, err)
+	}
+	// and refused if the proof does not lead to it.
+	other := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: scheme, Group: forged})
+	defer other.Close()
+	if _, err := other.PrivateGet(ctx, leaf); !errors.Is(err, bitswap.ErrBadBlock) {
+		t.Fatalf("leaf with a forged proof retrieved with %v", err)
+	}
+}
+
+// dagNode adds a dag-cbor block linking to children to store.
+func dagNode(t *testing.T, store bitswapserver.MutableBlockstore, children ...cid.Cid) cid.Cid {
+	n, err := qp.BuildList(basicnode.Prototype.Any, int64(len(children)), func(la datamodel.ListAssembler) {
+		for _, c := range children {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: c}))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(n, &buf); err != nil {
+		t.Fatal(err)
+	}
+	h, err := multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid(buf.Bytes(), cid.NewCidV1(cid.DagCBOR, h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), blk); err != nil {
+		t.Fatal(err)
+	}
+	return blk.Cid()
+}
+
+func TestSubtree(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte)).(bitswapserver.MutableBlockstore)
+	a, b := util.Add(store, []byte("a")), util.Add(store, []byte("b"))
+	root := dagNode(t, store, a, dagNode(t, store, b))
+	if err := bitswapserver.AttachBitswapServer(serverHost, store); err != nil {
+		t.Fatal(err)
+	}
+
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{})
+	defer session.Close()
+	st, err := session.GetSubtree(context.Background(), root, 2, 0)
+	if err != nil {
+		t.Fatalf("should get subtree, got %v", err)
+	}
+	if len(st.Blocks) != 4 || st.Truncated || st.Blocks[0].Cid() != root || string(st.Blocks[3].RawData()) != "b" {
+		t.Fatalf("got subtree of %d blocks, truncated %v", len(st.Blocks), st.Truncated)
+	}
+	// a limit of one level leaves out b.
+	if st, err = session.GetSubtree(context.Background(), root, 1, 0); err != nil || len(st.Blocks) != 3 {
+		t.Fatalf("should get 3 blocks down to depth 1, got %v %v", st, err)
+	}
+
+	missing := util.Add(util.NewMemStore(make(map[cid.Cid][]byte)), []byte("missing"))
+	if _, err := session.GetSubtree(context.Background(), missing, 1, 0); !errors.Is(err, bitswap.ErrNotFound) {
+		t.Fatalf("should not find subtree of a missing block, got %v", err)
+	}
+}
+
+func TestPrivateNegotiation(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(store, []byte("hello world"))
+	if err := bitswapserver.AttachBitswapServer(serverHost, store,
+		bitswapserver.WithPIRScheme(fastpir.New(), pirstore.Options{}),
+		bitswapserver.WithPIRScheme(spiral.New(), pirstore.Options{})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []bitswap.Options{
+		{Scheme: fastpir.New()},
+		{Scheme: spiral.New(), Schemes: []pir.Scheme{fastpir.New()}},
+	} {
+		session := bitswap.New(clientHost, serverHost.ID(), opts)
+		blk, err := session.PrivateGet(context.Background(), c)
+		if err != nil {
+			t.Fatalf("should get block with %s, got %v", opts.Scheme.ID(), err)
+		}
+		if string(blk) != "hello world" {
+			t.Fatalf("private get didn't succeed")
+		}
+	}
+
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: spiral.New(), MaxElementSize: 1})
+	if _, err := session.PrivateGet(context.Background(), c); !errors.Is(err, bitswap.ErrNoCommonScheme) {
+		t.Fatalf("should not agree on a database over the limit, got %v", err)
+	}
+}
+
+func TestPlaintextFallback(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(store, []byte("hello world"))
+	if err := bitswapserver.AttachBitswapServer(serverHost, store); err != nil {
+		t.Fatal(err)
+	}
+
+	session := bitswap.New(clientHost, serverHost.ID(), bitswap.Options{Scheme: fastpir.New()})
+	if _, err := session.PrivateGet(context.Background(), c); !errors.Is(err, bitswap.ErrNoCommonScheme) {
+		t.Fatalf("should not fall back without opting in, got %v", err)
+	}
+
+	var reported []cid.Cid
+	session = bitswap.New(clientHost, serverHost.ID(), bitswap.Options{
+		Scheme:                 fastpir.New(),
+		AllowPlaintextFallback: true,
+		OnPlaintextFallback: func(p peer.ID, c cid.Cid, reason error) {
+			reported = append(reported, c)
+		},
+	})
+	blk, err := session.PrivateGet(context.Background(), c)
+	if err != nil {
+		t.Fatalf("should get block in plaintext, got %v", err)
+	}
+	if string(blk) != "hello world" {
+		t.Fatalf("plaintext get didn't succeed")
+	}
+	if len(reported) != 1 || !reported[0].Equals(c) {
+		t.Fatalf("fallback should be reported, got %v", reported)
+	}
+}
+
+func TestPrivateResync(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(store, []byte("hello world"))
+	db, err := bitswapserver.NewPIRStore(store, fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bitswapserver.AttachBitswapServer(serverHost, store, bitswapserver.WithPIRStore(db)); err != nil {
+		t.Fatal(err)
+	}
+
+	client := bitswap.NewClient(clientHost, bitswap.Options{Scheme: fastpir.New()})
+	defer client.Close()
+	if _, err := client.PrivateGet(context.Background(), serverHost.ID(), c1); err != nil {
+		t.Fatal(err)
+	}
+	// the client's cached parameters are now stale, and are refreshed by
+	// the retry.
+	c2 := util.Add(store, []byte("hello again"))
+	all, err := bitswapserver.Enumerate(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sync(all); err != nil {
+		t.Fatal(err)
+	}
+	blk, err := client.PrivateGet(context.Background(), serverHost.ID(), c2)
+	if err != nil {
+		t.Fatalf("should resync and get block, got %v", err)
+	}
+	if string(blk) != "hello again" {
+		t.Fatalf("resynced get didn't succeed")
+	}
+}
+
+func TestClientPrivateGet(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(store, []byte("hello world"))
+	scheme := fastpir.New()
+	if err := bitswapserver.AttachBitswapServer(serverHost, store, bitswapserver.WithPIRScheme(scheme, pirstore.Options{})); err != nil {
+		t.Fatal(err)
+	}
+
+	client := bitswap.NewClient(clientHost, bitswap.Options{Scheme: scheme})
+	defer client.Close()
+	for i := 0; i < 2; i++ {
+		blk, err := client.PrivateGet(context.Background(), serverHost.ID(), c)
+		if err != nil {
+			t.Fatalf("should get block, got %v", err)
+		}
+		if string(blk) != "hello world" {
+			t.Fatalf("private get didn't succeed")
+		}
+	}
+}
+
+func TestMultiSessionPrivateGet(t *testing.T) {
+	emptyHost, _ := libp2p.New()
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(emptyHost.ID(), emptyHost.Addrs(), time.Hour)
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	scheme := fastpir.New()
+	empty := util.NewMemStore(make(map[cid.Cid][]byte))
+	_ = util.Add(empty, []byte("something else"))
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(store, []byte("hello world"))
+	if err := bitswapserver.AttachBitswapServer(emptyHost, empty, bitswapserver.WithPIRScheme(scheme, pirstore.Options{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := bitswapserver.AttachBitswapServer(serverHost, store, bitswapserver.WithPIRScheme(scheme, pirstore.Options{})); err != nil {
+		t.Fatal(err)
+	}
+
+	client := bitswap.NewClient(clientHost, bitswap.Options{Scheme: scheme})
+	defer client.Close()
+	session := client.NewMultiSession([]peer.ID{emptyHost.ID(), serverHost.ID()}, bitswap.MultiOptions{Private: true, FanOut: 1})
+	blk, err := session.Get(context.Background(), c)
+	if err != nil {
+		t.Fatalf("should get block, got %v", err)
+	}
+	if string(blk) != "hello world" {
+		t.Fatalf("multi session get didn't succeed")
+	}
+
+	session = client.NewMultiSession([]peer.ID{emptyHost.ID()}, bitswap.MultiOptions{Private: true})
+	if _, err := session.Get(context.Background(), c); err != bitswap.ErrNotFound {
+		t.Fatalf("should not find a cid no peer has, got %v", err)
 	}
 }
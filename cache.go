@@ -0,0 +1,123 @@
+package bitswap
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+)
+
+// DefaultBlockCacheSize is the number of bytes of blocks a BlockCache made
+// with a size of zero keeps.
+const DefaultBlockCacheSize = 64 << 20
+
+// BlockCache keeps recently retrieved blocks in memory, by CID, so that
+// retrieving them again, as repeated traversals of a DAG do, costs no PIR
+// rounds. Once the blocks kept exceed its size, the least recently used are
+// evicted. Only blocks verified against their CID are kept. It may be shared
+// between sessions and clients, and is safe for concurrent use.
+type BlockCache struct {
+	mtx     sync.Mutex
+	size    int
+	max     int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedBlock struct {
+	key  string
+	data []byte
+}
+
+// NewBlockCache creates a cache keeping up to max bytes of blocks, or
+// DefaultBlockCacheSize if max is zero.
+func NewBlockCache(max int) *BlockCache {
+	if max == 0 {
+		max = DefaultBlockCacheSize
+	}
+	return &BlockCache{
+		max:     max,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the block named by c, if it is kept. The data returned is
+// shared, and must not be modified.
+func (bc *BlockCache) Get(c cid.Cid) ([]byte, bool) {
+	bc.mtx.Lock()
+	defer bc.mtx.Unlock()
+	e, ok := bc.entries[string(c.Hash())]
+	if !ok {
+		return nil, false
+	}
+	bc.order.MoveToFront(e)
+	return e.Value.(*cachedBlock).data, true
+}
+
+// Put keeps blk, which is trusted to match its CID, making BlockCaches
+// BlockSinks.
+func (bc *BlockCache) Put(_ context.Context, blk blocks.Block) error {
+	bc.add(blk.Cid(), blk.RawData())
+	return nil
+}
+
+// Len returns the number of blocks kept.
+func (bc *BlockCache) Len() int {
+	bc.mtx.Lock()
+	defer bc.mtx.Unlock()
+	return bc.order.Len()
+}
+
+// Size returns the number of bytes of blocks kept.
+func (bc *BlockCache) Size() int {
+	bc.mtx.Lock()
+	defer bc.mtx.Unlock()
+	return bc.size
+}
+
+// add keeps data as the block c, evicting the least recently used blocks to
+// make room, and returns how many were evicted. Blocks larger than the whole
+// cache are not kept.
+func (bc *BlockCache) add(c cid.Cid, data []byte) int {
+	if len(data) > bc.max {
+		return 0
+	}
+	key := string(c.Hash())
+	bc.mtx.Lock()
+	defer bc.mtx.Unlock()
+	if e, ok := bc.entries[key]; ok {
+		bc.order.MoveToFront(e)
+		return 0
+	}
+	bc.entries[key] = bc.order.PushFront(&cachedBlock{key, data})
+	bc.size += len(data)
+	evicted := 0
+	for bc.size > bc.max {
+		cb := bc.order.Remove(bc.order.Back()).(*cachedBlock)
+		delete(bc.entries, cb.key)
+		bc.size -= len(cb.data)
+		evicted++
+	}
+	return evicted
+}
+
+// cached returns the block c from the session's cache, if it has one which
+// holds it. Blocks found are passed to the session's sink, as if retrieved.
+func (s *Session) cached(ctx context.Context, c cid.Cid) ([]byte, bool, error) {
+	if s.cache == nil {
+		return nil, false, nil
+	}
+	data, ok := s.cache.Get(c)
+	if !ok {
+		s.metrics.Add("block_cache_misses", 1)
+		return nil, false, nil
+	}
+	s.metrics.Add("block_cache_hits", 1)
+	if err := s.record(ctx, c, data); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
@@ -0,0 +1,59 @@
+package bitswap
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+func rawBlock(t *testing.T, data string) blocks.Block {
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid([]byte(data), cid.NewCidV1(cid.Raw, h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+func TestBlockCache(t *testing.T) {
+	bc := NewBlockCache(10)
+	a, b, c := rawBlock(t, "aaaa"), rawBlock(t, "bbbb"), rawBlock(t, "cccc")
+	for _, blk := range []blocks.Block{a, b} {
+		if err := bc.Put(context.Background(), blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, ok := bc.Get(a.Cid()); !ok || string(data) != "aaaa" {
+		t.Fatalf("got %q %v", data, ok)
+	}
+	// blocks are found by multihash, whatever the codec of the CID.
+	if _, ok := bc.Get(cid.NewCidV1(cid.DagProtobuf, a.Cid().Hash())); !ok {
+		t.Fatal("block not found under another codec")
+	}
+
+	// the least recently used block, b as a was read since, makes room.
+	if evicted := bc.add(c.Cid(), c.RawData()); evicted != 1 {
+		t.Fatalf("%d blocks evicted", evicted)
+	}
+	if _, ok := bc.Get(b.Cid()); ok {
+		t.Fatal("least recently used block kept")
+	}
+	if bc.Len() != 2 || bc.Size() != 8 {
+		t.Fatalf("cache holds %d blocks of %d bytes", bc.Len(), bc.Size())
+	}
+
+	// blocks larger than the cache are not kept.
+	big := rawBlock(t, "larger than the cache")
+	if evicted := bc.add(big.Cid(), big.RawData()); evicted != 0 || bc.Len() != 2 {
+		t.Fatalf("%d blocks evicted for a block larger than the cache", evicted)
+	}
+	if _, ok := bc.Get(big.Cid()); ok {
+		t.Fatal("block larger than the cache kept")
+	}
+}
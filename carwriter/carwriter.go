@@ -0,0 +1,110 @@
+// Package carwriter records retrieved blocks in CAR files, so a retrieval can
+// be verified offline and imported into other IPFS tooling.
+//
+// A Writer is a bitswap.BlockSink and a fetcher.Sink: set as the Sink of a
+// client's Options it records every block the client retrieves, and passed
+// to a fetcher it records a whole DAG.
+package carwriter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/ipld/go-car/v2/storage"
+)
+
+var ErrBadBlock = errors.New("block does not match its cid")
+
+// Writer writes blocks to a CAR as they arrive. Blocks are written once, no
+// matter how often they are put. It is safe for concurrent use.
+type Writer struct {
+	car storage.WritableCar
+	// file is closed with the writer, if the writer opened it.
+	file *os.File
+}
+
+// NewV1 streams a CARv1 with roots to w. CARv1 files have no index, but need
+// no seeking either, so w may be a pipe or a network stream.
+func NewV1(w io.Writer, roots []cid.Cid) (*Writer, error) {
+	car, err := storage.NewWritable(w, roots, carv2.WriteAsCarV1(true))
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{car: car}, nil
+}
+
+// NewV2 writes a CARv2 with roots to rw. The index is written, and the
+// header completed, when the writer is closed.
+func NewV2(rw storage.ReaderAtWriterAt, roots []cid.Cid) (*Writer, error) {
+	car, err := storage.NewReadableWritable(rw, roots)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{car: car}, nil
+}
+
+// Create creates, or truncates, the CARv2 file at path and writes to it.
+func Create(path string, roots []cid.Cid) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewV2(f, roots)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+// Put writes blk to the CAR.
+func (w *Writer) Put(ctx context.Context, blk blocks.Block) error {
+	return w.car.Put(ctx, blk.Cid().KeyString(), blk.RawData())
+}
+
+// Close completes the CAR, writing its index if it has one. Blocks put
+// after Close are rejected.
+func (w *Writer) Close() error {
+	err := w.car.Finalize()
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Verify reads a CARv1 or CARv2 from r, checking every block against its
+// CID, and returns the number of blocks read.
+func Verify(r io.Reader) (int, error) {
+	// blocks are checked here, to fail with ErrBadBlock.
+	br, err := carv2.NewBlockReader(r, carv2.WithTrustedCAR(true))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		blk, err := br.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		c, err := blk.Cid().Prefix().Sum(blk.RawData())
+		if err != nil {
+			return n, err
+		}
+		if !c.Equals(blk.Cid()) {
+			return n, fmt.Errorf("%s: %w", blk.Cid(), ErrBadBlock)
+		}
+		n++
+	}
+}
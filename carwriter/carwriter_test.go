@@ -0,0 +1,100 @@
+package carwriter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/carwriter"
+)
+
+func testBlocks(t *testing.T) []blocks.Block {
+	var out []blocks.Block
+	for _, data := range []string{"hello world", "hello world 2", "hello world 3"} {
+		h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk, err := blocks.NewBlockWithCid([]byte(data), cid.NewCidV1(cid.Raw, h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, blk)
+	}
+	return out
+}
+
+func TestV1(t *testing.T) {
+	blks := testBlocks(t)
+	var buf bytes.Buffer
+	w, err := carwriter.NewV1(&buf, []cid.Cid{blks[0].Cid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, blk := range append(blks, blks[0]) {
+		if err := w.Put(context.Background(), blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := carwriter.Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(blks) {
+		t.Fatalf("read %d blocks, expected %d", n, len(blks))
+	}
+
+	// flip a byte of the last block's data.
+	corrupt := buf.Bytes()
+	corrupt[len(corrupt)-1] ^= 1
+	if _, err := carwriter.Verify(bytes.NewReader(corrupt)); !errors.Is(err, carwriter.ErrBadBlock) {
+		t.Fatalf("expected corrupt block to be detected, got %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	blks := testBlocks(t)
+	path := filepath.Join(t.TempDir(), "out.car")
+	w, err := carwriter.Create(path, []cid.Cid{blks[0].Cid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, blk := range blks {
+		if err := w.Put(context.Background(), blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := carv2.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Version != 2 {
+		t.Fatalf("wrote a CARv%d", r.Version)
+	}
+	if ir, err := r.IndexReader(); err != nil || ir == nil {
+		t.Fatalf("expected an index, got %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if n, err := carwriter.Verify(f); err != nil || n != len(blks) {
+		t.Fatalf("read %d blocks: %v", n, err)
+	}
+}
@@ -0,0 +1,164 @@
+package bitswap
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// chunkSize is the size of the chunks blocks are split into below, less
+// than the blocks tested, as servers split blocks larger than their
+// message limit.
+const chunkSize = 1024 * 1024
+
+// chunks splits data, the block c, as servers do.
+func chunks(c cid.Cid, data []byte) []bitswap_message_pb.Message_BlockChunk {
+	var chs []bitswap_message_pb.Message_BlockChunk
+	for off := 0; off < len(data); off += chunkSize {
+		end := off + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chs = append(chs, bitswap_message_pb.Message_BlockChunk{
+			Cid:    bitswap_message_pb.Cid{Cid: c},
+			Offset: uint64(off),
+			Total:  uint64(len(data)),
+			Data:   data[off:end],
+		})
+	}
+	return chs
+}
+
+func largeBlock(t *testing.T) (cid.Cid, []byte) {
+	data := make([]byte, 3*chunkSize+chunkSize/2)
+	rand.New(rand.NewSource(1)).Read(data)
+	h, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h), data
+}
+
+// awaitBlock registers interest in c, returning the channel its block is
+// sent on.
+func awaitBlock(s *Session, c cid.Cid) chan []byte {
+	got := make(chan []byte, 1)
+	s.on(c, func(data []byte, err error) {
+		got <- data
+	})
+	return got
+}
+
+func TestChunkedBlock(t *testing.T) {
+	s := New(nil, "p", Options{})
+	c, data := largeBlock(t)
+	got := awaitBlock(s, c)
+	chs := chunks(c, data)
+
+	// chunks are placed by their offset, whatever order they arrive in.
+	for i := len(chs) - 1; i >= 0; i-- {
+		deliver(t, s, bitswap_message_pb.Message{Chunks: chs[i : i+1]})
+	}
+	select {
+	case blk := <-got:
+		if !bytes.Equal(blk, data) {
+			t.Fatal("reassembled block differs from the one sent")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block not resolved once all its chunks arrived")
+	}
+	if len(s.partials) != 0 {
+		t.Fatalf("%d partial blocks kept after reassembly", len(s.partials))
+	}
+
+	// a block which does not match its cid is refused as invalid.
+	got = awaitBlock(s, c)
+	corrupt := chunks(c, append([]byte(nil), data...))
+	corrupt[1].Data[0] ^= 1
+	deliver(t, s, bitswap_message_pb.Message{Chunks: corrupt})
+	select {
+	case <-got:
+		t.Fatal("corrupt block resolved")
+	default:
+	}
+	if s.invalid != 1 {
+		t.Fatalf("%d invalid blocks recorded, want 1", s.invalid)
+	}
+}
+
+func TestTruncatedChunks(t *testing.T) {
+	s := New(nil, "p", Options{})
+	c, data := largeBlock(t)
+	got := awaitBlock(s, c)
+	chs := chunks(c, data)
+
+	// without its last chunk, the block is kept waiting.
+	deliver(t, s, bitswap_message_pb.Message{Chunks: chs[:len(chs)-1]})
+	select {
+	case <-got:
+		t.Fatal("block resolved without its last chunk")
+	default:
+	}
+	// chunks sent again are not counted twice.
+	deliver(t, s, bitswap_message_pb.Message{Chunks: chs[:1]})
+	select {
+	case <-got:
+		t.Fatal("block resolved from a chunk sent twice")
+	default:
+	}
+	deliver(t, s, bitswap_message_pb.Message{Chunks: chs[len(chs)-1:]})
+	select {
+	case blk := <-got:
+		if !bytes.Equal(blk, data) {
+			t.Fatal("reassembled block differs from the one sent")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block not resolved once all its chunks arrived")
+	}
+	if s.invalid != 0 {
+		t.Fatalf("%d invalid blocks recorded", s.invalid)
+	}
+}
+
+func TestOverlongChunks(t *testing.T) {
+	c, data := largeBlock(t)
+	for name, ch := range map[string]func([]bitswap_message_pb.Message_BlockChunk) bitswap_message_pb.Message_BlockChunk{
+		"past the block's end": func(chs []bitswap_message_pb.Message_BlockChunk) bitswap_message_pb.Message_BlockChunk {
+			last := chs[len(chs)-1]
+			last.Offset++
+			return last
+		},
+		"of another total": func(chs []bitswap_message_pb.Message_BlockChunk) bitswap_message_pb.Message_BlockChunk {
+			last := chs[len(chs)-1]
+			last.Total++
+			return last
+		},
+		"larger than blocks may be": func(chs []bitswap_message_pb.Message_BlockChunk) bitswap_message_pb.Message_BlockChunk {
+			last := chs[len(chs)-1]
+			last.Total = MaxBlockSize + 1
+			return last
+		},
+	} {
+		s := New(nil, "p", Options{})
+		got := awaitBlock(s, c)
+		chs := chunks(c, data)
+		deliver(t, s, bitswap_message_pb.Message{Chunks: chs[:len(chs)-1]})
+		buf, err := (&bitswap_message_pb.Message{Chunks: []bitswap_message_pb.Message_BlockChunk{ch(chs)}}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.handle(buf); err == nil {
+			t.Fatalf("chunk %s accepted", name)
+		}
+		select {
+		case <-got:
+			t.Fatalf("block resolved with a chunk %s", name)
+		default:
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+)
+
+// Client retrieves blocks privately from arbitrary peers, keeping a session
+// open to each peer it has fetched from.
+type Client struct {
+	host host.Host
+	opts Options
+
+	mtx      sync.Mutex
+	sessions map[peer.ID]*Session
+	// penalized holds peers which sent corrupt data, until when they are
+	// refused.
+	penalized map[peer.ID]time.Time
+	wants     *wantlist
+}
+
+// CorruptPeerTimeout is how long a Client refuses to fetch from a peer whose
+// session failed with ErrCorruptPeer.
+const CorruptPeerTimeout = 10 * time.Minute
+
+// NewClient creates a client fetching through h. All sessions it opens share
+// opts, including a single parameter cache.
+func NewClient(h host.Host, opts Options) *Client {
+	if opts.Params == nil {
+		opts.Params = NewParamCache()
+	}
+	if opts.Retry == (RetryPolicy{}) {
+		opts.Retry = DefaultRetryPolicy
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = nopSink{}
+	}
+	if opts.Scores == nil {
+		opts.Scores = NewScores(DefaultScoreParams)
+	}
+	if opts.AddressBook != nil {
+		var ps peerstore.Peerstore
+		if h != nil {
+			ps = h.Peerstore()
+		}
+		if err := opts.AddressBook.restore(context.Background(), ps, opts.Scores); err != nil {
+			logger.Warnw("failed to restore address book", "err", err)
+		}
+	}
+	return &Client{
+		host:      h,
+		opts:      opts,
+		sessions:  make(map[peer.ID]*Session),
+		penalized: make(map[peer.ID]time.Time),
+		wants:     newWantlist(opts.Metrics),
+	}
+}
+
+// session returns an open session to p, replacing one whose stream failed.
+// Wants waiting to be retried after the failure are resent on the new
+// session at once. Peers which sent corrupt data are refused for
+// CorruptPeerTimeout.
+func (cl *Client) session(p peer.ID) (*Session, error) {
+	cl.mtx.Lock()
+	defer cl.mtx.Unlock()
+	if until, ok := cl.penalized[p]; ok {
+		if time.Now().Before(until) {
+			return nil, ErrCorruptPeer
+		}
+		delete(cl.penalized, p)
+	}
+	s, ok := cl.sessions[p]
+	if ok && s.failure() == nil {
+		return s, nil
+	}
+	if ok {
+		s.Close()
+		delete(cl.sessions, p)
+		if errors.Is(s.failure(), ErrCorruptPeer) {
+			cl.penalized[p] = time.Now().Add(CorruptPeerTimeout)
+			return nil, ErrCorruptPeer
+		}
+	}
+	s = New(cl.host, p, cl.opts)
+	cl.sessions[p] = s
+	if ok {
+		cl.wants.reconnect(p)
+	}
+	return s, nil
+}
+
+// Scores returns the ratings of the peers the client fetched from.
+func (cl *Client) Scores() *Scores {
+	return cl.opts.Scores
+}
+
+// Host returns the host the client fetches through.
+func (cl *Client) Host() host.Host {
+	return cl.host
+}
+
+// Get fetches the block named by c from p, retrying failures as the client's
+// RetryPolicy allows. Concurrent Gets of the same block from p share one
+// fetch, and its result, which must not be modified.
+func (cl *Client) Get(ctx context.Context, p peer.ID, c cid.Cid) ([]byte, error) {
+	return cl.wants.get(ctx, p, c, false, func(ctx context.Context) ([]byte, error) {
+		return cl.retry(ctx, p, func(ctx context.Context, s *Session) ([]byte, error) {
+			return s.Get(ctx, c)
+		})
+	})
+}
+
+// PrivateGet fetches the block named by c from p without revealing c to p:
+// the peer's PIR parameters are negotiated on first contact, the CID and the
+// block position are only ever sent encrypted, and the decrypted block is
+// checked against the multihash of c before being returned. Failures are
+// retried as the client's RetryPolicy allows. Concurrent PrivateGets of the
+// same block from p share one retrieval, as Get.
+func (cl *Client) PrivateGet(ctx context.Context, p peer.ID, c cid.Cid) ([]byte, error) {
+	return cl.wants.get(ctx, p, c, true, func(ctx context.Context) ([]byte, error) {
+		return cl.retry(ctx, p, func(ctx context.Context, s *Session) ([]byte, error) {
+			return s.PrivateGet(ctx, c)
+		})
+	})
+}
+
+// Wantlist returns the CIDs of the blocks the client's callers are waiting
+// for from p, by Get or PrivateGet.
+func (cl *Client) Wantlist(p peer.ID) []cid.Cid {
+	return cl.wants.peerWants(p)
+}
+
+// PrivateGetBatch privately fetches the blocks named by cids from p, as
+// Session.PrivateGetBatch, retrying according to the client's RetryPolicy.
+func (cl *Client) PrivateGetBatch(ctx context.Context, p peer.ID, cids []cid.Cid) ([][]byte, error) {
+	var out [][]byte
+	_, err := cl.retry(ctx, p, func(ctx context.Context, s *Session) ([]byte, error) {
+		var err error
+		out, err = s.PrivateGetBatch(ctx, cids)
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Close ends all sessions of the client, saving the scores of their peers
+// to the address book, if set.
+func (cl *Client) Close() error {
+	cl.mtx.Lock()
+	defer cl.mtx.Unlock()
+	for p, s := range cl.sessions {
+		s.Close()
+		delete(cl.sessions, p)
+		if cl.opts.AddressBook != nil {
+			if err := cl.opts.AddressBook.saveScore(context.Background(), p, cl.opts.Scores); err != nil {
+				logger.Debugw("failed to save peer score", "peer", p, "err", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Command pirbench measures each PIR scheme over a range of database sizes,
+// writing a CSV or JSON report for comparing them.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/registry"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "pirbench",
+		Usage: "Measure PIR schemes over varying database sizes",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "scheme",
+				Usage: "schemes to measure, of " + strings.Join(registry.Names(), ", "),
+				Value: cli.NewStringSlice("fastpir", "spiral"),
+			},
+			&cli.IntSliceFlag{
+				Name:  "elements",
+				Usage: "numbers of elements in the databases",
+				Value: cli.NewIntSlice(pirtest.DatabaseSizes...),
+			},
+			&cli.IntSliceFlag{
+				Name:  "size",
+				Usage: "element sizes in bytes",
+				Value: cli.NewIntSlice(pirtest.ElementSizes...),
+			},
+			&cli.IntFlag{
+				Name:  "max-bytes",
+				Usage: "skip databases larger than this",
+				Value: pirtest.MaxDatabaseBytes,
+			},
+			&cli.IntFlag{
+				Name:  "answer-workers",
+				Usage: "goroutines each answer is split across",
+				Value: 1,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "csv or json",
+				Value: "csv",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "file to write the report to, instead of stdout",
+			},
+		},
+		Action: Bench,
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Bench(c *cli.Context) error {
+	write := writeCSV
+	switch c.String("format") {
+	case "csv":
+	case "json":
+		write = writeJSON
+	default:
+		return fmt.Errorf("unknown format %q", c.String("format"))
+	}
+	var measure []pir.Scheme
+	for _, name := range c.StringSlice("scheme") {
+		scheme, err := registry.New(name)
+		if err != nil {
+			return err
+		}
+		if p, ok := scheme.(pir.Parallel); ok {
+			p.SetWorkers(c.Int("answer-workers"))
+		}
+		measure = append(measure, scheme)
+	}
+	pirtest.DatabaseSizes = c.IntSlice("elements")
+	pirtest.ElementSizes = c.IntSlice("size")
+	pirtest.MaxDatabaseBytes = c.Int("max-bytes")
+
+	var report []pirtest.Measurement
+	for _, scheme := range measure {
+		log.Printf("measuring %s", scheme.ID())
+		ms, err := pirtest.MeasureAll(scheme)
+		if err != nil {
+			return fmt.Errorf("%s: %w", scheme.ID(), err)
+		}
+		report = append(report, ms...)
+	}
+
+	out := io.Writer(os.Stdout)
+	if path := c.String("output"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return write(out, report)
+}
+
+func writeCSV(w io.Writer, report []pirtest.Measurement) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"scheme", "elements", "element_size", "query_ns", "answer_ns", "decode_ns", "query_bytes", "answer_bytes", "hint_bytes"})
+	for _, m := range report {
+		_ = cw.Write([]string{
+			m.Scheme,
+			strconv.Itoa(m.Elements),
+			strconv.Itoa(m.ElementSize),
+			strconv.FormatInt(m.Query.Nanoseconds(), 10),
+			strconv.FormatInt(m.Answer.Nanoseconds(), 10),
+			strconv.FormatInt(m.Decode.Nanoseconds(), 10),
+			strconv.Itoa(m.QueryBytes),
+			strconv.Itoa(m.AnswerBytes),
+			strconv.Itoa(m.HintBytes),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSON(w io.Writer, report []pirtest.Measurement) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
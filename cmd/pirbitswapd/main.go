@@ -0,0 +1,606 @@
+// Command pirbitswapd serves the blocks of a CAR file or a datastore over
+// bitswap, answering private retrievals as well as plaintext ones.
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	"github.com/libp2p/go-libp2p/p2p/host/resource-manager/obs"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/cli/v2"
+	"github.com/willscott/go-selfish-bitswap-client/announce"
+	"github.com/willscott/go-selfish-bitswap-client/announce/ipni"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+	"github.com/willscott/go-selfish-bitswap-client/metrics"
+	"github.com/willscott/go-selfish-bitswap-client/padding"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/registry"
+	"github.com/willscott/go-selfish-bitswap-client/pir/shard"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+	"github.com/willscott/go-selfish-bitswap-client/server/util/carstore"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "pirbitswapd",
+		Usage: "Private bitswap server",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "car",
+				Usage: "serve the blocks of this CAR file",
+			},
+			&cli.StringFlag{
+				Name:  "flatfs",
+				Usage: "serve the blocks of the flatfs repository at this path, such as the blocks directory of a Kubo repository",
+			},
+			&cli.StringFlag{
+				Name:  "badger",
+				Usage: "serve the blocks of the badger repository at this path, such as the badgerds directory of a Kubo repository",
+			},
+			&cli.StringFlag{
+				Name:  "identity",
+				Usage: "file holding the host's private key, created if missing",
+				Value: "identity.key",
+			},
+			&cli.StringSliceFlag{
+				Name:  "listen",
+				Usage: "multiaddrs to listen on; browsers reach the webtransport one",
+				Value: cli.NewStringSlice("/ip4/0.0.0.0/tcp/4001", "/ip4/0.0.0.0/udp/4001/quic-v1", "/ip4/0.0.0.0/udp/4001/quic-v1/webtransport"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "scheme",
+				Usage: "PIR schemes to answer with, in order of preference, of " + strings.Join(registry.Names(), ", "),
+				Value: cli.NewStringSlice("fastpir"),
+			},
+			&cli.IntFlag{
+				Name:  "element-size",
+				Usage: "PIR database element size in bytes; zero fits the largest block",
+			},
+			&cli.IntFlag{
+				Name:  "shards",
+				Usage: "split each PIR database into this many shards, answered in parallel",
+			},
+			&cli.IntFlag{
+				Name:  "batch-size",
+				Usage: "also lay the PIR databases out for batches of this many blocks",
+			},
+			&cli.IntSliceFlag{
+				Name:  "padding",
+				Usage: "pad messages and PIR elements up to these sizes in bytes, so their length does not reveal the block",
+			},
+			&cli.DurationFlag{
+				Name:  "answer-period",
+				Usage: "release PIR answers only at whole periods of this long after their request, hiding how long they took",
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "plaintext block requests served at once",
+				Value: bitswapserver.DefaultWorkers,
+			},
+			&cli.IntFlag{
+				Name:  "pir-workers",
+				Usage: "PIR answers computed at once; zero is one per CPU",
+			},
+			&cli.StringSliceFlag{
+				Name:  "pir-weight",
+				Usage: "PEER=WEIGHT: share of the PIR workers PEER is given when others also wait, against 1 for peers not listed",
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "JSON file of limits, budgets, answer-workers and allow-peer to reload on SIGHUP or when it changes, overriding their flags",
+			},
+			&cli.DurationFlag{
+				Name:  "config-poll",
+				Usage: "how often to check --config for changes; zero reloads it on SIGHUP only",
+				Value: 5 * time.Second,
+			},
+			&cli.StringSliceFlag{
+				Name:  "allow-peer",
+				Usage: "serve private retrievals only to these peers; all peers if unset",
+			},
+			&cli.DurationFlag{
+				Name:  "pir-budget",
+				Usage: "estimated compute time of the PIR requests admitted but not yet answered, past which requests are refused with a time to retry; zero is unlimited",
+			},
+			&cli.DurationFlag{
+				Name:  "pir-max-defer",
+				Usage: "how long PIR requests over --pir-budget wait for room before they are refused",
+			},
+			&cli.Uint64Flag{
+				Name:  "quota-bytes",
+				Usage: "bytes of PIR answers each peer may be sent per --quota-period; zero is unlimited",
+			},
+			&cli.DurationFlag{
+				Name:  "quota-compute",
+				Usage: "compute time of PIR answers each peer may use per --quota-period; zero is unlimited",
+			},
+			&cli.DurationFlag{
+				Name:  "quota-period",
+				Usage: "how often the quotas of peers renew",
+				Value: time.Hour,
+			},
+			&cli.IntFlag{
+				Name:  "answer-workers",
+				Usage: "goroutines each PIR answer is split across, so one query can use several cores",
+				Value: 1,
+			},
+			&cli.IntFlag{
+				Name:  "max-streams",
+				Usage: "streams each peer may have open; zero is unlimited",
+			},
+			&cli.Float64Flag{
+				Name:  "pir-rate",
+				Usage: "PIR requests per second each peer may make; zero is unlimited",
+			},
+			&cli.IntFlag{
+				Name:  "pir-burst",
+				Usage: "PIR requests each peer may make at once",
+			},
+			&cli.IntFlag{
+				Name:  "bandwidth",
+				Usage: "bytes per second written to each peer; zero is unlimited",
+			},
+			&cli.Int64Flag{
+				Name:  "pir-memory",
+				Usage: "bytes of PIR answers queued for peers at once; zero scales with the host's memory",
+			},
+			&cli.StringFlag{
+				Name:  "snapshot-dir",
+				Usage: "directory the encoded PIR databases are saved to and loaded from on restart",
+			},
+			&cli.StringFlag{
+				Name:  "snapshot-key",
+				Usage: "file holding the key sealing the PIR snapshots, generated if missing; unsealed if unset",
+			},
+			&cli.BoolFlag{
+				Name:  "receipts",
+				Usage: "sign a receipt for each PIR answer whose client asks for one",
+			},
+			&cli.BoolFlag{
+				Name:  "background-encoding",
+				Usage: "encode changes to the PIR databases in the background, answering from the last databases until they are done",
+			},
+			&cli.BoolFlag{
+				Name:  "mmap",
+				Usage: "answer from the unsealed PIR snapshots memory mapped, rather than read into memory",
+			},
+			&cli.StringSliceFlag{
+				Name:  "pir-group",
+				Usage: "also lay the DAG of this root CID out apart, for clients which know it to query its smaller PIR databases",
+			},
+			&cli.Float64Flag{
+				Name:  "presence-filter",
+				Usage: "send clients a filter of the CIDs held, falsely reporting others present at this rate, so they skip the server for blocks it lacks",
+			},
+			&cli.StringSliceFlag{
+				Name:  "indexer",
+				Usage: "base URLs of network indexers to advertise the blocks to",
+			},
+			&cli.StringFlag{
+				Name:  "ipni-http",
+				Usage: "HTTP multiaddr indexers fetch advertisements from, such as /ip4/192.0.2.1/tcp/3104/http, listened on at its port",
+			},
+			&cli.StringSliceFlag{
+				Name:  "log-level",
+				Usage: "SUBSYSTEM=LEVEL, such as bitswap-pir=debug, or a LEVEL for all subsystems",
+			},
+			&cli.IntFlag{
+				Name:  "log-sample",
+				Usage: "log the first N entries of each message per second, then one in N; zero logs all",
+			},
+			&cli.StringFlag{
+				Name:  "metrics",
+				Usage: "address to serve Prometheus metrics on at /metrics, such as :9090",
+			},
+			&cli.StringFlag{
+				Name:  "debug",
+				Usage: "address to serve pprof, expvar stats and /healthz on, such as localhost:6060",
+			},
+		},
+		Action: Serve,
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Serve(c *cli.Context) error {
+	if err := configureLogging(c); err != nil {
+		return err
+	}
+	bs, err := openStore(c)
+	if err != nil {
+		return err
+	}
+	defer bs.Close()
+
+	key, err := loadIdentity(c.String("identity"))
+	if err != nil {
+		return err
+	}
+	var reg *prometheus.Registry
+	if c.String("metrics") != "" {
+		reg = prometheus.NewRegistry()
+	}
+	mgr, err := resourceManager(c, reg)
+	if err != nil {
+		return err
+	}
+	host, err := libp2p.New(libp2p.Identity(key), libp2p.ListenAddrStrings(c.StringSlice("listen")...), libp2p.ResourceManager(mgr))
+	if err != nil {
+		return err
+	}
+	defer host.Close()
+
+	re := &reloader{c: c, mon: bitswapserver.NewMonitor(), modified: modified(c)}
+	conf, err := loadSettings(c)
+	if err != nil {
+		return err
+	}
+	opts := []bitswapserver.Option{
+		bitswapserver.WithWorkers(c.Int("workers")),
+		bitswapserver.WithAnswerPeriod(c.Duration("answer-period")),
+		bitswapserver.WithAdmission(conf.admission()),
+		bitswapserver.WithLimits(conf.limits()),
+		bitswapserver.WithMonitor(re.mon),
+	}
+	if c.IsSet("pir-weight") {
+		weights, err := parseWeights(c.StringSlice("pir-weight"))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, bitswapserver.WithPIRWeights(func(p peer.ID) float64 {
+			if w, ok := weights[p]; ok {
+				return w
+			}
+			return 1
+		}))
+	}
+	if conf.AllowPeer != nil {
+		peers, err := conf.allowed()
+		if err != nil {
+			return err
+		}
+		re.allow = bitswapserver.NewAllowlist(peers...)
+		opts = append(opts, bitswapserver.WithAuthorizer(re.allow))
+	}
+	if c.Bool("receipts") {
+		opts = append(opts, bitswapserver.WithReceipts())
+	}
+	if c.IsSet("quota-bytes") || c.IsSet("quota-compute") {
+		opts = append(opts, bitswapserver.WithSettlement(bitswapserver.NewQuotaSettlement(bitswapserver.Quota{
+			Bytes:   c.Uint64("quota-bytes"),
+			Compute: c.Duration("quota-compute"),
+			Period:  c.Duration("quota-period"),
+		})))
+	}
+	if n := c.Int("pir-workers"); n > 0 {
+		opts = append(opts, bitswapserver.WithPIRWorkers(n))
+	}
+	sopts := pirstore.Options{ElementSize: c.Int("element-size"), BatchSize: c.Int("batch-size"), Mapped: c.Bool("mmap"), FilterRate: c.Float64("presence-filter"), Background: c.Bool("background-encoding")}
+	if sopts.Mapped && (c.String("snapshot-dir") == "" || c.String("snapshot-key") != "") {
+		return fmt.Errorf("--mmap needs a --snapshot-dir, unsealed")
+	}
+	if c.IsSet("padding") {
+		policy, err := padding.NewPolicy(c.IntSlice("padding")...)
+		if err != nil {
+			return err
+		}
+		sopts.Padding = policy
+		opts = append(opts, bitswapserver.WithPadding(policy))
+	}
+	var schemes []string
+	for _, name := range c.StringSlice("scheme") {
+		scheme, err := registry.New(name)
+		if err != nil {
+			return err
+		}
+		if p, ok := scheme.(pir.Parallel); ok {
+			p.SetWorkers(conf.AnswerWorkers)
+			re.parallel = append(re.parallel, p)
+		}
+		if n := c.Int("shards"); n > 1 {
+			scheme = shard.New(scheme, shard.Options{Shards: n})
+		}
+		opts = append(opts, bitswapserver.WithPIRScheme(scheme, sopts))
+		schemes = append(schemes, scheme.ID())
+	}
+	for _, root := range c.StringSlice("pir-group") {
+		g, err := cid.Decode(root)
+		if err != nil {
+			return fmt.Errorf("--pir-group %s: %w", root, err)
+		}
+		opts = append(opts, bitswapserver.WithPIRGroups(g))
+	}
+	if dir := c.String("snapshot-dir"); dir != "" {
+		var skey []byte
+		if path := c.String("snapshot-key"); path != "" {
+			if skey, err = loadSnapshotKey(path); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		opts = append(opts, bitswapserver.WithSnapshots(dir, skey))
+	}
+	if addr := c.String("metrics"); addr != "" {
+		sink, err := metrics.NewServer(reg)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, bitswapserver.WithMetrics(sink))
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		go func() {
+			log.Fatal(http.ListenAndServe(addr, mux))
+		}()
+	}
+
+	if addr := c.String("debug"); addr != "" {
+		go func() {
+			log.Fatal(http.ListenAndServe(addr, debugHandler(re.mon)))
+		}()
+	}
+
+	// the PIR databases are built here, before the first stream is accepted.
+	count, largest, err := bitswapserver.Size(c.Context, bs)
+	if err != nil {
+		return err
+	}
+	log.Printf("building PIR databases of %d blocks, the largest %d bytes", count, largest)
+	if err := bitswapserver.AttachBitswapServer(host, bs, opts...); err != nil {
+		return err
+	}
+	for _, a := range host.Addrs() {
+		log.Printf("listening on %s/p2p/%s", a, host.ID())
+	}
+	if indexers := c.StringSlice("indexer"); len(indexers) > 0 {
+		a, err := advertise(c, key, host.Addrs(), schemes, bs)
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+	}
+
+	// settings are reloaded on SIGHUP, or as the --config file changes.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	var poll <-chan time.Time
+	if c.String("config") != "" && c.Duration("config-poll") > 0 {
+		t := time.NewTicker(c.Duration("config-poll"))
+		defer t.Stop()
+		poll = t.C
+	}
+	for {
+		select {
+		case s := <-sig:
+			if s != syscall.SIGHUP {
+				return nil
+			}
+		case <-poll:
+			if !re.changed() {
+				continue
+			}
+		}
+		if err := re.reload(); err != nil {
+			log.Printf("reloading settings: %v", err)
+		}
+	}
+}
+
+// debugHandler serves the runtime's profiles under /debug/pprof/, its
+// expvars and the stats of the server, as "bitswap", at /debug/vars, and
+// /healthz, which fails until the server is attached.
+func debugHandler(mon *bitswapserver.Monitor) http.Handler {
+	expvar.Publish("bitswap", expvar.Func(func() interface{} {
+		s, _ := mon.Stats()
+		return s
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := mon.Stats(); !ok {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// resourceManager limits the host's resources, with a service of their own
+// for private bitswap streams, reporting their use to reg if it is set.
+func resourceManager(c *cli.Context, reg prometheus.Registerer) (network.ResourceManager, error) {
+	limits := rcmgr.DefaultLimits
+	libp2p.SetDefaultServiceLimits(&limits)
+	bitswapserver.SetServiceLimits(&limits)
+	if n := c.Int64("pir-memory"); n > 0 {
+		base := bitswapserver.ServiceLimit
+		base.Memory = n
+		limits.AddServiceLimit(bitswapserver.ServiceName, base, rcmgr.BaseLimitIncrease{})
+	}
+	var opts []rcmgr.Option
+	if reg != nil {
+		obs.MustRegisterWith(reg)
+		reporter, err := obs.NewStatsTraceReporter()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rcmgr.WithTraceReporter(reporter))
+	}
+	return rcmgr.NewResourceManager(rcmgr.NewFixedLimiter(limits.AutoScale()), opts...)
+}
+
+// advertise publishes advertisements of the blocks of bs to the indexers,
+// serving them over HTTP at the ipni-http address.
+func advertise(c *cli.Context, key crypto.PrivKey, addrs []ma.Multiaddr, schemes []string, bs store) (*announce.Announcer, error) {
+	if !c.IsSet("ipni-http") {
+		return nil, errors.New("--ipni-http must be given to advertise to indexers")
+	}
+	addr, err := ma.NewMultiaddr(c.String("ipni-http"))
+	if err != nil {
+		return nil, err
+	}
+	port, err := addr.ValueForProtocol(ma.P_TCP)
+	if err != nil {
+		return nil, fmt.Errorf("--ipni-http has no port: %w", err)
+	}
+	pub, err := ipni.New(key, ipni.Options{
+		Schemes:        schemes,
+		Addrs:          addrs,
+		PublisherAddrs: []ma.Multiaddr{addr},
+		Indexers:       c.StringSlice("indexer"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		log.Fatal(http.ListenAndServe(":"+port, pub))
+	}()
+	a := announce.New(bs, announce.Options{}, pub)
+	a.Start()
+	return a, nil
+}
+
+type store interface {
+	bitswapserver.EnumerableBlockstore
+	io.Closer
+}
+
+// openStore opens the one blockstore named by the flags.
+func openStore(c *cli.Context) (store, error) {
+	var opened []string
+	for _, name := range []string{"car", "flatfs", "badger"} {
+		if c.IsSet(name) {
+			opened = append(opened, name)
+		}
+	}
+	if len(opened) != 1 {
+		return nil, errors.New("exactly one of --car, --flatfs and --badger must be given")
+	}
+	switch opened[0] {
+	case "car":
+		return carstore.Open(c.String("car"))
+	case "flatfs":
+		return util.NewFlatFSStore(c.String("flatfs"))
+	default:
+		return util.NewBadgerStore(c.String("badger"))
+	}
+}
+
+// loadIdentity reads the private key at path, generating and saving one the
+// first time so the host keeps its peer ID across restarts.
+func loadIdentity(path string) (crypto.PrivKey, error) {
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		return crypto.UnmarshalPrivateKey(raw)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if raw, err = crypto.MarshalPrivateKey(key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// loadSnapshotKey reads the key sealing PIR snapshots at path, generating
+// and saving one the first time.
+func loadSnapshotKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != pirstore.KeySize {
+			return nil, fmt.Errorf("%s: snapshot key is %d bytes, not %d", path, len(key), pirstore.KeySize)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	key = make([]byte, pirstore.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// configureLogging sets the levels and sampling of the loggers as flagged.
+func configureLogging(c *cli.Context) error {
+	cfg := logging.Config{Levels: make(map[string]string)}
+	for _, v := range c.StringSlice("log-level") {
+		name, level, ok := strings.Cut(v, "=")
+		if !ok {
+			name, level = "*", v
+		}
+		cfg.Levels[name] = level
+	}
+	if n := c.Int("log-sample"); n > 0 {
+		cfg.Sampling = &logging.Sampling{Tick: time.Second, First: n, Thereafter: n}
+	}
+	if err := logging.Configure(cfg); err != nil {
+		return fmt.Errorf("--log-level: %w", err)
+	}
+	return nil
+}
+
+// parseWeights parses --pir-weight values, PEER=WEIGHT.
+func parseWeights(values []string) (map[peer.ID]float64, error) {
+	weights := make(map[peer.ID]float64)
+	for _, v := range values {
+		id, weight, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("--pir-weight %s: not PEER=WEIGHT", v)
+		}
+		p, err := peer.Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("--pir-weight %s: %w", v, err)
+		}
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("--pir-weight %s: weight is not a positive number", v)
+		}
+		weights[p] = w
+	}
+	return weights, nil
+}
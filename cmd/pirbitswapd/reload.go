@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/urfave/cli/v2"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+)
+
+// settings are the flags which may be reloaded while the daemon runs, from
+// a --config file of JSON naming them as the flags do, such as
+//
+//	{"pir-rate": 20, "pir-budget": "2s", "allow-peer": ["12D3Koo..."]}
+//
+// Reloading them keeps the streams open and the PIR databases built.
+// Values in the file take precedence over the flags.
+type settings struct {
+	MaxStreams    int      `json:"max-streams"`
+	PIRRate       float64  `json:"pir-rate"`
+	PIRBurst      int      `json:"pir-burst"`
+	Bandwidth     int      `json:"bandwidth"`
+	PIRBudget     duration `json:"pir-budget"`
+	PIRMaxDefer   duration `json:"pir-max-defer"`
+	AnswerWorkers int      `json:"answer-workers"`
+	// AllowPeer is nil to serve private retrievals to all peers.
+	AllowPeer []string `json:"allow-peer"`
+}
+
+// duration is a time.Duration written as in flags, such as "250ms".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// loadSettings returns the settings of the flags, overridden by those of
+// the --config file if there is one.
+func loadSettings(c *cli.Context) (settings, error) {
+	s := settings{
+		MaxStreams:    c.Int("max-streams"),
+		PIRRate:       c.Float64("pir-rate"),
+		PIRBurst:      c.Int("pir-burst"),
+		Bandwidth:     c.Int("bandwidth"),
+		PIRBudget:     duration(c.Duration("pir-budget")),
+		PIRMaxDefer:   duration(c.Duration("pir-max-defer")),
+		AnswerWorkers: c.Int("answer-workers"),
+	}
+	if c.IsSet("allow-peer") {
+		s.AllowPeer = c.StringSlice("allow-peer")
+	}
+	path := c.String("config")
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return s, fmt.Errorf("--config %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s settings) limits() bitswapserver.Limits {
+	return bitswapserver.Limits{
+		MaxStreams:          s.MaxStreams,
+		PIRQueriesPerSecond: s.PIRRate,
+		PIRBurst:            s.PIRBurst,
+		BytesPerSecond:      s.Bandwidth,
+	}
+}
+
+func (s settings) admission() bitswapserver.Admission {
+	return bitswapserver.Admission{
+		Budget:   time.Duration(s.PIRBudget),
+		MaxDefer: time.Duration(s.PIRMaxDefer),
+	}
+}
+
+func (s settings) allowed() ([]peer.ID, error) {
+	peers := make([]peer.ID, 0, len(s.AllowPeer))
+	for _, id := range s.AllowPeer {
+		p, err := peer.Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("--allow-peer %s: %w", id, err)
+		}
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
+// reloader applies reloaded settings to the running server.
+type reloader struct {
+	c   *cli.Context
+	mon *bitswapserver.Monitor
+	// allow is the server's allowlist, nil if it serves every peer.
+	allow *bitswapserver.Allowlist
+	// parallel are the schemes whose answers are split across workers.
+	parallel []pir.Parallel
+	// modified is when the --config file was changed, as last read.
+	modified time.Time
+}
+
+// reload reads the settings again and applies them. Settings which fail
+// to load leave the server as it was.
+func (r *reloader) reload() error {
+	r.modified = modified(r.c)
+	s, err := loadSettings(r.c)
+	if err != nil {
+		return err
+	}
+	peers, err := s.allowed()
+	if err != nil {
+		return err
+	}
+	if (r.allow == nil) != (s.AllowPeer == nil) {
+		return fmt.Errorf("allow-peer must be set, or unset, as the daemon started; restart to change it")
+	}
+	r.mon.SetLimits(s.limits())
+	r.mon.SetAdmission(s.admission())
+	if r.allow != nil {
+		r.allow.Set(peers...)
+	}
+	for _, p := range r.parallel {
+		p.SetWorkers(s.AnswerWorkers)
+	}
+	log.Printf("reloaded settings")
+	return nil
+}
+
+// changed reports whether the --config file changed since it was read.
+func (r *reloader) changed() bool {
+	return !modified(r.c).Equal(r.modified)
+}
+
+// modified returns when the --config file was last changed, or the zero
+// time if it cannot be read.
+func modified(c *cli.Context) time.Time {
+	fi, err := os.Stat(c.String("config"))
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
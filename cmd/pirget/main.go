@@ -0,0 +1,317 @@
+// Command pirget privately retrieves a block, or a whole DAG, from a peer
+// given by its multiaddr or found through private provider lookups.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/urfave/cli/v2"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/announce/ipni"
+	"github.com/willscott/go-selfish-bitswap-client/carwriter"
+	"github.com/willscott/go-selfish-bitswap-client/fetcher"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/registry"
+	"github.com/willscott/go-selfish-bitswap-client/pir/shard"
+	"github.com/willscott/go-selfish-bitswap-client/pir/xorpir"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func main() {
+	app := &cli.App{
+		Name:      "pirget",
+		Usage:     "Private bitswap retrieval client",
+		ArgsUsage: "<cid>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "peer",
+				Aliases: []string{"p"},
+				Usage:   "multiaddr, ending in /p2p/<id>, of the peer to fetch from",
+			},
+			&cli.StringFlag{
+				Name:  "pair",
+				Usage: "multiaddr of a second peer holding the same blocks as --peer, to fetch from both with the two-server xorpir scheme",
+			},
+			&cli.StringSliceFlag{
+				Name:  "finder",
+				Usage: "multiaddrs of private provider servers to look the cid up on, when no --peer is given",
+			},
+			&cli.StringFlag{
+				Name:  "indexer",
+				Usage: "URL of a network indexer to look up providers advertising the schemes on, when no --peer is given",
+			},
+			&cli.StringFlag{
+				Name:  "delegated",
+				Usage: "URL of a delegated routing (/routing/v1) server to look up providers on, when no --peer is given, trying those declaring PIR first",
+			},
+			&cli.StringSliceFlag{
+				Name:  "scheme",
+				Usage: "PIR schemes to offer, in order of preference, of " + strings.Join(registry.Single(), ", "),
+				Value: cli.NewStringSlice(registry.Single()...),
+			},
+			&cli.BoolFlag{
+				Name:  "sharded",
+				Usage: "query peers serving sharded databases of the scheme",
+			},
+			&cli.BoolFlag{
+				Name:  "dag",
+				Usage: "fetch the whole DAG under the cid, written as a CAR",
+			},
+			&cli.StringFlag{
+				Name:  "store",
+				Usage: "flatfs directory keeping the blocks of a --dag fetch, so an interrupted fetch resumes where it left off when run again",
+			},
+			&cli.BoolFlag{
+				Name:  "car",
+				Usage: "write a CAR rather than the raw block",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "file to write to, instead of stdout; CARs written to files are indexed CARv2",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "give up after this long",
+				Value: 5 * time.Minute,
+			},
+		},
+		Action: Get,
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Get(c *cli.Context) error {
+	if c.Args().Len() == 0 {
+		return fmt.Errorf("no cid specified")
+	}
+	root, err := cid.Parse(c.Args().First())
+	if err != nil {
+		return err
+	}
+	var schemes []pir.Scheme
+	for _, name := range c.StringSlice("scheme") {
+		scheme, err := registry.New(name)
+		if err != nil {
+			return err
+		}
+		if c.Bool("sharded") {
+			scheme = shard.New(scheme, shard.Options{})
+		}
+		schemes = append(schemes, scheme)
+	}
+	if len(schemes) == 0 {
+		return fmt.Errorf("no scheme specified")
+	}
+	scheme := schemes[0]
+
+	ctx, cncl := context.WithTimeout(context.Background(), c.Duration("timeout"))
+	defer cncl()
+
+	h, err := libp2p.New()
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	client := bitswap.NewClient(h, bitswap.Options{Scheme: scheme, Schemes: schemes[1:]})
+	defer client.Close()
+
+	var finder routing.Finder
+	if c.IsSet("peer") {
+		ai, err := addPeer(h, c.String("peer"))
+		if err != nil {
+			return err
+		}
+		finder = known{ai}
+	} else if c.IsSet("finder") {
+		var servers []peer.ID
+		for _, s := range c.StringSlice("finder") {
+			ai, err := addPeer(h, s)
+			if err != nil {
+				return err
+			}
+			servers = append(servers, ai.ID)
+		}
+		finder = routing.NewPrivateFinder(h, scheme, servers...)
+	} else if c.IsSet("indexer") {
+		var ids []string
+		for _, s := range schemes {
+			ids = append(ids, s.ID())
+		}
+		finder = ipni.NewFinder(c.String("indexer"), ids...)
+	} else if c.IsSet("delegated") {
+		finder = routing.PIRFirst(routing.NewDelegatedFinder(c.String("delegated"), h.Peerstore()), h.Peerstore())
+	} else {
+		return errors.New("one of --peer, --finder, --indexer and --delegated must be given")
+	}
+
+	if c.IsSet("pair") {
+		return getPair(ctx, c, h, root, finder)
+	}
+
+	if !c.Bool("dag") {
+		data, err := routing.New(finder, client, routing.Options{Private: true}).Get(ctx, root)
+		if err != nil {
+			return err
+		}
+		return writeBlock(ctx, c, root, data)
+	}
+
+	// the whole DAG is fetched from the first provider of its root.
+	p, err := firstProvider(ctx, h, finder, root)
+	if err != nil {
+		return err
+	}
+	opts := fetcher.Options{Private: true}
+	if c.IsSet("store") {
+		store, err := util.NewFlatFSStore(c.String("store"))
+		if err != nil {
+			return err
+		}
+		opts.Store = store
+	}
+	f := fetcher.New(client, p, opts)
+	return writeCAR(c, root, func(car *carwriter.Writer) error {
+		return f.Fetch(ctx, root, car)
+	})
+}
+
+// getPair fetches the block root from the peer given by --peer and the one
+// given by --pair, splitting each query between them.
+func getPair(ctx context.Context, c *cli.Context, h host.Host, root cid.Cid, finder routing.Finder) error {
+	k, ok := finder.(known)
+	if !ok || c.Bool("dag") {
+		return errors.New("--pair fetches a single block, and needs --peer")
+	}
+	ai, err := addPeer(h, c.String("pair"))
+	if err != nil {
+		return err
+	}
+	pair, err := bitswap.NewPair(h, k.ai.ID, ai.ID, xorpir.New(), bitswap.Options{})
+	if err != nil {
+		return err
+	}
+	defer pair.Close()
+	data, err := pair.PrivateGet(ctx, root)
+	if err != nil {
+		return err
+	}
+	return writeBlock(ctx, c, root, data)
+}
+
+// writeBlock writes the block root, raw or as a CAR.
+func writeBlock(ctx context.Context, c *cli.Context, root cid.Cid, data []byte) error {
+	if !c.Bool("car") {
+		return output(c, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+	}
+	blk, err := blocks.NewBlockWithCid(data, root)
+	if err != nil {
+		return err
+	}
+	return writeCAR(c, root, func(car *carwriter.Writer) error {
+		return car.Put(ctx, blk)
+	})
+}
+
+// writeCAR calls write with a CAR writer to the output file, or stdout.
+func writeCAR(c *cli.Context, root cid.Cid, write func(*carwriter.Writer) error) error {
+	var car *carwriter.Writer
+	var err error
+	if path := c.String("output"); path != "" {
+		car, err = carwriter.Create(path, []cid.Cid{root})
+	} else {
+		car, err = carwriter.NewV1(os.Stdout, []cid.Cid{root})
+	}
+	if err != nil {
+		return err
+	}
+	if err := write(car); err != nil {
+		_ = car.Close()
+		return err
+	}
+	return car.Close()
+}
+
+// output calls write with the output file, or stdout.
+func output(c *cli.Context, write func(io.Writer) error) error {
+	path := c.String("output")
+	if path == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// addPeer parses the multiaddr s of a peer, and adds its address to the
+// peerstore of h.
+func addPeer(h host.Host, s string) (peer.AddrInfo, error) {
+	ma, err := multiaddr.NewMultiaddr(s)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	ai, err := peer.AddrInfoFromP2pAddr(ma)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	h.Peerstore().AddAddrs(ai.ID, ai.Addrs, peerstore.PermanentAddrTTL)
+	return *ai, nil
+}
+
+// firstProvider looks up the providers of c with finder, returning the first
+// which is not h itself.
+func firstProvider(ctx context.Context, h host.Host, finder routing.Finder, c cid.Cid) (peer.ID, error) {
+	ctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+	for ai := range finder.FindProvidersAsync(ctx, c, 0) {
+		if ai.ID == h.ID() {
+			continue
+		}
+		h.Peerstore().AddAddrs(ai.ID, ai.Addrs, peerstore.TempAddrTTL)
+		return ai.ID, nil
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return "", routing.ErrNoProviders
+}
+
+// known is a Finder returning the one peer given on the command line.
+type known struct {
+	ai peer.AddrInfo
+}
+
+func (k known) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo, 1)
+	out <- k.ai
+	close(out)
+	return out
+}
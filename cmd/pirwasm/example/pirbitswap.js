@@ -0,0 +1,43 @@
+// Binds the pirwasm client to a js-libp2p node, which dials peers over
+// WebTransport or WebSockets for it.
+import { peerIdFromString } from '@libp2p/peer-id'
+import { pushable } from 'it-pushable'
+
+// load instantiates pirwasm.wasm, which sets globalThis.pirbitswap. Go's
+// wasm_exec.js must be loaded first.
+export async function load (url = 'pirwasm.wasm') {
+  const go = new Go()
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject)
+  go.run(instance)
+  return globalThis.pirbitswap
+}
+
+// dialer adapts the streams of node to the read, write and close functions
+// the client expects.
+export function dialer (node) {
+  return async (peerId, protocols) => {
+    const stream = await node.dialProtocol(peerIdFromString(peerId), protocols)
+    const source = stream.source[Symbol.asyncIterator]()
+    const sink = pushable()
+    stream.sink(sink).catch(() => {})
+    return {
+      protocol: stream.protocol,
+      read: async () => {
+        const { value, done } = await source.next()
+        return done ? null : value.subarray()
+      },
+      write: async (buf) => { sink.push(buf) },
+      close: () => {
+        sink.end()
+        stream.close()
+      }
+    }
+  }
+}
+
+// get privately retrieves the block cid from the peer peerId, which node
+// must know an address of.
+export async function get (node, peerId, cid, options = {}) {
+  const client = globalThis.pirbitswap ?? await load()
+  return client.get(dialer(node), peerId, cid, options)
+}
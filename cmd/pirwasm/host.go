@@ -0,0 +1,112 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"io"
+	"syscall/js"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// jsHost opens streams through a js-libp2p node, which dials peers over
+// WebTransport or WebSockets as browsers allow. Sessions only open
+// streams and read the peerstore, so the rest of host.Host is left
+// unimplemented.
+type jsHost struct {
+	host.Host
+	// dial is called with a peer ID and a list of protocols, and returns a
+	// promise of a stream, as adapted by dialer in pirbitswap.js.
+	dial js.Value
+	ps   peerstore.Peerstore
+}
+
+// Peerstore knows nothing js-libp2p learned of peers, so sessions take the
+// keys of peers from their IDs.
+func (h *jsHost) Peerstore() peerstore.Peerstore {
+	return h.ps
+}
+
+// SetStreamHandler is a no-op: peers reply on the streams sessions open.
+func (h *jsHost) SetStreamHandler(protocol.ID, network.StreamHandler) {}
+
+func (h *jsHost) RemoveStreamHandler(protocol.ID) {}
+
+// ConnManager manages no connections: js-libp2p manages its own.
+func (h *jsHost) ConnManager() connmgr.ConnManager {
+	return &connmgr.NullConnMgr{}
+}
+
+func (h *jsHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error) {
+	protos := make([]interface{}, len(pids))
+	for i, pid := range pids {
+		protos[i] = string(pid)
+	}
+	v, err := await(ctx, h.dial.Invoke(p.String(), protos))
+	if err != nil {
+		return nil, err
+	}
+	proto := pids[0]
+	if v.Get("protocol").Type() == js.TypeString {
+		proto = protocol.ID(v.Get("protocol").String())
+	}
+	return &jsStream{v: v, proto: proto}, nil
+}
+
+// jsStream adapts a stream with read, write and close functions, read
+// resolving to a Uint8Array or null at the end of the stream.
+type jsStream struct {
+	network.Stream
+	v     js.Value
+	proto protocol.ID
+	buf   []byte
+}
+
+func (s *jsStream) Read(b []byte) (int, error) {
+	for len(s.buf) == 0 {
+		v, err := await(context.Background(), s.v.Call("read"))
+		if err != nil {
+			return 0, err
+		}
+		if v.IsNull() || v.IsUndefined() {
+			return 0, io.EOF
+		}
+		s.buf = make([]byte, v.Get("length").Int())
+		js.CopyBytesToGo(s.buf, v)
+	}
+	n := copy(b, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *jsStream) Write(b []byte) (int, error) {
+	if _, err := await(context.Background(), s.v.Call("write", bytesToJS(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (s *jsStream) Close() error {
+	s.v.Call("close")
+	return nil
+}
+
+func (s *jsStream) Reset() error {
+	return s.Close()
+}
+
+func (s *jsStream) CloseWrite() error { return nil }
+func (s *jsStream) CloseRead() error  { return nil }
+
+func (s *jsStream) SetDeadline(time.Time) error      { return nil }
+func (s *jsStream) SetReadDeadline(time.Time) error  { return nil }
+func (s *jsStream) SetWriteDeadline(time.Time) error { return nil }
+
+func (s *jsStream) Protocol() protocol.ID { return s.proto }
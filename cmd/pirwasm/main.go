@@ -0,0 +1,125 @@
+//go:build js && wasm
+
+// Command pirwasm is the client compiled to WebAssembly for browsers. It
+// registers a global pirbitswap object whose get function privately
+// retrieves a block from a peer, through streams opened by a js-libp2p node
+// over WebTransport or WebSockets; see example/ for the JavaScript side.
+//
+//	GOOS=js GOARCH=wasm go build -o pirwasm.wasm ./cmd/pirwasm
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/registry"
+)
+
+// client keeps a session per peer, sharing the parameters and hints of
+// their databases.
+type client struct {
+	mtx      sync.Mutex
+	params   *bitswap.ParamCache
+	ps       peerstore.Peerstore
+	sessions map[peer.ID]*bitswap.Session
+}
+
+// session returns the session with p, dialing through dial. The schemes
+// are fixed by the first get from each peer.
+func (c *client) session(p peer.ID, dial js.Value, schemes []pir.Scheme) *bitswap.Session {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if s, ok := c.sessions[p]; ok {
+		return s
+	}
+	s := bitswap.New(&jsHost{dial: dial, ps: c.ps}, p, bitswap.Options{
+		Scheme:  schemes[0],
+		Schemes: schemes[1:],
+		Params:  c.params,
+	})
+	c.sessions[p] = s
+	return s
+}
+
+func (c *client) close() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for p, s := range c.sessions {
+		_ = s.Close()
+		delete(c.sessions, p)
+	}
+}
+
+// get is pirbitswap.get(dial, peerId, cid, options), which resolves to the
+// block as a Uint8Array. options may set schemes, the names of the schemes
+// to offer in order of preference, and timeout, in milliseconds.
+func (c *client) get(_ js.Value, args []js.Value) interface{} {
+	return promise(func() (interface{}, error) {
+		if len(args) < 3 {
+			return nil, errors.New("pirbitswap.get(dial, peerId, cid, options)")
+		}
+		p, err := peer.Decode(args[1].String())
+		if err != nil {
+			return nil, err
+		}
+		root, err := cid.Parse(args[2].String())
+		if err != nil {
+			return nil, err
+		}
+		names, timeout := registry.Single(), time.Minute
+		if len(args) > 3 && args[3].Type() == js.TypeObject {
+			if v := args[3].Get("schemes"); v.Type() == js.TypeObject {
+				names = make([]string, v.Length())
+				for i := range names {
+					names[i] = v.Index(i).String()
+				}
+			}
+			if v := args[3].Get("timeout"); v.Type() == js.TypeNumber {
+				timeout = time.Duration(v.Int()) * time.Millisecond
+			}
+		}
+		var schemes []pir.Scheme
+		for _, name := range names {
+			scheme, err := registry.New(name)
+			if err != nil {
+				return nil, err
+			}
+			schemes = append(schemes, scheme)
+		}
+		if len(schemes) == 0 {
+			return nil, errors.New("no scheme specified")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		blk, err := c.session(p, args[0], schemes).PrivateGet(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		return bytesToJS(blk), nil
+	})
+}
+
+func main() {
+	ps, err := pstoremem.NewPeerstore()
+	if err != nil {
+		panic(err)
+	}
+	c := &client{params: bitswap.NewParamCache(), ps: ps, sessions: make(map[peer.ID]*bitswap.Session)}
+	js.Global().Set("pirbitswap", js.ValueOf(map[string]interface{}{
+		"get": js.FuncOf(c.get),
+		"close": js.FuncOf(func(js.Value, []js.Value) interface{} {
+			c.close()
+			return nil
+		}),
+	}))
+	select {}
+}
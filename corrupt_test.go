@@ -0,0 +1,75 @@
+package bitswap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// readStream is a stream reading the messages a peer sent from a buffer.
+type readStream struct {
+	network.Stream
+	r *bytes.Reader
+}
+
+func (s readStream) Read(p []byte) (int, error) { return s.r.Read(p) }
+func (readStream) Close() error                 { return nil }
+
+func TestCorruptPeer(t *testing.T) {
+	cl := NewClient(nil, Options{})
+	p := peer.ID("p")
+	s, err := cl.session(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := make(chan error, 1)
+	s.on(rawBlock(t, "wanted").Cid(), func(_ []byte, err error) {
+		failed <- err
+	})
+
+	// bare blocks matching no want are invalid.
+	m := bitswap_message_pb.Message{}
+	for i := 0; i < MaxInvalidBlocks; i++ {
+		m.Blocks = append(m.Blocks, []byte(fmt.Sprintf("bad block %d", i)))
+	}
+	msg, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	framed := append(buf[:binary.PutUvarint(buf, uint64(len(msg)))], msg...)
+	s.onStream(readStream{r: bytes.NewReader(framed)})
+	select {
+	case err := <-failed:
+		if !errors.Is(err, ErrCorruptPeer) {
+			t.Fatalf("get failed with %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("get outlived the session")
+	}
+
+	// the peer is refused until CorruptPeerTimeout expires.
+	for i := 0; i < 2; i++ {
+		if _, err := cl.session(p); !errors.Is(err, ErrCorruptPeer) {
+			t.Fatalf("corrupt peer given a session: %v", err)
+		}
+	}
+	if until := cl.penalized[p]; until.Before(time.Now().Add(CorruptPeerTimeout - time.Minute)) {
+		t.Fatalf("corrupt peer refused only until %v", until)
+	}
+	cl.penalized[p] = time.Now().Add(-time.Second)
+	fresh, err := cl.session(p)
+	if err != nil || fresh == s {
+		t.Fatalf("peer not given a new session once its penalty expired: %v", err)
+	}
+	if _, ok := cl.penalized[p]; ok {
+		t.Fatal("expired penalty kept")
+	}
+}
@@ -0,0 +1,50 @@
+// Package dag decodes the links of IPLD blocks, for walking DAGs block by
+// block, and checks the proofs linking a block to the root of its DAG.
+package dag
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ipfs/go-cid"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime/codec"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/ipld/go-ipld-prime/traversal"
+)
+
+var ErrUnsupportedCodec = errors.New("block codec cannot be traversed")
+
+// Links decodes the block data named by c and returns the CIDs it links to,
+// in the order they appear. Raw blocks have no links; dag-pb and dag-cbor
+// blocks are decoded, and any other codec is unsupported.
+func Links(c cid.Cid, data []byte) ([]cid.Cid, error) {
+	var decode codec.Decoder
+	switch c.Prefix().Codec {
+	case cid.Raw:
+		return nil, nil
+	case cid.DagProtobuf:
+		decode = dagpb.Decode
+	case cid.DagCBOR:
+		decode = dagcbor.Decode
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := decode(nb, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	links, err := traversal.SelectLinks(nb.Build())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]cid.Cid, 0, len(links))
+	for _, l := range links {
+		if cl, ok := l.(cidlink.Link); ok {
+			out = append(out, cl.Cid)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+package dag_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+)
+
+func rawCid(t *testing.T, data string) cid.Cid {
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func TestLinks(t *testing.T) {
+	a, b := rawCid(t, "a"), rawCid(t, "b")
+
+	pb, err := qp.BuildMap(dagpb.Type.PBNode, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "Links", qp.List(2, func(la datamodel.ListAssembler) {
+			for _, c := range []cid.Cid{a, b} {
+				c := c
+				qp.ListEntry(la, qp.Map(1, func(ma datamodel.MapAssembler) {
+					qp.MapEntry(ma, "Hash", qp.Link(cidlink.Link{Cid: c}))
+				}))
+			}
+		}))
+		qp.MapEntry(ma, "Data", qp.Bytes([]byte("dir")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pbData bytes.Buffer
+	if err := dagpb.Encode(pb, &pbData); err != nil {
+		t.Fatal(err)
+	}
+
+	cbor, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "child", qp.Link(cidlink.Link{Cid: b}))
+		qp.MapEntry(ma, "nested", qp.List(1, func(la datamodel.ListAssembler) {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: a}))
+		}))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cborData bytes.Buffer
+	if err := dagcbor.Encode(cbor, &cborData); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		codec uint64
+		data  []byte
+		want  []cid.Cid
+	}{
+		{cid.DagProtobuf, pbData.Bytes(), []cid.Cid{a, b}},
+		{cid.DagCBOR, cborData.Bytes(), []cid.Cid{b, a}},
+		{cid.Raw, []byte("leaf"), nil},
+	} {
+		h, _ := multihash.Sum(tc.data, multihash.SHA2_256, -1)
+		links, err := dag.Links(cid.NewCidV1(tc.codec, h), tc.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(links) != len(tc.want) {
+			t.Fatalf("codec %x: got %d links, expected %d", tc.codec, len(links), len(tc.want))
+		}
+		for i := range links {
+			if !links[i].Equals(tc.want[i]) {
+				t.Fatalf("codec %x: link %d is %s, expected %s", tc.codec, i, links[i], tc.want[i])
+			}
+		}
+	}
+
+	if _, err := dag.Links(cid.NewCidV1(cid.DagJSON, a.Hash()), []byte("{}")); err != dag.ErrUnsupportedCodec {
+		t.Fatalf("expected unsupported codec, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package dag
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+var (
+	// ErrBadProof is returned for proofs which do not link a block to the
+	// root they are checked against.
+	ErrBadProof = errors.New("dag: block not linked from root by its proof")
+	// ErrMalformedProof is returned for data which cannot be split into a
+	// block and its proof.
+	ErrMalformedProof = errors.New("dag: malformed proof")
+)
+
+// AppendProof appends block and its proof to dst: path, the blocks linking
+// the root of a DAG to it, root first and the block's parent last. The
+// root's proof is empty.
+func AppendProof(dst, block []byte, path [][]byte) []byte {
+	dst = appendPart(dst, block)
+	for _, data := range path {
+		dst = appendPart(dst, data)
+	}
+	return dst
+}
+
+func appendPart(b, p []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	b = append(b, buf[:binary.PutUvarint(buf[:], uint64(len(p)))]...)
+	return append(b, p...)
+}
+
+// SplitProof splits data appended by AppendProof into the block and its
+// path. Both alias data.
+func SplitProof(data []byte) (block []byte, path [][]byte, err error) {
+	var parts [][]byte
+	for len(data) > 0 {
+		n, read := binary.Uvarint(data)
+		if read <= 0 || n > uint64(len(data)-read) {
+			return nil, nil, ErrMalformedProof
+		}
+		parts = append(parts, data[read:read+int(n)])
+		data = data[read+int(n):]
+	}
+	if len(parts) == 0 {
+		return nil, nil, ErrMalformedProof
+	}
+	return parts[0], parts[1:], nil
+}
+
+// CheckProof checks that path links the DAG of root to the block named by
+// c: that each of its blocks is named by a link of the one before, the
+// first by root, and that the last links to c. Blocks are matched to links
+// by multihash, as c may be of another CID version than the link to it.
+func CheckProof(root, c cid.Cid, path [][]byte) error {
+	if len(path) == 0 {
+		if string(root.Hash()) != string(c.Hash()) {
+			return ErrBadProof
+		}
+		return nil
+	}
+	cur := root
+	for i, data := range path {
+		if sum, err := cur.Prefix().Sum(data); err != nil || !sum.Equals(cur) {
+			return ErrBadProof
+		}
+		links, err := Links(cur, data)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadProof, err)
+		}
+		next, ok := cid.Undef, false
+		if i+1 < len(path) {
+			next, ok = linkTo(links, path[i+1])
+		} else {
+			for _, l := range links {
+				if ok = string(l.Hash()) == string(c.Hash()); ok {
+					break
+				}
+			}
+		}
+		if !ok {
+			return ErrBadProof
+		}
+		cur = next
+	}
+	return nil
+}
+
+// linkTo returns the link of links naming data, hashing data once for each
+// kind of CID linked.
+func linkTo(links []cid.Cid, data []byte) (cid.Cid, bool) {
+	sums := make(map[cid.Prefix]cid.Cid)
+	for _, l := range links {
+		p := l.Prefix()
+		sum, ok := sums[p]
+		if !ok {
+			var err error
+			if sum, err = p.Sum(data); err != nil {
+				continue
+			}
+			sums[p] = sum
+		}
+		if sum.Equals(l) {
+			return l, true
+		}
+	}
+	return cid.Undef, false
+}
@@ -0,0 +1,78 @@
+package dag_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+)
+
+// node encodes a dag-cbor block linking to children.
+func node(t *testing.T, children ...cid.Cid) (cid.Cid, []byte) {
+	n, err := qp.BuildList(basicnode.Prototype.Any, int64(len(children)), func(la datamodel.ListAssembler) {
+		for _, c := range children {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: c}))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(n, &buf); err != nil {
+		t.Fatal(err)
+	}
+	h, err := multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.DagCBOR, h), buf.Bytes()
+}
+
+func TestProof(t *testing.T) {
+	leaf, sibling := rawCid(t, "leaf"), rawCid(t, "sibling")
+	mid, midData := node(t, sibling, leaf)
+	root, rootData := node(t, mid)
+	other, otherData := node(t, sibling)
+
+	block, path, err := dag.SplitProof(dag.AppendProof(nil, []byte("leaf"), [][]byte{rootData, midData}))
+	if err != nil || string(block) != "leaf" || len(path) != 2 {
+		t.Fatalf("split into %q and %d blocks: %v", block, len(path), err)
+	}
+	if err := dag.CheckProof(root, leaf, path); err != nil {
+		t.Fatalf("proof of the leaf refused: %v", err)
+	}
+	// the leaf may be named by a CID of another version than the link.
+	if err := dag.CheckProof(root, cid.NewCidV0(leaf.Hash()), path); err != nil {
+		t.Fatalf("proof of the leaf by another CID refused: %v", err)
+	}
+	if err := dag.CheckProof(root, root, nil); err != nil {
+		t.Fatalf("empty proof of the root refused: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		root, c cid.Cid
+		path    [][]byte
+	}{
+		"another root":         {other, leaf, path},
+		"another block":        {root, rawCid(t, "elsewhere"), path},
+		"a block left out":     {root, leaf, path[1:]},
+		"a block of elsewhere": {root, leaf, [][]byte{rootData, otherData}},
+		"no blocks":            {root, leaf, nil},
+	} {
+		if err := dag.CheckProof(tc.root, tc.c, tc.path); !errors.Is(err, dag.ErrBadProof) {
+			t.Fatalf("proof with %s checked with %v", name, err)
+		}
+	}
+
+	if _, _, err := dag.SplitProof([]byte{5, 'l', 'e'}); !errors.Is(err, dag.ErrMalformedProof) {
+		t.Fatalf("truncated proof split with %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+)
+
+// DecoyPolicy decides when a session sends decoy retrievals: complete PIR
+// retrievals of random positions, which peers cannot tell from real ones
+// and answer exactly alike, so an observer of when queries are sent cannot
+// tell when blocks are really wanted.
+type DecoyPolicy struct {
+	// Interval is the mean time between decoys sent in the background,
+	// whether or not the session is in use. The times between them are
+	// exponentially distributed, so they follow no pattern. Zero sends none.
+	Interval time.Duration
+	// PerRetrieval is the number of decoys sent along with each PrivateGet.
+	PerRetrieval int
+	// Spread delays each decoy sent along with a PrivateGet by a random time
+	// up to this long, so they are not all sent together with it.
+	Spread time.Duration
+}
+
+func (dp DecoyPolicy) enabled() bool {
+	return dp.Interval > 0 || dp.PerRetrieval > 0
+}
+
+// startDecoys sends decoys in the background until the session is closed,
+// if the policy asks for any.
+func (s *Session) startDecoys(dp DecoyPolicy) {
+	if !dp.enabled() {
+		return
+	}
+	s.decoyPolicy = dp
+	s.decoyCtx, s.stopDecoys = context.WithCancel(context.Background())
+	if dp.Interval > 0 {
+		go s.decoyLoop()
+	}
+}
+
+func (s *Session) decoyLoop() {
+	for {
+		gap := time.Duration(rand.ExpFloat64() * float64(s.decoyPolicy.Interval))
+		select {
+		case <-time.After(gap):
+		case <-s.decoyCtx.Done():
+			return
+		}
+		if err := s.decoy(s.decoyCtx); err != nil {
+			if s.decoyCtx.Err() != nil || s.failure() != nil || errors.Is(err, ErrNoCommonScheme) {
+				return
+			}
+			pirLogger.Debugw("decoy retrieval failed", "peer", s.peer, "err", err)
+		}
+	}
+}
+
+// sendDecoys sends the decoys accompanying a retrieval, each after a random
+// delay up to the policy's Spread. They outlive the retrieval, but not the
+// session.
+func (s *Session) sendDecoys() {
+	if s.decoyCtx == nil {
+		return
+	}
+	for i := 0; i < s.decoyPolicy.PerRetrieval; i++ {
+		var delay time.Duration
+		if s.decoyPolicy.Spread > 0 {
+			delay = time.Duration(rand.Int63n(int64(s.decoyPolicy.Spread)))
+		}
+		go func() {
+			select {
+			case <-time.After(delay):
+			case <-s.decoyCtx.Done():
+				return
+			}
+			if err := s.decoy(s.decoyCtx); err != nil && s.decoyCtx.Err() == nil {
+				pirLogger.Debugw("decoy retrieval failed", "peer", s.peer, "err", err)
+			}
+		}()
+	}
+}
+
+// decoy runs both rounds of a private retrieval, for a random key in the
+// index and a random block, discarding the answers.
+func (s *Session) decoy(ctx context.Context) error {
+	pp, err := s.privateParams(ctx)
+	if err != nil {
+		return err
+	}
+	session := atomic.AddUint64(&s.pirSession, 1)
+	s.metrics.Add("pir_decoys", 1)
+
+	key := make([]byte, 36)
+	rand.Read(key)
+	slots := keyword.Slots(key, pp.Index.NumElements)
+	if _, err := s.query(ctx, session, bitswap_message_pb.Message_IndexRound, pp.Epoch, pp.Index, slots[:]...); err != nil {
+		return err
+	}
+	var index uint64
+	if pp.Blocks.NumElements > 0 {
+		index = uint64(rand.Int63n(int64(pp.Blocks.NumElements)))
+	}
+	_, err = s.query(ctx, session, bitswap_message_pb.Message_BlockRound, pp.Epoch, pp.Blocks, index)
+	return err
+}
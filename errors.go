@@ -0,0 +1,118 @@
+package bitswap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+var (
+	// ErrRateLimited is reported by peers which received queries faster than
+	// they allow.
+	ErrRateLimited = errors.New("peer rate limited queries")
+	// ErrPeerBusy is reported by peers with too many requests waiting to be
+	// answered.
+	ErrPeerBusy = errors.New("peer too busy to answer")
+	// ErrBadRequest is reported by peers which could not parse or answer a
+	// request as it was sent.
+	ErrBadRequest = errors.New("peer rejected request")
+	// ErrUnauthorized is reported by peers which do not serve the client,
+	// or do not serve it private retrievals.
+	ErrUnauthorized = errors.New("peer refused to serve the client")
+	// ErrPeerFailed is reported by peers which failed to answer for reasons
+	// of their own.
+	ErrPeerFailed = errors.New("peer failed to answer")
+	// ErrDatabaseTooLarge is reported by peers whose databases, of every
+	// scheme offered, exceed the session's MaxElements or MaxElementSize. It
+	// is an ErrNoCommonScheme.
+	ErrDatabaseTooLarge = fmt.Errorf("%w: peer's databases exceed the session's limits", ErrNoCommonScheme)
+)
+
+// PeerError is an error reported by a peer, failing a PIR round, a
+// handshake, or the whole session. It wraps the error its code stands for:
+// pir.ErrSchemeMismatch (ErrNoCommonScheme for handshakes),
+// ErrDatabaseTooLarge, ErrRateLimited, ErrStaleParams, ErrPeerBusy,
+// ErrBadRequest, ErrUnauthorized or ErrPeerFailed.
+type PeerError struct {
+	Code bitswap_message_pb.Message_ErrorCode
+	// Message is the peer's description of the error, for people.
+	Message string
+	// RetryAfter is how long a busy or rate limiting peer asked to be left
+	// before the request is resent, if it said.
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *PeerError) Error() string {
+	return fmt.Sprintf("%v: %s", e.err, e.Message)
+}
+
+func (e *PeerError) Unwrap() error {
+	return e.err
+}
+
+// peerError returns the PeerError reported by e, which answers a handshake
+// if handshake is set.
+func peerError(e bitswap_message_pb.Message_Error, handshake bool) *PeerError {
+	pe := &PeerError{Code: e.Code, Message: e.Message, RetryAfter: time.Duration(e.RetryAfter) * time.Millisecond}
+	switch e.Code {
+	case bitswap_message_pb.Message_SchemeMismatch:
+		pe.err = pir.ErrSchemeMismatch
+		if handshake {
+			pe.err = ErrNoCommonScheme
+		}
+	case bitswap_message_pb.Message_DatabaseTooLarge:
+		pe.err = ErrDatabaseTooLarge
+	case bitswap_message_pb.Message_RateLimited:
+		pe.err = ErrRateLimited
+	case bitswap_message_pb.Message_StaleEpoch:
+		pe.err = ErrStaleParams
+	case bitswap_message_pb.Message_Busy:
+		pe.err = ErrPeerBusy
+	case bitswap_message_pb.Message_BadRequest:
+		pe.err = ErrBadRequest
+	case bitswap_message_pb.Message_Unauthorized:
+		pe.err = ErrUnauthorized
+	default:
+		pe.err = ErrPeerFailed
+	}
+	return pe
+}
+
+// onError fails what a peer reports e failed: the request e names, the
+// round of e's session, the handshake e was sent with, or else the whole
+// session.
+func (s *Session) onError(e bitswap_message_pb.Message_Error, handshake bool) {
+	s.metrics.Add("peer_errors", 1)
+	switch {
+	case e.Session != 0:
+		err := peerError(e, false)
+		prefix := progressInterest(e.Session, e.Round) + "/"
+		s.interestMtx.Lock()
+		var keys []string
+		for key := range s.interests {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if as, ok := s.answers[key]; e.Id != 0 && ok && as.id != e.Id {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		s.interestMtx.Unlock()
+		for _, key := range keys {
+			_ = s.deliver(key, nil, err)
+		}
+	case handshake:
+		if err := s.deliver(handshakeInterest, nil, peerError(e, true)); err != nil {
+			pirLogger.Warnw("unexpected PIR handshake", "err", err)
+		}
+	default:
+		logger.Debugw("peer reported error", "peer", s.peer, "code", e.Code, "message", e.Message)
+		s.fail(peerError(e, false))
+	}
+}
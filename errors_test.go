@@ -0,0 +1,66 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+func TestPeerErrors(t *testing.T) {
+	s := New(nil, "p", Options{ResponseTimeout: time.Second})
+	s.private = sinkStream{}
+	round := bitswap_message_pb.Message_BlockRound
+	params := pir.Params{Scheme: "test"}
+	s.params.Put(s.peer, PeerParams{Epoch: 3, Blocks: params})
+
+	// busy peers say when to retry.
+	if pe := peerError(bitswap_message_pb.Message_Error{Code: bitswap_message_pb.Message_Busy, RetryAfter: 250}, false); !errors.Is(pe, ErrPeerBusy) || pe.RetryAfter != 250*time.Millisecond {
+		t.Fatalf("busy peer reported as %v, retry after %v", pe, pe.RetryAfter)
+	}
+	if pe := peerError(bitswap_message_pb.Message_Error{Code: bitswap_message_pb.Message_Unauthorized}, false); !errors.Is(pe, ErrUnauthorized) || (RetryPolicy{}).retryable(context.Background(), context.Background(), pe) {
+		t.Fatalf("unauthorized client reported as %v", pe)
+	}
+
+	// errors for a session fail its round, and stale epochs its parameters.
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.ask(context.Background(), 1, round, 3, params, [][]byte{nil, nil}, nil)
+		done <- err
+	}()
+	awaiting(s, pirInterest(1, round, 1))
+	deliver(t, s, bitswap_message_pb.Message{Errors: []bitswap_message_pb.Message_Error{{
+		Code: bitswap_message_pb.Message_StaleEpoch, Session: 1, Round: round, Message: "epoch 4",
+	}}})
+	err := <-done
+	var pe *PeerError
+	if !errors.Is(err, ErrStaleParams) || !errors.As(err, &pe) || pe.Message != "epoch 4" {
+		t.Fatalf("stale round failed with %v", err)
+	}
+	if _, ok := s.params.Get(s.peer); ok {
+		t.Fatal("parameters of a stale epoch kept")
+	}
+
+	// those sent with a handshake fail it.
+	go func() {
+		_, err := s.roundtrip(context.Background(), &bitswap_message_pb.Message{}, "", handshakeInterest)
+		done <- err
+	}()
+	awaiting(s, handshakeInterest)
+	deliver(t, s, bitswap_message_pb.Message{
+		PirHandshake: &bitswap_message_pb.Message_PIRHandshake{Schemes: []string{"test"}},
+		Errors:       []bitswap_message_pb.Message_Error{{Code: bitswap_message_pb.Message_DatabaseTooLarge}},
+	})
+	if err := <-done; !errors.Is(err, ErrDatabaseTooLarge) || !errors.Is(err, ErrNoCommonScheme) {
+		t.Fatalf("refused handshake failed with %v", err)
+	}
+
+	// and others fail the session.
+	deliver(t, s, bitswap_message_pb.Message{Errors: []bitswap_message_pb.Message_Error{{Code: bitswap_message_pb.Message_RateLimited}}})
+	if err := s.failure(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("session failed with %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package exchange adapts the client to the exchange interface of IPFS
+// implementations, so blockservices, and the unixfs readers and DAG walkers
+// built on them, retrieve blocks privately through it.
+//
+// The interface is that of go-ipfs-exchange-interface, which boxo's
+// exchange package keeps unchanged: an Exchange is a boxo
+// exchange.SessionExchange too.
+package exchange
+
+import (
+	"context"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ipfsexchange "github.com/ipfs/go-ipfs-exchange-interface"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+)
+
+// DefaultConcurrency is the number of blocks GetBlocks retrieves at once
+// when Options does not say otherwise.
+const DefaultConcurrency = 8
+
+var logger = logging.Logger("bitswap-exchange")
+
+type Options struct {
+	// Concurrency bounds the number of blocks each GetBlocks retrieves at
+	// once.
+	Concurrency int
+}
+
+// Exchange retrieves blocks from their providers with a routing.Fetcher,
+// privately if the fetcher is. It serves no blocks of its own: a server
+// attached to the same host answers peers from its blockstore.
+type Exchange struct {
+	f    *routing.Fetcher
+	opts Options
+}
+
+var _ ipfsexchange.SessionExchange = (*Exchange)(nil)
+
+// New creates an exchange retrieving blocks with f.
+func New(f *routing.Fetcher, opts Options) *Exchange {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	return &Exchange{f: f, opts: opts}
+}
+
+// GetBlock retrieves the block named by c from the first of its providers to
+// return it.
+func (e *Exchange) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	data, err := e.f.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// GetBlocks retrieves the blocks named by cids, sending each on the channel
+// returned as it arrives, in no particular order. Blocks which cannot be
+// retrieved are left out, and the channel is closed once the rest are sent
+// or ctx is done.
+func (e *Exchange) GetBlocks(ctx context.Context, cids []cid.Cid) (<-chan blocks.Block, error) {
+	out := make(chan blocks.Block)
+	go func() {
+		defer close(out)
+		slots := make(chan struct{}, e.opts.Concurrency)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		for _, c := range cids {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(c cid.Cid) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				blk, err := e.GetBlock(ctx, c)
+				if err != nil {
+					logger.Debugw("failed to get block", "cid", c, "err", err)
+					return
+				}
+				select {
+				case out <- blk:
+				case <-ctx.Done():
+				}
+			}(c)
+		}
+	}()
+	return out, nil
+}
+
+// NotifyNewBlocks does nothing, as the exchange serves no blocks.
+func (e *Exchange) NotifyNewBlocks(context.Context, ...blocks.Block) error {
+	return nil
+}
+
+// NewSession returns the exchange itself: the client keeps a session open
+// to each provider it fetched from already, which all callers share.
+func (e *Exchange) NewSession(context.Context) ipfsexchange.Fetcher {
+	return e
+}
+
+// Close does nothing: the client the exchange retrieves with is its
+// creator's to close.
+func (e *Exchange) Close() error {
+	return nil
+}
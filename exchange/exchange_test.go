@@ -0,0 +1,74 @@
+package exchange_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/exchange"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// fixedFinder reports the same providers for every CID.
+type fixedFinder []peer.ID
+
+func (f fixedFinder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	ch := make(chan peer.AddrInfo, len(f))
+	for _, p := range f {
+		ch <- peer.AddrInfo{ID: p}
+	}
+	close(ch)
+	return ch
+}
+
+func TestBlockService(t *testing.T) {
+	serverHost, _ := libp2p.New()
+	clientHost, _ := libp2p.New()
+	clientHost.Peerstore().AddAddrs(serverHost.ID(), serverHost.Addrs(), time.Hour)
+
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	a, b := util.Add(store, []byte("a")), util.Add(store, []byte("b"))
+	scheme := fastpir.New()
+	if err := bitswapserver.AttachBitswapServer(serverHost, store, bitswapserver.WithPIRScheme(scheme, pirstore.Options{})); err != nil {
+		t.Fatal(err)
+	}
+	cl := bitswap.NewClient(clientHost, bitswap.Options{Scheme: scheme})
+	defer cl.Close()
+	ex := exchange.New(routing.New(fixedFinder{serverHost.ID()}, cl, routing.Options{Private: true}), exchange.Options{})
+
+	// a blockservice over an empty blockstore retrieves through the
+	// exchange, and keeps what it retrieves.
+	local := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	bserv := blockservice.New(local, ex)
+	defer bserv.Close()
+	blk, err := bserv.GetBlock(context.Background(), a)
+	if err != nil || string(blk.RawData()) != "a" {
+		t.Fatalf("should get block, got %v %v", blk, err)
+	}
+	if has, err := local.Has(context.Background(), a); err != nil || !has {
+		t.Fatalf("block retrieved not kept: %v", err)
+	}
+
+	got := 0
+	for blk := range bserv.GetBlocks(context.Background(), []cid.Cid{a, b}) {
+		if blk.Cid() != a && blk.Cid() != b {
+			t.Fatalf("got unexpected block %s", blk.Cid())
+		}
+		got++
+	}
+	if got != 2 {
+		t.Fatalf("got %d blocks of 2", got)
+	}
+}
@@ -0,0 +1,242 @@
+// Package fetcher retrieves whole DAGs from a peer through the bitswap client.
+// Starting from a root CID, every block retrieved is decoded for links, and
+// the blocks linked to are retrieved in turn until the DAG is complete.
+//
+// Retrievals run concurrently, and when fetching privately the links found
+// are gathered into batches, which peers laying their databases out for
+// batches answer much more cheaply than one query per block.
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/carwriter"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+)
+
+const (
+	// DefaultConcurrency is the number of retrievals run at once when
+	// Options does not say otherwise.
+	DefaultConcurrency = 8
+	// DefaultBatchSize is the number of blocks retrieved together when
+	// fetching privately, when Options does not say otherwise.
+	DefaultBatchSize = 16
+	// DefaultPrefetch is the number of sibling links a Session retrieves
+	// ahead of demand when Options does not say otherwise.
+	DefaultPrefetch = 4
+)
+
+var logger = logging.Logger("bitswap-fetcher")
+
+// Sink receives the blocks of a fetched DAG. Blockstores are Sinks.
+type Sink interface {
+	Put(ctx context.Context, blk blocks.Block) error
+}
+
+// Store keeps blocks locally, such as a blockstore on disk. Blockstores are
+// Stores.
+type Store interface {
+	Sink
+	Has(ctx context.Context, c cid.Cid) (bool, error)
+	Get(ctx context.Context, c cid.Cid) (blocks.Block, error)
+}
+
+type Options struct {
+	// Concurrency bounds the number of retrievals in flight.
+	Concurrency int
+	// Private fetches blocks with PrivateGetBatch rather than Get, so the
+	// peer does not learn which blocks make up the DAG.
+	Private bool
+	// BatchSize bounds the number of blocks retrieved together when fetching
+	// privately. Batches are sent as soon as a retrieval can run, so they
+	// may be smaller.
+	BatchSize int
+	// Prefetch is the number of links a Session retrieves ahead of demand:
+	// the links following the block asked for in its parent, and the first
+	// links of the block itself. Negative disables prefetching.
+	Prefetch int
+	// Store, if set, keeps every block retrieved, and blocks it holds
+	// already are read from it rather than retrieved. A Store which
+	// outlives the process, such as util.NewFlatFSStore, lets a fetch
+	// interrupted by a restart resume where it left off: fetching the DAG
+	// again walks the blocks retrieved before locally, and retrieves only
+	// the rest.
+	Store Store
+}
+
+// Fetcher retrieves DAGs from one peer.
+type Fetcher struct {
+	client *bitswap.Client
+	peer   peer.ID
+	opts   Options
+	// retrieve is get, but may be replaced in tests.
+	retrieve func(context.Context, []cid.Cid) ([][]byte, error)
+}
+
+// New creates a fetcher retrieving DAGs from p with client.
+func New(client *bitswap.Client, p peer.ID, opts Options) *Fetcher {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if !opts.Private {
+		opts.BatchSize = 1
+	}
+	if opts.Prefetch == 0 {
+		opts.Prefetch = DefaultPrefetch
+	} else if opts.Prefetch < 0 {
+		opts.Prefetch = 0
+	}
+	f := &Fetcher{client: client, peer: p, opts: opts}
+	f.retrieve = f.get
+	return f
+}
+
+// Fetch retrieves the DAG under root, putting each of its blocks to out as
+// it arrives. Blocks linked to more than once are retrieved once. Fetch fails
+// if any block of the DAG cannot be retrieved or decoded.
+func (f *Fetcher) Fetch(ctx context.Context, root cid.Cid, out Sink) error {
+	ctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+
+	type result struct {
+		cids []cid.Cid
+		data [][]byte
+		err  error
+	}
+	// buffered so retrievals still running when Fetch fails can finish.
+	results := make(chan result, f.opts.Concurrency)
+	pending := []cid.Cid{root}
+	seen := map[cid.Cid]struct{}{root: {}}
+	inflight := 0
+	for len(pending) > 0 || inflight > 0 {
+		for len(pending) > 0 && inflight < f.opts.Concurrency {
+			n := f.opts.BatchSize
+			if n > len(pending) {
+				n = len(pending)
+			}
+			batch := pending[:n:n]
+			pending = pending[n:]
+			inflight++
+			go func() {
+				data, err := f.fetch(ctx, batch)
+				results <- result{batch, data, err}
+			}()
+		}
+
+		r := <-results
+		inflight--
+		if r.err != nil {
+			return r.err
+		}
+		for i, c := range r.cids {
+			if r.data[i] == nil {
+				return fmt.Errorf("fetch %s: %w", c, bitswap.ErrNotFound)
+			}
+			blk, err := blocks.NewBlockWithCid(r.data[i], c)
+			if err != nil {
+				return err
+			}
+			if err := out.Put(ctx, blk); err != nil {
+				return err
+			}
+			links, err := Links(c, r.data[i])
+			if err != nil {
+				return fmt.Errorf("decode %s: %w", c, err)
+			}
+			for _, l := range links {
+				if _, ok := seen[l]; ok {
+					continue
+				}
+				seen[l] = struct{}{}
+				pending = append(pending, l)
+			}
+		}
+	}
+	logger.Debugw("fetched dag", "root", root, "blocks", len(seen))
+	return nil
+}
+
+// fetch returns the blocks named by cids, leaving nil the ones the peer
+// doesn't have. Blocks held in the Store are read from it, and the others
+// retrieved and added to it.
+func (f *Fetcher) fetch(ctx context.Context, cids []cid.Cid) ([][]byte, error) {
+	if f.opts.Store == nil {
+		return f.retrieve(ctx, cids)
+	}
+	out := make([][]byte, len(cids))
+	var missing []int
+	var wanted []cid.Cid
+	for i, c := range cids {
+		if has, err := f.opts.Store.Has(ctx, c); err != nil {
+			return nil, err
+		} else if !has {
+			missing = append(missing, i)
+			wanted = append(wanted, c)
+			continue
+		}
+		blk, err := f.opts.Store.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = blk.RawData()
+	}
+	if len(wanted) == 0 {
+		return out, nil
+	}
+	got, err := f.retrieve(ctx, wanted)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		if got[j] == nil {
+			continue
+		}
+		blk, err := blocks.NewBlockWithCid(got[j], cids[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := f.opts.Store.Put(ctx, blk); err != nil {
+			return nil, err
+		}
+		out[i] = got[j]
+	}
+	return out, nil
+}
+
+// get retrieves a batch of blocks, leaving nil the ones the peer doesn't have.
+func (f *Fetcher) get(ctx context.Context, cids []cid.Cid) ([][]byte, error) {
+	if f.opts.Private {
+		return f.client.PrivateGetBatch(ctx, f.peer, cids)
+	}
+	out := make([][]byte, len(cids))
+	for i, c := range cids {
+		data, err := f.client.Get(ctx, f.peer, c)
+		if err != nil && err != bitswap.ErrNotFound {
+			return nil, fmt.Errorf("fetch %s: %w", c, err)
+		}
+		out[i] = data
+	}
+	return out, nil
+}
+
+// WriteCAR fetches the DAG under root and streams it to w as a CARv1 with
+// root as its only root. Blocks are written in the order they arrive.
+func (f *Fetcher) WriteCAR(ctx context.Context, root cid.Cid, w io.Writer) error {
+	car, err := carwriter.NewV1(w, []cid.Cid{root})
+	if err != nil {
+		return err
+	}
+	if err := f.Fetch(ctx, root, car); err != nil {
+		return err
+	}
+	return car.Close()
+}
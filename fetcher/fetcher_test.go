@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+)
+
+// memStore is a Store in memory.
+type memStore struct {
+	mtx    sync.Mutex
+	blocks map[cid.Cid]blocks.Block
+}
+
+func (s *memStore) Put(_ context.Context, blk blocks.Block) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.blocks[blk.Cid()] = blk
+	return nil
+}
+
+func (s *memStore) Has(_ context.Context, c cid.Cid) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.blocks[c]
+	return ok, nil
+}
+
+func (s *memStore) Get(_ context.Context, c cid.Cid) (blocks.Block, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.blocks[c], nil
+}
+
+func TestFetchResume(t *testing.T) {
+	d := newFakeDAG(t, 10)
+	store := &memStore{blocks: make(map[cid.Cid]blocks.Block)}
+	f := New(nil, "p", Options{Private: true, BatchSize: 1, Concurrency: 1, Store: store})
+
+	// a fetch interrupted after a few blocks keeps those it retrieved,
+	interrupted := errors.New("interrupted")
+	f.retrieve = func(ctx context.Context, cids []cid.Cid) ([][]byte, error) {
+		d.mtx.Lock()
+		n := len(d.retrieved)
+		d.mtx.Unlock()
+		if n == 4 {
+			return nil, interrupted
+		}
+		return d.retrieve(ctx, cids)
+	}
+	out := &memStore{blocks: make(map[cid.Cid]blocks.Block)}
+	if err := f.Fetch(context.Background(), d.root, out); !errors.Is(err, interrupted) {
+		t.Fatalf("expected the fetch interrupted, got %v", err)
+	}
+	if len(store.blocks) != 4 {
+		t.Fatalf("store kept %d blocks of 4 retrieved", len(store.blocks))
+	}
+
+	// and fetching again retrieves only the rest, with the DAG complete.
+	f.retrieve = d.retrieve
+	out = &memStore{blocks: make(map[cid.Cid]blocks.Block)}
+	if err := f.Fetch(context.Background(), d.root, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.blocks) != 11 {
+		t.Fatalf("fetched %d blocks of 11", len(out.blocks))
+	}
+	if n := d.retrievals(d.root); n != 1 {
+		t.Fatalf("root retrieved %d times", n)
+	}
+	for i, l := range d.leaves {
+		if n := d.retrievals(l); n != 1 {
+			t.Fatalf("leaf %d retrieved %d times", i, n)
+		}
+	}
+}
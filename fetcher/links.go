@@ -0,0 +1,14 @@
+package fetcher
+
+import (
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+)
+
+// ErrUnsupportedCodec is dag.ErrUnsupportedCodec.
+var ErrUnsupportedCodec = dag.ErrUnsupportedCodec
+
+// Links is dag.Links.
+func Links(c cid.Cid, data []byte) ([]cid.Cid, error) {
+	return dag.Links(c, data)
+}
@@ -0,0 +1,164 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+)
+
+// Session retrieves the blocks of DAGs on demand, for applications which
+// walk a DAG as they consume it, such as readers of large files. Each block
+// retrieved is decoded for links, and while the application works through a
+// block's links the session retrieves the next ones ahead of demand, so the
+// latency of their queries overlaps with the application's work rather than
+// adding to it. When fetching privately the links retrieved ahead are
+// batched, and the peer learns no more than it would from the blocks asked
+// for. Prefetched blocks are held until asked for, at most Options.Prefetch
+// times Options.Concurrency of them.
+type Session struct {
+	f      *Fetcher
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mtx sync.Mutex
+	// prefetched holds the retrievals started ahead of demand, until they
+	// are asked for.
+	prefetched map[cid.Cid]*retrieval
+	// next holds, for each link seen, the links following it in its parent.
+	next map[cid.Cid][]cid.Cid
+	// served holds the blocks already returned, which are not prefetched
+	// again.
+	served map[cid.Cid]struct{}
+}
+
+// retrieval is a block retrieved ahead of demand.
+type retrieval struct {
+	// done is closed once data and err are set.
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// NewSession starts a session retrieving blocks on demand. Retrievals ahead
+// of demand run until ctx is done or the session is closed.
+func (f *Fetcher) NewSession(ctx context.Context) *Session {
+	ctx, cncl := context.WithCancel(ctx)
+	return &Session{
+		f:          f,
+		ctx:        ctx,
+		cancel:     cncl,
+		prefetched: make(map[cid.Cid]*retrieval),
+		next:       make(map[cid.Cid][]cid.Cid),
+		served:     make(map[cid.Cid]struct{}),
+	}
+}
+
+// Get retrieves the block named by c, from the blocks retrieved ahead of
+// demand if it is among them, and starts retrieving the links following it
+// and its own first links.
+func (s *Session) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	s.mtx.Lock()
+	r, ok := s.prefetched[c]
+	delete(s.prefetched, c)
+	s.served[c] = struct{}{}
+	siblings := s.next[c]
+	s.mtx.Unlock()
+	s.prefetch(siblings)
+
+	var data []byte
+	if ok {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if r.err != nil {
+			logger.Debugw("prefetch failed, retrieving on demand", "cid", c, "err", r.err)
+			ok = false
+		}
+		data = r.data
+	}
+	if !ok {
+		got, err := s.f.fetch(ctx, []cid.Cid{c})
+		if err != nil {
+			return nil, err
+		}
+		data = got[0]
+	}
+	if data == nil {
+		return nil, fmt.Errorf("fetch %s: %w", c, bitswap.ErrNotFound)
+	}
+	blk, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := Links(c, data)
+	if err != nil {
+		// the block is still served; only its links go unprefetched.
+		logger.Debugw("not prefetching links of undecodable block", "cid", c, "err", err)
+		return blk, nil
+	}
+	s.mtx.Lock()
+	for i, l := range links {
+		s.next[l] = links[i+1:]
+	}
+	s.mtx.Unlock()
+	s.prefetch(links)
+	return blk, nil
+}
+
+// prefetch starts retrieving, in one batch, those of the first
+// Options.Prefetch of cids which were neither served nor already started.
+func (s *Session) prefetch(cids []cid.Cid) {
+	window := s.f.opts.Prefetch
+	if window > len(cids) {
+		window = len(cids)
+	}
+	s.mtx.Lock()
+	var batch []cid.Cid
+	var pending []*retrieval
+	for _, c := range cids[:window] {
+		if len(s.prefetched) >= s.f.opts.Prefetch*s.f.opts.Concurrency {
+			break
+		}
+		if _, ok := s.served[c]; ok {
+			continue
+		}
+		if _, ok := s.prefetched[c]; ok {
+			continue
+		}
+		r := &retrieval{done: make(chan struct{})}
+		s.prefetched[c] = r
+		batch = append(batch, c)
+		pending = append(pending, r)
+	}
+	s.mtx.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	go func() {
+		data, err := s.f.fetch(s.ctx, batch)
+		for i, r := range pending {
+			if err == nil {
+				r.data = data[i]
+			}
+			r.err = err
+			close(r.done)
+		}
+	}()
+}
+
+// Close abandons the retrievals started ahead of demand.
+func (s *Session) Close() error {
+	s.cancel()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.prefetched = make(map[cid.Cid]*retrieval)
+	return nil
+}
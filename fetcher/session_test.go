@@ -0,0 +1,142 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
+)
+
+// fakeDAG serves a dag-cbor root linking to raw leaves, recording each
+// retrieval.
+type fakeDAG struct {
+	root   cid.Cid
+	leaves []cid.Cid
+	blocks map[cid.Cid][]byte
+
+	mtx       sync.Mutex
+	retrieved [][]cid.Cid
+}
+
+func newFakeDAG(t *testing.T, n int) *fakeDAG {
+	d := &fakeDAG{blocks: make(map[cid.Cid][]byte)}
+	add := func(codec uint64, data []byte) cid.Cid {
+		h, err := multihash.Sum(data, multihash.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := cid.NewCidV1(codec, h)
+		d.blocks[c] = data
+		return c
+	}
+	for i := 0; i < n; i++ {
+		d.leaves = append(d.leaves, add(cid.Raw, []byte(fmt.Sprintf("leaf %d", i))))
+	}
+	node, err := qp.BuildList(basicnode.Prototype.Any, int64(n), func(la datamodel.ListAssembler) {
+		for _, l := range d.leaves {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: l}))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(node, &buf); err != nil {
+		t.Fatal(err)
+	}
+	d.root = add(cid.DagCBOR, buf.Bytes())
+	return d
+}
+
+func (d *fakeDAG) retrieve(ctx context.Context, cids []cid.Cid) ([][]byte, error) {
+	d.mtx.Lock()
+	d.retrieved = append(d.retrieved, cids)
+	d.mtx.Unlock()
+	out := make([][]byte, len(cids))
+	for i, c := range cids {
+		out[i] = d.blocks[c]
+	}
+	return out, nil
+}
+
+// retrievals returns the number of retrievals of c.
+func (d *fakeDAG) retrievals(c cid.Cid) int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	n := 0
+	for _, batch := range d.retrieved {
+		for _, r := range batch {
+			if r.Equals(c) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestSessionPrefetch(t *testing.T) {
+	d := newFakeDAG(t, 10)
+	f := New(nil, "p", Options{Private: true, Prefetch: 3})
+	f.retrieve = d.retrieve
+	ctx := context.Background()
+	s := f.NewSession(ctx)
+	defer s.Close()
+
+	if _, err := s.Get(ctx, d.root); err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range d.leaves {
+		blk, err := s.Get(ctx, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(blk.RawData()) != fmt.Sprintf("leaf %d", i) {
+			t.Fatalf("leaf %d: got %q", i, blk.RawData())
+		}
+	}
+	for i, l := range d.leaves {
+		if n := d.retrievals(l); n != 1 {
+			t.Fatalf("leaf %d retrieved %d times", i, n)
+		}
+	}
+	// the first links of the root were retrieved together, ahead of demand.
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for _, batch := range d.retrieved {
+		if len(batch) == 3 && batch[0].Equals(d.leaves[0]) && batch[2].Equals(d.leaves[2]) {
+			return
+		}
+	}
+	t.Fatalf("expected the first links of the root prefetched together, got %v", d.retrieved)
+}
+
+func TestSessionNoPrefetch(t *testing.T) {
+	d := newFakeDAG(t, 4)
+	f := New(nil, "p", Options{Prefetch: -1})
+	f.retrieve = d.retrieve
+	ctx := context.Background()
+	s := f.NewSession(ctx)
+	defer s.Close()
+
+	if _, err := s.Get(ctx, d.root); err != nil {
+		t.Fatal(err)
+	}
+	d.mtx.Lock()
+	n := len(d.retrieved)
+	d.mtx.Unlock()
+	if n != 1 {
+		t.Fatalf("expected only the root retrieved, got %d retrievals", n)
+	}
+	if _, err := s.Get(ctx, d.leaves[0]); err != nil {
+		t.Fatal(err)
+	}
+}
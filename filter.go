@@ -0,0 +1,25 @@
+package bitswap
+
+import "time"
+
+// DefaultFilterMaxAge is how long presence filters are trusted for when
+// Options.FilterMaxAge is not set.
+const DefaultFilterMaxAge = time.Minute
+
+// lacks reports whether the presence filter of pp shows that the peer does
+// not hold the block named by key. The check is local, so the peer learns
+// nothing of key, only that no round was run. Filters older than the
+// session's FilterMaxAge are not trusted, as the peer may have added the
+// block since: its parameters are forgotten instead, so the next retrieval
+// runs the handshake again for a fresh one.
+func (s *Session) lacks(pp PeerParams, key []byte) bool {
+	if pp.Filter == nil || s.filterAge < 0 || pp.Filter.Has(key) {
+		return false
+	}
+	if time.Since(pp.received) > s.filterAge {
+		s.params.Forget(s.peer)
+		return false
+	}
+	s.metrics.Add("filter_skips", 1)
+	return true
+}
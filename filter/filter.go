@@ -0,0 +1,79 @@
+// Package filter summarises the CIDs a server holds in a Bloom filter sent
+// with its PIR handshake. Clients check it locally, so a peer which surely
+// lacks a block is skipped without a PIR round, and without telling the
+// peer which block was looked for.
+package filter
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrFilter = errors.New("malformed presence filter")
+
+// Bloom is a Bloom filter over byte strings. Has never reports false for a
+// key which was added, and reports true for others at the rate the filter
+// was sized for.
+type Bloom struct {
+	// Bits holds the filter, a multiple of 8 bits long.
+	Bits []byte
+	// Hashes is the number of bits set for each key.
+	Hashes uint32
+}
+
+// New creates a filter for n keys falsely reporting others present at rate.
+func New(n int, rate float64) *Bloom {
+	if n < 1 {
+		n = 1
+	}
+	if rate <= 0 || rate >= 1 {
+		rate = 0.01
+	}
+	// the optimal size and number of hashes for n keys at rate.
+	bits := math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(bits / float64(n) * math.Ln2)
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &Bloom{Bits: make([]byte, (int(bits)+7)/8), Hashes: uint32(hashes)}
+}
+
+// Check reports an error if b cannot be a filter, as when received from a
+// peer.
+func (b *Bloom) Check() error {
+	if len(b.Bits) == 0 || b.Hashes == 0 || b.Hashes > 64 {
+		return ErrFilter
+	}
+	return nil
+}
+
+// Add adds key to the filter.
+func (b *Bloom) Add(key []byte) {
+	b.each(key, func(i uint64) bool {
+		b.Bits[i/8] |= 1 << (i % 8)
+		return true
+	})
+}
+
+// Has reports whether key may have been added to the filter.
+func (b *Bloom) Has(key []byte) bool {
+	return b.each(key, func(i uint64) bool {
+		return b.Bits[i/8]&(1<<(i%8)) != 0
+	})
+}
+
+// each calls f with the bits of key, by double hashing, while it returns
+// true, and reports whether it always did.
+func (b *Bloom) each(key []byte, f func(i uint64) bool) bool {
+	sum := sha256.Sum256(key)
+	h1, h2 := binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])|1
+	size := uint64(len(b.Bits)) * 8
+	for i := uint64(0); i < uint64(b.Hashes); i++ {
+		if !f((h1 + i*h2) % size) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloom(t *testing.T) {
+	b := New(1000, 0.01)
+	if err := b.Check(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		b.Add([]byte(fmt.Sprintf("key %d", i)))
+	}
+	for i := 0; i < 1000; i++ {
+		if !b.Has([]byte(fmt.Sprintf("key %d", i))) {
+			t.Fatalf("key %d added but missing", i)
+		}
+	}
+	wrong := 0
+	for i := 0; i < 10000; i++ {
+		if b.Has([]byte(fmt.Sprintf("other %d", i))) {
+			wrong++
+		}
+	}
+	if wrong > 300 {
+		t.Fatalf("%d of 10000 keys falsely present", wrong)
+	}
+	if err := (&Bloom{}).Check(); err == nil {
+		t.Fatal("empty filter checked")
+	}
+}
@@ -0,0 +1,315 @@
+// Package gateway serves content retrieved through the client over HTTP, as
+// an IPFS gateway does, so browsers and other HTTP consumers fetch it
+// privately without change.
+//
+// Content is addressed by path, as /ipfs/<cid>/<path>, or by subdomain, as
+// <cid>.ipfs.<domain>/<path> for each of Options.Domains, which gives each
+// root an origin of its own in browsers. UnixFS files are served with Range
+// requests, directories with their index.html or a listing, and any block
+// as it is with ?format=raw.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+	"github.com/willscott/go-selfish-bitswap-client/unixfs"
+)
+
+// DefaultTimeout bounds the retrievals of a request when Options does not
+// say otherwise.
+const DefaultTimeout = 2 * time.Minute
+
+// rawType is the content type of blocks served as they are.
+const rawType = "application/vnd.ipld.raw"
+
+var logger = logging.Logger("bitswap-gateway")
+
+type Options struct {
+	// Domains are the hosts the gateway serves subdomains of: requests to
+	// <cid>.ipfs.<domain> are served the content under cid, and path
+	// requests to the domain itself redirected there.
+	Domains []string
+	// Timeout bounds the retrievals of each request.
+	Timeout time.Duration
+}
+
+// Gateway is an http.Handler serving the content it retrieves with a
+// routing.Fetcher, privately if the fetcher is.
+type Gateway struct {
+	blocks unixfs.Getter
+	opts   Options
+}
+
+// New creates a gateway retrieving content with f.
+func New(f *routing.Fetcher, opts Options) *Gateway {
+	return newGateway(fetcherGetter{f}, opts)
+}
+
+func newGateway(g unixfs.Getter, opts Options) *Gateway {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Gateway{blocks: g, opts: opts}
+}
+
+// fetcherGetter retrieves the blocks of a unixfs.FS with a routing.Fetcher.
+type fetcherGetter struct {
+	f *routing.Fetcher
+}
+
+func (g fetcherGetter) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	data, err := g.f.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// ServeHTTP serves GET and HEAD requests for content by path or subdomain.
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	root, name, ok := gw.subdomain(r)
+	if !ok {
+		rest := strings.TrimPrefix(r.URL.Path, "/ipfs/")
+		if rest == r.URL.Path {
+			http.NotFound(w, r)
+			return
+		}
+		root, name, _ = strings.Cut(rest, "/")
+		name = "/" + name
+		if domain, ok := gw.domain(r.Host); ok {
+			gw.redirectToSubdomain(w, r, domain, root, name)
+			return
+		}
+	}
+	c, err := cid.Decode(root)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cncl := context.WithTimeout(r.Context(), gw.opts.Timeout)
+	defer cncl()
+	r = r.WithContext(ctx)
+	w.Header().Set("X-Ipfs-Path", "/ipfs/"+c.String()+name)
+	if r.URL.Query().Get("format") == "raw" || r.Header.Get("Accept") == rawType {
+		gw.serveRaw(w, r, c, name)
+		return
+	}
+	gw.serveUnixFS(w, r, c, name)
+}
+
+// subdomain returns the root and path of a request to <cid>.ipfs.<domain>.
+func (gw *Gateway) subdomain(r *http.Request) (string, string, bool) {
+	host := stripPort(r.Host)
+	for _, domain := range gw.opts.Domains {
+		if root, ok := cutSuffix(host, ".ipfs."+domain); ok && root != "" {
+			return root, r.URL.Path, true
+		}
+	}
+	return "", "", false
+}
+
+// domain returns the domain of Options.Domains which host is, if any.
+func (gw *Gateway) domain(host string) (string, bool) {
+	host = stripPort(host)
+	for _, domain := range gw.opts.Domains {
+		if host == domain {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// redirectToSubdomain sends path requests to a subdomain of domain, naming
+// the root as a base32 CIDv1, as DNS labels are case insensitive.
+func (gw *Gateway) redirectToSubdomain(w http.ResponseWriter, r *http.Request, domain, root, name string) {
+	c, err := cid.Decode(root)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	host := cid.NewCidV1(c.Type(), c.Hash()).String() + ".ipfs." + domain
+	if _, port, err := net.SplitHostPort(r.Host); err == nil {
+		host = net.JoinHostPort(host, port)
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := *r.URL
+	u.Scheme, u.Host, u.Path = scheme, host, name
+	http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+}
+
+// serveRaw serves the block c as it is.
+func (gw *Gateway) serveRaw(w http.ResponseWriter, r *http.Request, c cid.Cid, name string) {
+	if name != "/" && name != "" {
+		http.Error(w, "raw blocks have no paths", http.StatusBadRequest)
+		return
+	}
+	blk, err := gw.blocks.Get(r.Context(), c)
+	if err != nil {
+		gw.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", rawType)
+	setImmutable(w, c, "raw")
+	http.ServeContent(w, r, c.String()+".bin", time.Time{}, bytes.NewReader(blk.RawData()))
+}
+
+// serveUnixFS serves the file or directory at name under c.
+func (gw *Gateway) serveUnixFS(w http.ResponseWriter, r *http.Request, c cid.Cid, name string) {
+	fsys := unixfs.NewFromGetter(r.Context(), gw.blocks, c)
+	defer fsys.Close()
+	fname := strings.Trim(path.Clean(name), "/")
+	if fname == "" {
+		fname = "."
+	}
+	info, err := fsys.Stat(fname)
+	if err != nil {
+		gw.fail(w, r, err)
+		return
+	}
+	if info.IsDir() {
+		// relative links in directories need the trailing slash.
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, path.Base(r.URL.Path)+"/", http.StatusMovedPermanently)
+			return
+		}
+		index := path.Join(fname, "index.html")
+		if _, err := fsys.Stat(index); err == nil {
+			fname = index
+		} else if errors.Is(err, fs.ErrNotExist) {
+			gw.serveListing(w, r, fsys, fname, info)
+			return
+		} else {
+			gw.fail(w, r, err)
+			return
+		}
+	}
+	file, err := fsys.OpenFile(fname)
+	if err != nil {
+		gw.fail(w, r, err)
+		return
+	}
+	defer file.Close()
+	info, err = file.Stat()
+	if err != nil {
+		gw.fail(w, r, err)
+		return
+	}
+	setImmutable(w, info.Sys().(cid.Cid), "")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+var listing = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Path}}</title></head>
+<body>
+<h1>{{.Path}}</h1>
+<table>
+{{range .Entries}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td>{{.Size}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type listingEntry struct {
+	Name string
+	Href string
+	Size string
+}
+
+// serveListing serves an HTML list of the entries of the directory name.
+func (gw *Gateway) serveListing(w http.ResponseWriter, r *http.Request, fsys *unixfs.FS, name string, info fs.FileInfo) {
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		gw.fail(w, r, err)
+		return
+	}
+	data := struct {
+		Path    string
+		Entries []listingEntry
+	}{Path: r.URL.Path}
+	for _, e := range entries {
+		le := listingEntry{Name: e.Name(), Href: url.PathEscape(e.Name())}
+		if e.IsDir() {
+			le.Href += "/"
+		} else if info, err := e.Info(); err == nil {
+			le.Size = fmt.Sprintf("%d", info.Size())
+		}
+		data.Entries = append(data.Entries, le)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	setImmutable(w, info.Sys().(cid.Cid), "listing")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := listing.Execute(w, data); err != nil {
+		logger.Debugw("failed to write listing", "path", r.URL.Path, "err", err)
+	}
+}
+
+// fail reports err, a failure to retrieve or read content, with the status
+// closest to its cause.
+func (gw *Gateway) fail(w http.ResponseWriter, r *http.Request, err error) {
+	logger.Debugw("request failed", "path", r.URL.Path, "err", err)
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, bitswap.ErrNotFound), errors.Is(err, routing.ErrNoProviders):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, unixfs.ErrNotUnixFS):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
+
+// setImmutable marks responses as the content of c, which never changes.
+// Different representations of the same CID are told apart by variant.
+func setImmutable(w http.ResponseWriter, c cid.Cid, variant string) {
+	etag := c.String()
+	if variant != "" {
+		etag += "." + variant
+	}
+	w.Header().Set("Etag", `"`+etag+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=29030400, immutable")
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// cutSuffix is strings.CutSuffix, which is too recent for the module's Go
+// version.
+func cutSuffix(s, suffix string) (string, bool) {
+	if !strings.HasSuffix(s, suffix) {
+		return s, false
+	}
+	return s[:len(s)-len(suffix)], true
+}
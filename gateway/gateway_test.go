@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+)
+
+// site builds a directory holding index.html and a file, next to a
+// directory of files without an index, into a blockstore.
+func site(t *testing.T) (blockstore.Blockstore, cid.Cid, []byte) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageWriteOpener = func(lctx ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+		var buf bytes.Buffer
+		return &buf, func(l ipld.Link) error {
+			blk, err := blocks.NewBlockWithCid(buf.Bytes(), l.(cidlink.Link).Cid)
+			if err != nil {
+				return err
+			}
+			return bs.Put(lctx.Ctx, blk)
+		}, nil
+	}
+	file := func(name string, content []byte) dagpb.PBLink {
+		l, size, err := builder.BuildUnixFSFile(bytes.NewReader(content), "size-100", &lsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry(t, name, size, l)
+	}
+	dir := func(name string, entries ...dagpb.PBLink) dagpb.PBLink {
+		l, size, err := builder.BuildUnixFSDirectory(entries, &lsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry(t, name, size, l)
+	}
+
+	big := bytes.Repeat([]byte("0123456789"), 100)
+	root := dir("",
+		file("index.html", []byte("<p>hello</p>")),
+		file("big.txt", big),
+		dir("files", file("a b.txt", []byte("a")), file("c.txt", []byte("c"))),
+	)
+	return bs, root.FieldHash().Link().(cidlink.Link).Cid, big
+}
+
+func entry(t *testing.T, name string, size uint64, l ipld.Link) dagpb.PBLink {
+	t.Helper()
+	e, err := builder.BuildUnixFSDirectoryEntry(name, int64(size), l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func get(gw *Gateway, host, target string, header ...string) *http.Response {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Host = host
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func body(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGateway(t *testing.T) {
+	bs, root, big := site(t)
+	gw := newGateway(bs, Options{Domains: []string{"localhost"}})
+	prefix := "/ipfs/" + root.String()
+
+	// files are served by path, in ranges if asked.
+	resp := get(gw, "example.com", prefix+"/big.txt")
+	if resp.StatusCode != http.StatusOK || body(t, resp) != string(big) {
+		t.Fatalf("big.txt served with status %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Etag") == "" || !strings.Contains(resp.Header.Get("Cache-Control"), "immutable") {
+		t.Fatalf("big.txt served with headers %v", resp.Header)
+	}
+	resp = get(gw, "example.com", prefix+"/big.txt", "Range", "bytes=995-")
+	if resp.StatusCode != http.StatusPartialContent || body(t, resp) != "56789" {
+		t.Fatalf("range of big.txt served with status %d", resp.StatusCode)
+	}
+
+	// directories are served their index, or listed without one.
+	if resp = get(gw, "example.com", prefix+"/"); body(t, resp) != "<p>hello</p>" {
+		t.Fatalf("root served with status %d", resp.StatusCode)
+	}
+	if resp = get(gw, "example.com", prefix+"/files"); resp.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("directory without a slash served with status %d", resp.StatusCode)
+	}
+	resp = get(gw, "example.com", prefix+"/files/")
+	if listing := body(t, resp); !strings.Contains(listing, `href="a%20b.txt"`) || !strings.Contains(listing, "c.txt") {
+		t.Fatalf("directory listed as %s", listing)
+	}
+	if resp = get(gw, "example.com", prefix+"/missing"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing file served with status %d", resp.StatusCode)
+	}
+
+	// blocks are served as they are.
+	blk, err := bs.Get(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = get(gw, "example.com", prefix+"?format=raw")
+	if resp.Header.Get("Content-Type") != rawType || body(t, resp) != string(blk.RawData()) {
+		t.Fatalf("raw root served as %s", resp.Header.Get("Content-Type"))
+	}
+
+	// path requests to subdomain gateways are redirected to subdomains,
+	// which are served alike.
+	resp = get(gw, "localhost:8080", prefix+"/big.txt")
+	want := "http://" + root.String() + ".ipfs.localhost:8080/big.txt"
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+		t.Fatalf("path request to subdomain gateway redirected to %q", resp.Header.Get("Location"))
+	}
+	if resp = get(gw, root.String()+".ipfs.localhost:8080", "/big.txt"); body(t, resp) != string(big) {
+		t.Fatalf("subdomain request served with status %d", resp.StatusCode)
+	}
+}
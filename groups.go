@@ -0,0 +1,68 @@
+package bitswap
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// groupBytes returns the session's group as it is named on the wire, empty
+// if it has none.
+func (s *Session) groupBytes() []byte {
+	if !s.group.Defined() {
+		return nil
+	}
+	return s.group.Bytes()
+}
+
+// groupsOf returns the group of the databases described by hs, answering an
+// offer of the group offered, and those the peer advertises.
+func groupsOf(hs bitswap_message_pb.Message_PIRHandshake, offered cid.Cid) (cid.Cid, []cid.Cid, error) {
+	var group cid.Cid
+	if len(hs.Group) > 0 {
+		var err error
+		if group, err = cid.Cast(hs.Group); err != nil {
+			return cid.Undef, nil, fmt.Errorf("%w: group: %v", pir.ErrMalformed, err)
+		}
+		if group != offered {
+			return cid.Undef, nil, fmt.Errorf("%w: databases of group %s, not %s", pir.ErrSchemeMismatch, group, offered)
+		}
+	}
+	var groups []cid.Cid
+	for _, g := range hs.Groups {
+		c, err := cid.Cast(g)
+		if err != nil {
+			return cid.Undef, nil, fmt.Errorf("%w: group: %v", pir.ErrMalformed, err)
+		}
+		groups = append(groups, c)
+	}
+	return group, groups, nil
+}
+
+// open unpads element, retrieved from the databases of pp as the block
+// named by c, and checks the block against c. The databases of a content
+// group lay each block out with its proof, which is checked against the
+// group's root too, failing with ErrBadBlock.
+func open(pp PeerParams, c cid.Cid, element []byte) ([]byte, error) {
+	blk, err := pir.UnpadBlock(element)
+	if err != nil {
+		return nil, err
+	}
+	if !pp.Group.Defined() {
+		return blk, verify(c, blk)
+	}
+	blk, path, err := dag.SplitProof(blk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadBlock, err)
+	}
+	if err := verify(c, blk); err != nil {
+		return nil, err
+	}
+	if err := dag.CheckProof(pp.Group, c, path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadBlock, err)
+	}
+	return blk, nil
+}
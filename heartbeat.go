@@ -0,0 +1,71 @@
+package bitswap
+
+import (
+	"errors"
+	"time"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// ErrPingTimeout fails sessions whose peer did not answer a ping on their
+// idle private stream within the session's PingTimeout.
+var ErrPingTimeout = errors.New("peer did not answer ping")
+
+// heard notes that a message was received from the peer, which shows it is
+// alive as well as a ping would.
+func (s *Session) heard() {
+	if s.alive == nil {
+		return
+	}
+	select {
+	case s.alive <- struct{}{}:
+	default:
+	}
+}
+
+// pingLoop pings the peer on the private stream whenever nothing has been
+// received from it for the ping interval, and fails the session if nothing
+// is received within the ping timeout of a ping. It returns once the
+// private stream has ended, closed by done.
+func (s *Session) pingLoop(done <-chan struct{}) {
+	idle := time.NewTimer(s.pingInterval)
+	defer idle.Stop()
+	var nonce uint64
+	for {
+		select {
+		case <-s.alive:
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(s.pingInterval)
+			continue
+		case <-done:
+			return
+		case <-idle.C:
+		}
+
+		nonce++
+		if err := s.writePrivate(&bitswap_message_pb.Message{Ping: nonce}); err != nil {
+			s.fail(err)
+			return
+		}
+		s.metrics.Add("pings_sent", 1)
+		t := time.NewTimer(s.pingTimeout)
+		select {
+		case <-s.alive:
+		case <-done:
+			t.Stop()
+			return
+		case <-t.C:
+			logger.Debugw("peer did not answer ping", "peer", s.peer)
+			s.metrics.Add("pings_unanswered", 1)
+			s.fail(ErrPingTimeout)
+			return
+		}
+		t.Stop()
+		idle.Reset(s.pingInterval)
+	}
+}
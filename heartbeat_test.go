@@ -0,0 +1,61 @@
+package bitswap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// pingStream signals each message written to it, discarding it.
+type pingStream struct {
+	network.Stream
+	written chan struct{}
+}
+
+func (s pingStream) Write(p []byte) (int, error) {
+	// the length prefix of a ping is a single byte, and the ping longer.
+	if len(p) > 1 {
+		s.written <- struct{}{}
+	}
+	return len(p), nil
+}
+
+func (pingStream) Close() error { return nil }
+
+func TestPing(t *testing.T) {
+	s := New(nil, "p", Options{PingInterval: 20 * time.Millisecond, PingTimeout: 50 * time.Millisecond})
+	written := make(chan struct{}, 1)
+	s.private = pingStream{written: written}
+	stopped := make(chan struct{})
+	go func() {
+		s.pingLoop(make(chan struct{}))
+		close(stopped)
+	}()
+
+	// a peer answering pings keeps the session open.
+	for i := 1; i <= 3; i++ {
+		select {
+		case <-written:
+		case <-time.After(time.Second):
+			t.Fatal("idle session sent no ping")
+		}
+		deliver(t, s, bitswap_message_pb.Message{Pong: uint64(i)})
+	}
+	if err := s.failure(); err != nil {
+		t.Fatalf("answered pings failed the session: %v", err)
+	}
+
+	// one which stops answering fails it.
+	<-written
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("unanswered ping left the session open")
+	}
+	if err := s.failure(); !errors.Is(err, ErrPingTimeout) {
+		t.Fatalf("unanswered ping failed the session with %v", err)
+	}
+}
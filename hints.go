@@ -0,0 +1,118 @@
+package bitswap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// DefaultMaxHintSize bounds the hints sessions download when
+// Options.MaxHintSize is not set.
+const DefaultMaxHintSize = 256 << 20
+
+func hintInterest(round bitswap_message_pb.Message_PIRRound, offset uint64) string {
+	return fmt.Sprintf("pir/hint/%s/%d", round, offset)
+}
+
+// fetchHints sets the hints of the databases of pp which have one but lack
+// it, reusing hints cached for other peers holding the same databases,
+// patching those the peer sent before the databases changed, and downloading
+// the rest.
+func (s *Session) fetchHints(ctx context.Context, pp *PeerParams) error {
+	for _, db := range []struct {
+		round  bitswap_message_pb.Message_PIRRound
+		params *pir.Params
+	}{
+		{bitswap_message_pb.Message_IndexRound, &pp.Index},
+		{bitswap_message_pb.Message_BlockRound, &pp.Blocks},
+	} {
+		if len(db.params.HintDigest) == 0 || db.params.Hint != nil {
+			continue
+		}
+		hint, ok := s.params.hint(db.params.HintDigest)
+		if !ok {
+			var err error
+			if hint, err = s.updateHint(ctx, db.round, pp.Epoch, pp.Group, *db.params); err != nil {
+				return err
+			}
+		}
+		db.params.Hint = hint
+	}
+	return nil
+}
+
+// updateHint downloads the changes to the hint of the database described by
+// params, laying out group, since the peer's previous epoch, if the session
+// still holds its hint, and the whole hint if not, or if the changes do not
+// patch it into the hint of params.
+func (s *Session) updateHint(ctx context.Context, round bitswap_message_pb.Message_PIRRound, epoch uint64, group cid.Cid, params pir.Params) ([]byte, error) {
+	old, since, ok := s.params.staleHint(s.peer, params.Scheme, group, round == bitswap_message_pb.Message_BlockRound)
+	if !ok || since == epoch {
+		since = 0
+	}
+	data, from, err := s.downloadHint(ctx, round, epoch, since, params)
+	if err != nil || from == 0 {
+		return data, err
+	}
+	if hint, err := pir.PatchHint(old, data); err == nil && pir.CheckHint(params, hint) {
+		s.metrics.Add("pir_hint_deltas", 1)
+		return hint, nil
+	}
+	data, _, err = s.downloadHint(ctx, round, epoch, 0, params)
+	return data, err
+}
+
+// downloadHint downloads the hint of the database described by params, a
+// message's worth at a time. If since is set, the peer may send the changes
+// to the hint of that epoch instead, which are returned unchecked along with
+// it.
+func (s *Session) downloadHint(ctx context.Context, round bitswap_message_pb.Message_PIRRound, epoch, since uint64, params pir.Params) ([]byte, uint64, error) {
+	var hint []byte
+	total, from := uint64(0), uint64(0)
+	for {
+		offset := uint64(len(hint))
+		m := bitswap_message_pb.Message{PirHintRequests: []bitswap_message_pb.Message_PIRHintRequest{{
+			Scheme: params.Scheme,
+			Round:  round,
+			Epoch:  epoch,
+			Offset: offset,
+			Since:  since,
+			Group:  s.groupBytes(),
+		}}}
+		data, err := s.roundtrip(ctx, &m, "", hintInterest(round, offset))
+		if err != nil {
+			return nil, 0, err
+		}
+		h := bitswap_message_pb.Message_PIRHint{}
+		if err := h.Unmarshal(data[0]); err != nil {
+			return nil, 0, fmt.Errorf("%w: hint: %v", pir.ErrMalformed, err)
+		}
+		if err := s.checkEpoch(epoch, h.Epoch); err != nil {
+			return nil, 0, err
+		}
+		if offset == 0 {
+			total, from = h.Total, h.Since
+		}
+		// every message must make progress through a hint, or its changes,
+		// of a fixed size.
+		if h.Total != total || h.Since != from || (from != 0 && from != since) || total > s.maxHint || h.Offset != offset ||
+			(len(h.Data) == 0 && offset < total) || offset+uint64(len(h.Data)) > total {
+			return nil, 0, fmt.Errorf("%w: hint of %d bytes", pir.ErrMalformed, h.Total)
+		}
+		s.metrics.Add("pir_hint_bytes", float64(len(h.Data)))
+		hint = append(hint, h.Data...)
+		if uint64(len(hint)) == h.Total {
+			break
+		}
+	}
+	if from != 0 {
+		return hint, from, nil
+	}
+	if !pir.CheckHint(params, hint) {
+		return nil, 0, fmt.Errorf("%w: hint does not match its digest", pir.ErrMalformed)
+	}
+	return hint, 0, nil
+}
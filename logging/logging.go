@@ -0,0 +1,195 @@
+// Package logging names the subsystems bitswap clients and servers log
+// under, and configures their levels, the sampling of frequent entries, and
+// sinks which receive entries besides the usual output.
+//
+//	err := logging.Configure(logging.Config{
+//		Levels:   map[string]string{logging.PIR: "debug"},
+//		Sampling: &logging.Sampling{Tick: time.Second, First: 10, Thereafter: 100},
+//	})
+//
+// Loggers are go-log loggers, so their levels may also be set with
+// GOLOG_LOG_LEVEL, and their output is set up as go-log's.
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/ipfs/go-log/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The subsystems which cut across packages. Each package also logs under
+// its own, such as "bitswap-client" and "bitswap-server".
+const (
+	// Framing logs the messages read from and written to streams which
+	// could not be parsed, sent, or matched to a request.
+	Framing = "bitswap-framing"
+	// PIR logs handshakes, PIR answers, and their failures.
+	PIR = "bitswap-pir"
+	// Scheduler logs the queueing of requests for the server's workers.
+	Scheduler = "bitswap-scheduler"
+)
+
+// Config configures the loggers of every subsystem.
+type Config struct {
+	// Levels sets the level, such as "debug" or "warn", of each subsystem
+	// named, or of all of them for "*".
+	Levels map[string]string
+	// Sampling, if set, bounds how often each entry is logged.
+	Sampling *Sampling
+	// Sink, if set, is passed every entry logged, once sampled. It must be
+	// safe for concurrent use, and should return quickly, as the code
+	// logging waits on it.
+	Sink func(Entry)
+}
+
+// Sampling logs the First entries of each subsystem, level and message in
+// every Tick, then one in Thereafter, dropping the rest. Zero Thereafter
+// drops every entry past the First.
+type Sampling struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+// Entry is an entry passed to a sink.
+type Entry struct {
+	Time      time.Time
+	Subsystem string
+	Level     string
+	Message   string
+	Fields    map[string]interface{}
+}
+
+// Logger returns the logger of subsystem.
+func Logger(subsystem string) *zap.SugaredLogger {
+	return log.Logger(subsystem).Desugar().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &core{Core: c, subsystem: subsystem}
+	})).Sugar()
+}
+
+var (
+	mtx     sync.RWMutex
+	sampler *entrySampler
+	sink    func(Entry)
+)
+
+// Configure applies cfg to the loggers of every subsystem, those created
+// before as well as after. Sampling and sinks replace those configured
+// before; subsystems not named keep their levels.
+func Configure(cfg Config) error {
+	for name, level := range cfg.Levels {
+		if err := log.SetLogLevel(name, level); err != nil {
+			return fmt.Errorf("level %s of %s: %w", level, name, err)
+		}
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	sampler = nil
+	if cfg.Sampling != nil {
+		sampler = &entrySampler{Sampling: *cfg.Sampling, counts: make(map[sampleKey]*sampleCount)}
+	}
+	sink = cfg.Sink
+	return nil
+}
+
+// core samples the entries of a subsystem's logger, and passes those kept
+// to the sink, as configured.
+type core struct {
+	zapcore.Core
+	subsystem string
+	// fields are those the logger was given With, for the sink.
+	fields []zapcore.Field
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(fields), subsystem: c.subsystem, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	mtx.RLock()
+	s, out := sampler, sink
+	mtx.RUnlock()
+	if s != nil && !s.allow(c.subsystem, ent) {
+		return ce
+	}
+	ce = c.Core.Check(ent, ce)
+	if out != nil {
+		ce = ce.AddCore(ent, &sinkCore{sink: out, subsystem: c.subsystem, fields: c.fields})
+	}
+	return ce
+}
+
+// sinkCore passes the entries written to it to a sink.
+type sinkCore struct {
+	sink      func(Entry)
+	subsystem string
+	fields    []zapcore.Field
+}
+
+func (s *sinkCore) Enabled(zapcore.Level) bool { return true }
+
+func (s *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sinkCore{sink: s.sink, subsystem: s.subsystem, fields: append(s.fields[:len(s.fields):len(s.fields)], fields...)}
+}
+
+func (s *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, s)
+}
+
+func (s *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range s.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	s.sink(Entry{Time: ent.Time, Subsystem: s.subsystem, Level: ent.Level.String(), Message: ent.Message, Fields: enc.Fields})
+	return nil
+}
+
+func (s *sinkCore) Sync() error { return nil }
+
+type sampleKey struct {
+	subsystem string
+	level     zapcore.Level
+	message   string
+}
+
+type sampleCount struct {
+	// until is when the tick counted ends.
+	until time.Time
+	n     int
+}
+
+// entrySampler counts the entries of each subsystem, level and message in
+// the current tick. Messages are constant strings, so the counts stay few.
+type entrySampler struct {
+	Sampling
+
+	mtx    sync.Mutex
+	counts map[sampleKey]*sampleCount
+}
+
+func (s *entrySampler) allow(subsystem string, ent zapcore.Entry) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key := sampleKey{subsystem, ent.Level, ent.Message}
+	c, ok := s.counts[key]
+	if !ok || !ent.Time.Before(c.until) {
+		c = &sampleCount{until: ent.Time.Add(s.Tick)}
+		s.counts[key] = c
+	}
+	c.n++
+	if c.n <= s.First {
+		return true
+	}
+	return s.Thereafter > 0 && (c.n-s.First)%s.Thereafter == 0
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/ipfs/go-log/v2"
+)
+
+func TestConfigure(t *testing.T) {
+	l := Logger("logging-test").With("peer", "p")
+	var mtx sync.Mutex
+	var got []Entry
+	sink := func(e Entry) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		got = append(got, e)
+	}
+	err := Configure(Config{
+		Levels:   map[string]string{"logging-test": "debug"},
+		Sampling: &Sampling{Tick: time.Hour, First: 2, Thereafter: 3},
+		Sink:     sink,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := Configure(Config{Levels: map[string]string{"logging-test": "error"}}); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// the first entries of a message are kept, then one in three.
+	for i := 0; i < 8; i++ {
+		l.Debugw("frequent", "i", i)
+	}
+	l.Infow("rare")
+	if len(got) != 5 {
+		t.Fatalf("sink got %d entries", len(got))
+	}
+	for i, want := range []int{0, 1, 4, 7} {
+		if got[i].Message != "frequent" || got[i].Fields["i"] != int64(want) || got[i].Fields["peer"] != "p" {
+			t.Fatalf("entry %d is %v", i, got[i])
+		}
+	}
+	if e := got[4]; e.Message != "rare" || e.Level != "info" || e.Subsystem != "logging-test" {
+		t.Fatalf("entry %v", e)
+	}
+
+	// entries under the subsystem's level are not logged.
+	if err := Configure(Config{Levels: map[string]string{"logging-test": "warn"}, Sink: sink}); err != nil {
+		t.Fatal(err)
+	}
+	l.Infow("rare")
+	l.Warnw("rare")
+	if len(got) != 6 {
+		t.Fatalf("sink got %d entries", len(got))
+	}
+
+	if err := Configure(Config{Levels: map[string]string{"no-such-subsystem": "debug"}}); !errors.Is(err, log.ErrNoSuchLogger) {
+		t.Fatalf("unknown subsystem configured with %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package bitswap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// manifestHistory is how many epochs of each peer's manifests of a scheme
+// are kept to be compared against.
+const manifestHistory = 16
+
+var (
+	// ErrBadManifest fails handshakes whose manifest is not signed by the
+	// peer, or does not describe the databases handed over with it.
+	ErrBadManifest = errors.New("PIR manifest not signed by the peer")
+	// ErrEquivocation fails handshakes with peers which committed to two
+	// different CID→index maps for the same databases.
+	ErrEquivocation = errors.New("peer committed to conflicting CID→index maps")
+)
+
+// Manifest is a peer's signed commitment to the CID→index map of its PIR
+// databases of one scheme at one epoch. Clients cannot check the map
+// against it, as they never see the map whole, but a peer handing different
+// maps to different clients, to tell them apart by the positions they
+// query, must sign a manifest for each: two validly signed manifests of the
+// same scheme, group and epoch with different digests prove it.
+type Manifest struct {
+	Scheme string
+	// Group is the content group the databases lay out, or cid.Undef for
+	// the peer's whole store.
+	Group     cid.Cid
+	Epoch     uint64
+	Digest    []byte
+	Signature []byte
+}
+
+func manifestFrom(m bitswap_message_pb.Message_PIRManifest) (Manifest, error) {
+	var group cid.Cid
+	if len(m.Group) > 0 {
+		var err error
+		if group, err = cid.Cast(m.Group); err != nil {
+			return Manifest{}, fmt.Errorf("%w: group: %v", ErrBadManifest, err)
+		}
+	}
+	return Manifest{Scheme: m.Scheme, Group: group, Epoch: m.Epoch, Digest: m.Digest, Signature: m.Signature}, nil
+}
+
+// Verify checks that m was signed with the private half of key.
+func (m Manifest) Verify(key crypto.PubKey) error {
+	var group []byte
+	if m.Group.Defined() {
+		group = m.Group.Bytes()
+	}
+	ok, err := key.Verify(bitswap_message_pb.ManifestPayload(m.Scheme, group, m.Epoch, m.Digest), m.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadManifest, err)
+	}
+	if !ok {
+		return ErrBadManifest
+	}
+	return nil
+}
+
+// EquivocationError is an ErrEquivocation, holding the two manifests of
+// Peer which prove it. They may be passed on to others as evidence.
+type EquivocationError struct {
+	Peer          peer.ID
+	First, Second Manifest
+}
+
+func (e *EquivocationError) Error() string {
+	return fmt.Sprintf("%v: %s at epoch %d of %s", ErrEquivocation, e.Peer, e.First.Epoch, e.First.Scheme)
+}
+
+func (e *EquivocationError) Unwrap() error {
+	return ErrEquivocation
+}
+
+type manifestKey struct {
+	peer   peer.ID
+	scheme string
+	group  cid.Cid
+}
+
+// Witness records m, a manifest of p verified with its key, failing with an
+// EquivocationError if p signed another for the same scheme, group and epoch.
+// Sessions witness the manifests of their handshakes; applications may
+// witness those gathered by other clients too, so that sessions sharing the
+// cache catch peers handing their maps out unevenly.
+func (pc *ParamCache) Witness(p peer.ID, m Manifest) error {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	if pc.manifests == nil {
+		pc.manifests = make(map[manifestKey][]Manifest)
+	}
+	key := manifestKey{p, m.Scheme, m.Group}
+	seen := pc.manifests[key]
+	for _, prev := range seen {
+		if prev.Epoch != m.Epoch {
+			continue
+		}
+		if !bytes.Equal(prev.Digest, m.Digest) {
+			return &EquivocationError{Peer: p, First: prev, Second: m}
+		}
+		return nil
+	}
+	seen = append(seen, m)
+	if len(seen) > manifestHistory {
+		// forget the earliest epoch.
+		oldest := 0
+		for i, prev := range seen {
+			if prev.Epoch < seen[oldest].Epoch {
+				oldest = i
+			}
+		}
+		seen = append(seen[:oldest], seen[oldest+1:]...)
+	}
+	pc.manifests[key] = seen
+	return nil
+}
+
+// checkManifest verifies the manifest sent with the handshake hs against the
+// peer's key and witnesses it, returning it for the peer's parameters.
+func (s *Session) checkManifest(hs bitswap_message_pb.Message_PIRHandshake) (*Manifest, error) {
+	if hs.Manifest == nil {
+		return nil, nil
+	}
+	m, err := manifestFrom(*hs.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	if m.Scheme != hs.Index.Scheme || m.Epoch != hs.Epoch || string(hs.Manifest.Group) != string(hs.Group) {
+		return nil, fmt.Errorf("%w: manifest of %s at epoch %d", ErrBadManifest, m.Scheme, m.Epoch)
+	}
+	key, err := s.peerKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadManifest, err)
+	}
+	if err := m.Verify(key); err != nil {
+		return nil, err
+	}
+	if err := s.params.Witness(s.peer, m); err != nil {
+		s.metrics.Add("equivocations", 1)
+		return nil, err
+	}
+	return &m, nil
+}
+
+// peerKey returns the public key of the peer, as learned when connecting to
+// it or, for peers whose IDs embed their keys, from its ID.
+func (s *Session) peerKey() (crypto.PubKey, error) {
+	if s.Host != nil {
+		if key := s.Peerstore().PubKey(s.peer); key != nil {
+			return key, nil
+		}
+	}
+	return s.peer.ExtractPublicKey()
+}
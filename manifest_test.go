@@ -0,0 +1,111 @@
+package bitswap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestManifest(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(nil, p, Options{})
+	handshake := func(epoch uint64, digest string) bitswap_message_pb.Message_PIRHandshake {
+		payload := bitswap_message_pb.ManifestPayload("test", nil, epoch, []byte(digest))
+		sig, err := priv.Sign(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bitswap_message_pb.Message_PIRHandshake{
+			Index:    bitswap_message_pb.Message_PIRParams{Scheme: "test"},
+			Epoch:    epoch,
+			Manifest: &bitswap_message_pb.Message_PIRManifest{Scheme: "test", Epoch: epoch, Digest: []byte(digest), Signature: sig},
+		}
+	}
+
+	// manifests signed by the peer are witnessed, as often as they are sent,
+	first := handshake(1, "map")
+	for i := 0; i < 2; i++ {
+		if m, err := s.checkManifest(first); err != nil || m == nil {
+			t.Fatalf("signed manifest refused: %v", err)
+		}
+	}
+	if _, err := s.checkManifest(handshake(2, "other map")); err != nil {
+		t.Fatalf("manifest of a later epoch refused: %v", err)
+	}
+
+	// but not those signed by others, or describing other databases.
+	forged := handshake(3, "map")
+	forged.Manifest.Signature[0] ^= 1
+	if _, err := s.checkManifest(forged); !errors.Is(err, ErrBadManifest) {
+		t.Fatalf("forged manifest checked with %v", err)
+	}
+	other := handshake(3, "map")
+	other.Epoch = 4
+	if _, err := s.checkManifest(other); !errors.Is(err, ErrBadManifest) {
+		t.Fatalf("manifest of another epoch checked with %v", err)
+	}
+
+	// a second map for the same epoch proves equivocation.
+	_, err = s.checkManifest(handshake(1, "another map"))
+	var ee *EquivocationError
+	if !errors.Is(err, ErrEquivocation) || !errors.As(err, &ee) || string(ee.First.Digest) != "map" || string(ee.Second.Digest) != "another map" {
+		t.Fatalf("equivocation checked with %v", err)
+	}
+}
+
+func TestManifestRestart(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(nil, p, Options{})
+	// handshake signs the manifest of a store loaded afresh from data, as
+	// a server does once restarted.
+	handshake := func(data string) bitswap_message_pb.Message_PIRHandshake {
+		bs := util.NewMemStore(make(map[cid.Cid][]byte))
+		util.Add(bs, []byte(data))
+		st, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap, err := st.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := priv.Sign(bitswap_message_pb.ManifestPayload(fastpir.ID, nil, snap.Epoch, snap.Manifest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bitswap_message_pb.Message_PIRHandshake{
+			Index:    bitswap_message_pb.Message_PIRParams{Scheme: fastpir.ID},
+			Epoch:    snap.Epoch,
+			Manifest: &bitswap_message_pb.Message_PIRManifest{Scheme: fastpir.ID, Epoch: snap.Epoch, Digest: snap.Manifest, Signature: sig},
+		}
+	}
+
+	// an honest server restarted with other blocks is not taken for one
+	// equivocating.
+	for _, data := range []string{"hello world", "hello world 2"} {
+		if _, err := s.checkManifest(handshake(data)); err != nil {
+			t.Fatalf("manifest after a restart refused: %v", err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package bitswap_message_pb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// CompressionZstd identifies zstd compression in the handshake.
+const CompressionZstd = "zstd"
+
+// ErrCompressed fails compressed messages which cannot be decompressed, or
+// which decompress to more than they may hold. It is a pir.ErrMalformed.
+var ErrCompressed = fmt.Errorf("%w: compressed message", pir.ErrMalformed)
+
+var encoder, _ = zstd.NewWriter(nil)
+
+// OffersZstd reports whether offer includes zstd compression.
+func (m *Message_PIROffer) OffersZstd() bool {
+	for _, c := range m.GetCompression() {
+		if c == CompressionZstd {
+			return true
+		}
+	}
+	return false
+}
+
+// Compress moves the wantlist, block presences, hints and subtree ends of m, which are
+// metadata and compress well, into its Zstd field. Blocks and PIR queries
+// and answers are left as they are: ciphertexts gain nothing from
+// compression, and the size of plaintext they compress to could betray it.
+func (m *Message) Compress() error {
+	inner := Message{Wantlist: m.Wantlist, BlockPresences: m.BlockPresences, PirHints: m.PirHints, SubtreeEnds: m.SubtreeEnds}
+	if len(inner.Wantlist.Entries) == 0 && !inner.Wantlist.Full && len(inner.BlockPresences) == 0 && len(inner.PirHints) == 0 && len(inner.SubtreeEnds) == 0 {
+		return nil
+	}
+	data, err := inner.Marshal()
+	if err != nil {
+		return err
+	}
+	m.Zstd = encoder.EncodeAll(data, nil)
+	m.Wantlist, m.BlockPresences, m.PirHints, m.SubtreeEnds = Message_Wantlist{}, nil, nil, nil
+	return nil
+}
+
+// Decompress merges the message compressed in the Zstd field of m, which may
+// be no larger than maxSize decompressed, into m.
+func (m *Message) Decompress(maxSize int) error {
+	if len(m.Zstd) == 0 {
+		return nil
+	}
+	// messages are decompressed as a stream, so that one claiming to
+	// decompress to more than maxSize is found out without holding it.
+	r, err := zstd.NewReader(bytes.NewReader(m.Zstd), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCompressed, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCompressed, err)
+	}
+	if len(data) > maxSize {
+		return fmt.Errorf("%w: content larger than %d bytes", ErrCompressed, maxSize)
+	}
+	inner := Message{}
+	if err := inner.Unmarshal(data); err != nil {
+		return fmt.Errorf("%w: %v", ErrCompressed, err)
+	}
+	m.Wantlist.Entries = append(m.Wantlist.Entries, inner.Wantlist.Entries...)
+	m.Wantlist.Full = m.Wantlist.Full || inner.Wantlist.Full
+	m.BlockPresences = append(m.BlockPresences, inner.BlockPresences...)
+	m.PirHints = append(m.PirHints, inner.PirHints...)
+	m.SubtreeEnds = append(m.SubtreeEnds, inner.SubtreeEnds...)
+	m.Zstd = nil
+	return nil
+}
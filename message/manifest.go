@@ -0,0 +1,29 @@
+package bitswap_message_pb
+
+import "encoding/binary"
+
+// manifestDomain separates manifest signatures from others made with the
+// same peer key, and groupManifestDomain those of content groups from those
+// of whole stores.
+const (
+	manifestDomain      = "bitswap-pir/manifest/v1"
+	groupManifestDomain = "bitswap-pir/manifest/v1/group"
+)
+
+// ManifestPayload returns what servers sign to commit to digest as the
+// CID→index map of their databases of scheme at epoch, laying out the
+// content group group, or their whole store if it is empty.
+func ManifestPayload(scheme string, group []byte, epoch uint64, digest []byte) []byte {
+	var n [binary.MaxVarintLen64]byte
+	b := []byte(manifestDomain)
+	if len(group) > 0 {
+		b = append([]byte(groupManifestDomain), n[:binary.PutUvarint(n[:], uint64(len(group)))]...)
+		b = append(b, group...)
+	}
+	b = append(b, n[:binary.PutUvarint(n[:], uint64(len(scheme)))]...)
+	b = append(b, scheme...)
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], epoch)
+	b = append(b, e[:]...)
+	return append(b, digest...)
+}
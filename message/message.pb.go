@@ -48,6 +48,80 @@ func (Message_BlockPresenceType) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_33c57e4bae7b9afd, []int{0, 0}
 }
 
+type Message_PIRRound int32
+
+const (
+	Message_IndexRound      Message_PIRRound = 0
+	Message_BlockRound      Message_PIRRound = 1
+	Message_BatchIndexRound Message_PIRRound = 2
+	Message_BatchBlockRound Message_PIRRound = 3
+)
+
+var Message_PIRRound_name = map[int32]string{
+	0: "IndexRound",
+	1: "BlockRound",
+	2: "BatchIndexRound",
+	3: "BatchBlockRound",
+}
+
+var Message_PIRRound_value = map[string]int32{
+	"IndexRound":      0,
+	"BlockRound":      1,
+	"BatchIndexRound": 2,
+	"BatchBlockRound": 3,
+}
+
+func (x Message_PIRRound) String() string {
+	return proto.EnumName(Message_PIRRound_name, int32(x))
+}
+
+func (Message_PIRRound) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 1}
+}
+
+type Message_ErrorCode int32
+
+const (
+	Message_InternalError    Message_ErrorCode = 0
+	Message_SchemeMismatch   Message_ErrorCode = 1
+	Message_DatabaseTooLarge Message_ErrorCode = 2
+	Message_RateLimited      Message_ErrorCode = 3
+	Message_StaleEpoch       Message_ErrorCode = 4
+	Message_Busy             Message_ErrorCode = 5
+	Message_BadRequest       Message_ErrorCode = 6
+	Message_Unauthorized     Message_ErrorCode = 7
+)
+
+var Message_ErrorCode_name = map[int32]string{
+	0: "InternalError",
+	1: "SchemeMismatch",
+	2: "DatabaseTooLarge",
+	3: "RateLimited",
+	4: "StaleEpoch",
+	5: "Busy",
+	6: "BadRequest",
+	7: "Unauthorized",
+}
+
+var Message_ErrorCode_value = map[string]int32{
+	"InternalError":    0,
+	"SchemeMismatch":   1,
+	"DatabaseTooLarge": 2,
+	"RateLimited":      3,
+	"StaleEpoch":       4,
+	"Busy":             5,
+	"BadRequest":       6,
+	"Unauthorized":     7,
+}
+
+func (x Message_ErrorCode) String() string {
+	return proto.EnumName(Message_ErrorCode_name, int32(x))
+}
+
+func (Message_ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 2}
+}
+
 type Message_Wantlist_WantType int32
 
 const (
@@ -74,11 +148,25 @@ func (Message_Wantlist_WantType) EnumDescriptor() ([]byte, []int) {
 }
 
 type Message struct {
-	Wantlist       Message_Wantlist        `protobuf:"bytes,1,opt,name=wantlist,proto3" json:"wantlist"`
-	Blocks         [][]byte                `protobuf:"bytes,2,rep,name=blocks,proto3" json:"blocks,omitempty"`
-	Payload        []Message_Block         `protobuf:"bytes,3,rep,name=payload,proto3" json:"payload"`
-	BlockPresences []Message_BlockPresence `protobuf:"bytes,4,rep,name=blockPresences,proto3" json:"blockPresences"`
-	PendingBytes   int32                   `protobuf:"varint,5,opt,name=pendingBytes,proto3" json:"pendingBytes,omitempty"`
+	Wantlist        Message_Wantlist         `protobuf:"bytes,1,opt,name=wantlist,proto3" json:"wantlist"`
+	Blocks          [][]byte                 `protobuf:"bytes,2,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	Payload         []Message_Block          `protobuf:"bytes,3,rep,name=payload,proto3" json:"payload"`
+	BlockPresences  []Message_BlockPresence  `protobuf:"bytes,4,rep,name=blockPresences,proto3" json:"blockPresences"`
+	PendingBytes    int32                    `protobuf:"varint,5,opt,name=pendingBytes,proto3" json:"pendingBytes,omitempty"`
+	PirRequests     []Message_PIRRequest     `protobuf:"bytes,6,rep,name=pirRequests,proto3" json:"pirRequests"`
+	PirResponses    []Message_PIRResponse    `protobuf:"bytes,7,rep,name=pirResponses,proto3" json:"pirResponses"`
+	PirHandshake    *Message_PIRHandshake    `protobuf:"bytes,8,opt,name=pirHandshake,proto3" json:"pirHandshake,omitempty"`
+	Chunks          []Message_BlockChunk     `protobuf:"bytes,9,rep,name=chunks,proto3" json:"chunks"`
+	PirProgress     []Message_PIRProgress    `protobuf:"bytes,10,rep,name=pirProgress,proto3" json:"pirProgress"`
+	InlineReplies   bool                     `protobuf:"varint,11,opt,name=inlineReplies,proto3" json:"inlineReplies,omitempty"`
+	Padding         [][]byte                 `protobuf:"bytes,12,rep,name=padding,proto3" json:"padding,omitempty"`
+	PirHintRequests []Message_PIRHintRequest `protobuf:"bytes,13,rep,name=pirHintRequests,proto3" json:"pirHintRequests"`
+	PirHints        []Message_PIRHint        `protobuf:"bytes,14,rep,name=pirHints,proto3" json:"pirHints"`
+	Ping            uint64                   `protobuf:"varint,15,opt,name=ping,proto3" json:"ping,omitempty"`
+	Pong            uint64                   `protobuf:"varint,16,opt,name=pong,proto3" json:"pong,omitempty"`
+	Errors          []Message_Error          `protobuf:"bytes,17,rep,name=errors,proto3" json:"errors"`
+	Zstd            []byte                   `protobuf:"bytes,18,opt,name=zstd,proto3" json:"zstd,omitempty"`
+	SubtreeEnds     []Message_SubtreeEnd     `protobuf:"bytes,19,rep,name=subtreeEnds,proto3" json:"subtreeEnds"`
 }
 
 func (m *Message) Reset()         { *m = Message{} }
@@ -149,6 +237,104 @@ func (m *Message) GetPendingBytes() int32 {
 	return 0
 }
 
+func (m *Message) GetPirRequests() []Message_PIRRequest {
+	if m != nil {
+		return m.PirRequests
+	}
+	return nil
+}
+
+func (m *Message) GetPirResponses() []Message_PIRResponse {
+	if m != nil {
+		return m.PirResponses
+	}
+	return nil
+}
+
+func (m *Message) GetPirHandshake() *Message_PIRHandshake {
+	if m != nil {
+		return m.PirHandshake
+	}
+	return nil
+}
+
+func (m *Message) GetChunks() []Message_BlockChunk {
+	if m != nil {
+		return m.Chunks
+	}
+	return nil
+}
+
+func (m *Message) GetPirProgress() []Message_PIRProgress {
+	if m != nil {
+		return m.PirProgress
+	}
+	return nil
+}
+
+func (m *Message) GetInlineReplies() bool {
+	if m != nil {
+		return m.InlineReplies
+	}
+	return false
+}
+
+func (m *Message) GetPadding() [][]byte {
+	if m != nil {
+		return m.Padding
+	}
+	return nil
+}
+
+func (m *Message) GetPirHintRequests() []Message_PIRHintRequest {
+	if m != nil {
+		return m.PirHintRequests
+	}
+	return nil
+}
+
+func (m *Message) GetPirHints() []Message_PIRHint {
+	if m != nil {
+		return m.PirHints
+	}
+	return nil
+}
+
+func (m *Message) GetPing() uint64 {
+	if m != nil {
+		return m.Ping
+	}
+	return 0
+}
+
+func (m *Message) GetPong() uint64 {
+	if m != nil {
+		return m.Pong
+	}
+	return 0
+}
+
+func (m *Message) GetErrors() []Message_Error {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+func (m *Message) GetZstd() []byte {
+	if m != nil {
+		return m.Zstd
+	}
+	return nil
+}
+
+func (m *Message) GetSubtreeEnds() []Message_SubtreeEnd {
+	if m != nil {
+		return m.SubtreeEnds
+	}
+	return nil
+}
+
 type Message_Wantlist struct {
 	Entries []Message_Wantlist_Entry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries"`
 	Full    bool                     `protobuf:"varint,2,opt,name=full,proto3" json:"full,omitempty"`
@@ -207,6 +393,8 @@ type Message_Wantlist_Entry struct {
 	Cancel       bool                      `protobuf:"varint,3,opt,name=cancel,proto3" json:"cancel,omitempty"`
 	WantType     Message_Wantlist_WantType `protobuf:"varint,4,opt,name=wantType,proto3,enum=bitswap.message.pb.Message_Wantlist_WantType" json:"wantType,omitempty"`
 	SendDontHave bool                      `protobuf:"varint,5,opt,name=sendDontHave,proto3" json:"sendDontHave,omitempty"`
+	Depth        uint32                    `protobuf:"varint,6,opt,name=depth,proto3" json:"depth,omitempty"`
+	MaxBytes     uint64                    `protobuf:"varint,7,opt,name=maxBytes,proto3" json:"maxBytes,omitempty"`
 }
 
 func (m *Message_Wantlist_Entry) Reset()         { *m = Message_Wantlist_Entry{} }
@@ -270,6 +458,20 @@ func (m *Message_Wantlist_Entry) GetSendDontHave() bool {
 	return false
 }
 
+func (m *Message_Wantlist_Entry) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *Message_Wantlist_Entry) GetMaxBytes() uint64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
 type Message_Block struct {
 	Prefix []byte `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
 	Data   []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
@@ -367,467 +569,6710 @@ func (m *Message_BlockPresence) GetType() Message_BlockPresenceType {
 	return Message_Have
 }
 
-func init() {
-	proto.RegisterEnum("bitswap.message.pb.Message_BlockPresenceType", Message_BlockPresenceType_name, Message_BlockPresenceType_value)
-	proto.RegisterEnum("bitswap.message.pb.Message_Wantlist_WantType", Message_Wantlist_WantType_name, Message_Wantlist_WantType_value)
-	proto.RegisterType((*Message)(nil), "bitswap.message.pb.Message")
-	proto.RegisterType((*Message_Wantlist)(nil), "bitswap.message.pb.Message.Wantlist")
-	proto.RegisterType((*Message_Wantlist_Entry)(nil), "bitswap.message.pb.Message.Wantlist.Entry")
-	proto.RegisterType((*Message_Block)(nil), "bitswap.message.pb.Message.Block")
-	proto.RegisterType((*Message_BlockPresence)(nil), "bitswap.message.pb.Message.BlockPresence")
+type Message_BlockChunk struct {
+	Cid    Cid    `protobuf:"bytes,1,opt,name=cid,proto3,customtype=Cid" json:"cid"`
+	Offset uint64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Total  uint64 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	Data   []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
 }
 
-func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
-
-var fileDescriptor_33c57e4bae7b9afd = []byte{
-	// 497 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x93, 0xdf, 0x8a, 0xd3, 0x40,
-	0x14, 0xc6, 0x33, 0x4d, 0xd2, 0xc6, 0xd3, 0xee, 0x52, 0xe7, 0x42, 0x42, 0xc0, 0x34, 0x5b, 0xbc,
-	0x88, 0xca, 0x66, 0xa1, 0xfb, 0x04, 0x5b, 0xff, 0xa0, 0x82, 0x20, 0x83, 0xd0, 0xeb, 0xfc, 0x99,
-	0xd6, 0xc1, 0x6c, 0x12, 0x33, 0x53, 0xd7, 0xbe, 0x85, 0x8f, 0xb5, 0x37, 0xc2, 0x5e, 0x8a, 0xca,
-	0x22, 0xed, 0x8b, 0x48, 0x4e, 0xa6, 0x85, 0xba, 0xe2, 0xee, 0xdd, 0x9c, 0x33, 0xe7, 0xfb, 0x65,
-	0xbe, 0xef, 0x10, 0x38, 0x38, 0xe7, 0x52, 0xc6, 0x0b, 0x1e, 0x55, 0x75, 0xa9, 0x4a, 0x4a, 0x13,
-	0xa1, 0xe4, 0x45, 0x5c, 0x45, 0xbb, 0x76, 0xe2, 0x1d, 0x2f, 0x84, 0xfa, 0xb0, 0x4c, 0xa2, 0xb4,
-	0x3c, 0x3f, 0x59, 0x94, 0x8b, 0xf2, 0x04, 0x47, 0x93, 0xe5, 0x1c, 0x2b, 0x2c, 0xf0, 0xd4, 0x22,
-	0xc6, 0xbf, 0xba, 0xd0, 0x7b, 0xdb, 0xaa, 0xe9, 0x4b, 0x70, 0x2e, 0xe2, 0x42, 0xe5, 0x42, 0x2a,
-	0x97, 0x04, 0x24, 0xec, 0x4f, 0x1e, 0x45, 0x37, 0xbf, 0x10, 0xe9, 0xf1, 0x68, 0xa6, 0x67, 0xa7,
-	0xd6, 0xe5, 0xf5, 0xc8, 0x60, 0x3b, 0x2d, 0x7d, 0x00, 0xdd, 0x24, 0x2f, 0xd3, 0x8f, 0xd2, 0xed,
-	0x04, 0x66, 0x38, 0x60, 0xba, 0xa2, 0x67, 0xd0, 0xab, 0xe2, 0x55, 0x5e, 0xc6, 0x99, 0x6b, 0x06,
-	0x66, 0xd8, 0x9f, 0x1c, 0xfd, 0x0f, 0x3f, 0x6d, 0x44, 0x9a, 0xbd, 0xd5, 0xd1, 0x19, 0x1c, 0x22,
-	0xec, 0x5d, 0xcd, 0x25, 0x2f, 0x52, 0x2e, 0x5d, 0x0b, 0x49, 0x8f, 0x6f, 0x25, 0x6d, 0x15, 0x9a,
-	0xf8, 0x17, 0x86, 0x8e, 0x61, 0x50, 0xf1, 0x22, 0x13, 0xc5, 0x62, 0xba, 0x52, 0x5c, 0xba, 0x76,
-	0x40, 0x42, 0x9b, 0xed, 0xf5, 0xbc, 0x9f, 0x1d, 0x70, 0xb6, 0xa6, 0xe9, 0x1b, 0xe8, 0xf1, 0x42,
-	0xd5, 0x82, 0x4b, 0x97, 0xe0, 0x13, 0x9e, 0xdc, 0x25, 0xab, 0xe8, 0x45, 0xa1, 0xea, 0xd5, 0xd6,
-	0x95, 0x06, 0x50, 0x0a, 0xd6, 0x7c, 0x99, 0xe7, 0x6e, 0x27, 0x20, 0xa1, 0xc3, 0xf0, 0xec, 0x7d,
-	0x23, 0x60, 0xe3, 0x30, 0x3d, 0x02, 0x1b, 0x1f, 0x8b, 0x3b, 0x19, 0x4c, 0xfb, 0x8d, 0xf6, 0xc7,
-	0xf5, 0xc8, 0x7c, 0x26, 0x32, 0xd6, 0xde, 0x50, 0x0f, 0x9c, 0xaa, 0x16, 0x65, 0x2d, 0xd4, 0x0a,
-	0x21, 0x36, 0xdb, 0xd5, 0xcd, 0x36, 0xd2, 0xb8, 0x48, 0x79, 0xee, 0x9a, 0x88, 0xd7, 0x15, 0x7d,
-	0xdd, 0x6e, 0xfb, 0xfd, 0xaa, 0xe2, 0xae, 0x15, 0x90, 0xf0, 0x70, 0x72, 0x7c, 0x27, 0x07, 0x33,
-	0x2d, 0x62, 0x3b, 0x79, 0x13, 0x9e, 0xe4, 0x45, 0xf6, 0xbc, 0x2c, 0xd4, 0xab, 0xf8, 0x33, 0xc7,
-	0xf0, 0x1c, 0xb6, 0xd7, 0x1b, 0x8f, 0xda, 0xec, 0x70, 0xfe, 0x1e, 0xd8, 0xb8, 0x93, 0xa1, 0x41,
-	0x1d, 0xb0, 0x9a, 0xeb, 0x21, 0xf1, 0x4e, 0x75, 0xb3, 0x79, 0x70, 0x55, 0xf3, 0xb9, 0xf8, 0xd2,
-	0x1a, 0x66, 0xba, 0x6a, 0x52, 0xca, 0x62, 0x15, 0xa3, 0xc1, 0x01, 0xc3, 0xb3, 0xf7, 0x09, 0x0e,
-	0xf6, 0xb6, 0x4b, 0x1f, 0x82, 0x99, 0x8a, 0xec, 0x5f, 0x51, 0x35, 0x7d, 0x7a, 0x06, 0x96, 0x6a,
-	0x0c, 0x77, 0x6e, 0x37, 0xbc, 0xc7, 0x45, 0xc3, 0x28, 0x1d, 0x3f, 0x85, 0xfb, 0x37, 0xae, 0x76,
-	0x36, 0x0c, 0x3a, 0x00, 0x67, 0xeb, 0x79, 0x48, 0xa6, 0xee, 0xe5, 0xda, 0x27, 0x57, 0x6b, 0x9f,
-	0xfc, 0x5e, 0xfb, 0xe4, 0xeb, 0xc6, 0x37, 0xae, 0x36, 0xbe, 0xf1, 0x7d, 0xe3, 0x1b, 0x49, 0x17,
-	0xff, 0xbf, 0xd3, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x8a, 0x8a, 0xaf, 0x83, 0xd3, 0x03, 0x00,
-	0x00,
+func (m *Message_BlockChunk) Reset()         { *m = Message_BlockChunk{} }
+func (m *Message_BlockChunk) String() string { return proto.CompactTextString(m) }
+func (*Message_BlockChunk) ProtoMessage()    {}
+func (*Message_BlockChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 3}
+}
+func (m *Message_BlockChunk) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_BlockChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_BlockChunk.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_BlockChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_BlockChunk.Merge(m, src)
+}
+func (m *Message_BlockChunk) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_BlockChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_BlockChunk.DiscardUnknown(m)
 }
 
-func (m *Message) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_Message_BlockChunk proto.InternalMessageInfo
+
+func (m *Message_BlockChunk) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *Message) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *Message_BlockChunk) GetTotal() uint64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
 }
 
-func (m *Message) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.PendingBytes != 0 {
-		i = encodeVarintMessage(dAtA, i, uint64(m.PendingBytes))
-		i--
-		dAtA[i] = 0x28
+func (m *Message_BlockChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
 	}
-	if len(m.BlockPresences) > 0 {
-		for iNdEx := len(m.BlockPresences) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.BlockPresences[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintMessage(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x22
+	return nil
+}
+
+type Message_PIRRequest struct {
+	Session uint64           `protobuf:"varint,1,opt,name=session,proto3" json:"session,omitempty"`
+	Round   Message_PIRRound `protobuf:"varint,2,opt,name=round,proto3,enum=bitswap.message.pb.Message_PIRRound" json:"round,omitempty"`
+	Query   []byte           `protobuf:"bytes,3,opt,name=query,proto3" json:"query,omitempty"`
+	Part    uint32           `protobuf:"varint,4,opt,name=part,proto3" json:"part,omitempty"`
+	Cancel  bool             `protobuf:"varint,5,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	Scheme  string           `protobuf:"bytes,6,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Epoch   uint64           `protobuf:"varint,7,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Id      uint64           `protobuf:"varint,8,opt,name=id,proto3" json:"id,omitempty"`
+	Group   []byte           `protobuf:"bytes,9,opt,name=group,proto3" json:"group,omitempty"`
+	Receipt bool             `protobuf:"varint,10,opt,name=receipt,proto3" json:"receipt,omitempty"`
+}
+
+func (m *Message_PIRRequest) Reset()         { *m = Message_PIRRequest{} }
+func (m *Message_PIRRequest) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRRequest) ProtoMessage()    {}
+func (*Message_PIRRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 4}
+}
+func (m *Message_PIRRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	if len(m.Payload) > 0 {
-		for iNdEx := len(m.Payload) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Payload[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintMessage(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x1a
-		}
+}
+func (m *Message_PIRRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRRequest.Merge(m, src)
+}
+func (m *Message_PIRRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRRequest proto.InternalMessageInfo
+
+func (m *Message_PIRRequest) GetSession() uint64 {
+	if m != nil {
+		return m.Session
 	}
-	if len(m.Blocks) > 0 {
-		for iNdEx := len(m.Blocks) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Blocks[iNdEx])
-			copy(dAtA[i:], m.Blocks[iNdEx])
-			i = encodeVarintMessage(dAtA, i, uint64(len(m.Blocks[iNdEx])))
-			i--
-			dAtA[i] = 0x12
-		}
+	return 0
+}
+
+func (m *Message_PIRRequest) GetRound() Message_PIRRound {
+	if m != nil {
+		return m.Round
 	}
-	{
-		size, err := m.Wantlist.MarshalToSizedBuffer(dAtA[:i])
-		if err != nil {
-			return 0, err
-		}
-		i -= size
-		i = encodeVarintMessage(dAtA, i, uint64(size))
+	return Message_IndexRound
+}
+
+func (m *Message_PIRRequest) GetQuery() []byte {
+	if m != nil {
+		return m.Query
 	}
-	i--
-	dAtA[i] = 0xa
-	return len(dAtA) - i, nil
+	return nil
 }
 
-func (m *Message_Wantlist) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *Message_PIRRequest) GetPart() uint32 {
+	if m != nil {
+		return m.Part
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *Message_Wantlist) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *Message_PIRRequest) GetCancel() bool {
+	if m != nil {
+		return m.Cancel
+	}
+	return false
 }
 
-func (m *Message_Wantlist) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.Full {
-		i--
-		if m.Full {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x10
-	}
-	if len(m.Entries) > 0 {
-		for iNdEx := len(m.Entries) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Entries[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintMessage(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
-		}
+func (m *Message_PIRRequest) GetScheme() string {
+	if m != nil {
+		return m.Scheme
 	}
-	return len(dAtA) - i, nil
+	return ""
 }
 
-func (m *Message_Wantlist_Entry) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *Message_PIRRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *Message_Wantlist_Entry) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *Message_PIRRequest) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
 }
 
-func (m *Message_Wantlist_Entry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.SendDontHave {
-		i--
-		if m.SendDontHave {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x28
+func (m *Message_PIRRequest) GetGroup() []byte {
+	if m != nil {
+		return m.Group
 	}
-	if m.WantType != 0 {
-		i = encodeVarintMessage(dAtA, i, uint64(m.WantType))
-		i--
-		dAtA[i] = 0x20
+	return nil
+}
+
+func (m *Message_PIRRequest) GetReceipt() bool {
+	if m != nil {
+		return m.Receipt
 	}
-	if m.Cancel {
-		i--
-		if m.Cancel {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	return false
+}
+
+type Message_PIRResponse struct {
+	Session uint64              `protobuf:"varint,1,opt,name=session,proto3" json:"session,omitempty"`
+	Round   Message_PIRRound    `protobuf:"varint,2,opt,name=round,proto3,enum=bitswap.message.pb.Message_PIRRound" json:"round,omitempty"`
+	Answer  []byte              `protobuf:"bytes,3,opt,name=answer,proto3" json:"answer,omitempty"`
+	Part    uint32              `protobuf:"varint,4,opt,name=part,proto3" json:"part,omitempty"`
+	Epoch   uint64              `protobuf:"varint,5,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Offset  uint64              `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`
+	Total   uint64              `protobuf:"varint,7,opt,name=total,proto3" json:"total,omitempty"`
+	Id      uint64              `protobuf:"varint,8,opt,name=id,proto3" json:"id,omitempty"`
+	Receipt *Message_PIRReceipt `protobuf:"bytes,9,opt,name=receipt,proto3" json:"receipt,omitempty"`
+}
+
+func (m *Message_PIRResponse) Reset()         { *m = Message_PIRResponse{} }
+func (m *Message_PIRResponse) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRResponse) ProtoMessage()    {}
+func (*Message_PIRResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 5}
+}
+func (m *Message_PIRResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
-		i--
-		dAtA[i] = 0x18
+		return b[:n], nil
 	}
-	if m.Priority != 0 {
-		i = encodeVarintMessage(dAtA, i, uint64(m.Priority))
-		i--
-		dAtA[i] = 0x10
+}
+func (m *Message_PIRResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRResponse.Merge(m, src)
+}
+func (m *Message_PIRResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRResponse proto.InternalMessageInfo
+
+func (m *Message_PIRResponse) GetSession() uint64 {
+	if m != nil {
+		return m.Session
 	}
-	{
-		size := m.Block.Size()
-		i -= size
-		if _, err := m.Block.MarshalTo(dAtA[i:]); err != nil {
-			return 0, err
-		}
-		i = encodeVarintMessage(dAtA, i, uint64(size))
+	return 0
+}
+
+func (m *Message_PIRResponse) GetRound() Message_PIRRound {
+	if m != nil {
+		return m.Round
 	}
-	i--
-	dAtA[i] = 0xa
-	return len(dAtA) - i, nil
+	return Message_IndexRound
 }
 
-func (m *Message_Block) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *Message_PIRResponse) GetAnswer() []byte {
+	if m != nil {
+		return m.Answer
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *Message_Block) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *Message_PIRResponse) GetPart() uint32 {
+	if m != nil {
+		return m.Part
+	}
+	return 0
 }
 
-func (m *Message_Block) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if len(m.Data) > 0 {
-		i -= len(m.Data)
-		copy(dAtA[i:], m.Data)
-		i = encodeVarintMessage(dAtA, i, uint64(len(m.Data)))
-		i--
-		dAtA[i] = 0x12
+func (m *Message_PIRResponse) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
 	}
-	if len(m.Prefix) > 0 {
-		i -= len(m.Prefix)
-		copy(dAtA[i:], m.Prefix)
-		i = encodeVarintMessage(dAtA, i, uint64(len(m.Prefix)))
-		i--
-		dAtA[i] = 0xa
+	return 0
+}
+
+func (m *Message_PIRResponse) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
 	}
-	return len(dAtA) - i, nil
+	return 0
 }
 
-func (m *Message_BlockPresence) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *Message_PIRResponse) GetTotal() uint64 {
+	if m != nil {
+		return m.Total
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *Message_BlockPresence) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *Message_PIRResponse) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
 }
 
-func (m *Message_BlockPresence) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.Type != 0 {
-		i = encodeVarintMessage(dAtA, i, uint64(m.Type))
-		i--
-		dAtA[i] = 0x10
+func (m *Message_PIRResponse) GetReceipt() *Message_PIRReceipt {
+	if m != nil {
+		return m.Receipt
 	}
-	{
-		size := m.Cid.Size()
-		i -= size
-		if _, err := m.Cid.MarshalTo(dAtA[i:]); err != nil {
-			return 0, err
+	return nil
+}
+
+type Message_PIRReceipt struct {
+	Scheme    string `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Group     []byte `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Epoch     uint64 `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Query     []byte `protobuf:"bytes,4,opt,name=query,proto3" json:"query,omitempty"`
+	Answer    []byte `protobuf:"bytes,5,opt,name=answer,proto3" json:"answer,omitempty"`
+	Timestamp uint64 `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Signature []byte `protobuf:"bytes,7,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *Message_PIRReceipt) Reset()         { *m = Message_PIRReceipt{} }
+func (m *Message_PIRReceipt) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRReceipt) ProtoMessage()    {}
+func (*Message_PIRReceipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 6}
+}
+func (m *Message_PIRReceipt) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRReceipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRReceipt.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
-		i = encodeVarintMessage(dAtA, i, uint64(size))
+		return b[:n], nil
 	}
-	i--
-	dAtA[i] = 0xa
-	return len(dAtA) - i, nil
+}
+func (m *Message_PIRReceipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRReceipt.Merge(m, src)
+}
+func (m *Message_PIRReceipt) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRReceipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRReceipt.DiscardUnknown(m)
 }
 
-func encodeVarintMessage(dAtA []byte, offset int, v uint64) int {
-	offset -= sovMessage(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+var xxx_messageInfo_Message_PIRReceipt proto.InternalMessageInfo
+
+func (m *Message_PIRReceipt) GetScheme() string {
+	if m != nil {
+		return m.Scheme
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return ""
 }
-func (m *Message) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	l = m.Wantlist.Size()
-	n += 1 + l + sovMessage(uint64(l))
-	if len(m.Blocks) > 0 {
-		for _, b := range m.Blocks {
-			l = len(b)
-			n += 1 + l + sovMessage(uint64(l))
-		}
-	}
-	if len(m.Payload) > 0 {
-		for _, e := range m.Payload {
-			l = e.Size()
-			n += 1 + l + sovMessage(uint64(l))
-		}
+
+func (m *Message_PIRReceipt) GetGroup() []byte {
+	if m != nil {
+		return m.Group
 	}
-	if len(m.BlockPresences) > 0 {
-		for _, e := range m.BlockPresences {
-			l = e.Size()
-			n += 1 + l + sovMessage(uint64(l))
-		}
+	return nil
+}
+
+func (m *Message_PIRReceipt) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
 	}
-	if m.PendingBytes != 0 {
-		n += 1 + sovMessage(uint64(m.PendingBytes))
+	return 0
+}
+
+func (m *Message_PIRReceipt) GetQuery() []byte {
+	if m != nil {
+		return m.Query
 	}
-	return n
+	return nil
 }
 
-func (m *Message_Wantlist) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Message_PIRReceipt) GetAnswer() []byte {
+	if m != nil {
+		return m.Answer
 	}
-	var l int
-	_ = l
-	if len(m.Entries) > 0 {
-		for _, e := range m.Entries {
-			l = e.Size()
-			n += 1 + l + sovMessage(uint64(l))
+	return nil
+}
+
+func (m *Message_PIRReceipt) GetTimestamp() uint64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Message_PIRReceipt) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type Message_PIRProgress struct {
+	Session       uint64           `protobuf:"varint,1,opt,name=session,proto3" json:"session,omitempty"`
+	Round         Message_PIRRound `protobuf:"varint,2,opt,name=round,proto3,enum=bitswap.message.pb.Message_PIRRound" json:"round,omitempty"`
+	ElapsedMillis uint64           `protobuf:"varint,3,opt,name=elapsedMillis,proto3" json:"elapsedMillis,omitempty"`
+}
+
+func (m *Message_PIRProgress) Reset()         { *m = Message_PIRProgress{} }
+func (m *Message_PIRProgress) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRProgress) ProtoMessage()    {}
+func (*Message_PIRProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 7}
+}
+func (m *Message_PIRProgress) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRProgress.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	if m.Full {
-		n += 2
+}
+func (m *Message_PIRProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRProgress.Merge(m, src)
+}
+func (m *Message_PIRProgress) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRProgress proto.InternalMessageInfo
+
+func (m *Message_PIRProgress) GetSession() uint64 {
+	if m != nil {
+		return m.Session
 	}
-	return n
+	return 0
 }
 
-func (m *Message_Wantlist_Entry) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Message_PIRProgress) GetRound() Message_PIRRound {
+	if m != nil {
+		return m.Round
 	}
-	var l int
-	_ = l
-	l = m.Block.Size()
-	n += 1 + l + sovMessage(uint64(l))
-	if m.Priority != 0 {
-		n += 1 + sovMessage(uint64(m.Priority))
+	return Message_IndexRound
+}
+
+func (m *Message_PIRProgress) GetElapsedMillis() uint64 {
+	if m != nil {
+		return m.ElapsedMillis
 	}
-	if m.Cancel {
-		n += 2
+	return 0
+}
+
+type Message_PIRParams struct {
+	Scheme      string `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	NumElements uint64 `protobuf:"varint,2,opt,name=numElements,proto3" json:"numElements,omitempty"`
+	ElementSize uint64 `protobuf:"varint,3,opt,name=elementSize,proto3" json:"elementSize,omitempty"`
+	Extra       []byte `protobuf:"bytes,4,opt,name=extra,proto3" json:"extra,omitempty"`
+	HintDigest  []byte `protobuf:"bytes,5,opt,name=hintDigest,proto3" json:"hintDigest,omitempty"`
+}
+
+func (m *Message_PIRParams) Reset()         { *m = Message_PIRParams{} }
+func (m *Message_PIRParams) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRParams) ProtoMessage()    {}
+func (*Message_PIRParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 8}
+}
+func (m *Message_PIRParams) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRParams.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.WantType != 0 {
-		n += 1 + sovMessage(uint64(m.WantType))
+}
+func (m *Message_PIRParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRParams.Merge(m, src)
+}
+func (m *Message_PIRParams) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRParams proto.InternalMessageInfo
+
+func (m *Message_PIRParams) GetScheme() string {
+	if m != nil {
+		return m.Scheme
 	}
-	if m.SendDontHave {
-		n += 2
+	return ""
+}
+
+func (m *Message_PIRParams) GetNumElements() uint64 {
+	if m != nil {
+		return m.NumElements
 	}
-	return n
+	return 0
 }
 
-func (m *Message_Block) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Message_PIRParams) GetElementSize() uint64 {
+	if m != nil {
+		return m.ElementSize
 	}
-	var l int
-	_ = l
-	l = len(m.Prefix)
-	if l > 0 {
-		n += 1 + l + sovMessage(uint64(l))
+	return 0
+}
+
+func (m *Message_PIRParams) GetExtra() []byte {
+	if m != nil {
+		return m.Extra
 	}
-	l = len(m.Data)
-	if l > 0 {
-		n += 1 + l + sovMessage(uint64(l))
+	return nil
+}
+
+func (m *Message_PIRParams) GetHintDigest() []byte {
+	if m != nil {
+		return m.HintDigest
 	}
-	return n
+	return nil
 }
 
-func (m *Message_BlockPresence) Size() (n int) {
-	if m == nil {
-		return 0
+type Message_PIRBatchParams struct {
+	NumElements uint64            `protobuf:"varint,1,opt,name=numElements,proto3" json:"numElements,omitempty"`
+	BatchSize   uint64            `protobuf:"varint,2,opt,name=batchSize,proto3" json:"batchSize,omitempty"`
+	Buckets     uint64            `protobuf:"varint,3,opt,name=buckets,proto3" json:"buckets,omitempty"`
+	Bucket      Message_PIRParams `protobuf:"bytes,4,opt,name=bucket,proto3" json:"bucket"`
+}
+
+func (m *Message_PIRBatchParams) Reset()         { *m = Message_PIRBatchParams{} }
+func (m *Message_PIRBatchParams) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRBatchParams) ProtoMessage()    {}
+func (*Message_PIRBatchParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 9}
+}
+func (m *Message_PIRBatchParams) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRBatchParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRBatchParams.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	var l int
-	_ = l
-	l = m.Cid.Size()
-	n += 1 + l + sovMessage(uint64(l))
-	if m.Type != 0 {
-		n += 1 + sovMessage(uint64(m.Type))
+}
+func (m *Message_PIRBatchParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRBatchParams.Merge(m, src)
+}
+func (m *Message_PIRBatchParams) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRBatchParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRBatchParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRBatchParams proto.InternalMessageInfo
+
+func (m *Message_PIRBatchParams) GetNumElements() uint64 {
+	if m != nil {
+		return m.NumElements
 	}
-	return n
+	return 0
 }
 
-func sovMessage(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
+func (m *Message_PIRBatchParams) GetBatchSize() uint64 {
+	if m != nil {
+		return m.BatchSize
+	}
+	return 0
 }
-func sozMessage(x uint64) (n int) {
-	return sovMessage(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+
+func (m *Message_PIRBatchParams) GetBuckets() uint64 {
+	if m != nil {
+		return m.Buckets
+	}
+	return 0
 }
-func (m *Message) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowMessage
+
+func (m *Message_PIRBatchParams) GetBucket() Message_PIRParams {
+	if m != nil {
+		return m.Bucket
+	}
+	return Message_PIRParams{}
+}
+
+type Message_PIRManifest struct {
+	Epoch     uint64 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Digest    []byte `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	Scheme    string `protobuf:"bytes,4,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Group     []byte `protobuf:"bytes,5,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (m *Message_PIRManifest) Reset()         { *m = Message_PIRManifest{} }
+func (m *Message_PIRManifest) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRManifest) ProtoMessage()    {}
+func (*Message_PIRManifest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 10}
+}
+func (m *Message_PIRManifest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRManifest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRManifest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_PIRManifest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRManifest.Merge(m, src)
+}
+func (m *Message_PIRManifest) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRManifest) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRManifest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRManifest proto.InternalMessageInfo
+
+func (m *Message_PIRManifest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *Message_PIRManifest) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *Message_PIRManifest) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Message_PIRManifest) GetScheme() string {
+	if m != nil {
+		return m.Scheme
+	}
+	return ""
+}
+
+func (m *Message_PIRManifest) GetGroup() []byte {
+	if m != nil {
+		return m.Group
+	}
+	return nil
+}
+
+type Message_PIRFilter struct {
+	Bits   []byte `protobuf:"bytes,1,opt,name=bits,proto3" json:"bits,omitempty"`
+	Hashes uint32 `protobuf:"varint,2,opt,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *Message_PIRFilter) Reset()         { *m = Message_PIRFilter{} }
+func (m *Message_PIRFilter) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRFilter) ProtoMessage()    {}
+func (*Message_PIRFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 11}
+}
+func (m *Message_PIRFilter) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRFilter.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_PIRFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRFilter.Merge(m, src)
+}
+func (m *Message_PIRFilter) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRFilter proto.InternalMessageInfo
+
+func (m *Message_PIRFilter) GetBits() []byte {
+	if m != nil {
+		return m.Bits
+	}
+	return nil
+}
+
+func (m *Message_PIRFilter) GetHashes() uint32 {
+	if m != nil {
+		return m.Hashes
+	}
+	return 0
+}
+
+type Message_PIROffer struct {
+	Schemes        []string `protobuf:"bytes,1,rep,name=schemes,proto3" json:"schemes,omitempty"`
+	MaxElements    uint64   `protobuf:"varint,2,opt,name=maxElements,proto3" json:"maxElements,omitempty"`
+	MaxElementSize uint64   `protobuf:"varint,3,opt,name=maxElementSize,proto3" json:"maxElementSize,omitempty"`
+	Compression    []string `protobuf:"bytes,4,rep,name=compression,proto3" json:"compression,omitempty"`
+	Group          []byte   `protobuf:"bytes,5,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (m *Message_PIROffer) Reset()         { *m = Message_PIROffer{} }
+func (m *Message_PIROffer) String() string { return proto.CompactTextString(m) }
+func (*Message_PIROffer) ProtoMessage()    {}
+func (*Message_PIROffer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 12}
+}
+func (m *Message_PIROffer) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIROffer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIROffer.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_PIROffer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIROffer.Merge(m, src)
+}
+func (m *Message_PIROffer) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIROffer) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIROffer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIROffer proto.InternalMessageInfo
+
+func (m *Message_PIROffer) GetSchemes() []string {
+	if m != nil {
+		return m.Schemes
+	}
+	return nil
+}
+
+func (m *Message_PIROffer) GetMaxElements() uint64 {
+	if m != nil {
+		return m.MaxElements
+	}
+	return 0
+}
+
+func (m *Message_PIROffer) GetMaxElementSize() uint64 {
+	if m != nil {
+		return m.MaxElementSize
+	}
+	return 0
+}
+
+func (m *Message_PIROffer) GetCompression() []string {
+	if m != nil {
+		return m.Compression
+	}
+	return nil
+}
+
+func (m *Message_PIROffer) GetGroup() []byte {
+	if m != nil {
+		return m.Group
+	}
+	return nil
+}
+
+type Message_PIRHandshake struct {
+	Index       Message_PIRParams       `protobuf:"bytes,1,opt,name=index,proto3" json:"index"`
+	Blocks      Message_PIRParams       `protobuf:"bytes,2,opt,name=blocks,proto3" json:"blocks"`
+	IndexBatch  *Message_PIRBatchParams `protobuf:"bytes,3,opt,name=indexBatch,proto3" json:"indexBatch,omitempty"`
+	BlocksBatch *Message_PIRBatchParams `protobuf:"bytes,4,opt,name=blocksBatch,proto3" json:"blocksBatch,omitempty"`
+	Offer       *Message_PIROffer       `protobuf:"bytes,5,opt,name=offer,proto3" json:"offer,omitempty"`
+	Schemes     []string                `protobuf:"bytes,6,rep,name=schemes,proto3" json:"schemes,omitempty"`
+	Epoch       uint64                  `protobuf:"varint,7,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Compression string                  `protobuf:"bytes,8,opt,name=compression,proto3" json:"compression,omitempty"`
+	Manifest    *Message_PIRManifest    `protobuf:"bytes,9,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	Filter      *Message_PIRFilter      `protobuf:"bytes,10,opt,name=filter,proto3" json:"filter,omitempty"`
+	Groups      [][]byte                `protobuf:"bytes,11,rep,name=groups,proto3" json:"groups,omitempty"`
+	Group       []byte                  `protobuf:"bytes,12,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (m *Message_PIRHandshake) Reset()         { *m = Message_PIRHandshake{} }
+func (m *Message_PIRHandshake) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRHandshake) ProtoMessage()    {}
+func (*Message_PIRHandshake) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 13}
+}
+func (m *Message_PIRHandshake) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRHandshake) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRHandshake.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_PIRHandshake) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRHandshake.Merge(m, src)
+}
+func (m *Message_PIRHandshake) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRHandshake) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRHandshake.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRHandshake proto.InternalMessageInfo
+
+func (m *Message_PIRHandshake) GetIndex() Message_PIRParams {
+	if m != nil {
+		return m.Index
+	}
+	return Message_PIRParams{}
+}
+
+func (m *Message_PIRHandshake) GetBlocks() Message_PIRParams {
+	if m != nil {
+		return m.Blocks
+	}
+	return Message_PIRParams{}
+}
+
+func (m *Message_PIRHandshake) GetIndexBatch() *Message_PIRBatchParams {
+	if m != nil {
+		return m.IndexBatch
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetBlocksBatch() *Message_PIRBatchParams {
+	if m != nil {
+		return m.BlocksBatch
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetOffer() *Message_PIROffer {
+	if m != nil {
+		return m.Offer
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetSchemes() []string {
+	if m != nil {
+		return m.Schemes
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *Message_PIRHandshake) GetCompression() string {
+	if m != nil {
+		return m.Compression
+	}
+	return ""
+}
+
+func (m *Message_PIRHandshake) GetManifest() *Message_PIRManifest {
+	if m != nil {
+		return m.Manifest
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetFilter() *Message_PIRFilter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetGroups() [][]byte {
+	if m != nil {
+		return m.Groups
+	}
+	return nil
+}
+
+func (m *Message_PIRHandshake) GetGroup() []byte {
+	if m != nil {
+		return m.Group
+	}
+	return nil
+}
+
+type Message_Error struct {
+	Code       Message_ErrorCode `protobuf:"varint,1,opt,name=code,proto3,enum=bitswap.message.pb.Message_ErrorCode" json:"code,omitempty"`
+	Session    uint64            `protobuf:"varint,2,opt,name=session,proto3" json:"session,omitempty"`
+	Round      Message_PIRRound  `protobuf:"varint,3,opt,name=round,proto3,enum=bitswap.message.pb.Message_PIRRound" json:"round,omitempty"`
+	Message    string            `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Id         uint64            `protobuf:"varint,5,opt,name=id,proto3" json:"id,omitempty"`
+	RetryAfter uint64            `protobuf:"varint,6,opt,name=retryAfter,proto3" json:"retryAfter,omitempty"`
+}
+
+func (m *Message_Error) Reset()         { *m = Message_Error{} }
+func (m *Message_Error) String() string { return proto.CompactTextString(m) }
+func (*Message_Error) ProtoMessage()    {}
+func (*Message_Error) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 14}
+}
+func (m *Message_Error) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_Error) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_Error.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_Error) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_Error.Merge(m, src)
+}
+func (m *Message_Error) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_Error) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_Error.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_Error proto.InternalMessageInfo
+
+func (m *Message_Error) GetCode() Message_ErrorCode {
+	if m != nil {
+		return m.Code
+	}
+	return Message_InternalError
+}
+
+func (m *Message_Error) GetSession() uint64 {
+	if m != nil {
+		return m.Session
+	}
+	return 0
+}
+
+func (m *Message_Error) GetRound() Message_PIRRound {
+	if m != nil {
+		return m.Round
+	}
+	return Message_IndexRound
+}
+
+func (m *Message_Error) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *Message_Error) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Message_Error) GetRetryAfter() uint64 {
+	if m != nil {
+		return m.RetryAfter
+	}
+	return 0
+}
+
+type Message_SubtreeEnd struct {
+	Root      Cid    `protobuf:"bytes,1,opt,name=root,proto3,customtype=Cid" json:"root"`
+	Blocks    uint64 `protobuf:"varint,2,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	Truncated bool   `protobuf:"varint,3,opt,name=truncated,proto3" json:"truncated,omitempty"`
+}
+
+func (m *Message_SubtreeEnd) Reset()         { *m = Message_SubtreeEnd{} }
+func (m *Message_SubtreeEnd) String() string { return proto.CompactTextString(m) }
+func (*Message_SubtreeEnd) ProtoMessage()    {}
+func (*Message_SubtreeEnd) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 15}
+}
+func (m *Message_SubtreeEnd) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_SubtreeEnd) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_SubtreeEnd.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_SubtreeEnd) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_SubtreeEnd.Merge(m, src)
+}
+func (m *Message_SubtreeEnd) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_SubtreeEnd) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_SubtreeEnd.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_SubtreeEnd proto.InternalMessageInfo
+
+func (m *Message_SubtreeEnd) GetBlocks() uint64 {
+	if m != nil {
+		return m.Blocks
+	}
+	return 0
+}
+
+func (m *Message_SubtreeEnd) GetTruncated() bool {
+	if m != nil {
+		return m.Truncated
+	}
+	return false
+}
+
+type Message_PIRHintRequest struct {
+	Scheme string           `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Round  Message_PIRRound `protobuf:"varint,2,opt,name=round,proto3,enum=bitswap.message.pb.Message_PIRRound" json:"round,omitempty"`
+	Epoch  uint64           `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Offset uint64           `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Since  uint64           `protobuf:"varint,5,opt,name=since,proto3" json:"since,omitempty"`
+	Group  []byte           `protobuf:"bytes,6,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (m *Message_PIRHintRequest) Reset()         { *m = Message_PIRHintRequest{} }
+func (m *Message_PIRHintRequest) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRHintRequest) ProtoMessage()    {}
+func (*Message_PIRHintRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 16}
+}
+func (m *Message_PIRHintRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRHintRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRHintRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_PIRHintRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRHintRequest.Merge(m, src)
+}
+func (m *Message_PIRHintRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRHintRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRHintRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRHintRequest proto.InternalMessageInfo
+
+func (m *Message_PIRHintRequest) GetScheme() string {
+	if m != nil {
+		return m.Scheme
+	}
+	return ""
+}
+
+func (m *Message_PIRHintRequest) GetRound() Message_PIRRound {
+	if m != nil {
+		return m.Round
+	}
+	return Message_IndexRound
+}
+
+func (m *Message_PIRHintRequest) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *Message_PIRHintRequest) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Message_PIRHintRequest) GetSince() uint64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *Message_PIRHintRequest) GetGroup() []byte {
+	if m != nil {
+		return m.Group
+	}
+	return nil
+}
+
+type Message_PIRHint struct {
+	Scheme string           `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Round  Message_PIRRound `protobuf:"varint,2,opt,name=round,proto3,enum=bitswap.message.pb.Message_PIRRound" json:"round,omitempty"`
+	Epoch  uint64           `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Offset uint64           `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Total  uint64           `protobuf:"varint,5,opt,name=total,proto3" json:"total,omitempty"`
+	Data   []byte           `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	Since  uint64           `protobuf:"varint,7,opt,name=since,proto3" json:"since,omitempty"`
+}
+
+func (m *Message_PIRHint) Reset()         { *m = Message_PIRHint{} }
+func (m *Message_PIRHint) String() string { return proto.CompactTextString(m) }
+func (*Message_PIRHint) ProtoMessage()    {}
+func (*Message_PIRHint) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{0, 17}
+}
+func (m *Message_PIRHint) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message_PIRHint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message_PIRHint.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message_PIRHint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message_PIRHint.Merge(m, src)
+}
+func (m *Message_PIRHint) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message_PIRHint) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message_PIRHint.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message_PIRHint proto.InternalMessageInfo
+
+func (m *Message_PIRHint) GetScheme() string {
+	if m != nil {
+		return m.Scheme
+	}
+	return ""
+}
+
+func (m *Message_PIRHint) GetRound() Message_PIRRound {
+	if m != nil {
+		return m.Round
+	}
+	return Message_IndexRound
+}
+
+func (m *Message_PIRHint) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *Message_PIRHint) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Message_PIRHint) GetTotal() uint64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *Message_PIRHint) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Message_PIRHint) GetSince() uint64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("bitswap.message.pb.Message_BlockPresenceType", Message_BlockPresenceType_name, Message_BlockPresenceType_value)
+	proto.RegisterEnum("bitswap.message.pb.Message_PIRRound", Message_PIRRound_name, Message_PIRRound_value)
+	proto.RegisterEnum("bitswap.message.pb.Message_ErrorCode", Message_ErrorCode_name, Message_ErrorCode_value)
+	proto.RegisterEnum("bitswap.message.pb.Message_Wantlist_WantType", Message_Wantlist_WantType_name, Message_Wantlist_WantType_value)
+	proto.RegisterType((*Message)(nil), "bitswap.message.pb.Message")
+	proto.RegisterType((*Message_Wantlist)(nil), "bitswap.message.pb.Message.Wantlist")
+	proto.RegisterType((*Message_Wantlist_Entry)(nil), "bitswap.message.pb.Message.Wantlist.Entry")
+	proto.RegisterType((*Message_Block)(nil), "bitswap.message.pb.Message.Block")
+	proto.RegisterType((*Message_BlockPresence)(nil), "bitswap.message.pb.Message.BlockPresence")
+	proto.RegisterType((*Message_BlockChunk)(nil), "bitswap.message.pb.Message.BlockChunk")
+	proto.RegisterType((*Message_PIRRequest)(nil), "bitswap.message.pb.Message.PIRRequest")
+	proto.RegisterType((*Message_PIRResponse)(nil), "bitswap.message.pb.Message.PIRResponse")
+	proto.RegisterType((*Message_PIRReceipt)(nil), "bitswap.message.pb.Message.PIRReceipt")
+	proto.RegisterType((*Message_PIRProgress)(nil), "bitswap.message.pb.Message.PIRProgress")
+	proto.RegisterType((*Message_PIRParams)(nil), "bitswap.message.pb.Message.PIRParams")
+	proto.RegisterType((*Message_PIRBatchParams)(nil), "bitswap.message.pb.Message.PIRBatchParams")
+	proto.RegisterType((*Message_PIRManifest)(nil), "bitswap.message.pb.Message.PIRManifest")
+	proto.RegisterType((*Message_PIRFilter)(nil), "bitswap.message.pb.Message.PIRFilter")
+	proto.RegisterType((*Message_PIROffer)(nil), "bitswap.message.pb.Message.PIROffer")
+	proto.RegisterType((*Message_PIRHandshake)(nil), "bitswap.message.pb.Message.PIRHandshake")
+	proto.RegisterType((*Message_Error)(nil), "bitswap.message.pb.Message.Error")
+	proto.RegisterType((*Message_SubtreeEnd)(nil), "bitswap.message.pb.Message.SubtreeEnd")
+	proto.RegisterType((*Message_PIRHintRequest)(nil), "bitswap.message.pb.Message.PIRHintRequest")
+	proto.RegisterType((*Message_PIRHint)(nil), "bitswap.message.pb.Message.PIRHint")
+}
+
+func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
+
+var fileDescriptor_33c57e4bae7b9afd = []byte{
+	// 1749 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0x4f, 0x8f, 0x1b, 0x49,
+	0x15, 0x9f, 0xb6, 0xbb, 0xfd, 0xe7, 0xf9, 0x4f, 0x9c, 0xda, 0x55, 0xd4, 0xb2, 0x96, 0x89, 0x77,
+	0x08, 0xbb, 0x06, 0xb4, 0xb3, 0x52, 0xf6, 0x80, 0x40, 0x42, 0x90, 0x99, 0xcc, 0x6a, 0x67, 0x95,
+	0xb0, 0x43, 0x25, 0x28, 0x12, 0xb7, 0x72, 0x77, 0xd9, 0x2e, 0xc5, 0xee, 0xee, 0x74, 0x95, 0xc9,
+	0x4c, 0xbe, 0x00, 0xe2, 0x80, 0x84, 0x90, 0x38, 0x72, 0x84, 0x2b, 0x07, 0x6e, 0x1c, 0x38, 0xaf,
+	0xc4, 0x65, 0x8f, 0x88, 0xc3, 0x0a, 0x25, 0x5f, 0x80, 0x6f, 0x00, 0xaa, 0x57, 0xd5, 0xdd, 0x65,
+	0x67, 0x92, 0x9e, 0x80, 0x22, 0x71, 0xeb, 0xf7, 0x5c, 0xef, 0xd5, 0x7b, 0xbf, 0xf7, 0xab, 0x57,
+	0xaf, 0x0c, 0x83, 0x35, 0x97, 0x92, 0x2d, 0xf8, 0x61, 0x96, 0xa7, 0x2a, 0x25, 0x64, 0x26, 0x94,
+	0x7c, 0xca, 0xb2, 0xc3, 0x52, 0x3d, 0x1b, 0x7f, 0xb4, 0x10, 0x6a, 0xb9, 0x99, 0x1d, 0x46, 0xe9,
+	0xfa, 0xe3, 0x45, 0xba, 0x48, 0x3f, 0xc6, 0xa5, 0xb3, 0xcd, 0x1c, 0x25, 0x14, 0xf0, 0xcb, 0xb8,
+	0x38, 0xf8, 0xf3, 0x87, 0xd0, 0xbe, 0x6f, 0xac, 0xc9, 0xa7, 0xd0, 0x79, 0xca, 0x12, 0xb5, 0x12,
+	0x52, 0x85, 0xde, 0xc4, 0x9b, 0xf6, 0x6e, 0xdf, 0x3a, 0x7c, 0x79, 0x87, 0x43, 0xbb, 0xfc, 0xf0,
+	0x91, 0x5d, 0x7b, 0xe4, 0x7f, 0xf9, 0xf5, 0xcd, 0x3d, 0x5a, 0xda, 0x92, 0x1b, 0xd0, 0x9a, 0xad,
+	0xd2, 0xe8, 0xb1, 0x0c, 0x1b, 0x93, 0xe6, 0xb4, 0x4f, 0xad, 0x44, 0xee, 0x40, 0x3b, 0x63, 0x17,
+	0xab, 0x94, 0xc5, 0x61, 0x73, 0xd2, 0x9c, 0xf6, 0x6e, 0xbf, 0xff, 0x3a, 0xf7, 0x47, 0xda, 0xc8,
+	0xfa, 0x2e, 0xec, 0xc8, 0x23, 0x18, 0xa2, 0xb3, 0xb3, 0x9c, 0x4b, 0x9e, 0x44, 0x5c, 0x86, 0x3e,
+	0x7a, 0xfa, 0x76, 0xad, 0xa7, 0xc2, 0xc2, 0x7a, 0xdc, 0x71, 0x43, 0x0e, 0xa0, 0x9f, 0xf1, 0x24,
+	0x16, 0xc9, 0xe2, 0xe8, 0x42, 0x71, 0x19, 0x06, 0x13, 0x6f, 0x1a, 0xd0, 0x2d, 0x1d, 0xf9, 0x09,
+	0xf4, 0x32, 0x91, 0x53, 0xfe, 0x64, 0xc3, 0xa5, 0x92, 0x61, 0x0b, 0x77, 0xfe, 0xe0, 0x75, 0x3b,
+	0x9f, 0x9d, 0x52, 0xbb, 0xdc, 0x6e, 0xeb, 0x3a, 0x20, 0x3f, 0x85, 0x3e, 0x8a, 0x32, 0x4b, 0x13,
+	0xc9, 0x65, 0xd8, 0x46, 0x87, 0x1f, 0xd6, 0x3a, 0x34, 0xeb, 0xad, 0xc7, 0x2d, 0x17, 0xe4, 0x1e,
+	0xba, 0xfc, 0x8c, 0x25, 0xb1, 0x5c, 0xb2, 0xc7, 0x3c, 0xec, 0x60, 0x19, 0xa7, 0x35, 0x2e, 0xcb,
+	0xf5, 0x74, 0xcb, 0x9a, 0xdc, 0x85, 0x56, 0xb4, 0xdc, 0x24, 0x8f, 0x65, 0xd8, 0xad, 0xcf, 0x15,
+	0x51, 0x3e, 0xd6, 0xcb, 0x6d, 0x64, 0xd6, 0x96, 0x7c, 0x81, 0xb0, 0x9d, 0xe5, 0xe9, 0x22, 0xe7,
+	0x52, 0x86, 0x70, 0xa5, 0x2c, 0x8b, 0xe5, 0x0e, 0x6e, 0x85, 0x8a, 0xdc, 0x82, 0x81, 0x48, 0x56,
+	0x22, 0xe1, 0x94, 0x67, 0x2b, 0xc1, 0x65, 0xd8, 0x9b, 0x78, 0xd3, 0x0e, 0xdd, 0x56, 0x92, 0x50,
+	0xb3, 0x2d, 0xd6, 0xd5, 0x0b, 0xfb, 0x48, 0xc3, 0x42, 0x24, 0x3f, 0x87, 0x6b, 0x3a, 0x4d, 0x91,
+	0xa8, 0xb2, 0x96, 0x03, 0x0c, 0xea, 0x3b, 0x75, 0x38, 0x55, 0x26, 0x36, 0xae, 0x5d, 0x47, 0xe4,
+	0x04, 0x3a, 0x56, 0x25, 0xc3, 0x21, 0x3a, 0xfd, 0xe6, 0x15, 0x9c, 0x16, 0x47, 0xa8, 0x30, 0x25,
+	0x04, 0xfc, 0x4c, 0x47, 0x7e, 0x6d, 0xe2, 0x4d, 0x7d, 0x8a, 0xdf, 0xa8, 0x4b, 0x93, 0x45, 0x38,
+	0xb2, 0xba, 0x34, 0x59, 0x90, 0x1f, 0x41, 0x8b, 0xe7, 0x79, 0x9a, 0xcb, 0xf0, 0x7a, 0xfd, 0x89,
+	0x3a, 0xd1, 0x2b, 0x8b, 0xe2, 0x18, 0x33, 0xed, 0xf4, 0x99, 0x54, 0x71, 0x48, 0x26, 0xde, 0xb4,
+	0x4f, 0xf1, 0x5b, 0xf3, 0x5c, 0x6e, 0x66, 0x2a, 0xe7, 0xfc, 0x24, 0x89, 0x65, 0xf8, 0x4e, 0x7d,
+	0xed, 0x1f, 0x94, 0xcb, 0x8b, 0x7a, 0x39, 0x0e, 0xc6, 0xbf, 0x6e, 0x42, 0xa7, 0x68, 0x16, 0xe4,
+	0x73, 0x68, 0xf3, 0x44, 0xe5, 0xba, 0x6c, 0x5e, 0x3d, 0xe8, 0x85, 0xd9, 0xe1, 0x49, 0xa2, 0xf2,
+	0x8b, 0xa2, 0x1b, 0x58, 0x07, 0x3a, 0xf8, 0xf9, 0x66, 0xb5, 0x0a, 0x1b, 0x58, 0x7f, 0xfc, 0x1e,
+	0xff, 0xdb, 0x83, 0x00, 0x17, 0x93, 0xf7, 0x21, 0xc0, 0x43, 0x8e, 0xbd, 0xac, 0x7f, 0xd4, 0xd3,
+	0xb6, 0xff, 0xf8, 0xfa, 0x66, 0xf3, 0x58, 0xc4, 0xd4, 0xfc, 0x42, 0xc6, 0xd0, 0xc9, 0x72, 0x91,
+	0xe6, 0x42, 0x5d, 0xa0, 0x93, 0x80, 0x96, 0xb2, 0xee, 0x62, 0x11, 0x4b, 0x22, 0xbe, 0x0a, 0x9b,
+	0xe8, 0xde, 0x4a, 0xe4, 0xd4, 0x74, 0xc9, 0x87, 0x17, 0x19, 0x0f, 0xfd, 0x89, 0x37, 0x1d, 0xde,
+	0xfe, 0xe8, 0x4a, 0x19, 0x3c, 0xb2, 0x46, 0xb4, 0x34, 0xd7, 0x4d, 0x47, 0xf2, 0x24, 0xbe, 0x9b,
+	0x26, 0xea, 0x33, 0xf6, 0x0b, 0x8e, 0x4d, 0xa7, 0x43, 0xb7, 0x74, 0xe4, 0x5d, 0x08, 0x62, 0x9e,
+	0xa9, 0x65, 0xd8, 0x9a, 0x78, 0xd3, 0x01, 0x35, 0x82, 0x0e, 0x7c, 0xcd, 0xce, 0x4d, 0xab, 0x6a,
+	0x23, 0x1f, 0x4a, 0xf9, 0xe0, 0xa6, 0x41, 0x1b, 0x77, 0xe8, 0x42, 0x80, 0xe7, 0x72, 0xb4, 0x47,
+	0x3a, 0xe0, 0x6b, 0x87, 0x23, 0x6f, 0xfc, 0x89, 0x55, 0xea, 0x14, 0xb3, 0x9c, 0xcf, 0xc5, 0xb9,
+	0x81, 0x88, 0x5a, 0x49, 0xe3, 0x1a, 0x33, 0xc5, 0x10, 0x92, 0x3e, 0xc5, 0xef, 0xf1, 0x13, 0x18,
+	0x6c, 0xf5, 0x51, 0xf2, 0x0d, 0x68, 0x46, 0x22, 0xbe, 0x0c, 0x5c, 0xad, 0x27, 0x77, 0xc0, 0x57,
+	0x1a, 0xa2, 0x46, 0x3d, 0x44, 0x5b, 0x7e, 0x11, 0x22, 0x34, 0x1d, 0xaf, 0x01, 0xaa, 0xa6, 0x52,
+	0xb7, 0xdf, 0x0d, 0x68, 0xa5, 0xf3, 0xb9, 0xe4, 0x0a, 0x77, 0xf4, 0xa9, 0x95, 0x34, 0x7e, 0x2a,
+	0x55, 0xcc, 0x54, 0xd1, 0xa7, 0x46, 0x28, 0x33, 0xf4, 0x9d, 0x0c, 0x7f, 0xdb, 0x00, 0xa8, 0x1a,
+	0xb6, 0xee, 0x1f, 0x92, 0x4b, 0x29, 0xd2, 0x04, 0xf7, 0xf4, 0x69, 0x21, 0x92, 0x1f, 0x40, 0x90,
+	0xa7, 0x9b, 0x24, 0xb6, 0xb9, 0xdd, 0xaa, 0x6b, 0xd8, 0x7a, 0x2d, 0x35, 0x26, 0x3a, 0x9c, 0x27,
+	0x1b, 0x9e, 0x5f, 0x60, 0x38, 0x7d, 0x6a, 0x04, 0x3c, 0xda, 0x2c, 0x57, 0x18, 0xce, 0x80, 0xe2,
+	0xb7, 0xc3, 0xbf, 0x60, 0x8b, 0x7f, 0x37, 0xa0, 0x25, 0xa3, 0x25, 0x5f, 0x73, 0x64, 0x44, 0x97,
+	0x5a, 0x49, 0x7b, 0xe6, 0x59, 0x1a, 0x2d, 0x2d, 0x1f, 0x8c, 0x40, 0x86, 0xd0, 0x10, 0x31, 0x5e,
+	0x03, 0x3e, 0x6d, 0x08, 0xdc, 0x7f, 0x91, 0xa7, 0x9b, 0x2c, 0xec, 0x9a, 0xfd, 0x51, 0xd0, 0xb9,
+	0xe6, 0x3c, 0xe2, 0x22, 0x53, 0x21, 0xe0, 0x66, 0x85, 0x38, 0xfe, 0x43, 0x03, 0x7a, 0xce, 0xa5,
+	0xf3, 0x96, 0x50, 0xb9, 0x01, 0x2d, 0x96, 0xc8, 0xa7, 0x3c, 0xb7, 0xb0, 0x58, 0xe9, 0x52, 0x5c,
+	0xca, 0x3c, 0x03, 0x37, 0xcf, 0xaa, 0xfc, 0xad, 0xcb, 0xcb, 0xdf, 0x76, 0xcb, 0xbf, 0x8b, 0xca,
+	0x8f, 0xab, 0xfc, 0xbb, 0x13, 0xaf, 0xae, 0xdb, 0x21, 0x1e, 0xb8, 0xba, 0xc2, 0xe9, 0x2f, 0x9e,
+	0x25, 0x0f, 0x8a, 0x4e, 0x91, 0xbc, 0xdd, 0x22, 0x19, 0xf8, 0x1b, 0x2e, 0xfc, 0x65, 0x4a, 0x4d,
+	0x37, 0xa5, 0x92, 0x2a, 0xbe, 0x4b, 0x95, 0x0a, 0xaa, 0x60, 0x0b, 0xaa, 0xf7, 0xa0, 0xab, 0xc4,
+	0x9a, 0x4b, 0xc5, 0xd6, 0x99, 0xc5, 0xa0, 0x52, 0xe8, 0x5f, 0xa5, 0x58, 0x24, 0x4c, 0x6d, 0x72,
+	0x8e, 0x50, 0xf4, 0x69, 0xa5, 0x18, 0xff, 0xca, 0xc3, 0x22, 0x97, 0x17, 0xec, 0xdb, 0x29, 0xf2,
+	0x2d, 0x18, 0xf0, 0x15, 0xcb, 0x24, 0x8f, 0xef, 0x8b, 0xd5, 0x4a, 0x48, 0x9b, 0xed, 0xb6, 0x72,
+	0xfc, 0x7b, 0x0f, 0xba, 0x3a, 0x16, 0x96, 0xb3, 0xb5, 0x7c, 0x25, 0x8e, 0x13, 0xe8, 0x25, 0x9b,
+	0xf5, 0xc9, 0x8a, 0xaf, 0xb9, 0xbe, 0x69, 0xcd, 0x91, 0x77, 0x55, 0x7a, 0x05, 0x37, 0xdf, 0x0f,
+	0xc4, 0x33, 0x6e, 0xf7, 0x72, 0x55, 0x88, 0xfa, 0xb9, 0xca, 0x8b, 0x26, 0x60, 0x04, 0xb2, 0x0f,
+	0xb0, 0x14, 0x89, 0xba, 0x2b, 0x16, 0x5c, 0x2a, 0x8b, 0xb1, 0xa3, 0x19, 0xff, 0xc9, 0x83, 0xe1,
+	0xd9, 0x29, 0x3d, 0x62, 0x2a, 0x5a, 0xda, 0x20, 0x77, 0x82, 0xf1, 0x5e, 0x0e, 0xe6, 0x3d, 0xe8,
+	0xce, 0xb4, 0x01, 0x86, 0x62, 0x82, 0xad, 0x14, 0x1a, 0xee, 0xd9, 0x26, 0x7a, 0xcc, 0x55, 0x01,
+	0x49, 0x21, 0x92, 0x63, 0x68, 0x99, 0x4f, 0x8c, 0xb1, 0x77, 0xfb, 0x5b, 0x75, 0x53, 0x13, 0x06,
+	0x54, 0x5c, 0xf1, 0xc6, 0x74, 0xfc, 0x4b, 0x53, 0xdd, 0xfb, 0x2c, 0x11, 0x73, 0xdd, 0xd8, 0x4a,
+	0xb6, 0x79, 0x3b, 0x07, 0x28, 0x36, 0x39, 0x1b, 0x6a, 0x5a, 0x69, 0x9b, 0x39, 0xcd, 0x1d, 0xe6,
+	0x38, 0xf5, 0xf1, 0x2f, 0xe7, 0x79, 0xe0, 0xf0, 0x7c, 0xfc, 0x3d, 0x2c, 0xed, 0xa7, 0x62, 0xa5,
+	0xcc, 0xd9, 0xd6, 0xc9, 0xd8, 0xab, 0x07, 0xbf, 0xb5, 0xbb, 0x25, 0x93, 0x4b, 0x6e, 0x2a, 0x3a,
+	0xa0, 0x56, 0x1a, 0xff, 0xd1, 0x83, 0xce, 0xd9, 0x29, 0xfd, 0x62, 0x3e, 0xe7, 0x39, 0xb2, 0x13,
+	0x77, 0x31, 0x13, 0x44, 0x97, 0x16, 0xa2, 0x2e, 0xc4, 0x9a, 0x9d, 0xef, 0xb2, 0xc2, 0x51, 0x91,
+	0x0f, 0x60, 0x58, 0x89, 0x0e, 0x31, 0x76, 0xb4, 0xda, 0x53, 0x94, 0xae, 0xb3, 0xdc, 0x9e, 0x02,
+	0x1f, 0xf7, 0x71, 0x55, 0xaf, 0xc8, 0xf0, 0x5f, 0x3e, 0xf4, 0xdd, 0x81, 0x9a, 0xdc, 0x81, 0x40,
+	0x24, 0x31, 0x3f, 0x0f, 0xbd, 0x37, 0x2f, 0xa0, 0xb1, 0x44, 0x12, 0x14, 0xcf, 0xa9, 0xff, 0x82,
+	0x04, 0x68, 0x4a, 0x3e, 0x07, 0x40, 0x6f, 0xc8, 0x5b, 0x4c, 0xba, 0x7e, 0xdc, 0x75, 0x38, 0x4e,
+	0x1d, 0x6b, 0x72, 0x0f, 0x7a, 0xc6, 0xab, 0x71, 0xe6, 0xbf, 0xb1, 0x33, 0xd7, 0x5c, 0xb7, 0x94,
+	0x54, 0xd7, 0x35, 0x0c, 0xea, 0x9f, 0x9c, 0x05, 0x07, 0x68, 0x90, 0xee, 0x52, 0xa1, 0xb5, 0x4d,
+	0x85, 0xcb, 0x6f, 0xc3, 0x9d, 0xb2, 0x76, 0x90, 0xb3, 0x5b, 0x65, 0x3d, 0xd6, 0x83, 0x95, 0x39,
+	0x28, 0xf6, 0x2a, 0xa8, 0x7b, 0xa9, 0x14, 0xe7, 0x8a, 0x96, 0x86, 0xe4, 0x87, 0xd0, 0x9a, 0x23,
+	0xc9, 0x43, 0xb8, 0x52, 0xc5, 0xcc, 0x89, 0xa0, 0xd6, 0x48, 0x9f, 0x02, 0x64, 0x93, 0x7e, 0xd8,
+	0xe0, 0xfb, 0xd9, 0x48, 0x15, 0xe5, 0xfa, 0x2e, 0xe5, 0x9e, 0xeb, 0x81, 0x57, 0x0f, 0xf3, 0xe4,
+	0xfb, 0xe0, 0x47, 0x69, 0x6c, 0x5a, 0xe5, 0xf0, 0xf5, 0x9b, 0xa2, 0xc1, 0x71, 0x1a, 0x73, 0x8a,
+	0x26, 0x6e, 0xc7, 0x6f, 0xbc, 0xa2, 0xe3, 0x37, 0xdf, 0xbc, 0xe3, 0x87, 0xd0, 0xb6, 0xab, 0x6c,
+	0x7b, 0x28, 0x44, 0x7b, 0x01, 0x07, 0xe5, 0x05, 0xbc, 0x0f, 0x90, 0x73, 0x95, 0x5f, 0xdc, 0x99,
+	0x6b, 0xd4, 0xcc, 0xf5, 0xe5, 0x68, 0xc6, 0x11, 0x40, 0xf5, 0xc6, 0x20, 0x37, 0xc1, 0xcf, 0xd3,
+	0x54, 0x5d, 0x36, 0x0b, 0xe2, 0x0f, 0x5b, 0xff, 0x40, 0xe0, 0x34, 0x60, 0x24, 0xbc, 0x24, 0xf3,
+	0x4d, 0x12, 0x31, 0xc5, 0x63, 0x3b, 0xd6, 0x57, 0x8a, 0xf1, 0x5f, 0x4d, 0x6b, 0x77, 0xde, 0x73,
+	0xaf, 0xbc, 0x7f, 0xfe, 0xc7, 0x11, 0xf0, 0x92, 0xdb, 0xbe, 0x1a, 0x60, 0xfc, 0xdd, 0x01, 0x46,
+	0x8a, 0x24, 0xe2, 0xc5, 0xb8, 0x83, 0x42, 0x45, 0x85, 0x96, 0x4b, 0x85, 0xbf, 0x79, 0xd0, 0xb6,
+	0x09, 0xfc, 0x7f, 0x44, 0x6e, 0x46, 0xaf, 0xe0, 0xb2, 0xc9, 0xbb, 0x55, 0x4d, 0xde, 0x55, 0x8e,
+	0x6d, 0x27, 0xc7, 0x83, 0xef, 0xc2, 0xf5, 0x97, 0x5e, 0x06, 0xe5, 0x2b, 0x66, 0x8f, 0xf4, 0xa1,
+	0x53, 0x3c, 0x92, 0x46, 0xde, 0xc1, 0x43, 0xe8, 0x14, 0xd1, 0x92, 0x21, 0xc0, 0xa9, 0xee, 0x56,
+	0x28, 0x8d, 0xf6, 0xb4, 0x8c, 0x8e, 0x8c, 0xec, 0x91, 0x77, 0xe0, 0x1a, 0xb6, 0x1e, 0x67, 0x51,
+	0xa3, 0x54, 0x3a, 0x2b, 0x9b, 0x07, 0xbf, 0xf3, 0xa0, 0x5b, 0x1e, 0x15, 0x72, 0x1d, 0x06, 0xa7,
+	0x89, 0xe2, 0x79, 0xc2, 0x56, 0xa8, 0x1c, 0xed, 0x11, 0x02, 0xc3, 0x07, 0x88, 0xeb, 0x7d, 0x21,
+	0xd7, 0xda, 0x7c, 0xe4, 0x91, 0x77, 0x61, 0x74, 0x97, 0x29, 0x36, 0x63, 0x92, 0x3f, 0x4c, 0xd3,
+	0x7b, 0x2c, 0x5f, 0xf0, 0x51, 0x83, 0x5c, 0x83, 0x1e, 0x65, 0x8a, 0xdf, 0x13, 0x6b, 0xa1, 0x78,
+	0x3c, 0x6a, 0xea, 0xa8, 0x1e, 0x28, 0xb6, 0xe2, 0x27, 0x1a, 0xc4, 0x91, 0xaf, 0x33, 0x3b, 0xda,
+	0xc8, 0x8b, 0x51, 0x80, 0xf1, 0xb2, 0xd8, 0x52, 0x70, 0xd4, 0x22, 0x23, 0xe8, 0xff, 0x2c, 0x61,
+	0x1b, 0xb5, 0x4c, 0x73, 0xf1, 0x8c, 0xc7, 0xa3, 0xf6, 0x51, 0xf8, 0xe5, 0xf3, 0x7d, 0xef, 0xab,
+	0xe7, 0xfb, 0xde, 0x3f, 0x9f, 0xef, 0x7b, 0xbf, 0x79, 0xb1, 0xbf, 0xf7, 0xd5, 0x8b, 0xfd, 0xbd,
+	0xbf, 0xbf, 0xd8, 0xdf, 0x9b, 0xb5, 0xf0, 0x6f, 0xbd, 0x4f, 0xfe, 0x33, 0x00, 0xd7, 0xab, 0x8b,
+	0xea, 0x2a, 0x14, 0x00, 0x00,
+}
+
+func (m *Message) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.SubtreeEnds) > 0 {
+		for iNdEx := len(m.SubtreeEnds) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.SubtreeEnds[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0x9a
+		}
+	}
+	if len(m.Zstd) > 0 {
+		i -= len(m.Zstd)
+		copy(dAtA[i:], m.Zstd)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Zstd)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x92
+	}
+	if len(m.Errors) > 0 {
+		for iNdEx := len(m.Errors) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Errors[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0x8a
+		}
+	}
+	if m.Pong != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Pong))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
+	}
+	if m.Ping != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Ping))
+		i--
+		dAtA[i] = 0x78
+	}
+	if len(m.PirHints) > 0 {
+		for iNdEx := len(m.PirHints) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.PirHints[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x72
+		}
+	}
+	if len(m.PirHintRequests) > 0 {
+		for iNdEx := len(m.PirHintRequests) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.PirHintRequests[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x6a
+		}
+	}
+	if len(m.Padding) > 0 {
+		for iNdEx := len(m.Padding) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Padding[iNdEx])
+			copy(dAtA[i:], m.Padding[iNdEx])
+			i = encodeVarintMessage(dAtA, i, uint64(len(m.Padding[iNdEx])))
+			i--
+			dAtA[i] = 0x62
+		}
+	}
+	if m.InlineReplies {
+		i--
+		if m.InlineReplies {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x58
+	}
+	if len(m.PirProgress) > 0 {
+		for iNdEx := len(m.PirProgress) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.PirProgress[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x52
+		}
+	}
+	if len(m.Chunks) > 0 {
+		for iNdEx := len(m.Chunks) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Chunks[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x4a
+		}
+	}
+	if m.PirHandshake != nil {
+		{
+			size, err := m.PirHandshake.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x42
+	}
+	if len(m.PirResponses) > 0 {
+		for iNdEx := len(m.PirResponses) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.PirResponses[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x3a
+		}
+	}
+	if len(m.PirRequests) > 0 {
+		for iNdEx := len(m.PirRequests) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.PirRequests[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x32
+		}
+	}
+	if m.PendingBytes != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.PendingBytes))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.BlockPresences) > 0 {
+		for iNdEx := len(m.BlockPresences) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.BlockPresences[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Payload) > 0 {
+		for iNdEx := len(m.Payload) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Payload[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.Blocks) > 0 {
+		for iNdEx := len(m.Blocks) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Blocks[iNdEx])
+			copy(dAtA[i:], m.Blocks[iNdEx])
+			i = encodeVarintMessage(dAtA, i, uint64(len(m.Blocks[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	{
+		size, err := m.Wantlist.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_Wantlist) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_Wantlist) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_Wantlist) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Full {
+		i--
+		if m.Full {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Entries) > 0 {
+		for iNdEx := len(m.Entries) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Entries[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintMessage(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_Wantlist_Entry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_Wantlist_Entry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_Wantlist_Entry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.MaxBytes != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.MaxBytes))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.Depth != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Depth))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.SendDontHave {
+		i--
+		if m.SendDontHave {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.WantType != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.WantType))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Cancel {
+		i--
+		if m.Cancel {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Priority != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Priority))
+		i--
+		dAtA[i] = 0x10
+	}
+	{
+		size := m.Block.Size()
+		i -= size
+		if _, err := m.Block.MarshalTo(dAtA[i:]); err != nil {
+			return 0, err
+		}
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_Block) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_Block) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_Block) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Prefix) > 0 {
+		i -= len(m.Prefix)
+		copy(dAtA[i:], m.Prefix)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Prefix)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_BlockPresence) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_BlockPresence) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_BlockPresence) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Type != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Type))
+		i--
+		dAtA[i] = 0x10
+	}
+	{
+		size := m.Cid.Size()
+		i -= size
+		if _, err := m.Cid.MarshalTo(dAtA[i:]); err != nil {
+			return 0, err
+		}
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_BlockChunk) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_BlockChunk) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_BlockChunk) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Total != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Total))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Offset != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Offset))
+		i--
+		dAtA[i] = 0x10
+	}
+	{
+		size := m.Cid.Size()
+		i -= size
+		if _, err := m.Cid.MarshalTo(dAtA[i:]); err != nil {
+			return 0, err
+		}
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Receipt {
+		i--
+		if m.Receipt {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x50
+	}
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Group)))
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.Id != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Id))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.Scheme) > 0 {
+		i -= len(m.Scheme)
+		copy(dAtA[i:], m.Scheme)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Scheme)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.Cancel {
+		i--
+		if m.Cancel {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Part != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Part))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Query) > 0 {
+		i -= len(m.Query)
+		copy(dAtA[i:], m.Query)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Query)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Round != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Round))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Session != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Session))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Receipt != nil {
+		{
+			size, err := m.Receipt.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.Id != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Id))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.Total != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Total))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.Offset != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Offset))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Part != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Part))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Answer) > 0 {
+		i -= len(m.Answer)
+		copy(dAtA[i:], m.Answer)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Answer)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Round != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Round))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Session != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Session))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRReceipt) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRReceipt) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRReceipt) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Signature) > 0 {
+		i -= len(m.Signature)
+		copy(dAtA[i:], m.Signature)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Signature)))
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.Timestamp != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Timestamp))
+		i--
+		dAtA[i] = 0x30
+	}
+	if len(m.Answer) > 0 {
+		i -= len(m.Answer)
+		copy(dAtA[i:], m.Answer)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Answer)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if len(m.Query) > 0 {
+		i -= len(m.Query)
+		copy(dAtA[i:], m.Query)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Query)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Group)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Scheme) > 0 {
+		i -= len(m.Scheme)
+		copy(dAtA[i:], m.Scheme)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Scheme)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRProgress) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRProgress) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRProgress) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.ElapsedMillis != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.ElapsedMillis))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Round != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Round))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Session != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Session))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.HintDigest) > 0 {
+		i -= len(m.HintDigest)
+		copy(dAtA[i:], m.HintDigest)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.HintDigest)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if len(m.Extra) > 0 {
+		i -= len(m.Extra)
+		copy(dAtA[i:], m.Extra)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Extra)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.ElementSize != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.ElementSize))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.NumElements != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.NumElements))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Scheme) > 0 {
+		i -= len(m.Scheme)
+		copy(dAtA[i:], m.Scheme)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Scheme)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRBatchParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRBatchParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRBatchParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	{
+		size, err := m.Bucket.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x22
+	if m.Buckets != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Buckets))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.BatchSize != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.BatchSize))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.NumElements != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.NumElements))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRManifest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRManifest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRManifest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Group)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if len(m.Scheme) > 0 {
+		i -= len(m.Scheme)
+		copy(dAtA[i:], m.Scheme)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Scheme)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if len(m.Signature) > 0 {
+		i -= len(m.Signature)
+		copy(dAtA[i:], m.Signature)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Signature)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Digest) > 0 {
+		i -= len(m.Digest)
+		copy(dAtA[i:], m.Digest)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Digest)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRFilter) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRFilter) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRFilter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Hashes != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Hashes))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Bits) > 0 {
+		i -= len(m.Bits)
+		copy(dAtA[i:], m.Bits)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Bits)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIROffer) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIROffer) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIROffer) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Group)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if len(m.Compression) > 0 {
+		for iNdEx := len(m.Compression) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Compression[iNdEx])
+			copy(dAtA[i:], m.Compression[iNdEx])
+			i = encodeVarintMessage(dAtA, i, uint64(len(m.Compression[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if m.MaxElementSize != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.MaxElementSize))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.MaxElements != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.MaxElements))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Schemes) > 0 {
+		for iNdEx := len(m.Schemes) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Schemes[iNdEx])
+			copy(dAtA[i:], m.Schemes[iNdEx])
+			i = encodeVarintMessage(dAtA, i, uint64(len(m.Schemes[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRHandshake) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRHandshake) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRHandshake) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Group)))
+		i--
+		dAtA[i] = 0x62
+	}
+	if len(m.Groups) > 0 {
+		for iNdEx := len(m.Groups) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Groups[iNdEx])
+			copy(dAtA[i:], m.Groups[iNdEx])
+			i = encodeVarintMessage(dAtA, i, uint64(len(m.Groups[iNdEx])))
+			i--
+			dAtA[i] = 0x5a
+		}
+	}
+	if m.Filter != nil {
+		{
+			size, err := m.Filter.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.Manifest != nil {
+		{
+			size, err := m.Manifest.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if len(m.Compression) > 0 {
+		i -= len(m.Compression)
+		copy(dAtA[i:], m.Compression)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Compression)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.Schemes) > 0 {
+		for iNdEx := len(m.Schemes) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Schemes[iNdEx])
+			copy(dAtA[i:], m.Schemes[iNdEx])
+			i = encodeVarintMessage(dAtA, i, uint64(len(m.Schemes[iNdEx])))
+			i--
+			dAtA[i] = 0x32
+		}
+	}
+	if m.Offer != nil {
+		{
+			size, err := m.Offer.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.BlocksBatch != nil {
+		{
+			size, err := m.BlocksBatch.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.IndexBatch != nil {
+		{
+			size, err := m.IndexBatch.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintMessage(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	{
+		size, err := m.Blocks.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x12
+	{
+		size, err := m.Index.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_Error) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_Error) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_Error) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.RetryAfter != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.RetryAfter))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Id != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Id))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.Message) > 0 {
+		i -= len(m.Message)
+		copy(dAtA[i:], m.Message)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Message)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Round != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Round))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Session != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Session))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Code != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Code))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_SubtreeEnd) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_SubtreeEnd) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_SubtreeEnd) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Truncated {
+		i--
+		if m.Truncated {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Blocks != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Blocks))
+		i--
+		dAtA[i] = 0x10
+	}
+	{
+		size := m.Root.Size()
+		i -= size
+		if _, err := m.Root.MarshalTo(dAtA[i:]); err != nil {
+			return 0, err
+		}
+		i = encodeVarintMessage(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRHintRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRHintRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRHintRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Group)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.Since != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Since))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Offset != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Offset))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Round != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Round))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Scheme) > 0 {
+		i -= len(m.Scheme)
+		copy(dAtA[i:], m.Scheme)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Scheme)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message_PIRHint) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message_PIRHint) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message_PIRHint) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Since != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Since))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.Total != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Total))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Offset != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Offset))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Epoch != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Epoch))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Round != 0 {
+		i = encodeVarintMessage(dAtA, i, uint64(m.Round))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Scheme) > 0 {
+		i -= len(m.Scheme)
+		copy(dAtA[i:], m.Scheme)
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Scheme)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintMessage(dAtA []byte, offset int, v uint64) int {
+	offset -= sovMessage(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *Message) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = m.Wantlist.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	if len(m.Blocks) > 0 {
+		for _, b := range m.Blocks {
+			l = len(b)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if len(m.Payload) > 0 {
+		for _, e := range m.Payload {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if len(m.BlockPresences) > 0 {
+		for _, e := range m.BlockPresences {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.PendingBytes != 0 {
+		n += 1 + sovMessage(uint64(m.PendingBytes))
+	}
+	if len(m.PirRequests) > 0 {
+		for _, e := range m.PirRequests {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if len(m.PirResponses) > 0 {
+		for _, e := range m.PirResponses {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.PirHandshake != nil {
+		l = m.PirHandshake.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if len(m.Chunks) > 0 {
+		for _, e := range m.Chunks {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if len(m.PirProgress) > 0 {
+		for _, e := range m.PirProgress {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.InlineReplies {
+		n += 2
+	}
+	if len(m.Padding) > 0 {
+		for _, b := range m.Padding {
+			l = len(b)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if len(m.PirHintRequests) > 0 {
+		for _, e := range m.PirHintRequests {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if len(m.PirHints) > 0 {
+		for _, e := range m.PirHints {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Ping != 0 {
+		n += 1 + sovMessage(uint64(m.Ping))
+	}
+	if m.Pong != 0 {
+		n += 2 + sovMessage(uint64(m.Pong))
+	}
+	if len(m.Errors) > 0 {
+		for _, e := range m.Errors {
+			l = e.Size()
+			n += 2 + l + sovMessage(uint64(l))
+		}
+	}
+	l = len(m.Zstd)
+	if l > 0 {
+		n += 2 + l + sovMessage(uint64(l))
+	}
+	if len(m.SubtreeEnds) > 0 {
+		for _, e := range m.SubtreeEnds {
+			l = e.Size()
+			n += 2 + l + sovMessage(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Message_Wantlist) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for _, e := range m.Entries {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Full {
+		n += 2
+	}
+	return n
+}
+
+func (m *Message_Wantlist_Entry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = m.Block.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	if m.Priority != 0 {
+		n += 1 + sovMessage(uint64(m.Priority))
+	}
+	if m.Cancel {
+		n += 2
+	}
+	if m.WantType != 0 {
+		n += 1 + sovMessage(uint64(m.WantType))
+	}
+	if m.SendDontHave {
+		n += 2
+	}
+	if m.Depth != 0 {
+		n += 1 + sovMessage(uint64(m.Depth))
+	}
+	if m.MaxBytes != 0 {
+		n += 1 + sovMessage(uint64(m.MaxBytes))
+	}
+	return n
+}
+
+func (m *Message_Block) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Prefix)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_BlockPresence) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = m.Cid.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	if m.Type != 0 {
+		n += 1 + sovMessage(uint64(m.Type))
+	}
+	return n
+}
+
+func (m *Message_BlockChunk) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = m.Cid.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	if m.Offset != 0 {
+		n += 1 + sovMessage(uint64(m.Offset))
+	}
+	if m.Total != 0 {
+		n += 1 + sovMessage(uint64(m.Total))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Session != 0 {
+		n += 1 + sovMessage(uint64(m.Session))
+	}
+	if m.Round != 0 {
+		n += 1 + sovMessage(uint64(m.Round))
+	}
+	l = len(m.Query)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Part != 0 {
+		n += 1 + sovMessage(uint64(m.Part))
+	}
+	if m.Cancel {
+		n += 2
+	}
+	l = len(m.Scheme)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	if m.Id != 0 {
+		n += 1 + sovMessage(uint64(m.Id))
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Receipt {
+		n += 2
+	}
+	return n
+}
+
+func (m *Message_PIRResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Session != 0 {
+		n += 1 + sovMessage(uint64(m.Session))
+	}
+	if m.Round != 0 {
+		n += 1 + sovMessage(uint64(m.Round))
+	}
+	l = len(m.Answer)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Part != 0 {
+		n += 1 + sovMessage(uint64(m.Part))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	if m.Offset != 0 {
+		n += 1 + sovMessage(uint64(m.Offset))
+	}
+	if m.Total != 0 {
+		n += 1 + sovMessage(uint64(m.Total))
+	}
+	if m.Id != 0 {
+		n += 1 + sovMessage(uint64(m.Id))
+	}
+	if m.Receipt != nil {
+		l = m.Receipt.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRReceipt) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Scheme)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	l = len(m.Query)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.Answer)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Timestamp != 0 {
+		n += 1 + sovMessage(uint64(m.Timestamp))
+	}
+	l = len(m.Signature)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRProgress) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Session != 0 {
+		n += 1 + sovMessage(uint64(m.Session))
+	}
+	if m.Round != 0 {
+		n += 1 + sovMessage(uint64(m.Round))
+	}
+	if m.ElapsedMillis != 0 {
+		n += 1 + sovMessage(uint64(m.ElapsedMillis))
+	}
+	return n
+}
+
+func (m *Message_PIRParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Scheme)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.NumElements != 0 {
+		n += 1 + sovMessage(uint64(m.NumElements))
+	}
+	if m.ElementSize != 0 {
+		n += 1 + sovMessage(uint64(m.ElementSize))
+	}
+	l = len(m.Extra)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.HintDigest)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRBatchParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.NumElements != 0 {
+		n += 1 + sovMessage(uint64(m.NumElements))
+	}
+	if m.BatchSize != 0 {
+		n += 1 + sovMessage(uint64(m.BatchSize))
+	}
+	if m.Buckets != 0 {
+		n += 1 + sovMessage(uint64(m.Buckets))
+	}
+	l = m.Bucket.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	return n
+}
+
+func (m *Message_PIRManifest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	l = len(m.Digest)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.Signature)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.Scheme)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRFilter) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Bits)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Hashes != 0 {
+		n += 1 + sovMessage(uint64(m.Hashes))
+	}
+	return n
+}
+
+func (m *Message_PIROffer) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Schemes) > 0 {
+		for _, s := range m.Schemes {
+			l = len(s)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.MaxElements != 0 {
+		n += 1 + sovMessage(uint64(m.MaxElements))
+	}
+	if m.MaxElementSize != 0 {
+		n += 1 + sovMessage(uint64(m.MaxElementSize))
+	}
+	if len(m.Compression) > 0 {
+		for _, s := range m.Compression {
+			l = len(s)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRHandshake) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = m.Index.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	l = m.Blocks.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	if m.IndexBatch != nil {
+		l = m.IndexBatch.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.BlocksBatch != nil {
+		l = m.BlocksBatch.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Offer != nil {
+		l = m.Offer.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if len(m.Schemes) > 0 {
+		for _, s := range m.Schemes {
+			l = len(s)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	l = len(m.Compression)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Manifest != nil {
+		l = m.Manifest.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Filter != nil {
+		l = m.Filter.Size()
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if len(m.Groups) > 0 {
+		for _, b := range m.Groups {
+			l = len(b)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_Error) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Code != 0 {
+		n += 1 + sovMessage(uint64(m.Code))
+	}
+	if m.Session != 0 {
+		n += 1 + sovMessage(uint64(m.Session))
+	}
+	if m.Round != 0 {
+		n += 1 + sovMessage(uint64(m.Round))
+	}
+	l = len(m.Message)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Id != 0 {
+		n += 1 + sovMessage(uint64(m.Id))
+	}
+	if m.RetryAfter != 0 {
+		n += 1 + sovMessage(uint64(m.RetryAfter))
+	}
+	return n
+}
+
+func (m *Message_SubtreeEnd) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = m.Root.Size()
+	n += 1 + l + sovMessage(uint64(l))
+	if m.Blocks != 0 {
+		n += 1 + sovMessage(uint64(m.Blocks))
+	}
+	if m.Truncated {
+		n += 2
+	}
+	return n
+}
+
+func (m *Message_PIRHintRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Scheme)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Round != 0 {
+		n += 1 + sovMessage(uint64(m.Round))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	if m.Offset != 0 {
+		n += 1 + sovMessage(uint64(m.Offset))
+	}
+	if m.Since != 0 {
+		n += 1 + sovMessage(uint64(m.Since))
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	return n
+}
+
+func (m *Message_PIRHint) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Scheme)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Round != 0 {
+		n += 1 + sovMessage(uint64(m.Round))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovMessage(uint64(m.Epoch))
+	}
+	if m.Offset != 0 {
+		n += 1 + sovMessage(uint64(m.Offset))
+	}
+	if m.Total != 0 {
+		n += 1 + sovMessage(uint64(m.Total))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Since != 0 {
+		n += 1 + sovMessage(uint64(m.Since))
+	}
+	return n
+}
+
+func sovMessage(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozMessage(x uint64) (n int) {
+	return sovMessage(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Message) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Message: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Wantlist", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Wantlist.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Blocks = append(m.Blocks, make([]byte, postIndex-iNdEx))
+			copy(m.Blocks[len(m.Blocks)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Payload = append(m.Payload, Message_Block{})
+			if err := m.Payload[len(m.Payload)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockPresences", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BlockPresences = append(m.BlockPresences, Message_BlockPresence{})
+			if err := m.BlockPresences[len(m.BlockPresences)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PendingBytes", wireType)
+			}
+			m.PendingBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PendingBytes |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PirRequests", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PirRequests = append(m.PirRequests, Message_PIRRequest{})
+			if err := m.PirRequests[len(m.PirRequests)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PirResponses", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PirResponses = append(m.PirResponses, Message_PIRResponse{})
+			if err := m.PirResponses[len(m.PirResponses)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PirHandshake", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.PirHandshake == nil {
+				m.PirHandshake = &Message_PIRHandshake{}
+			}
+			if err := m.PirHandshake.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunks", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Chunks = append(m.Chunks, Message_BlockChunk{})
+			if err := m.Chunks[len(m.Chunks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PirProgress", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PirProgress = append(m.PirProgress, Message_PIRProgress{})
+			if err := m.PirProgress[len(m.PirProgress)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InlineReplies", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.InlineReplies = bool(v != 0)
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Padding", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Padding = append(m.Padding, make([]byte, postIndex-iNdEx))
+			copy(m.Padding[len(m.Padding)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PirHintRequests", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PirHintRequests = append(m.PirHintRequests, Message_PIRHintRequest{})
+			if err := m.PirHintRequests[len(m.PirHintRequests)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PirHints", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PirHints = append(m.PirHints, Message_PIRHint{})
+			if err := m.PirHints[len(m.PirHints)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ping", wireType)
+			}
+			m.Ping = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Ping |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pong", wireType)
+			}
+			m.Pong = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Pong |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Errors", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Errors = append(m.Errors, Message_Error{})
+			if err := m.Errors[len(m.Errors)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Zstd", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Zstd = append(m.Zstd[:0], dAtA[iNdEx:postIndex]...)
+			if m.Zstd == nil {
+				m.Zstd = []byte{}
+			}
+			iNdEx = postIndex
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SubtreeEnds", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SubtreeEnds = append(m.SubtreeEnds, Message_SubtreeEnd{})
+			if err := m.SubtreeEnds[len(m.SubtreeEnds)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_Wantlist) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Wantlist: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Wantlist: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Entries = append(m.Entries, Message_Wantlist_Entry{})
+			if err := m.Entries[len(m.Entries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Full", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Full = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Entry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Entry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Block", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Block.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			}
+			m.Priority = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Priority |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cancel", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Cancel = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WantType", wireType)
+			}
+			m.WantType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.WantType |= Message_Wantlist_WantType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SendDontHave", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SendDontHave = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Depth", wireType)
+			}
+			m.Depth = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Depth |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBytes", wireType)
+			}
+			m.MaxBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxBytes |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_Block) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Block: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Block: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Prefix = append(m.Prefix[:0], dAtA[iNdEx:postIndex]...)
+			if m.Prefix == nil {
+				m.Prefix = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_BlockPresence) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BlockPresence: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BlockPresence: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Cid.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= Message_BlockPresenceType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_BlockChunk) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BlockChunk: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BlockChunk: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Cid.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Total", wireType)
+			}
+			m.Total = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Total |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			m.Session = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Session |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Round", wireType)
+			}
+			m.Round = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Round |= Message_PIRRound(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Query", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Query = append(m.Query[:0], dAtA[iNdEx:postIndex]...)
+			if m.Query == nil {
+				m.Query = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Part", wireType)
+			}
+			m.Part = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Part |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cancel", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Cancel = bool(v != 0)
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Scheme", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Scheme = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			m.Id = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Id |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Group = append(m.Group[:0], dAtA[iNdEx:postIndex]...)
+			if m.Group == nil {
+				m.Group = []byte{}
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Receipt", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Receipt = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			m.Session = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Session |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Round", wireType)
+			}
+			m.Round = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Round |= Message_PIRRound(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Answer", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Answer = append(m.Answer[:0], dAtA[iNdEx:postIndex]...)
+			if m.Answer == nil {
+				m.Answer = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Part", wireType)
+			}
+			m.Part = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Part |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Total", wireType)
+			}
+			m.Total = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Total |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			m.Id = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Id |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Receipt", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Receipt == nil {
+				m.Receipt = &Message_PIRReceipt{}
+			}
+			if err := m.Receipt.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRReceipt) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRReceipt: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRReceipt: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Scheme", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Scheme = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Group = append(m.Group[:0], dAtA[iNdEx:postIndex]...)
+			if m.Group == nil {
+				m.Group = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Query", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Query = append(m.Query[:0], dAtA[iNdEx:postIndex]...)
+			if m.Query == nil {
+				m.Query = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Answer", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Answer = append(m.Answer[:0], dAtA[iNdEx:postIndex]...)
+			if m.Answer == nil {
+				m.Answer = []byte{}
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			m.Timestamp = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Timestamp |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Signature", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Signature = append(m.Signature[:0], dAtA[iNdEx:postIndex]...)
+			if m.Signature == nil {
+				m.Signature = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRProgress) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRProgress: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRProgress: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			m.Session = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Session |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Round", wireType)
+			}
+			m.Round = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Round |= Message_PIRRound(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ElapsedMillis", wireType)
+			}
+			m.ElapsedMillis = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ElapsedMillis |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRParams) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRParams: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRParams: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Scheme", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Scheme = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumElements", wireType)
+			}
+			m.NumElements = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NumElements |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ElementSize", wireType)
+			}
+			m.ElementSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ElementSize |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
+			if m.Extra == nil {
+				m.Extra = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HintDigest", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.HintDigest = append(m.HintDigest[:0], dAtA[iNdEx:postIndex]...)
+			if m.HintDigest == nil {
+				m.HintDigest = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRBatchParams) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRBatchParams: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRBatchParams: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumElements", wireType)
+			}
+			m.NumElements = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NumElements |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BatchSize", wireType)
+			}
+			m.BatchSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BatchSize |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Buckets", wireType)
+			}
+			m.Buckets = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Buckets |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bucket", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Bucket.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRManifest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRManifest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRManifest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Digest", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Digest = append(m.Digest[:0], dAtA[iNdEx:postIndex]...)
+			if m.Digest == nil {
+				m.Digest = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Signature", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Signature = append(m.Signature[:0], dAtA[iNdEx:postIndex]...)
+			if m.Signature == nil {
+				m.Signature = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Scheme", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Scheme = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Group = append(m.Group[:0], dAtA[iNdEx:postIndex]...)
+			if m.Group == nil {
+				m.Group = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRFilter) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRFilter: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRFilter: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bits", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Bits = append(m.Bits[:0], dAtA[iNdEx:postIndex]...)
+			if m.Bits == nil {
+				m.Bits = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hashes", wireType)
+			}
+			m.Hashes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Hashes |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIROffer) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIROffer: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIROffer: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Schemes", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Schemes = append(m.Schemes, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxElements", wireType)
+			}
+			m.MaxElements = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxElements |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxElementSize", wireType)
+			}
+			m.MaxElementSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxElementSize |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Compression = append(m.Compression, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Group = append(m.Group[:0], dAtA[iNdEx:postIndex]...)
+			if m.Group == nil {
+				m.Group = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_PIRHandshake) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PIRHandshake: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PIRHandshake: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Index.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Blocks.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexBatch", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.IndexBatch == nil {
+				m.IndexBatch = &Message_PIRBatchParams{}
+			}
+			if err := m.IndexBatch.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlocksBatch", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.BlocksBatch == nil {
+				m.BlocksBatch = &Message_PIRBatchParams{}
+			}
+			if err := m.BlocksBatch.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offer", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if iNdEx >= l {
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			if m.Offer == nil {
+				m.Offer = &Message_PIROffer{}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Message: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			if err := m.Offer.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Wantlist", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Schemes", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Schemes = append(m.Schemes, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Compression = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Manifest", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -854,13 +7299,52 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Wantlist.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if m.Manifest == nil {
+				m.Manifest = &Message_PIRManifest{}
+			}
+			if err := m.Manifest.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 2:
+		case 10:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Filter", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Filter == nil {
+				m.Filter = &Message_PIRFilter{}
+			}
+			if err := m.Filter.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Groups", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -887,14 +7371,155 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Blocks = append(m.Blocks, make([]byte, postIndex-iNdEx))
-			copy(m.Blocks[len(m.Blocks)-1], dAtA[iNdEx:postIndex])
+			m.Groups = append(m.Groups, make([]byte, postIndex-iNdEx))
+			copy(m.Groups[len(m.Groups)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Group = append(m.Group[:0], dAtA[iNdEx:postIndex]...)
+			if m.Group == nil {
+				m.Group = []byte{}
+			}
 			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message_Error) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Error: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Error: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= Message_ErrorCode(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			m.Session = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Session |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Round", wireType)
+			}
+			m.Round = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Round |= Message_PIRRound(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Message", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -904,31 +7529,29 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthMessage
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthMessage
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Payload = append(m.Payload, Message_Block{})
-			if err := m.Payload[len(m.Payload)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Message = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field BlockPresences", wireType)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
 			}
-			var msglen int
+			m.Id = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -938,31 +7561,16 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Id |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthMessage
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthMessage
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.BlockPresences = append(m.BlockPresences, Message_BlockPresence{})
-			if err := m.BlockPresences[len(m.BlockPresences)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 5:
+		case 6:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PendingBytes", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RetryAfter", wireType)
 			}
-			m.PendingBytes = 0
+			m.RetryAfter = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -972,7 +7580,7 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.PendingBytes |= int32(b&0x7F) << shift
+				m.RetryAfter |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -998,7 +7606,7 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Message_Wantlist) Unmarshal(dAtA []byte) error {
+func (m *Message_SubtreeEnd) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1021,17 +7629,17 @@ func (m *Message_Wantlist) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Wantlist: wiretype end group for non-group")
+			return fmt.Errorf("proto: SubtreeEnd: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Wantlist: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SubtreeEnd: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Root", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1041,29 +7649,47 @@ func (m *Message_Wantlist) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthMessage
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthMessage
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Entries = append(m.Entries, Message_Wantlist_Entry{})
-			if err := m.Entries[len(m.Entries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Root.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Full", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+			}
+			m.Blocks = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Blocks |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Truncated", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -1080,7 +7706,7 @@ func (m *Message_Wantlist) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.Full = bool(v != 0)
+			m.Truncated = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -1102,7 +7728,7 @@ func (m *Message_Wantlist) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
+func (m *Message_PIRHintRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1125,17 +7751,17 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Entry: wiretype end group for non-group")
+			return fmt.Errorf("proto: PIRHintRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Entry: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: PIRHintRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Block", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Scheme", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1145,30 +7771,29 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthMessage
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthMessage
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Block.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Scheme = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Round", wireType)
 			}
-			m.Priority = 0
+			m.Round = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1178,16 +7803,16 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Priority |= int32(b&0x7F) << shift
+				m.Round |= Message_PIRRound(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cancel", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
 			}
-			var v int
+			m.Epoch = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1197,17 +7822,16 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.Epoch |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Cancel = bool(v != 0)
 		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field WantType", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
 			}
-			m.WantType = 0
+			m.Offset = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1217,16 +7841,16 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.WantType |= Message_Wantlist_WantType(b&0x7F) << shift
+				m.Offset |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SendDontHave", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Since", wireType)
 			}
-			var v int
+			m.Since = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1236,12 +7860,45 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.Since |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.SendDontHave = bool(v != 0)
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Group = append(m.Group[:0], dAtA[iNdEx:postIndex]...)
+			if m.Group == nil {
+				m.Group = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -1263,7 +7920,7 @@ func (m *Message_Wantlist_Entry) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Message_Block) Unmarshal(dAtA []byte) error {
+func (m *Message_PIRHint) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1286,17 +7943,17 @@ func (m *Message_Block) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Block: wiretype end group for non-group")
+			return fmt.Errorf("proto: PIRHint: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Block: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: PIRHint: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Scheme", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1306,31 +7963,29 @@ func (m *Message_Block) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthMessage
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthMessage
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Prefix = append(m.Prefix[:0], dAtA[iNdEx:postIndex]...)
-			if m.Prefix == nil {
-				m.Prefix = []byte{}
-			}
+			m.Scheme = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Round", wireType)
 			}
-			var byteLen int
+			m.Round = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1340,79 +7995,71 @@ func (m *Message_Block) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				m.Round |= Message_PIRRound(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthMessage
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex < 0 {
-				return ErrInvalidLengthMessage
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
-			if m.Data == nil {
-				m.Data = []byte{}
-			}
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipMessage(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
 			}
-			if (skippy < 0) || (iNdEx+skippy) < 0 {
-				return ErrInvalidLengthMessage
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Message_BlockPresence) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowMessage
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Total", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			m.Total = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Total |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: BlockPresence: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: BlockPresence: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -1439,15 +8086,16 @@ func (m *Message_BlockPresence) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Cid.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
 			iNdEx = postIndex
-		case 2:
+		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Since", wireType)
 			}
-			m.Type = 0
+			m.Since = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -1457,7 +8105,7 @@ func (m *Message_BlockPresence) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Type |= Message_BlockPresenceType(b&0x7F) << shift
+				m.Since |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
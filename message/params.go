@@ -0,0 +1,61 @@
+package bitswap_message_pb
+
+import (
+	"github.com/willscott/go-selfish-bitswap-client/filter"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+)
+
+// NewPIRParams converts the public parameters of a PIR database for the wire.
+// Hints are downloaded apart, so only their digest is sent.
+func NewPIRParams(p pir.Params) Message_PIRParams {
+	return Message_PIRParams{
+		Scheme:      p.Scheme,
+		NumElements: p.NumElements,
+		ElementSize: p.ElementSize,
+		Extra:       p.Extra,
+		HintDigest:  p.HintDigest,
+	}
+}
+
+// Params converts wire parameters back to their pir form.
+func (m Message_PIRParams) Params() pir.Params {
+	return pir.Params{
+		Scheme:      m.Scheme,
+		NumElements: m.NumElements,
+		ElementSize: m.ElementSize,
+		Extra:       m.Extra,
+		HintDigest:  m.HintDigest,
+	}
+}
+
+// NewPIRBatchParams converts the public parameters of a batched PIR database
+// for the wire.
+func NewPIRBatchParams(p batch.Params) *Message_PIRBatchParams {
+	return &Message_PIRBatchParams{
+		NumElements: p.NumElements,
+		BatchSize:   p.BatchSize,
+		Buckets:     p.Buckets,
+		Bucket:      NewPIRParams(p.Bucket),
+	}
+}
+
+// Params converts wire batch parameters back to their batch form.
+func (m *Message_PIRBatchParams) Params() batch.Params {
+	return batch.Params{
+		NumElements: m.NumElements,
+		BatchSize:   m.BatchSize,
+		Buckets:     m.Buckets,
+		Bucket:      m.Bucket.Params(),
+	}
+}
+
+// NewPIRFilter converts a presence filter for the wire.
+func NewPIRFilter(b *filter.Bloom) *Message_PIRFilter {
+	return &Message_PIRFilter{Bits: b.Bits, Hashes: b.Hashes}
+}
+
+// Bloom converts a wire presence filter back to its filter form.
+func (m *Message_PIRFilter) Bloom() *filter.Bloom {
+	return &filter.Bloom{Bits: m.Bits, Hashes: m.Hashes}
+}
@@ -0,0 +1,30 @@
+package bitswap_message_pb
+
+import "encoding/binary"
+
+// receiptDomain separates receipt signatures from others made with the same
+// peer key.
+const receiptDomain = "bitswap-pir/receipt/v1"
+
+// ReceiptPayload returns what servers sign to attest that they answered the
+// query of digest query with the answer of digest answer, from their
+// databases of scheme at epoch laying out the content group group, or their
+// whole store if it is empty, at timestamp.
+func ReceiptPayload(scheme string, group []byte, epoch uint64, query, answer []byte, timestamp uint64) []byte {
+	var n [binary.MaxVarintLen64]byte
+	b := []byte(receiptDomain)
+	for _, f := range [][]byte{[]byte(scheme), group, query, answer} {
+		b = append(b, n[:binary.PutUvarint(n[:], uint64(len(f)))]...)
+		b = append(b, f...)
+	}
+	var u [8]byte
+	binary.BigEndian.PutUint64(u[:], epoch)
+	b = append(b, u[:]...)
+	binary.BigEndian.PutUint64(u[:], timestamp)
+	return append(b, u[:]...)
+}
+
+// Payload returns what the server signed to issue r.
+func (r *Message_PIRReceipt) Payload() []byte {
+	return ReceiptPayload(r.Scheme, r.Group, r.Epoch, r.Query, r.Answer, r.Timestamp)
+}
@@ -0,0 +1,159 @@
+// Package metrics exports the activity of bitswap clients and servers as
+// Prometheus metrics.
+//
+//	sink, err := metrics.NewServer(prometheus.DefaultRegisterer)
+//	bitswapserver.AttachBitswapServer(h, bs, bitswapserver.WithMetrics(sink))
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "bitswap"
+
+// latencyBuckets spans the time of a PIR answer on small and large
+// databases, in seconds.
+var latencyBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
+type metric struct {
+	name string
+	help string
+}
+
+var serverCounters = []metric{
+	{"streams_opened", "Bitswap streams accepted."},
+	{"streams_refused", "Bitswap streams refused because the peer was over its limit."},
+	{"streams_unauthorized", "Bitswap streams, and private retrievals over them, refused by the server's authorizer."},
+	{"streams_idle_closed", "Bitswap streams closed after their idle timeout."},
+	{"messages_received", "Bitswap messages parsed."},
+	{"blocks_served", "Blocks sent in response to wants."},
+	{"subtrees_served", "Subtrees of blocks walked and sent in response to wants with a depth."},
+	{"pir_queries", "PIR queries received."},
+	{"pir_answer_cache_hits", "PIR queries answered from the answer cache."},
+	{"pir_answer_cache_misses", "PIR queries answered by a pass over the database."},
+	{"pir_queue_overflows", "Streams closed because the PIR answer queue was full."},
+	{"pir_deferred", "PIR requests over the admission budget held back until others were answered."},
+	{"pir_over_budget", "PIR requests refused for exceeding the admission budget."},
+	{"pir_unsettled", "PIR queries refused by the server's settlement, such as for exceeding a quota."},
+	{"pir_receipts_signed", "Receipts signed for PIR answers."},
+	{"pir_duplicate_requests", "PIR requests resent by clients while the first was being answered, and not answered again."},
+	{"pir_timeouts", "PIR handshakes and rounds which ran out of time."},
+	{"pir_progress_sent", "Progress messages sent while computing PIR answers."},
+	{"pir_hint_bytes", "Bytes of PIR database hints sent to clients."},
+	{"pir_memory_refused", "PIR answers and progress messages dropped because the resource manager refused their memory."},
+	{"pir_answer_overruns", "PIR answers which took longer than the answer period, and were released a period late."},
+	{"pings_answered", "Pings from clients checking their streams are alive."},
+	{"errors_sent", "Errors sent to clients for requests which failed."},
+	{"bytes_received", "Bytes read from streams."},
+	{"bytes_sent", "Bytes written to streams."},
+	{"send_queue_overflows", "Responses dropped because a stream's send queue stayed full for the send timeout."},
+	{"read_backpressure", "Pauses in reading a stream's requests because its send queue was full."},
+	{"padding_bytes", "Bytes of padding added to messages to round their sizes up to a bucket."},
+	{"reply_streams_opened", "Streams opened to stock bitswap peers, which read replies apart from their requests."},
+	{"buffers_allocated", "Message buffers allocated because none could be reused."},
+	{"buffers_reused", "Message buffers reused from the pool."},
+}
+
+var serverHistograms = []metric{
+	{"pir_answer_seconds", "Time taken to answer a PIR query."},
+	{"pir_queue_seconds", "Time PIR queries waited for a worker."},
+}
+
+var clientCounters = []metric{
+	{"streams_opened", "Bitswap streams opened to peers."},
+	{"messages_received", "Bitswap messages parsed."},
+	{"blocks_received", "Blocks received for outstanding wants."},
+	{"pir_queries", "PIR queries sent."},
+	{"pir_decoys", "Decoy PIR retrievals sent."},
+	{"pir_hint_bytes", "Bytes of PIR database hints downloaded."},
+	{"filter_skips", "PIR retrievals skipped because the peer's presence filter showed it lacks the block."},
+	{"pir_hint_deltas", "PIR database hints updated from the changes since a hint held, rather than downloaded whole."},
+	{"subtree_blocks", "Blocks received as descendants of subtrees wanted."},
+	{"block_cache_hits", "Retrievals answered from the client's block cache."},
+	{"block_cache_misses", "Retrievals of blocks the client's block cache did not hold."},
+	{"block_cache_evictions", "Blocks evicted from the client's block cache to make room."},
+	{"invalid_blocks", "Blocks received which did not match their CID."},
+	{"plaintext_fallbacks", "Blocks fetched in plaintext from peers without a PIR scheme in common."},
+	{"stale_params", "PIR answers from databases which changed since the handshake."},
+	{"params_restored", "PIR parameters restored from the address book rather than negotiated."},
+	{"pir_progress_received", "Progress messages received from peers computing PIR answers."},
+	{"pings_sent", "Pings sent to peers on idle private streams."},
+	{"pings_unanswered", "Sessions closed because their peer did not answer a ping."},
+	{"peer_errors", "Errors reported by peers for requests which failed."},
+	{"equivocations", "Handshakes whose signed manifest conflicted with another the peer signed for the same databases."},
+	{"pir_receipts", "Signed receipts for PIR answers received and verified."},
+	{"wants_coalesced", "Requests for blocks already being fetched from the same peer."},
+	{"bytes_received", "Bytes read from streams."},
+	{"bytes_sent", "Bytes written to streams."},
+}
+
+var clientHistograms = []metric{
+	{"pir_query_seconds", "Time from sending a PIR round to receiving all of its answers."},
+}
+
+// Sink records measurements in Prometheus collectors. It satisfies the
+// MetricsSink interface of both the client and the server. Measurements
+// under names it does not know are dropped.
+type Sink struct {
+	counters   map[string]prometheus.Counter
+	histograms map[string]prometheus.Histogram
+}
+
+// NewServer registers the server metrics with reg and returns a sink
+// recording into them.
+func NewServer(reg prometheus.Registerer) (*Sink, error) {
+	return newSink(reg, "server", serverCounters, serverHistograms)
+}
+
+// NewClient registers the client metrics with reg and returns a sink
+// recording into them. All sessions of a process should share one.
+func NewClient(reg prometheus.Registerer) (*Sink, error) {
+	return newSink(reg, "client", clientCounters, clientHistograms)
+}
+
+func newSink(reg prometheus.Registerer, subsystem string, counters, histograms []metric) (*Sink, error) {
+	s := &Sink{
+		counters:   make(map[string]prometheus.Counter),
+		histograms: make(map[string]prometheus.Histogram),
+	}
+	for _, m := range counters {
+		c := prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      m.name + "_total",
+			Help:      m.help,
+		})
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+		s.counters[m.name] = c
+	}
+	for _, m := range histograms {
+		h := prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      m.name,
+			Help:      m.help,
+			Buckets:   latencyBuckets,
+		})
+		if err := reg.Register(h); err != nil {
+			return nil, err
+		}
+		s.histograms[m.name] = h
+	}
+	return s, nil
+}
+
+// Add increases the counter name by v.
+func (s *Sink) Add(name string, v float64) {
+	if c, ok := s.counters[name]; ok {
+		c.Add(v)
+	}
+}
+
+// Observe records v in the histogram name.
+func (s *Sink) Observe(name string, v float64) {
+	if h, ok := s.histograms[name]; ok {
+		h.Observe(v)
+	}
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSink(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	server, err := NewServer(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Add("blocks_served", 2)
+	server.Add("unknown", 1)
+	server.Observe("pir_answer_seconds", 0.5)
+	client.Add("blocks_received", 1)
+
+	if v := testutil.ToFloat64(server.counters["blocks_served"]); v != 2 {
+		t.Fatalf("blocks served = %v, want 2", v)
+	}
+	if v := testutil.ToFloat64(client.counters["blocks_received"]); v != 1 {
+		t.Fatalf("blocks received = %v, want 1", v)
+	}
+	if n := testutil.CollectAndCount(server.histograms["pir_answer_seconds"]); n != 1 {
+		t.Fatalf("got %d histograms", n)
+	}
+
+	if _, err := NewServer(reg); err == nil {
+		t.Fatal("registering the server metrics twice should fail")
+	}
+}
@@ -0,0 +1,155 @@
+// Package mobile wraps the client in the types gomobile binds, so iOS and
+// Android apps can embed private retrieval:
+//
+//	gomobile bind -target=android ./mobile
+//	gomobile bind -target=ios ./mobile
+//
+// Lists are passed as comma separated strings, and results of asynchronous
+// fetches through a Callback implemented by the app.
+package mobile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/registry"
+)
+
+const defaultTimeout = time.Minute
+
+// Config configures a Client. Zero values select the defaults.
+type Config struct {
+	// Schemes are the names of the PIR schemes to offer, comma separated, in
+	// order of preference. Empty offers every scheme built in.
+	Schemes string
+	// TimeoutMillis bounds each fetch, one minute if zero.
+	TimeoutMillis int64
+	// MaxHintBytes bounds the hints downloaded for schemes with an offline
+	// phase.
+	MaxHintBytes int64
+}
+
+// NewConfig returns a Config selecting the defaults.
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// Callback receives the result of a GetAsync. Exactly one of its methods is
+// called, from another thread than the one which called GetAsync.
+type Callback interface {
+	OnBlock(cid string, data []byte)
+	OnError(cid string, message string)
+}
+
+// Client privately retrieves blocks from peers given by multiaddrs.
+type Client struct {
+	host    host.Host
+	client  *bitswap.Client
+	timeout time.Duration
+}
+
+// NewClient starts a libp2p host and a client fetching through it. The host
+// only dials out.
+func NewClient(cfg *Config) (*Client, error) {
+	if cfg == nil {
+		cfg = NewConfig()
+	}
+	names := registry.Single()
+	if cfg.Schemes != "" {
+		names = strings.Split(cfg.Schemes, ",")
+	}
+	var schemes []pir.Scheme
+	for _, name := range names {
+		scheme, err := registry.New(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		schemes = append(schemes, scheme)
+	}
+	if len(schemes) == 0 {
+		return nil, errors.New("no scheme configured")
+	}
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		return nil, err
+	}
+	timeout := defaultTimeout
+	if cfg.TimeoutMillis > 0 {
+		timeout = time.Duration(cfg.TimeoutMillis) * time.Millisecond
+	}
+	return &Client{
+		host: h,
+		client: bitswap.NewClient(h, bitswap.Options{
+			Scheme:      schemes[0],
+			Schemes:     schemes[1:],
+			MaxHintSize: uint64(cfg.MaxHintBytes),
+		}),
+		timeout: timeout,
+	}, nil
+}
+
+// Get privately retrieves the block named by c from the peer at peerAddr, a
+// multiaddr ending in /p2p/<id>, blocking until it is retrieved.
+func (cl *Client) Get(peerAddr, c string) ([]byte, error) {
+	return cl.get(context.Background(), peerAddr, c)
+}
+
+func (cl *Client) get(ctx context.Context, peerAddr, c string) ([]byte, error) {
+	root, err := cid.Parse(c)
+	if err != nil {
+		return nil, err
+	}
+	ma, err := multiaddr.NewMultiaddr(peerAddr)
+	if err != nil {
+		return nil, err
+	}
+	ai, err := peer.AddrInfoFromP2pAddr(ma)
+	if err != nil {
+		return nil, err
+	}
+	cl.host.Peerstore().AddAddrs(ai.ID, ai.Addrs, peerstore.PermanentAddrTTL)
+	ctx, cancel := context.WithTimeout(ctx, cl.timeout)
+	defer cancel()
+	return cl.client.PrivateGet(ctx, ai.ID, root)
+}
+
+// Fetch is a retrieval started by GetAsync.
+type Fetch struct {
+	cancel context.CancelFunc
+}
+
+// Cancel abandons the retrieval, whose callback receives an error.
+func (f *Fetch) Cancel() {
+	f.cancel()
+}
+
+// GetAsync retrieves as Get, without blocking, reporting the result to cb.
+func (cl *Client) GetAsync(peerAddr, c string, cb Callback) *Fetch {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		data, err := cl.get(ctx, peerAddr, c)
+		if err != nil {
+			cb.OnError(c, err.Error())
+			return
+		}
+		cb.OnBlock(c, data)
+	}()
+	return &Fetch{cancel: cancel}
+}
+
+// Close ends the client's sessions and stops its host.
+func (cl *Client) Close() error {
+	_ = cl.client.Close()
+	return cl.host.Close()
+}
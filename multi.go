@@ -0,0 +1,190 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// DefaultFanOut is the number of peers a MultiSession asks at once.
+	DefaultFanOut = 3
+	// DefaultBackoff is how long a failing peer is first skipped for.
+	DefaultBackoff = time.Second
+	// DefaultMaxBackoff bounds how long a failing peer is skipped for.
+	DefaultMaxBackoff = time.Minute
+)
+
+var ErrNoPeers = errors.New("no peers available")
+
+type MultiOptions struct {
+	// FanOut is the number of peers asked at once. When one fails, the next
+	// candidate is asked in its place.
+	FanOut int
+	// Private fetches with PrivateGet rather than Get.
+	Private bool
+	// Backoff is how long a peer is skipped after failing, doubling with
+	// each consecutive failure up to MaxBackoff. Peers which simply do not
+	// have a block are not backed off.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// MultiSession races each request across several candidate peers, returning
+// the first valid block and cancelling the requests still outstanding.
+type MultiSession struct {
+	client *Client
+	peers  []peer.ID
+	opts   MultiOptions
+
+	mtx     sync.Mutex
+	backoff map[peer.ID]*backoff
+}
+
+type backoff struct {
+	failures int
+	until    time.Time
+}
+
+// NewMultiSession creates a session fetching from peers through cl. Peers
+// are asked in order of their scores with cl, best first, and in the order
+// given when scored alike.
+func (cl *Client) NewMultiSession(peers []peer.ID, opts MultiOptions) *MultiSession {
+	if opts.FanOut <= 0 {
+		opts.FanOut = DefaultFanOut
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	return &MultiSession{
+		client:  cl,
+		peers:   peers,
+		opts:    opts,
+		backoff: make(map[peer.ID]*backoff),
+	}
+}
+
+// Get fetches the block named by c from whichever candidate returns it
+// first. If no peer has it ErrNotFound is returned; otherwise the error of
+// the last peer to fail.
+func (m *MultiSession) Get(ctx context.Context, c cid.Cid) ([]byte, error) {
+	candidates := m.available()
+	if len(candidates) == 0 {
+		return nil, ErrNoPeers
+	}
+	ctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+
+	type result struct {
+		p    peer.ID
+		data []byte
+		err  error
+	}
+	results := make(chan result, len(candidates))
+	next, inflight := 0, 0
+	launch := func() {
+		p := candidates[next]
+		next++
+		inflight++
+		go func() {
+			data, err := m.fetch(ctx, p, c)
+			results <- result{p, data, err}
+		}()
+	}
+	for inflight < m.opts.FanOut && next < len(candidates) {
+		launch()
+	}
+
+	var lastErr error
+	for inflight > 0 {
+		r := <-results
+		inflight--
+		if r.err == nil {
+			m.succeeded(r.p)
+			return r.data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if r.err != ErrNotFound {
+			m.failed(r.p)
+			lastErr = r.err
+		}
+		logger.Debugw("peer failed", "peer", r.p, "cid", c, "err", r.err)
+		if next < len(candidates) {
+			launch()
+		}
+	}
+	if lastErr == nil {
+		return nil, ErrNotFound
+	}
+	return nil, lastErr
+}
+
+func (m *MultiSession) fetch(ctx context.Context, p peer.ID, c cid.Cid) ([]byte, error) {
+	if m.opts.Private {
+		return m.client.PrivateGet(ctx, p, c)
+	}
+	data, err := m.client.Get(ctx, p, c)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(c, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// available returns the peers not currently backed off, best scored first.
+func (m *MultiSession) available() []peer.ID {
+	m.mtx.Lock()
+	now := time.Now()
+	out := make([]peer.ID, 0, len(m.peers))
+	for _, p := range m.peers {
+		if b, ok := m.backoff[p]; ok && now.Before(b.until) {
+			continue
+		}
+		out = append(out, p)
+	}
+	m.mtx.Unlock()
+
+	scores := make(map[peer.ID]float64, len(out))
+	for _, p := range out {
+		scores[p] = m.client.Scores().Score(p)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return scores[out[i]] > scores[out[j]] })
+	return out
+}
+
+func (m *MultiSession) failed(p peer.ID) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	b, ok := m.backoff[p]
+	if !ok {
+		b = &backoff{}
+		m.backoff[p] = b
+	}
+	b.failures++
+	delay := m.opts.Backoff
+	for i := 1; i < b.failures && delay < m.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > m.opts.MaxBackoff {
+		delay = m.opts.MaxBackoff
+	}
+	b.until = time.Now().Add(delay)
+}
+
+func (m *MultiSession) succeeded(p peer.ID) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.backoff, p)
+}
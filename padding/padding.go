@@ -0,0 +1,105 @@
+// Package padding rounds the sizes of responses and PIR database elements up
+// to a fixed set of buckets, so the length of what a server sends does not
+// tell an observer which block it carries. Any two blocks falling in the
+// same bucket are indistinguishable by size.
+package padding
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrBuckets = errors.New("padding buckets must be positive")
+
+// Policy pads sizes up to the smallest of its buckets which fits them. Sizes
+// larger than every bucket are rounded up to a multiple of the largest. The
+// zero Policy pads nothing.
+type Policy struct {
+	buckets []int
+}
+
+// NewPolicy creates a policy padding to buckets, given in any order.
+func NewPolicy(buckets ...int) (Policy, error) {
+	if len(buckets) == 0 {
+		return Policy{}, ErrBuckets
+	}
+	b := append([]int(nil), buckets...)
+	sort.Ints(b)
+	if b[0] <= 0 {
+		return Policy{}, ErrBuckets
+	}
+	return Policy{buckets: b}, nil
+}
+
+// PowersOfTwo pads to powers of two from min up to max bytes, and to
+// multiples of max beyond. Sizes are revealed to within a factor of two, at
+// a cost of at most doubling them.
+func PowersOfTwo(min, max int) Policy {
+	if min <= 0 {
+		min = 1
+	}
+	var b []int
+	for n := min; n < max; n *= 2 {
+		b = append(b, n)
+	}
+	return Policy{buckets: append(b, max)}
+}
+
+// Buckets returns the sizes the policy pads to, in ascending order.
+func (p Policy) Buckets() []int {
+	return append([]int(nil), p.buckets...)
+}
+
+// Enabled reports whether the policy pads anything.
+func (p Policy) Enabled() bool {
+	return len(p.buckets) > 0
+}
+
+// Size returns the size n bytes are padded to.
+func (p Policy) Size(n int) int {
+	if len(p.buckets) == 0 {
+		return n
+	}
+	i := sort.SearchInts(p.buckets, n)
+	if i < len(p.buckets) {
+		return p.buckets[i]
+	}
+	largest := p.buckets[len(p.buckets)-1]
+	return (n + largest - 1) / largest * largest
+}
+
+// Overhead is the padding added to some payload.
+type Overhead struct {
+	Payload int64
+	Padding int64
+}
+
+// Ratio is the padding added per byte of payload.
+func (o Overhead) Ratio() float64 {
+	if o.Payload == 0 {
+		return 0
+	}
+	return float64(o.Padding) / float64(o.Payload)
+}
+
+// Counter accumulates the overhead of padding. It is safe for concurrent use.
+type Counter struct {
+	mtx sync.Mutex
+	o   Overhead
+}
+
+// Add accounts for payload bytes padded to padded bytes.
+func (c *Counter) Add(payload, padded int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.o.Payload += int64(payload)
+	c.o.Padding += int64(padded - payload)
+}
+
+// Load returns the overhead accumulated so far.
+func (c *Counter) Load() Overhead {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.o
+}
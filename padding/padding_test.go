@@ -0,0 +1,63 @@
+package padding
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestPolicySize(t *testing.T) {
+	p, err := NewPolicy(4096, 1024, 16384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ n, want int }{
+		{0, 1024},
+		{1, 1024},
+		{1024, 1024},
+		{1025, 4096},
+		{16384, 16384},
+		{16385, 32768},
+		{40000, 49152},
+	} {
+		if got := p.Size(tc.n); got != tc.want {
+			t.Errorf("Size(%d) = %d, expected %d", tc.n, got, tc.want)
+		}
+	}
+	if got := (Policy{}).Size(1234); got != 1234 {
+		t.Fatalf("zero policy padded to %d", got)
+	}
+	if _, err := NewPolicy(0, 16); !errors.Is(err, ErrBuckets) {
+		t.Fatalf("accepted an empty bucket: %v", err)
+	}
+}
+
+func TestPowersOfTwo(t *testing.T) {
+	p := PowersOfTwo(256, 1<<20)
+	if b := p.Buckets(); len(b) != 13 || b[0] != 256 || b[12] != 1<<20 {
+		t.Fatalf("buckets %v", b)
+	}
+	if got := p.Size(300); got != 512 {
+		t.Fatalf("300 padded to %d", got)
+	}
+	if got := p.Size(3<<20 - 1); got != 3<<20 {
+		t.Fatalf("large size padded to %d", got)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	var c Counter
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Add(100, 128)
+		}()
+	}
+	wg.Wait()
+	o := c.Load()
+	if o.Payload != 800 || o.Padding != 224 || o.Ratio() != 0.28 {
+		t.Fatalf("accounted %+v", o)
+	}
+}
@@ -0,0 +1,190 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrNotPair is returned by NewPair when the scheme is not split across
+	// two servers, or both peers are the same.
+	ErrNotPair = errors.New("a pair needs a two-server scheme and two distinct peers")
+	// ErrPairMismatch is returned when the peers of a Pair do not hold the
+	// same databases, so their answers cannot be combined.
+	ErrPairMismatch = errors.New("peers of the pair hold different databases")
+)
+
+// Pair retrieves blocks privately from two peers holding the same blocks,
+// with a two-server scheme such as xorpir. Each query is split between the
+// peers, which learn nothing of it unless they collude, and whose answers
+// are far cheaper to compute than those of single-server schemes. The peers
+// must lay their blocks out alike, as stores loaded from the same blocks
+// are.
+type Pair struct {
+	scheme   pir.MultiServer
+	sessions [2]*Session
+}
+
+// NewPair creates a pair of sessions retrieving from a and b with scheme,
+// configured by opts otherwise. The sessions do not fall back to plaintext.
+func NewPair(h host.Host, a, b peer.ID, scheme pir.MultiServer, opts Options) (*Pair, error) {
+	if scheme.Servers() != 2 || a == b {
+		return nil, ErrNotPair
+	}
+	opts.Scheme, opts.Schemes = scheme, nil
+	opts.AllowPlaintextFallback = false
+	return &Pair{
+		scheme:   scheme,
+		sessions: [2]*Session{New(h, a, opts), New(h, b, opts)},
+	}, nil
+}
+
+// Close closes the sessions with both peers.
+func (p *Pair) Close() error {
+	for _, s := range p.sessions {
+		_ = s.Close()
+	}
+	return nil
+}
+
+// both runs f for each session at once, returning the first error.
+func (p *Pair) both(f func(i int, s *Session) error) error {
+	errs := make(chan error, len(p.sessions))
+	for i, s := range p.sessions {
+		go func(i int, s *Session) { errs <- f(i, s) }(i, s)
+	}
+	var first error
+	for range p.sessions {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// params returns the parameters of both peers, which must describe the same
+// databases.
+func (p *Pair) params(ctx context.Context) ([2]PeerParams, error) {
+	var pps [2]PeerParams
+	err := p.both(func(i int, s *Session) (err error) {
+		pps[i], err = s.privateParams(ctx)
+		return err
+	})
+	if err != nil {
+		return pps, err
+	}
+	if !sameParams(pps[0].Index, pps[1].Index) || !sameParams(pps[0].Blocks, pps[1].Blocks) {
+		return pps, ErrPairMismatch
+	}
+	return pps, nil
+}
+
+func sameParams(a, b pir.Params) bool {
+	return a.Scheme == b.Scheme && a.NumElements == b.NumElements &&
+		a.ElementSize == b.ElementSize && string(a.Extra) == string(b.Extra)
+}
+
+// query runs one round with both peers, retrieving the elements at each of
+// indices.
+func (p *Pair) query(ctx context.Context, round bitswap_message_pb.Message_PIRRound, sessions [2]uint64, pps [2]PeerParams, params func(PeerParams) pir.Params, indices ...uint64) ([][]byte, error) {
+	var queries [2][][]byte
+	secrets := make([]pir.Secret, len(indices))
+	for i, index := range indices {
+		qs, secret, err := p.scheme.QueryServers(params(pps[0]), index)
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = secret
+		for j := range queries {
+			queries[j] = append(queries[j], qs[j])
+		}
+	}
+	var answers [2][][]byte
+	err := p.both(func(j int, s *Session) (err error) {
+		answers[j], err = s.ask(ctx, sessions[j], round, pps[j].Epoch, params(pps[j]), queries[j], nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	elements := make([][]byte, len(indices))
+	for i := range indices {
+		if elements[i], err = p.scheme.DecodeServers(params(pps[0]), secrets[i], [][]byte{answers[0][i], answers[1][i]}); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// PrivateGet retrieves a block from the pair without revealing which CID
+// was requested to either peer, in the same two rounds as
+// Session.PrivateGet, each split between the peers. Retrieved blocks are
+// verified against c before being returned.
+func (p *Pair) PrivateGet(ctx context.Context, c cid.Cid) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PairPrivateGet", trace.WithAttributes(
+		attribute.String("peer", p.sessions[0].peer.String()),
+		attribute.String("peer2", p.sessions[1].peer.String()),
+	))
+	defer func() { endSpan(span, err) }()
+	if data, ok, err := p.sessions[0].cached(ctx, c); ok || err != nil {
+		return data, err
+	}
+	pps, err := p.params(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sessions [2]uint64
+	for i, s := range p.sessions {
+		sessions[i] = atomic.AddUint64(&s.pirSession, 1)
+	}
+
+	key := []byte(c.Hash())
+	slots := keyword.Slots(key, pps[0].Index.NumElements)
+	index := func(pp PeerParams) pir.Params { return pp.Index }
+	found, err := p.query(ctx, bitswap_message_pb.Message_IndexRound, sessions, pps, index, slots[:]...)
+	if err != nil {
+		return nil, err
+	}
+	pos, ok := uint64(0), false
+	for _, slot := range found {
+		if pos, ok = keyword.Find(slot, key); ok {
+			break
+		}
+	}
+	if !ok || pos >= pps[0].Blocks.NumElements {
+		pos, ok = 0, false
+	}
+	span.SetAttributes(attribute.Bool("found", ok))
+
+	blocks := func(pp PeerParams) pir.Params { return pp.Blocks }
+	element, err := p.query(ctx, bitswap_message_pb.Message_BlockRound, sessions, pps, blocks, pos)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	blk, err := open(pps[0], c, element[0])
+	if errors.Is(err, ErrBadBlock) {
+		return nil, fmt.Errorf("%w: the peers may hold different databases", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.sessions[0].record(ctx, c, blk); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
@@ -0,0 +1,126 @@
+package bitswap
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/willscott/go-selfish-bitswap-client/filter"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+)
+
+// PeerParams are the public parameters of a peer's PIR databases, as sent in
+// the handshake.
+type PeerParams struct {
+	// Epoch is the version of the databases described, or zero if the peer
+	// does not version them.
+	Epoch  uint64
+	Index  pir.Params
+	Blocks pir.Params
+	// IndexBatch and BlocksBatch describe the batched layouts of the
+	// databases, and are nil if the peer does not answer batched queries.
+	IndexBatch  *batch.Params
+	BlocksBatch *batch.Params
+	// Compression is the compression the peer accepted in the handshake,
+	// empty for none.
+	Compression string
+	// Manifest is the peer's signed commitment to the CID→index map of the
+	// databases, nil if it sent none.
+	Manifest *Manifest
+	// Filter is the peer's presence filter of the CIDs it held at the
+	// handshake, nil if it sent none.
+	Filter *filter.Bloom
+	// Group is the content group the databases lay out, or cid.Undef for
+	// the peer's whole store, and Groups the roots of all the groups the
+	// peer lays out apart.
+	Group  cid.Cid
+	Groups []cid.Cid
+	// offered is the group offered in the handshake, and received when it
+	// was run.
+	offered  cid.Cid
+	received time.Time
+}
+
+// ParamCache remembers the PIR parameters of peers so the handshake is only
+// run once per peer. The parameters hold the hints of their databases, so
+// hints are forgotten along with them, once the databases change. It is safe
+// for concurrent use.
+type ParamCache struct {
+	mtx    sync.Mutex
+	params map[peer.ID]PeerParams
+	// stale holds the parameters last forgotten of each peer, whose hints
+	// the new ones are patched from.
+	stale map[peer.ID]PeerParams
+	// manifests holds the manifests witnessed of each peer, which outlive
+	// the parameters they were sent with.
+	manifests map[manifestKey][]Manifest
+}
+
+// NewParamCache returns an empty cache.
+func NewParamCache() *ParamCache {
+	return &ParamCache{params: make(map[peer.ID]PeerParams), stale: make(map[peer.ID]PeerParams)}
+}
+
+// Get returns the cached parameters of p.
+func (pc *ParamCache) Get(p peer.ID) (PeerParams, bool) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	pp, ok := pc.params[p]
+	return pp, ok
+}
+
+// Put records the parameters of p.
+func (pc *ParamCache) Put(p peer.ID, pp PeerParams) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	pc.params[p] = pp
+	delete(pc.stale, p)
+}
+
+// Forget drops the parameters of p, so they are requested again on next use.
+// Their hints are kept until then, so that only the changes to them need be
+// downloaded.
+func (pc *ParamCache) Forget(p peer.ID) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	if pp, ok := pc.params[p]; ok {
+		pc.stale[p] = pp
+	}
+	delete(pc.params, p)
+}
+
+// staleHint returns the hint of the database of scheme laying out group, the
+// blocks database if blocks is set, in the parameters last forgotten of p,
+// and their epoch.
+func (pc *ParamCache) staleHint(p peer.ID, scheme string, group cid.Cid, blocks bool) ([]byte, uint64, bool) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	pp, ok := pc.stale[p]
+	params := pp.Index
+	if blocks {
+		params = pp.Blocks
+	}
+	if !ok || pp.Epoch == 0 || params.Scheme != scheme || pp.Group != group || params.Hint == nil {
+		return nil, 0, false
+	}
+	return params.Hint, pp.Epoch, true
+}
+
+// hint returns a cached hint named by digest, which peers holding the same
+// database share.
+func (pc *ParamCache) hint(digest []byte) ([]byte, bool) {
+	pc.mtx.Lock()
+	defer pc.mtx.Unlock()
+	for _, pp := range pc.params {
+		for _, params := range []pir.Params{pp.Index, pp.Blocks} {
+			if params.Hint != nil && bytes.Equal(params.HintDigest, digest) {
+				return params.Hint, true
+			}
+		}
+	}
+	return nil, false
+}
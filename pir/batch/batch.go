@@ -0,0 +1,344 @@
+// Package batch implements batch PIR on top of index based PIR schemes with
+// a probabilistic batch code.
+//
+// The database is split into Buckets buckets and every element is replicated
+// into each of its NumHashes candidate buckets. To retrieve up to a batch of
+// elements a client assigns each of them to a distinct candidate bucket with
+// cuckoo hashing and sends exactly one query per bucket, dummy queries for
+// the buckets left empty. Each bucket holds about NumHashes/Buckets of the
+// database, so the server answers the whole batch for around NumHashes
+// passes over the database instead of one pass per element, and learns
+// nothing about which buckets carried real queries.
+package batch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"sort"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+const (
+	// NumHashes is the number of candidate buckets of each element.
+	NumHashes = 3
+	// maxKicks bounds the eviction walk when assigning a batch to buckets.
+	maxKicks = 100
+)
+
+var (
+	// ErrNoBucket is returned when a query names a bucket the database does not have.
+	ErrNoBucket = errors.New("batch: bucket out of range")
+	// ErrNoUpdate is returned by Update when the scheme is not a pir.Updater.
+	ErrNoUpdate = errors.New("batch: scheme cannot update encoded databases")
+	// ErrUnevenBuckets is returned by Setup when the scheme produced
+	// different parameters for buckets of the same size.
+	ErrUnevenBuckets = errors.New("batch: scheme parameters differ between buckets")
+)
+
+// Params are the public parameters of a batched database.
+type Params struct {
+	// NumElements is the number of elements of the underlying database.
+	NumElements uint64
+	// BatchSize is the number of elements the layout was sized to retrieve
+	// at once. Assignment of larger batches is likely to leave some over.
+	BatchSize uint64
+	// Buckets is the number of buckets, and of queries in every batch.
+	Buckets uint64
+	// Bucket are the parameters of every bucket. Buckets are padded to the
+	// same size so that one set of parameters describes them all.
+	Bucket pir.Params
+}
+
+// Encoded is a database laid out in buckets, each encoded by a Scheme.
+type Encoded struct {
+	Params  Params
+	Buckets []*pir.Encoded
+	// layout is the Layout of the buckets.
+	layout [][]uint64
+}
+
+// NumBuckets returns the number of buckets used for batches of batchSize.
+func NumBuckets(batchSize int) uint64 {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return uint64((3*batchSize + 1) / 2)
+}
+
+// Candidates returns the candidate buckets of the element at index.
+// Candidates may repeat, in which case the element is stored once in that
+// bucket.
+func Candidates(index, buckets uint64) [NumHashes]uint64 {
+	var c [NumHashes]uint64
+	if buckets == 0 {
+		return c
+	}
+	for i := range c {
+		c[i] = mix(index*NumHashes+uint64(i)) % buckets
+	}
+	return c
+}
+
+// mix is the splitmix64 finalizer. Buckets need only be spread evenly, not
+// be hard to predict, so a cheap hash keeps Layout fast on large databases.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Layout returns the indices of the elements held by each bucket, in
+// ascending order. An element's position in a bucket is its place in that
+// bucket's list.
+func Layout(numElements, buckets uint64) [][]uint64 {
+	out := make([][]uint64, buckets)
+	for j := uint64(0); j < numElements; j++ {
+		cands := Candidates(j, buckets)
+		for i, b := range cands {
+			if !repeated(cands, i) {
+				out[b] = append(out[b], j)
+			}
+		}
+	}
+	return out
+}
+
+// repeated reports whether the i'th candidate already occurs before i.
+func repeated(c [NumHashes]uint64, i int) bool {
+	for _, b := range c[:i] {
+		if b == c[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Setup lays db out in buckets for batches of batchSize and encodes every
+// bucket with scheme.
+func Setup(scheme pir.Scheme, db pir.Database, batchSize int) (*Encoded, error) {
+	buckets := NumBuckets(batchSize)
+	layout := Layout(uint64(len(db.Elements)), buckets)
+	size := 1
+	for _, members := range layout {
+		if len(members) > size {
+			size = len(members)
+		}
+	}
+	enc := &Encoded{
+		Params: Params{
+			NumElements: uint64(len(db.Elements)),
+			BatchSize:   uint64(batchSize),
+			Buckets:     buckets,
+		},
+		Buckets: make([]*pir.Encoded, buckets),
+		layout:  layout,
+	}
+	for b, members := range layout {
+		bucket := pir.Database{Elements: make([][]byte, size), ElementSize: db.ElementSize}
+		for pos, j := range members {
+			bucket.Elements[pos] = db.Elements[j]
+		}
+		e, err := scheme.Setup(bucket)
+		if err != nil {
+			return nil, err
+		}
+		if b > 0 && !sameParams(e.Params, enc.Buckets[0].Params) {
+			return nil, ErrUnevenBuckets
+		}
+		enc.Buckets[b] = e
+	}
+	enc.Params.Bucket = enc.Buckets[0].Params
+	return enc, nil
+}
+
+func sameParams(a, b pir.Params) bool {
+	return a.Scheme == b.Scheme && a.NumElements == b.NumElements &&
+		a.ElementSize == b.ElementSize && bytes.Equal(a.Extra, b.Extra)
+}
+
+// Update returns a copy of db with the elements at each index of changes
+// replaced in every bucket holding them, through scheme, which must be a
+// pir.Updater. Buckets without changes are shared with db.
+func Update(scheme pir.Scheme, db *Encoded, changes map[uint64][]byte) (*Encoded, error) {
+	u, ok := scheme.(pir.Updater)
+	if !ok {
+		return nil, ErrNoUpdate
+	}
+	perBucket := make(map[uint64]map[uint64][]byte)
+	for j, e := range changes {
+		if j >= db.Params.NumElements {
+			return nil, pir.ErrIndexOutOfRange
+		}
+		cands := Candidates(j, db.Params.Buckets)
+		for i, b := range cands {
+			if repeated(cands, i) {
+				continue
+			}
+			members := db.layout[b]
+			pos := sort.Search(len(members), func(k int) bool { return members[k] >= j })
+			if perBucket[b] == nil {
+				perBucket[b] = make(map[uint64][]byte)
+			}
+			perBucket[b][uint64(pos)] = e
+		}
+	}
+	next := &Encoded{Params: db.Params, Buckets: append([]*pir.Encoded(nil), db.Buckets...), layout: db.layout}
+	for b, bc := range perBucket {
+		e, err := u.Update(db.Buckets[b], bc)
+		if err != nil {
+			return nil, err
+		}
+		next.Buckets[b] = e
+	}
+	return next, nil
+}
+
+// Answer computes the response to a query for bucket of db.
+func Answer(scheme pir.Scheme, db *Encoded, bucket uint64, query []byte) ([]byte, error) {
+	if bucket >= uint64(len(db.Buckets)) {
+		return nil, ErrNoBucket
+	}
+	return scheme.Answer(db.Buckets[bucket], query)
+}
+
+// Query is the query a client sends for one bucket.
+type Query struct {
+	// Position is the place of the element retrieved within the bucket.
+	Position uint64
+	// Index is the element of the database retrieved, if not Dummy.
+	Index uint64
+	// Dummy queries retrieve nothing of use and only hide which buckets
+	// were needed.
+	Dummy bool
+}
+
+// Plan assigns indices to buckets, returning one query per bucket. Indices
+// which could not be assigned because their candidate buckets were taken are
+// returned in rest, for a later batch. Repeated indices share one query, and
+// indices out of range are ignored.
+func Plan(params Params, indices []uint64) (queries []Query, rest []uint64) {
+	queries = make([]Query, params.Buckets)
+	for i := range queries {
+		queries[i].Dummy = true
+	}
+	if params.Buckets == 0 {
+		return queries, indices
+	}
+	seen := make(map[uint64]struct{}, len(indices))
+	rng := rand.New(rand.NewSource(int64(len(indices))))
+	for _, index := range indices {
+		if _, ok := seen[index]; ok || index >= params.NumElements {
+			continue
+		}
+		seen[index] = struct{}{}
+		if evicted, ok := assign(queries, index, params.Buckets, rng); !ok {
+			delete(seen, evicted)
+			rest = append(rest, evicted)
+		}
+	}
+
+	// positions follow from the layout of every element up to the last one
+	// assigned.
+	last, assigned := uint64(0), false
+	for _, q := range queries {
+		if !q.Dummy && (!assigned || q.Index > last) {
+			last, assigned = q.Index, true
+		}
+	}
+	if !assigned {
+		return queries, rest
+	}
+	counts := make([]uint64, params.Buckets)
+	for j := uint64(0); j <= last; j++ {
+		cands := Candidates(j, params.Buckets)
+		for i, b := range cands {
+			if repeated(cands, i) {
+				continue
+			}
+			if q := &queries[b]; !q.Dummy && q.Index == j {
+				q.Position = counts[b]
+			}
+			counts[b]++
+		}
+	}
+	return queries, rest
+}
+
+// assign places index in one of its candidate buckets, evicting along a
+// random walk when they are all taken. If the walk gives up, the index left
+// without a bucket is returned.
+func assign(queries []Query, index, buckets uint64, rng *rand.Rand) (uint64, bool) {
+	for kick := 0; kick < maxKicks; kick++ {
+		cands := Candidates(index, buckets)
+		for _, b := range cands {
+			if queries[b].Dummy {
+				queries[b] = Query{Index: index}
+				return 0, true
+			}
+		}
+		b := cands[rng.Intn(NumHashes)]
+		index, queries[b].Index = queries[b].Index, index
+	}
+	return index, false
+}
+
+// Marshal encodes db, every bucket saved with scheme, which must be a
+// pir.Persister, for Unmarshal.
+func Marshal(scheme pir.Scheme, db *Encoded) ([]byte, error) {
+	out := appendUvarint(nil, db.Params.NumElements)
+	out = appendUvarint(out, db.Params.BatchSize)
+	out = appendUvarint(out, db.Params.Buckets)
+	for _, b := range db.Buckets {
+		bucket, err := pir.Marshal(scheme, b)
+		if err != nil {
+			return nil, err
+		}
+		out = pir.AppendAligned(out, bucket)
+	}
+	return out, nil
+}
+
+// Unmarshal restores a database saved by Marshal with scheme.
+func Unmarshal(scheme pir.Scheme, data []byte) (*Encoded, error) {
+	var fields [3]uint64
+	for i := range fields {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, pir.ErrMalformed
+		}
+		fields[i], data = v, data[n:]
+	}
+	params := Params{NumElements: fields[0], BatchSize: fields[1], Buckets: fields[2]}
+	if params.Buckets == 0 || params.Buckets != NumBuckets(int(params.BatchSize)) {
+		return nil, pir.ErrMalformed
+	}
+	enc := &Encoded{
+		Params:  params,
+		Buckets: make([]*pir.Encoded, params.Buckets),
+		layout:  Layout(params.NumElements, params.Buckets),
+	}
+	for b := range enc.Buckets {
+		part, rest, err := pir.Aligned(data)
+		if err != nil {
+			return nil, err
+		}
+		if enc.Buckets[b], err = pir.Unmarshal(scheme, part); err != nil {
+			return nil, err
+		}
+		data = rest
+	}
+	if len(data) != 0 {
+		return nil, pir.ErrMalformed
+	}
+	enc.Params.Bucket = enc.Buckets[0].Params
+	return enc, nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
@@ -0,0 +1,122 @@
+package batch_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+)
+
+func TestLayout(t *testing.T) {
+	layout := batch.Layout(1000, batch.NumBuckets(16))
+	held := make(map[uint64]int)
+	for b, members := range layout {
+		for i, j := range members {
+			if i > 0 && members[i-1] >= j {
+				t.Fatalf("bucket %d is not in ascending order", b)
+			}
+			held[j]++
+		}
+	}
+	for j := uint64(0); j < 1000; j++ {
+		if held[j] < 1 || held[j] > batch.NumHashes {
+			t.Fatalf("element %d held by %d buckets", j, held[j])
+		}
+	}
+}
+
+func TestRoundtrip(t *testing.T) {
+	scheme := fastpir.New()
+	db := pirtest.RandomDatabase(200, 32)
+	enc, err := batch.Setup(scheme, db, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(enc.Buckets)) != enc.Params.Buckets {
+		t.Fatalf("%d buckets encoded, %d advertised", len(enc.Buckets), enc.Params.Buckets)
+	}
+
+	pending := []uint64{3, 17, 17, 42, 99, 100, 150, 151, 199, 0}
+	got := make(map[uint64][]byte)
+	for rounds := 0; len(pending) > 0; rounds++ {
+		if rounds > 5 {
+			t.Fatalf("batch not retrieved after %d rounds", rounds)
+		}
+		queries, rest := batch.Plan(enc.Params, pending)
+		if uint64(len(queries)) != enc.Params.Buckets {
+			t.Fatalf("%d queries for %d buckets", len(queries), enc.Params.Buckets)
+		}
+		for b, q := range queries {
+			query, secret, err := scheme.Query(enc.Params.Bucket, q.Position)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := batch.Answer(scheme, enc, uint64(b), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := scheme.Decode(enc.Params.Bucket, secret, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !q.Dummy {
+				got[q.Index] = e
+			}
+		}
+		pending = rest
+	}
+	for _, j := range []uint64{0, 3, 17, 42, 99, 100, 150, 151, 199} {
+		if !bytes.Equal(got[j], db.Elements[j]) {
+			t.Fatalf("element %d retrieved incorrectly", j)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	scheme := fastpir.New()
+	db := pirtest.RandomDatabase(50, 16)
+	enc, err := batch.Setup(scheme, db, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := pirtest.RandomDatabase(1, 16).Elements[0]
+	updated, err := batch.Update(scheme, enc, map[uint64][]byte{7: changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := batch.Layout(50, enc.Params.Buckets)
+	for b, members := range layout {
+		for pos, j := range members {
+			want := db.Elements[j]
+			if j == 7 {
+				want = changed
+			}
+			if got := pirtest.Get(t, scheme, updated.Buckets[b], uint64(pos)); !bytes.Equal(got, want) {
+				t.Fatalf("bucket %d holds %x for element %d, expected %x", b, got, j, want)
+			}
+		}
+	}
+}
+
+func TestPlanOverflow(t *testing.T) {
+	params := batch.Params{NumElements: 1000, BatchSize: 4, Buckets: batch.NumBuckets(4)}
+	indices := make([]uint64, 20)
+	for i := range indices {
+		indices[i] = uint64(i * 37)
+	}
+	queries, rest := batch.Plan(params, indices)
+	assigned := 0
+	for _, q := range queries {
+		if !q.Dummy {
+			assigned++
+		}
+	}
+	if assigned+len(rest) != len(indices) {
+		t.Fatalf("%d assigned and %d left over of %d", assigned, len(rest), len(indices))
+	}
+	if assigned > int(params.Buckets) {
+		t.Fatalf("%d assigned to %d buckets", assigned, params.Buckets)
+	}
+}
@@ -0,0 +1,26 @@
+package pir
+
+import "time"
+
+// DefaultAnswerCost is the time per byte of database assumed of answers of
+// schemes which are not CostModels.
+const DefaultAnswerCost = time.Nanosecond
+
+// CostModel is implemented by schemes which can estimate the work of an
+// answer before computing it, so servers can bound the work they take on.
+type CostModel interface {
+	// AnswerCost estimates the time Answer takes, on one core, over a
+	// database described by params.
+	AnswerCost(params Params) time.Duration
+}
+
+// AnswerCost estimates the time scheme takes to answer a query over a
+// database described by params: its own estimate if it is a CostModel, or
+// else DefaultAnswerCost per byte of the database. Estimates are rough, and
+// vary with the hardware; they serve to weigh queries against each other.
+func AnswerCost(scheme Scheme, params Params) time.Duration {
+	if cm, ok := scheme.(CostModel); ok {
+		return cm.AnswerCost(params)
+	}
+	return time.Duration(params.NumElements*params.ElementSize) * DefaultAnswerCost
+}
@@ -0,0 +1,84 @@
+package pir
+
+import "io"
+
+// StreamDecoder is implemented by schemes which can decode answers as they
+// arrive, piece by piece, so clients hold what is left of an answer to
+// decode rather than all of it, and decode while the rest arrives.
+type StreamDecoder interface {
+	// NewDecoder returns a Decoder of the answer to the query secret was
+	// returned with, by the database described by params.
+	NewDecoder(params Params, secret Secret) (Decoder, error)
+}
+
+// Decoder decodes an answer written to it in pieces of any size.
+type Decoder interface {
+	io.Writer
+	// Element returns the queried element once the whole answer has been
+	// written, failing with ErrMalformed if it has not.
+	Element() ([]byte, error)
+}
+
+// UnitDecoder returns a Decoder of answers made of units of size bytes
+// each, which passes each unit to decode as soon as it has arrived whole,
+// and once all have returns the element made by element.
+func UnitDecoder(units, size int, decode func(i int, unit []byte) error, element func() []byte) Decoder {
+	return &unitDecoder{units: units, size: size, decode: decode, element: element}
+}
+
+type unitDecoder struct {
+	units, size int
+	decode      func(i int, unit []byte) error
+	element     func() []byte
+	// n is the number of units decoded, and buf holds the start of the
+	// next when only part of it has arrived.
+	n   int
+	buf []byte
+}
+
+func (d *unitDecoder) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if d.n == d.units {
+			return 0, ErrMalformed
+		}
+		if len(d.buf) == 0 && len(p) >= d.size {
+			// whole units are decoded where they are.
+			if err := d.decode(d.n, p[:d.size]); err != nil {
+				return 0, err
+			}
+			d.n++
+			p = p[d.size:]
+			continue
+		}
+		k := d.size - len(d.buf)
+		if k > len(p) {
+			k = len(p)
+		}
+		d.buf, p = append(d.buf, p[:k]...), p[k:]
+		if len(d.buf) == d.size {
+			if err := d.decode(d.n, d.buf); err != nil {
+				return 0, err
+			}
+			d.n++
+			d.buf = d.buf[:0]
+		}
+	}
+	return written, nil
+}
+
+func (d *unitDecoder) Element() ([]byte, error) {
+	if d.n != d.units || len(d.buf) != 0 {
+		return nil, ErrMalformed
+	}
+	return d.element(), nil
+}
+
+// DecodeAll decodes a whole answer with d, as schemes which are
+// StreamDecoders implement Decode.
+func DecodeAll(d Decoder, answer []byte) ([]byte, error) {
+	if _, err := d.Write(answer); err != nil {
+		return nil, err
+	}
+	return d.Element()
+}
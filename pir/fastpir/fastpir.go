@@ -0,0 +1,269 @@
+// Package fastpir is a pure Go single-server PIR scheme following the
+// structure of FastPIR: there is no offline phase, the query is an encrypted
+// selection vector over the database columns, and the answer is computed as a
+// column-wise inner product of the database with the query.
+//
+// FastPIR packs the selection vector into a single BFV ciphertext. This port
+// uses secret-key Regev (LWE) encryption instead, with the public half of
+// each ciphertext expanded from a seed, which keeps queries at one word per
+// element at the cost of larger answers.
+package fastpir
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/lwe"
+)
+
+// N is the LWE secret dimension.
+const N = 1024
+
+// ID is the scheme identifier advertised to peers.
+const ID = "fastpir-lwe1024/v1"
+
+// Scheme implements pir.Scheme.
+type Scheme struct {
+	workers int32
+}
+
+var (
+	_ pir.Scheme        = (*Scheme)(nil)
+	_ pir.Updater       = (*Scheme)(nil)
+	_ pir.Persister     = (*Scheme)(nil)
+	_ pir.Parallel      = (*Scheme)(nil)
+	_ pir.StreamDecoder = (*Scheme)(nil)
+	_ pir.CostModel     = (*Scheme)(nil)
+)
+
+// New returns the FastPIR scheme.
+func New() *Scheme {
+	return &Scheme{}
+}
+
+type state struct {
+	logp   int
+	digits int
+	// db holds the digits of each element contiguously.
+	db []uint32
+}
+
+type secret struct {
+	s []uint32
+}
+
+func (s *Scheme) ID() string {
+	return ID
+}
+
+// AnswerCost is about 250ns per byte of the database, for the products of
+// each of its digits with a query ciphertext of N words.
+func (s *Scheme) AnswerCost(params pir.Params) time.Duration {
+	return time.Duration(params.NumElements*params.ElementSize) * 250
+}
+
+// SetWorkers splits each answer across n goroutines, each over a share of
+// the elements, summing their partial answers.
+func (s *Scheme) SetWorkers(n int) {
+	atomic.StoreInt32(&s.workers, int32(n))
+}
+
+func (s *Scheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	if db.ElementSize <= 0 {
+		return nil, fmt.Errorf("fastpir: invalid element size %d", db.ElementSize)
+	}
+	logp := lwe.PlaintextBits(len(db.Elements))
+	m := lwe.NumDigits(db.ElementSize, logp)
+	st := &state{logp: logp, digits: m, db: make([]uint32, m*len(db.Elements))}
+	for j, e := range db.Elements {
+		if len(e) > db.ElementSize {
+			return nil, fmt.Errorf("fastpir: element %d is %d bytes, larger than %d", j, len(e), db.ElementSize)
+		}
+		lwe.Split(st.db[j*m:(j+1)*m], e, logp)
+	}
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      ID,
+			NumElements: uint64(len(db.Elements)),
+			ElementSize: uint64(db.ElementSize),
+			Extra:       []byte{byte(logp)},
+		},
+		State: st,
+	}, nil
+}
+
+// Update re-encodes only the digits of the changed elements, in a copy of
+// the database.
+func (s *Scheme) Update(db *pir.Encoded, changes map[uint64][]byte) (*pir.Encoded, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	m := st.digits
+	next := &state{logp: st.logp, digits: m, db: append([]uint32(nil), st.db...)}
+	for j, e := range changes {
+		if j >= db.Params.NumElements {
+			return nil, pir.ErrIndexOutOfRange
+		}
+		if uint64(len(e)) > db.Params.ElementSize {
+			return nil, fmt.Errorf("fastpir: element %d is %d bytes, larger than %d", j, len(e), db.Params.ElementSize)
+		}
+		lwe.Split(next.db[int(j)*m:int(j+1)*m], e, st.logp)
+	}
+	return &pir.Encoded{Params: db.Params, State: next}, nil
+}
+
+// MarshalState returns the digits of the elements.
+func (s *Scheme) MarshalState(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return lwe.PutWords(st.db), nil
+}
+
+// UnmarshalState restores the digits saved by MarshalState, read from data
+// in place where it is aligned; Update copies them before changing any.
+func (s *Scheme) UnmarshalState(params pir.Params, data []byte) (interface{}, error) {
+	logp, err := logpOf(params)
+	if err != nil {
+		return nil, err
+	}
+	m := lwe.NumDigits(int(params.ElementSize), logp)
+	if uint64(len(data)) != 4*uint64(m)*params.NumElements {
+		return nil, pir.ErrMalformed
+	}
+	return &state{logp: logp, digits: m, db: lwe.View(data)}, nil
+}
+
+func logpOf(params pir.Params) (int, error) {
+	if params.Scheme != ID {
+		return 0, pir.ErrSchemeMismatch
+	}
+	if len(params.Extra) != 1 || params.Extra[0] == 0 || params.Extra[0] > 8 {
+		return 0, pir.ErrMalformed
+	}
+	return int(params.Extra[0]), nil
+}
+
+// Query encodes an LWE encryption of the selection vector for index. The
+// query is the seed of the public matrix followed by one word per element.
+func (s *Scheme) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	logp, err := logpOf(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	n := int(params.NumElements)
+	seed, err := lwe.NewSeed()
+	if err != nil {
+		return nil, nil, err
+	}
+	sk, err := lwe.Secret(N)
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := lwe.Errors(n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q := make([]byte, lwe.SeedSize+4*n)
+	copy(q, seed)
+	prg := lwe.NewPRG(seed)
+	a := make([]uint32, N)
+	for j := 0; j < n; j++ {
+		prg.Fill(a)
+		b := lwe.Dot(a, sk) + e[j]
+		if uint64(j) == index {
+			b += lwe.Delta(logp)
+		}
+		binary.LittleEndian.PutUint32(q[lwe.SeedSize+4*j:], b)
+	}
+	return q, &secret{sk}, nil
+}
+
+// Answer returns, for each digit row of the database, the inner product of
+// that row with the query ciphertexts: N words of the combined public part
+// followed by the combined body.
+func (s *Scheme) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	n := int(db.Params.NumElements)
+	if len(query) != lwe.SeedSize+4*n {
+		return nil, pir.ErrMalformed
+	}
+	m := st.digits
+	workers := int(atomic.LoadInt32(&s.workers))
+	parts := make([][]uint32, workers+1)
+	pir.Split(workers, n, func(w, lo, hi int) {
+		acc := make([]uint32, m*(N+1))
+		prg := lwe.NewPRGAt(query[:lwe.SeedSize], uint64(lo)*N)
+		a := make([]uint32, N)
+		for j := lo; j < hi; j++ {
+			prg.Fill(a)
+			b := binary.LittleEndian.Uint32(query[lwe.SeedSize+4*j:])
+			col := st.db[j*m : (j+1)*m]
+			for r, d := range col {
+				if d == 0 {
+					continue
+				}
+				row := acc[r*(N+1) : (r+1)*(N+1)]
+				lwe.MulAdd(row[:N], a, d)
+				row[N] += d * b
+			}
+		}
+		parts[w] = acc
+	})
+	acc := parts[0]
+	for _, part := range parts[1:] {
+		if part != nil {
+			lwe.MulAdd(acc, part, 1)
+		}
+	}
+	out := make([]byte, 4*len(acc))
+	for i, v := range acc {
+		binary.LittleEndian.PutUint32(out[4*i:], v)
+	}
+	return out, nil
+}
+
+func (s *Scheme) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	d, err := s.NewDecoder(params, sec)
+	if err != nil {
+		return nil, err
+	}
+	return pir.DecodeAll(d, answer)
+}
+
+// NewDecoder decodes answers a row at a time, each into one digit of the
+// element.
+func (s *Scheme) NewDecoder(params pir.Params, sec pir.Secret) (pir.Decoder, error) {
+	logp, err := logpOf(params)
+	if err != nil {
+		return nil, err
+	}
+	sk, ok := sec.(*secret)
+	if !ok {
+		return nil, pir.ErrSchemeMismatch
+	}
+	size := int(params.ElementSize)
+	row := make([]uint32, N+1)
+	digits := make([]uint32, lwe.NumDigits(size, logp))
+	return pir.UnitDecoder(len(digits), 4*(N+1), func(r int, unit []byte) error {
+		for k := range row {
+			row[k] = binary.LittleEndian.Uint32(unit[4*k:])
+		}
+		digits[r] = lwe.Round(row[N]-lwe.Dot(row[:N], sk.s), logp)
+		return nil
+	}, func() []byte {
+		return lwe.Join(digits, size, logp)
+	}), nil
+}
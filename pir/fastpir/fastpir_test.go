@@ -0,0 +1,40 @@
+package fastpir_test
+
+import (
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+)
+
+func TestRoundtrip(t *testing.T) {
+	pirtest.Roundtrip(t, fastpir.New())
+}
+
+func TestUpdate(t *testing.T) {
+	pirtest.Update(t, fastpir.New())
+}
+
+func TestPersist(t *testing.T) {
+	pirtest.Persist(t, fastpir.New())
+}
+
+func TestParallel(t *testing.T) {
+	pirtest.Parallel(t, fastpir.New())
+}
+
+func TestStreamDecode(t *testing.T) {
+	pirtest.StreamDecode(t, fastpir.New())
+}
+
+func FuzzAnswer(f *testing.F) {
+	pirtest.FuzzAnswer(f, fastpir.New())
+}
+
+func BenchmarkAnswer(b *testing.B) {
+	pirtest.BenchmarkAnswer(b, fastpir.New(), 32)
+}
+
+func BenchmarkScheme(b *testing.B) {
+	pirtest.Benchmark(b, fastpir.New())
+}
@@ -0,0 +1,118 @@
+package pir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrNoHint is returned when querying a database of a Hinter scheme with
+// parameters lacking its hint.
+var ErrNoHint = errors.New("pir: parameters lack the database's hint")
+
+// Hinter is implemented by schemes with an offline phase, such as SimplePIR:
+// the server derives a hint from each encoded database, which clients
+// download once per database, in exchange for much cheaper queries and
+// answers. Clients set the hint in Params.Hint before calling Query and
+// Decode.
+type Hinter interface {
+	Scheme
+	// Hint computes the hint of db.
+	Hint(db *Encoded) ([]byte, error)
+}
+
+// SetHint computes the hint of db, if scheme is a Hinter, and records it
+// and its digest in the parameters of db.
+func SetHint(scheme Scheme, db *Encoded) error {
+	h, ok := scheme.(Hinter)
+	if !ok {
+		return nil
+	}
+	hint, err := h.Hint(db)
+	if err != nil {
+		return err
+	}
+	db.Params.Hint = hint
+	db.Params.HintDigest = HintDigest(hint)
+	return nil
+}
+
+// HintDigest returns the digest naming hint.
+func HintDigest(hint []byte) []byte {
+	d := sha256.Sum256(hint)
+	return d[:]
+}
+
+// CheckHint reports whether hint is the one named by the digest of params.
+func CheckHint(params Params, hint []byte) bool {
+	return bytes.Equal(HintDigest(hint), params.HintDigest)
+}
+
+// hintDiffBlock is the granularity at which DiffHint compares hints.
+const hintDiffBlock = 64
+
+// DiffHint encodes the changes from the hint old to updated, of the same
+// size, as the ranges of updated which differ, each a uvarint gap since the
+// end of the previous range, a uvarint length, and its bytes. Changes to
+// databases of a fixed geometry, such as the few blocks added or removed
+// between snapshots, touch few parts of their hints, so the delta is far
+// smaller than the hint.
+func DiffHint(old, updated []byte) []byte {
+	var delta []byte
+	var n [binary.MaxVarintLen64]byte
+	last := 0
+	for start := 0; start < len(updated); {
+		end := start + hintDiffBlock
+		if end > len(updated) {
+			end = len(updated)
+		}
+		if bytes.Equal(old[start:end], updated[start:end]) {
+			start = end
+			continue
+		}
+		// extend the range over the following blocks which differ.
+		for end < len(updated) {
+			next := end + hintDiffBlock
+			if next > len(updated) {
+				next = len(updated)
+			}
+			if bytes.Equal(old[end:next], updated[end:next]) {
+				break
+			}
+			end = next
+		}
+		delta = append(delta, n[:binary.PutUvarint(n[:], uint64(start-last))]...)
+		delta = append(delta, n[:binary.PutUvarint(n[:], uint64(end-start))]...)
+		delta = append(delta, updated[start:end]...)
+		last, start = end, end
+	}
+	return delta
+}
+
+// PatchHint applies delta, encoded by DiffHint, to a copy of old.
+func PatchHint(old, delta []byte) ([]byte, error) {
+	hint := append([]byte(nil), old...)
+	pos := uint64(0)
+	for len(delta) > 0 {
+		gap, n := binary.Uvarint(delta)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: hint delta", ErrMalformed)
+		}
+		delta = delta[n:]
+		size, n := binary.Uvarint(delta)
+		if n <= 0 || size > uint64(len(delta)-n) {
+			return nil, fmt.Errorf("%w: hint delta", ErrMalformed)
+		}
+		delta = delta[n:]
+		if gap > uint64(len(hint))-pos || size > uint64(len(hint))-pos-gap {
+			return nil, fmt.Errorf("%w: hint delta past the end of the hint", ErrMalformed)
+		}
+		pos += gap
+		copy(hint[pos:], delta[:size])
+		pos += size
+		delta = delta[size:]
+	}
+	return hint, nil
+}
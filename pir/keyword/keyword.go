@@ -0,0 +1,195 @@
+// Package keyword implements keyword PIR on top of index based PIR schemes
+// using bucketized cuckoo hashing.
+//
+// Each key may live in one of NumHashes slots of a table, and each slot holds
+// up to SlotEntries (tag, value) entries. The table is the PIR database; to
+// look up a key a client privately retrieves all of its candidate slots and
+// scans them locally, so it never needs the full key to value mapping and the
+// server never learns which key was looked up.
+package keyword
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+const (
+	// NumHashes is the number of candidate slots of each key.
+	NumHashes = 2
+	// SlotEntries is the number of entries held by each slot.
+	SlotEntries = 4
+	// EntrySize is the size of one entry: a key tag and a 4 byte value.
+	EntrySize = tagSize + 4
+	// SlotSize is the element size of the table.
+	SlotSize = SlotEntries * EntrySize
+
+	tagSize = 8
+	// maxLoad is the fraction of entries filled when sizing a table.
+	maxLoad = 0.75
+	// maxKicks bounds the eviction walk before the table is grown.
+	maxKicks = 500
+)
+
+var ErrTooLarge = errors.New("keyword: value does not fit in an entry")
+
+func tag(key []byte) []byte {
+	h := sha256.Sum256(key)
+	return h[:tagSize]
+}
+
+// Slots returns the candidate slots of key in a table of numSlots slots.
+func Slots(key []byte, numSlots uint64) [NumHashes]uint64 {
+	var slots [NumHashes]uint64
+	if numSlots == 0 {
+		return slots
+	}
+	buf := make([]byte, 1+len(key))
+	copy(buf[1:], key)
+	for i := range slots {
+		buf[0] = byte(i)
+		h := sha256.Sum256(buf)
+		slots[i] = binary.BigEndian.Uint64(h[:8]) % numSlots
+	}
+	return slots
+}
+
+// Find scans a retrieved slot for key and returns its value.
+func Find(slot []byte, key []byte) (uint64, bool) {
+	t := tag(key)
+	for len(slot) >= EntrySize {
+		if string(slot[:tagSize]) == string(t) {
+			return uint64(binary.BigEndian.Uint32(slot[tagSize:EntrySize])), true
+		}
+		slot = slot[EntrySize:]
+	}
+	return 0, false
+}
+
+type entry struct {
+	key   []byte
+	value uint64
+}
+
+// Build lays out a table mapping each non-nil key to its position in keys.
+// The table has at least minSlots slots, and more if needed to fit the keys.
+func Build(keys [][]byte, minSlots int) (pir.Database, error) {
+	t, err := NewTable(keys, minSlots)
+	if err != nil {
+		return pir.Database{}, err
+	}
+	return t.Database(), nil
+}
+
+// Table is a laid out table which keys can be inserted into and removed
+// from one at a time, changing only a few of its slots.
+type Table struct {
+	slots [][]entry
+	rng   *rand.Rand
+}
+
+// NewTable lays out a table as Build does.
+func NewTable(keys [][]byte, minSlots int) (*Table, error) {
+	if uint64(len(keys)) > 1<<32 {
+		return nil, ErrTooLarge
+	}
+	n := 0
+	for _, k := range keys {
+		if k != nil {
+			n++
+		}
+	}
+	numSlots := int(float64(n)/(maxLoad*SlotEntries)) + 1
+	if numSlots < minSlots {
+		numSlots = minSlots
+	}
+	for {
+		if t, ok := insertAll(keys, numSlots); ok {
+			return t, nil
+		}
+		numSlots *= 2
+	}
+}
+
+func insertAll(keys [][]byte, numSlots int) (*Table, bool) {
+	t := &Table{
+		slots: make([][]entry, numSlots),
+		rng:   rand.New(rand.NewSource(int64(numSlots))),
+	}
+	for pos, k := range keys {
+		if k == nil {
+			continue
+		}
+		if _, ok := t.Insert(k, uint64(pos)); !ok {
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+// NumSlots returns the number of slots of the table.
+func (t *Table) NumSlots() uint64 {
+	return uint64(len(t.slots))
+}
+
+// Insert maps key to value, returning the slots which changed. When the
+// table is too full to fit key, it returns false and the table must be
+// discarded, as an entry displaced along the way may have been lost.
+func (t *Table) Insert(key []byte, value uint64) ([]uint64, bool) {
+	if value >= 1<<32 {
+		return nil, false
+	}
+	e := entry{key, value}
+	var changed []uint64
+	for kick := 0; kick < maxKicks; kick++ {
+		cands := Slots(e.key, t.NumSlots())
+		for _, s := range cands {
+			if len(t.slots[s]) < SlotEntries {
+				t.slots[s] = append(t.slots[s], e)
+				return append(changed, s), true
+			}
+		}
+		s := cands[t.rng.Intn(NumHashes)]
+		victim := t.rng.Intn(SlotEntries)
+		e, t.slots[s][victim] = t.slots[s][victim], e
+		changed = append(changed, s)
+	}
+	return changed, false
+}
+
+// Remove drops key from the table, returning the slot it was in.
+func (t *Table) Remove(key []byte) (uint64, bool) {
+	for _, s := range Slots(key, t.NumSlots()) {
+		for i, e := range t.slots[s] {
+			if string(e.key) == string(key) {
+				t.slots[s] = append(t.slots[s][:i], t.slots[s][i+1:]...)
+				return s, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Slot returns the contents of slot i as an element of the database.
+func (t *Table) Slot(i uint64) []byte {
+	e := make([]byte, 0, len(t.slots[i])*EntrySize)
+	for _, ent := range t.slots[i] {
+		var v [4]byte
+		binary.BigEndian.PutUint32(v[:], uint32(ent.value))
+		e = append(e, tag(ent.key)...)
+		e = append(e, v[:]...)
+	}
+	return e
+}
+
+// Database returns the table as a PIR database.
+func (t *Table) Database() pir.Database {
+	db := pir.Database{Elements: make([][]byte, len(t.slots)), ElementSize: SlotSize}
+	for i := range t.slots {
+		db.Elements[i] = t.Slot(uint64(i))
+	}
+	return db
+}
@@ -0,0 +1,98 @@
+package keyword_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+)
+
+func TestBuildAndFind(t *testing.T) {
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		if i%7 == 3 {
+			continue
+		}
+		keys[i] = []byte(fmt.Sprintf("key %d", i))
+	}
+	db, err := keyword.Build(keys, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.ElementSize != keyword.SlotSize {
+		t.Fatalf("unexpected element size %d", db.ElementSize)
+	}
+	for i, k := range keys {
+		if k == nil {
+			continue
+		}
+		found := false
+		for _, s := range keyword.Slots(k, uint64(len(db.Elements))) {
+			if v, ok := keyword.Find(db.Elements[s], k); ok {
+				if v != uint64(i) {
+					t.Fatalf("key %d maps to %d", i, v)
+				}
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("key %d not in any of its slots", i)
+		}
+	}
+	for _, s := range keyword.Slots([]byte("missing"), uint64(len(db.Elements))) {
+		if _, ok := keyword.Find(db.Elements[s], []byte("missing")); ok {
+			t.Fatal("found a key that was never inserted")
+		}
+	}
+}
+
+func TestTableUpdate(t *testing.T) {
+	keys := make([][]byte, 100)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key %d", i))
+	}
+	table, err := keyword.NewTable(keys, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := table.Database()
+	slot, ok := table.Remove(keys[42])
+	if !ok {
+		t.Fatal("inserted key not removed")
+	}
+	if _, ok := table.Remove(keys[42]); ok {
+		t.Fatal("removed key removed again")
+	}
+	changed, ok := table.Insert([]byte("new key"), 42)
+	if !ok {
+		t.Fatal("key not inserted into a table with room")
+	}
+
+	db := table.Database()
+	dirty := map[uint64]bool{slot: true}
+	for _, s := range changed {
+		dirty[s] = true
+	}
+	for i := range db.Elements {
+		if !dirty[uint64(i)] && string(db.Elements[i]) != string(before.Elements[i]) {
+			t.Fatalf("slot %d changed but was not reported", i)
+		}
+	}
+	lookup := func(k []byte) (uint64, bool) {
+		for _, s := range keyword.Slots(k, table.NumSlots()) {
+			if v, ok := keyword.Find(db.Elements[s], k); ok {
+				return v, true
+			}
+		}
+		return 0, false
+	}
+	if _, ok := lookup(keys[42]); ok {
+		t.Fatal("found a removed key")
+	}
+	if v, ok := lookup([]byte("new key")); !ok || v != 42 {
+		t.Fatalf("inserted key maps to %d, %v", v, ok)
+	}
+	if v, ok := lookup(keys[7]); !ok || v != 7 {
+		t.Fatalf("untouched key maps to %d, %v", v, ok)
+	}
+}
@@ -0,0 +1,43 @@
+package pir
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// BlockHeaderSize is the size of the length prefix of padded blocks.
+	BlockHeaderSize = 4
+)
+
+var (
+	// ErrBlockTooShort is returned when a padded element is missing its length prefix.
+	ErrBlockTooShort = errors.New("pir: padded block too short")
+	// ErrBlockTooLarge is returned when a block does not fit in an element.
+	ErrBlockTooLarge = errors.New("pir: block larger than element size")
+)
+
+// PadBlock prefixes block with its length so blocks of different sizes can
+// share one element size. The result is at most size bytes; schemes pad
+// shorter elements with zeros.
+func PadBlock(block []byte, size int) ([]byte, error) {
+	if len(block)+BlockHeaderSize > size {
+		return nil, ErrBlockTooLarge
+	}
+	e := make([]byte, BlockHeaderSize+len(block))
+	binary.BigEndian.PutUint32(e, uint32(len(block)))
+	copy(e[BlockHeaderSize:], block)
+	return e, nil
+}
+
+// UnpadBlock strips the padding PadBlock added to a block.
+func UnpadBlock(element []byte) ([]byte, error) {
+	if len(element) < BlockHeaderSize {
+		return nil, ErrBlockTooShort
+	}
+	n := binary.BigEndian.Uint32(element)
+	if uint64(n) > uint64(len(element)-BlockHeaderSize) {
+		return nil, ErrBlockTooShort
+	}
+	return element[BlockHeaderSize : BlockHeaderSize+n], nil
+}
@@ -0,0 +1,36 @@
+package lwe
+
+// The inner loops of the pure Go schemes are Dot and MulAdd. Where the CPU
+// has vector instructions, AVX2 on amd64 and NEON on arm64, they run over
+// simdWidth words at a time in assembly, the remainder in Go. Building with
+// the purego tag leaves them in Go everywhere.
+
+// Dot returns the inner product of a and b.
+func Dot(a, b []uint32) uint32 {
+	b = b[:len(a)]
+	var sum uint32
+	n := 0
+	if useSIMD {
+		if n = len(a) &^ (simdWidth - 1); n > 0 {
+			sum = dotSIMD(&a[0], &b[0], n)
+		}
+	}
+	for i := n; i < len(a); i++ {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// MulAdd adds d times each word of a to the same word of dst.
+func MulAdd(dst, a []uint32, d uint32) {
+	dst = dst[:len(a)]
+	n := 0
+	if useSIMD {
+		if n = len(a) &^ (simdWidth - 1); n > 0 {
+			mulAddSIMD(&dst[0], &a[0], n, d)
+		}
+	}
+	for i := n; i < len(a); i++ {
+		dst[i] += d * a[i]
+	}
+}
@@ -0,0 +1,103 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func dotSIMD(a, b *uint32, n int) uint32
+TEXT ·dotSIMD(SB), NOSPLIT, $0-28
+	MOVQ a+0(FP), SI
+	MOVQ b+8(FP), DI
+	MOVQ n+16(FP), CX
+	VPXOR Y0, Y0, Y0
+	VPXOR Y1, Y1, Y1
+	VPXOR Y2, Y2, Y2
+	VPXOR Y3, Y3, Y3
+
+loop32:
+	CMPQ CX, $32
+	JB   loop8
+	VMOVDQU (SI), Y4
+	VMOVDQU 32(SI), Y5
+	VMOVDQU 64(SI), Y6
+	VMOVDQU 96(SI), Y7
+	VPMULLD (DI), Y4, Y4
+	VPMULLD 32(DI), Y5, Y5
+	VPMULLD 64(DI), Y6, Y6
+	VPMULLD 96(DI), Y7, Y7
+	VPADDD  Y4, Y0, Y0
+	VPADDD  Y5, Y1, Y1
+	VPADDD  Y6, Y2, Y2
+	VPADDD  Y7, Y3, Y3
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	SUBQ    $32, CX
+	JMP     loop32
+
+loop8:
+	CMPQ    CX, $8
+	JB      sum
+	VMOVDQU (SI), Y4
+	VPMULLD (DI), Y4, Y4
+	VPADDD  Y4, Y0, Y0
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $8, CX
+	JMP     loop8
+
+sum:
+	VPADDD       Y1, Y0, Y0
+	VPADDD       Y3, Y2, Y2
+	VPADDD       Y2, Y0, Y0
+	VEXTRACTI128 $1, Y0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0xb1, X0, X1
+	VPADDD       X1, X0, X0
+	VMOVD        X0, AX
+	VZEROUPPER
+	MOVL         AX, ret+24(FP)
+	RET
+
+// func mulAddSIMD(dst, a *uint32, n int, d uint32)
+TEXT ·mulAddSIMD(SB), NOSPLIT, $0-28
+	MOVQ         dst+0(FP), DI
+	MOVQ         a+8(FP), SI
+	MOVQ         n+16(FP), CX
+	MOVL         d+24(FP), AX
+	VMOVD        AX, X0
+	VPBROADCASTD X0, Y0
+
+loop32:
+	CMPQ    CX, $32
+	JB      loop8
+	VPMULLD (SI), Y0, Y1
+	VPMULLD 32(SI), Y0, Y2
+	VPMULLD 64(SI), Y0, Y3
+	VPMULLD 96(SI), Y0, Y4
+	VPADDD  (DI), Y1, Y1
+	VPADDD  32(DI), Y2, Y2
+	VPADDD  64(DI), Y3, Y3
+	VPADDD  96(DI), Y4, Y4
+	VMOVDQU Y1, (DI)
+	VMOVDQU Y2, 32(DI)
+	VMOVDQU Y3, 64(DI)
+	VMOVDQU Y4, 96(DI)
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	SUBQ    $32, CX
+	JMP     loop32
+
+loop8:
+	CMPQ    CX, $8
+	JB      done
+	VPMULLD (SI), Y0, Y1
+	VPADDD  (DI), Y1, Y1
+	VMOVDQU Y1, (DI)
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $8, CX
+	JMP     loop8
+
+done:
+	VZEROUPPER
+	RET
@@ -0,0 +1,21 @@
+//go:build arm64 && !purego
+
+package lwe
+
+import "golang.org/x/sys/cpu"
+
+const simdWidth = 4
+
+var useSIMD = cpu.ARM64.HasASIMD
+
+// dotSIMD returns the inner product of the n words at a and b, n a multiple
+// of simdWidth.
+//
+//go:noescape
+func dotSIMD(a, b *uint32, n int) uint32
+
+// mulAddSIMD adds d times each of the n words at a to those at dst, n a
+// multiple of simdWidth.
+//
+//go:noescape
+func mulAddSIMD(dst, a *uint32, n int, d uint32)
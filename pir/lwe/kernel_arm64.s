@@ -0,0 +1,75 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// The multiply-accumulates are encoded as words, as older assemblers lack
+// the mnemonic.
+
+// func dotSIMD(a, b *uint32, n int) uint32
+TEXT ·dotSIMD(SB), NOSPLIT, $0-28
+	MOVD a+0(FP), R0
+	MOVD b+8(FP), R1
+	MOVD n+16(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+
+loop8:
+	CMP    $8, R2
+	BLT    loop4
+	VLD1.P 32(R0), [V2.S4, V3.S4]
+	VLD1.P 32(R1), [V4.S4, V5.S4]
+	WORD   $0x4ea49440 // MLA V0.4S, V2.4S, V4.4S
+	WORD   $0x4ea59461 // MLA V1.4S, V3.4S, V5.4S
+	SUB    $8, R2
+	B      loop8
+
+loop4:
+	CBZ    R2, sum
+	VLD1.P 16(R0), [V2.S4]
+	VLD1.P 16(R1), [V4.S4]
+	WORD   $0x4ea49440 // MLA V0.4S, V2.4S, V4.4S
+	SUB    $4, R2
+	B      loop4
+
+sum:
+	VADD V1.S4, V0.S4, V0.S4
+	VMOV V0.S[0], R3
+	VMOV V0.S[1], R4
+	VMOV V0.S[2], R5
+	VMOV V0.S[3], R6
+	ADDW R4, R3
+	ADDW R6, R5
+	ADDW R5, R3
+	MOVW R3, ret+24(FP)
+	RET
+
+// func mulAddSIMD(dst, a *uint32, n int, d uint32)
+TEXT ·mulAddSIMD(SB), NOSPLIT, $0-28
+	MOVD  dst+0(FP), R0
+	MOVD  a+8(FP), R1
+	MOVD  n+16(FP), R2
+	MOVWU d+24(FP), R3
+	VDUP  R3, V0.S4
+
+loop8:
+	CMP    $8, R2
+	BLT    loop4
+	VLD1   (R0), [V1.S4, V2.S4]
+	VLD1.P 32(R1), [V3.S4, V4.S4]
+	WORD   $0x4ea09461 // MLA V1.4S, V3.4S, V0.4S
+	WORD   $0x4ea09482 // MLA V2.4S, V4.4S, V0.4S
+	VST1.P [V1.S4, V2.S4], 32(R0)
+	SUB    $8, R2
+	B      loop8
+
+loop4:
+	CBZ    R2, done
+	VLD1   (R0), [V1.S4]
+	VLD1.P 16(R1), [V3.S4]
+	WORD   $0x4ea09461 // MLA V1.4S, V3.4S, V0.4S
+	VST1.P [V1.S4], 16(R0)
+	SUB    $4, R2
+	B      loop4
+
+done:
+	RET
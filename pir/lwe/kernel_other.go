@@ -0,0 +1,12 @@
+//go:build !(amd64 || arm64) || purego
+
+package lwe
+
+const (
+	simdWidth = 1
+	useSIMD   = false
+)
+
+func dotSIMD(a, b *uint32, n int) uint32 { panic("lwe: no vector kernels") }
+
+func mulAddSIMD(dst, a *uint32, n int, d uint32) { panic("lwe: no vector kernels") }
@@ -0,0 +1,206 @@
+// Package lwe holds the learning-with-errors primitives shared by the pure Go
+// PIR schemes. All arithmetic is modulo 2^32 and relies on uint32 wrapping.
+package lwe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	// SeedSize is the size of the seeds used to expand public matrices.
+	SeedSize = 16
+	// Sigma is the standard deviation of the error distribution.
+	Sigma = 8
+)
+
+// PRG deterministically expands a seed into uniform words modulo 2^32.
+type PRG struct {
+	stream cipher.Stream
+	buf    []byte
+}
+
+// NewPRG returns an AES-CTR based generator keyed by seed.
+func NewPRG(seed []byte) *PRG {
+	return NewPRGAt(seed, 0)
+}
+
+// NewPRGAt returns the generator NewPRG returns for seed, offset words into
+// its stream, so goroutines can each expand their own part of it.
+func NewPRGAt(seed []byte, offset uint64) *PRG {
+	blk, err := aes.NewCipher(seed)
+	if err != nil {
+		// seeds are always SeedSize long.
+		panic(err)
+	}
+	// the counter counts blocks of four words.
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[8:], offset/4)
+	p := &PRG{stream: cipher.NewCTR(blk, iv)}
+	if r := offset % 4; r != 0 {
+		p.Fill(make([]uint32, r))
+	}
+	return p
+}
+
+// Fill overwrites dst with the next len(dst) words of the stream.
+func (p *PRG) Fill(dst []uint32) {
+	if cap(p.buf) < 4*len(dst) {
+		p.buf = make([]byte, 4*len(dst))
+	}
+	buf := p.buf[:4*len(dst)]
+	for i := range buf {
+		buf[i] = 0
+	}
+	p.stream.XORKeyStream(buf, buf)
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint32(buf[4*i:])
+	}
+}
+
+// NewSeed returns a fresh random seed.
+func NewSeed() ([]byte, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// Secret returns a uniformly random secret vector of dimension n.
+func Secret(n int) ([]uint32, error) {
+	s := make([]uint32, n)
+	buf := make([]byte, 4*n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint32(buf[4*i:])
+	}
+	return s, nil
+}
+
+// Errors returns n samples of a centered binomial distribution with standard
+// deviation Sigma.
+func Errors(n int) ([]uint32, error) {
+	buf := make([]byte, 32*n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	e := make([]uint32, n)
+	for i := range e {
+		w := buf[32*i:]
+		pos := bits.OnesCount64(binary.LittleEndian.Uint64(w)) + bits.OnesCount64(binary.LittleEndian.Uint64(w[8:]))
+		neg := bits.OnesCount64(binary.LittleEndian.Uint64(w[16:])) + bits.OnesCount64(binary.LittleEndian.Uint64(w[24:]))
+		e[i] = uint32(int32(pos - neg))
+	}
+	return e, nil
+}
+
+// PlaintextBits returns the largest plaintext modulus, as a number of bits,
+// for which the sum of n noisy products still decodes correctly.
+func PlaintextBits(n int) int {
+	bound := 7 * Sigma * math.Sqrt(float64(n))
+	for logp := 8; logp > 1; logp-- {
+		p := float64(uint64(1) << logp)
+		if bound*(p-1) < float64(uint64(1)<<31)/p {
+			return logp
+		}
+	}
+	return 1
+}
+
+// Delta returns the scaling factor for plaintexts modulo 2^logp.
+func Delta(logp int) uint32 {
+	return uint32(1) << (32 - logp)
+}
+
+// Round removes the noise from v and returns the plaintext digit it encodes.
+func Round(v uint32, logp int) uint32 {
+	return ((v + Delta(logp)/2) >> (32 - logp)) & (uint32(1)<<logp - 1)
+}
+
+// NumDigits is the number of logp bit digits needed to hold size bytes.
+func NumDigits(size, logp int) int {
+	return (8*size + logp - 1) / logp
+}
+
+// Split writes the logp bit digits of data into dst, which must hold
+// NumDigits(len(data), logp) digits. Missing trailing bytes are zero.
+func Split(dst []uint32, data []byte, logp int) {
+	var acc uint64
+	nbits := 0
+	pos := 0
+	for i := range dst {
+		for nbits < logp {
+			var b byte
+			if pos < len(data) {
+				b = data[pos]
+			}
+			pos++
+			acc |= uint64(b) << nbits
+			nbits += 8
+		}
+		dst[i] = uint32(acc & (1<<logp - 1))
+		acc >>= logp
+		nbits -= logp
+	}
+}
+
+// Join is the inverse of Split, reassembling size bytes from digits.
+func Join(digits []uint32, size, logp int) []byte {
+	out := make([]byte, size)
+	var acc uint64
+	nbits := 0
+	pos := 0
+	for _, d := range digits {
+		acc |= uint64(d) << nbits
+		nbits += logp
+		for nbits >= 8 && pos < size {
+			out[pos] = byte(acc)
+			acc >>= 8
+			nbits -= 8
+			pos++
+		}
+	}
+	return out
+}
+
+// PutWords encodes w little-endian, as schemes save their databases.
+func PutWords(w []uint32) []byte {
+	out := make([]byte, 4*len(w))
+	for i, v := range w {
+		binary.LittleEndian.PutUint32(out[4*i:], v)
+	}
+	return out
+}
+
+// Words decodes little-endian words from b, whose length is a multiple of 4.
+func Words(b []byte) []uint32 {
+	out := make([]uint32, len(b)/4)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return out
+}
+
+// View returns the little-endian words of b as Words does, but reading them
+// from b in place, without a copy, where b is aligned and the platform
+// little-endian. The words must then only be read, as b may be a read only
+// mapping of a file, and b must outlive them.
+func View(b []byte) []uint32 {
+	if len(b) == 0 || !littleEndian || uintptr(unsafe.Pointer(&b[0]))%4 != 0 {
+		return Words(b)
+	}
+	return unsafe.Slice((*uint32)(unsafe.Pointer(&b[0])), len(b)/4)
+}
+
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
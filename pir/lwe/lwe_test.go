@@ -0,0 +1,73 @@
+package lwe
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKernels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	fill := func(n int) []uint32 {
+		w := make([]uint32, n)
+		for i := range w {
+			w[i] = r.Uint32()
+		}
+		return w
+	}
+	// lengths around each multiple of the vector width, and the unrolled
+	// loops', take every path through the kernels.
+	for n := 0; n <= 100; n++ {
+		a, b, d := fill(n), fill(n), r.Uint32()
+		var want uint32
+		for i := range a {
+			want += a[i] * b[i]
+		}
+		if got := Dot(a, b); got != want {
+			t.Fatalf("Dot of %d words: %d, want %d", n, got, want)
+		}
+
+		// the word past dst is left alone.
+		dst := fill(n + 1)
+		expect := append([]uint32(nil), dst...)
+		for i := range a {
+			expect[i] += d * a[i]
+		}
+		MulAdd(dst[:n], a, d)
+		for i := range dst {
+			if dst[i] != expect[i] {
+				t.Fatalf("MulAdd of %d words: word %d is %d, want %d", n, i, dst[i], expect[i])
+			}
+		}
+	}
+}
+
+func BenchmarkDot(b *testing.B) {
+	a, c := make([]uint32, 4096), make([]uint32, 4096)
+	b.SetBytes(4 * 4096)
+	for i := 0; i < b.N; i++ {
+		Dot(a, c)
+	}
+}
+
+func BenchmarkMulAdd(b *testing.B) {
+	dst, a := make([]uint32, 4096), make([]uint32, 4096)
+	b.SetBytes(4 * 4096)
+	for i := 0; i < b.N; i++ {
+		MulAdd(dst, a, 3)
+	}
+}
+
+func TestPRGAt(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	stream := make([]uint32, 64)
+	NewPRG(seed).Fill(stream)
+	for offset := range stream {
+		got := make([]uint32, len(stream)-offset)
+		NewPRGAt(seed, uint64(offset)).Fill(got)
+		for i, w := range got {
+			if w != stream[offset+i] {
+				t.Fatalf("word %d at offset %d: %x, want %x", i, offset, w, stream[offset+i])
+			}
+		}
+	}
+}
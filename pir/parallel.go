@@ -0,0 +1,38 @@
+package pir
+
+import "sync"
+
+// Parallel is implemented by schemes which can split each answer across
+// goroutines, each computing over a share of the database, so a single
+// query uses several cores rather than one.
+type Parallel interface {
+	// SetWorkers sets how many goroutines each answer is split across.
+	// Zero or one, the default, answers on the calling goroutine. It may
+	// be called while the scheme answers; answers under way keep the
+	// number they started with.
+	SetWorkers(n int)
+}
+
+// Split calls fn concurrently over up to workers contiguous ranges
+// [lo, hi) covering [0, n), returning once every call has. w numbers the
+// ranges from zero, so calls can keep partial results apart for the caller
+// to merge. With one worker, or nothing to split, fn is called once, on
+// the calling goroutine.
+func Split(workers, n int, fn func(w, lo, hi int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		fn(0, 0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			fn(w, w*n/workers, (w+1)*n/workers)
+		}(w)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,168 @@
+package pir
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrNotPersistent is returned when saving databases of schemes which are
+// not Persisters.
+var ErrNotPersistent = errors.New("pir: scheme cannot save encoded databases")
+
+// Alignment is the alignment, in the buffers Marshal writes, of the states
+// of encoded databases, so schemes can answer from them in place where the
+// buffer itself is aligned, as memory mapped files are.
+const Alignment = 8
+
+// Persister is implemented by schemes which can save encoded databases, so
+// servers can load them again rather than encode them afresh.
+type Persister interface {
+	// MarshalState returns the state of db.
+	MarshalState(db *Encoded) ([]byte, error)
+	// UnmarshalState restores the state of the database described by
+	// params from data returned by MarshalState. data may be a read only
+	// mapping of a file, and must stay valid as long as the state is used;
+	// it starts at a multiple of Alignment from the start of the buffer
+	// passed to Unmarshal.
+	UnmarshalState(params Params, data []byte) (interface{}, error)
+}
+
+// Marshal encodes db, parameters and state, for Unmarshal.
+func Marshal(scheme Scheme, db *Encoded) ([]byte, error) {
+	p, ok := scheme.(Persister)
+	if !ok {
+		return nil, ErrNotPersistent
+	}
+	state, err := p.MarshalState(db)
+	if err != nil {
+		return nil, err
+	}
+	out := appendPadding(MarshalParams(nil, db.Params))
+	return append(out, state...), nil
+}
+
+// Unmarshal restores a database saved by Marshal with scheme.
+func Unmarshal(scheme Scheme, data []byte) (*Encoded, error) {
+	p, ok := scheme.(Persister)
+	if !ok {
+		return nil, ErrNotPersistent
+	}
+	params, rest, err := UnmarshalParams(data)
+	if err != nil {
+		return nil, err
+	}
+	if rest, err = skipPadding(rest); err != nil {
+		return nil, err
+	}
+	if params.Scheme != scheme.ID() {
+		return nil, ErrSchemeMismatch
+	}
+	state, err := p.UnmarshalState(params, rest)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoded{Params: params, State: state}, nil
+}
+
+// MarshalParams appends params to b.
+func MarshalParams(b []byte, params Params) []byte {
+	b = appendPart(b, []byte(params.Scheme))
+	b = appendUvarint(b, params.NumElements)
+	b = appendUvarint(b, params.ElementSize)
+	b = appendPart(b, params.Extra)
+	b = appendPart(b, params.HintDigest)
+	return appendPart(b, params.Hint)
+}
+
+// UnmarshalParams reads parameters appended by MarshalParams from the
+// start of b, returning the rest. The byte fields of the parameters alias b.
+func UnmarshalParams(b []byte) (params Params, rest []byte, err error) {
+	var scheme []byte
+	if scheme, b, err = part(b); err != nil {
+		return
+	}
+	params.Scheme = string(scheme)
+	if params.NumElements, b, err = uvarint(b); err != nil {
+		return
+	}
+	if params.ElementSize, b, err = uvarint(b); err != nil {
+		return
+	}
+	for _, f := range []*[]byte{&params.Extra, &params.HintDigest, &params.Hint} {
+		if *f, b, err = part(b); err != nil {
+			return
+		}
+		if len(*f) == 0 {
+			*f = nil
+		}
+	}
+	return params, b, nil
+}
+
+// AppendAligned appends p to b prefixed by its length, padded so p starts
+// at a multiple of Alignment from the start of b. Encoded databases are
+// kept aligned within buffers by appending them with AppendAligned.
+func AppendAligned(b, p []byte) []byte {
+	return append(appendPadding(appendUvarint(b, uint64(len(p)))), p...)
+}
+
+// Aligned reads a part appended by AppendAligned from the start of b,
+// returning the rest.
+func Aligned(b []byte) (p, rest []byte, err error) {
+	n, b, err := uvarint(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b, err = skipPadding(b); err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(b)) {
+		return nil, nil, ErrMalformed
+	}
+	return b[:n:n], b[n:], nil
+}
+
+// appendPadding appends the number of bytes of padding needed to align the
+// end of b, and that padding.
+func appendPadding(b []byte) []byte {
+	pad := (Alignment - (len(b)+1)%Alignment) % Alignment
+	b = append(b, byte(pad))
+	return append(b, make([]byte, pad)...)
+}
+
+func skipPadding(b []byte) ([]byte, error) {
+	if len(b) == 0 || int(b[0]) >= Alignment || 1+int(b[0]) > len(b) {
+		return nil, ErrMalformed
+	}
+	return b[1+int(b[0]):], nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// appendPart appends p to b, prefixed by its length.
+func appendPart(b, p []byte) []byte {
+	return append(appendUvarint(b, uint64(len(p))), p...)
+}
+
+func uvarint(b []byte) (uint64, []byte, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, ErrMalformed
+	}
+	return v, b[n:], nil
+}
+
+// part reads a length prefixed part from the start of b.
+func part(b []byte) ([]byte, []byte, error) {
+	n, b, err := uvarint(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(b)) {
+		return nil, nil, ErrMalformed
+	}
+	return b[:n:n], b[n:], nil
+}
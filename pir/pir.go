@@ -0,0 +1,121 @@
+// Package pir defines the interface implemented by private information
+// retrieval schemes used by the bitswap client and server.
+package pir
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrIndexOutOfRange is returned when a query names an element the database does not hold.
+	ErrIndexOutOfRange = errors.New("pir: index out of range")
+	// ErrSchemeMismatch is returned when parameters or databases were produced by a different scheme.
+	ErrSchemeMismatch = errors.New("pir: scheme mismatch")
+	// ErrMalformed is returned when a query or answer cannot be parsed.
+	ErrMalformed = errors.New("pir: malformed message")
+	// ErrMultiServer is returned by Query and Decode of MultiServer schemes,
+	// whose queries must be split across servers.
+	ErrMultiServer = errors.New("pir: scheme needs several servers")
+)
+
+// Database is a plaintext PIR database: a list of elements which are all
+// ElementSize bytes long.
+type Database struct {
+	Elements    [][]byte
+	ElementSize int
+}
+
+// Params are the public parameters of an encoded database. They are all a
+// client needs to query the database and decode answers.
+type Params struct {
+	// Scheme is the ID of the scheme which produced these parameters.
+	Scheme      string
+	NumElements uint64
+	ElementSize uint64
+	// Extra holds scheme specific public parameters.
+	Extra []byte
+	// HintDigest names the hint of databases of Hinter schemes, and Hint
+	// is the hint itself, which clients download apart from the other
+	// parameters and must set before querying.
+	HintDigest []byte
+	Hint       []byte
+}
+
+// Encoded is a database prepared by a Scheme for answering queries.
+type Encoded struct {
+	Params Params
+	// State is private to the scheme which produced it.
+	State interface{}
+}
+
+// Secret is the client state needed to decode the answer to a query.
+// It must never leave the client.
+type Secret interface{}
+
+// Scheme is a single-server PIR scheme. The server side calls Setup once per
+// database and Answer per query; the client side calls Query and Decode.
+// Implementations must be safe for concurrent use.
+type Scheme interface {
+	// ID uniquely names the scheme and its parameter set, and the version
+	// of its encoding, as built by VersionedID.
+	ID() string
+	// Setup encodes db for answering queries.
+	Setup(db Database) (*Encoded, error)
+	// Query builds a query for the element at index of the database described by params.
+	Query(params Params, index uint64) ([]byte, Secret, error)
+	// Answer computes the response to query over db.
+	Answer(db *Encoded, query []byte) ([]byte, error)
+	// Decode recovers the queried element from answer.
+	Decode(params Params, secret Secret, answer []byte) ([]byte, error)
+}
+
+// Updater is implemented by schemes which can change elements of an encoded
+// database in place of encoding it again, which is much cheaper when few
+// elements change.
+type Updater interface {
+	// Update returns a copy of db with the element at each index of
+	// changes replaced. A nil element is all zeroes, as in Setup. db itself
+	// is left unchanged, so it may still be answered from.
+	Update(db *Encoded, changes map[uint64][]byte) (*Encoded, error)
+}
+
+// MultiServer is implemented by schemes splitting each query across several
+// servers holding the same database, which stay private as long as the
+// servers do not collude. Servers set up and answer as with any Scheme;
+// clients split queries with QueryServers and combine the answers with
+// DecodeServers, as Query and Decode fail with ErrMultiServer.
+type MultiServer interface {
+	Scheme
+	// Servers is the number of servers each query is split across.
+	Servers() int
+	// QueryServers builds the queries, one per server, for the element at
+	// index of the database described by params.
+	QueryServers(params Params, index uint64) ([][]byte, Secret, error)
+	// DecodeServers recovers the queried element from the answers of each
+	// server, in the order of the queries.
+	DecodeServers(params Params, secret Secret, answers [][]byte) ([]byte, error)
+}
+
+// VersionedID builds the identifier of version of the scheme called name.
+// Different versions of a scheme cannot query each other's databases, so
+// peers only agree on a scheme when both name and version match.
+func VersionedID(name string, version uint32) string {
+	return fmt.Sprintf("%s/v%d", name, version)
+}
+
+// ParseID splits an identifier built by VersionedID into the scheme name and
+// version.
+func ParseID(id string) (name string, version uint32, err error) {
+	i := strings.LastIndex(id, "/v")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("%w: scheme id %q has no version", ErrMalformed, id)
+	}
+	v, err := strconv.ParseUint(id[i+2:], 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: scheme id %q has no version", ErrMalformed, id)
+	}
+	return id[:i], uint32(v), nil
+}
@@ -0,0 +1,184 @@
+package pirtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// ElementSizes are the element sizes, in bytes, the benchmark suite runs
+// with: roughly an index slot, a small block and a page.
+var ElementSizes = []int{32, 1024, 4096}
+
+// MaxDatabaseBytes bounds the databases of the benchmark suite. Larger
+// combinations of DatabaseSizes and ElementSizes are skipped.
+var MaxDatabaseBytes = 16 << 20
+
+// Measurement is the cost of each phase of retrieving one element from a
+// database.
+type Measurement struct {
+	Scheme      string `json:"scheme"`
+	Elements    int    `json:"elements"`
+	ElementSize int    `json:"element_size"`
+	// Query, Answer and Decode are the time taken by the client to build a
+	// query, by the server to answer it, and by the client to decode the
+	// answer.
+	Query  time.Duration `json:"query_ns"`
+	Answer time.Duration `json:"answer_ns"`
+	Decode time.Duration `json:"decode_ns"`
+	// QueryBytes and AnswerBytes are the sizes sent each way.
+	QueryBytes  int `json:"query_bytes"`
+	AnswerBytes int `json:"answer_bytes"`
+	// HintBytes is the size of the hint clients download once per
+	// database, for pir.Hinter schemes.
+	HintBytes int `json:"hint_bytes,omitempty"`
+}
+
+// fixture is a database encoded by a scheme, along with a query for one of
+// its elements and the answer to it.
+type fixture struct {
+	scheme pir.Scheme
+	enc    *pir.Encoded
+	index  uint64
+	query  []byte
+	secret pir.Secret
+	answer []byte
+	// err is the first failure of a benchmarked phase.
+	err error
+}
+
+func newFixture(scheme pir.Scheme, n, size int) (*fixture, error) {
+	db := RandomDatabase(n, size)
+	enc, err := setup(scheme, db)
+	if err != nil {
+		return nil, err
+	}
+	f := &fixture{scheme: scheme, enc: enc, index: uint64(n / 2)}
+	if f.query, f.secret, err = scheme.Query(enc.Params, f.index); err != nil {
+		return nil, err
+	}
+	if f.answer, err = scheme.Answer(enc, f.query); err != nil {
+		return nil, err
+	}
+	got, err := scheme.Decode(enc.Params, f.secret, f.answer)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(got, db.Elements[f.index]) {
+		return nil, errors.New("decoded the wrong element")
+	}
+	return f, nil
+}
+
+func (f *fixture) fail(b *testing.B, err error) {
+	f.err = err
+	b.Fatal(err)
+}
+
+func (f *fixture) benchQuery(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, _, err := f.scheme.Query(f.enc.Params, f.index); err != nil {
+			f.fail(b, err)
+		}
+	}
+	b.ReportMetric(float64(len(f.query)), "query-bytes")
+}
+
+func (f *fixture) benchAnswer(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := f.scheme.Answer(f.enc, f.query); err != nil {
+			f.fail(b, err)
+		}
+	}
+	b.ReportMetric(float64(len(f.answer)), "answer-bytes")
+}
+
+func (f *fixture) benchDecode(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := f.scheme.Decode(f.enc.Params, f.secret, f.answer); err != nil {
+			f.fail(b, err)
+		}
+	}
+}
+
+// sizes calls run with each combination of DatabaseSizes and ElementSizes
+// within MaxDatabaseBytes.
+func sizes(run func(n, size int)) {
+	for _, n := range DatabaseSizes {
+		for _, size := range ElementSizes {
+			if n*size <= MaxDatabaseBytes {
+				run(n, size)
+			}
+		}
+	}
+}
+
+// Benchmark measures query generation, answering and decoding over
+// databases of DatabaseSizes elements of ElementSizes bytes, reporting the
+// size of queries and answers too.
+func Benchmark(b *testing.B, scheme pir.Scheme) {
+	sizes(func(n, size int) {
+		b.Run(fmt.Sprintf("%s/n=%d/size=%d", scheme.ID(), n, size), func(b *testing.B) {
+			f, err := newFixture(scheme, n, size)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run("query", f.benchQuery)
+			b.Run("answer", f.benchAnswer)
+			b.Run("decode", f.benchDecode)
+		})
+	})
+}
+
+// Measure runs the phases of the benchmark suite outside of go test, over a
+// database of n elements of size bytes.
+func Measure(scheme pir.Scheme, n, size int) (Measurement, error) {
+	f, err := newFixture(scheme, n, size)
+	if err != nil {
+		return Measurement{}, err
+	}
+	m := Measurement{
+		Scheme:      scheme.ID(),
+		Elements:    n,
+		ElementSize: size,
+		QueryBytes:  len(f.query),
+		AnswerBytes: len(f.answer),
+		HintBytes:   len(f.enc.Params.Hint),
+	}
+	for _, phase := range []struct {
+		bench func(*testing.B)
+		out   *time.Duration
+	}{
+		{f.benchQuery, &m.Query},
+		{f.benchAnswer, &m.Answer},
+		{f.benchDecode, &m.Decode},
+	} {
+		r := testing.Benchmark(phase.bench)
+		if f.err != nil {
+			return Measurement{}, f.err
+		}
+		*phase.out = time.Duration(r.NsPerOp())
+	}
+	return m, nil
+}
+
+// MeasureAll measures scheme over each combination of DatabaseSizes and
+// ElementSizes within MaxDatabaseBytes.
+func MeasureAll(scheme pir.Scheme) ([]Measurement, error) {
+	var out []Measurement
+	var err error
+	sizes(func(n, size int) {
+		if err != nil {
+			return
+		}
+		var m Measurement
+		if m, err = Measure(scheme, n, size); err == nil {
+			out = append(out, m)
+		}
+	})
+	return out, err
+}
@@ -0,0 +1,100 @@
+package pirtest
+
+import (
+	"crypto/sha256"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// Hinted wraps scheme as a pir.Hinter for tests of the offline phase. Its
+// hints are size bytes derived from the contents of the database, each
+// element setting its share of them, as the hints of real schemes change
+// only where their databases do. Its queries fail without them.
+func Hinted(scheme pir.Scheme, size int) pir.Hinter {
+	return &hinted{Scheme: scheme, size: size}
+}
+
+type hinted struct {
+	pir.Scheme
+	size int
+}
+
+type hintedState struct {
+	inner interface{}
+	// digests holds the digest of each element.
+	digests [][sha256.Size]byte
+}
+
+func (h *hinted) ID() string {
+	return "hinted-" + h.Scheme.ID()
+}
+
+func (h *hinted) Setup(db pir.Database) (*pir.Encoded, error) {
+	enc, err := h.Scheme.Setup(db)
+	if err != nil {
+		return nil, err
+	}
+	st := &hintedState{inner: enc.State}
+	for _, e := range db.Elements {
+		st.digests = append(st.digests, sha256.Sum256(e))
+	}
+	enc.Params.Scheme = h.ID()
+	enc.State = st
+	return enc, nil
+}
+
+func (h *hinted) Hint(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*hintedState)
+	if !ok {
+		return nil, pir.ErrSchemeMismatch
+	}
+	hint := make([]byte, h.size)
+	if len(st.digests) == 0 {
+		return hint, nil
+	}
+	for i := range hint {
+		digest := st.digests[i*len(st.digests)/len(hint)]
+		hint[i] = digest[i%len(digest)]
+	}
+	return hint, nil
+}
+
+// inner returns params as the wrapped scheme knows them.
+func (h *hinted) inner(params pir.Params) (pir.Params, error) {
+	if params.Scheme != h.ID() {
+		return params, pir.ErrSchemeMismatch
+	}
+	params.Scheme = h.Scheme.ID()
+	return params, nil
+}
+
+func (h *hinted) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	if len(params.HintDigest) == 0 || !pir.CheckHint(params, params.Hint) {
+		return nil, nil, pir.ErrNoHint
+	}
+	inner, err := h.inner(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h.Scheme.Query(inner, index)
+}
+
+func (h *hinted) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*hintedState)
+	if !ok {
+		return nil, pir.ErrSchemeMismatch
+	}
+	inner, err := h.inner(db.Params)
+	if err != nil {
+		return nil, err
+	}
+	return h.Scheme.Answer(&pir.Encoded{Params: inner, State: st.inner}, query)
+}
+
+func (h *hinted) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	inner, err := h.inner(params)
+	if err != nil {
+		return nil, err
+	}
+	return h.Scheme.Decode(inner, sec, answer)
+}
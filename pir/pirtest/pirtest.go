@@ -0,0 +1,322 @@
+// Package pirtest provides conformance tests and benchmarks shared by all
+// pir.Scheme implementations.
+package pirtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// RandomDatabase returns a database of n random elements of size bytes.
+func RandomDatabase(n, size int) pir.Database {
+	db := pir.Database{Elements: make([][]byte, n), ElementSize: size}
+	for i := range db.Elements {
+		db.Elements[i] = make([]byte, size)
+		_, _ = rand.Read(db.Elements[i])
+	}
+	return db
+}
+
+// setup encodes db with scheme, along with its hint if scheme is a
+// pir.Hinter.
+func setup(scheme pir.Scheme, db pir.Database) (*pir.Encoded, error) {
+	enc, err := scheme.Setup(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := pir.SetHint(scheme, enc); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// Roundtrip checks that every element of a small database can be retrieved
+// through scheme, including elements shorter than the element size.
+func Roundtrip(t *testing.T, scheme pir.Scheme) {
+	db := RandomDatabase(17, 45)
+	db.Elements[3] = []byte("short")
+	enc, err := setup(scheme, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Params.Scheme != scheme.ID() {
+		t.Fatalf("params name scheme %q, expected %q", enc.Params.Scheme, scheme.ID())
+	}
+	for i, want := range db.Elements {
+		q, sec, err := scheme.Query(enc.Params, uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ans, err := scheme.Answer(enc, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := scheme.Decode(enc.Params, sec, ans)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got[:len(want)], want) || len(got) != db.ElementSize {
+			t.Fatalf("element %d: got %x, expected %x", i, got, want)
+		}
+	}
+	if _, _, err := scheme.Query(enc.Params, uint64(len(db.Elements))); err == nil {
+		t.Fatal("query past the end of the database should fail")
+	}
+	if _, err := scheme.Answer(enc, []byte("garbage")); err == nil {
+		t.Fatal("malformed query should be rejected")
+	}
+}
+
+// Get retrieves the element at index of enc through scheme.
+func Get(t testing.TB, scheme pir.Scheme, enc *pir.Encoded, index uint64) []byte {
+	t.Helper()
+	q, sec, err := scheme.Query(enc.Params, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ans, err := scheme.Answer(enc, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := scheme.Decode(enc.Params, sec, ans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// Update checks that elements changed by scheme, which must be a
+// pir.Updater, are retrieved with their new contents from the updated
+// database and their old ones from the original.
+func Update(t *testing.T, scheme pir.Scheme) {
+	db := RandomDatabase(17, 45)
+	enc, err := setup(scheme, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := map[uint64][]byte{0: []byte("short"), 5: nil, 16: RandomDatabase(1, 45).Elements[0]}
+	updated, err := scheme.(pir.Updater).Update(enc, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pir.SetHint(scheme, updated); err != nil {
+		t.Fatal(err)
+	}
+	for i, old := range db.Elements {
+		want, ok := changes[uint64(i)]
+		if !ok {
+			want = old
+		}
+		if got := Get(t, scheme, updated, uint64(i)); !bytes.Equal(got, append(want, make([]byte, db.ElementSize-len(want))...)) {
+			t.Fatalf("updated element %d: got %x, expected %x", i, got, want)
+		}
+		if got := Get(t, scheme, enc, uint64(i)); !bytes.Equal(got, old) {
+			t.Fatalf("original element %d changed: got %x, expected %x", i, got, old)
+		}
+	}
+	if _, err := scheme.(pir.Updater).Update(enc, map[uint64][]byte{17: nil}); err == nil {
+		t.Fatal("update past the end of the database should fail")
+	}
+}
+
+// Persist checks that a database saved by scheme, which must be a
+// pir.Persister, loads back answering as the original does, and that
+// truncated databases are rejected.
+func Persist(t *testing.T, scheme pir.Scheme) {
+	enc, err := setup(scheme, RandomDatabase(17, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := pir.Marshal(scheme, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := pir.Unmarshal(scheme, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pir.MarshalParams(nil, loaded.Params), pir.MarshalParams(nil, enc.Params)) {
+		t.Fatalf("loaded params %+v, expected %+v", loaded.Params, enc.Params)
+	}
+	for _, i := range []uint64{0, 16} {
+		q, err := anyQuery(scheme, enc.Params, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := scheme.Answer(enc, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := scheme.Answer(loaded, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("loaded database answers element %d differently", i)
+		}
+	}
+	if _, err := pir.Unmarshal(scheme, data[:len(data)-1]); err == nil {
+		t.Fatal("truncated database should be rejected")
+	}
+}
+
+// Parallel checks that answers split across goroutines, by a scheme which
+// is a pir.Parallel, are those computed on one.
+func Parallel(t *testing.T, scheme pir.Scheme) {
+	p := scheme.(pir.Parallel)
+	defer p.SetWorkers(0)
+	enc, err := setup(scheme, RandomDatabase(17, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []uint64{0, 9, 16} {
+		q, err := anyQuery(scheme, enc.Params, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SetWorkers(0)
+		want, err := scheme.Answer(enc, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// more workers than elements leaves some without a share.
+		for _, n := range []int{2, 3, 64} {
+			p.SetWorkers(n)
+			got, err := scheme.Answer(enc, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("element %d answered differently by %d workers", i, n)
+			}
+		}
+	}
+}
+
+// StreamDecode checks that answers written piece by piece to the decoders
+// of scheme, a pir.StreamDecoder, decode as Decode does, and that decoders
+// reject answers cut short or overlong.
+func StreamDecode(t *testing.T, scheme pir.Scheme) {
+	sd := scheme.(pir.StreamDecoder)
+	db := RandomDatabase(17, 45)
+	enc, err := setup(scheme, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []uint64{0, 9, 16} {
+		q, sec, err := scheme.Query(enc.Params, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		answer, err := scheme.Answer(enc, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// pieces of odd sizes split the scheme's units of answer.
+		for _, piece := range []int{1, 7, 333, len(answer)} {
+			d, err := sd.NewDecoder(enc.Params, sec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for rest := answer; len(rest) > 0; {
+				n := piece
+				if n > len(rest) {
+					n = len(rest)
+				}
+				if _, err := d.Write(rest[:n]); err != nil {
+					t.Fatal(err)
+				}
+				rest = rest[n:]
+			}
+			got, err := d.Element()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, db.Elements[i]) {
+				t.Fatalf("element %d decoded wrongly from pieces of %d bytes", i, piece)
+			}
+		}
+
+		d, err := sd.NewDecoder(enc.Params, sec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Write(answer[:len(answer)-1]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Element(); err == nil {
+			t.Fatal("truncated answer should be rejected")
+		}
+		if _, err := d.Write(answer[len(answer)-1:]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Write([]byte{0}); err == nil {
+			t.Fatal("overlong answer should be rejected")
+		}
+	}
+}
+
+// anyQuery builds a query for index, the first server's if scheme is a
+// pir.MultiServer.
+func anyQuery(scheme pir.Scheme, params pir.Params, index uint64) ([]byte, error) {
+	if ms, ok := scheme.(pir.MultiServer); ok {
+		qs, _, err := ms.QueryServers(params, index)
+		if err != nil {
+			return nil, err
+		}
+		return qs[0], nil
+	}
+	q, _, err := scheme.Query(params, index)
+	return q, err
+}
+
+// FuzzAnswer checks that scheme answers or rejects arbitrary queries
+// without panicking. It is seeded with genuine queries for a small
+// database.
+func FuzzAnswer(f *testing.F, scheme pir.Scheme) {
+	enc, err := setup(scheme, RandomDatabase(17, 45))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, i := range []uint64{0, 16} {
+		q, err := anyQuery(scheme, enc.Params, i)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(q)
+	}
+	f.Add([]byte("garbage"))
+	f.Fuzz(func(t *testing.T, query []byte) {
+		_, _ = scheme.Answer(enc, query)
+	})
+}
+
+// DatabaseSizes are the numbers of elements benchmarks are run against.
+var DatabaseSizes = []int{1000, 10000, 100000}
+
+// BenchmarkAnswer measures server answer latency over databases of each of
+// DatabaseSizes with elements of size bytes.
+func BenchmarkAnswer(b *testing.B, scheme pir.Scheme, size int) {
+	for _, n := range DatabaseSizes {
+		b.Run(fmt.Sprintf("%s/n=%d", scheme.ID(), n), func(b *testing.B) {
+			enc, err := setup(scheme, RandomDatabase(n, size))
+			if err != nil {
+				b.Fatal(err)
+			}
+			q, err := anyQuery(scheme, enc.Params, uint64(n/2))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := scheme.Answer(enc, q); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package registry
+
+import (
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/simplepir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+	"github.com/willscott/go-selfish-bitswap-client/pir/xorpir"
+)
+
+// the pure Go schemes, registered in every build.
+func init() {
+	Register("fastpir", func() pir.Scheme { return fastpir.New() })
+	Register("spiral", func() pir.Scheme { return spiral.New() })
+	Register("simplepir", func() pir.Scheme { return simplepir.New() })
+	Register("doublepir", func() pir.Scheme { return simplepir.NewDouble() })
+	Register("xorpir", func() pir.Scheme { return xorpir.New() })
+}
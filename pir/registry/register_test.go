@@ -0,0 +1,22 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+)
+
+func TestRegister(t *testing.T) {
+	Register("test-scheme", func() pir.Scheme { return fastpir.New() })
+	t.Cleanup(func() { unregister("test-scheme") })
+	if _, err := New("test-scheme"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a name twice should panic")
+		}
+	}()
+	Register("test-scheme", func() pir.Scheme { return fastpir.New() })
+}
@@ -0,0 +1,88 @@
+// Package registry names the PIR schemes built into a binary, so commands
+// and clients can offer whichever are available rather than a fixed list.
+//
+// The pure Go schemes are always registered. Backends which need cgo, such
+// as bindings to native PIR libraries, register themselves from the init
+// functions of files built only with the cgo build tag, and a tag of their
+// own where the library must be installed apart (sealpir for SealPIR), so
+// builds without cgo (wasm, mobile, cross-compiled) still get every pure Go
+// scheme, and builds with it add the rest.
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// ErrUnknownScheme is returned by New for names not registered.
+var ErrUnknownScheme = errors.New("registry: unknown PIR scheme")
+
+var (
+	mtx     sync.RWMutex
+	names   []string
+	schemes = make(map[string]func() pir.Scheme)
+)
+
+// Register makes the scheme created by newScheme available as name.
+// Schemes registered earlier are preferred. Registering a name twice
+// panics.
+func Register(name string, newScheme func() pir.Scheme) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if _, ok := schemes[name]; ok {
+		panic(fmt.Sprintf("registry: scheme %q registered twice", name))
+	}
+	names = append(names, name)
+	schemes[name] = newScheme
+}
+
+// unregister removes the scheme registered as name, for tests to undo
+// Register.
+func unregister(name string) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	delete(schemes, name)
+	for i, n := range names {
+		if n == name {
+			names = append(names[:i], names[i+1:]...)
+			break
+		}
+	}
+}
+
+// New creates the scheme registered as name.
+func New(name string) (pir.Scheme, error) {
+	mtx.RLock()
+	newScheme, ok := schemes[name]
+	mtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q, not one of %v", ErrUnknownScheme, name, Names())
+	}
+	return newScheme(), nil
+}
+
+// Names returns the names of the registered schemes, in order of
+// preference.
+func Names() []string {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return append([]string(nil), names...)
+}
+
+// Single returns the names of the registered schemes which a client can
+// query on its own, leaving out those split across several servers, in
+// order of preference. Clients offering all of them let the handshake
+// settle on whichever the server also has.
+func Single() []string {
+	var out []string
+	for _, name := range Names() {
+		scheme, _ := New(name)
+		if _, multi := scheme.(pir.MultiServer); !multi {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,27 @@
+package registry_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/registry"
+)
+
+func TestBuiltin(t *testing.T) {
+	scheme, err := registry.New("fastpir")
+	if err != nil || scheme.ID() != fastpir.ID {
+		t.Fatalf("got %v, %v", scheme, err)
+	}
+	if _, err := registry.New("seal"); !errors.Is(err, registry.ErrUnknownScheme) {
+		t.Fatalf("unregistered scheme: %v", err)
+	}
+	if names := registry.Names(); len(names) < 5 || names[0] != "fastpir" {
+		t.Fatalf("registered %v", names)
+	}
+	for _, name := range registry.Single() {
+		if name == "xorpir" {
+			t.Fatal("two-server scheme offered to single peers")
+		}
+	}
+}
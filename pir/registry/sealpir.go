@@ -0,0 +1,14 @@
+//go:build cgo && sealpir
+
+package registry
+
+import (
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/sealpir"
+)
+
+// SealPIR, in builds with the sealpir tag, for comparison with the pure Go
+// schemes. It follows them in preference.
+func init() {
+	Register("sealpir", func() pir.Scheme { return sealpir.New() })
+}
@@ -0,0 +1,12 @@
+// Package sealpir binds SealPIR, Microsoft's single-server PIR scheme over
+// BFV ciphertexts, as a pir.Scheme, to compare the pure Go schemes against.
+//
+// It is built only with cgo and the sealpir build tag, and links against
+// SealPIR and Microsoft SEAL, which must be built beforehand:
+//
+//	CGO_CXXFLAGS=-I$SEALPIR/src CGO_LDFLAGS=-L$SEALPIR/build/lib \
+//		go test -tags sealpir -bench BenchmarkAnswer ./pir/fastpir ./pir/sealpir
+//
+// SEAL is found with pkg-config. Builds without the tag leave the package
+// empty, and the registry without it.
+package sealpir
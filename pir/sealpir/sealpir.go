@@ -0,0 +1,208 @@
+//go:build cgo && sealpir
+
+package sealpir
+
+/*
+#cgo CXXFLAGS: -std=c++17
+#cgo pkg-config: seal
+#cgo LDFLAGS: -lsealpir
+#include <stdlib.h>
+#include "shim.h"
+*/
+import "C"
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// ID is the scheme identifier advertised to peers.
+const ID = "sealpir-bfv4096/v1"
+
+// D is the number of dimensions databases are laid out in, as in SealPIR's
+// own benchmark.
+const D = 2
+
+// maxClients bounds the Galois keys a database holds at once. Keys are
+// replaced oldest first.
+const maxClients = 64
+
+// Scheme implements pir.Scheme.
+type Scheme struct{}
+
+var _ pir.Scheme = (*Scheme)(nil)
+
+// New returns the SealPIR scheme.
+func New() *Scheme {
+	return &Scheme{}
+}
+
+type state struct {
+	mtx    sync.Mutex
+	server *C.sealpir_server
+	// clients maps the digests of the Galois keys set on server to the
+	// client they were set for, and slots the other way.
+	clients map[[32]byte]uint32
+	slots   [maxClients]*[32]byte
+	next    uint32
+}
+
+type secret struct {
+	client *C.sealpir_client
+	index  uint64
+}
+
+func (s *Scheme) ID() string {
+	return ID
+}
+
+func (s *Scheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	if db.ElementSize <= 0 {
+		return nil, fmt.Errorf("sealpir: invalid element size %d", db.ElementSize)
+	}
+	if len(db.Elements) == 0 {
+		return nil, fmt.Errorf("sealpir: empty database")
+	}
+	flat := make([]byte, len(db.Elements)*db.ElementSize)
+	for j, e := range db.Elements {
+		if len(e) > db.ElementSize {
+			return nil, fmt.Errorf("sealpir: element %d is %d bytes, larger than %d", j, len(e), db.ElementSize)
+		}
+		copy(flat[j*db.ElementSize:], e)
+	}
+	st := &state{clients: make(map[[32]byte]uint32)}
+	var cerr *C.char
+	if C.sealpir_server_new(C.uint64_t(len(db.Elements)), C.uint64_t(db.ElementSize), D, ptr(flat), &st.server, &cerr) != 0 {
+		return nil, errorOf(cerr)
+	}
+	runtime.SetFinalizer(st, func(st *state) { C.sealpir_server_free(st.server) })
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      ID,
+			NumElements: uint64(len(db.Elements)),
+			ElementSize: uint64(db.ElementSize),
+		},
+		State: st,
+	}, nil
+}
+
+// Query generates a fresh client, whose Galois keys are sent ahead of the
+// query itself: SealPIR servers need them to expand it. Servers keep the
+// keys of recent clients, so queries reusing them cost no more to answer.
+func (s *Scheme) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	if params.Scheme != ID {
+		return nil, nil, pir.ErrSchemeMismatch
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	sec := &secret{index: index}
+	var cerr *C.char
+	if C.sealpir_client_new(C.uint64_t(params.NumElements), C.uint64_t(params.ElementSize), D, &sec.client, &cerr) != 0 {
+		return nil, nil, errorOf(cerr)
+	}
+	runtime.SetFinalizer(sec, func(sec *secret) { C.sealpir_client_free(sec.client) })
+	var keys, q C.sealpir_buf
+	if C.sealpir_client_keys(sec.client, &keys, &cerr) != 0 {
+		return nil, nil, errorOf(cerr)
+	}
+	defer C.sealpir_buf_free(&keys)
+	if C.sealpir_client_query(sec.client, C.uint64_t(index), &q, &cerr) != 0 {
+		return nil, nil, errorOf(cerr)
+	}
+	defer C.sealpir_buf_free(&q)
+	var n [binary.MaxVarintLen64]byte
+	out := append(n[:binary.PutUvarint(n[:], uint64(keys.len))], bytesOf(keys)...)
+	return append(out, bytesOf(q)...), sec, nil
+}
+
+func (s *Scheme) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	n, l := binary.Uvarint(query)
+	if l <= 0 || n == 0 || n >= uint64(len(query)-l) {
+		return nil, pir.ErrMalformed
+	}
+	keys, q := query[l:l+int(n)], query[l+int(n):]
+
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	client, err := st.client(keys)
+	if err != nil {
+		return nil, err
+	}
+	var ans C.sealpir_buf
+	var cerr *C.char
+	if C.sealpir_server_answer(st.server, C.uint32_t(client), ptr(q), C.size_t(len(q)), &ans, &cerr) != 0 {
+		return nil, fmt.Errorf("%w: %v", pir.ErrMalformed, errorOf(cerr))
+	}
+	defer C.sealpir_buf_free(&ans)
+	return bytesOf(ans), nil
+}
+
+// client returns the client the Galois keys are set for on the server,
+// setting them in the slot of the oldest keys if they are not.
+func (st *state) client(keys []byte) (uint32, error) {
+	digest := sha256.Sum256(keys)
+	if c, ok := st.clients[digest]; ok {
+		return c, nil
+	}
+	c := st.next
+	var cerr *C.char
+	if C.sealpir_server_set_keys(st.server, C.uint32_t(c), ptr(keys), C.size_t(len(keys)), &cerr) != 0 {
+		return 0, fmt.Errorf("%w: %v", pir.ErrMalformed, errorOf(cerr))
+	}
+	if old := st.slots[c]; old != nil {
+		delete(st.clients, *old)
+	}
+	st.slots[c] = &digest
+	st.clients[digest] = c
+	st.next = (c + 1) % maxClients
+	return c, nil
+}
+
+func (s *Scheme) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	if params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	se, ok := sec.(*secret)
+	if !ok || len(answer) == 0 {
+		return nil, pir.ErrMalformed
+	}
+	var elem C.sealpir_buf
+	var cerr *C.char
+	if C.sealpir_client_decode(se.client, C.uint64_t(se.index), ptr(answer), C.size_t(len(answer)), &elem, &cerr) != 0 {
+		return nil, fmt.Errorf("%w: %v", pir.ErrMalformed, errorOf(cerr))
+	}
+	defer C.sealpir_buf_free(&elem)
+	runtime.KeepAlive(se)
+	if uint64(elem.len) != params.ElementSize {
+		return nil, pir.ErrMalformed
+	}
+	return bytesOf(elem), nil
+}
+
+// ptr points C at the bytes of b, which must not be empty.
+func ptr(b []byte) *C.uint8_t {
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
+
+// bytesOf copies buf into Go memory.
+func bytesOf(buf C.sealpir_buf) []byte {
+	return C.GoBytes(unsafe.Pointer(buf.data), C.int(buf.len))
+}
+
+// errorOf frees the message set by a failed call of the shim and returns it
+// as an error.
+func errorOf(cerr *C.char) error {
+	defer C.free(unsafe.Pointer(cerr))
+	return fmt.Errorf("sealpir: %s", C.GoString(cerr))
+}
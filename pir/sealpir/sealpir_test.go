@@ -0,0 +1,22 @@
+//go:build cgo && sealpir
+
+package sealpir_test
+
+import (
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/sealpir"
+)
+
+func TestRoundtrip(t *testing.T) {
+	pirtest.Roundtrip(t, sealpir.New())
+}
+
+func BenchmarkAnswer(b *testing.B) {
+	pirtest.BenchmarkAnswer(b, sealpir.New(), 32)
+}
+
+func BenchmarkScheme(b *testing.B) {
+	pirtest.Benchmark(b, sealpir.New())
+}
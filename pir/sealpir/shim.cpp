@@ -0,0 +1,185 @@
+//go:build cgo && sealpir
+
+#include "shim.h"
+
+#include <cstdlib>
+#include <cstring>
+#include <memory>
+#include <sstream>
+#include <string>
+
+#include "pir.hpp"
+#include "pir_client.hpp"
+#include "pir_server.hpp"
+
+using namespace seal;
+
+// The parameters of SealPIR's own benchmark: a polynomial degree of 4096
+// and 20 bit plaintexts.
+static const uint32_t poly_degree = 4096;
+static const uint32_t plain_bits = 20;
+// bounds the ciphertexts a reply claims to hold, far above the expansion
+// of any database SealPIR lays out with these parameters.
+static const uint32_t max_reply_ciphertexts = 1 << 16;
+
+struct sealpir_server {
+	EncryptionParameters enc;
+	PirParams pir;
+	std::unique_ptr<SEALContext> context;
+	std::unique_ptr<PIRServer> server;
+};
+
+struct sealpir_client {
+	EncryptionParameters enc;
+	PirParams pir;
+	std::unique_ptr<SEALContext> context;
+	std::unique_ptr<PIRClient> client;
+};
+
+static int fail(char **err, const char *msg) {
+	*err = strdup(msg);
+	return -1;
+}
+
+static void to_buf(const std::string &s, sealpir_buf *out) {
+	out->len = s.size();
+	out->data = static_cast<uint8_t *>(malloc(s.size()));
+	memcpy(out->data, s.data(), s.size());
+}
+
+static void params(uint64_t num, uint64_t size, uint32_t d,
+		EncryptionParameters &enc, PirParams &pir) {
+	gen_encryption_params(poly_degree, plain_bits, enc);
+	verify_encryption_params(enc);
+	gen_pir_params(num, size, d, enc, pir);
+}
+
+void sealpir_buf_free(sealpir_buf *buf) {
+	free(buf->data);
+	buf->data = nullptr;
+	buf->len = 0;
+}
+
+int sealpir_server_new(uint64_t num, uint64_t size, uint32_t d,
+		const uint8_t *db, sealpir_server **out, char **err) {
+	try {
+		auto s = std::make_unique<sealpir_server>();
+		s->enc = EncryptionParameters(scheme_type::bfv);
+		params(num, size, d, s->enc, s->pir);
+		s->context = std::make_unique<SEALContext>(s->enc, true);
+		s->server = std::make_unique<PIRServer>(s->enc, s->pir);
+		auto bytes = std::make_unique<uint8_t[]>(num * size);
+		memcpy(bytes.get(), db, num * size);
+		std::unique_ptr<const uint8_t[]> held(bytes.release());
+		s->server->set_database(held, num, size);
+		s->server->preprocess_database();
+		*out = s.release();
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
+
+void sealpir_server_free(sealpir_server *s) {
+	delete s;
+}
+
+int sealpir_server_set_keys(sealpir_server *s, uint32_t client,
+		const uint8_t *keys, size_t len, char **err) {
+	try {
+		std::stringstream in(std::string(reinterpret_cast<const char *>(keys), len));
+		GaloisKeys gk;
+		gk.load(*s->context, in);
+		s->server->set_galois_key(client, gk);
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
+
+int sealpir_server_answer(sealpir_server *s, uint32_t client,
+		const uint8_t *query, size_t len, sealpir_buf *out, char **err) {
+	try {
+		std::stringstream in(std::string(reinterpret_cast<const char *>(query), len));
+		PirQuery q = s->server->deserialize_query(in);
+		PirReply reply = s->server->generate_reply(q, client);
+		std::stringstream res;
+		uint32_t n = reply.size();
+		res.write(reinterpret_cast<const char *>(&n), sizeof(n));
+		for (auto &ct : reply) {
+			ct.save(res);
+		}
+		to_buf(res.str(), out);
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
+
+int sealpir_client_new(uint64_t num, uint64_t size, uint32_t d,
+		sealpir_client **out, char **err) {
+	try {
+		auto c = std::make_unique<sealpir_client>();
+		c->enc = EncryptionParameters(scheme_type::bfv);
+		params(num, size, d, c->enc, c->pir);
+		c->context = std::make_unique<SEALContext>(c->enc, true);
+		c->client = std::make_unique<PIRClient>(c->enc, c->pir);
+		*out = c.release();
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
+
+void sealpir_client_free(sealpir_client *c) {
+	delete c;
+}
+
+int sealpir_client_keys(sealpir_client *c, sealpir_buf *out, char **err) {
+	try {
+		GaloisKeys gk = c->client->generate_galois_keys();
+		std::stringstream res;
+		gk.save(res);
+		to_buf(res.str(), out);
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
+
+int sealpir_client_query(sealpir_client *c, uint64_t index,
+		sealpir_buf *out, char **err) {
+	try {
+		std::stringstream res;
+		c->client->generate_serialized_query(c->client->get_fv_index(index), res);
+		to_buf(res.str(), out);
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
+
+int sealpir_client_decode(sealpir_client *c, uint64_t index,
+		const uint8_t *reply, size_t len, sealpir_buf *out, char **err) {
+	try {
+		std::stringstream in(std::string(reinterpret_cast<const char *>(reply), len));
+		uint32_t n;
+		if (!in.read(reinterpret_cast<char *>(&n), sizeof(n))) {
+			return fail(err, "truncated reply");
+		}
+		if (n > max_reply_ciphertexts) {
+			return fail(err, "reply has too many ciphertexts");
+		}
+		PirReply r(n);
+		for (auto &ct : r) {
+			ct.load(*c->context, in);
+		}
+		std::vector<uint8_t> elem = c->client->decode_reply(r, c->client->get_fv_offset(index));
+		out->len = elem.size();
+		out->data = static_cast<uint8_t *>(malloc(elem.size()));
+		memcpy(out->data, elem.data(), elem.size());
+		return 0;
+	} catch (const std::exception &e) {
+		return fail(err, e.what());
+	}
+}
@@ -0,0 +1,53 @@
+//go:build cgo && sealpir
+
+// C interface to the SealPIR client and server, for cgo. Functions
+// returning int return 0 on success, or -1 with *err set to a message the
+// caller frees.
+#ifndef SEALPIR_SHIM_H
+#define SEALPIR_SHIM_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct sealpir_server sealpir_server;
+typedef struct sealpir_client sealpir_client;
+
+typedef struct {
+	uint8_t *data;
+	size_t len;
+} sealpir_buf;
+
+void sealpir_buf_free(sealpir_buf *buf);
+
+// sealpir_server_new lays out num elements of size bytes, concatenated in
+// db, with d dimensions of recursion.
+int sealpir_server_new(uint64_t num, uint64_t size, uint32_t d,
+		const uint8_t *db, sealpir_server **out, char **err);
+void sealpir_server_free(sealpir_server *s);
+// sealpir_server_set_keys sets the Galois keys of client, serialized by
+// sealpir_client_keys.
+int sealpir_server_set_keys(sealpir_server *s, uint32_t client,
+		const uint8_t *keys, size_t len, char **err);
+int sealpir_server_answer(sealpir_server *s, uint32_t client,
+		const uint8_t *query, size_t len, sealpir_buf *out, char **err);
+
+int sealpir_client_new(uint64_t num, uint64_t size, uint32_t d,
+		sealpir_client **out, char **err);
+void sealpir_client_free(sealpir_client *c);
+int sealpir_client_keys(sealpir_client *c, sealpir_buf *out, char **err);
+int sealpir_client_query(sealpir_client *c, uint64_t index,
+		sealpir_buf *out, char **err);
+// sealpir_client_decode recovers the element at index from the reply to
+// the query built for it.
+int sealpir_client_decode(sealpir_client *c, uint64_t index,
+		const uint8_t *reply, size_t len, sealpir_buf *out, char **err);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,444 @@
+// Package shard splits PIR databases by index range into shards which are
+// encoded and answered separately, by several workers or several machines,
+// so that databases too large for one pass of one machine can be served.
+//
+// A query for an element carries one query of the underlying scheme per
+// shard, each for the element's offset within its shard, and the answer one
+// answer per shard. The coordinator answering it routes each encoded query
+// to its shard and gathers the answers; as every shard is queried alike,
+// neither the coordinator nor the shards learn which one held the element.
+// The shards together answer at the cost of one pass over the database, but
+// each takes only its share of the time.
+package shard
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+var (
+	// ErrUnevenShards is returned by Setup when shards of the same size were
+	// set up with different parameters.
+	ErrUnevenShards = errors.New("shard: parameters differ between shards")
+	// ErrNoUpdate is returned by Update for databases whose shards were
+	// placed elsewhere.
+	ErrNoUpdate = errors.New("shard: shards cannot be updated")
+)
+
+// A Shard answers queries over one index range of a database.
+// Implementations must be safe for concurrent use.
+type Shard interface {
+	// Params are the parameters of the shard's encoded database.
+	Params() pir.Params
+	// Answer computes the response to query over the shard.
+	Answer(query []byte) ([]byte, error)
+}
+
+// Options configure how databases are sharded.
+type Options struct {
+	// Shards is the number of shards a database is split into. Databases
+	// with fewer elements have one shard per element.
+	Shards int
+	// Place, if set, sets up shard i of a database in place of encoding it
+	// locally, for instance by sending it to another machine and returning
+	// a Shard which forwards queries there. Databases with placed shards
+	// cannot be updated.
+	Place func(i int, db pir.Database) (Shard, error)
+}
+
+// Scheme wraps another scheme, sharding its databases. Clients need not
+// know the number of shards, which the parameters carry.
+type Scheme struct {
+	scheme pir.Scheme
+	opts   Options
+}
+
+// updater is a Scheme over a pir.Updater.
+type updater struct {
+	*Scheme
+}
+
+var (
+	_ pir.Scheme    = (*Scheme)(nil)
+	_ pir.Updater   = updater{}
+	_ pir.Persister = (*Scheme)(nil)
+	_ pir.Parallel  = (*Scheme)(nil)
+	_ pir.CostModel = (*Scheme)(nil)
+)
+
+// New returns scheme with its databases sharded as opts say. It is a
+// pir.Updater if scheme is.
+func New(scheme pir.Scheme, opts Options) pir.Scheme {
+	s := &Scheme{scheme: scheme, opts: opts}
+	if _, ok := scheme.(pir.Updater); ok {
+		return updater{s}
+	}
+	return s
+}
+
+// SetWorkers passes n to the underlying scheme, if it is a pir.Parallel,
+// which splits the answer of each local shard across that many goroutines,
+// on top of the shards being answered at once.
+func (s *Scheme) SetWorkers(n int) {
+	if p, ok := s.scheme.(pir.Parallel); ok {
+		p.SetWorkers(n)
+	}
+}
+
+// Local returns the shard of db, as encoded by scheme, answered locally.
+func Local(scheme pir.Scheme, db *pir.Encoded) Shard {
+	return local{scheme, db}
+}
+
+type local struct {
+	scheme pir.Scheme
+	db     *pir.Encoded
+}
+
+func (l local) Params() pir.Params { return l.db.Params }
+
+func (l local) Answer(query []byte) ([]byte, error) {
+	return l.scheme.Answer(l.db, query)
+}
+
+type state struct {
+	shards []Shard
+	// placed is set when shards were set up by Options.Place.
+	placed bool
+}
+
+type secret struct {
+	shard  int
+	secret pir.Secret
+}
+
+// ID names the underlying scheme as sharded. Sharded and unsharded
+// databases cannot query each other.
+func (s *Scheme) ID() string {
+	return "sharded-" + s.scheme.ID()
+}
+
+// AnswerCost is that of answering every shard with the underlying scheme,
+// wherever the shards are placed.
+func (s *Scheme) AnswerCost(params pir.Params) time.Duration {
+	shards, inner, err := s.shardParams(params)
+	if err != nil {
+		return time.Duration(params.NumElements*params.ElementSize) * pir.DefaultAnswerCost
+	}
+	return time.Duration(shards) * pir.AnswerCost(s.scheme, inner)
+}
+
+// Setup splits db into contiguous ranges of the same size, padding the last
+// with empty elements, and sets up each range as a shard, all at once.
+// Schemes with hints are not sharded, as clients would need one per shard.
+func (s *Scheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	if _, ok := s.scheme.(pir.Hinter); ok {
+		return nil, fmt.Errorf("shard: %s has hints, which cannot be sharded", s.scheme.ID())
+	}
+	n := len(db.Elements)
+	shards := s.opts.Shards
+	if shards > n {
+		shards = n
+	}
+	if shards < 1 {
+		shards = 1
+	}
+	size := (n + shards - 1) / shards
+	if size < 1 {
+		size = 1
+	}
+	st := &state{shards: make([]Shard, shards), placed: s.opts.Place != nil}
+	errs := make([]error, shards)
+	var wg sync.WaitGroup
+	for i := range st.shards {
+		part := pir.Database{Elements: make([][]byte, size), ElementSize: db.ElementSize}
+		if i*size < n {
+			end := (i + 1) * size
+			if end > n {
+				end = n
+			}
+			copy(part.Elements, db.Elements[i*size:end])
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			st.shards[i], errs[i] = s.place(i, part)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	inner := st.shards[0].Params()
+	for _, sh := range st.shards[1:] {
+		if !sameParams(sh.Params(), inner) {
+			return nil, ErrUnevenShards
+		}
+	}
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      s.ID(),
+			NumElements: uint64(n),
+			ElementSize: uint64(db.ElementSize),
+			Extra:       encodeExtra(uint64(shards), inner),
+		},
+		State: st,
+	}, nil
+}
+
+func (s *Scheme) place(i int, db pir.Database) (Shard, error) {
+	if s.opts.Place != nil {
+		return s.opts.Place(i, db)
+	}
+	enc, err := s.scheme.Setup(db)
+	if err != nil {
+		return nil, err
+	}
+	return Local(s.scheme, enc), nil
+}
+
+func sameParams(a, b pir.Params) bool {
+	return a.Scheme == b.Scheme && a.NumElements == b.NumElements &&
+		a.ElementSize == b.ElementSize && string(a.Extra) == string(b.Extra)
+}
+
+// encodeExtra encodes the number of shards and the parameters they share,
+// less the element size, which they share with the whole database.
+func encodeExtra(shards uint64, inner pir.Params) []byte {
+	out := appendUvarint(nil, shards)
+	out = appendUvarint(out, inner.NumElements)
+	out = appendUvarint(out, uint64(len(inner.Scheme)))
+	out = append(out, inner.Scheme...)
+	return append(out, inner.Extra...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// appendPart appends part to b, prefixed by its length.
+func appendPart(b, part []byte) []byte {
+	return append(appendUvarint(b, uint64(len(part))), part...)
+}
+
+// shardParams returns the number of shards of the database described by
+// params, and the parameters of each.
+func (s *Scheme) shardParams(params pir.Params) (int, pir.Params, error) {
+	if params.Scheme != s.ID() {
+		return 0, pir.Params{}, pir.ErrSchemeMismatch
+	}
+	extra := params.Extra
+	var fields [3]uint64
+	for i := range fields {
+		v, k := binary.Uvarint(extra)
+		if k <= 0 {
+			return 0, pir.Params{}, pir.ErrMalformed
+		}
+		fields[i], extra = v, extra[k:]
+	}
+	shards, size, idLen := fields[0], fields[1], fields[2]
+	// the shards are laid out as Setup lays them out, so a peer can't have
+	// clients query more of them, or larger ones, than the database needs.
+	n := params.NumElements
+	if n == 0 {
+		n = 1
+	}
+	if shards == 0 || shards > n || size != (n+shards-1)/shards || idLen > uint64(len(extra)) {
+		return 0, pir.Params{}, pir.ErrMalformed
+	}
+	inner := pir.Params{
+		Scheme:      string(extra[:idLen]),
+		NumElements: size,
+		ElementSize: params.ElementSize,
+		Extra:       extra[idLen:],
+	}
+	if inner.Scheme != s.scheme.ID() {
+		return 0, pir.Params{}, pir.ErrSchemeMismatch
+	}
+	return int(shards), inner, nil
+}
+
+// Query builds one query of the underlying scheme per shard, all for the
+// offset of index within its shard, keeping the secret of the one for the
+// shard holding it.
+func (s *Scheme) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	shards, inner, err := s.shardParams(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	sec := secret{shard: int(index / inner.NumElements)}
+	var out []byte
+	for i := 0; i < shards; i++ {
+		q, qs, err := s.scheme.Query(inner, index%inner.NumElements)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == sec.shard {
+			sec.secret = qs
+		}
+		out = appendPart(out, q)
+	}
+	return out, sec, nil
+}
+
+// split parses n length-prefixed parts of msg.
+func split(msg []byte, n int) ([][]byte, error) {
+	parts := make([][]byte, n)
+	for i := range parts {
+		l, k := binary.Uvarint(msg)
+		if k <= 0 || l > uint64(len(msg)-k) {
+			return nil, pir.ErrMalformed
+		}
+		parts[i], msg = msg[k:k+int(l)], msg[k+int(l):]
+	}
+	if len(msg) != 0 {
+		return nil, pir.ErrMalformed
+	}
+	return parts, nil
+}
+
+// Answer routes each shard's query to it, answering all shards at once.
+func (s *Scheme) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != s.ID() {
+		return nil, pir.ErrSchemeMismatch
+	}
+	queries, err := split(query, len(st.shards))
+	if err != nil {
+		return nil, err
+	}
+	answers := make([][]byte, len(st.shards))
+	errs := make([]error, len(st.shards))
+	var wg sync.WaitGroup
+	for i, sh := range st.shards {
+		wg.Add(1)
+		go func(i int, sh Shard) {
+			defer wg.Done()
+			answers[i], errs[i] = sh.Answer(queries[i])
+		}(i, sh)
+	}
+	wg.Wait()
+	var out []byte
+	for i, a := range answers {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, errs[i])
+		}
+		out = appendPart(out, a)
+	}
+	return out, nil
+}
+
+// Decode decodes the answer of the shard holding the queried element.
+func (s *Scheme) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	shards, inner, err := s.shardParams(params)
+	if err != nil {
+		return nil, err
+	}
+	qs, ok := sec.(secret)
+	if !ok || qs.shard >= shards {
+		return nil, pir.ErrSchemeMismatch
+	}
+	answers, err := split(answer, shards)
+	if err != nil {
+		return nil, err
+	}
+	return s.scheme.Decode(inner, qs.secret, answers[qs.shard])
+}
+
+// Update updates the shards holding the changed elements, sharing the others
+// with db.
+func (u updater) Update(db *pir.Encoded, changes map[uint64][]byte) (*pir.Encoded, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != u.ID() {
+		return nil, pir.ErrSchemeMismatch
+	}
+	if st.placed {
+		return nil, ErrNoUpdate
+	}
+	size := st.shards[0].Params().NumElements
+	perShard := make(map[uint64]map[uint64][]byte)
+	for j, e := range changes {
+		if j >= db.Params.NumElements {
+			return nil, pir.ErrIndexOutOfRange
+		}
+		if perShard[j/size] == nil {
+			perShard[j/size] = make(map[uint64][]byte)
+		}
+		perShard[j/size][j%size] = e
+	}
+	next := &state{shards: append([]Shard(nil), st.shards...)}
+	for i, sc := range perShard {
+		l := st.shards[i].(local)
+		enc, err := l.scheme.(pir.Updater).Update(l.db, sc)
+		if err != nil {
+			return nil, err
+		}
+		next.shards[i] = local{l.scheme, enc}
+	}
+	return &pir.Encoded{Params: db.Params, State: next}, nil
+}
+
+// MarshalState returns the states of the shards, through the underlying
+// scheme, which must be a pir.Persister. Shards placed elsewhere cannot be
+// saved.
+func (s *Scheme) MarshalState(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != s.ID() {
+		return nil, pir.ErrSchemeMismatch
+	}
+	p, ok := s.scheme.(pir.Persister)
+	if !ok || st.placed {
+		return nil, pir.ErrNotPersistent
+	}
+	var out []byte
+	for _, sh := range st.shards {
+		l := sh.(local)
+		part, err := p.MarshalState(l.db)
+		if err != nil {
+			return nil, err
+		}
+		out = pir.AppendAligned(out, part)
+	}
+	return out, nil
+}
+
+// UnmarshalState restores the shards saved by MarshalState, answered
+// locally.
+func (s *Scheme) UnmarshalState(params pir.Params, data []byte) (interface{}, error) {
+	shards, inner, err := s.shardParams(params)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := s.scheme.(pir.Persister)
+	if !ok {
+		return nil, pir.ErrNotPersistent
+	}
+	st := &state{shards: make([]Shard, shards)}
+	for i := range st.shards {
+		part, rest, err := pir.Aligned(data)
+		if err != nil {
+			return nil, err
+		}
+		sst, err := p.UnmarshalState(inner, part)
+		if err != nil {
+			return nil, err
+		}
+		st.shards[i] = Local(s.scheme, &pir.Encoded{Params: inner, State: sst})
+		data = rest
+	}
+	if len(data) != 0 {
+		return nil, pir.ErrMalformed
+	}
+	return st, nil
+}
@@ -0,0 +1,79 @@
+package shard_test
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/shard"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+)
+
+func TestRoundtrip(t *testing.T) {
+	for _, scheme := range []pir.Scheme{fastpir.New(), spiral.New()} {
+		for _, shards := range []int{1, 4, 40} {
+			pirtest.Roundtrip(t, shard.New(scheme, shard.Options{Shards: shards}))
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	pirtest.Update(t, shard.New(fastpir.New(), shard.Options{Shards: 4}))
+}
+
+func TestPersist(t *testing.T) {
+	pirtest.Persist(t, shard.New(spiral.New(), shard.Options{Shards: 4}))
+}
+
+func TestParallel(t *testing.T) {
+	pirtest.Parallel(t, shard.New(fastpir.New(), shard.Options{Shards: 4}))
+}
+
+func FuzzAnswer(f *testing.F) {
+	pirtest.FuzzAnswer(f, shard.New(fastpir.New(), shard.Options{Shards: 4}))
+}
+
+// counted is a shard counting the queries it answers, as one on another
+// machine would be reached.
+type counted struct {
+	shard.Shard
+	answered *int64
+}
+
+func (c counted) Answer(query []byte) ([]byte, error) {
+	atomic.AddInt64(c.answered, 1)
+	return c.Shard.Answer(query)
+}
+
+func TestPlace(t *testing.T) {
+	inner := fastpir.New()
+	answered := make([]int64, 3)
+	scheme := shard.New(inner, shard.Options{Shards: 3, Place: func(i int, db pir.Database) (shard.Shard, error) {
+		enc, err := inner.Setup(db)
+		if err != nil {
+			return nil, err
+		}
+		return counted{shard.Local(inner, enc), &answered[i]}, nil
+	}})
+	db := pirtest.RandomDatabase(10, 32)
+	enc, err := scheme.Setup(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pirtest.Get(t, scheme, enc, 9); !bytes.Equal(got, db.Elements[9]) {
+		t.Fatalf("got %x, expected %x", got, db.Elements[9])
+	}
+	// every shard is queried, whichever holds the element.
+	for i, n := range answered {
+		if n != 1 {
+			t.Fatalf("shard %d answered %d queries", i, n)
+		}
+	}
+	if _, err := scheme.(pir.Updater).Update(enc, map[uint64][]byte{0: nil}); !errors.Is(err, shard.ErrNoUpdate) {
+		t.Fatalf("updating placed shards: %v", err)
+	}
+}
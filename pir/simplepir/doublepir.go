@@ -0,0 +1,304 @@
+package simplepir
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/lwe"
+)
+
+// DoubleID is the DoublePIR scheme identifier advertised to peers.
+const DoubleID = "doublepir-lwe1024/v1"
+
+// Double implements pir.Hinter with DoublePIR.
+type Double struct {
+	workers int32
+}
+
+var (
+	_ pir.Hinter    = (*Double)(nil)
+	_ pir.Persister = (*Double)(nil)
+	_ pir.Parallel  = (*Double)(nil)
+	_ pir.CostModel = (*Double)(nil)
+)
+
+// NewDouble returns the DoublePIR scheme.
+func NewDouble() *Double {
+	return &Double{}
+}
+
+// second is the shape of the second layer, which runs over the k elements
+// of a column: each of their words is split into kappa digits of logp bits.
+type second struct {
+	logp  int
+	kappa int
+}
+
+func newSecond(lo layout) second {
+	logp := lwe.PlaintextBits(lo.k)
+	return second{logp: logp, kappa: (32 + logp - 1) / logp}
+}
+
+// decompose writes the kappa digits of w to dst.
+func (sc second) decompose(dst []uint32, w uint32) {
+	for u := range dst[:sc.kappa] {
+		dst[u] = (w >> (sc.logp * u)) & (uint32(1)<<sc.logp - 1)
+	}
+}
+
+// recompose reads a word back from its noisy encrypted digits, given the
+// masks to remove from them.
+func (sc second) recompose(digits, masks []uint32) uint32 {
+	var w uint32
+	for u := 0; u < sc.kappa; u++ {
+		w |= lwe.Round(digits[u]-masks[u], sc.logp) << (sc.logp * u)
+	}
+	return w
+}
+
+type doubleState struct {
+	*state
+	second
+	seed2 []byte
+	// mh holds, for each of the k elements of a column, the digits of the
+	// rows of the SimplePIR hint holding it: digits*N*kappa per element.
+	mh []uint32
+}
+
+// width is the number of second layer digits of the hint per element.
+func (ds *doubleState) width() int {
+	return ds.digits * N * ds.kappa
+}
+
+type doubleSecret struct {
+	s1, s2 []uint32
+}
+
+func (d *Double) ID() string {
+	return DoubleID
+}
+
+// AnswerCost is about a nanosecond per byte of the database, for the first
+// layer, and a few milliseconds for the second, whatever the database.
+func (d *Double) AnswerCost(params pir.Params) time.Duration {
+	return time.Duration(params.NumElements*params.ElementSize) + 3*time.Millisecond
+}
+
+// SetWorkers splits each answer across n goroutines: the first layer by
+// rows of the database, the second by columns of the hint.
+func (d *Double) SetWorkers(n int) {
+	atomic.StoreInt32(&d.workers, int32(n))
+}
+
+// Setup lays the elements out as a matrix, under fresh public matrices for
+// both layers, and decomposes its SimplePIR hint for the second.
+func (d *Double) Setup(db pir.Database) (*pir.Encoded, error) {
+	st, err := encode(db, "doublepir")
+	if err != nil {
+		return nil, err
+	}
+	seed2, err := lwe.NewSeed()
+	if err != nil {
+		return nil, err
+	}
+	ds := &doubleState{state: st, second: newSecond(st.layout), seed2: seed2}
+	h1 := st.hint()
+	ds.mh = make([]uint32, st.k*ds.width())
+	for r := 0; r < st.rows(); r++ {
+		// row r holds digit t of the element in slot i.
+		i, t := r/st.digits, r%st.digits
+		for x := 0; x < N; x++ {
+			ds.decompose(ds.mh[i*ds.width()+(t*N+x)*ds.kappa:], h1[r*N+x])
+		}
+	}
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      DoubleID,
+			NumElements: uint64(len(db.Elements)),
+			ElementSize: uint64(db.ElementSize),
+			Extra:       encodeExtra(st.layout, st.seed, seed2),
+		},
+		State: ds,
+	}, nil
+}
+
+// MarshalState returns the database matrix, then the decomposed SimplePIR
+// hint, whose computation is most of the cost of Setup.
+func (d *Double) MarshalState(db *pir.Encoded) ([]byte, error) {
+	ds, ok := db.State.(*doubleState)
+	if !ok || db.Params.Scheme != DoubleID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return append(putWords(ds.db), putWords(ds.mh)...), nil
+}
+
+// UnmarshalState restores the matrix and hint saved by MarshalState, both
+// read from data in place where it is aligned.
+func (d *Double) UnmarshalState(params pir.Params, data []byte) (interface{}, error) {
+	if params.Scheme != DoubleID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	lo, seeds, err := decodeExtra(params, 2)
+	if err != nil {
+		return nil, err
+	}
+	ds := &doubleState{state: &state{layout: lo, seed: seeds[0]}, second: newSecond(lo), seed2: seeds[1]}
+	n := 4 * lo.rows() * lo.m
+	if len(data) != n+4*lo.k*ds.width() {
+		return nil, pir.ErrMalformed
+	}
+	ds.db, ds.mh = lwe.View(data[:n]), lwe.View(data[n:])
+	return ds, nil
+}
+
+// mulA2 returns the transpose of the k rows of m, of width words each,
+// times the second layer's public matrix: N words per column of m.
+func (ds *doubleState) mulA2(m []uint32, width int) []uint32 {
+	out := make([]uint32, width*N)
+	prg := lwe.NewPRG(ds.seed2)
+	a := make([]uint32, N)
+	for i := 0; i < ds.k; i++ {
+		prg.Fill(a)
+		for c, v := range m[i*width : (i+1)*width] {
+			if v == 0 {
+				continue
+			}
+			lwe.MulAdd(out[c*N:(c+1)*N], a, v)
+		}
+	}
+	return out
+}
+
+// mulQuery returns the transpose of the k rows of m, of width words each,
+// times the second layer query, split across workers by column.
+func mulQuery(m []uint32, width int, query []uint32, workers int) []uint32 {
+	out := make([]uint32, width)
+	pir.Split(workers, width, func(_, lo, hi int) {
+		for i, q := range query {
+			lwe.MulAdd(out[lo:hi], m[i*width+lo:i*width+hi], q)
+		}
+	})
+	return out
+}
+
+// Hint returns the decomposed SimplePIR hint times the second layer's
+// public matrix, N words per digit: digits*kappa*N*N words whatever the
+// number of elements.
+func (d *Double) Hint(db *pir.Encoded) ([]byte, error) {
+	ds, ok := db.State.(*doubleState)
+	if !ok || db.Params.Scheme != DoubleID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return putWords(ds.mulA2(ds.mh, ds.width())), nil
+}
+
+// doubleLayout parses the layout of both layers from params.
+func doubleLayout(params pir.Params) (layout, second, [][]byte, error) {
+	if params.Scheme != DoubleID {
+		return layout{}, second{}, nil, pir.ErrSchemeMismatch
+	}
+	lo, seeds, err := decodeExtra(params, 2)
+	if err != nil {
+		return layout{}, second{}, nil, err
+	}
+	sc := newSecond(lo)
+	if len(params.Hint) != 4*lo.digits*sc.kappa*N*N {
+		return layout{}, second{}, nil, pir.ErrNoHint
+	}
+	return lo, sc, seeds, nil
+}
+
+// Query encrypts the selection vectors of the column holding index, for
+// the first layer, and of its slot within the column, for the second.
+func (d *Double) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	lo, sc, seeds, err := doubleLayout(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	col, row := lo.position(index)
+	slot := row / lo.digits
+	c1, s1, err := encrypt(seeds[0], lo.m, col, lo.logp)
+	if err != nil {
+		return nil, nil, err
+	}
+	c2, s2, err := encrypt(seeds[1], lo.k, slot, sc.logp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return putWords(append(c1, c2...)), &doubleSecret{s1: s1, s2: s2}, nil
+}
+
+// Answer computes the SimplePIR answer, then answers the second layer query
+// over the digits of both it and the SimplePIR hint. The answer is the
+// hint's digits*N*kappa words, then the SimplePIR answer's digits*kappa
+// words, then their masks, N words each.
+func (d *Double) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	ds, ok := db.State.(*doubleState)
+	if !ok || db.Params.Scheme != DoubleID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	if len(query) != 4*(ds.m+ds.k) {
+		return nil, pir.ErrMalformed
+	}
+	workers := int(atomic.LoadInt32(&d.workers))
+	q := words(query)
+	a1 := ds.answer(q[:ds.m], workers)
+	c2 := q[ds.m:]
+	width := ds.digits * ds.kappa
+	ma := make([]uint32, ds.k*width)
+	for r, w := range a1 {
+		ds.decompose(ma[r*ds.kappa:], w)
+	}
+	out := mulQuery(ds.mh, ds.width(), c2, workers)
+	out = append(out, mulQuery(ma, width, c2, workers)...)
+	out = append(out, ds.mulA2(ma, width)...)
+	return putWords(out), nil
+}
+
+// Decode recovers the rows of the SimplePIR hint and answer holding the
+// element from the second layer, then the element from them.
+func (d *Double) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	lo, sc, _, err := doubleLayout(params)
+	if err != nil {
+		return nil, err
+	}
+	sk, ok := sec.(*doubleSecret)
+	if !ok {
+		return nil, pir.ErrSchemeMismatch
+	}
+	hw := lo.digits * N * sc.kappa
+	aw := lo.digits * sc.kappa
+	if len(answer) != 4*(hw+aw+aw*N) {
+		return nil, pir.ErrMalformed
+	}
+	ans := words(answer)
+	h, a, masks := ans[:hw], ans[hw:hw+aw], ans[hw+aw:]
+	hint := words(params.Hint)
+	// masks of the hint's digits come from the offline hint, and those of
+	// the answer's from the answer.
+	hmask := make([]uint32, sc.kappa)
+	amask := make([]uint32, sc.kappa)
+	h1 := make([]uint32, N)
+	digits := make([]uint32, lo.digits)
+	for t := range digits {
+		for x := range h1 {
+			base := (t*N + x) * sc.kappa
+			for u := range hmask {
+				c := base + u
+				hmask[u] = lwe.Dot(hint[c*N:(c+1)*N], sk.s2)
+			}
+			h1[x] = sc.recompose(h[base:], hmask)
+		}
+		for u := range amask {
+			c := t*sc.kappa + u
+			amask[u] = lwe.Dot(masks[c*N:(c+1)*N], sk.s2)
+		}
+		a1 := sc.recompose(a[t*sc.kappa:], amask)
+		digits[t] = lwe.Round(a1-lwe.Dot(h1, sk.s1), lo.logp)
+	}
+	return lwe.Join(digits, int(params.ElementSize), lo.logp), nil
+}
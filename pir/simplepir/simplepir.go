@@ -0,0 +1,397 @@
+// Package simplepir is a pure Go port of the single-server PIR schemes
+// SimplePIR and DoublePIR of Henzinger, Hong, Corrigan-Gibbs, Meiklejohn and
+// Vaikuntanathan, both with an offline phase.
+//
+// The database is laid out as a matrix D of plaintext digits, each element
+// spanning a few rows of one column. SimplePIR queries are LWE encryptions,
+// under a public matrix A expanded from a seed, of the selection vector of
+// the element's column; the answer D times the query is one word per row,
+// and the hint D·A, downloaded once, lets the client strip the masks from
+// the rows holding the element. Answers cost one multiplication per digit
+// of the database, as for FastPIR, but are a column of the matrix long
+// rather than thousands of times the element.
+//
+// DoublePIR answers again, privately, for the rows holding the element: the
+// digits of the SimplePIR answer and hint are queried with a second LWE
+// layer, so the client's hint no longer grows with the database. It does
+// grow with the element size, by several megabytes per digit, so DoublePIR
+// suits small elements such as index slots.
+package simplepir
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/lwe"
+)
+
+// N is the LWE secret dimension.
+const N = 1024
+
+// ID is the scheme identifier advertised to peers.
+const ID = "simplepir-lwe1024/v1"
+
+// Scheme implements pir.Hinter with SimplePIR.
+type Scheme struct {
+	workers int32
+}
+
+var (
+	_ pir.Hinter        = (*Scheme)(nil)
+	_ pir.Updater       = (*Scheme)(nil)
+	_ pir.Persister     = (*Scheme)(nil)
+	_ pir.Parallel      = (*Scheme)(nil)
+	_ pir.StreamDecoder = (*Scheme)(nil)
+	_ pir.CostModel     = (*Scheme)(nil)
+)
+
+// New returns the SimplePIR scheme.
+func New() *Scheme {
+	return &Scheme{}
+}
+
+// layout is the shape of the database matrix: l = k*digits rows, m columns,
+// with k elements per column of digits rows each.
+type layout struct {
+	logp   int
+	digits int
+	k      int
+	m      int
+}
+
+func (lo layout) rows() int {
+	return lo.k * lo.digits
+}
+
+// newLayout shapes a database of n elements of size bytes into a roughly
+// square matrix.
+func newLayout(n, size int) layout {
+	// the inner products run over at most n columns.
+	logp := lwe.PlaintextBits(n)
+	lo := layout{logp: logp, digits: lwe.NumDigits(size, logp), k: 1}
+	if n > 0 {
+		lo.k = int(math.Ceil(math.Sqrt(float64(n) / float64(lo.digits))))
+	}
+	lo.m = (n + lo.k - 1) / lo.k
+	return lo
+}
+
+// position returns the column holding element j and its first row.
+func (lo layout) position(j uint64) (int, int) {
+	return int(j / uint64(lo.k)), int(j%uint64(lo.k)) * lo.digits
+}
+
+type state struct {
+	layout
+	seed []byte
+	// db holds the matrix row by row.
+	db []uint32
+}
+
+type secret struct {
+	s      []uint32
+	column int
+	row    int
+}
+
+func (s *Scheme) ID() string {
+	return ID
+}
+
+// AnswerCost is about half a nanosecond per byte of the database, for one
+// pass of the matrix-vector product over it.
+func (s *Scheme) AnswerCost(params pir.Params) time.Duration {
+	return time.Duration(params.NumElements*params.ElementSize) / 2
+}
+
+// SetWorkers splits each answer across n goroutines, each over a share of
+// the rows.
+func (s *Scheme) SetWorkers(n int) {
+	atomic.StoreInt32(&s.workers, int32(n))
+}
+
+// encode lays db out as a matrix.
+func encode(db pir.Database, name string) (*state, error) {
+	if db.ElementSize <= 0 {
+		return nil, fmt.Errorf("%s: invalid element size %d", name, db.ElementSize)
+	}
+	seed, err := lwe.NewSeed()
+	if err != nil {
+		return nil, err
+	}
+	st := &state{layout: newLayout(len(db.Elements), db.ElementSize), seed: seed}
+	st.db = make([]uint32, st.rows()*st.m)
+	digits := make([]uint32, st.digits)
+	for j, e := range db.Elements {
+		if len(e) > db.ElementSize {
+			return nil, fmt.Errorf("%s: element %d is %d bytes, larger than %d", name, j, len(e), db.ElementSize)
+		}
+		st.set(uint64(j), e, digits)
+	}
+	return st, nil
+}
+
+// set writes the digits of element j, using digits as scratch space.
+func (st *state) set(j uint64, e []byte, digits []uint32) {
+	lwe.Split(digits, e, st.logp)
+	col, row := st.position(j)
+	for t, d := range digits {
+		st.db[(row+t)*st.m+col] = d
+	}
+}
+
+// encodeExtra records the layout and the seeds of the public matrices.
+func encodeExtra(lo layout, seeds ...[]byte) []byte {
+	out := []byte{byte(lo.logp)}
+	for _, seed := range seeds {
+		out = append(out, seed...)
+	}
+	var buf [binary.MaxVarintLen64]byte
+	return append(out, buf[:binary.PutUvarint(buf[:], uint64(lo.k))]...)
+}
+
+// decodeExtra parses the layout and seeds recorded by encodeExtra, checking
+// that they fit the database described by params.
+func decodeExtra(params pir.Params, seeds int) (layout, [][]byte, error) {
+	extra := params.Extra
+	if len(extra) < 1+seeds*lwe.SeedSize || extra[0] == 0 || extra[0] > 8 {
+		return layout{}, nil, pir.ErrMalformed
+	}
+	lo := layout{logp: int(extra[0])}
+	out := make([][]byte, seeds)
+	for i := range out {
+		out[i] = extra[1+i*lwe.SeedSize : 1+(i+1)*lwe.SeedSize]
+	}
+	k, n := binary.Uvarint(extra[1+seeds*lwe.SeedSize:])
+	if n <= 0 || 1+seeds*lwe.SeedSize+n != len(extra) || k == 0 || (k > params.NumElements && k > 1) {
+		return layout{}, nil, pir.ErrMalformed
+	}
+	lo.k = int(k)
+	lo.digits = lwe.NumDigits(int(params.ElementSize), lo.logp)
+	lo.m = int((params.NumElements + k - 1) / k)
+	return lo, out, nil
+}
+
+// Setup lays the elements out as a matrix, under a fresh public matrix.
+func (s *Scheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	st, err := encode(db, "simplepir")
+	if err != nil {
+		return nil, err
+	}
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      ID,
+			NumElements: uint64(len(db.Elements)),
+			ElementSize: uint64(db.ElementSize),
+			Extra:       encodeExtra(st.layout, st.seed),
+		},
+		State: st,
+	}, nil
+}
+
+// Update rewrites the digits of the changed elements, in a copy of the
+// database. The hint must be computed again.
+func (s *Scheme) Update(db *pir.Encoded, changes map[uint64][]byte) (*pir.Encoded, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	next := &state{layout: st.layout, seed: st.seed, db: append([]uint32(nil), st.db...)}
+	digits := make([]uint32, st.digits)
+	for j, e := range changes {
+		if j >= db.Params.NumElements {
+			return nil, pir.ErrIndexOutOfRange
+		}
+		if uint64(len(e)) > db.Params.ElementSize {
+			return nil, fmt.Errorf("simplepir: element %d is %d bytes, larger than %d", j, len(e), db.Params.ElementSize)
+		}
+		next.set(j, e, digits)
+	}
+	return &pir.Encoded{Params: db.Params, State: next}, nil
+}
+
+// MarshalState returns the database matrix.
+func (s *Scheme) MarshalState(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return putWords(st.db), nil
+}
+
+// UnmarshalState restores the matrix saved by MarshalState, under the
+// public matrix recorded in params, reading it from data in place where
+// data is aligned.
+func (s *Scheme) UnmarshalState(params pir.Params, data []byte) (interface{}, error) {
+	if params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	lo, seeds, err := decodeExtra(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 4*lo.rows()*lo.m {
+		return nil, pir.ErrMalformed
+	}
+	return &state{layout: lo, seed: seeds[0], db: lwe.View(data)}, nil
+}
+
+// hint computes D·A, row by row, for the public matrix of m rows expanded
+// from seed.
+func (st *state) hint() []uint32 {
+	h := make([]uint32, st.rows()*N)
+	prg := lwe.NewPRG(st.seed)
+	a := make([]uint32, N)
+	for c := 0; c < st.m; c++ {
+		prg.Fill(a)
+		for r := 0; r < st.rows(); r++ {
+			d := st.db[r*st.m+c]
+			if d == 0 {
+				continue
+			}
+			lwe.MulAdd(h[r*N:(r+1)*N], a, d)
+		}
+	}
+	return h
+}
+
+// Hint returns D·A, one row of N words per row of the database.
+func (s *Scheme) Hint(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return putWords(st.hint()), nil
+}
+
+// encrypt returns an LWE encryption under a fresh secret of the selection
+// vector of index, of length rows, under the public matrix expanded from
+// seed, for plaintexts of logp bits.
+func encrypt(seed []byte, rows, index, logp int) ([]uint32, []uint32, error) {
+	sk, err := lwe.Secret(N)
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := lwe.Errors(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	prg := lwe.NewPRG(seed)
+	a := make([]uint32, N)
+	c := make([]uint32, rows)
+	for j := range c {
+		prg.Fill(a)
+		c[j] = lwe.Dot(a, sk) + e[j]
+		if j == index {
+			c[j] += lwe.Delta(logp)
+		}
+	}
+	return c, sk, nil
+}
+
+// Query encrypts the selection vector of the column holding index: one word
+// per column.
+func (s *Scheme) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	if params.Scheme != ID {
+		return nil, nil, pir.ErrSchemeMismatch
+	}
+	lo, seeds, err := decodeExtra(params, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(params.Hint) != 4*lo.rows()*N {
+		return nil, nil, pir.ErrNoHint
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	col, row := lo.position(index)
+	c, sk, err := encrypt(seeds[0], lo.m, col, lo.logp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return putWords(c), &secret{s: sk, column: col, row: row}, nil
+}
+
+// answer computes D·query.
+func (st *state) answer(query []uint32, workers int) []uint32 {
+	out := make([]uint32, st.rows())
+	pir.Split(workers, len(out), func(_, lo, hi int) {
+		for r := lo; r < hi; r++ {
+			out[r] = lwe.Dot(st.db[r*st.m:(r+1)*st.m], query)
+		}
+	})
+	return out
+}
+
+// Answer returns D times the query, one word per row.
+func (s *Scheme) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	if len(query) != 4*st.m {
+		return nil, pir.ErrMalformed
+	}
+	return putWords(st.answer(words(query), int(atomic.LoadInt32(&s.workers)))), nil
+}
+
+// Decode strips the mask from the rows holding the element, with the hint.
+func (s *Scheme) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	d, err := s.NewDecoder(params, sec)
+	if err != nil {
+		return nil, err
+	}
+	return pir.DecodeAll(d, answer)
+}
+
+// NewDecoder decodes answers a row at a time, keeping the rows holding the
+// element and passing over the rest.
+func (s *Scheme) NewDecoder(params pir.Params, sec pir.Secret) (pir.Decoder, error) {
+	if params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	lo, _, err := decodeExtra(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	sk, ok := sec.(*secret)
+	if !ok {
+		return nil, pir.ErrSchemeMismatch
+	}
+	if len(params.Hint) != 4*lo.rows()*N {
+		return nil, pir.ErrNoHint
+	}
+	hint := params.Hint
+	digits := make([]uint32, lo.digits)
+	return pir.UnitDecoder(lo.rows(), 4, func(r int, unit []byte) error {
+		if t := r - sk.row; t >= 0 && t < len(digits) {
+			h := words(hint[4*r*N : 4*(r+1)*N])
+			digits[t] = lwe.Round(binary.LittleEndian.Uint32(unit)-lwe.Dot(h, sk.s), lo.logp)
+		}
+		return nil
+	}, func() []byte {
+		return lwe.Join(digits, int(params.ElementSize), lo.logp)
+	}), nil
+}
+
+// words reads little-endian words from b, whose length is a multiple of 4.
+func words(b []byte) []uint32 {
+	out := make([]uint32, len(b)/4)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return out
+}
+
+func putWords(w []uint32) []byte {
+	out := make([]byte, 4*len(w))
+	for i, v := range w {
+		binary.LittleEndian.PutUint32(out[4*i:], v)
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package simplepir_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/simplepir"
+)
+
+func TestRoundtrip(t *testing.T) {
+	pirtest.Roundtrip(t, simplepir.New())
+}
+
+func TestUpdate(t *testing.T) {
+	pirtest.Update(t, simplepir.New())
+}
+
+func TestPersist(t *testing.T) {
+	pirtest.Persist(t, simplepir.New())
+	pirtest.Persist(t, simplepir.NewDouble())
+}
+
+func TestParallel(t *testing.T) {
+	pirtest.Parallel(t, simplepir.New())
+	pirtest.Parallel(t, simplepir.NewDouble())
+}
+
+func TestStreamDecode(t *testing.T) {
+	pirtest.StreamDecode(t, simplepir.New())
+}
+
+// TestLayout retrieves from databases laid out over several columns, with
+// elements of one and many digits.
+func TestLayout(t *testing.T) {
+	scheme := simplepir.New()
+	for _, tc := range []struct{ n, size int }{{1000, 3}, {150, 500}, {1, 10}} {
+		db := pirtest.RandomDatabase(tc.n, tc.size)
+		enc, err := scheme.Setup(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := scheme.Query(enc.Params, 0); !errors.Is(err, pir.ErrNoHint) {
+			t.Fatalf("query without the hint: %v", err)
+		}
+		if err := pir.SetHint(scheme, enc); err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range []int{0, tc.n / 2, tc.n - 1} {
+			if got := pirtest.Get(t, scheme, enc, uint64(i)); !bytes.Equal(got, db.Elements[i]) {
+				t.Fatalf("n=%d size=%d element %d: got %x, expected %x", tc.n, tc.size, i, got, db.Elements[i])
+			}
+		}
+	}
+}
+
+// TestDouble retrieves small elements through both layers.
+func TestDouble(t *testing.T) {
+	scheme := simplepir.NewDouble()
+	for _, tc := range []struct{ n, size int }{{300, 2}, {7, 1}} {
+		db := pirtest.RandomDatabase(tc.n, tc.size)
+		enc, err := scheme.Setup(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := scheme.Query(enc.Params, 0); !errors.Is(err, pir.ErrNoHint) {
+			t.Fatalf("query without the hint: %v", err)
+		}
+		if err := pir.SetHint(scheme, enc); err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range []int{0, tc.n / 2, tc.n - 1} {
+			if got := pirtest.Get(t, scheme, enc, uint64(i)); !bytes.Equal(got, db.Elements[i]) {
+				t.Fatalf("n=%d element %d: got %x, expected %x", tc.n, i, got, db.Elements[i])
+			}
+		}
+		if _, _, err := scheme.Query(enc.Params, uint64(tc.n)); err == nil {
+			t.Fatal("query past the end of the database should fail")
+		}
+		if _, err := scheme.Answer(enc, []byte("garbage")); err == nil {
+			t.Fatal("malformed query should be rejected")
+		}
+	}
+}
+
+func FuzzAnswer(f *testing.F) {
+	pirtest.FuzzAnswer(f, simplepir.New())
+}
+
+func BenchmarkAnswer(b *testing.B) {
+	pirtest.BenchmarkAnswer(b, simplepir.New(), 32)
+}
+
+func BenchmarkScheme(b *testing.B) {
+	pirtest.Benchmark(b, simplepir.New())
+}
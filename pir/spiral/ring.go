@@ -0,0 +1,172 @@
+package spiral
+
+import (
+	"github.com/willscott/go-selfish-bitswap-client/pir/lwe"
+)
+
+const (
+	// D is the degree of the ring Z_Q[X]/(X^D+1).
+	D = 2048
+	// Q is the ciphertext modulus, an NTT friendly prime: 2D divides Q-1.
+	Q = 1<<32 - 1<<20 + 1
+	// logD is log2(D).
+	logD = 11
+)
+
+// poly is a polynomial of the ring, with coefficients in [0, Q). Whether it
+// holds coefficients or NTT evaluations is up to the caller.
+type poly []uint32
+
+var (
+	// psiRev holds the powers of a primitive 2D-th root of unity in
+	// bit-reversed order, and psiInvRev those of its inverse.
+	psiRev, psiInvRev [D]uint32
+	dInv              uint32
+)
+
+func init() {
+	// any element whose (Q-1)/2D-th power has order exactly 2D will do.
+	var psi uint32
+	for g := uint32(2); ; g++ {
+		psi = pow(g, (Q-1)/(2*D))
+		if pow(psi, D) == Q-1 {
+			break
+		}
+	}
+	psiInv := pow(psi, Q-2)
+	for i := 0; i < D; i++ {
+		r := reverse(uint32(i))
+		psiRev[i] = pow(psi, r)
+		psiInvRev[i] = pow(psiInv, r)
+	}
+	dInv = pow(D, Q-2)
+}
+
+func reverse(i uint32) uint32 {
+	var r uint32
+	for b := 0; b < logD; b++ {
+		r = r<<1 | i&1
+		i >>= 1
+	}
+	return r
+}
+
+func mul(a, b uint32) uint32 {
+	return uint32(uint64(a) * uint64(b) % Q)
+}
+
+func add(a, b uint32) uint32 {
+	s := uint64(a) + uint64(b)
+	if s >= Q {
+		s -= Q
+	}
+	return uint32(s)
+}
+
+func sub(a, b uint32) uint32 {
+	if a >= b {
+		return a - b
+	}
+	return uint32(uint64(a) + Q - uint64(b))
+}
+
+func pow(a, e uint32) uint32 {
+	r := uint32(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = mul(r, a)
+		}
+		a = mul(a, a)
+	}
+	return r
+}
+
+// signed maps a small two's complement value, as sampled by the lwe package,
+// into [0, Q).
+func signed(v uint32) uint32 {
+	if int32(v) < 0 {
+		return uint32(Q - uint64(-int32(v)))
+	}
+	return v
+}
+
+// ntt transforms p in place from coefficients to evaluations, in bit-reversed
+// order, so that products in the ring become pointwise.
+func (p poly) ntt() {
+	t := D
+	for m := 1; m < D; m <<= 1 {
+		t >>= 1
+		for i := 0; i < m; i++ {
+			s := psiRev[m+i]
+			j1 := 2 * i * t
+			for j := j1; j < j1+t; j++ {
+				u, v := p[j], mul(p[j+t], s)
+				p[j], p[j+t] = add(u, v), sub(u, v)
+			}
+		}
+	}
+}
+
+// intt is the inverse of ntt.
+func (p poly) intt() {
+	t := 1
+	for m := D; m > 1; m >>= 1 {
+		h := m / 2
+		j1 := 0
+		for i := 0; i < h; i++ {
+			s := psiInvRev[h+i]
+			for j := j1; j < j1+t; j++ {
+				u, v := p[j], p[j+t]
+				p[j], p[j+t] = add(u, v), mul(sub(u, v), s)
+			}
+			j1 += 2 * t
+		}
+		t <<= 1
+	}
+	for i := range p {
+		p[i] = mul(p[i], dInv)
+	}
+}
+
+// mulAcc adds the pointwise product of a and b, both evaluations, to acc.
+// acc is reduced lazily; see reduce.
+func mulAcc(acc []uint64, a, b poly) {
+	for i := range acc {
+		acc[i] += uint64(a[i]) * uint64(b[i]) % Q
+	}
+}
+
+// reduce writes acc modulo Q to p and clears acc.
+func reduce(p poly, acc []uint64) {
+	for i := range acc {
+		p[i] = uint32(acc[i] % Q)
+		acc[i] = 0
+	}
+}
+
+// uniform fills p with uniform elements of [0, Q) expanded from prg. Uniform
+// polynomials are uniform in either representation, so p may be taken as
+// evaluations directly.
+func uniform(p poly, prg *lwe.PRG) {
+	prg.Fill(p)
+	for i := range p {
+		for p[i] >= Q {
+			prg.Fill(p[i : i+1])
+		}
+	}
+}
+
+// small returns a polynomial with coefficients drawn from the lwe error
+// distribution, as evaluations.
+func small() (poly, error) {
+	e, err := lwe.Errors(D)
+	if err != nil {
+		return nil, err
+	}
+	p := poly(e)
+	for i := range p {
+		p[i] = signed(p[i])
+	}
+	p.ntt()
+	return p, nil
+}
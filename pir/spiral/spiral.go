@@ -0,0 +1,524 @@
+// Package spiral is a pure Go single-server PIR scheme following the
+// structure of Spiral: records of the database are packed into ring
+// plaintexts and laid out as a grid. The query selects a row of the grid
+// with RLWE ciphertexts, and the column with one RGSW ciphertext per bit of
+// its index, which the server folds the selected row with.
+//
+// The answer is a single RLWE ciphertext per plaintext of a record, about
+// eight times the size of the element for large elements, where FastPIR
+// answers are several thousand times larger. Spiral composes the ciphertexts
+// over larger moduli and compresses both queries and answers; this port
+// keeps one 32 bit modulus and expands the public half of every ciphertext
+// from a seed instead.
+package spiral
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/lwe"
+)
+
+// ID is the scheme identifier advertised to peers.
+const ID = "spiral-rlwe2048/v1"
+
+const (
+	// logP is the number of bits of plaintext held by each coefficient.
+	logP = 8
+	// delta scales plaintext coefficients into ciphertexts.
+	delta = Q >> logP
+	// gadgetBits and gadgetLen describe the gadget decomposition of
+	// coefficients used by RGSW ciphertexts: gadgetLen digits of gadgetBits.
+	gadgetBits = 8
+	gadgetLen  = 32 / gadgetBits
+	// maxRows bounds the rows of the grid, which bounds the noise of the
+	// first dimension. Larger databases get more columns.
+	maxRows = 64
+)
+
+// Scheme implements pir.Scheme.
+type Scheme struct {
+	workers int32
+}
+
+var (
+	_ pir.Scheme        = (*Scheme)(nil)
+	_ pir.Updater       = (*Scheme)(nil)
+	_ pir.Persister     = (*Scheme)(nil)
+	_ pir.Parallel      = (*Scheme)(nil)
+	_ pir.StreamDecoder = (*Scheme)(nil)
+	_ pir.CostModel     = (*Scheme)(nil)
+)
+
+// New returns the Spiral scheme.
+func New() *Scheme {
+	return &Scheme{}
+}
+
+// layout describes how elements are packed into records and records into
+// the grid. Records are numbered down the rows of each column in turn.
+type layout struct {
+	// digits is the number of coefficients holding an element.
+	digits int
+	// perRecord elements share a record, if they fit in one plaintext.
+	perRecord int
+	// polys is the number of plaintexts per record.
+	polys int
+	rows  int
+	// steps is the number of bits of the column index: there are 1<<steps
+	// columns.
+	steps int
+}
+
+func newLayout(n uint64, size int) layout {
+	l := layout{digits: lwe.NumDigits(size, logP), perRecord: 1, polys: 1}
+	if l.digits <= D {
+		l.perRecord = D / l.digits
+	} else {
+		l.polys = (l.digits + D - 1) / D
+	}
+	records := (n + uint64(l.perRecord) - 1) / uint64(l.perRecord)
+	if records == 0 {
+		records = 1
+	}
+	for (records+1<<l.steps-1)>>l.steps > maxRows {
+		l.steps++
+	}
+	l.rows = int((records + 1<<l.steps - 1) >> l.steps)
+	return l
+}
+
+func (l layout) extra() []byte {
+	e := []byte{logP, byte(l.steps), 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(e[2:], uint32(l.rows))
+	return e
+}
+
+func layoutOf(params pir.Params) (layout, error) {
+	if params.Scheme != ID {
+		return layout{}, pir.ErrSchemeMismatch
+	}
+	if len(params.Extra) != 6 || params.Extra[0] != logP || params.Extra[1] > 32 || params.ElementSize == 0 {
+		return layout{}, pir.ErrMalformed
+	}
+	l := newLayout(0, int(params.ElementSize))
+	l.steps = int(params.Extra[1])
+	l.rows = int(binary.LittleEndian.Uint32(params.Extra[2:]))
+	if l.rows == 0 || l.rows > maxRows || uint64(l.rows)<<l.steps*uint64(l.perRecord) < params.NumElements {
+		return layout{}, pir.ErrMalformed
+	}
+	return l, nil
+}
+
+// querySize is the length of queries: the seed, then the body of one RLWE
+// ciphertext per row and of 2*gadgetLen per RGSW ciphertext.
+func (l layout) querySize() int {
+	return lwe.SeedSize + 4*D*(l.rows+2*gadgetLen*l.steps)
+}
+
+func (l layout) answerSize() int {
+	return 4 * 2 * D * l.polys
+}
+
+type state struct {
+	layout
+	records int
+	// db holds the plaintexts of each record as evaluations, records
+	// contiguously.
+	db []uint32
+}
+
+type secret struct {
+	// s is the RLWE secret, as evaluations.
+	s    poly
+	slot int
+}
+
+// ciphertext is an RLWE ciphertext of m, with b = a*s + e + delta*m.
+type ciphertext struct {
+	a, b poly
+}
+
+func (s *Scheme) ID() string {
+	return ID
+}
+
+// AnswerCost is about 25ns per byte of the database, for the products of
+// its polynomials with those of the query.
+func (s *Scheme) AnswerCost(params pir.Params) time.Duration {
+	return time.Duration(params.NumElements*params.ElementSize) * 25
+}
+
+// SetWorkers splits each answer across n goroutines, each computing a share
+// of the plaintexts of every column, and of every fold.
+func (s *Scheme) SetWorkers(n int) {
+	atomic.StoreInt32(&s.workers, int32(n))
+}
+
+func (s *Scheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	if db.ElementSize <= 0 {
+		return nil, fmt.Errorf("spiral: invalid element size %d", db.ElementSize)
+	}
+	l := newLayout(uint64(len(db.Elements)), db.ElementSize)
+	records := (len(db.Elements) + l.perRecord - 1) / l.perRecord
+	st := &state{layout: l, records: records, db: make([]uint32, records*l.polys*D)}
+	for j, e := range db.Elements {
+		if len(e) > db.ElementSize {
+			return nil, fmt.Errorf("spiral: element %d is %d bytes, larger than %d", j, len(e), db.ElementSize)
+		}
+		start := (j/l.perRecord)*l.polys*D + (j%l.perRecord)*l.digits
+		lwe.Split(st.db[start:start+l.digits], e, logP)
+	}
+	for i := 0; i < len(st.db); i += D {
+		poly(st.db[i : i+D]).ntt()
+	}
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      ID,
+			NumElements: uint64(len(db.Elements)),
+			ElementSize: uint64(db.ElementSize),
+			Extra:       l.extra(),
+		},
+		State: st,
+	}, nil
+}
+
+// MarshalState returns the plaintexts of the records, as evaluations, so
+// loading them skips their transforms.
+func (s *Scheme) MarshalState(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return lwe.PutWords(st.db), nil
+}
+
+// UnmarshalState restores the plaintexts saved by MarshalState, answering
+// from data in place where it is aligned.
+func (s *Scheme) UnmarshalState(params pir.Params, data []byte) (interface{}, error) {
+	l, err := layoutOf(params)
+	if err != nil {
+		return nil, err
+	}
+	records := (params.NumElements + uint64(l.perRecord) - 1) / uint64(l.perRecord)
+	if uint64(len(data)) != 4*records*uint64(l.polys*D) {
+		return nil, pir.ErrMalformed
+	}
+	return &state{layout: l, records: int(records), db: lwe.View(data)}, nil
+}
+
+// Update re-encodes, in a copy of the database, only the plaintexts holding
+// changed elements: they are taken back to coefficients, the elements split
+// into them again, and transformed anew.
+func (s *Scheme) Update(db *pir.Encoded, changes map[uint64][]byte) (*pir.Encoded, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	l := st.layout
+	next := &state{layout: l, records: st.records, db: append([]uint32(nil), st.db...)}
+	starts := make(map[uint64]int, len(changes))
+	touched := make(map[int]bool)
+	for j, e := range changes {
+		if j >= db.Params.NumElements {
+			return nil, pir.ErrIndexOutOfRange
+		}
+		if uint64(len(e)) > db.Params.ElementSize {
+			return nil, fmt.Errorf("spiral: element %d is %d bytes, larger than %d", j, len(e), db.Params.ElementSize)
+		}
+		start := int(j)/l.perRecord*l.polys*D + int(j)%l.perRecord*l.digits
+		starts[j] = start
+		for p := start / D; p <= (start+l.digits-1)/D; p++ {
+			touched[p] = true
+		}
+	}
+	for p := range touched {
+		poly(next.db[p*D : (p+1)*D]).intt()
+	}
+	for j, start := range starts {
+		lwe.Split(next.db[start:start+l.digits], changes[j], logP)
+	}
+	for p := range touched {
+		poly(next.db[p*D : (p+1)*D]).ntt()
+	}
+	return &pir.Encoded{Params: db.Params, State: next}, nil
+}
+
+// encrypt returns the body of an RLWE encryption of zero under s with public
+// part a, all as evaluations.
+func encrypt(a, s poly) (poly, error) {
+	b, err := small()
+	if err != nil {
+		return nil, err
+	}
+	for i := range b {
+		b[i] = add(b[i], mul(a[i], s[i]))
+	}
+	return b, nil
+}
+
+// Query encrypts a selection of the row holding index under a fresh secret,
+// and each bit of its column, least significant first, as RGSW ciphertexts.
+// Only the bodies of the ciphertexts are sent; their public parts are
+// expanded from the seed, in the same order.
+func (s *Scheme) Query(params pir.Params, index uint64) ([]byte, pir.Secret, error) {
+	l, err := layoutOf(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	record := int(index) / l.perRecord
+	row, col := record%l.rows, record/l.rows
+	seed, err := lwe.NewSeed()
+	if err != nil {
+		return nil, nil, err
+	}
+	sk, err := small()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q := make([]byte, l.querySize())
+	copy(q, seed)
+	off := lwe.SeedSize
+	prg := lwe.NewPRG(seed)
+	a := make(poly, D)
+	next := func(m func(b poly)) error {
+		uniform(a, prg)
+		b, err := encrypt(a, sk)
+		if err != nil {
+			return err
+		}
+		m(b)
+		putPoly(q[off:], b)
+		off += 4 * D
+		return nil
+	}
+	for r := 0; r < l.rows; r++ {
+		err := next(func(b poly) {
+			if r == row {
+				for i := range b {
+					b[i] = add(b[i], delta)
+				}
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for step := 0; step < l.steps; step++ {
+		bit := col>>step&1 == 1
+		for g := 0; g < 2*gadgetLen; g++ {
+			err := next(func(b poly) {
+				if !bit {
+					return
+				}
+				gi := uint32(1) << (gadgetBits * (g % gadgetLen))
+				for i := range b {
+					// the rows decomposing a subtract bit*g*s from the
+					// phase, the rows decomposing b add bit*g.
+					if g < gadgetLen {
+						b[i] = sub(b[i], mul(gi, sk[i]))
+					} else {
+						b[i] = add(b[i], gi)
+					}
+				}
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return q, &secret{s: sk, slot: int(index) % l.perRecord}, nil
+}
+
+// Answer selects the queried row of every column, then folds the columns
+// together by the bits of the queried column.
+func (s *Scheme) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	l := st.layout
+	if len(query) != l.querySize() {
+		return nil, pir.ErrMalformed
+	}
+	prg := lwe.NewPRG(query[:lwe.SeedSize])
+	query = query[lwe.SeedSize:]
+	next := func() (ciphertext, error) {
+		ct := ciphertext{a: make(poly, D), b: make(poly, D)}
+		uniform(ct.a, prg)
+		if !getPoly(ct.b, query) {
+			return ct, pir.ErrMalformed
+		}
+		query = query[4*D:]
+		return ct, nil
+	}
+	sel := make([]ciphertext, l.rows)
+	for r := range sel {
+		ct, err := next()
+		if err != nil {
+			return nil, err
+		}
+		sel[r] = ct
+	}
+	gsw := make([][2 * gadgetLen]ciphertext, l.steps)
+	for step := range gsw {
+		for g := range gsw[step] {
+			ct, err := next()
+			if err != nil {
+				return nil, err
+			}
+			gsw[step][g] = ct
+		}
+	}
+
+	workers := int(atomic.LoadInt32(&s.workers))
+	cols := make([][]ciphertext, 1<<l.steps)
+	for c := range cols {
+		cols[c] = make([]ciphertext, l.polys)
+	}
+	// each column's plaintexts are computed apart, so the workers take a
+	// share of them, each with its own accumulators.
+	pir.Split(workers, len(cols)*l.polys, func(_, lo, hi int) {
+		acc := [2][]uint64{make([]uint64, D), make([]uint64, D)}
+		for i := lo; i < hi; i++ {
+			c, k := i/l.polys, i%l.polys
+			for r := 0; r < l.rows && c*l.rows+r < st.records; r++ {
+				pt := poly(st.db[((c*l.rows+r)*l.polys+k)*D:][:D])
+				mulAcc(acc[0], sel[r].a, pt)
+				mulAcc(acc[1], sel[r].b, pt)
+			}
+			ct := ciphertext{a: make(poly, D), b: make(poly, D)}
+			reduce(ct.a, acc[0])
+			reduce(ct.b, acc[1])
+			ct.a.intt()
+			ct.b.intt()
+			cols[c][k] = ct
+		}
+	})
+	for step := 0; step < l.steps; step++ {
+		next := make([][]ciphertext, len(cols)/2)
+		for c := range next {
+			next[c] = make([]ciphertext, l.polys)
+		}
+		pir.Split(workers, len(next)*l.polys, func(_, lo, hi int) {
+			acc := [2][]uint64{make([]uint64, D), make([]uint64, D)}
+			for i := lo; i < hi; i++ {
+				c, k := i/l.polys, i%l.polys
+				next[c][k] = fold(&gsw[step], cols[2*c][k], cols[2*c+1][k], acc)
+			}
+		})
+		cols = next
+	}
+
+	out := make([]byte, l.answerSize())
+	for k, ct := range cols[0] {
+		putPoly(out[8*D*k:], ct.a)
+		putPoly(out[8*D*k+4*D:], ct.b)
+	}
+	return out, nil
+}
+
+// fold returns x0 if gsw encrypts 0, and x1 if it encrypts 1, computing
+// x0 + gsw*(x1-x0) with the external product. x0 and x1 are coefficients.
+func fold(gsw *[2 * gadgetLen]ciphertext, x0, x1 ciphertext, acc [2][]uint64) ciphertext {
+	diff := [2]poly{make(poly, D), make(poly, D)}
+	for i := 0; i < D; i++ {
+		diff[0][i] = sub(x1.a[i], x0.a[i])
+		diff[1][i] = sub(x1.b[i], x0.b[i])
+	}
+	u := make(poly, D)
+	for part, p := range diff {
+		for g := 0; g < gadgetLen; g++ {
+			for i, v := range p {
+				u[i] = v >> (gadgetBits * g) & (1<<gadgetBits - 1)
+			}
+			u.ntt()
+			row := gsw[part*gadgetLen+g]
+			mulAcc(acc[0], u, row.a)
+			mulAcc(acc[1], u, row.b)
+		}
+	}
+	out := ciphertext{a: diff[0], b: diff[1]}
+	reduce(out.a, acc[0])
+	reduce(out.b, acc[1])
+	out.a.intt()
+	out.b.intt()
+	for i := 0; i < D; i++ {
+		out.a[i] = add(out.a[i], x0.a[i])
+		out.b[i] = add(out.b[i], x0.b[i])
+	}
+	return out
+}
+
+func (s *Scheme) Decode(params pir.Params, sec pir.Secret, answer []byte) ([]byte, error) {
+	d, err := s.NewDecoder(params, sec)
+	if err != nil {
+		return nil, err
+	}
+	return pir.DecodeAll(d, answer)
+}
+
+// NewDecoder decrypts answers a ciphertext at a time, keeping only the
+// digits of the queried element.
+func (s *Scheme) NewDecoder(params pir.Params, sec pir.Secret) (pir.Decoder, error) {
+	l, err := layoutOf(params)
+	if err != nil {
+		return nil, err
+	}
+	sk, ok := sec.(*secret)
+	if !ok {
+		return nil, pir.ErrSchemeMismatch
+	}
+	start := sk.slot * l.digits
+	digits := make([]uint32, l.digits)
+	a, b := make(poly, D), make(poly, D)
+	return pir.UnitDecoder(l.polys, 8*D, func(k int, unit []byte) error {
+		// only the plaintexts holding the element's digits are decrypted.
+		lo, hi := k*D, (k+1)*D
+		if hi <= start || lo >= start+l.digits {
+			return nil
+		}
+		if !getPoly(a, unit) || !getPoly(b, unit[4*D:]) {
+			return pir.ErrMalformed
+		}
+		a.ntt()
+		for i := range a {
+			a[i] = mul(a[i], sk.s[i])
+		}
+		a.intt()
+		for i := range b {
+			if j := lo + i - start; j >= 0 && j < l.digits {
+				phase := sub(b[i], a[i])
+				digits[j] = uint32((uint64(phase)<<logP+Q/2)/Q) & (1<<logP - 1)
+			}
+		}
+		return nil
+	}, func() []byte {
+		return lwe.Join(digits, int(params.ElementSize), logP)
+	}), nil
+}
+
+func putPoly(dst []byte, p poly) {
+	for i, v := range p {
+		binary.LittleEndian.PutUint32(dst[4*i:], v)
+	}
+}
+
+// getPoly reads p from src, reporting whether all of its coefficients were
+// in range.
+func getPoly(p poly, src []byte) bool {
+	for i := range p {
+		p[i] = binary.LittleEndian.Uint32(src[4*i:])
+		if p[i] >= Q {
+			return false
+		}
+	}
+	return true
+}
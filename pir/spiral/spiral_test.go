@@ -0,0 +1,96 @@
+package spiral_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+)
+
+func TestRoundtrip(t *testing.T) {
+	pirtest.Roundtrip(t, spiral.New())
+}
+
+func TestUpdate(t *testing.T) {
+	pirtest.Update(t, spiral.New())
+}
+
+func TestPersist(t *testing.T) {
+	pirtest.Persist(t, spiral.New())
+}
+
+func TestParallel(t *testing.T) {
+	pirtest.Parallel(t, spiral.New())
+}
+
+func TestStreamDecode(t *testing.T) {
+	pirtest.StreamDecode(t, spiral.New())
+}
+
+func FuzzAnswer(f *testing.F) {
+	pirtest.FuzzAnswer(f, spiral.New())
+}
+
+// TestFolding retrieves from databases laid out over several columns, with
+// elements packed several to a plaintext and spread over several plaintexts.
+func TestFolding(t *testing.T) {
+	scheme := spiral.New()
+	for _, tc := range []struct{ n, size int }{{1000, 300}, {150, 5000}} {
+		db := pirtest.RandomDatabase(tc.n, tc.size)
+		enc, err := scheme.Setup(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range []int{0, 1, tc.n / 2, tc.n - 1} {
+			q, sec, err := scheme.Query(enc.Params, uint64(i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ans, err := scheme.Answer(enc, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := scheme.Decode(enc.Params, sec, ans)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, db.Elements[i]) {
+				t.Fatalf("n=%d size=%d: element %d retrieved incorrectly", tc.n, tc.size, i)
+			}
+		}
+	}
+}
+
+func TestSmallerAnswers(t *testing.T) {
+	db := pirtest.RandomDatabase(1000, 1024)
+	answer := func(scheme pir.Scheme) []byte {
+		enc, err := scheme.Setup(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, _, err := scheme.Query(enc.Params, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := scheme.Answer(enc, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	s, f := len(answer(spiral.New())), len(answer(fastpir.New()))
+	if s > 16*db.ElementSize || s >= f {
+		t.Fatalf("spiral answers are %d bytes, fastpir %d, for %d byte elements", s, f, db.ElementSize)
+	}
+}
+
+func BenchmarkAnswer(b *testing.B) {
+	pirtest.BenchmarkAnswer(b, spiral.New(), 32)
+}
+
+func BenchmarkScheme(b *testing.B) {
+	pirtest.Benchmark(b, spiral.New())
+}
@@ -0,0 +1,220 @@
+// Package xorpir is the two-server information-theoretic PIR scheme of Chor,
+// Goldreich, Kushilevitz and Sudan. The client sends each server a subset of
+// the database, chosen uniformly at random for the first and differing only
+// in the queried element for the second; each server answers with the XOR of
+// the elements in its subset, and the XOR of both answers is the element.
+//
+// Each server on its own sees a uniformly random subset, so learns nothing
+// of the query however much it computes, as long as the two do not collude.
+// Answering costs one XOR per element in the subset, which is orders of
+// magnitude cheaper than the single-server schemes; queries are one bit per
+// element, and answers one element long.
+package xorpir
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// ID is the scheme identifier advertised to peers.
+const ID = "xorpir2/v1"
+
+// Scheme implements pir.MultiServer.
+type Scheme struct {
+	workers int32
+}
+
+var (
+	_ pir.MultiServer = (*Scheme)(nil)
+	_ pir.Persister   = (*Scheme)(nil)
+	_ pir.Parallel    = (*Scheme)(nil)
+	_ pir.CostModel   = (*Scheme)(nil)
+)
+
+// New returns the two-server XOR scheme.
+func New() *Scheme {
+	return &Scheme{}
+}
+
+type state struct {
+	// db holds the elements contiguously, padded to the element size.
+	db []byte
+}
+
+func (s *Scheme) ID() string {
+	return ID
+}
+
+// AnswerCost is about half a nanosecond per byte of the database, the half
+// of its elements selected by the query being XORed together.
+func (s *Scheme) AnswerCost(params pir.Params) time.Duration {
+	return time.Duration(params.NumElements*params.ElementSize) / 2
+}
+
+// SetWorkers splits each answer across n goroutines, each over a share of
+// the elements, combining their partial answers.
+func (s *Scheme) SetWorkers(n int) {
+	atomic.StoreInt32(&s.workers, int32(n))
+}
+
+func (s *Scheme) Servers() int {
+	return 2
+}
+
+// Setup lays the elements out contiguously. The parameters carry a digest
+// of the database, so clients can tell that both servers hold the same one.
+func (s *Scheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	if db.ElementSize <= 0 {
+		return nil, fmt.Errorf("xorpir: invalid element size %d", db.ElementSize)
+	}
+	st := &state{db: make([]byte, len(db.Elements)*db.ElementSize)}
+	for j, e := range db.Elements {
+		if len(e) > db.ElementSize {
+			return nil, fmt.Errorf("xorpir: element %d is %d bytes, larger than %d", j, len(e), db.ElementSize)
+		}
+		copy(st.db[j*db.ElementSize:], e)
+	}
+	h := sha256.New()
+	var dims [16]byte
+	binary.BigEndian.PutUint64(dims[:8], uint64(len(db.Elements)))
+	binary.BigEndian.PutUint64(dims[8:], uint64(db.ElementSize))
+	h.Write(dims[:])
+	h.Write(st.db)
+	return &pir.Encoded{
+		Params: pir.Params{
+			Scheme:      ID,
+			NumElements: uint64(len(db.Elements)),
+			ElementSize: uint64(db.ElementSize),
+			Extra:       h.Sum(nil),
+		},
+		State: st,
+	}, nil
+}
+
+// MarshalState returns the elements.
+func (s *Scheme) MarshalState(db *pir.Encoded) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	return st.db, nil
+}
+
+// UnmarshalState answers from the elements saved by MarshalState in place,
+// as they are only read.
+func (s *Scheme) UnmarshalState(params pir.Params, data []byte) (interface{}, error) {
+	if params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	if uint64(len(data)) != params.NumElements*params.ElementSize {
+		return nil, pir.ErrMalformed
+	}
+	return &state{db: data}, nil
+}
+
+// queryLen is the length of a query: one bit per element.
+func queryLen(n uint64) int {
+	return int((n + 7) / 8)
+}
+
+// QueryServers draws a random subset of the elements for the first server,
+// and sends the second the same subset with the element at index toggled.
+func (s *Scheme) QueryServers(params pir.Params, index uint64) ([][]byte, pir.Secret, error) {
+	if params.Scheme != ID {
+		return nil, nil, pir.ErrSchemeMismatch
+	}
+	if index >= params.NumElements {
+		return nil, nil, pir.ErrIndexOutOfRange
+	}
+	a := make([]byte, queryLen(params.NumElements))
+	if _, err := rand.Read(a); err != nil {
+		return nil, nil, err
+	}
+	// bits past the last element are left clear.
+	if r := params.NumElements % 8; r != 0 {
+		a[len(a)-1] &= byte(1)<<r - 1
+	}
+	b := append([]byte(nil), a...)
+	b[index/8] ^= 1 << (index % 8)
+	return [][]byte{a, b}, nil, nil
+}
+
+// Answer XORs together the elements in the subset of query.
+func (s *Scheme) Answer(db *pir.Encoded, query []byte) ([]byte, error) {
+	st, ok := db.State.(*state)
+	if !ok || db.Params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	n := db.Params.NumElements
+	if len(query) != queryLen(n) {
+		return nil, pir.ErrMalformed
+	}
+	if r := n % 8; r != 0 && query[len(query)-1]>>r != 0 {
+		return nil, pir.ErrMalformed
+	}
+	size := int(db.Params.ElementSize)
+	workers := int(atomic.LoadInt32(&s.workers))
+	parts := make([][]byte, workers+1)
+	pir.Split(workers, int(n), func(w, lo, hi int) {
+		out := make([]byte, size)
+		for j := lo; j < hi; j++ {
+			if query[j/8]&(1<<(j%8)) == 0 {
+				continue
+			}
+			xor(out, st.db[j*size:(j+1)*size])
+		}
+		parts[w] = out
+	})
+	out := parts[0]
+	for _, part := range parts[1:] {
+		if part != nil {
+			xor(out, part)
+		}
+	}
+	return out, nil
+}
+
+// xor sets dst to dst XOR src, 8 bytes at a time where it can.
+func xor(dst, src []byte) {
+	i := 0
+	for ; i+8 <= len(dst); i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(dst[i:])^binary.LittleEndian.Uint64(src[i:]))
+	}
+	for ; i < len(dst); i++ {
+		dst[i] ^= src[i]
+	}
+}
+
+// DecodeServers XORs the two answers together.
+func (s *Scheme) DecodeServers(params pir.Params, _ pir.Secret, answers [][]byte) ([]byte, error) {
+	if params.Scheme != ID {
+		return nil, pir.ErrSchemeMismatch
+	}
+	if len(answers) != 2 {
+		return nil, fmt.Errorf("%w: %d answers for 2 servers", pir.ErrMalformed, len(answers))
+	}
+	for _, a := range answers {
+		if uint64(len(a)) != params.ElementSize {
+			return nil, pir.ErrMalformed
+		}
+	}
+	out := append([]byte(nil), answers[0]...)
+	xor(out, answers[1])
+	return out, nil
+}
+
+// Query fails, as a query sent to a single server would reveal the index.
+func (s *Scheme) Query(pir.Params, uint64) ([]byte, pir.Secret, error) {
+	return nil, nil, pir.ErrMultiServer
+}
+
+// Decode fails, as there is no single answer to decode.
+func (s *Scheme) Decode(pir.Params, pir.Secret, []byte) ([]byte, error) {
+	return nil, pir.ErrMultiServer
+}
@@ -0,0 +1,88 @@
+package xorpir_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/xorpir"
+)
+
+func TestRoundtrip(t *testing.T) {
+	scheme := xorpir.New()
+	db := pirtest.RandomDatabase(19, 45)
+	db.Elements[3] = []byte("short")
+	// the two servers encode their copies of the database apart.
+	a, err := scheme.Setup(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := scheme.Setup(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Params.Extra, b.Params.Extra) {
+		t.Fatal("copies of the same database have different digests")
+	}
+	for i, want := range db.Elements {
+		qs, sec, err := scheme.QueryServers(a.Params, uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ansA, err := scheme.Answer(a, qs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		ansB, err := scheme.Answer(b, qs[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := scheme.DecodeServers(a.Params, sec, [][]byte{ansA, ansB})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got[:len(want)], want) || len(got) != db.ElementSize {
+			t.Fatalf("element %d: got %x, expected %x", i, got, want)
+		}
+	}
+	if _, _, err := scheme.QueryServers(a.Params, uint64(len(db.Elements))); err == nil {
+		t.Fatal("query past the end of the database should fail")
+	}
+	if _, err := scheme.Answer(a, []byte("garbage")); err == nil {
+		t.Fatal("malformed query should be rejected")
+	}
+	// 19 elements leave 5 bits of the last byte unused.
+	if _, err := scheme.Answer(a, []byte{0, 0, 0x80}); err == nil {
+		t.Fatal("query for elements past the end should be rejected")
+	}
+	if _, _, err := scheme.Query(a.Params, 0); !errors.Is(err, pir.ErrMultiServer) {
+		t.Fatalf("single-server query: %v", err)
+	}
+
+	db.Elements[0] = []byte("changed")
+	c, err := scheme.Setup(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.Params.Extra, c.Params.Extra) {
+		t.Fatal("different databases have the same digest")
+	}
+}
+
+func TestPersist(t *testing.T) {
+	pirtest.Persist(t, xorpir.New())
+}
+
+func TestParallel(t *testing.T) {
+	pirtest.Parallel(t, xorpir.New())
+}
+
+func FuzzAnswer(f *testing.F) {
+	pirtest.FuzzAnswer(f, xorpir.New())
+}
+
+func BenchmarkAnswer(b *testing.B) {
+	pirtest.BenchmarkAnswer(b, xorpir.New(), 32)
+}
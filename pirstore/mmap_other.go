@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package pirstore
+
+// mapFile reads the file at path, on platforms without memory mapping.
+func mapFile(path string) ([]byte, func() error, error) {
+	return readFile(path)
+}
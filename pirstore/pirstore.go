@@ -0,0 +1,765 @@
+// Package pirstore lays the contents of a blockstore out as a pair of PIR
+// databases: a keyword index resolving CIDs to positions and a block
+// database holding each block, padded, at its position. The index is keyed
+// by the multihash of each CID, as bitswap names blocks, so a block is found
+// whatever the version or codec of the CID it was stored or asked for under.
+//
+// The layout has a fixed geometry, so the public parameters only change when
+// the store outgrows it: the number of positions and the element size are
+// powers of two, positions of blocks never move, and the positions of
+// removed blocks are reused. Encoding is lazy; mutations are logged and the
+// next Snapshot encodes them. While the geometry holds, and the scheme is a
+// pir.Updater, only the changed blocks and index slots are encoded again,
+// until enough have changed that the store is compacted: laid out and
+// encoded afresh.
+package pirstore
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/filter"
+	"github.com/willscott/go-selfish-bitswap-client/padding"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+)
+
+const (
+	// DefaultMinCapacity is the smallest number of positions in a store.
+	DefaultMinCapacity = 16
+	// DefaultCompactAfter is the number of blocks changed incrementally
+	// before a store is compacted.
+	DefaultCompactAfter = 1024
+	// DefaultHintHistory is the number of earlier snapshots whose hints are
+	// kept.
+	DefaultHintHistory = 2
+)
+
+var ErrNotHave = errors.New("block not in store")
+
+// Enumerable is implemented by blockstores which can list their contents.
+type Enumerable interface {
+	GetAll() map[cid.Cid][]byte
+}
+
+// Options configure the geometry of a Store.
+type Options struct {
+	// ElementSize fixes the block element size, including the length
+	// prefix. Larger blocks are rejected. If zero, elements grow to fit the
+	// largest block.
+	ElementSize int
+	// Padding chooses the sizes elements grow to, when ElementSize is zero.
+	// Defaults to powers of two.
+	Padding padding.Policy
+	// MinCapacity is the smallest number of positions. Defaults to DefaultMinCapacity.
+	MinCapacity int
+	// BatchSize, if positive, also lays both databases out for batch
+	// retrieval of up to BatchSize blocks at once. Each CID needs
+	// keyword.NumHashes index slots, so the index is laid out for that many
+	// more. Stores of pir.Hinter schemes are not laid out for batches,
+	// whose every bucket would need a hint of its own.
+	BatchSize int
+	// CompactAfter is the number of blocks which may be changed
+	// incrementally after the store was last encoded in full. Once more
+	// have, the next Snapshot compacts the store, laying out its index
+	// afresh. Negative encodes every snapshot in full. Defaults to
+	// DefaultCompactAfter.
+	CompactAfter int
+	// Mapped answers from the databases of unsealed snapshot files loaded
+	// by Restore in place, memory mapped, rather than reading them into
+	// memory, so databases larger than memory can be served from the page
+	// cache. The files must not be truncated or written over in place
+	// while mapped; Save replaces them whole.
+	Mapped bool
+	// HintHistory is the number of earlier snapshots of pir.Hinter schemes
+	// whose hints are kept, so that clients holding one of them are sent
+	// only its changes by HintDelta. Negative keeps none. Defaults to
+	// DefaultHintHistory.
+	HintHistory int
+	// FilterRate, if positive, builds a presence filter of the CIDs of each
+	// snapshot which falsely reports others present at that rate, for
+	// servers to send clients in the handshake.
+	FilterRate float64
+	// Group is the root of the content group whose blocks the store lays
+	// out, for servers to tell its databases from those of the whole
+	// blockstore, or cid.Undef. It does not change the layout.
+	Group cid.Cid
+	// Background encodes the changes to the store in the background once
+	// it has a snapshot: Snapshot returns the last snapshot while the next
+	// is encoded, and switches to it once it is, so a large batch of
+	// changes does not hold up the queries answered meanwhile. Snapshots
+	// so lag the contents of the store until they are encoded.
+	Background bool
+}
+
+// Snapshot is an encoded, immutable view of a store.
+type Snapshot struct {
+	// Epoch numbers the snapshots of a store, increasing each time it is
+	// encoded again. Epochs count up from the time the store was created,
+	// in nanoseconds, so a store created again, as after a restart, never
+	// reuses the epoch of a layout clients may have seen before.
+	Epoch  uint64
+	Index  *pir.Encoded
+	Blocks *pir.Encoded
+	// IndexBatch and BlocksBatch are the batched layouts of the same
+	// databases, if the store has a BatchSize.
+	IndexBatch  *batch.Encoded
+	BlocksBatch *batch.Encoded
+	// Manifest is the SHA-256 digest of the CID→index map of the snapshot:
+	// the CID of the block at each position of the blocks database.
+	// Servers sign it, so that clients may tell whether they were all
+	// given the same map.
+	Manifest []byte
+	// Filter is the presence filter of the CIDs of the snapshot, nil if
+	// the store has no FilterRate.
+	Filter *filter.Bloom
+
+	// keys holds the multihashes of the blocks laid out.
+	keys map[string]bool
+	// digest names the layout of the snapshot, as Store.digest.
+	digest []byte
+}
+
+// Has reports whether the block named by c is laid out in the snapshot.
+func (snap *Snapshot) Has(c cid.Cid) bool {
+	return snap.keys[string(c.Hash())]
+}
+
+// Store maintains the PIR layout of a set of blocks.
+type Store struct {
+	scheme pir.Scheme
+	opts   Options
+
+	mtx         sync.Mutex
+	keys        [][]byte // cid bytes at each position, nil if free
+	hashes      [][]byte // multihash at each position, the index's keys
+	blocks      [][]byte
+	positions   map[string]uint64 // by multihash
+	free        []uint64
+	elementSize int
+	// current is nil when the store changed since last was encoded.
+	current *Snapshot
+	last    *Snapshot
+	epoch   uint64
+	// committed is the epoch of last, read by Epoch without mtx.
+	committed uint64
+	// version counts the changes to the contents.
+	version uint64
+	// building is the build under way, if any, and failed is set if the
+	// last build failed.
+	building *build
+	failed   bool
+
+	// table is the index of the store, kept up to date with every change,
+	// and nil if a change didn't fit it.
+	table *keyword.Table
+	// changed and slots log the positions and index slots changed since
+	// last was encoded.
+	changed map[uint64]bool
+	slots   map[uint64]bool
+	// updates counts the blocks changed incrementally since the store was
+	// last encoded in full.
+	updates int
+	// hints holds the hints of the latest snapshots, oldest first, and
+	// deltas those computed between them.
+	hints  []pastHints
+	deltas map[deltaKey][]byte
+}
+
+// pastHints are the hints of the databases of one snapshot.
+type pastHints struct {
+	epoch         uint64
+	index, blocks []byte
+}
+
+type deltaKey struct {
+	since, epoch uint64
+	blocks       bool
+}
+
+// New creates an empty store encoded with scheme.
+func New(scheme pir.Scheme, opts Options) *Store {
+	if opts.MinCapacity <= 0 {
+		opts.MinCapacity = DefaultMinCapacity
+	}
+	if opts.CompactAfter == 0 {
+		opts.CompactAfter = DefaultCompactAfter
+	}
+	if opts.HintHistory == 0 {
+		opts.HintHistory = DefaultHintHistory
+	}
+	return &Store{
+		scheme:      scheme,
+		opts:        opts,
+		epoch:       uint64(time.Now().UnixNano()),
+		positions:   make(map[string]uint64),
+		elementSize: opts.ElementSize,
+		changed:     make(map[uint64]bool),
+		slots:       make(map[uint64]bool),
+	}
+}
+
+// Load creates a store holding the contents of src.
+func Load(src Enumerable, scheme pir.Scheme, opts Options) (*Store, error) {
+	s := New(scheme, opts)
+	if err := s.Sync(src); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Scheme returns the scheme the store is encoded with.
+func (s *Store) Scheme() pir.Scheme {
+	return s.scheme
+}
+
+// Group returns the content group of the store, as set in its Options.
+func (s *Store) Group() cid.Cid {
+	return s.opts.Group
+}
+
+// Add places a block in the store, keeping its position if already present.
+func (s *Store) Add(c cid.Cid, data []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.add(c, data)
+}
+
+func (s *Store) add(c cid.Cid, data []byte) error {
+	key, hash := c.Bytes(), []byte(c.Hash())
+	if _, ok := s.positions[string(hash)]; ok {
+		return nil
+	}
+	if err := s.fit(data); err != nil {
+		return err
+	}
+
+	var pos uint64
+	if n := len(s.free); n > 0 {
+		pos = s.free[n-1]
+		s.free = s.free[:n-1]
+		s.keys[pos] = key
+		s.hashes[pos] = hash
+		s.blocks[pos] = data
+	} else {
+		pos = uint64(len(s.keys))
+		s.keys = append(s.keys, key)
+		s.hashes = append(s.hashes, hash)
+		s.blocks = append(s.blocks, data)
+	}
+	s.positions[string(hash)] = pos
+	s.log(pos)
+	if s.table != nil {
+		slots, ok := s.table.Insert(hash, pos)
+		if !ok {
+			s.table = nil
+		}
+		for _, slot := range slots {
+			s.slots[slot] = true
+		}
+	}
+	return nil
+}
+
+// fit grows the element size to hold data.
+func (s *Store) fit(data []byte) error {
+	need := pir.BlockHeaderSize + len(data)
+	if s.opts.ElementSize > 0 && need > s.opts.ElementSize {
+		return pir.ErrBlockTooLarge
+	}
+	if need > s.elementSize && s.opts.Padding.Enabled() {
+		s.elementSize = s.opts.Padding.Size(need)
+	}
+	for need > s.elementSize {
+		s.elementSize = grow(s.elementSize)
+	}
+	return nil
+}
+
+// Replace places a block in the store, replacing the data of any block of
+// the same multihash in its position, so snapshots hold one or the other
+// but never neither.
+func (s *Store) Replace(c cid.Cid, data []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	pos, ok := s.positions[string(c.Hash())]
+	if !ok {
+		return s.add(c, data)
+	}
+	if err := s.fit(data); err != nil {
+		return err
+	}
+	s.keys[pos] = c.Bytes()
+	s.blocks[pos] = data
+	s.log(pos)
+	return nil
+}
+
+// Remove drops a block from the store, freeing its position for reuse.
+func (s *Store) Remove(c cid.Cid) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.remove(string(c.Hash()))
+}
+
+// remove drops the block of multihash hash.
+func (s *Store) remove(hash string) error {
+	pos, ok := s.positions[hash]
+	if !ok {
+		return ErrNotHave
+	}
+	delete(s.positions, hash)
+	s.keys[pos] = nil
+	s.hashes[pos] = nil
+	s.blocks[pos] = nil
+	s.free = append(s.free, pos)
+	s.log(pos)
+	if s.table != nil {
+		if slot, ok := s.table.Remove([]byte(hash)); ok {
+			s.slots[slot] = true
+		}
+	}
+	return nil
+}
+
+// log records that the block at pos changed.
+func (s *Store) log(pos uint64) {
+	s.changed[pos] = true
+	s.current = nil
+	s.version++
+}
+
+// Sync brings the store in line with the contents of src, adding and
+// removing only the blocks which differ. Blocks are added in the order of
+// their CIDs, so stores loaded from the same blocks are laid out alike, as
+// the servers of a pir.MultiServer scheme must be.
+func (s *Store) Sync(src Enumerable) error {
+	all := src.GetAll()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	held := make(map[string]bool, len(all))
+	added := make([]cid.Cid, 0, len(all))
+	for c := range all {
+		held[string(c.Hash())] = true
+		added = append(added, c)
+	}
+	for hash := range s.positions {
+		if !held[hash] {
+			if err := s.remove(hash); err != nil {
+				return err
+			}
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].KeyString() < added[j].KeyString() })
+	for _, c := range added {
+		if err := s.add(c, all[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Position returns where the block named by c is stored.
+func (s *Store) Position(c cid.Cid) (uint64, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	pos, ok := s.positions[string(c.Hash())]
+	return pos, ok
+}
+
+// Len returns the number of blocks in the store.
+func (s *Store) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.positions)
+}
+
+// Epoch returns the epoch of the last snapshot encoded, or zero if none
+// was. It does not wait for a snapshot being encoded.
+func (s *Store) Epoch() uint64 {
+	return atomic.LoadUint64(&s.committed)
+}
+
+// Overhead returns the size of the blocks in the store, and the padding the
+// block database adds to them: the rest of each element, and the empty
+// positions.
+func (s *Store) Overhead() padding.Overhead {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var o padding.Overhead
+	for pos, key := range s.keys {
+		if key != nil {
+			o.Payload += int64(len(s.blocks[pos]))
+		}
+	}
+	o.Padding = int64(s.capacity())*int64(s.paddedSize()) - o.Payload
+	return o
+}
+
+// Snapshot returns the encoded databases for the current contents,
+// encoding the changes since the last snapshot if there are any. Stores
+// encoding in the Background return the last snapshot instead, once they
+// have one, unless the last encoding failed.
+func (s *Store) Snapshot() (*Snapshot, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.snapshot(!s.opts.Background)
+}
+
+// build encodes a snapshot of the contents of the store as they were when
+// it began, without mtx held, so that the store may change and the last
+// snapshot be served meanwhile. One build runs at a time.
+type build struct {
+	snap *Snapshot
+	// version is that of the contents encoded.
+	version uint64
+	// run encodes snap.
+	run func() error
+	// full is set if the store was laid out afresh. Otherwise updates
+	// blocks were changed incrementally.
+	full    bool
+	updates int
+	// changed and slots are the changes encoded, logged again if the
+	// build fails.
+	changed, slots map[uint64]bool
+
+	// done is closed once the build ends, with err.
+	done chan struct{}
+	err  error
+}
+
+// snapshot is Snapshot, with mtx held, which it releases while it waits for
+// a build. Unless wait is set, it returns the last snapshot, if there is
+// one and the last build did not fail, rather than waiting.
+func (s *Store) snapshot(wait bool) (*Snapshot, error) {
+	want := s.version
+	for {
+		if s.current != nil {
+			return s.current, nil
+		}
+		b := s.building
+		if b == nil {
+			var err error
+			if b, err = s.prepare(); err != nil {
+				return nil, err
+			}
+			s.building = b
+			go s.run(b)
+		}
+		if !wait && s.last != nil && !s.failed {
+			return s.last, nil
+		}
+		s.mtx.Unlock()
+		<-b.done
+		s.mtx.Lock()
+		if b.err != nil {
+			return nil, b.err
+		}
+		// a build begun before the changes waited for encodes none of
+		// them, so another follows.
+		if b.version >= want {
+			return b.snap, nil
+		}
+	}
+}
+
+// prepare captures the contents of the store for a build, either the
+// changes logged since the last snapshot or the whole layout, and starts a
+// new log. The caller holds mtx.
+func (s *Store) prepare() (*build, error) {
+	b := &build{version: s.version, changed: s.changed, slots: s.slots, done: make(chan struct{})}
+	var err error
+	if s.incremental() {
+		b.snap, b.run, err = s.update()
+		b.updates = len(s.changed)
+	} else {
+		b.full = true
+		b.snap, b.run, err = s.encode()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.describe(b.snap)
+	s.changed = make(map[uint64]bool)
+	s.slots = make(map[uint64]bool)
+	return b, nil
+}
+
+// run runs b, and commits its snapshot.
+func (s *Store) run(b *build) {
+	err := b.run()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	defer close(b.done)
+	s.building, s.failed, b.err = nil, err != nil, err
+	if err != nil {
+		for pos := range b.changed {
+			s.changed[pos] = true
+		}
+		for slot := range b.slots {
+			s.slots[slot] = true
+		}
+		if b.full {
+			// the changes logged since are to the new layout, so they
+			// cannot be applied to the last snapshot.
+			s.table = nil
+		}
+		return
+	}
+	if b.full {
+		s.updates = 0
+	}
+	s.updates += b.updates
+	s.epoch++
+	b.snap.Epoch = s.epoch
+	s.commit(b.snap)
+	if s.version != b.version {
+		s.current = nil
+	}
+}
+
+// describe records the blocks laid out in snap, from the current contents.
+// The caller holds mtx.
+func (s *Store) describe(snap *Snapshot) {
+	snap.keys = make(map[string]bool, len(s.positions))
+	for hash := range s.positions {
+		snap.keys[hash] = true
+	}
+	snap.Manifest = s.manifest()
+	if s.opts.FilterRate > 0 {
+		snap.Filter = filter.New(len(s.positions), s.opts.FilterRate)
+		for hash := range s.positions {
+			snap.Filter.Add([]byte(hash))
+		}
+	}
+	snap.digest = s.digest()
+}
+
+// commit makes snap, encoded from the current contents, the current
+// snapshot. The caller holds mtx.
+func (s *Store) commit(snap *Snapshot) {
+	s.keepHints(snap)
+	s.current, s.last = snap, snap
+	atomic.StoreUint64(&s.committed, snap.Epoch)
+}
+
+// keepHints records the hints of snap, forgetting those of snapshots past
+// the store's HintHistory. The caller holds mtx.
+func (s *Store) keepHints(snap *Snapshot) {
+	if snap.Index.Params.Hint == nil || s.opts.HintHistory < 0 {
+		return
+	}
+	s.hints = append(s.hints, pastHints{snap.Epoch, snap.Index.Params.Hint, snap.Blocks.Params.Hint})
+	if len(s.hints) > s.opts.HintHistory+1 {
+		s.hints = append(s.hints[:0], s.hints[len(s.hints)-s.opts.HintHistory-1:]...)
+	}
+	s.deltas = nil
+}
+
+// HintDelta returns the changes to the hint of the index, or of the blocks
+// database if blocks is set, from the snapshot of epoch since to that of
+// epoch, encoded by pir.DiffHint. It reports false if either hint is no
+// longer kept, the geometry of the database changed in between, or the
+// changes are no smaller than the hint.
+func (s *Store) HintDelta(since, epoch uint64, blocks bool) ([]byte, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key := deltaKey{since, epoch, blocks}
+	if delta, ok := s.deltas[key]; ok {
+		return delta, delta != nil
+	}
+	var old, cur []byte
+	for _, h := range s.hints {
+		hint := h.index
+		if blocks {
+			hint = h.blocks
+		}
+		switch h.epoch {
+		case since:
+			old = hint
+		case epoch:
+			cur = hint
+		}
+	}
+	if old == nil || cur == nil || len(old) != len(cur) {
+		return nil, false
+	}
+	// the delta is asked for a piece at a time, so it is kept, or its
+	// absence if it is too large to be worth sending.
+	if s.deltas == nil {
+		s.deltas = make(map[deltaKey][]byte)
+	}
+	delta := pir.DiffHint(old, cur)
+	if len(delta) >= len(cur) {
+		delta = nil
+	}
+	s.deltas[key] = delta
+	return delta, delta != nil
+}
+
+// manifest digests the position of every block laid out. The caller holds
+// mtx.
+func (s *Store) manifest() []byte {
+	h := sha256.New()
+	var buf []byte
+	for pos, key := range s.keys {
+		if key == nil {
+			continue
+		}
+		buf = appendUvarint(buf[:0], uint64(pos))
+		buf = appendPart(buf, key)
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+// incremental reports whether the logged changes can be applied to the
+// last snapshot, rather than encoding the store afresh.
+func (s *Store) incremental() bool {
+	if _, ok := s.scheme.(pir.Updater); !ok || s.last == nil || s.table == nil {
+		return false
+	}
+	if s.opts.CompactAfter < 0 || s.updates+len(s.changed) > s.opts.CompactAfter {
+		return false
+	}
+	blocks := s.last.Blocks.Params
+	return blocks.NumElements == uint64(s.capacity()) && blocks.ElementSize == uint64(s.paddedSize()) &&
+		s.last.Index.Params.NumElements == s.table.NumSlots()
+}
+
+// update captures the logged changes, returning a snapshot which run
+// encodes them into, a copy of the last snapshot.
+func (s *Store) update() (*Snapshot, func() error, error) {
+	u := s.scheme.(pir.Updater)
+	blocks := make(map[uint64][]byte, len(s.changed))
+	for pos := range s.changed {
+		if s.keys[pos] == nil {
+			blocks[pos] = nil
+			continue
+		}
+		e, err := pir.PadBlock(s.blocks[pos], s.paddedSize())
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks[pos] = e
+	}
+	index := make(map[uint64][]byte, len(s.slots))
+	for slot := range s.slots {
+		index[slot] = s.table.Slot(slot)
+	}
+	last := s.last
+	snap := &Snapshot{}
+	return snap, func() error {
+		var err error
+		if snap.Index, err = u.Update(last.Index, index); err != nil {
+			return err
+		}
+		if snap.Blocks, err = u.Update(last.Blocks, blocks); err != nil {
+			return err
+		}
+		if err := s.hint(snap); err != nil {
+			return err
+		}
+		if last.IndexBatch != nil {
+			if snap.IndexBatch, err = batch.Update(s.scheme, last.IndexBatch, index); err != nil {
+				return err
+			}
+			if snap.BlocksBatch, err = batch.Update(s.scheme, last.BlocksBatch, blocks); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// encode lays the store out afresh, returning a snapshot which run encodes
+// all of it into.
+func (s *Store) encode() (*Snapshot, func() error, error) {
+	index, blocks, err := s.layout()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, hinted := s.scheme.(pir.Hinter)
+	batched := s.opts.BatchSize > 0 && !hinted
+	snap := &Snapshot{}
+	return snap, func() error {
+		var err error
+		if snap.Index, err = s.scheme.Setup(index); err != nil {
+			return err
+		}
+		if snap.Blocks, err = s.scheme.Setup(blocks); err != nil {
+			return err
+		}
+		if err := s.hint(snap); err != nil {
+			return err
+		}
+		if batched {
+			if snap.IndexBatch, err = batch.Setup(s.scheme, index, keyword.NumHashes*s.opts.BatchSize); err != nil {
+				return err
+			}
+			if snap.BlocksBatch, err = batch.Setup(s.scheme, blocks, s.opts.BatchSize); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// hint computes the hints of the databases of snap, if the scheme has any,
+// so every snapshot is versioned with its own.
+func (s *Store) hint(snap *Snapshot) error {
+	if err := pir.SetHint(s.scheme, snap.Index); err != nil {
+		return err
+	}
+	return pir.SetHint(s.scheme, snap.Blocks)
+}
+
+// capacity is the number of positions in the current geometry.
+func (s *Store) capacity() int {
+	c := s.opts.MinCapacity
+	for c < len(s.keys) {
+		c *= 2
+	}
+	return c
+}
+
+// paddedSize is the element size of the block database.
+func (s *Store) paddedSize() int {
+	if s.elementSize < pir.BlockHeaderSize {
+		return pir.BlockHeaderSize
+	}
+	return s.elementSize
+}
+
+func (s *Store) layout() (index, blocks pir.Database, err error) {
+	capacity := s.capacity()
+	elementSize := s.paddedSize()
+	blocks = pir.Database{Elements: make([][]byte, capacity), ElementSize: elementSize}
+	for pos, key := range s.keys {
+		if key == nil {
+			continue
+		}
+		if blocks.Elements[pos], err = pir.PadBlock(s.blocks[pos], elementSize); err != nil {
+			return
+		}
+	}
+	// half filled slots keep cuckoo insertion from having to grow the table.
+	if s.table, err = keyword.NewTable(s.hashes, 2*capacity/keyword.SlotEntries); err != nil {
+		return
+	}
+	return s.table.Database(), blocks, nil
+}
+
+// grow doubles a size, starting from a small power of two.
+func grow(n int) int {
+	if n < 16 {
+		return 16
+	}
+	return 2 * n
+}
@@ -0,0 +1,666 @@
+package pirstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/padding"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/xorpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func fetch(t *testing.T, s *pirstore.Store, c cid.Cid) []byte {
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheme := s.Scheme()
+	get := func(db *pir.Encoded, i uint64) []byte {
+		q, sec, err := scheme.Query(db.Params, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := scheme.Answer(db, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := scheme.Decode(db.Params, sec, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	var pos uint64
+	found := false
+	for _, slot := range keyword.Slots(c.Hash(), snap.Index.Params.NumElements) {
+		if p, ok := keyword.Find(get(snap.Index, slot), c.Hash()); ok {
+			pos, found = p, true
+		}
+	}
+	if !found {
+		return nil
+	}
+	blk, err := pir.UnpadBlock(get(snap.Blocks, pos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+func TestLayout(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(bs, []byte("hello world"))
+	c2 := util.Add(bs, []byte("hello world 2"))
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fetch(t, s, c1); !bytes.Equal(got, []byte("hello world")) {
+		t.Fatalf("got %q", got)
+	}
+	p2, _ := s.Position(c2)
+
+	if err := s.Remove(c1); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetch(t, s, c1); got != nil {
+		t.Fatalf("removed block should not be found, got %q", got)
+	}
+	if p, _ := s.Position(c2); p != p2 {
+		t.Fatalf("position of remaining block moved from %d to %d", p2, p)
+	}
+
+	// the freed position is reused and geometry is unchanged.
+	before, _ := s.Snapshot()
+	c3 := util.Add(bs, []byte("hello world 3"))
+	if err := s.Sync(bs.(pirstore.Enumerable)); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := s.Snapshot()
+	if before.Blocks.Params.NumElements != after.Blocks.Params.NumElements {
+		t.Fatal("geometry changed though capacity was not exceeded")
+	}
+	if after.Epoch <= before.Epoch {
+		t.Fatalf("epoch did not advance with the contents: %d then %d", before.Epoch, after.Epoch)
+	}
+	if before.Has(c1) || before.Has(c3) || !after.Has(c3) {
+		t.Fatal("snapshots should hold the blocks laid out when they were taken")
+	}
+	if got := fetch(t, s, c3); !bytes.Equal(got, []byte("hello world 3")) {
+		t.Fatalf("got %q", got)
+	}
+	// Sync re-added c1, which is still in bs.
+	if s.Len() != 3 {
+		t.Fatalf("expected 3 blocks, have %d", s.Len())
+	}
+}
+
+func TestRestartEpochs(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(bs, []byte("hello world"))
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fastpir.pirdb")
+	if err := s.Save(path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// a store loaded again, as after a restart, from other blocks numbers
+	// its layout afresh, never as one clients may have seen.
+	if err := bs.DeleteBlock(context.Background(), c1); err != nil {
+		t.Fatal(err)
+	}
+	c2 := util.Add(bs, []byte("hello world 2"))
+	r, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := r.Restore(path, nil); err != nil || ok {
+		t.Fatalf("restored a snapshot of other blocks: %v, %v", ok, err)
+	}
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Epoch <= first.Epoch || bytes.Equal(snap.Manifest, first.Manifest) {
+		t.Fatalf("restarted at epoch %d, after %d", snap.Epoch, first.Epoch)
+	}
+	if got := fetch(t, r, c2); !bytes.Equal(got, []byte("hello world 2")) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFixedElementSize(t *testing.T) {
+	s := pirstore.New(fastpir.New(), pirstore.Options{ElementSize: 8})
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(bs, []byte("too large for eight"))
+	if err := s.Add(c, []byte("too large for eight")); err != pir.ErrBlockTooLarge {
+		t.Fatalf("expected oversized block to be rejected, got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(bs, []byte("hello world"))
+	s := pirstore.New(fastpir.New(), pirstore.Options{ElementSize: 64})
+	if err := s.Replace(c, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos, _ := s.Position(c)
+
+	if err := s.Replace(c, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.Position(c); p != pos || s.Len() != 1 {
+		t.Fatalf("replaced block moved from %d to %d among %d", pos, p, s.Len())
+	}
+	if got := fetch(t, s, c); !bytes.Equal(got, []byte("second")) {
+		t.Fatalf("got %q", got)
+	}
+	if after, _ := s.Snapshot(); after.Epoch <= before.Epoch {
+		t.Fatalf("epoch did not advance with the contents: %d then %d", before.Epoch, after.Epoch)
+	}
+
+	if err := s.Replace(c, make([]byte, 64)); err != pir.ErrBlockTooLarge {
+		t.Fatalf("expected oversized block to be rejected, got %v", err)
+	}
+	if got := fetch(t, s, c); !bytes.Equal(got, []byte("second")) {
+		t.Fatalf("refused replacement left %q", got)
+	}
+}
+
+func TestPadding(t *testing.T) {
+	policy, err := padding.NewPolicy(100, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := pirstore.New(fastpir.New(), pirstore.Options{Padding: policy})
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	small := util.Add(bs, []byte("small"))
+	if err := s.Add(small, []byte("small")); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Blocks.Params.ElementSize != 100 {
+		t.Fatalf("elements padded to %d", snap.Blocks.Params.ElementSize)
+	}
+	large := util.Add(bs, bytes.Repeat([]byte{1}, 200))
+	if err := s.Add(large, bytes.Repeat([]byte{1}, 200)); err != nil {
+		t.Fatal(err)
+	}
+	if snap, err = s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Blocks.Params.ElementSize != 1000 {
+		t.Fatalf("elements grew to %d", snap.Blocks.Params.ElementSize)
+	}
+	if got := fetch(t, s, small); !bytes.Equal(got, []byte("small")) {
+		t.Fatalf("got %q", got)
+	}
+	o := s.Overhead()
+	if o.Payload != 205 || o.Padding != pirstore.DefaultMinCapacity*1000-205 {
+		t.Fatalf("accounted %+v", o)
+	}
+}
+
+func TestBatchLayout(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	_ = util.Add(bs, []byte("hello world"))
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{BatchSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.BlocksBatch == nil || snap.IndexBatch == nil {
+		t.Fatal("expected batched layouts")
+	}
+	if snap.BlocksBatch.Params.NumElements != snap.Blocks.Params.NumElements ||
+		snap.IndexBatch.Params.NumElements != snap.Index.Params.NumElements {
+		t.Fatal("batched layouts hold different databases")
+	}
+	if snap.IndexBatch.Params.BatchSize != keyword.NumHashes*4 {
+		t.Fatalf("index laid out for batches of %d", snap.IndexBatch.Params.BatchSize)
+	}
+}
+
+// countingScheme counts full encodings of databases.
+type countingScheme struct {
+	*fastpir.Scheme
+	setups int
+}
+
+func (s *countingScheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	s.setups++
+	return s.Scheme.Setup(db)
+}
+
+func TestIncremental(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(bs, []byte("hello world"))
+	c2 := util.Add(bs, []byte("hello world 2"))
+	scheme := &countingScheme{Scheme: fastpir.New()}
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), scheme, pirstore.Options{CompactAfter: 3, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setups := scheme.setups
+
+	c3 := util.Add(bs, []byte("hello world 3"))
+	if err := s.Add(c3, []byte("hello world 3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove(c1); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetch(t, s, c3); !bytes.Equal(got, []byte("hello world 3")) {
+		t.Fatalf("got %q", got)
+	}
+	if got := fetch(t, s, c1); got != nil {
+		t.Fatalf("removed block should not be found, got %q", got)
+	}
+	if got := fetch(t, s, c2); !bytes.Equal(got, []byte("hello world 2")) {
+		t.Fatalf("got %q", got)
+	}
+	if scheme.setups != setups {
+		t.Fatalf("%d databases encoded in full for a small change", scheme.setups-setups)
+	}
+	if snap, _ := s.Snapshot(); snap.Epoch <= first.Epoch || snap.Blocks == first.Blocks {
+		t.Fatal("changes were applied to the earlier snapshot")
+	}
+
+	// a third and fourth change exceed CompactAfter.
+	if err := s.Add(c1, []byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	c4 := util.Add(bs, []byte("hello world 4"))
+	if err := s.Add(c4, []byte("hello world 4")); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetch(t, s, c4); !bytes.Equal(got, []byte("hello world 4")) {
+		t.Fatalf("got %q", got)
+	}
+	if scheme.setups == setups {
+		t.Fatal("store was not compacted")
+	}
+}
+
+// gatedScheme holds its encodings until gate, once set, is closed.
+type gatedScheme struct {
+	*fastpir.Scheme
+	gate chan struct{}
+}
+
+func (s *gatedScheme) Setup(db pir.Database) (*pir.Encoded, error) {
+	if s.gate != nil {
+		<-s.gate
+	}
+	return s.Scheme.Setup(db)
+}
+
+func TestBackground(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(bs, []byte("hello world"))
+	c2 := util.Add(bs, []byte("hello world 2"))
+	scheme := &gatedScheme{Scheme: fastpir.New()}
+	s := pirstore.New(scheme, pirstore.Options{Background: true, CompactAfter: -1})
+	if err := s.Add(c1, []byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	// the first snapshot is waited for.
+	first, err := s.Snapshot()
+	if err != nil || !first.Has(c1) {
+		t.Fatalf("got %v", err)
+	}
+
+	// later ones are encoded while the last is served, and the store
+	// changed.
+	scheme.gate = make(chan struct{})
+	if err := s.Add(c2, []byte("hello world 2")); err != nil {
+		t.Fatal(err)
+	}
+	if snap, err := s.Snapshot(); err != nil || snap != first {
+		t.Fatalf("got epoch %d, %v, while the next was encoded", snap.Epoch, err)
+	}
+	if err := s.Remove(c1); err != nil || s.Len() != 1 {
+		t.Fatalf("store not changed while encoded: %v", err)
+	}
+	close(scheme.gate)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		snap, err := s.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Epoch > first.Epoch && snap.Has(c2) && !snap.Has(c1) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("still serving epoch %d", snap.Epoch)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := fetch(t, s, c2); !bytes.Equal(got, []byte("hello world 2")) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	for i := 0; i < 40; i++ {
+		util.Add(bs, []byte(fmt.Sprintf("block %d", i)))
+	}
+	// servers of a multi-server scheme loading the same blocks must hold
+	// the same databases, whatever order the blockstore lists them in.
+	var digests [][]byte
+	for i := 0; i < 3; i++ {
+		s, err := pirstore.Load(bs.(pirstore.Enumerable), xorpir.New(), pirstore.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap, err := s.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests = append(digests, append(snap.Index.Params.Extra, snap.Blocks.Params.Extra...))
+	}
+	for _, d := range digests[1:] {
+		if !bytes.Equal(d, digests[0]) {
+			t.Fatal("stores loaded from the same blocks are laid out differently")
+		}
+	}
+}
+
+func TestManifest(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	for i := 0; i < 10; i++ {
+		util.Add(bs, []byte(fmt.Sprintf("block %d", i)))
+	}
+	manifest := func(s *pirstore.Store) []byte {
+		snap, err := s.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return snap.Manifest
+	}
+	a, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stores loaded from the same blocks commit to the same map,
+	if !bytes.Equal(manifest(a), manifest(b)) {
+		t.Fatal("stores of the same blocks have different manifests")
+	}
+	// and any change to it differently.
+	before := manifest(a)
+	blk := []byte("block 10")
+	c, err := cid.V1Builder{Codec: cid.Raw, MhType: 0x12}.Sum(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Add(c, blk); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(manifest(a), before) {
+		t.Fatal("manifest unchanged by an added block")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(bs, []byte("hello world"))
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{FilterRate: 0.001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Filter == nil || !snap.Filter.Has(c.Hash()) {
+		t.Fatal("filter lacks a block of the snapshot")
+	}
+	added := util.Add(bs, []byte("hello again"))
+	if snap.Filter.Has(added.Hash()) {
+		t.Fatal("filter has a block not yet added")
+	}
+	if err := s.Add(added, []byte("hello again")); err != nil {
+		t.Fatal(err)
+	}
+	if snap, err = s.Snapshot(); err != nil || !snap.Filter.Has(added.Hash()) {
+		t.Fatalf("filter lacks an added block: %v", err)
+	}
+
+	unfiltered, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), pirstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap, err := unfiltered.Snapshot(); err != nil || snap.Filter != nil {
+		t.Fatalf("store without a FilterRate built a filter: %v", err)
+	}
+}
+
+func TestHints(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(bs, []byte("hello world"))
+	scheme := pirtest.Hinted(fastpir.New(), 100)
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), scheme, pirstore.Options{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, db := range []*pir.Encoded{first.Index, first.Blocks} {
+		if len(db.Params.Hint) != 100 || !pir.CheckHint(db.Params, db.Params.Hint) {
+			t.Fatalf("database has hint %x with digest %x", db.Params.Hint, db.Params.HintDigest)
+		}
+	}
+	if first.IndexBatch != nil || first.BlocksBatch != nil {
+		t.Fatal("databases with hints laid out for batches")
+	}
+	if got := fetch(t, s, c1); !bytes.Equal(got, []byte("hello world")) {
+		t.Fatalf("got %q", got)
+	}
+
+	c2 := util.Add(bs, []byte("hello world 2"))
+	if err := s.Add(c2, []byte("hello world 2")); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(snap.Blocks.Params.HintDigest, first.Blocks.Params.HintDigest) {
+		t.Fatal("hint unchanged by a change to the database")
+	}
+	if got := fetch(t, s, c2); !bytes.Equal(got, []byte("hello world 2")) {
+		t.Fatalf("got %q", got)
+	}
+
+}
+
+func TestHintDelta(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	for i := 0; i < 31; i++ {
+		util.Add(bs, []byte(fmt.Sprintf("block %d", i)))
+	}
+	scheme := pirtest.Hinted(fastpir.New(), 4096)
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), scheme, pirstore.Options{HintHistory: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := util.Add(bs, []byte("another block"))
+	if err := s.Add(c, []byte("another block")); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// clients holding the earlier hints are sent their changes.
+	for _, blocks := range []bool{false, true} {
+		old, cur := first.Index.Params.Hint, snap.Index.Params
+		if blocks {
+			old, cur = first.Blocks.Params.Hint, snap.Blocks.Params
+		}
+		delta, ok := s.HintDelta(first.Epoch, snap.Epoch, blocks)
+		if !ok || len(delta) >= len(cur.Hint) {
+			t.Fatalf("delta of %d bytes from a kept hint", len(delta))
+		}
+		if hint, err := pir.PatchHint(old, delta); err != nil || !pir.CheckHint(cur, hint) {
+			t.Fatalf("delta does not patch the hint: %v", err)
+		}
+	}
+	if _, ok := s.HintDelta(snap.Epoch+1, snap.Epoch, true); ok {
+		t.Fatal("delta from an unknown epoch")
+	}
+
+	// hints past the history are forgotten.
+	c = util.Add(bs, []byte("yet another block"))
+	if err := s.Add(c, []byte("yet another block")); err != nil {
+		t.Fatal(err)
+	}
+	last, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.HintDelta(first.Epoch, last.Epoch, true); ok {
+		t.Fatal("delta from a hint past the history")
+	}
+	if _, ok := s.HintDelta(snap.Epoch, last.Epoch, true); !ok {
+		t.Fatal("no delta from the previous hint")
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c1 := util.Add(bs, []byte("hello world"))
+	util.Add(bs, []byte("hello world 2"))
+	opts := pirstore.Options{BatchSize: 2}
+	sealing := bytes.Repeat([]byte{7}, pirstore.KeySize)
+	for _, c := range []struct {
+		key    []byte
+		mapped bool
+	}{{nil, false}, {nil, true}, {sealing, false}} {
+		key := c.key
+		path := filepath.Join(t.TempDir(), "fastpir.pirdb")
+		s, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Save(path, key); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := s.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// mapped stores answer from the file, and copy the databases
+		// before changing them.
+		scheme := &countingScheme{Scheme: fastpir.New()}
+		ropts := opts
+		ropts.Mapped = c.mapped
+		r, err := pirstore.Load(bs.(pirstore.Enumerable), scheme, ropts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.Restore(path, key); err != nil || !ok {
+			t.Fatalf("restored %v: %v", ok, err)
+		}
+		snap, err := r.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Epoch != saved.Epoch || snap.BlocksBatch == nil || scheme.setups != 0 {
+			t.Fatalf("restored epoch %d of %d, encoding %d databases", snap.Epoch, saved.Epoch, scheme.setups)
+		}
+		if got := fetch(t, r, c1); !bytes.Equal(got, []byte("hello world")) {
+			t.Fatalf("got %q", got)
+		}
+		// changes are encoded incrementally into the restored databases.
+		c3 := util.Add(bs, []byte("hello world 3"))
+		if err := r.Add(c3, []byte("hello world 3")); err != nil {
+			t.Fatal(err)
+		}
+		if got := fetch(t, r, c3); !bytes.Equal(got, []byte("hello world 3")) {
+			t.Fatalf("got %q", got)
+		}
+		if scheme.setups != 0 {
+			t.Fatal("restored store encoded in full for a small change")
+		}
+
+		// the saved databases no longer match the blockstore.
+		stale, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := stale.Restore(path, key); err != nil || ok {
+			t.Fatalf("restored stale snapshot %v: %v", ok, err)
+		}
+		if err := bs.DeleteBlock(context.Background(), c3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "fastpir.pirdb")
+	s, err := pirstore.Load(bs.(pirstore.Enumerable), fastpir.New(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(path, sealing); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range [][]byte{nil, bytes.Repeat([]byte{8}, pirstore.KeySize)} {
+		if _, err := s.Restore(path, key); !errors.Is(err, pirstore.ErrSnapshotKey) {
+			t.Fatalf("restored sealed snapshot with key %x: %v", key, err)
+		}
+	}
+	if ok, err := s.Restore(filepath.Join(t.TempDir(), "missing"), nil); err != nil || ok {
+		t.Fatalf("restored missing snapshot %v: %v", ok, err)
+	}
+}
@@ -0,0 +1,289 @@
+package pirstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+)
+
+// SnapshotVersion is the version of the snapshot file format. Files of
+// other versions are ignored by Restore.
+const SnapshotVersion = 3
+
+// KeySize is the size of the keys sealing snapshot files.
+const KeySize = 32
+
+var (
+	// ErrSnapshotKey is returned when a snapshot file cannot be unsealed
+	// with the key given, or is sealed and no key was given.
+	ErrSnapshotKey = errors.New("pirstore: snapshot sealed under a different key")
+	// ErrBadSnapshot is returned for snapshot files which cannot be parsed.
+	ErrBadSnapshot = errors.New("pirstore: malformed snapshot file")
+)
+
+var snapshotMagic = []byte("pirstore")
+
+const (
+	// headerSize keeps the body, and the databases aligned within it,
+	// aligned in the file.
+	headerSize = 16
+	// flagSealed marks files whose body is sealed with AES-GCM.
+	flagSealed = 1
+)
+
+// Save writes the encoded databases of the store's current contents to
+// path, encoding them first if they are out of date, so a later Restore can
+// load them rather than encode them again. The file replaces any at path
+// once fully written.
+//
+// If key is set, the databases are sealed with it, with AES-256-GCM, so the
+// file reveals nothing of the blocks to whoever can read it and cannot be
+// altered undetected. Unsealed files are answered from in place by stores
+// restoring them Mapped.
+func (s *Store) Save(path string, key []byte) error {
+	s.mtx.Lock()
+	snap, err := s.snapshot(true)
+	s.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	body := appendUvarint(nil, snap.Epoch)
+	body = append(body, snap.digest...)
+	body = pir.AppendAligned(body, []byte(s.scheme.ID()))
+	for _, db := range []*pir.Encoded{snap.Index, snap.Blocks} {
+		data, err := pir.Marshal(s.scheme, db)
+		if err != nil {
+			return err
+		}
+		body = pir.AppendAligned(body, data)
+	}
+	for _, db := range []*batch.Encoded{snap.IndexBatch, snap.BlocksBatch} {
+		var data []byte
+		if db != nil {
+			if data, err = batch.Marshal(s.scheme, db); err != nil {
+				return err
+			}
+		}
+		body = pir.AppendAligned(body, data)
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, snapshotMagic)
+	binary.LittleEndian.PutUint32(header[8:], SnapshotVersion)
+	if key != nil {
+		header[12] = flagSealed
+		if body, err = seal(key, header, body); err != nil {
+			return err
+		}
+	}
+	return writeFile(path, append(header, body...))
+}
+
+// Restore loads the databases saved at path by Save in place of encoding
+// the store, if they were saved from the same contents, laid out alike. It
+// reports whether it did: files of other contents, geometries, schemes or
+// versions, and missing files, are ignored, as are all files while the
+// store is being encoded. Incremental updates continue from the loaded
+// databases.
+//
+// Files are read into memory, unless the store's options are Mapped and the
+// file unsealed, when it is memory mapped, where the platform allows, and
+// answered from in place.
+func (s *Store) Restore(path string, key []byte) (bool, error) {
+	load := readFile
+	if s.opts.Mapped {
+		load = mapFile
+	}
+	data, unmap, err := load(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			_ = unmap()
+		}
+	}()
+	if len(data) < headerSize || !bytes.Equal(data[:8], snapshotMagic) {
+		return false, ErrBadSnapshot
+	}
+	if binary.LittleEndian.Uint32(data[8:]) != SnapshotVersion {
+		return false, nil
+	}
+	header, body := data[:headerSize], data[headerSize:]
+	switch {
+	case header[12]&flagSealed != 0:
+		if key == nil {
+			return false, ErrSnapshotKey
+		}
+		if body, err = unseal(key, header, body); err != nil {
+			return false, err
+		}
+	case key != nil:
+		// an unsealed file where a sealed one was expected was not
+		// written by this server.
+		return false, ErrSnapshotKey
+	}
+
+	epoch, n := binary.Uvarint(body)
+	if n <= 0 || len(body) < n+sha256.Size {
+		return false, ErrBadSnapshot
+	}
+	saved, body := body[n:n+sha256.Size], body[n+sha256.Size:]
+	parts := make([][]byte, 5)
+	for i := range parts {
+		if parts[i], body, err = pir.Aligned(body); err != nil {
+			return false, ErrBadSnapshot
+		}
+	}
+	if string(parts[0]) != s.scheme.ID() {
+		return false, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.building != nil || !bytes.Equal(saved, s.digest()) {
+		return false, nil
+	}
+	snap := &Snapshot{Epoch: epoch}
+	if snap.Index, err = pir.Unmarshal(s.scheme, parts[1]); err != nil {
+		return false, err
+	}
+	if snap.Blocks, err = pir.Unmarshal(s.scheme, parts[2]); err != nil {
+		return false, err
+	}
+	if len(parts[3]) > 0 {
+		if snap.IndexBatch, err = batch.Unmarshal(s.scheme, parts[3]); err != nil {
+			return false, err
+		}
+		if snap.BlocksBatch, err = batch.Unmarshal(s.scheme, parts[4]); err != nil {
+			return false, err
+		}
+	}
+	// the index is laid out from the positions alone, so the table
+	// rebuilt from them is the one the saved index was encoded from.
+	if s.table, err = keyword.NewTable(s.hashes, 2*s.capacity()/keyword.SlotEntries); err != nil {
+		return false, err
+	}
+	if epoch > s.epoch {
+		s.epoch = epoch
+	}
+	s.updates = 0
+	s.describe(snap)
+	s.changed = make(map[uint64]bool)
+	s.slots = make(map[uint64]bool)
+	s.commit(snap)
+	keep = header[12]&flagSealed == 0
+	return true, nil
+}
+
+// digest names the layout of the store: its scheme, geometry, and the block
+// at each position. The caller holds mtx.
+func (s *Store) digest() []byte {
+	h := sha256.New()
+	var buf []byte
+	buf = appendPart(buf, []byte(s.scheme.ID()))
+	buf = appendUvarint(buf, uint64(s.capacity()))
+	buf = appendUvarint(buf, uint64(s.paddedSize()))
+	buf = appendUvarint(buf, uint64(s.opts.BatchSize))
+	h.Write(buf)
+	for pos, key := range s.keys {
+		buf = appendPart(buf[:0], key)
+		if key != nil {
+			buf = appendPart(buf, s.blocks[pos])
+		}
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+func seal(key, header, body []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, body, header), nil
+}
+
+func unseal(key, header, body []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < aead.NonceSize() {
+		return nil, ErrBadSnapshot
+	}
+	out, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], header)
+	if err != nil {
+		return nil, ErrSnapshotKey
+	}
+	return out, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("pirstore: snapshot key is %d bytes, not %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFile writes data to a temporary file beside path, renaming it over
+// path once synced, so a crash never leaves a partial snapshot at path.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendPart(b, p []byte) []byte {
+	return append(appendUvarint(b, uint64(len(p))), p...)
+}
+
+// readFile reads the file at path into memory, for Restore to load as it
+// would a mapping.
+func readFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	return data, func() error { return nil }, err
+}
@@ -0,0 +1,568 @@
+package bitswap
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/willscott/go-selfish-bitswap-client/filter"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrNoScheme = errors.New("no PIR scheme configured")
+	// ErrNoCommonScheme is returned when the peer supports none of the
+	// session's schemes, or none within its limits.
+	ErrNoCommonScheme = errors.New("no PIR scheme in common with peer")
+	ErrNotFound       = errors.New("block not held by peer")
+	ErrBadBlock       = errors.New("retrieved block does not match its cid")
+	// ErrStaleParams is returned when the peer's databases changed since its
+	// parameters were cached. The parameters are forgotten, so a retry runs
+	// the handshake again.
+	ErrStaleParams = errors.New("peer's PIR databases changed")
+	// ErrResponseTimeout is returned when a peer went quiet for longer than
+	// the session's ResponseTimeout while answering.
+	ErrResponseTimeout = errors.New("peer stopped responding")
+)
+
+// Progress reports that a peer is still computing the answers to a PIR
+// round.
+type Progress struct {
+	// Session identifies the retrieval, and Round the round being answered.
+	Session uint64
+	Round   bitswap_message_pb.Message_PIRRound
+	// Elapsed is how long the peer has been answering the round.
+	Elapsed time.Duration
+}
+
+const handshakeInterest = "pir/handshake"
+
+func pirInterest(session uint64, round bitswap_message_pb.Message_PIRRound, part uint32) string {
+	return fmt.Sprintf("pir/%d/%s/%d", session, round, part)
+}
+
+func progressInterest(session uint64, round bitswap_message_pb.Message_PIRRound) string {
+	return fmt.Sprintf("pir/%d/%s", session, round)
+}
+
+// progressed extends the wait for the answers to the round pr reports
+// progress on.
+func (s *Session) progressed(pr bitswap_message_pb.Message_PIRProgress) {
+	s.interestMtx.Lock()
+	ch, ok := s.progress[progressInterest(pr.Session, pr.Round)]
+	s.interestMtx.Unlock()
+	if !ok {
+		pirLogger.Debugw("progress on no outstanding PIR round", "session", pr.Session, "round", pr.Round)
+		return
+	}
+	s.metrics.Add("pir_progress_received", 1)
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+	if s.onProgress != nil {
+		s.onProgress(s.peer, Progress{
+			Session: pr.Session,
+			Round:   pr.Round,
+			Elapsed: time.Duration(pr.ElapsedMillis) * time.Millisecond,
+		})
+	}
+}
+
+// resolveKey delivers a response to the caller waiting on key.
+func (s *Session) resolveKey(key string, data []byte) error {
+	return s.deliver(key, data, nil)
+}
+
+// deliver delivers a response, or the error receiving it, to the caller
+// waiting on key.
+func (s *Session) deliver(key string, data []byte, err error) error {
+	s.interestMtx.Lock()
+	cb, ok := s.interests[key]
+	if ok {
+		delete(s.interests, key)
+	}
+	s.interestMtx.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no callback registered for %s", key)
+	}
+	cb(data, err)
+	return nil
+}
+
+// roundtrip sends m on the private stream and waits for the responses
+// delivered under each of keys. Progress reported under the progress key, if
+// any, extends the response timeout.
+func (s *Session) roundtrip(ctx context.Context, m *bitswap_message_pb.Message, progress string, keys ...string) ([][]byte, error) {
+	type result struct {
+		i    int
+		data []byte
+		err  error
+	}
+	done := make(chan result, len(keys))
+	progressed := make(chan struct{}, 1)
+	s.interestMtx.Lock()
+	if progress != "" {
+		s.progress[progress] = progressed
+	}
+	for i, key := range keys {
+		i := i
+		s.interests[key] = func(data []byte, err error) {
+			done <- result{i, data, err}
+		}
+	}
+	s.interestMtx.Unlock()
+	forget := func() {
+		s.interestMtx.Lock()
+		for _, key := range keys {
+			delete(s.interests, key)
+		}
+		if progress != "" {
+			delete(s.progress, progress)
+		}
+		s.interestMtx.Unlock()
+	}
+	defer forget()
+	defer s.protect()()
+
+	if err := s.writePrivate(m); err != nil {
+		return nil, err
+	}
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	if s.rtimeout > 0 {
+		timer = time.NewTimer(s.rtimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	extend := func() {
+		if timer != nil {
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(s.rtimeout)
+		}
+	}
+	out := make([][]byte, len(keys))
+	for got := 0; got < len(keys); {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				return nil, r.err
+			}
+			out[r.i] = r.data
+			got++
+			extend()
+		case <-progressed:
+			extend()
+		case <-timeout:
+			return nil, ErrResponseTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return out, nil
+}
+
+// protect protects the connection to the peer from the connection manager
+// until the returned function is called: answers may take minutes to
+// compute, during which the connection must not be pruned.
+func (s *Session) protect() func() {
+	if s.Host == nil {
+		return func() {}
+	}
+	tag := fmt.Sprintf("bitswap-pir/%d", atomic.AddUint64(&s.protections, 1))
+	s.ConnManager().Protect(s.peer, tag)
+	return func() { s.ConnManager().Unprotect(s.peer, tag) }
+}
+
+// privateParams opens the private stream, if it isn't already, and returns
+// the PIR parameters of the peer.
+func (s *Session) privateParams(ctx context.Context) (PeerParams, error) {
+	s.privateOnce.Do(s.connectPrivate)
+	if s.privateErr != nil {
+		return PeerParams{}, s.privateErr
+	}
+	return s.peerParams(ctx)
+}
+
+// peerParams returns the PIR parameters of the peer, running the handshake
+// if they are not already cached. Cached parameters negotiated by a session
+// with other schemes or limits are negotiated again.
+func (s *Session) peerParams(ctx context.Context) (_ PeerParams, err error) {
+	if pp, ok := s.params.Get(s.peer); ok && s.accepts(pp) {
+		return pp, nil
+	}
+	s.handshakeMtx.Lock()
+	defer s.handshakeMtx.Unlock()
+	if pp, ok := s.params.Get(s.peer); ok && s.accepts(pp) {
+		return pp, nil
+	}
+	if pp, ok := s.restoreParams(ctx); ok {
+		return pp, nil
+	}
+	ctx, span := tracer.Start(ctx, "Handshake")
+	defer func() { endSpan(span, err) }()
+
+	offer := s.limits
+	for _, scheme := range s.schemes {
+		offer.Schemes = append(offer.Schemes, scheme.ID())
+	}
+	if s.compress {
+		offer.Compression = []string{bitswap_message_pb.CompressionZstd}
+	}
+	offer.Group = s.groupBytes()
+	m := bitswap_message_pb.Message{PirHandshake: &bitswap_message_pb.Message_PIRHandshake{Offer: &offer}}
+	data, err := s.roundtrip(ctx, &m, "", handshakeInterest)
+	if err != nil {
+		return PeerParams{}, err
+	}
+	pp, err := s.paramsOf(data[0])
+	if err != nil {
+		return PeerParams{}, err
+	}
+	pp.received = time.Now()
+	if err := s.fetchHints(ctx, &pp); err != nil {
+		return PeerParams{}, err
+	}
+	span.SetAttributes(attribute.String("scheme", pp.Index.Scheme))
+	s.params.Put(s.peer, pp)
+	s.saveParams(ctx, data[0], pp)
+	return pp, nil
+}
+
+// paramsOf checks the handshake reply data, sent in answer to the session's
+// offer, and returns the parameters it describes, without their hints.
+func (s *Session) paramsOf(data []byte) (PeerParams, error) {
+	hs := bitswap_message_pb.Message_PIRHandshake{}
+	if err := hs.Unmarshal(data); err != nil {
+		return PeerParams{}, fmt.Errorf("%w: handshake: %v", pir.ErrMalformed, err)
+	}
+	if hs.Index.Scheme == "" {
+		return PeerParams{}, fmt.Errorf("%w: peer supports %v", ErrNoCommonScheme, hs.Schemes)
+	}
+	pp := PeerParams{Epoch: hs.Epoch, Index: hs.Index.Params(), Blocks: hs.Blocks.Params(), Compression: hs.Compression}
+	if hs.IndexBatch != nil && hs.BlocksBatch != nil {
+		ib, bb := hs.IndexBatch.Params(), hs.BlocksBatch.Params()
+		pp.IndexBatch, pp.BlocksBatch = &ib, &bb
+	}
+	var err error
+	if pp.Group, pp.Groups, err = groupsOf(hs, s.group); err != nil {
+		return PeerParams{}, err
+	}
+	pp.offered = s.group
+	if !s.accepts(pp) {
+		return PeerParams{}, pir.ErrSchemeMismatch
+	}
+	if pp.Manifest, err = s.checkManifest(hs); err != nil {
+		return PeerParams{}, err
+	}
+	if hs.Filter != nil {
+		if pp.Filter = hs.Filter.Bloom(); pp.Filter.Check() != nil {
+			return PeerParams{}, fmt.Errorf("%w: %v", pir.ErrMalformed, filter.ErrFilter)
+		}
+	}
+	return pp, nil
+}
+
+// schemeFor returns the session's scheme identified by id, or nil.
+func (s *Session) schemeFor(id string) pir.Scheme {
+	for _, scheme := range s.schemes {
+		if scheme.ID() == id {
+			return scheme
+		}
+	}
+	return nil
+}
+
+// accepts reports whether pp describe databases of one of the session's
+// schemes, all of the same scheme, within the session's limits, negotiated
+// for the session's group.
+func (s *Session) accepts(pp PeerParams) bool {
+	id := pp.Index.Scheme
+	if s.schemeFor(id) == nil || pp.Blocks.Scheme != id {
+		return false
+	}
+	if pp.offered != s.group {
+		return false
+	}
+	if (pp.IndexBatch == nil) != (pp.BlocksBatch == nil) {
+		return false
+	}
+	if pp.IndexBatch != nil && (pp.IndexBatch.Bucket.Scheme != id || pp.BlocksBatch.Bucket.Scheme != id) {
+		return false
+	}
+	if s.limits.MaxElements > 0 && pp.Blocks.NumElements > s.limits.MaxElements {
+		return false
+	}
+	return s.limits.MaxElementSize == 0 || pp.Blocks.ElementSize <= s.limits.MaxElementSize
+}
+
+// query runs one PIR round against the peer, retrieving the elements at
+// each of indices with one query per index. Answers from databases of
+// another epoch than params fail with ErrStaleParams.
+func (s *Session) query(ctx context.Context, session uint64, round bitswap_message_pb.Message_PIRRound, epoch uint64, params pir.Params, indices ...uint64) ([][]byte, error) {
+	scheme := s.schemeFor(params.Scheme)
+	if scheme == nil {
+		return nil, pir.ErrSchemeMismatch
+	}
+	queries := make([][]byte, len(indices))
+	secrets := make([]pir.Secret, len(indices))
+	for i, index := range indices {
+		var err error
+		if queries[i], secrets[i], err = scheme.Query(params, index); err != nil {
+			return nil, err
+		}
+	}
+	// answers are decoded as they arrive by schemes which can.
+	var decoders []pir.Decoder
+	if sd, ok := scheme.(pir.StreamDecoder); ok {
+		decoders = make([]pir.Decoder, len(indices))
+		for i := range decoders {
+			var err error
+			if decoders[i], err = sd.NewDecoder(params, secrets[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	answers, err := s.ask(ctx, session, round, epoch, params, queries, decoders)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([][]byte, len(answers))
+	for i, answer := range answers {
+		if decoders != nil {
+			elements[i], err = decoders[i].Element()
+		} else {
+			elements[i], err = scheme.Decode(params, secrets[i], answer)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// ask sends queries, built for the database described by params, to the
+// peer as one PIR round, and returns their answers undecoded. The answer to
+// each query with a decoder is written to it as it arrives instead, and
+// returned nil.
+func (s *Session) ask(ctx context.Context, session uint64, round bitswap_message_pb.Message_PIRRound, epoch uint64, params pir.Params, queries [][]byte, decoders []pir.Decoder) (_ [][]byte, err error) {
+	ctx, span := tracer.Start(ctx, "PIRRound", trace.WithAttributes(
+		attribute.String("round", round.String()),
+		attribute.Int("parts", len(queries)),
+		attribute.Int64("elements", int64(params.NumElements)),
+	))
+	defer func() { endSpan(span, err) }()
+	m := bitswap_message_pb.Message{}
+	keys := make([]string, len(queries))
+	for i, q := range queries {
+		keys[i] = pirInterest(session, round, uint32(i))
+		m.PirRequests = append(m.PirRequests, bitswap_message_pb.Message_PIRRequest{
+			Session: session,
+			Round:   round,
+			Query:   q,
+			Part:    uint32(i),
+			Scheme:  params.Scheme,
+			Epoch:   epoch,
+			Id:      atomic.AddUint64(&s.pirRequest, 1),
+			Group:   s.groupBytes(),
+			Receipt: s.onReceipt != nil,
+		})
+	}
+	streams := make([]*answerStream, len(keys))
+	s.interestMtx.Lock()
+	for i, key := range keys {
+		as := &answerStream{id: m.PirRequests[i].Id}
+		if decoders != nil {
+			as.dec = decoders[i]
+		}
+		if s.onReceipt != nil {
+			as.digest = sha256.New()
+		}
+		s.answers[key] = as
+		streams[i] = as
+	}
+	s.interestMtx.Unlock()
+	defer func() {
+		s.interestMtx.Lock()
+		for _, key := range keys {
+			delete(s.answers, key)
+		}
+		s.interestMtx.Unlock()
+	}()
+	start := time.Now()
+	s.metrics.Add("pir_queries", float64(len(queries)))
+	responses, err := s.roundtrip(ctx, &m, progressInterest(session, round), keys...)
+	s.metrics.Observe("pir_query_seconds", time.Since(start).Seconds())
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, ErrResponseTimeout) {
+			s.cancelPIR(session)
+		}
+		if errors.Is(err, ErrStaleParams) {
+			s.forgetEpoch(epoch)
+		}
+		return nil, err
+	}
+	answers := make([][]byte, len(responses))
+	for i, data := range responses {
+		r := bitswap_message_pb.Message_PIRResponse{}
+		if err := r.Unmarshal(data); err != nil {
+			return nil, fmt.Errorf("%w: response: %v", pir.ErrMalformed, err)
+		}
+		if err := s.checkEpoch(epoch, r.Epoch); err != nil {
+			return nil, err
+		}
+		if s.onReceipt != nil {
+			if err := s.checkReceipt(queries[i], streams[i], r); err != nil {
+				return nil, err
+			}
+		}
+		answers[i] = r.Answer
+	}
+	return answers, nil
+}
+
+// checkEpoch fails answers from databases of another epoch than the one
+// queried, forgetting the cached parameters of the peer if they are still
+// those of the queried epoch. Peers which do not version their databases
+// send no epoch.
+func (s *Session) checkEpoch(queried, answered uint64) error {
+	if queried == 0 || answered == 0 || queried == answered {
+		return nil
+	}
+	s.forgetEpoch(queried)
+	return fmt.Errorf("%w: queried epoch %d, answered from %d", ErrStaleParams, queried, answered)
+}
+
+// forgetEpoch forgets the cached parameters of the peer if they are still
+// those of epoch, which the peer no longer serves.
+func (s *Session) forgetEpoch(epoch uint64) {
+	if pp, ok := s.params.Get(s.peer); ok && pp.Epoch == epoch {
+		s.params.Forget(s.peer)
+	}
+	s.metrics.Add("stale_params", 1)
+}
+
+// cancelPIR asks the peer to abandon outstanding work for a session.
+func (s *Session) cancelPIR(session uint64) {
+	m := bitswap_message_pb.Message{}
+	m.PirRequests = append(m.PirRequests, bitswap_message_pb.Message_PIRRequest{
+		Session: session,
+		Cancel:  true,
+	})
+	if err := s.writePrivate(&m); err != nil {
+		pirLogger.Debugw("failed to cancel PIR session", "session", session, "err", err)
+	}
+}
+
+// PrivateGet retrieves a block without revealing to the peer which CID was
+// requested. The first round looks the CID up in the peer's keyword index,
+// retrieving every slot it may be in, and the second retrieves the block at
+// the position found; all queries are encrypted with the PIR scheme negotiated
+// with the peer. The peer's parameters are requested on first use and cached.
+//
+// When the CID is not held by the peer the second round is still run, for a
+// dummy position, so the peer cannot distinguish misses from hits. If the
+// peer supports none of the session's schemes, the block is only fetched in
+// plaintext if the session allows it, after reporting the fallback. Retrieved
+// blocks are verified against c before being returned. If ctx is done before
+// the retrieval completes the peer is asked to abandon its work on it.
+//
+// Sessions with a DecoyPolicy send its decoys along with each retrieval.
+func (s *Session) PrivateGet(ctx context.Context, c cid.Cid) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PrivateGet", trace.WithAttributes(attribute.String("peer", s.peer.String())))
+	defer func() { endSpan(span, err) }()
+	if len(s.schemes) == 0 {
+		return nil, ErrNoScheme
+	}
+	if data, ok, err := s.cached(ctx, c); ok || err != nil {
+		span.SetAttributes(attribute.Bool("cached", true))
+		return data, err
+	}
+	pp, err := s.privateParams(ctx)
+	if s.plaintext(err) {
+		span.SetAttributes(attribute.Bool("plaintext", true))
+		return s.getPlaintext(ctx, c, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(c.Hash())
+	if s.lacks(pp, key) {
+		span.SetAttributes(attribute.Bool("filtered", true))
+		return nil, ErrNotFound
+	}
+	session := atomic.AddUint64(&s.pirSession, 1)
+	s.sendDecoys()
+
+	slots := keyword.Slots(key, pp.Index.NumElements)
+	found, err := s.query(ctx, session, bitswap_message_pb.Message_IndexRound, pp.Epoch, pp.Index, slots[:]...)
+	if err != nil {
+		return nil, err
+	}
+	index, ok := uint64(0), false
+	for _, slot := range found {
+		if index, ok = keyword.Find(slot, key); ok {
+			break
+		}
+	}
+	if !ok || index >= pp.Blocks.NumElements {
+		index = 0
+		ok = false
+	}
+	span.SetAttributes(attribute.Bool("found", ok))
+
+	element, err := s.query(ctx, session, bitswap_message_pb.Message_BlockRound, pp.Epoch, pp.Blocks, index)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	blk, err := open(pp, c, element[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, c, blk); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
+
+// plaintext reports whether retrievals whose handshake failed with err
+// should be made in plaintext instead.
+func (s *Session) plaintext(err error) bool {
+	return s.onFallback != nil && errors.Is(err, ErrNoCommonScheme)
+}
+
+// getPlaintext tells the caller that c is retrieved in plaintext, because of
+// reason, and retrieves it with Get.
+func (s *Session) getPlaintext(ctx context.Context, c cid.Cid, reason error) ([]byte, error) {
+	logger.Warnw("retrieving in plaintext", "peer", s.peer, "cid", c, "reason", reason)
+	s.metrics.Add("plaintext_fallbacks", 1)
+	s.onFallback(s.peer, c, reason)
+	return s.Get(ctx, c)
+}
+
+// verify checks that data hashes to the multihash of c.
+func verify(c cid.Cid, data []byte) error {
+	actual, err := c.Prefix().Sum(data)
+	if err != nil {
+		return err
+	}
+	if !actual.Equals(c) {
+		return ErrBadBlock
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// sinkStream is a stream discarding what it is sent.
+type sinkStream struct {
+	network.Stream
+}
+
+func (sinkStream) Write(p []byte) (int, error) { return len(p), nil }
+func (sinkStream) Close() error                { return nil }
+
+func deliver(t *testing.T, s *Session, m bitswap_message_pb.Message) {
+	buf, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.handle(buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResponseTimeout(t *testing.T) {
+	var mtx sync.Mutex
+	var reports []Progress
+	s := New(nil, "p", Options{
+		ResponseTimeout: 100 * time.Millisecond,
+		OnProgress: func(p peer.ID, pr Progress) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			reports = append(reports, pr)
+		},
+	})
+	s.private = sinkStream{}
+	ctx := context.Background()
+	round := bitswap_message_pb.Message_BlockRound
+
+	if _, err := s.roundtrip(ctx, &bitswap_message_pb.Message{}, progressInterest(1, round), pirInterest(1, round, 0)); !errors.Is(err, ErrResponseTimeout) {
+		t.Fatalf("silent peer: got %v", err)
+	}
+
+	// progress keeps the round waiting past the timeout.
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.roundtrip(ctx, &bitswap_message_pb.Message{}, progressInterest(2, round), pirInterest(2, round, 0))
+		done <- err
+	}()
+	for i := 1; i <= 5; i++ {
+		time.Sleep(50 * time.Millisecond)
+		deliver(t, s, bitswap_message_pb.Message{PirProgress: []bitswap_message_pb.Message_PIRProgress{{
+			Session: 2, Round: round, ElapsedMillis: uint64(50 * i),
+		}}})
+	}
+	deliver(t, s, bitswap_message_pb.Message{PirResponses: []bitswap_message_pb.Message_PIRResponse{{Session: 2, Round: round}}})
+	if err := <-done; err != nil {
+		t.Fatalf("progressing peer: got %v", err)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(reports) != 5 || reports[4].Elapsed != 250*time.Millisecond || reports[4].Session != 2 {
+		t.Fatalf("reported %v", reports)
+	}
+}
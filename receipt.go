@@ -0,0 +1,100 @@
+package bitswap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// ErrBadReceipt fails PIR rounds whose answer came with a receipt not signed
+// by the peer, or not for the query sent and the answer received.
+var ErrBadReceipt = errors.New("PIR receipt not signed by the peer")
+
+// Receipt is a peer's signed attestation that it answered a PIR query, from
+// its databases of one scheme at one epoch, with an answer. Clients holding
+// one can prove what they were served by the peer, such as an answer which
+// failed to decode, to others who know its key.
+type Receipt struct {
+	Scheme string
+	// Group is the content group the databases lay out, or cid.Undef for
+	// the peer's whole store.
+	Group cid.Cid
+	Epoch uint64
+	// Query and Answer are the SHA-256 digests of the query sent and of
+	// the whole answer received.
+	Query  []byte
+	Answer []byte
+	// Time is when the peer signed the receipt, by its clock.
+	Time      time.Time
+	Signature []byte
+}
+
+func receiptFrom(r bitswap_message_pb.Message_PIRReceipt) (Receipt, error) {
+	var group cid.Cid
+	if len(r.Group) > 0 {
+		var err error
+		if group, err = cid.Cast(r.Group); err != nil {
+			return Receipt{}, fmt.Errorf("%w: group: %v", ErrBadReceipt, err)
+		}
+	}
+	return Receipt{
+		Scheme:    r.Scheme,
+		Group:     group,
+		Epoch:     r.Epoch,
+		Query:     r.Query,
+		Answer:    r.Answer,
+		Time:      time.UnixMilli(int64(r.Timestamp)),
+		Signature: r.Signature,
+	}, nil
+}
+
+// Verify checks that r was signed with the private half of key.
+func (r Receipt) Verify(key crypto.PubKey) error {
+	var group []byte
+	if r.Group.Defined() {
+		group = r.Group.Bytes()
+	}
+	payload := bitswap_message_pb.ReceiptPayload(r.Scheme, group, r.Epoch, r.Query, r.Answer, uint64(r.Time.UnixMilli()))
+	ok, err := key.Verify(payload, r.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadReceipt, err)
+	}
+	if !ok {
+		return ErrBadReceipt
+	}
+	return nil
+}
+
+// checkReceipt verifies the receipt sent with r, the whole response to
+// query, whose answer as received, against the peer's key, and passes it to
+// the session's receipt hook. Peers which sign no receipts send none, and
+// are not failed for it.
+func (s *Session) checkReceipt(query []byte, as *answerStream, r bitswap_message_pb.Message_PIRResponse) error {
+	if r.Receipt == nil {
+		return nil
+	}
+	rc, err := receiptFrom(*r.Receipt)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(query)
+	if rc.Epoch != r.Epoch || !bytes.Equal(rc.Query, digest[:]) || !bytes.Equal(rc.Answer, as.digest.Sum(nil)) {
+		return fmt.Errorf("%w: receipt for another query or answer", ErrBadReceipt)
+	}
+	key, err := s.peerKey()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadReceipt, err)
+	}
+	if err := rc.Verify(key); err != nil {
+		return err
+	}
+	s.metrics.Add("pir_receipts", 1)
+	s.onReceipt(s.peer, rc)
+	return nil
+}
@@ -0,0 +1,81 @@
+package bitswap
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+func TestReceipt(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Receipt
+	s := New(nil, p, Options{OnReceipt: func(from peer.ID, r Receipt) {
+		if from != p {
+			t.Errorf("receipt reported from %s", from)
+		}
+		got = append(got, r)
+	}})
+	query, answer := []byte("query"), []byte("answer")
+	respond := func(query, answer []byte) (*answerStream, bitswap_message_pb.Message_PIRResponse) {
+		qd, ad := sha256.Sum256(query), sha256.Sum256(answer)
+		rc := &bitswap_message_pb.Message_PIRReceipt{Scheme: "test", Epoch: 3, Query: qd[:], Answer: ad[:], Timestamp: 1700000000123}
+		if rc.Signature, err = priv.Sign(rc.Payload()); err != nil {
+			t.Fatal(err)
+		}
+		as := &answerStream{digest: sha256.New()}
+		r := bitswap_message_pb.Message_PIRResponse{Answer: answer, Epoch: 3, Receipt: rc}
+		if _, err := as.add(r); err != nil {
+			t.Fatal(err)
+		}
+		return as, r
+	}
+
+	// receipts signed by the peer for the query and answer are passed on.
+	as, r := respond(query, answer)
+	if err := s.checkReceipt(query, as, r); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Epoch != 3 || !got[0].Time.Equal(time.UnixMilli(1700000000123)) {
+		t.Fatalf("reported receipts %v", got)
+	}
+	if key, _ := p.ExtractPublicKey(); got[0].Verify(key) != nil {
+		t.Fatal("reported receipt does not verify")
+	}
+
+	// but not those forged, or for another query or answer.
+	as, r = respond(query, answer)
+	r.Receipt.Signature[0] ^= 1
+	if err := s.checkReceipt(query, as, r); !errors.Is(err, ErrBadReceipt) {
+		t.Fatalf("forged receipt checked with %v", err)
+	}
+	as, r = respond([]byte("other query"), answer)
+	if err := s.checkReceipt(query, as, r); !errors.Is(err, ErrBadReceipt) {
+		t.Fatalf("receipt for another query checked with %v", err)
+	}
+	as, r = respond(query, []byte("other answer"))
+	r.Receipt.Answer = sha256.New().Sum(nil)
+	if err := s.checkReceipt(query, as, r); !errors.Is(err, ErrBadReceipt) {
+		t.Fatalf("receipt for another answer checked with %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("bad receipts reported: %v", got)
+	}
+
+	// peers signing no receipts are not failed for it.
+	r.Receipt = nil
+	if err := s.checkReceipt(query, as, r); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// RetryPolicy decides whether and when a Client retries a failed fetch.
+// Failures of the stream are always retried, on a new session, as are
+// answers from databases which changed since the handshake; whether
+// timeouts and missing blocks are is configurable. Misconfiguration and
+// invalid responses are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts. 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, multiplied by
+	// Multiplier for each one after, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes each wait by up to this fraction of it, in either
+	// direction, so clients failing together don't retry together.
+	Jitter float64
+
+	// AttemptTimeout, if set, bounds each attempt.
+	AttemptTimeout time.Duration
+	// RetryOnTimeout retries attempts which ran out of AttemptTimeout, or of
+	// the peer's ResponseTimeout or PingTimeout.
+	RetryOnTimeout bool
+	// RetryOnNotFound retries when the peer doesn't have the block, for
+	// peers which may still be fetching it themselves.
+	RetryOnNotFound bool
+}
+
+// DefaultRetryPolicy retries transient failures and timeouts a few times
+// within a couple of seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryOnTimeout: true,
+}
+
+// retryable reports whether err, from an attempt run under actx, should be
+// retried. ctx is the context of the whole fetch.
+func (rp RetryPolicy) retryable(ctx, actx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch {
+	case errors.Is(actx.Err(), context.DeadlineExceeded), errors.Is(err, ErrResponseTimeout), errors.Is(err, ErrPingTimeout):
+		return rp.RetryOnTimeout
+	case errors.Is(err, ErrNotFound):
+		return rp.RetryOnNotFound
+	case errors.Is(err, ErrNoScheme), errors.Is(err, ErrNoCommonScheme), errors.Is(err, ErrBadBlock), errors.Is(err, ErrCorruptPeer),
+		errors.Is(err, ErrBadRequest), errors.Is(err, pir.ErrSchemeMismatch), errors.Is(err, pir.ErrMalformed),
+		errors.Is(err, ErrBadManifest), errors.Is(err, ErrEquivocation), errors.Is(err, ErrUnauthorized), errors.Is(err, ErrBadReceipt):
+		return false
+	}
+	return true
+}
+
+// wait returns the jittered wait before the retry after one which waited
+// backoff, along with the backoff for the retry after that.
+func (rp RetryPolicy) wait(backoff time.Duration) (time.Duration, time.Duration) {
+	d := backoff
+	if rp.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + rp.Jitter*(2*rand.Float64()-1)))
+	}
+	next := time.Duration(float64(backoff) * rp.Multiplier)
+	if rp.MaxBackoff > 0 && next > rp.MaxBackoff {
+		next = rp.MaxBackoff
+	}
+	return d, next
+}
+
+// retry runs fetch against a session to p until it succeeds or the policy
+// gives up, returning the last error. Retries wait out their backoff, or the
+// wait a busy or rate limiting peer asked for if longer, unless a new
+// session to p is opened in the meantime.
+func (cl *Client) retry(ctx context.Context, p peer.ID, fetch func(context.Context, *Session) ([]byte, error)) ([]byte, error) {
+	rp := cl.opts.Retry
+	backoff := rp.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		actx, cncl := ctx, context.CancelFunc(func() {})
+		if rp.AttemptTimeout > 0 {
+			actx, cncl = context.WithTimeout(ctx, rp.AttemptTimeout)
+		}
+		var data []byte
+		s, err := cl.session(p)
+		reconnected := cl.wants.reconnected(p)
+		if err == nil {
+			data, err = fetch(actx, s)
+			cl.opts.Scores.record(p, actx, err)
+		}
+		retry := err != nil && attempt < rp.MaxAttempts && rp.retryable(ctx, actx, err)
+		cncl()
+		if !retry {
+			return data, err
+		}
+
+		var d time.Duration
+		d, backoff = rp.wait(backoff)
+		var pe *PeerError
+		if errors.As(err, &pe) && pe.RetryAfter > d {
+			// the peer said when it would have room.
+			d = pe.RetryAfter
+		}
+		logger.Debugw("retrying fetch", "peer", p, "attempt", attempt, "wait", d, "err", err)
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-reconnected:
+			// another fetch replaced the failed session; resend now.
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	rp := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 2}
+	ctx := context.Background()
+
+	if !rp.retryable(ctx, ctx, errors.New("stream reset")) {
+		t.Fatal("stream failures should be retried")
+	}
+	if rp.retryable(ctx, ctx, ErrNotFound) || rp.retryable(ctx, ctx, ErrBadBlock) {
+		t.Fatal("missing and corrupt blocks should not be retried by default")
+	}
+	rp.RetryOnNotFound = true
+	if !rp.retryable(ctx, ctx, ErrNotFound) {
+		t.Fatal("missing blocks should be retried when asked")
+	}
+
+	expired, cncl := context.WithTimeout(ctx, 0)
+	defer cncl()
+	if rp.retryable(ctx, expired, context.DeadlineExceeded) {
+		t.Fatal("timeouts should not be retried unless asked")
+	}
+	rp.RetryOnTimeout = true
+	if !rp.retryable(ctx, expired, context.DeadlineExceeded) {
+		t.Fatal("timeouts should be retried when asked")
+	}
+	if rp.retryable(expired, expired, context.DeadlineExceeded) {
+		t.Fatal("nothing should be retried once the caller gives up")
+	}
+
+	var waits []time.Duration
+	backoff := rp.InitialBackoff
+	for i := 0; i < 3; i++ {
+		var d time.Duration
+		d, backoff = rp.wait(backoff)
+		waits = append(waits, d)
+	}
+	if waits[0] != time.Second || waits[1] != 2*time.Second || waits[2] != 3*time.Second {
+		t.Fatalf("unexpected waits %v", waits)
+	}
+
+	rp.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d, _ := rp.wait(time.Second); d < time.Second/2 || d > 3*time.Second/2 {
+			t.Fatalf("jittered wait %v out of range", d)
+		}
+	}
+}
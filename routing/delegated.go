@@ -0,0 +1,191 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+)
+
+// delegatedResponse is the reply of a delegated routing server to a
+// provider lookup.
+type delegatedResponse struct {
+	Providers []delegatedRecord
+}
+
+// delegatedRecord is a provider record of the "peer" schema, or of the
+// older "bitswap" schema, which names one protocol rather than a list.
+type delegatedRecord struct {
+	Schema    string
+	ID        *peer.ID
+	Addrs     []string
+	Protocols []string
+	Protocol  string
+}
+
+// transportBitswap is the name delegated routing records give bitswap.
+const transportBitswap = "transport-bitswap"
+
+// DelegatedFinder looks up providers with the HTTP delegated routing API,
+// /routing/v1, as served by cid.contact and by IPFS nodes, and is a Finder.
+// It suits light clients, which would rather not run a DHT client. The
+// server learns which CID was looked up, as a DHT server would.
+type DelegatedFinder struct {
+	url    string
+	client *http.Client
+	ps     peerstore.Peerstore
+}
+
+// NewDelegatedFinder creates a finder querying the delegated routing server
+// at the base URL url. Providers whose records list bitswap.ProtocolPrivate
+// among their protocols are recorded as such in ps, if it is not nil, so
+// fetchers preferring PIR, or filtering with PIRCapable, know them before
+// dialling them.
+func NewDelegatedFinder(url string, ps peerstore.Peerstore) *DelegatedFinder {
+	return &DelegatedFinder{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: time.Minute},
+		ps:     ps,
+	}
+}
+
+// Find returns the providers of c retrieving over bitswap, or over
+// protocols the records do not say.
+func (f *DelegatedFinder) Find(ctx context.Context, c cid.Cid) ([]peer.AddrInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url+"/routing/v1/providers/"+c.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("delegated routing server replied %s", resp.Status)
+	}
+	var found delegatedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, err
+	}
+	var out []peer.AddrInfo
+	seen := make(map[peer.ID]bool)
+	for _, r := range found.Providers {
+		if r.ID == nil || seen[*r.ID] {
+			continue
+		}
+		protos := r.Protocols
+		switch r.Schema {
+		case "peer":
+		case "bitswap":
+			protos = []string{r.Protocol}
+		default:
+			continue
+		}
+		bitswapped, private := len(protos) == 0, false
+		for _, p := range protos {
+			bitswapped = bitswapped || p == transportBitswap || p == string(bitswap.ProtocolPrivate)
+			private = private || p == string(bitswap.ProtocolPrivate)
+		}
+		if !bitswapped {
+			continue
+		}
+		ai := peer.AddrInfo{ID: *r.ID}
+		for _, s := range r.Addrs {
+			if a, err := multiaddr.NewMultiaddr(s); err == nil {
+				ai.Addrs = append(ai.Addrs, a)
+			}
+		}
+		if private && f.ps != nil {
+			if err := f.ps.AddProtocols(ai.ID, bitswap.ProtocolPrivate); err != nil {
+				logger.Debugw("failed to record provider protocols", "peer", ai.ID, "err", err)
+			}
+		}
+		seen[ai.ID] = true
+		out = append(out, ai)
+	}
+	return out, nil
+}
+
+// FindProvidersAsync returns up to count of the providers Find returns, or
+// all of them if count is 0. Failed lookups find none.
+func (f *DelegatedFinder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		found, err := f.Find(ctx, c)
+		if err != nil {
+			logger.Warnw("delegated routing lookup failed", "server", f.url, "cid", c, "err", err)
+			return
+		}
+		for i, ai := range found {
+			if count > 0 && i >= count {
+				return
+			}
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+type pirFirstFinder struct {
+	finder Finder
+	ps     peerstore.Peerstore
+}
+
+// PIRFirst orders the providers found by finder so that those known from ps
+// to answer private retrievals come first, those not identified yet next,
+// and those known not to last. The providers are gathered before any is
+// returned, so the first is returned only once the lookup completes.
+func PIRFirst(finder Finder, ps peerstore.Peerstore) Finder {
+	return &pirFirstFinder{finder: finder, ps: ps}
+}
+
+func (f *pirFirstFinder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		var found []peer.AddrInfo
+		for ai := range f.finder.FindProvidersAsync(ctx, c, count) {
+			found = append(found, ai)
+		}
+		rank := func(p peer.ID) int {
+			switch supports, known := SupportsPIR(f.ps, p); {
+			case supports:
+				return 0
+			case !known:
+				return 1
+			default:
+				return 2
+			}
+		}
+		sort.SliceStable(found, func(i, j int) bool {
+			return rank(found[i].ID) < rank(found[j].ID)
+		})
+		for _, ai := range found {
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,87 @@
+package routing_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/multiformats/go-multihash"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+)
+
+func newPeer(t *testing.T) peer.ID {
+	t.Helper()
+	_, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDelegatedFinder(t *testing.T) {
+	h, err := multihash.Sum([]byte("block"), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cid.NewCidV1(cid.Raw, h)
+	plain, private, legacy, other := newPeer(t), newPeer(t), newPeer(t), newPeer(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/routing/v1/providers/"+c.String() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Providers": []map[string]interface{}{
+			{"Schema": "peer", "ID": plain, "Addrs": []string{"/ip4/127.0.0.1/tcp/4001"}, "Protocols": []string{"transport-bitswap"}},
+			{"Schema": "peer", "ID": other, "Protocols": []string{"transport-ipfs-gateway-http"}},
+			{"Schema": "bitswap", "ID": legacy, "Protocol": "transport-bitswap"},
+			{"Schema": "peer", "ID": private, "Protocols": []string{string(bitswap.ProtocolPrivate)}},
+		}})
+	}))
+	defer server.Close()
+
+	ps, err := pstoremem.NewPeerstore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+	finder := routing.NewDelegatedFinder(server.URL, ps)
+
+	// providers retrieving over other protocols are left out, and those
+	// declaring private retrieval recorded as answering it.
+	found, err := finder.Find(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || found[0].ID != plain || len(found[0].Addrs) != 1 || found[1].ID != legacy || found[2].ID != private {
+		t.Fatalf("found %v", found)
+	}
+	if supports, _ := routing.SupportsPIR(ps, private); !supports {
+		t.Fatal("provider declaring private retrieval not recorded as answering it")
+	}
+
+	// PIRFirst puts the providers known to answer private retrievals first,
+	// and those known not to last.
+	if err := ps.SetProtocols(legacy, bitswap.ProtocolBitswap); err != nil {
+		t.Fatal(err)
+	}
+	var order []peer.ID
+	for ai := range routing.PIRFirst(finder, ps).FindProvidersAsync(context.Background(), c, 0) {
+		order = append(order, ai.ID)
+	}
+	if len(order) != 3 || order[0] != private || order[1] != plain || order[2] != legacy {
+		t.Fatalf("providers ordered %v", order)
+	}
+}
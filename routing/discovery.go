@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+)
+
+// SupportsPIR reports whether p answers private retrievals, as far as the
+// protocols identify recorded for it in ps tell, and whether they tell at
+// all: peers never connected to have no protocols recorded.
+func SupportsPIR(ps peerstore.Peerstore, p peer.ID) (supports, known bool) {
+	protos, err := ps.GetProtocols(p)
+	if err != nil || len(protos) == 0 {
+		return false, false
+	}
+	for _, proto := range protos {
+		if proto == bitswap.ProtocolPrivate {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+type pirFinder struct {
+	finder Finder
+	ps     peerstore.Peerstore
+	strict bool
+}
+
+// PIRCapable filters the providers found by finder to those which may
+// answer private retrievals, so plain bitswap peers are not dialled for
+// nothing. Providers known from ps not to speak bitswap.ProtocolPrivate are
+// dropped; those not identified yet are kept, unless strict. Older servers
+// answering private retrievals on the bitswap protocol are dropped too.
+//
+// Indexers tell which providers answer private retrievals before they are
+// dialled, so an ipni.Finder needs no such filter.
+func PIRCapable(finder Finder, ps peerstore.Peerstore, strict bool) Finder {
+	return &pirFinder{finder: finder, ps: ps, strict: strict}
+}
+
+func (f *pirFinder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		ctx, cncl := context.WithCancel(ctx)
+		defer cncl()
+		// the filtered providers do not count towards count.
+		found := 0
+		for ai := range f.finder.FindProvidersAsync(ctx, c, 0) {
+			supports, known := SupportsPIR(f.ps, ai.ID)
+			if !supports && (known || f.strict) {
+				logger.Debugw("skipping provider without PIR", "peer", ai.ID, "cid", c)
+				continue
+			}
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+			if found++; count > 0 && found >= count {
+				return
+			}
+		}
+	}()
+	return out
+}
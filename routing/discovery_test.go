@@ -0,0 +1,118 @@
+package routing_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+)
+
+// endless is a Finder reporting its providers over and over until the
+// lookup is cancelled, which closes stopped.
+type endless struct {
+	providers
+	stopped chan struct{}
+}
+
+func (e *endless) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		defer close(e.stopped)
+		for {
+			for _, ai := range e.providers {
+				select {
+				case out <- ai:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// found returns the peers f finds, of at most count.
+func found(t *testing.T, f routing.Finder, count int) []peer.ID {
+	t.Helper()
+	var out []peer.ID
+	for ai := range f.FindProvidersAsync(context.Background(), rawCid(t, "provided"), count) {
+		out = append(out, ai.ID)
+	}
+	return out
+}
+
+// seeded returns a peerstore which identified private as answering private
+// retrievals, and plain as speaking only bitswap.
+func seeded(t *testing.T, private, plain peer.ID) peerstore.Peerstore {
+	t.Helper()
+	ps, err := pstoremem.NewPeerstore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ps.Close() })
+	if err := ps.SetProtocols(private, bitswap.ProtocolBitswap, bitswap.ProtocolPrivate); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.SetProtocols(plain, bitswap.ProtocolBitswap); err != nil {
+		t.Fatal(err)
+	}
+	return ps
+}
+
+func TestSupportsPIR(t *testing.T) {
+	private, plain, unknown := newPeer(t), newPeer(t), newPeer(t)
+	ps := seeded(t, private, plain)
+	for _, tc := range []struct {
+		p                       peer.ID
+		wantSupports, wantKnown bool
+	}{
+		{private, true, true},
+		{plain, false, true},
+		{unknown, false, false},
+	} {
+		if supports, known := routing.SupportsPIR(ps, tc.p); supports != tc.wantSupports || known != tc.wantKnown {
+			t.Fatalf("%s: supports %v, known %v", tc.p, supports, known)
+		}
+	}
+}
+
+func TestPIRCapable(t *testing.T) {
+	private, plain, unknown, other := newPeer(t), newPeer(t), newPeer(t), newPeer(t)
+	ps := seeded(t, private, plain)
+	if err := ps.SetProtocols(other, bitswap.ProtocolPrivate); err != nil {
+		t.Fatal(err)
+	}
+	f := providers{{ID: plain}, {ID: private}, {ID: unknown}, {ID: other}}
+
+	// known plain peers are dropped, and unidentified ones kept,
+	if got := found(t, routing.PIRCapable(f, ps, false), 0); !reflect.DeepEqual(got, []peer.ID{private, unknown, other}) {
+		t.Fatalf("found %v", got)
+	}
+	// unless strict.
+	if got := found(t, routing.PIRCapable(f, ps, true), 0); !reflect.DeepEqual(got, []peer.ID{private, other}) {
+		t.Fatalf("found %v strictly", got)
+	}
+	// count bounds the providers passing the filter only.
+	if got := found(t, routing.PIRCapable(f, ps, true), 2); !reflect.DeepEqual(got, []peer.ID{private, other}) {
+		t.Fatalf("found %v of 2", got)
+	}
+
+	// once count are found, the lookup behind the filter is cancelled.
+	e := &endless{providers: providers{{ID: plain}, {ID: private}}, stopped: make(chan struct{})}
+	if got := found(t, routing.PIRCapable(e, ps, false), 3); !reflect.DeepEqual(got, []peer.ID{private, private, private}) {
+		t.Fatalf("found %v of 3", got)
+	}
+	select {
+	case <-e.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lookup not cancelled once count providers were found")
+	}
+}
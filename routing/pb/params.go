@@ -0,0 +1,25 @@
+package routing_pb
+
+import (
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// NewParams converts the public parameters of a PIR database for the wire.
+func NewParams(p pir.Params) Message_Params {
+	return Message_Params{
+		Scheme:      p.Scheme,
+		NumElements: p.NumElements,
+		ElementSize: p.ElementSize,
+		Extra:       p.Extra,
+	}
+}
+
+// Params converts wire parameters back to their pir form.
+func (m Message_Params) Params() pir.Params {
+	return pir.Params{
+		Scheme:      m.Scheme,
+		NumElements: m.NumElements,
+		ElementSize: m.ElementSize,
+		Extra:       m.Extra,
+	}
+}
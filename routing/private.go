@@ -0,0 +1,208 @@
+package routing
+
+import (
+	"bufio"
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+	pb "github.com/willscott/go-selfish-bitswap-client/routing/pb"
+)
+
+// PrivateFinder looks up provider records on a set of DHT servers with PIR,
+// so that the servers do not learn which CID was looked up. Every server is
+// asked about every CID, as choosing servers by their distance to the CID
+// would itself reveal it.
+type PrivateFinder struct {
+	host    host.Host
+	scheme  pir.Scheme
+	servers []peer.ID
+}
+
+// NewPrivateFinder creates a finder querying servers through h with scheme.
+func NewPrivateFinder(h host.Host, scheme pir.Scheme, servers ...peer.ID) *PrivateFinder {
+	return &PrivateFinder{host: h, scheme: scheme, servers: servers}
+}
+
+// FindProvidersAsync looks c up on all servers at once, returning up to
+// count distinct providers, or all of them if count is 0.
+func (f *PrivateFinder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		ctx, cncl := context.WithCancel(ctx)
+		defer cncl()
+
+		found := make(chan []peer.AddrInfo, len(f.servers))
+		var wg sync.WaitGroup
+		for _, s := range f.servers {
+			wg.Add(1)
+			go func(s peer.ID) {
+				defer wg.Done()
+				providers, err := f.Lookup(ctx, s, c)
+				if err != nil {
+					logger.Debugw("private lookup failed", "server", s, "err", err)
+					return
+				}
+				found <- providers
+			}(s)
+		}
+		go func() {
+			wg.Wait()
+			close(found)
+		}()
+
+		seen := make(map[peer.ID]struct{})
+		for providers := range found {
+			for _, ai := range providers {
+				if _, ok := seen[ai.ID]; ok {
+					continue
+				}
+				seen[ai.ID] = struct{}{}
+				select {
+				case out <- ai:
+				case <-ctx.Done():
+					return
+				}
+				if count > 0 && len(seen) >= count {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Lookup privately retrieves the providers of c known to server. It returns
+// no providers, and no error, if the server has none.
+//
+// The first round retrieves every slot of the server's keyword index which
+// may hold c, and the second the records at the position found. As with
+// block retrieval, the second round runs even on a miss.
+func (f *PrivateFinder) Lookup(ctx context.Context, server peer.ID, c cid.Cid) ([]peer.AddrInfo, error) {
+	s, err := f.host.NewStream(ctx, server, ProtocolPrivateProviders)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Reset()
+		case <-done:
+		}
+	}()
+	rd := bufio.NewReader(s)
+
+	resp, err := roundtrip(s, rd, &pb.Message{Handshake: &pb.Message_Handshake{}})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Handshake == nil {
+		return nil, pir.ErrMalformed
+	}
+	index, records := resp.Handshake.Index.Params(), resp.Handshake.Records.Params()
+	if index.Scheme != f.scheme.ID() || records.Scheme != f.scheme.ID() {
+		return nil, pir.ErrSchemeMismatch
+	}
+
+	key := []byte(c.Hash())
+	slots := keyword.Slots(key, index.NumElements)
+	found, err := f.round(s, rd, pb.Message_Index, index, slots[:]...)
+	if err != nil {
+		return nil, err
+	}
+	pos, ok := uint64(0), false
+	for _, slot := range found {
+		if pos, ok = keyword.Find(slot, key); ok {
+			break
+		}
+	}
+	if !ok || pos >= records.NumElements {
+		pos = 0
+		ok = false
+	}
+	elements, err := f.round(s, rd, pb.Message_Records, records, pos)
+	if err != nil || !ok {
+		return nil, err
+	}
+	data, err := pir.UnpadBlock(elements[0])
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecords(data)
+}
+
+// round sends one query per index and decodes the answers.
+func (f *PrivateFinder) round(s network.Stream, rd *bufio.Reader, round pb.Message_Round, params pir.Params, indices ...uint64) ([][]byte, error) {
+	m := pb.Message{}
+	secrets := make([]pir.Secret, len(indices))
+	for i, index := range indices {
+		q, secret, err := f.scheme.Query(params, index)
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = secret
+		m.Queries = append(m.Queries, pb.Message_Query{Round: round, Part: uint32(i), Query: q})
+	}
+	resp, err := roundtrip(s, rd, &m)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Answers) != len(indices) {
+		return nil, pir.ErrMalformed
+	}
+	out := make([][]byte, len(indices))
+	for _, a := range resp.Answers {
+		if a.Round != round || int(a.Part) >= len(indices) || out[a.Part] != nil {
+			return nil, pir.ErrMalformed
+		}
+		if out[a.Part], err = f.scheme.Decode(params, secrets[a.Part], a.Answer); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func roundtrip(s network.Stream, rd *bufio.Reader, m *pb.Message) (*pb.Message, error) {
+	if err := writeMessage(s, m); err != nil {
+		return nil, err
+	}
+	resp := &pb.Message{}
+	if err := readMessage(rd, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func decodeRecords(data []byte) ([]peer.AddrInfo, error) {
+	rec := pb.ProviderRecords{}
+	if err := rec.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	out := make([]peer.AddrInfo, 0, len(rec.Providers))
+	for _, p := range rec.Providers {
+		id, err := peer.IDFromBytes(p.Id)
+		if err != nil {
+			return nil, err
+		}
+		ai := peer.AddrInfo{ID: id}
+		for _, a := range p.Addrs {
+			addr, err := ma.NewMultiaddrBytes(a)
+			if err != nil {
+				return nil, err
+			}
+			ai.Addrs = append(ai.Addrs, addr)
+		}
+		out = append(out, ai)
+	}
+	return out, nil
+}
@@ -0,0 +1,86 @@
+package routing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/xorpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+)
+
+func rawCid(t *testing.T, data string) cid.Cid {
+	t.Helper()
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func TestPrivateLookup(t *testing.T) {
+	mn := mocknet.New()
+	defer mn.Close()
+	server, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := fastpir.New()
+	records := routing.NewRecords(scheme, pirstore.Options{})
+	held, missing := rawCid(t, "held"), rawCid(t, "missing")
+	provider, other := newPeer(t), newPeer(t)
+	addr := multiaddr.StringCast("/ip4/127.0.0.1/tcp/4001")
+	if err := records.Put(held, []peer.AddrInfo{{ID: other}}); err != nil {
+		t.Fatal(err)
+	}
+	// put again, the records replace those put before.
+	if err := records.Put(held, []peer.AddrInfo{{ID: provider, Addrs: []multiaddr.Multiaddr{addr}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := records.Put(rawCid(t, "elsewhere"), []peer.AddrInfo{{ID: other}}); err != nil {
+		t.Fatal(err)
+	}
+	routing.AttachPrivateProviderServer(server, records)
+
+	ctx := context.Background()
+	finder := routing.NewPrivateFinder(client, scheme, server.ID())
+	ais, err := finder.Lookup(ctx, server.ID(), held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ais) != 1 || ais[0].ID != provider || len(ais[0].Addrs) != 1 || !ais[0].Addrs[0].Equal(addr) {
+		t.Fatalf("looked up %v, want %s at %s", ais, provider, addr)
+	}
+
+	if ais, err := finder.Lookup(ctx, server.ID(), missing); err != nil || len(ais) != 0 {
+		t.Fatalf("lookup of a CID without records returned %v, %v", ais, err)
+	}
+	var found []peer.AddrInfo
+	for ai := range finder.FindProvidersAsync(ctx, held, 0) {
+		found = append(found, ai)
+	}
+	if len(found) != 1 || found[0].ID != provider {
+		t.Fatalf("found %v, want %s", found, provider)
+	}
+
+	mismatched := routing.NewPrivateFinder(client, xorpir.New(), server.ID())
+	if _, err := mismatched.Lookup(ctx, server.ID(), held); !errors.Is(err, pir.ErrSchemeMismatch) {
+		t.Fatalf("lookup with another scheme returned %v", err)
+	}
+}
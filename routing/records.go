@@ -0,0 +1,163 @@
+package routing
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	pb "github.com/willscott/go-selfish-bitswap-client/routing/pb"
+)
+
+const (
+	// ProtocolPrivateProviders is the protocol on which provider records are
+	// looked up with PIR.
+	ProtocolPrivateProviders protocol.ID = "/dhtpir/providers/1.0.0"
+
+	// MaxMessageSize bounds the messages of the lookup protocol. PIR answers
+	// are much larger than the records they carry.
+	MaxMessageSize = 16 * 1024 * 1024
+	// LookupTimeout bounds the life of a lookup stream.
+	LookupTimeout = time.Minute
+)
+
+var ErrMessageTooLarge = errors.New("message exceeds maximum size")
+
+// Records holds the provider records of a DHT server laid out for private
+// lookup, keyed by CID.
+type Records struct {
+	store *pirstore.Store
+}
+
+// NewRecords creates an empty set of records encoded with scheme.
+func NewRecords(scheme pir.Scheme, opts pirstore.Options) *Records {
+	return &Records{store: pirstore.New(scheme, opts)}
+}
+
+// Put sets the providers of c, replacing any previously put.
+func (r *Records) Put(c cid.Cid, providers []peer.AddrInfo) error {
+	rec := pb.ProviderRecords{}
+	for _, ai := range providers {
+		p := pb.ProviderRecords_Provider{Id: []byte(ai.ID)}
+		for _, a := range ai.Addrs {
+			p.Addrs = append(p.Addrs, a.Bytes())
+		}
+		rec.Providers = append(rec.Providers, p)
+	}
+	data, err := rec.Marshal()
+	if err != nil {
+		return err
+	}
+	return r.store.Replace(c, data)
+}
+
+// Remove forgets the providers of c.
+func (r *Records) Remove(c cid.Cid) error {
+	return r.store.Remove(c)
+}
+
+// AttachPrivateProviderServer answers private lookups of records on h.
+func AttachPrivateProviderServer(h host.Host, records *Records) {
+	h.SetStreamHandler(ProtocolPrivateProviders, records.onStream)
+}
+
+func (r *Records) onStream(s network.Stream) {
+	defer s.Close()
+	if err := s.SetDeadline(time.Now().Add(LookupTimeout)); err != nil {
+		// transports without deadlines have the stream reset instead.
+		logger.Debugw("failed to set lookup deadline", "peer", s.Conn().RemotePeer(), "err", err)
+		t := time.AfterFunc(LookupTimeout, func() { _ = s.Reset() })
+		defer t.Stop()
+	}
+	rd := bufio.NewReader(s)
+	// every round of the lookup is answered from the layout of the first.
+	var snap *pirstore.Snapshot
+	for {
+		m := pb.Message{}
+		if err := readMessage(rd, &m); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logger.Debugw("failed to read lookup", "peer", s.Conn().RemotePeer(), "err", err)
+			}
+			return
+		}
+		if snap == nil {
+			var err error
+			if snap, err = r.store.Snapshot(); err != nil {
+				logger.Warnw("failed to encode provider records", "err", err)
+				_ = s.Reset()
+				return
+			}
+		}
+		resp, err := r.answer(snap, &m)
+		if err != nil {
+			logger.Debugw("failed to answer lookup", "peer", s.Conn().RemotePeer(), "err", err)
+			_ = s.Reset()
+			return
+		}
+		if err := writeMessage(s, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (r *Records) answer(snap *pirstore.Snapshot, m *pb.Message) (*pb.Message, error) {
+	resp := &pb.Message{}
+	if m.Handshake != nil {
+		resp.Handshake = &pb.Message_Handshake{
+			Index:   pb.NewParams(snap.Index.Params),
+			Records: pb.NewParams(snap.Blocks.Params),
+		}
+	}
+	for _, q := range m.Queries {
+		var db *pir.Encoded
+		switch q.Round {
+		case pb.Message_Index:
+			db = snap.Index
+		case pb.Message_Records:
+			db = snap.Blocks
+		default:
+			return nil, fmt.Errorf("unknown round %d", q.Round)
+		}
+		a, err := r.store.Scheme().Answer(db, q.Query)
+		if err != nil {
+			return nil, err
+		}
+		resp.Answers = append(resp.Answers, pb.Message_Answer{Round: q.Round, Part: q.Part, Answer: a})
+	}
+	return resp, nil
+}
+
+func readMessage(r *bufio.Reader, m *pb.Message) error {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if n > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	return m.Unmarshal(buf)
+}
+
+func writeMessage(w io.Writer, m *pb.Message) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(data))
+	n := binary.PutUvarint(buf, uint64(len(data)))
+	_, err = w.Write(append(buf[:n], data...))
+	return err
+}
@@ -0,0 +1,134 @@
+// Package routing retrieves content without knowing in advance which peer
+// holds it, by looking up providers and fetching from them in turn.
+//
+// Providers may be found with any libp2p content router, or privately with a
+// PrivateFinder, which queries DHT servers running
+// AttachPrivateProviderServer over their own protocol.
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+)
+
+// DefaultMaxProviders is the number of providers tried when Options does not
+// say otherwise.
+const DefaultMaxProviders = 10
+
+var ErrNoProviders = errors.New("no providers found")
+
+var logger = logging.Logger("bitswap-routing")
+
+// Finder discovers the peers providing a CID. A libp2p ContentRouting, such
+// as the Kademlia DHT, is a Finder.
+type Finder interface {
+	FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo
+}
+
+type Options struct {
+	// MaxProviders bounds the number of providers looked up and tried.
+	MaxProviders int
+	// Private fetches blocks with PrivateGet, so providers do not learn which
+	// CID was retrieved. The provider lookup itself is only as private as
+	// the Finder. Providers known not to answer private retrievals are
+	// skipped, as by PIRCapable.
+	Private bool
+	// OnlyIdentified skips, when Private, the providers not yet known to
+	// answer private retrievals too, rather than dialling them to find out.
+	OnlyIdentified bool
+	// ProviderTimeout bounds the attempt on each provider. If zero, a
+	// provider may take as long as the context given to Get allows.
+	ProviderTimeout time.Duration
+	// DialBudget bounds the providers each Get may dial, those already
+	// connected to costing nothing, so a lookup returning many unreachable
+	// or unwilling providers does not open connections to all of them. If
+	// zero, every provider looked up may be dialled.
+	DialBudget int
+	// PreferPIR tries the providers known to answer private retrievals
+	// before the others, as ordered by PIRFirst. Without Private, the others
+	// are still tried if those fail. Lookups are waited for in full, so it
+	// suits finders which return all their providers at once, such as a
+	// DelegatedFinder or an ipni.Finder, better than a DHT.
+	PreferPIR bool
+}
+
+// Fetcher retrieves blocks from whichever peers a Finder reports as
+// providing them.
+type Fetcher struct {
+	finder Finder
+	client *bitswap.Client
+	opts   Options
+}
+
+// New creates a fetcher looking up providers with finder and retrieving
+// blocks from them with client.
+func New(finder Finder, client *bitswap.Client, opts Options) *Fetcher {
+	if opts.MaxProviders <= 0 {
+		opts.MaxProviders = DefaultMaxProviders
+	}
+	if opts.Private {
+		finder = PIRCapable(finder, client.Host().Peerstore(), opts.OnlyIdentified)
+	}
+	if opts.PreferPIR {
+		finder = PIRFirst(finder, client.Host().Peerstore())
+	}
+	return &Fetcher{finder: finder, client: client, opts: opts}
+}
+
+// Get looks up the providers of c and fetches it from the first one which
+// returns it. If none do, the error of the last attempt is returned.
+func (f *Fetcher) Get(ctx context.Context, c cid.Cid) ([]byte, error) {
+	ctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+
+	h := f.client.Host()
+	var lastErr error
+	dials := 0
+	for ai := range f.finder.FindProvidersAsync(ctx, c, f.opts.MaxProviders) {
+		if ai.ID == h.ID() {
+			continue
+		}
+		if f.opts.DialBudget > 0 && h.Network().Connectedness(ai.ID) != network.Connected {
+			if dials >= f.opts.DialBudget {
+				logger.Debugw("dial budget spent, skipping provider", "peer", ai.ID, "cid", c)
+				continue
+			}
+			dials++
+		}
+		h.Peerstore().AddAddrs(ai.ID, ai.Addrs, peerstore.TempAddrTTL)
+		data, err := f.fetch(ctx, ai.ID, c)
+		if err == nil {
+			return data, nil
+		}
+		logger.Debugw("provider failed", "peer", ai.ID, "cid", c, "err", err)
+		lastErr = fmt.Errorf("fetch from %s: %w", ai.ID, err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if lastErr == nil {
+		return nil, ErrNoProviders
+	}
+	return nil, lastErr
+}
+
+func (f *Fetcher) fetch(ctx context.Context, p peer.ID, c cid.Cid) ([]byte, error) {
+	if f.opts.ProviderTimeout > 0 {
+		var cncl context.CancelFunc
+		ctx, cncl = context.WithTimeout(ctx, f.opts.ProviderTimeout)
+		defer cncl()
+	}
+	if f.opts.Private {
+		return f.client.PrivateGet(ctx, p, c)
+	}
+	return f.client.Get(ctx, p, c)
+}
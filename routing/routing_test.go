@@ -0,0 +1,122 @@
+package routing_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/routing"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// providers is a Finder reporting the same providers for every CID, at most
+// count of them if count is positive.
+type providers []peer.AddrInfo
+
+func (ps providers) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		for i, ai := range ps {
+			if count > 0 && i == count {
+				return
+			}
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// newHosts returns n linked hosts.
+func newHosts(t *testing.T, n int) []host.Host {
+	t.Helper()
+	mn := mocknet.New()
+	t.Cleanup(func() { mn.Close() })
+	hs := make([]host.Host, n)
+	for i := range hs {
+		h, err := mn.GenPeer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hs[i] = h
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	return hs
+}
+
+func addrInfo(h host.Host) peer.AddrInfo {
+	return peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}
+}
+
+func TestFetcher(t *testing.T) {
+	hs := newHosts(t, 5)
+	self, holder, empty, mute, stalled := hs[0], hs[1], hs[2], hs[3], hs[4]
+	full := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(full, []byte("routed"))
+	if err := bitswapserver.AttachBitswapServer(holder, full); err != nil {
+		t.Fatal(err)
+	}
+	if err := bitswapserver.AttachBitswapServer(empty, util.NewMemStore(make(map[cid.Cid][]byte))); err != nil {
+		t.Fatal(err)
+	}
+	// mute speaks no bitswap, and stalled reads wants without answering.
+	release := make(chan struct{})
+	defer close(release)
+	stalled.SetStreamHandler(bitswap.ProtocolBitswap, func(s network.Stream) {
+		<-release
+		_ = s.Reset()
+	})
+
+	cl := bitswap.NewClient(self, bitswap.Options{Retry: bitswap.RetryPolicy{MaxAttempts: 1}})
+	defer cl.Close()
+	ctx, cncl := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cncl()
+	get := func(opts routing.Options, ps ...host.Host) ([]byte, error) {
+		var f providers
+		for _, h := range ps {
+			f = append(f, addrInfo(h))
+		}
+		return routing.New(f, cl, opts).Get(ctx, c)
+	}
+
+	// providers failing are passed over for the next,
+	if data, err := get(routing.Options{}, mute, empty, holder); err != nil || string(data) != "routed" {
+		t.Fatalf("should fetch from the last provider, got %q %v", data, err)
+	}
+	// the fetcher's own host is never asked,
+	if _, err := get(routing.Options{}, self); !errors.Is(err, routing.ErrNoProviders) {
+		t.Fatalf("should skip itself and find no providers, got %v", err)
+	}
+	if _, err := get(routing.Options{}); !errors.Is(err, routing.ErrNoProviders) {
+		t.Fatalf("should find no providers, got %v", err)
+	}
+	// and if all fail, the error of the last is returned.
+	_, err := get(routing.Options{}, mute, empty)
+	if !errors.Is(err, bitswap.ErrNotFound) || !strings.Contains(err.Error(), empty.ID().String()) {
+		t.Fatalf("should fail with the error of the last provider, got %v", err)
+	}
+
+	// a stalled provider is given up on after ProviderTimeout.
+	start := time.Now()
+	if data, err := get(routing.Options{ProviderTimeout: 100 * time.Millisecond}, stalled, holder); err != nil || string(data) != "routed" {
+		t.Fatalf("should fetch past the stalled provider, got %q %v", data, err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond || d > 5*time.Second {
+		t.Fatalf("stalled provider given up on after %s", d)
+	}
+}
@@ -0,0 +1,118 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+// ScoreParams weigh the outcomes of fetches from a peer in its score.
+// Rewards are positive and penalties negative.
+type ScoreParams struct {
+	// Success is added for each block fetched.
+	Success float64
+	// Timeout is added for each attempt which ran out of time.
+	Timeout float64
+	// Malformed is added for each PIR response which could not be parsed
+	// or decoded.
+	Malformed float64
+	// Invalid is added for each block which did not match its CID, and
+	// each manifest which was badly signed or conflicted with another.
+	Invalid float64
+	// HalfLife is the time over which a score decays to half, so peers
+	// recover from old failures and don't coast on old successes.
+	HalfLife time.Duration
+}
+
+// DefaultScoreParams forgive a timeout for a couple of successes, but a
+// peer sending corrupt data is demoted for much longer.
+var DefaultScoreParams = ScoreParams{
+	Success:   1,
+	Timeout:   -2,
+	Malformed: -10,
+	Invalid:   -20,
+	HalfLife:  10 * time.Minute,
+}
+
+// Scores rates peers by how they served past fetches. Peers start at zero,
+// rise with each block they return and sink with each timeout, malformed PIR
+// response or invalid block. Scores may be shared between clients, and are
+// safe for concurrent use.
+type Scores struct {
+	params ScoreParams
+
+	mtx   sync.Mutex
+	peers map[peer.ID]*score
+}
+
+type score struct {
+	value float64
+	at    time.Time
+}
+
+// NewScores creates scores weighing outcomes with params.
+func NewScores(params ScoreParams) *Scores {
+	return &Scores{params: params, peers: make(map[peer.ID]*score)}
+}
+
+// Score returns the current score of p.
+func (sc *Scores) Score(p peer.ID) float64 {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	s, ok := sc.peers[p]
+	if !ok {
+		return 0
+	}
+	return sc.decayed(s, time.Now())
+}
+
+// decayed returns the value of s at now. The caller holds mtx.
+func (sc *Scores) decayed(s *score, now time.Time) float64 {
+	if sc.params.HalfLife <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(now.Sub(s.at))/float64(sc.params.HalfLife))
+}
+
+// set sets the score of p to v, as it was at at.
+func (sc *Scores) set(p peer.ID, v float64, at time.Time) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	sc.peers[p] = &score{value: v, at: at}
+}
+
+// add adds v to the score of p.
+func (sc *Scores) add(p peer.ID, v float64) {
+	if v == 0 {
+		return
+	}
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	now := time.Now()
+	s, ok := sc.peers[p]
+	if !ok {
+		s = &score{}
+		sc.peers[p] = s
+	}
+	s.value = sc.decayed(s, now) + v
+	s.at = now
+}
+
+// record scores p for an attempt run under actx which ended with err.
+func (sc *Scores) record(p peer.ID, actx context.Context, err error) {
+	switch {
+	case err == nil:
+		sc.add(p, sc.params.Success)
+	case errors.Is(actx.Err(), context.DeadlineExceeded), errors.Is(err, ErrResponseTimeout), errors.Is(err, ErrPingTimeout):
+		sc.add(p, sc.params.Timeout)
+	case errors.Is(err, ErrBadBlock), errors.Is(err, ErrCorruptPeer), errors.Is(err, ErrBadManifest), errors.Is(err, ErrEquivocation):
+		sc.add(p, sc.params.Invalid)
+	case errors.Is(err, pir.ErrMalformed):
+		sc.add(p, sc.params.Malformed)
+	}
+}
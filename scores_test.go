@@ -0,0 +1,64 @@
+package bitswap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+func TestScores(t *testing.T) {
+	sc := NewScores(ScoreParams{Success: 1, Timeout: -2, Malformed: -10, Invalid: -20})
+	ctx := context.Background()
+	expired, cncl := context.WithTimeout(ctx, 0)
+	defer cncl()
+	<-expired.Done()
+
+	for _, tc := range []struct {
+		actx context.Context
+		err  error
+		want float64
+	}{
+		{ctx, nil, 1},
+		{ctx, ErrNotFound, 0},
+		{ctx, errors.New("stream reset"), 0},
+		{expired, context.DeadlineExceeded, -2},
+		{ctx, ErrResponseTimeout, -2},
+		{ctx, fmt.Errorf("%w: response", pir.ErrMalformed), -10},
+		{ctx, ErrBadBlock, -20},
+		{ctx, ErrCorruptPeer, -20},
+	} {
+		p := peer.ID(fmt.Sprint(tc.err))
+		sc.record(p, tc.actx, tc.err)
+		if s := sc.Score(p); s != tc.want {
+			t.Fatalf("%v: scored %v, expected %v", tc.err, s, tc.want)
+		}
+	}
+}
+
+func TestScoresDecay(t *testing.T) {
+	sc := NewScores(ScoreParams{Invalid: -20, HalfLife: time.Minute})
+	sc.record("p", context.Background(), ErrBadBlock)
+	sc.peers["p"].at = time.Now().Add(-time.Minute)
+	if s := sc.Score("p"); s < -10.1 || s > -9.9 {
+		t.Fatalf("expected the score to halve over its half life, got %v", s)
+	}
+}
+
+func TestMultiSessionPrefersScored(t *testing.T) {
+	cl := NewClient(nil, Options{})
+	cl.Scores().add("good", 5)
+	cl.Scores().add("bad", -5)
+	m := cl.NewMultiSession([]peer.ID{"bad", "a", "good", "b"}, MultiOptions{})
+	got := m.available()
+	want := []peer.ID{"good", "a", "b", "bad"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("asked %v, expected %v", got, want)
+		}
+	}
+}
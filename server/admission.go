@@ -0,0 +1,178 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+)
+
+// ErrOverBudget refuses PIR requests whose answers would take the work the
+// server has admitted past its Admission budget.
+var ErrOverBudget = errors.New("PIR requests exceed the server's compute budget")
+
+// BudgetError is an ErrOverBudget, telling when the request is likely to be
+// admitted if sent again. Clients are told it with a Busy error.
+type BudgetError struct {
+	RetryAfter time.Duration
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrOverBudget, e.RetryAfter)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrOverBudget
+}
+
+// Admission bounds the work of the PIR requests a server has admitted but not
+// yet answered, so that a server asked for more than it can answer in time
+// refuses requests, telling clients when to retry, rather than queueing them
+// all to time out.
+type Admission struct {
+	// Budget is the time the PIR requests admitted but not yet answered
+	// are estimated to take on one core, summed, past which requests are
+	// deferred, and then refused. Requests are estimated with
+	// pir.AnswerCost over the databases they query. A request is admitted
+	// whenever no other is, however costly. Zero admits every request.
+	Budget time.Duration
+	// MaxDefer is how long requests over budget wait for others to be
+	// answered before they are refused. Zero refuses them at once.
+	MaxDefer time.Duration
+}
+
+// admission tracks the work admitted against an Admission budget.
+type admission struct {
+	Admission
+	// workers is the number of PIR workers, which answer the work admitted
+	// together.
+	workers int
+
+	mtx      sync.Mutex
+	admitted time.Duration
+	// freed is closed, and replaced, whenever admitted work is answered.
+	freed chan struct{}
+}
+
+func newAdmission(a Admission, workers int) *admission {
+	if workers < 1 {
+		workers = 1
+	}
+	return &admission{Admission: a, workers: workers, freed: make(chan struct{})}
+}
+
+// get returns the budget in force.
+func (a *admission) get() Admission {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.Admission
+}
+
+// set replaces the budget, for the work admitted from now on. Work already
+// admitted is still accounted for, so a budget lowered below it admits no
+// more until it is answered.
+func (a *admission) set(adm Admission) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.Admission = adm
+	// wake the requests deferred to check the new budget.
+	close(a.freed)
+	a.freed = make(chan struct{})
+}
+
+// reserve admits work of cost if the budget has room for it, or else
+// returns a BudgetError estimating when it will. Work is accounted for
+// without a budget too, in case one is set later.
+func (a *admission) reserve(cost time.Duration) error {
+	if cost <= 0 {
+		return nil
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if excess := a.admitted + cost - a.Budget; a.Budget > 0 && a.admitted > 0 && excess > 0 {
+		return &BudgetError{RetryAfter: excess / time.Duration(a.workers)}
+	}
+	a.admitted += cost
+	return nil
+}
+
+// wait admits work of cost once the budget has room for it, waiting up to
+// MaxDefer, or until ctx is done.
+func (a *admission) wait(ctx context.Context, cost time.Duration) error {
+	t := time.NewTimer(a.get().MaxDefer)
+	defer t.Stop()
+	for {
+		a.mtx.Lock()
+		freed := a.freed
+		a.mtx.Unlock()
+		if err := a.reserve(cost); err == nil {
+			return nil
+		}
+		select {
+		case <-freed:
+		case <-t.C:
+			return a.reserve(cost)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release returns work of cost, once answered, to the budget.
+func (a *admission) release(cost time.Duration) {
+	if cost <= 0 {
+		return
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.admitted -= cost
+	close(a.freed)
+	a.freed = make(chan struct{})
+}
+
+// outstanding returns the work admitted and not yet answered.
+func (a *admission) outstanding() time.Duration {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.admitted
+}
+
+// pirCost estimates the work of answering reqs, parts of the same round of
+// a retrieval. Requests for stores not served cost nothing, as they fail
+// without computing anything.
+func (h *handler) pirCost(reqs []bitswap_message_pb.Message_PIRRequest) time.Duration {
+	store, err := h.storeIn(reqs[0].Group, reqs[0].Scheme)
+	if err != nil {
+		return 0
+	}
+	db, err := store.Snapshot()
+	if err != nil {
+		return 0
+	}
+	var params pir.Params
+	switch reqs[0].Round {
+	case bitswap_message_pb.Message_IndexRound:
+		params = db.Index.Params
+	case bitswap_message_pb.Message_BlockRound:
+		params = db.Blocks.Params
+	case bitswap_message_pb.Message_BatchIndexRound:
+		params = bucketParams(db.IndexBatch)
+	case bitswap_message_pb.Message_BatchBlockRound:
+		params = bucketParams(db.BlocksBatch)
+	}
+	return time.Duration(len(reqs)) * pir.AnswerCost(store.Scheme(), params)
+}
+
+// bucketParams returns the parameters of each bucket of b, or none if the
+// store has no batched layout.
+func bucketParams(b *batch.Encoded) pir.Params {
+	if b == nil {
+		return pir.Params{}
+	}
+	return b.Params.Bucket
+}
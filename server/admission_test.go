@@ -0,0 +1,107 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestAdmission(t *testing.T) {
+	a := newAdmission(Admission{Budget: 10 * time.Millisecond, MaxDefer: time.Second}, 2)
+	if err := a.reserve(8 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// work past the budget is refused, until the work admitted is answered
+	// by the two workers.
+	var be *BudgetError
+	if err := a.reserve(5 * time.Millisecond); !errors.As(err, &be) || !errors.Is(err, ErrOverBudget) || be.RetryAfter != 1500*time.Microsecond {
+		t.Fatalf("work over budget reserved with %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		a.release(8 * time.Millisecond)
+	}()
+	if err := a.wait(context.Background(), 5*time.Millisecond); err != nil {
+		t.Fatalf("deferred work admitted with %v", err)
+	}
+	ctx, cncl := context.WithCancel(context.Background())
+	cncl()
+	if err := a.wait(ctx, 6*time.Millisecond); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled work admitted with %v", err)
+	}
+
+	// work alone is admitted, however costly.
+	a.release(5 * time.Millisecond)
+	if err := a.reserve(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdmissionSet(t *testing.T) {
+	a := newAdmission(Admission{}, 1)
+	if err := a.reserve(8 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// work admitted without a budget counts against one set later.
+	a.set(Admission{Budget: 10 * time.Millisecond, MaxDefer: time.Second})
+	if err := a.reserve(5 * time.Millisecond); !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("work over budget reserved with %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		a.set(Admission{Budget: 20 * time.Millisecond, MaxDefer: time.Second})
+	}()
+	if err := a.wait(context.Background(), 5*time.Millisecond); err != nil {
+		t.Fatalf("work deferred admitted under a raised budget with %v", err)
+	}
+	a.release(13 * time.Millisecond)
+	if a.outstanding() != 0 {
+		t.Fatalf("%v still admitted", a.outstanding())
+	}
+}
+
+func TestAdmissionRefusal(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	metrics := countingSink{}
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithAdmission(Admission{Budget: time.Nanosecond}), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _, err := fastpir.New().Query(hs.Index.Params(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// other work fills the budget.
+	if err := h.admission.reserve(time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	r := bitswap_message_pb.Message_PIRRequest{Session: 1, Round: bitswap_message_pb.Message_IndexRound, Query: q, Id: 3}
+	msg, err := (&bitswap_message_pb.Message{PirRequests: []bitswap_message_pb.Message_PIRRequest{r}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	ss.replyInline()
+	if err := h.onMessage(context.Background(), ss, msg); err != nil {
+		t.Fatalf("stream failed with %v rather than the request", err)
+	}
+	m := queued(t, ss)
+	if len(m.Errors) != 1 || m.Errors[0].Code != bitswap_message_pb.Message_Busy || m.Errors[0].Id != r.Id || m.Errors[0].RetryAfter == 0 {
+		t.Fatalf("request over budget refused with %v", m.Errors)
+	}
+	if metrics["pir_over_budget"] != 1 || ss.outstanding() != 0 || !ss.startRequest(r.Id) {
+		t.Fatalf("refused request left outstanding, counted %v", metrics)
+	}
+}
@@ -0,0 +1,133 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/multiformats/go-multiaddr"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+)
+
+// ErrUnauthorized refuses the streams, and the private retrievals, of peers
+// the server's Authorizer does not allow. Clients are told it with an
+// Unauthorized error.
+var ErrUnauthorized = errors.New("peer not authorized")
+
+// An Authorizer decides which peers the server serves, and over which
+// protocols. Peer IDs are authenticated by the libp2p connection, so an
+// Authorizer may restrict the server to peers known by their keys, or to
+// peers which presented credentials, such as tokens, out of band.
+type Authorizer interface {
+	// Authorize is called as p opens a stream of proto from addr, and
+	// returns nil to serve it, or an error to refuse it. As PIR messages
+	// sent over plain bitswap streams are answered too, it is called
+	// again with bitswap.ProtocolPrivate for the first of them. It must be
+	// safe for concurrent use, and should return quickly, as the stream
+	// waits on it.
+	Authorize(ctx context.Context, p peer.ID, addr multiaddr.Multiaddr, proto protocol.ID) error
+}
+
+// AuthorizerFunc adapts a function to an Authorizer.
+type AuthorizerFunc func(ctx context.Context, p peer.ID, addr multiaddr.Multiaddr, proto protocol.ID) error
+
+func (f AuthorizerFunc) Authorize(ctx context.Context, p peer.ID, addr multiaddr.Multiaddr, proto protocol.ID) error {
+	return f(ctx, p, addr, proto)
+}
+
+// Allowlist is an Authorizer serving private retrievals only to the peers
+// it holds, and plaintext bitswap to every peer. Peers may be added and
+// removed while the server runs, for instance as they redeem tokens issued
+// by the operator.
+type Allowlist struct {
+	mtx   sync.RWMutex
+	peers map[peer.ID]struct{}
+}
+
+// NewAllowlist returns an Allowlist holding peers.
+func NewAllowlist(peers ...peer.ID) *Allowlist {
+	a := &Allowlist{peers: make(map[peer.ID]struct{}, len(peers))}
+	for _, p := range peers {
+		a.peers[p] = struct{}{}
+	}
+	return a
+}
+
+// Add allows p private retrievals.
+func (a *Allowlist) Add(p peer.ID) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.peers[p] = struct{}{}
+}
+
+// Set replaces the peers allowed with peers, as when the operator reloads
+// the list. Streams already serving private retrievals to peers left out
+// keep doing so.
+func (a *Allowlist) Set(peers ...peer.ID) {
+	set := make(map[peer.ID]struct{}, len(peers))
+	for _, p := range peers {
+		set[p] = struct{}{}
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.peers = set
+}
+
+// Remove stops serving p private retrievals over the streams it opens from
+// now on.
+func (a *Allowlist) Remove(p peer.ID) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	delete(a.peers, p)
+}
+
+func (a *Allowlist) Authorize(_ context.Context, p peer.ID, _ multiaddr.Multiaddr, proto protocol.ID) error {
+	if proto != bitswap.ProtocolPrivate {
+		return nil
+	}
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+	if _, ok := a.peers[p]; !ok {
+		return fmt.Errorf("%w: %s is not allowlisted", ErrUnauthorized, p)
+	}
+	return nil
+}
+
+// authorize asks the server's Authorizer whether the peer of s may use
+// proto over it, counting refusals as "streams_unauthorized".
+func (h *handler) authorize(ctx context.Context, s network.Stream, proto protocol.ID) error {
+	if h.cfg.authorizer == nil {
+		return nil
+	}
+	err := h.cfg.authorizer.Authorize(ctx, s.Conn().RemotePeer(), s.Conn().RemoteMultiaddr(), proto)
+	if err == nil {
+		return nil
+	}
+	h.cfg.metrics.Add("streams_unauthorized", 1)
+	if !errors.Is(err, ErrUnauthorized) {
+		err = fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// refuse tells the client of the private stream s, which it may not use,
+// why, in reply to its first message, then closes it.
+func (h *handler) refuse(s network.Stream, err error) {
+	ss := h.newStreamSender(context.Background(), s)
+	defer ss.cancelAll()
+	go ss.writeLoop()
+	ss.replyInline()
+	if h.cfg.timeouts.Send > 0 {
+		_ = s.SetReadDeadline(time.Now().Add(h.cfg.timeouts.Send))
+	}
+	_ = h.buffers.readMessages(s, bitswap.MaxBlockSize, func([]byte) error {
+		h.sendError(ss, protocolError(0, 0, err), true)
+		return err
+	})
+	_ = s.Close()
+}
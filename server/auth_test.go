@@ -0,0 +1,84 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// peerStream is a discardStream from a given peer.
+type peerStream struct {
+	discardStream
+	peer peer.ID
+}
+
+func (s peerStream) Conn() network.Conn { return peerConn{peer: s.peer} }
+
+type peerConn struct {
+	discardConn
+	peer peer.ID
+}
+
+func (c peerConn) RemotePeer() peer.ID { return c.peer }
+func (peerConn) RemoteMultiaddr() multiaddr.Multiaddr {
+	return multiaddr.StringCast("/ip4/192.0.2.1/tcp/4001")
+}
+
+func TestAllowlist(t *testing.T) {
+	metrics := countingSink{}
+	allow := NewAllowlist("allowed")
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithAuthorizer(allow), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	addr := multiaddr.StringCast("/ip4/192.0.2.1/tcp/4001")
+	if err := allow.Authorize(ctx, "other", addr, bitswap.ProtocolBitswap); err != nil {
+		t.Fatalf("plain bitswap refused: %v", err)
+	}
+	if err := allow.Authorize(ctx, "other", addr, bitswap.ProtocolPrivate); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("private protocol of a peer not allowlisted authorized with %v", err)
+	}
+
+	// private retrievals over plain bitswap streams are refused alike.
+	hs, err := (&bitswap_message_pb.Message{PirHandshake: &bitswap_message_pb.Message_PIRHandshake{
+		Offer: &bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}},
+	}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(ctx, peerStream{peer: "other"})
+	err = h.onMessage(ctx, ss, hs)
+	if !errors.Is(err, ErrUnauthorized) || errorCode(err) != bitswap_message_pb.Message_Unauthorized {
+		t.Fatalf("handshake of a peer not allowlisted failed with %v", err)
+	}
+	if metrics["streams_unauthorized"] != 1 {
+		t.Fatalf("counted %v", metrics)
+	}
+
+	// until the peer is allowed.
+	allow.Add("other")
+	if err := h.onMessage(ctx, ss, hs); err != nil || !ss.authorized {
+		t.Fatalf("handshake of an allowlisted peer failed with %v", err)
+	}
+	allow.Remove("other")
+	if err := h.authorize(ctx, peerStream{peer: "other"}, bitswap.ProtocolPrivate); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("removed peer authorized with %v", err)
+	}
+	allow.Set("other")
+	if allow.Authorize(ctx, "other", addr, bitswap.ProtocolPrivate) != nil || allow.Authorize(ctx, "allowed", addr, bitswap.ProtocolPrivate) == nil {
+		t.Fatal("set did not replace the peers allowed")
+	}
+}
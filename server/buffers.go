@@ -0,0 +1,119 @@
+package bitswapserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+
+	"github.com/libp2p/go-msgio"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+const (
+	// minBufferClass and maxBufferClass bound the sizes of pooled buffers,
+	// as powers of two. Larger buffers are allocated for each use.
+	minBufferClass = 12
+	maxBufferClass = 23
+)
+
+// bufferPool recycles the buffers messages are read into and framed in,
+// across all streams of a server, in power of two size classes. Whether
+// buffers were reused or allocated is reported as the "buffers_reused" and
+// "buffers_allocated" counters.
+type bufferPool struct {
+	classes [maxBufferClass - minBufferClass + 1]sync.Pool
+	metrics MetricsSink
+}
+
+func newBufferPool(metrics MetricsSink) *bufferPool {
+	return &bufferPool{metrics: metrics}
+}
+
+// class returns the size class holding buffers of n bytes, or -1 if they
+// are too large to pool.
+func class(n int) int {
+	c := minBufferClass
+	if n > 1<<minBufferClass {
+		c = bits.Len(uint(n - 1))
+	}
+	if c > maxBufferClass {
+		return -1
+	}
+	return c - minBufferClass
+}
+
+// get returns a buffer of n bytes.
+func (p *bufferPool) get(n int) []byte {
+	c := class(n)
+	if c >= 0 {
+		if b, ok := p.classes[c].Get().(*[]byte); ok {
+			p.metrics.Add("buffers_reused", 1)
+			return (*b)[:n]
+		}
+	}
+	p.metrics.Add("buffers_allocated", 1)
+	if c < 0 {
+		return make([]byte, n)
+	}
+	return make([]byte, n, 1<<(c+minBufferClass))
+}
+
+// put returns b, which must have come from get, to the pool. b must not be
+// used after.
+func (p *bufferPool) put(b []byte) {
+	c := class(cap(b))
+	if c < 0 || cap(b) != 1<<(c+minBufferClass) {
+		return
+	}
+	b = b[:0]
+	p.classes[c].Put(&b)
+}
+
+// frame marshals m behind its length prefix into a pooled buffer.
+func (p *bufferPool) frame(m *bitswap_message_pb.Message) ([]byte, error) {
+	size := m.Size()
+	buf := p.get(binary.MaxVarintLen64 + size)
+	n := binary.PutUvarint(buf, uint64(size))
+	if _, err := m.MarshalToSizedBuffer(buf[n : n+size]); err != nil {
+		p.put(buf)
+		return nil, err
+	}
+	return buf[:n+size], nil
+}
+
+// readMessages reads varint length prefixed messages from r, of up to max
+// bytes, passing each to handle. Messages are read into pooled buffers, which
+// are reused once handle returns. It returns nil when r ends between
+// messages, and otherwise the error which stopped it.
+func (p *bufferPool) readMessages(r io.Reader, max int, handle func([]byte) error) error {
+	mr := msgio.NewVarintReaderSize(r, max)
+	for {
+		n, err := mr.NextMsgLen()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n > max {
+			return fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, n)
+		}
+		buf := p.get(n)
+		if _, err := mr.Read(buf); err != nil {
+			p.put(buf)
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		// messages are copied out of buf as they are parsed.
+		err = handle(buf)
+		p.put(buf)
+		if err != nil {
+			return err
+		}
+	}
+}
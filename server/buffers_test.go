@@ -0,0 +1,136 @@
+package bitswapserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+type countingSink map[string]float64
+
+func (c countingSink) Add(name string, v float64) { c[name] += v }
+func (c countingSink) Observe(string, float64)    {}
+
+func TestBufferPool(t *testing.T) {
+	metrics := countingSink{}
+	p := newBufferPool(metrics)
+	// one size of each class, so none can be reused.
+	for _, n := range []int{1, 4097, 1 << 20, 1<<maxBufferClass + 1} {
+		b := p.get(n)
+		if len(b) != n {
+			t.Fatalf("asked for %d bytes, got %d", n, len(b))
+		}
+		p.put(b)
+	}
+	if metrics["buffers_allocated"] != 4 {
+		t.Fatalf("%v buffers allocated", metrics["buffers_allocated"])
+	}
+	// sync.Pool may drop buffers at any time, so only check that reused ones
+	// are counted and sized right.
+	b := p.get(100)
+	if len(b) != 100 || cap(b) != 1<<minBufferClass {
+		t.Fatalf("got a buffer of %d/%d bytes", len(b), cap(b))
+	}
+	if metrics["buffers_allocated"]+metrics["buffers_reused"] != 5 {
+		t.Fatalf("buffer counts %v", metrics)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	p := newBufferPool(nopSink{})
+	m := bitswap_message_pb.Message{Blocks: [][]byte{[]byte("hello world")}}
+	framed, err := p.frame(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, n := binary.Uvarint(framed)
+	if n <= 0 || int(size) != len(framed)-n {
+		t.Fatalf("prefix says %d bytes, %d follow", size, len(framed)-n)
+	}
+	var got bitswap_message_pb.Message
+	if err := got.Unmarshal(framed[n:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Blocks) != 1 || string(got.Blocks[0]) != "hello world" {
+		t.Fatalf("got %v", got.Blocks)
+	}
+}
+
+// chunkReader returns at most n bytes per read, as streams may split
+// messages, and prefixes, anywhere.
+type chunkReader struct {
+	r io.Reader
+	n int
+}
+
+func (c chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.n {
+		p = p[:c.n]
+	}
+	return c.r.Read(p)
+}
+
+func prefix(n int) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, uint64(n))]
+}
+
+func readAll(data []byte, chunk int) ([][]byte, error) {
+	var msgs [][]byte
+	err := newBufferPool(nopSink{}).readMessages(chunkReader{bytes.NewReader(data), chunk}, 1<<16, func(msg []byte) error {
+		msgs = append(msgs, append([]byte(nil), msg...))
+		return nil
+	})
+	return msgs, err
+}
+
+func FuzzReadMessages(f *testing.F) {
+	f.Add([]byte("hello"), []byte{}, uint8(1))
+	f.Add(bytes.Repeat([]byte{0xff}, 200), []byte("world"), uint8(7))
+	f.Fuzz(func(t *testing.T, a, b []byte, chunk uint8) {
+		var data []byte
+		for _, m := range [][]byte{a, b} {
+			data = append(data, prefix(len(m))...)
+			data = append(data, m...)
+		}
+		msgs, err := readAll(data, int(chunk)+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 2 || !bytes.Equal(msgs[0], a) || !bytes.Equal(msgs[1], b) {
+			t.Fatalf("framed %q and %q, read %q", a, b, msgs)
+		}
+		// a stream cut short fails, unless it is cut between messages.
+		for cut := 1; cut < len(data); cut++ {
+			msgs, err := readAll(data[:cut], int(chunk)+1)
+			if err == nil && len(msgs) != 1 {
+				t.Fatalf("stream cut at %d read as %d whole messages", cut, len(msgs))
+			}
+		}
+	})
+}
+
+func FuzzReadMessagesRaw(f *testing.F) {
+	f.Add([]byte{0x05, 'h', 'e', 'l', 'l', 'o'})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+	f.Add([]byte{0x80, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msgs, err := readAll(data, 3)
+		if err == nil {
+			// everything read must have been whole messages.
+			n := 0
+			for _, m := range msgs {
+				n += len(prefix(len(m))) + len(m)
+			}
+			if n != len(data) {
+				t.Fatalf("read %d bytes of messages from %d", n, len(data))
+			}
+		} else if errors.Is(err, io.EOF) {
+			t.Fatal("truncated stream reported as ended")
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package bitswapserver
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+)
+
+// DefaultAnswerCacheSize is the number of bytes of PIR answers kept for
+// repeated queries.
+const DefaultAnswerCacheSize = 32 << 20
+
+// answerKey identifies an answer by the query it answers and the database it
+// was computed from: the store, by storeID, and epoch of the snapshot, which
+// of its databases, and for batched databases the bucket.
+type answerKey struct {
+	store string
+	epoch uint64
+	db    string
+	part  uint32
+	query [sha256.Size]byte
+}
+
+type cachedAnswer struct {
+	key    answerKey
+	answer []byte
+}
+
+// answerCache remembers recent PIR answers so a client retrying an
+// identical query is not answered by a second pass over the database. Only
+// the latest epoch of each scheme is kept: answers computed from an older
+// snapshot are dropped once a newer one is seen. It is safe for concurrent
+// use, and a nil cache caches nothing.
+type answerCache struct {
+	mtx     sync.Mutex
+	size    int
+	max     int
+	order   *list.List
+	entries map[answerKey]*list.Element
+	epochs  map[string]uint64
+}
+
+func newAnswerCache(max int) *answerCache {
+	if max <= 0 {
+		return nil
+	}
+	return &answerCache{
+		max:     max,
+		order:   list.New(),
+		entries: make(map[answerKey]*list.Element),
+		epochs:  make(map[string]uint64),
+	}
+}
+
+func newAnswerKey(store string, epoch uint64, db string, part uint32, query []byte) answerKey {
+	return answerKey{store, epoch, db, part, sha256.Sum256(query)}
+}
+
+// get returns the cached answer for k.
+func (c *answerCache) get(k answerKey) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cachedAnswer).answer, true
+}
+
+// put caches answer for k, evicting the least recently used answers to make
+// room. Answers larger than the whole cache, or from a superseded epoch, are
+// not kept.
+func (c *answerCache) put(k answerKey, answer []byte) {
+	if c == nil || len(answer) > c.max {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if latest := c.epochs[k.store]; k.epoch < latest {
+		return
+	} else if k.epoch > latest {
+		c.epochs[k.store] = k.epoch
+		c.removeIf(func(o answerKey) bool { return o.store == k.store && o.epoch < k.epoch })
+	}
+	if _, ok := c.entries[k]; ok {
+		return
+	}
+	c.entries[k] = c.order.PushFront(&cachedAnswer{k, answer})
+	c.size += len(answer)
+	for c.size > c.max {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *answerCache) removeIf(match func(answerKey) bool) {
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+		if match(e.Value.(*cachedAnswer).key) {
+			c.remove(e)
+		}
+		e = next
+	}
+}
+
+func (c *answerCache) remove(e *list.Element) {
+	ca := c.order.Remove(e).(*cachedAnswer)
+	delete(c.entries, ca.key)
+	c.size -= len(ca.answer)
+}
+
+// answer returns the answer to query against the database named db of a
+// snapshot of store, from the cache if it was answered before, or computed
+// with compute unless ctx is done.
+func (h *handler) answer(ctx context.Context, store *pirstore.Store, snap *pirstore.Snapshot, db string, part uint32, query []byte, compute func() ([]byte, error)) ([]byte, error) {
+	if h.answers == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return compute()
+	}
+	k := newAnswerKey(storeID(store), snap.Epoch, db, part, query)
+	if a, ok := h.answers.get(k); ok {
+		h.cfg.metrics.Add("pir_answer_cache_hits", 1)
+		return a, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	a, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	h.cfg.metrics.Add("pir_answer_cache_misses", 1)
+	h.answers.put(k, a)
+	return a, nil
+}
@@ -0,0 +1,52 @@
+package bitswapserver
+
+import (
+	"testing"
+)
+
+func TestAnswerCache(t *testing.T) {
+	c := newAnswerCache(10)
+	k1 := newAnswerKey("s", 1, "index", 0, []byte("q1"))
+	k2 := newAnswerKey("s", 1, "index", 0, []byte("q2"))
+	k3 := newAnswerKey("s", 1, "blocks", 0, []byte("q1"))
+
+	c.put(k1, []byte("aaaa"))
+	c.put(k2, []byte("bbbb"))
+	if a, ok := c.get(k1); !ok || string(a) != "aaaa" {
+		t.Fatalf("expected cached answer, got %q %v", a, ok)
+	}
+	// k2 is now least recently used and is evicted to make room.
+	c.put(k3, []byte("cccc"))
+	if _, ok := c.get(k2); ok {
+		t.Fatal("least recently used answer was not evicted")
+	}
+	if _, ok := c.get(k1); !ok {
+		t.Fatal("recently used answer was evicted")
+	}
+	c.put(newAnswerKey("s", 1, "index", 0, []byte("big")), make([]byte, 11))
+	if c.size > c.max {
+		t.Fatalf("cache holds %d bytes, more than its %d", c.size, c.max)
+	}
+
+	// a new epoch invalidates the answers of the old one, and only of that
+	// scheme.
+	other := newAnswerKey("t", 1, "index", 0, []byte("q1"))
+	c.put(other, []byte("dd"))
+	c.put(newAnswerKey("s", 2, "index", 0, []byte("q1")), []byte("e"))
+	if _, ok := c.get(k1); ok {
+		t.Fatal("answer from a superseded epoch was kept")
+	}
+	if _, ok := c.get(other); !ok {
+		t.Fatal("answer of another scheme was dropped")
+	}
+	c.put(k2, []byte("ff"))
+	if _, ok := c.get(k2); ok {
+		t.Fatal("answer from a superseded epoch was cached")
+	}
+
+	var disabled *answerCache
+	disabled.put(k1, []byte("a"))
+	if _, ok := disabled.get(k1); ok {
+		t.Fatal("nil cache returned an answer")
+	}
+}
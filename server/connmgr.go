@@ -0,0 +1,48 @@
+package bitswapserver
+
+import (
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ProtectTag is the connection manager tag protecting the connections of
+// peers awaiting PIR answers.
+const ProtectTag = "bitswap-pir"
+
+// protector protects the connections of peers with PIR sessions in flight
+// from the connection manager, so connections are not pruned while answers,
+// which may take minutes to compute, are outstanding. Each peer stays
+// protected until the last of its sessions finishes.
+type protector struct {
+	cm     connmgr.ConnManager
+	mtx    sync.Mutex
+	active map[peer.ID]int
+}
+
+func (pr *protector) start(p peer.ID) {
+	if pr.cm == nil {
+		return
+	}
+	pr.mtx.Lock()
+	defer pr.mtx.Unlock()
+	if pr.active == nil {
+		pr.active = make(map[peer.ID]int)
+	}
+	if pr.active[p]++; pr.active[p] == 1 {
+		pr.cm.Protect(p, ProtectTag)
+	}
+}
+
+func (pr *protector) finish(p peer.ID) {
+	if pr.cm == nil {
+		return
+	}
+	pr.mtx.Lock()
+	defer pr.mtx.Unlock()
+	if pr.active[p]--; pr.active[p] <= 0 {
+		delete(pr.active, p)
+		pr.cm.Unprotect(p, ProtectTag)
+	}
+}
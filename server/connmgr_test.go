@@ -0,0 +1,47 @@
+package bitswapserver
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// protections records the peers protected, by tag.
+type protections struct {
+	connmgr.NullConnMgr
+	tags map[peer.ID]map[string]bool
+}
+
+func (pm *protections) Protect(p peer.ID, tag string) {
+	if pm.tags[p] == nil {
+		pm.tags[p] = make(map[string]bool)
+	}
+	pm.tags[p][tag] = true
+}
+
+func (pm *protections) Unprotect(p peer.ID, tag string) bool {
+	delete(pm.tags[p], tag)
+	return len(pm.tags[p]) > 0
+}
+
+func TestProtector(t *testing.T) {
+	cm := &protections{tags: make(map[peer.ID]map[string]bool)}
+	pr := &protector{cm: cm}
+	a, b := peer.ID("a"), peer.ID("b")
+	pr.start(a)
+	pr.start(a)
+	pr.start(b)
+	pr.finish(a)
+	if !cm.tags[a][ProtectTag] {
+		t.Fatal("peer with a session in flight should stay protected")
+	}
+	pr.finish(a)
+	if cm.tags[a][ProtectTag] || !cm.tags[b][ProtectTag] {
+		t.Fatalf("protections %v", cm.tags)
+	}
+	pr.finish(b)
+	if len(pr.active) != 0 {
+		t.Fatalf("sessions left %v", pr.active)
+	}
+}
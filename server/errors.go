@@ -0,0 +1,102 @@
+package bitswapserver
+
+import (
+	"errors"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+)
+
+var (
+	// ErrStaleEpoch fails PIR requests built for databases which have
+	// changed since.
+	ErrStaleEpoch = errors.New("PIR databases changed since the request's epoch")
+	// ErrDatabaseTooLarge refuses handshakes offering only schemes whose
+	// databases exceed the client's limits.
+	ErrDatabaseTooLarge = errors.New("no PIR database fits the client's limits")
+)
+
+// errorCode returns the code clients are told err with.
+func errorCode(err error) bitswap_message_pb.Message_ErrorCode {
+	switch {
+	case errors.Is(err, ErrUnknownScheme), errors.Is(err, ErrNoPIR), errors.Is(err, ErrNoBatch), errors.Is(err, pir.ErrSchemeMismatch):
+		return bitswap_message_pb.Message_SchemeMismatch
+	case errors.Is(err, ErrRateLimited), errors.Is(err, ErrQuotaExceeded):
+		return bitswap_message_pb.Message_RateLimited
+	case errors.Is(err, ErrStaleEpoch):
+		return bitswap_message_pb.Message_StaleEpoch
+	case errors.Is(err, ErrDatabaseTooLarge):
+		return bitswap_message_pb.Message_DatabaseTooLarge
+	case errors.Is(err, ErrUnauthorized):
+		return bitswap_message_pb.Message_Unauthorized
+	case errors.Is(err, ErrBusy), errors.Is(err, ErrMemoryLimit), errors.Is(err, ErrOverBudget):
+		return bitswap_message_pb.Message_Busy
+	case errors.Is(err, pir.ErrMalformed), errors.Is(err, pir.ErrIndexOutOfRange), errors.Is(err, ErrMessageTooLarge), errors.Is(err, ErrNotHave):
+		return bitswap_message_pb.Message_BadRequest
+	}
+	return bitswap_message_pb.Message_InternalError
+}
+
+// protocolError returns the Error telling the client of err, which failed
+// the round of session, or the whole stream if session is 0. The detail of
+// internal errors is kept from clients, and the wait asked of clients by
+// BudgetErrors and QuotaErrors is rounded up to a millisecond.
+func protocolError(session uint64, round bitswap_message_pb.Message_PIRRound, err error) bitswap_message_pb.Message_Error {
+	e := bitswap_message_pb.Message_Error{Code: errorCode(err), Session: session, Round: round, Message: err.Error()}
+	if e.Code == bitswap_message_pb.Message_InternalError {
+		e.Message = "internal error"
+	}
+	var be *BudgetError
+	var qe *QuotaError
+	switch {
+	case errors.As(err, &be):
+		e.RetryAfter = retryAfter(be.RetryAfter)
+	case errors.As(err, &qe):
+		e.RetryAfter = retryAfter(qe.RetryAfter)
+	}
+	return e
+}
+
+// retryAfter returns d in milliseconds, rounded up.
+func retryAfter(d time.Duration) uint64 {
+	return uint64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// sendError queues e for the client of ss, if it reads replies inline as
+// clients of this package do; stock bitswap peers know no errors. If wait is
+// set it returns once e is written, or the send timeout passes, so the
+// stream may be closed after it.
+func (h *handler) sendError(ss *streamSender, e bitswap_message_pb.Message_Error, wait bool) {
+	if !ss.repliesInline() {
+		return
+	}
+	m := bitswap_message_pb.Message{Errors: []bitswap_message_pb.Message_Error{e}}
+	msg, err := ss.frame(&m)
+	if err != nil {
+		return
+	}
+	out, err := ss.admit(msg, network.ReservationPriorityHigh, nil)
+	if err != nil {
+		return
+	}
+	if wait {
+		out.written = make(chan struct{})
+	}
+	select {
+	case ss.queue <- out:
+	default:
+		ss.drop(out)
+		return
+	}
+	h.cfg.metrics.Add("errors_sent", 1)
+	if wait {
+		t := time.NewTimer(ss.sendTimeout)
+		defer t.Stop()
+		select {
+		case <-out.written:
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package bitswapserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// queued returns the next message queued on ss.
+func queued(t *testing.T, ss *streamSender) bitswap_message_pb.Message {
+	t.Helper()
+	var out outgoing
+	select {
+	case out = <-ss.queue:
+	default:
+		t.Fatal("nothing queued")
+	}
+	l, n := binary.Uvarint(out.msg)
+	m := bitswap_message_pb.Message{}
+	if err := m.Unmarshal(out.msg[n : n+int(l)]); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestErrors(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		offer *bitswap_message_pb.Message_PIROffer
+		code  bitswap_message_pb.Message_ErrorCode
+	}{
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}, MaxElements: 1}, bitswap_message_pb.Message_DatabaseTooLarge},
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{"fastpir-lwe1024/v0"}}, bitswap_message_pb.Message_SchemeMismatch},
+	} {
+		hs, err := h.handshake(tc.offer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.refusal(tc.offer, hs); err == nil || errorCode(err) != tc.code {
+			t.Fatalf("offer %v refused with %v", tc.offer, err)
+		}
+	}
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.refusal(nil, hs); err != nil {
+		t.Fatalf("accepted handshake refused with %v", err)
+	}
+
+	// queries for databases which have changed since are not answered.
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	ss.replyInline()
+	q, _, err := fastpir.New().Query(hs.Index.Params(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bitswap_message_pb.Message_PIRRequest{Session: 1, Round: bitswap_message_pb.Message_IndexRound, Query: q, Epoch: hs.Epoch + 1}
+	ss.track(pirWork(r.Session))
+	h.answerPIR(context.Background(), ss, r, time.Now())
+	m := queued(t, ss)
+	if len(m.Errors) != 1 || m.Errors[0].Code != bitswap_message_pb.Message_StaleEpoch || m.Errors[0].Session != 1 || len(m.PirResponses) != 0 {
+		t.Fatalf("stale query answered with %v", m)
+	}
+	if ss.outstanding() != 0 {
+		t.Fatal("failed round was not released")
+	}
+
+	// the detail of internal errors is kept from clients.
+	if e := protocolError(0, 0, errors.New("disk on fire")); e.Code != bitswap_message_pb.Message_InternalError || e.Message != "internal error" {
+		t.Fatalf("internal error sent as %v", e)
+	}
+	if e := protocolError(0, 0, ErrRateLimited); e.Code != bitswap_message_pb.Message_RateLimited || e.Message != ErrRateLimited.Error() {
+		t.Fatalf("rate limit sent as %v", e)
+	}
+}
@@ -0,0 +1,115 @@
+package bitswapserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// The corpus in testdata/fuzz/FuzzOnMessage was captured from a client
+// retrieving blocks of fuzzHandler's store, so its PIR queries are valid
+// against it.
+
+// fuzzHandler returns a handler over four small blocks, answering PIR
+// requests with fastpir, in batches too, and spiral.
+func fuzzHandler(f *testing.F) *handler {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	for i := 0; i < 4; i++ {
+		util.Add(bs, []byte(fmt.Sprintf("block %d", i)))
+	}
+	h, err := newHandler(bs,
+		WithPIRScheme(fastpir.New(), pirstore.Options{BatchSize: 8}),
+		WithPIRScheme(spiral.New(), pirstore.Options{}),
+		WithRequestTimeout(time.Second),
+	)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return h
+}
+
+// discardStream is a stream to a peer which discards what it is sent.
+type discardStream struct {
+	network.Stream
+}
+
+func (discardStream) Conn() network.Conn          { return discardConn{} }
+func (discardStream) Write(p []byte) (int, error) { return len(p), nil }
+func (discardStream) Close() error                { return nil }
+func (discardStream) Reset() error                { return nil }
+func (discardStream) Protocol() protocol.ID       { return bitswap.ProtocolBitswap }
+
+type discardConn struct {
+	network.Conn
+}
+
+func (discardConn) RemotePeer() peer.ID { return "remote" }
+
+func FuzzOnMessage(f *testing.F) {
+	h := fuzzHandler(f)
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	go ss.writeLoop()
+
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		_ = h.onMessage(context.Background(), ss, msg)
+		// wait for the work the message started, so a message which hangs
+		// the server fails here rather than piling up.
+		deadline := time.Now().Add(10 * time.Second)
+		for ss.outstanding() > 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("work on message %x did not finish", msg)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+func FuzzPIRRequest(f *testing.F) {
+	h := fuzzHandler(f)
+	for _, store := range h.stores {
+		db, err := store.Snapshot()
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, round := range []bitswap_message_pb.Message_PIRRound{bitswap_message_pb.Message_IndexRound, bitswap_message_pb.Message_BlockRound} {
+			enc := db.Index
+			if round == bitswap_message_pb.Message_BlockRound {
+				enc = db.Blocks
+			}
+			q, _, err := store.Scheme().Query(enc.Params, 1)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(store.Scheme().ID(), int32(round), uint32(0), q)
+			// and as a part of a batched round of the same database.
+			f.Add(store.Scheme().ID(), int32(round+bitswap_message_pb.Message_BatchIndexRound), uint32(1), q)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, scheme string, round int32, part uint32, query []byte) {
+		r := bitswap_message_pb.Message_PIRRequest{
+			Scheme: scheme,
+			Round:  bitswap_message_pb.Message_PIRRound(round),
+			Part:   part,
+			Query:  query,
+		}
+		if isBatchRound(r.Round) {
+			_ = h.onPIRBatch(context.Background(), "fuzz", []bitswap_message_pb.Message_PIRRequest{r}, func(bitswap_message_pb.Message_PIRResponse) error { return nil })
+		} else {
+			_, _ = h.onPIRRequest(context.Background(), "fuzz", r)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package bitswapserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// recordStream is a discardStream of protocol proto passing on each write.
+type recordStream struct {
+	discardStream
+	proto protocol.ID
+	sent  chan []byte
+}
+
+func newRecordStream(proto protocol.ID) recordStream {
+	return recordStream{proto: proto, sent: make(chan []byte, 8)}
+}
+
+func (s recordStream) Protocol() protocol.ID { return s.proto }
+
+func (s recordStream) Write(p []byte) (int, error) {
+	s.sent <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+// next parses the next message written to s.
+func (s recordStream) next(t *testing.T) bitswap_message_pb.Message {
+	t.Helper()
+	select {
+	case msg := <-s.sent:
+		n, ln := binary.Uvarint(msg)
+		m := bitswap_message_pb.Message{}
+		if err := m.Unmarshal(msg[ln : ln+int(n)]); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no reply")
+		return bitswap_message_pb.Message{}
+	}
+}
+
+func wantBlock(t *testing.T, c cid.Cid, inline bool) []byte {
+	m := bitswap_message_pb.Message{InlineReplies: inline}
+	m.Wantlist.Entries = append(m.Wantlist.Entries, bitswap_message_pb.Message_Wantlist_Entry{Block: bitswap_message_pb.Cid{Cid: c}})
+	msg, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestReplyStreams(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	data := []byte("hello world")
+	c := util.Add(bs, data)
+	h, err := newHandler(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies := newRecordStream(bitswap.ProtocolBitswap)
+	h.open = func(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error) {
+		if len(pids) != 1 || pids[0] != bitswap.ProtocolBitswap {
+			t.Errorf("reply stream opened with %v", pids)
+		}
+		return replies, nil
+	}
+
+	// stock peers are sent blocks on a stream of the server's own.
+	requests := newRecordStream(bitswap.ProtocolBitswap)
+	ss := h.newStreamSender(context.Background(), requests)
+	go ss.writeLoop()
+	if err := h.onMessage(context.Background(), ss, wantBlock(t, c, false)); err != nil {
+		t.Fatal(err)
+	}
+	m := replies.next(t)
+	if len(m.Payload) != 1 || !bytes.Equal(m.Payload[0].Data, data) {
+		t.Fatalf("expected a bitswap 1.1 payload, got %+v", m)
+	}
+	if prefix, err := cid.PrefixFromBytes(m.Payload[0].Prefix); err != nil || prefix != c.Prefix() {
+		t.Fatalf("payload prefix %v: %v", prefix, err)
+	}
+	if len(requests.sent) != 0 {
+		t.Fatal("reply written to the request stream")
+	}
+
+	// clients reading replies inline get them there, as bare blocks on
+	// bitswap 1.0.
+	legacy := newRecordStream(bitswap.ProtocolBitswapOneZero)
+	ss = h.newStreamSender(context.Background(), legacy)
+	go ss.writeLoop()
+	if err := h.onMessage(context.Background(), ss, wantBlock(t, c, true)); err != nil {
+		t.Fatal(err)
+	}
+	if m := legacy.next(t); len(m.Blocks) != 1 || !bytes.Equal(m.Blocks[0], data) {
+		t.Fatalf("expected a bitswap 1.0 block, got %+v", m)
+	}
+}
+
+func TestFullWantlist(t *testing.T) {
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	a := util.Add(util.NewMemStore(make(map[cid.Cid][]byte)), []byte("a"))
+	b := util.Add(util.NewMemStore(make(map[cid.Cid][]byte)), []byte("b"))
+	kept, dropped, private := ss.track(cidWork(a)), ss.track(cidWork(b)), ss.track(pirWork(1))
+
+	ss.keepWants([]bitswap_message_pb.Message_Wantlist_Entry{{Block: bitswap_message_pb.Cid{Cid: a}}})
+	if dropped.Err() == nil {
+		t.Fatal("block missing from the full wantlist is still served")
+	}
+	if kept.Err() != nil || private.Err() != nil {
+		t.Fatal("full wantlist cancelled work it still wants")
+	}
+}
@@ -0,0 +1,113 @@
+package bitswapserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Usage is what a peer has consumed of a server.
+type Usage struct {
+	// BytesSent and BytesReceived count the bytes written to and read from
+	// the peer's streams, including framing.
+	BytesSent     uint64
+	BytesReceived uint64
+	// BlocksServed counts the blocks sent to the peer in plaintext.
+	BlocksServed uint64
+	// PIRQueries counts the PIR queries answered for the peer, and PIRTime
+	// the time spent computing their answers.
+	PIRQueries uint64
+	PIRTime    time.Duration
+}
+
+// Ledger accounts for the usage of a server by each peer, for operators to
+// bill or throttle heavy peers. Pass one to AttachBitswapServer with
+// WithLedger. Peers are kept until reset, so operators accounting for many
+// peers over a long time should Reset them once their usage is settled. It
+// is safe for concurrent use, and a nil Ledger records nothing.
+type Ledger struct {
+	mtx   sync.Mutex
+	peers map[peer.ID]*Usage
+}
+
+// NewLedger creates an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{peers: make(map[peer.ID]*Usage)}
+}
+
+// Usage returns the usage recorded for p.
+func (l *Ledger) Usage(p peer.ID) Usage {
+	if l == nil {
+		return Usage{}
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if u, ok := l.peers[p]; ok {
+		return *u
+	}
+	return Usage{}
+}
+
+// All returns the usage recorded for every peer.
+func (l *Ledger) All() map[peer.ID]Usage {
+	out := make(map[peer.ID]Usage)
+	if l == nil {
+		return out
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for p, u := range l.peers {
+		out[p] = *u
+	}
+	return out
+}
+
+// Reset forgets the usage recorded for p, returning it.
+func (l *Ledger) Reset(p peer.ID) Usage {
+	if l == nil {
+		return Usage{}
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	u, ok := l.peers[p]
+	if !ok {
+		return Usage{}
+	}
+	delete(l.peers, p)
+	return *u
+}
+
+// record applies update to the usage of p.
+func (l *Ledger) record(p peer.ID, update func(*Usage)) {
+	if l == nil {
+		return
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	u, ok := l.peers[p]
+	if !ok {
+		u = &Usage{}
+		l.peers[p] = u
+	}
+	update(u)
+}
+
+func (l *Ledger) sent(p peer.ID, n int) {
+	l.record(p, func(u *Usage) { u.BytesSent += uint64(n) })
+}
+
+func (l *Ledger) received(p peer.ID, n int) {
+	l.record(p, func(u *Usage) { u.BytesReceived += uint64(n) })
+}
+
+func (l *Ledger) served(p peer.ID) {
+	l.record(p, func(u *Usage) { u.BlocksServed++ })
+}
+
+func (l *Ledger) answered(p peer.ID, queries int, d time.Duration) {
+	l.record(p, func(u *Usage) {
+		u.PIRQueries += uint64(queries)
+		u.PIRTime += d
+	})
+}
@@ -0,0 +1,74 @@
+package bitswapserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestLedger(t *testing.T) {
+	l := NewLedger()
+	l.sent("a", 10)
+	l.received("a", 5)
+	l.served("a")
+	l.answered("a", 3, time.Second)
+	l.answered("b", 1, time.Millisecond)
+
+	want := Usage{BytesSent: 10, BytesReceived: 5, BlocksServed: 1, PIRQueries: 3, PIRTime: time.Second}
+	if u := l.Usage("a"); u != want {
+		t.Fatalf("got %+v, expected %+v", u, want)
+	}
+	if all := l.All(); len(all) != 2 || all["b"].PIRQueries != 1 {
+		t.Fatalf("got %v", all)
+	}
+	if u := l.Reset("a"); u != want {
+		t.Fatalf("reset returned %+v, expected %+v", u, want)
+	}
+	if u := l.Usage("a"); u != (Usage{}) {
+		t.Fatalf("reset peer has usage %+v", u)
+	}
+
+	var disabled *Ledger
+	disabled.served("a")
+	if u := disabled.Usage("a"); u != (Usage{}) || len(disabled.All()) != 0 {
+		t.Fatal("nil ledger recorded usage")
+	}
+}
+
+func TestLedgerServed(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(bs, []byte("hello world"))
+	l := NewLedger()
+	h, err := newHandler(bs, WithLedger(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	go ss.writeLoop()
+
+	m := bitswap_message_pb.Message{Wantlist: bitswap_message_pb.Message_Wantlist{Entries: []bitswap_message_pb.Message_Wantlist_Entry{{
+		Block:    bitswap_message_pb.Cid{Cid: c},
+		WantType: bitswap_message_pb.Message_Wantlist_Block,
+	}}}}
+	msg, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.onMessage(context.Background(), ss, msg); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for u := l.Usage("remote"); u.BytesSent == 0 || u.BlocksServed == 0; u = l.Usage("remote") {
+		if time.Now().After(deadline) {
+			t.Fatal("block was not sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if u := l.Usage("remote"); u.BlocksServed != 1 || u.BytesSent < uint64(len("hello world")) {
+		t.Fatalf("got %+v", u)
+	}
+}
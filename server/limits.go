@@ -0,0 +1,204 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/time/rate"
+)
+
+var ErrRateLimited = errors.New("peer exceeded its PIR query rate")
+
+// Limits bounds the resources a single peer may use. Zero fields are
+// unlimited.
+type Limits struct {
+	// MaxStreams is the number of bitswap streams a peer may have open at once.
+	MaxStreams int
+	// PIRQueriesPerSecond is the sustained rate of PIR requests a peer may
+	// make. A peer exceeding it has its stream closed.
+	PIRQueriesPerSecond float64
+	// PIRBurst is the number of PIR requests a peer may make at once.
+	// Defaults to one second's worth, and at least one.
+	PIRBurst int
+	// BytesPerSecond bounds the rate at which responses are written to a peer,
+	// across all of its streams.
+	BytesPerSecond int
+}
+
+type peerLimits struct {
+	streams int
+	queries *rate.Limiter
+	bytes   *rate.Limiter
+}
+
+// idle reports whether the peer has no streams and has used none of its
+// allowance, so forgetting it changes nothing.
+func (pl *peerLimits) idle() bool {
+	full := func(l *rate.Limiter) bool {
+		return l == nil || l.Tokens() >= float64(l.Burst())
+	}
+	return pl.streams == 0 && full(pl.queries) && full(pl.bytes)
+}
+
+// limiter tracks the usage of each peer against a set of Limits.
+type limiter struct {
+	Limits
+
+	mtx   sync.Mutex
+	peers map[peer.ID]*peerLimits
+}
+
+func newLimiter(l Limits) *limiter {
+	return &limiter{Limits: l.withDefaults(), peers: make(map[peer.ID]*peerLimits)}
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.PIRQueriesPerSecond > 0 && l.PIRBurst <= 0 {
+		l.PIRBurst = int(l.PIRQueriesPerSecond)
+		if l.PIRBurst < 1 {
+			l.PIRBurst = 1
+		}
+	}
+	return l
+}
+
+// set replaces the limits, applying them to the peers known as well as
+// those to come. Streams opened while writes were unlimited stay so.
+func (l *limiter) set(limits Limits) {
+	limits = limits.withDefaults()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.Limits = limits
+	for _, pl := range l.peers {
+		switch {
+		case limits.PIRQueriesPerSecond <= 0:
+			pl.queries = nil
+		case pl.queries == nil:
+			pl.queries = rate.NewLimiter(rate.Limit(limits.PIRQueriesPerSecond), limits.PIRBurst)
+		default:
+			pl.queries.SetLimit(rate.Limit(limits.PIRQueriesPerSecond))
+			pl.queries.SetBurst(limits.PIRBurst)
+		}
+		switch {
+		case pl.bytes == nil:
+			// created as the peer's next stream asks for it.
+		case limits.BytesPerSecond <= 0:
+			// the streams of the peer hold its limiter, so it is lifted
+			// rather than dropped.
+			pl.bytes.SetLimit(rate.Inf)
+		default:
+			pl.bytes.SetLimit(rate.Limit(limits.BytesPerSecond))
+			pl.bytes.SetBurst(limits.BytesPerSecond)
+		}
+	}
+}
+
+// get returns the state of p. The caller holds mtx.
+func (l *limiter) get(p peer.ID) *peerLimits {
+	pl, ok := l.peers[p]
+	if !ok {
+		pl = &peerLimits{}
+		if l.PIRQueriesPerSecond > 0 {
+			pl.queries = rate.NewLimiter(rate.Limit(l.PIRQueriesPerSecond), l.PIRBurst)
+		}
+		if l.BytesPerSecond > 0 {
+			pl.bytes = rate.NewLimiter(rate.Limit(l.BytesPerSecond), l.BytesPerSecond)
+		}
+		l.peers[p] = pl
+	}
+	return pl
+}
+
+// openStream accounts for a new stream from p, reporting false if p already
+// has as many as it may.
+func (l *limiter) openStream(p peer.ID) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for op, pl := range l.peers {
+		if op != p && pl.idle() {
+			delete(l.peers, op)
+		}
+	}
+	pl := l.get(p)
+	if l.MaxStreams > 0 && pl.streams >= l.MaxStreams {
+		return false
+	}
+	pl.streams++
+	return true
+}
+
+func (l *limiter) closeStream(p peer.ID) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	pl, ok := l.peers[p]
+	if !ok {
+		return
+	}
+	pl.streams--
+	if pl.idle() {
+		delete(l.peers, p)
+	}
+}
+
+// streams returns the number of streams open.
+func (l *limiter) streams() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	n := 0
+	for _, pl := range l.peers {
+		n += pl.streams
+	}
+	return n
+}
+
+// allowQueries reports whether p may make n more PIR requests now.
+func (l *limiter) allowQueries(p peer.ID, n int) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.PIRQueriesPerSecond <= 0 || n == 0 {
+		return true
+	}
+	return l.get(p).queries.AllowN(time.Now(), n)
+}
+
+// bytesFor returns the limiter shared by the streams of p, or nil if writes
+// are unlimited.
+func (l *limiter) bytesFor(p peer.ID) *rate.Limiter {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.BytesPerSecond <= 0 {
+		return nil
+	}
+	pl := l.get(p)
+	if pl.bytes == nil {
+		pl.bytes = rate.NewLimiter(rate.Limit(l.BytesPerSecond), l.BytesPerSecond)
+	}
+	return pl.bytes
+}
+
+// waitBytes blocks until n bytes may be written under lim, which is as
+// returned by bytesFor, a burst at a time, so that n may exceed the burst
+// of lim once its limits are lowered.
+func waitBytes(lim *rate.Limiter, n int) error {
+	if lim == nil {
+		return nil
+	}
+	for n > 0 {
+		k := n
+		if b := lim.Burst(); k > b {
+			k = b
+		}
+		if err := lim.WaitN(context.Background(), k); err != nil {
+			if k > lim.Burst() {
+				// lowered since it was read.
+				continue
+			}
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package bitswapserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiter(t *testing.T) {
+	p1, p2 := peer.ID("one"), peer.ID("two")
+	l := newLimiter(Limits{MaxStreams: 1, PIRQueriesPerSecond: 0.001, PIRBurst: 2})
+
+	if !l.openStream(p1) || l.openStream(p1) {
+		t.Fatal("expected exactly one stream for p1")
+	}
+	if !l.openStream(p2) {
+		t.Fatal("limits should be per peer")
+	}
+	l.closeStream(p1)
+	if !l.openStream(p1) {
+		t.Fatal("closed stream should free its slot")
+	}
+
+	if !l.allowQueries(p1, 2) {
+		t.Fatal("expected burst to be allowed")
+	}
+	if l.allowQueries(p1, 1) {
+		t.Fatal("expected query over rate to be refused")
+	}
+	if !l.allowQueries(p2, 1) {
+		t.Fatal("p2 should have its own allowance")
+	}
+	// closing the stream must not reset the allowance.
+	l.closeStream(p1)
+	if !l.openStream(p1) || l.allowQueries(p1, 1) {
+		t.Fatal("allowance reset by reconnecting")
+	}
+}
+
+func TestLimiterSet(t *testing.T) {
+	p := peer.ID("one")
+	l := newLimiter(Limits{MaxStreams: 1, PIRQueriesPerSecond: 0.001, PIRBurst: 1, BytesPerSecond: 10})
+	if !l.openStream(p) || !l.allowQueries(p, 1) || l.allowQueries(p, 1) {
+		t.Fatal("expected one stream and one query")
+	}
+	bytes := l.bytesFor(p)
+
+	// raised limits apply to the peer's open streams, its allowance
+	// refilling at the new rate.
+	l.set(Limits{MaxStreams: 2, PIRQueriesPerSecond: 1000, PIRBurst: 10})
+	time.Sleep(20 * time.Millisecond)
+	if !l.openStream(p) || !l.allowQueries(p, 10) {
+		t.Fatal("raised limits not applied")
+	}
+	if l.bytesFor(p) != nil || bytes.Limit() != rate.Inf {
+		t.Fatal("writes still limited")
+	}
+
+	// and so do lowered ones.
+	l.set(Limits{MaxStreams: 1, PIRQueriesPerSecond: 0.001, PIRBurst: 1, BytesPerSecond: 5})
+	if l.openStream(p) || l.allowQueries(p, 1) {
+		t.Fatal("lowered limits not applied")
+	}
+	if l.bytesFor(p) != bytes || bytes.Burst() != 5 {
+		t.Fatal("writes not limited again")
+	}
+	if err := waitBytes(bytes, 7); err != nil {
+		t.Fatalf("write over the lowered burst failed with %v", err)
+	}
+}
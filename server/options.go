@@ -0,0 +1,447 @@
+package bitswapserver
+
+import (
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/padding"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+)
+
+const (
+	// DefaultWorkers is the number of block requests served concurrently.
+	DefaultWorkers = 8
+	// DefaultSendQueueDepth is the number of responses which may wait to be
+	// written to a stream before the server stops reading from it.
+	DefaultSendQueueDepth = 5
+	// DefaultBlockstoreTimeout bounds each blockstore lookup.
+	DefaultBlockstoreTimeout = time.Second
+	// DefaultPIRQueueDepth is the number of PIR requests which may wait for
+	// a worker.
+	DefaultPIRQueueDepth = 256
+	// DefaultKeepalive is how often a client is told a PIR answer is still
+	// being computed.
+	DefaultKeepalive = 10 * time.Second
+	// DefaultMaxSubtreeBytes is the most bytes of blocks sent for one
+	// subtree want.
+	DefaultMaxSubtreeBytes = 64 << 20
+)
+
+// Timeouts bound each phase of serving a peer.
+type Timeouts struct {
+	// Idle is how long a stream may go without a message, while no request
+	// on it is outstanding, before it is closed. Zero keeps idle streams
+	// open.
+	Idle time.Duration
+	// Block bounds serving a plaintext block request.
+	Block time.Duration
+	// Handshake bounds answering a PIR handshake, which may first have to
+	// encode the PIR databases.
+	Handshake time.Duration
+	// IndexRound and BlockRound bound answering the first and second round
+	// of a private retrieval, including the time the request waits for a
+	// worker. Requests which wait out their timeout are not answered, and
+	// their stream is closed.
+	IndexRound time.Duration
+	BlockRound time.Duration
+	// Send is how long a response may wait for room in a stream's full send
+	// queue, while reads from the stream pause, before the client is
+	// considered too slow and the response is dropped.
+	Send time.Duration
+}
+
+// DefaultTimeouts keep idle streams open, and leave PIR rounds, whose
+// answers take a pass over a whole database, far longer than plaintext
+// requests.
+var DefaultTimeouts = Timeouts{
+	Block:      MaxRequestTimeout,
+	Handshake:  MaxRequestTimeout,
+	IndexRound: 5 * time.Minute,
+	BlockRound: 5 * time.Minute,
+	Send:       10 * time.Second,
+}
+
+// round returns the timeout of the PIR round r.
+func (t Timeouts) round(r bitswap_message_pb.Message_PIRRound) time.Duration {
+	if r == bitswap_message_pb.Message_BlockRound || r == bitswap_message_pb.Message_BatchBlockRound {
+		return t.BlockRound
+	}
+	return t.IndexRound
+}
+
+// MetricsSink receives measurements of the server's activity, such as the
+// "blocks_served" counter. See the metrics package for a Prometheus sink.
+type MetricsSink = bitswap.MetricsSink
+
+type nopSink struct{}
+
+func (nopSink) Add(string, float64)     {}
+func (nopSink) Observe(string, float64) {}
+
+// An Option configures a server created by AttachBitswapServer.
+type Option func(*config)
+
+type config struct {
+	timeouts          Timeouts
+	blockstoreTimeout time.Duration
+	sendQueueDepth    int
+	maxMessageSize    int
+	maxSubtreeBytes   uint64
+	answerCacheSize   int
+
+	// pir lists the PIR databases to answer from, in order of preference.
+	pir []pirSource
+	// groups are the roots of the content groups laid out apart for each
+	// scheme given with WithPIRScheme.
+	groups []cid.Cid
+	// snapshotDir, if set, is where the PIR databases laid out by the
+	// server are saved, sealed with snapshotKey if it is set.
+	snapshotDir string
+	snapshotKey []byte
+
+	scheduler Scheduler
+	workers   int
+	limits    Limits
+
+	pirScheduler  Scheduler
+	pirWeights    func(peer.ID) float64
+	pirWorkers    int
+	pirQueueDepth int
+	admission     Admission
+	keepalive     time.Duration
+	answerPeriod  time.Duration
+	padding       padding.Policy
+
+	authorizer Authorizer
+	settlement Settlement
+	receipts   bool
+	metrics    MetricsSink
+	ledger     *Ledger
+	monitor    *Monitor
+}
+
+func defaultConfig() config {
+	return config{
+		timeouts:          DefaultTimeouts,
+		blockstoreTimeout: DefaultBlockstoreTimeout,
+		sendQueueDepth:    DefaultSendQueueDepth,
+		maxMessageSize:    MaxSendMsgSize,
+		maxSubtreeBytes:   DefaultMaxSubtreeBytes,
+		answerCacheSize:   DefaultAnswerCacheSize,
+		workers:           DefaultWorkers,
+		pirQueueDepth:     DefaultPIRQueueDepth,
+		keepalive:         DefaultKeepalive,
+		metrics:           nopSink{},
+	}
+}
+
+// WithRequestTimeout sets how long a plaintext block request may take to be
+// answered. Defaults to MaxRequestTimeout. See WithTimeouts for the other
+// phases.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeouts.Block = d
+	}
+}
+
+// WithTimeouts sets the timeout of each phase of serving a peer. Zero fields
+// keep their defaults, from DefaultTimeouts.
+func WithTimeouts(t Timeouts) Option {
+	return func(c *config) {
+		for _, f := range []struct{ to, from *time.Duration }{
+			{&c.timeouts.Idle, &t.Idle},
+			{&c.timeouts.Block, &t.Block},
+			{&c.timeouts.Handshake, &t.Handshake},
+			{&c.timeouts.IndexRound, &t.IndexRound},
+			{&c.timeouts.BlockRound, &t.BlockRound},
+			{&c.timeouts.Send, &t.Send},
+		} {
+			if *f.from > 0 {
+				*f.to = *f.from
+			}
+		}
+	}
+}
+
+// WithKeepalive sets how often a client waiting on a PIR round is sent
+// progress while its answers are computed, so that answers taking longer than
+// the client's response timeout don't fail. Defaults to DefaultKeepalive;
+// zero disables progress.
+func WithKeepalive(d time.Duration) Option {
+	return func(c *config) {
+		c.keepalive = d
+	}
+}
+
+// WithBlockstoreTimeout bounds each blockstore lookup. Defaults to
+// DefaultBlockstoreTimeout.
+func WithBlockstoreTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.blockstoreTimeout = d
+	}
+}
+
+// WithSendQueueDepth sets how many responses may wait to be written to a
+// stream. Once they do, the server stops reading further requests from the
+// stream until there is room. Defaults to DefaultSendQueueDepth.
+func WithSendQueueDepth(n int) Option {
+	return func(c *config) {
+		c.sendQueueDepth = n
+	}
+}
+
+// WithMaxMessageSize sets the size above which blocks are split across
+// several messages. Defaults to MaxSendMsgSize.
+func WithMaxMessageSize(n int) Option {
+	return func(c *config) {
+		c.maxMessageSize = n
+	}
+}
+
+// WithMaxSubtreeBytes sets the most bytes of blocks sent for a want of a
+// subtree, whatever the client asks for. Defaults to DefaultMaxSubtreeBytes.
+func WithMaxSubtreeBytes(n uint64) Option {
+	return func(c *config) {
+		c.maxSubtreeBytes = n
+	}
+}
+
+// WithAnswerCacheSize sets how many bytes of PIR answers are kept, so that
+// identical queries, such as retries, are answered without another pass over
+// the database. Zero disables the cache. Defaults to DefaultAnswerCacheSize.
+func WithAnswerCacheSize(n int) Option {
+	return func(c *config) {
+		c.answerCacheSize = n
+	}
+}
+
+// pirSource is either a store, or the scheme and options to lay the
+// blockstore out with when the server is attached.
+type pirSource struct {
+	store  *pirstore.Store
+	scheme pir.Scheme
+	opts   pirstore.Options
+}
+
+// WithPIRStore answers PIR queries against db, as created by NewPIRStore.
+// The caller may keep db up to date as the blockstore changes. Stores
+// created by NewPIRGroupStore answer for their content group, alongside the
+// stores of the whole blockstore.
+//
+// PIR options may be given several times to answer with several schemes,
+// which clients choose between in the handshake. A later option for a scheme
+// already given replaces it. Clients which do not say which schemes they
+// support are answered with the scheme given first.
+func WithPIRStore(db *pirstore.Store) Option {
+	return func(c *config) {
+		c.addPIR(pirSource{store: db})
+	}
+}
+
+// WithPIRScheme answers PIR queries with scheme, against a layout of the
+// blockstore made when the server is attached. The blockstore must be
+// enumerable. See WithPIRStore for offering several schemes.
+func WithPIRScheme(scheme pir.Scheme, opts pirstore.Options) Option {
+	return func(c *config) {
+		c.addPIR(pirSource{scheme: scheme, opts: opts})
+	}
+}
+
+// WithSnapshots saves the PIR databases laid out by WithPIRScheme to dir,
+// once encoded, and loads them from there in place of encoding them when the
+// server is attached to the same blocks again, which for large stores saves
+// most of the time it takes to start. key, if set, seals them, as
+// pirstore.Store.Save describes.
+func WithSnapshots(dir string, key []byte) Option {
+	return func(c *config) {
+		c.snapshotDir, c.snapshotKey = dir, key
+	}
+}
+
+func (s pirSource) id() string {
+	if s.store != nil {
+		return storeID(s.store)
+	}
+	return s.scheme.ID()
+}
+
+// WithPIRGroups also lays out the DAG of each of roots, as held when the
+// server is attached, apart for each scheme given with WithPIRScheme, with
+// its options. Clients which know the root of the content they want offer
+// it in the handshake, and query the far smaller databases of its group.
+// Groups lose the blocks deleted from the blockstore, but are not grown by
+// those put, which need not belong to them.
+func WithPIRGroups(roots ...cid.Cid) Option {
+	return func(c *config) {
+		c.groups = append(c.groups, roots...)
+	}
+}
+
+func (c *config) addPIR(src pirSource) {
+	for i, s := range c.pir {
+		if s.id() == src.id() {
+			c.pir[i] = src
+			return
+		}
+	}
+	c.pir = append(c.pir, src)
+}
+
+// WithScheduler sets the order in which block requests are served. It must
+// not be shared with another server. Defaults to NewPriorityScheduler.
+func WithScheduler(s Scheduler) Option {
+	return func(c *config) {
+		c.scheduler = s
+	}
+}
+
+// WithWorkers sets how many block requests are served concurrently.
+// Defaults to DefaultWorkers.
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		c.workers = n
+	}
+}
+
+// WithPIRWorkers sets how many PIR answers are computed concurrently.
+// Answers are bound by CPU, so it defaults to GOMAXPROCS.
+func WithPIRWorkers(n int) Option {
+	return func(c *config) {
+		c.pirWorkers = n
+	}
+}
+
+// WithPIRQueueDepth sets how many PIR requests may wait for a worker. Streams
+// sending requests to a full queue are closed. Defaults to
+// DefaultPIRQueueDepth; zero or less leaves the queue unbounded.
+func WithPIRQueueDepth(n int) Option {
+	return func(c *config) {
+		c.pirQueueDepth = n
+	}
+}
+
+// WithAdmission bounds the work of the PIR requests admitted but not yet
+// answered, estimated from the databases they query, as a says. Requests
+// over budget are refused with a Busy error telling the client when to
+// retry, counted as "pir_over_budget", rather than queued; those deferred
+// first are counted as "pir_deferred". Unlike WithPIRQueueDepth, which
+// counts requests, it weighs them by their cost, and fails only the
+// requests refused rather than their stream.
+func WithAdmission(a Admission) Option {
+	return func(c *config) {
+		c.admission = a
+	}
+}
+
+// WithPIRScheduler sets the order in which PIR requests are answered. Each
+// request is a task whose Cost is the work of its answer estimated by
+// pir.AnswerCost, in nanoseconds; second rounds have a higher Priority than
+// first rounds, so retrievals which are under way finish before new ones
+// start. It must not be shared with another server. Defaults to
+// NewWFQScheduler, with the weights of WithPIRWeights, so that peers share
+// the PIR workers fairly.
+func WithPIRScheduler(s Scheduler) Option {
+	return func(c *config) {
+		c.pirScheduler = s
+	}
+}
+
+// WithPIRWeights weighs the share of the PIR workers each peer is given, when
+// several have requests waiting, by weight: a peer of weight 2 has twice the
+// work of one of weight 1 answered. Peers are weighed alike by default. It
+// has no effect with WithPIRScheduler.
+func WithPIRWeights(weight func(peer.ID) float64) Option {
+	return func(c *config) {
+		c.pirWeights = weight
+	}
+}
+
+// WithAnswerPeriod releases each PIR answer a whole number of periods
+// after its request was received, rather than as soon as it is computed, so
+// the time taken to answer reveals nothing of the database, such as the
+// locality of the positions queried or hits in the answer cache. A period
+// longer than answers take to compute, queueing included, releases every
+// answer after exactly one period; answers taking longer are released at
+// the next period, and counted as "pir_answer_overruns". Zero, the default,
+// releases answers at once.
+func WithAnswerPeriod(d time.Duration) Option {
+	return func(c *config) {
+		c.answerPeriod = d
+	}
+}
+
+// WithPadding pads every message the server sends up to a bucket of p, so
+// its size does not reveal which block it carries. The padding added is
+// counted as "padding_bytes". Messages which would be padded beyond
+// bitswap.MaxBlockSize are sent as they are. Pad PIR databases with
+// pirstore.Options.Padding.
+func WithPadding(p padding.Policy) Option {
+	return func(c *config) {
+		c.padding = p
+	}
+}
+
+// WithLimits bounds what each peer may ask of the server.
+func WithLimits(l Limits) Option {
+	return func(c *config) {
+		c.limits = l
+	}
+}
+
+// WithAuthorizer serves only the streams, and the private retrievals, a
+// allows. Refused private streams are answered with an Unauthorized error
+// and closed; refused plain bitswap streams are reset, as stock bitswap
+// peers know no errors. Refusals are counted as "streams_unauthorized".
+// Every peer is served by default.
+func WithAuthorizer(a Authorizer) Option {
+	return func(c *config) {
+		c.authorizer = a
+	}
+}
+
+// WithSettlement charges peers for their PIR queries with s, which is asked
+// to admit each query as it is received, and told what each answered cost.
+// Queries it refuses fail with the error it returns, and are counted as
+// "pir_unsettled". See NewQuotaSettlement for quotas.
+func WithSettlement(s Settlement) Option {
+	return func(c *config) {
+		c.settlement = s
+	}
+}
+
+// WithReceipts signs a receipt for each PIR answer whose client asks for one,
+// attesting with the server's peer key that it answered the query, at the
+// epoch and time given, with that answer. Clients may hold receipts as proof
+// of what they were served. Servers whose host has no private key sign
+// none. Receipts signed are counted as "pir_receipts_signed".
+func WithReceipts() Option {
+	return func(c *config) {
+		c.receipts = true
+	}
+}
+
+// WithMetrics reports the server's activity to m.
+func WithMetrics(m MetricsSink) Option {
+	return func(c *config) {
+		c.metrics = m
+	}
+}
+
+// WithMonitor lets m read the state of the server, such as its queues and
+// the epochs of its PIR databases, once it is created.
+func WithMonitor(m *Monitor) Option {
+	return func(c *config) {
+		c.monitor = m
+	}
+}
+
+// WithLedger accounts for the usage of the server by each peer in l.
+func WithLedger(l *Ledger) Option {
+	return func(c *config) {
+		c.ledger = l
+	}
+}
@@ -0,0 +1,50 @@
+package bitswapserver
+
+import (
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// pad fills m up to the size of its padding bucket. A padding field costs a
+// tag and a length besides its bytes, so m is padded to the smallest bucket
+// with room for an empty one. Messages which would be padded beyond
+// bitswap.MaxBlockSize, which clients refuse, are left as they are.
+func (ss *streamSender) pad(m *bitswap_message_pb.Message) {
+	size := m.Size()
+	target := ss.padding.Size(size + 2)
+	if target > bitswap.MaxBlockSize {
+		return
+	}
+	gap := target - size
+	if n := filler(gap); n >= 0 {
+		m.Padding = [][]byte{make([]byte, n)}
+	} else {
+		// no field takes exactly gap bytes, as its length grew a byte, but
+		// an empty field and one two bytes shorter do.
+		m.Padding = [][]byte{nil, make([]byte, filler(gap-2))}
+	}
+	ss.metrics.Add("padding_bytes", float64(gap))
+}
+
+// filler returns the length of the padding field taking n bytes, or -1 if
+// there is none.
+func filler(n int) int {
+	for l := n - 2; l >= 0; l-- {
+		w := 1 + varintLen(l) + l
+		if w == n {
+			return l
+		}
+		if w < n {
+			return -1
+		}
+	}
+	return -1
+}
+
+func varintLen(x int) int {
+	n := 1
+	for ; x >= 0x80; x >>= 7 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package bitswapserver
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/padding"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestPadMessages(t *testing.T) {
+	policy := padding.PowersOfTwo(64, 1<<20)
+	metrics := countingSink{}
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)), WithPadding(policy), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	var sizes []int
+	// padding fields change size where their length does.
+	for n := 0; n < 300; n++ {
+		sizes = append(sizes, n)
+	}
+	for n := 16250; n < 16500; n++ {
+		sizes = append(sizes, n)
+	}
+	added := 0
+	for _, n := range sizes {
+		m := bitswap_message_pb.Message{Blocks: [][]byte{make([]byte, n)}}
+		size := m.Size()
+		msg, err := ss.frame(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, ln := binary.Uvarint(msg)
+		if want := policy.Size(size + 2); int(l) != want {
+			t.Fatalf("block of %d bytes: message of %d bytes padded to %d, expected %d", n, size, l, want)
+		}
+		got := bitswap_message_pb.Message{}
+		if err := got.Unmarshal(msg[ln:]); err != nil || len(got.Blocks) != 1 || len(got.Blocks[0]) != n {
+			t.Fatalf("padded message of %d bytes did not parse: %v", n, err)
+		}
+		added += int(l) - size
+		ss.buffers.put(msg)
+	}
+	if metrics["padding_bytes"] != float64(added) {
+		t.Fatalf("counted %v padding bytes, added %d", metrics["padding_bytes"], added)
+	}
+}
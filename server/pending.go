@@ -0,0 +1,165 @@
+package bitswapserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// pendingWork is outstanding work on a stream which the client may cancel.
+// Several requests, such as the parts of a PIR round, may share one entry.
+type pendingWork struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	refs   int
+}
+
+func cidWork(c cid.Cid) string {
+	return "cid/" + c.KeyString()
+}
+
+func pirWork(session uint64) string {
+	return fmt.Sprintf("pir/%d", session)
+}
+
+func isPIRWork(key string) bool {
+	return strings.HasPrefix(key, "pir/")
+}
+
+// forget drops the work under key, which ss.pendingMtx guards, ending the
+// protection of the connection once no PIR work is left.
+func (ss *streamSender) forget(key string) {
+	delete(ss.pending, key)
+	if isPIRWork(key) && ss.protect != nil {
+		ss.protect.finish(ss.Conn().RemotePeer())
+	}
+}
+
+// track registers work under key, returning a context which is cancelled
+// if the client cancels key or the stream ends. Work is not registered once
+// the stream has ended, and its context is done already.
+func (ss *streamSender) track(key string) context.Context {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	if ss.ctx.Err() != nil {
+		return ss.ctx
+	}
+	w, ok := ss.pending[key]
+	if !ok {
+		ctx, cncl := context.WithCancel(ss.ctx)
+		w = &pendingWork{ctx: ctx, cancel: cncl}
+		ss.pending[key] = w
+		if isPIRWork(key) && ss.protect != nil {
+			ss.protect.start(ss.Conn().RemotePeer())
+		}
+	}
+	w.refs++
+	return w.ctx
+}
+
+// release marks one unit of work under key as finished. It reports whether
+// the work is still wanted, i.e. was not cancelled.
+func (ss *streamSender) release(key string) bool {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	w, ok := ss.pending[key]
+	if !ok {
+		return false
+	}
+	w.refs--
+	if w.refs <= 0 {
+		w.cancel()
+		ss.forget(key)
+	}
+	return true
+}
+
+// cancelWork abandons all work under key.
+func (ss *streamSender) cancelWork(key string) {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	if w, ok := ss.pending[key]; ok {
+		w.cancel()
+		ss.forget(key)
+	}
+}
+
+// cancelAll cancels all work pending on ss, and any tracked later, once its
+// stream has ended.
+func (ss *streamSender) cancelAll() {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	ss.cancel()
+	for key, w := range ss.pending {
+		w.cancel()
+		ss.forget(key)
+	}
+}
+
+// startRequest registers the PIR request id as being answered, reporting
+// false if it already is: a client which resends a request before its answer
+// arrives has it answered once.
+func (ss *streamSender) startRequest(id uint64) bool {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	if _, ok := ss.requests[id]; ok {
+		return false
+	}
+	ss.requests[id] = struct{}{}
+	return true
+}
+
+// finishRequests forgets the PIR request ids once they are answered.
+func (ss *streamSender) finishRequests(reqs ...bitswap_message_pb.Message_PIRRequest) {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	for _, r := range reqs {
+		delete(ss.requests, r.Id)
+	}
+}
+
+// outstanding returns the number of pieces of work still pending on ss.
+func (ss *streamSender) outstanding() int {
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	return len(ss.pending)
+}
+
+// keepWants cancels the blocks being served which are not wanted by
+// entries, a full wantlist replacing the peer's earlier wants.
+func (ss *streamSender) keepWants(entries []bitswap_message_pb.Message_Wantlist_Entry) {
+	keep := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		if !e.Cancel {
+			keep[cidWork(e.Block.Cid)] = struct{}{}
+		}
+	}
+	ss.pendingMtx.Lock()
+	defer ss.pendingMtx.Unlock()
+	for key, w := range ss.pending {
+		if _, ok := keep[key]; !ok && strings.HasPrefix(key, "cid/") {
+			w.cancel()
+			delete(ss.pending, key)
+		}
+	}
+}
+
+// queueBytes adds n to the size of the blocks waiting to be sent.
+func (ss *streamSender) queueBytes(n int) {
+	atomic.AddInt64(&ss.queued, int64(n))
+}
+
+// pendingBytes is the size of the blocks waiting to be sent, which bitswap
+// 1.2 tells peers with every message.
+func (ss *streamSender) pendingBytes() int32 {
+	n := atomic.LoadInt64(&ss.queued)
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(n)
+}
@@ -0,0 +1,677 @@
+package bitswapserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/batch"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+)
+
+// MaxPIRSessionAge bounds how long the server remembers the first round of a
+// private retrieval while waiting for the second.
+const MaxPIRSessionAge = time.Minute
+
+var (
+	ErrNotEnumerable = errors.New("blockstore cannot enumerate its blocks")
+	ErrNoBatch       = errors.New("PIR store is not laid out for batches")
+	ErrUnknownScheme = errors.New("PIR scheme not served")
+	ErrBusy          = errors.New("too many PIR requests waiting")
+)
+
+// NewPIRStore lays out the contents of bs for private retrieval with scheme.
+// bs must be an EnumerableBlockstore, or a pirstore.Enumerable.
+func NewPIRStore(bs Blockstore, scheme pir.Scheme, opts pirstore.Options) (*pirstore.Store, error) {
+	if ebs, ok := bs.(EnumerableBlockstore); ok {
+		all, err := Enumerate(context.Background(), ebs)
+		if err != nil {
+			return nil, err
+		}
+		return pirstore.Load(all, scheme, opts)
+	}
+	if all, ok := bs.(pirstore.Enumerable); ok {
+		return pirstore.Load(all, scheme, opts)
+	}
+	return nil, ErrNotEnumerable
+}
+
+// NewPIRGroupStore lays out root and the blocks of bs reachable from it, as
+// decoded by dag.Links, all of which must be held, for private retrieval
+// with scheme as the content group of root. Each block is laid out with its
+// proof, as appended by dag.AppendProof: the blocks linking root to it by
+// the shortest path, so clients check it is part of the DAG they asked for
+// without trusting the server's CID→index map.
+func NewPIRGroupStore(ctx context.Context, bs Blockstore, root cid.Cid, scheme pir.Scheme, opts pirstore.Options) (*pirstore.Store, error) {
+	blocks := make(map[cid.Cid][]byte)
+	parents := make(map[cid.Cid]cid.Cid)
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if _, ok := blocks[c]; ok {
+			continue
+		}
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("walking %s from %s: %w", c, root, err)
+		}
+		links, err := dag.Links(c, blk.RawData())
+		if err != nil {
+			return nil, fmt.Errorf("walking %s from %s: %w", c, root, err)
+		}
+		blocks[c] = blk.RawData()
+		for _, l := range links {
+			if _, ok := parents[l]; !ok && l != root {
+				parents[l] = c
+			}
+		}
+		queue = append(queue, links...)
+	}
+	group := make(blockMap, len(blocks))
+	for c, data := range blocks {
+		var path [][]byte
+		for p, ok := parents[c]; ok; p, ok = parents[p] {
+			path = append([][]byte{blocks[p]}, path...)
+		}
+		group[c] = dag.AppendProof(nil, data, path)
+	}
+	opts.Group = root
+	return pirstore.Load(group, scheme, opts)
+}
+
+// storeID names a store apart from the others of a server: by its scheme,
+// and its content group if it has one.
+func storeID(store *pirstore.Store) string {
+	if g := store.Group(); g.Defined() {
+		return store.Scheme().ID() + "@" + g.String()
+	}
+	return store.Scheme().ID()
+}
+
+// snapshotPath is the file in dir the databases of scheme are saved to.
+func snapshotPath(dir string, scheme pir.Scheme) string {
+	return filepath.Join(dir, strings.ReplaceAll(scheme.ID(), "/", "_")+".pirdb")
+}
+
+// restore loads the databases of db saved in dir, or encodes and saves them
+// if none were saved for its contents. Unreadable files are replaced, but
+// not those sealed under another key, which may be a mistaken key.
+func restore(db *pirstore.Store, dir string, key []byte) error {
+	path := snapshotPath(dir, db.Scheme())
+	ok, err := db.Restore(path, key)
+	if errors.Is(err, pirstore.ErrSnapshotKey) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err != nil {
+		pirLogger.Warnw("replacing unreadable PIR snapshot", "path", path, "err", err)
+	}
+	if ok {
+		pirLogger.Infow("loaded PIR databases", "path", path)
+		return nil
+	}
+	if err := db.Save(path, key); errors.Is(err, pir.ErrNotPersistent) {
+		pirLogger.Warnw("PIR databases not saved", "scheme", db.Scheme().ID(), "err", err)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// follow applies a mutation of a MutableBlockstore to the PIR stores built
+// from it. Blocks too large for a store are left out of it, and so only
+// served in plaintext.
+func follow(stores []*pirstore.Store, c cid.Cid, data []byte) {
+	for _, db := range stores {
+		var err error
+		if data == nil {
+			err = db.Remove(c)
+		} else {
+			err = db.Add(c, data)
+		}
+		if err != nil && !errors.Is(err, pirstore.ErrNotHave) {
+			pirLogger.Warnw("failed to update PIR store", "cid", c, "err", err)
+		}
+	}
+}
+
+// blockMap is a pirstore.Enumerable of blocks read into memory.
+type blockMap map[cid.Cid][]byte
+
+func (m blockMap) GetAll() map[cid.Cid][]byte {
+	return m
+}
+
+// Enumerate reads every block of bs into memory, as pirstore loads and
+// syncs from.
+func Enumerate(ctx context.Context, bs EnumerableBlockstore) (pirstore.Enumerable, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := make(blockMap)
+	for c := range keys {
+		blk, err := bs.Get(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", c, err)
+		}
+		all[c] = blk.RawData()
+	}
+	return all, nil
+}
+
+// Size returns the number of blocks in bs and the size of the largest,
+// which bound the size of its PIR databases, without reading the blocks.
+func Size(ctx context.Context, bs EnumerableBlockstore) (count, largest int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for c := range keys {
+		size, err := bs.GetSize(ctx, c)
+		if err != nil {
+			return 0, 0, fmt.Errorf("sizing %s: %w", c, err)
+		}
+		count++
+		if size > largest {
+			largest = size
+		}
+	}
+	return count, largest, nil
+}
+
+type inflightKey struct {
+	peer peer.ID
+	// store is the storeID of the store answering.
+	store   string
+	session uint64
+}
+
+// inflight remembers which snapshot answered the first round of a session
+// so the position it revealed is resolved against the same blocks. A batched
+// session may run several rounds of each kind, so sessions are only
+// forgotten once they expire.
+type inflight struct {
+	db      *pirstore.Snapshot
+	started time.Time
+}
+
+type inflightTable struct {
+	mtx      sync.Mutex
+	sessions map[inflightKey]inflight
+}
+
+// start pins db to the session, returning the snapshot the session is
+// answered from: db, or the one pinned by an earlier round.
+func (t *inflightTable) start(k inflightKey, db *pirstore.Snapshot) *pirstore.Snapshot {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.sessions == nil {
+		t.sessions = make(map[inflightKey]inflight)
+	}
+	now := time.Now()
+	for ok, s := range t.sessions {
+		if now.Sub(s.started) > MaxPIRSessionAge {
+			delete(t.sessions, ok)
+		}
+	}
+	if s, ok := t.sessions[k]; ok {
+		return s.db
+	}
+	t.sessions[k] = inflight{db, now}
+	return db
+}
+
+// finish returns the snapshot the session started with.
+func (t *inflightTable) finish(k inflightKey) (*pirstore.Snapshot, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	s, ok := t.sessions[k]
+	if !ok || time.Since(s.started) > MaxPIRSessionAge {
+		return nil, false
+	}
+	return s.db, true
+}
+
+// inUse reports whether the block named by c is laid out in a snapshot
+// answering an unexpired session.
+func (t *inflightTable) inUse(c cid.Cid) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	checked := make(map[*pirstore.Snapshot]bool)
+	for _, s := range t.sessions {
+		if checked[s.db] || time.Since(s.started) > MaxPIRSessionAge {
+			continue
+		}
+		checked[s.db] = true
+		if s.db.Has(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeFor returns the store answering with the scheme identified by id, or
+// the preferred store if id is empty.
+func (h *handler) storeFor(id string) (*pirstore.Store, error) {
+	if len(h.stores) == 0 {
+		return nil, ErrNoPIR
+	}
+	if id == "" {
+		return h.stores[0], nil
+	}
+	for _, st := range h.stores {
+		if st.Scheme().ID() == id {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownScheme, id)
+}
+
+// storeIn returns the store answering for the content group named by group
+// with the scheme identified by id, or the preferred scheme if id is empty.
+// Groups the server does not lay out apart with that scheme, and an empty
+// group, are answered from the store of the whole blockstore.
+func (h *handler) storeIn(group []byte, id string) (*pirstore.Store, error) {
+	if len(group) > 0 {
+		for _, st := range h.groups {
+			if string(st.Group().Bytes()) == string(group) && (id == "" || st.Scheme().ID() == id) {
+				return st, nil
+			}
+		}
+	}
+	return h.storeFor(id)
+}
+
+// timedHandshake answers a handshake, failing with ErrTimeout if the stores
+// take longer than the handshake timeout to snapshot their databases.
+func (h *handler) timedHandshake(offer *bitswap_message_pb.Message_PIROffer) (*bitswap_message_pb.Message_PIRHandshake, error) {
+	type result struct {
+		hs  *bitswap_message_pb.Message_PIRHandshake
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		hs, err := h.handshake(offer)
+		done <- result{hs, err}
+	}()
+	t := time.NewTimer(h.cfg.timeouts.Handshake)
+	defer t.Stop()
+	select {
+	case r := <-done:
+		return r.hs, r.err
+	case <-t.C:
+		h.cfg.metrics.Add("pir_timeouts", 1)
+		pirLogger.Warnw("PIR handshake timed out")
+		return nil, ErrTimeout
+	}
+}
+
+// manifest returns the signed commitment to the CID→index map of db, a
+// snapshot of st, or nil if the server has no key to sign it with.
+func (h *handler) manifest(st *pirstore.Store, db *pirstore.Snapshot) *bitswap_message_pb.Message_PIRManifest {
+	if h.key == nil || db.Manifest == nil {
+		return nil
+	}
+	scheme := st.Scheme().ID()
+	var group []byte
+	if st.Group().Defined() {
+		group = st.Group().Bytes()
+	}
+	sig, err := h.key.Sign(bitswap_message_pb.ManifestPayload(scheme, group, db.Epoch, db.Manifest))
+	if err != nil {
+		pirLogger.Warnw("failed to sign PIR manifest", "err", err)
+		return nil
+	}
+	return &bitswap_message_pb.Message_PIRManifest{Scheme: scheme, Group: group, Epoch: db.Epoch, Digest: db.Manifest, Signature: sig}
+}
+
+// receipt returns the signed receipt for answer, to req, from db, a snapshot
+// of st, or nil unless the client asked for one and the server signs them.
+func (h *handler) receipt(st *pirstore.Store, db *pirstore.Snapshot, req bitswap_message_pb.Message_PIRRequest, answer []byte) *bitswap_message_pb.Message_PIRReceipt {
+	if !req.Receipt || !h.cfg.receipts || h.key == nil {
+		return nil
+	}
+	query, digest := sha256.Sum256(req.Query), sha256.Sum256(answer)
+	r := &bitswap_message_pb.Message_PIRReceipt{
+		Scheme:    st.Scheme().ID(),
+		Epoch:     db.Epoch,
+		Query:     query[:],
+		Answer:    digest[:],
+		Timestamp: uint64(time.Now().UnixMilli()),
+	}
+	if st.Group().Defined() {
+		r.Group = st.Group().Bytes()
+	}
+	sig, err := h.key.Sign(r.Payload())
+	if err != nil {
+		pirLogger.Warnw("failed to sign PIR receipt", "err", err)
+		return nil
+	}
+	r.Signature = sig
+	h.cfg.metrics.Add("pir_receipts_signed", 1)
+	return r
+}
+
+// handshake describes to a client the current databases of the first scheme
+// it offered whose block database fits its limits. Clients offering nothing
+// are described the preferred scheme's databases. If nothing offered fits,
+// the databases are left empty and the client only learns which schemes
+// the server supports: none, if it isn't configured for private retrieval,
+// so clients may fall back to plaintext wants.
+func (h *handler) handshake(offer *bitswap_message_pb.Message_PIROffer) (*bitswap_message_pb.Message_PIRHandshake, error) {
+	hs := &bitswap_message_pb.Message_PIRHandshake{}
+	if len(h.stores) == 0 {
+		return hs, nil
+	}
+	for _, st := range h.stores {
+		hs.Schemes = append(hs.Schemes, st.Scheme().ID())
+	}
+	advertised := make(map[string]bool)
+	for _, st := range h.groups {
+		if g := string(st.Group().Bytes()); !advertised[g] {
+			advertised[g] = true
+			hs.Groups = append(hs.Groups, st.Group().Bytes())
+		}
+	}
+	candidates := h.stores[:1]
+	if offer != nil {
+		candidates = nil
+		for _, id := range offer.Schemes {
+			if id == "" {
+				continue
+			}
+			if st, err := h.storeIn(offer.Group, id); err == nil {
+				candidates = append(candidates, st)
+			}
+		}
+	}
+	for _, st := range candidates {
+		db, err := st.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		if offer != nil && !fits(offer, db.Blocks.Params) {
+			continue
+		}
+		hs.Epoch = db.Epoch
+		hs.Index = bitswap_message_pb.NewPIRParams(db.Index.Params)
+		hs.Blocks = bitswap_message_pb.NewPIRParams(db.Blocks.Params)
+		if db.IndexBatch != nil && db.BlocksBatch != nil {
+			hs.IndexBatch = bitswap_message_pb.NewPIRBatchParams(db.IndexBatch.Params)
+			hs.BlocksBatch = bitswap_message_pb.NewPIRBatchParams(db.BlocksBatch.Params)
+		}
+		if st.Group().Defined() {
+			hs.Group = st.Group().Bytes()
+		}
+		hs.Manifest = h.manifest(st, db)
+		if db.Filter != nil {
+			hs.Filter = bitswap_message_pb.NewPIRFilter(db.Filter)
+		}
+		return hs, nil
+	}
+	pirLogger.Debugw("no PIR scheme in common with client", "offered", offer.GetSchemes())
+	return hs, nil
+}
+
+// refusal returns why the handshake hs, answering offer, describes no
+// databases, or nil if it does.
+func (h *handler) refusal(offer *bitswap_message_pb.Message_PIROffer, hs *bitswap_message_pb.Message_PIRHandshake) error {
+	if hs.Index.Scheme != "" {
+		return nil
+	}
+	if len(h.stores) == 0 {
+		return ErrNoPIR
+	}
+	for _, id := range offer.GetSchemes() {
+		if _, err := h.storeFor(id); id != "" && err == nil {
+			return ErrDatabaseTooLarge
+		}
+	}
+	return fmt.Errorf("%w: offered %v", ErrUnknownScheme, offer.GetSchemes())
+}
+
+// checkEpoch fails requests built for other databases than db, if they name
+// the epoch they were built for.
+func checkEpoch(req bitswap_message_pb.Message_PIRRequest, db *pirstore.Snapshot) error {
+	if req.Epoch != 0 && req.Epoch != db.Epoch {
+		return fmt.Errorf("%w: request for epoch %d, serving %d", ErrStaleEpoch, req.Epoch, db.Epoch)
+	}
+	return nil
+}
+
+// fits reports whether a client making offer accepts a block database
+// with params.
+func fits(offer *bitswap_message_pb.Message_PIROffer, params pir.Params) bool {
+	if offer.MaxElements > 0 && params.NumElements > offer.MaxElements {
+		return false
+	}
+	return offer.MaxElementSize == 0 || params.ElementSize <= offer.MaxElementSize
+}
+
+// onPIRRequest answers one round of a private retrieval from p, unless ctx
+// is done before the answer is computed.
+func (h *handler) onPIRRequest(ctx context.Context, p peer.ID, req bitswap_message_pb.Message_PIRRequest) (bitswap_message_pb.Message_PIRResponse, error) {
+	resp := bitswap_message_pb.Message_PIRResponse{Session: req.Session, Round: req.Round, Part: req.Part, Id: req.Id}
+	store, err := h.storeIn(req.Group, req.Scheme)
+	if err != nil {
+		return resp, err
+	}
+	key := inflightKey{p, storeID(store), req.Session}
+	var db *pirstore.Snapshot
+	switch req.Round {
+	case bitswap_message_pb.Message_IndexRound:
+		if db, err = store.Snapshot(); err != nil {
+			break
+		}
+		if err = checkEpoch(req, db); err != nil {
+			break
+		}
+		db = h.inflight.start(key, db)
+		resp.Epoch = db.Epoch
+		resp.Answer, err = h.processPIRRequestFromEncryptedCIDToIndex(ctx, store, db, req.Query)
+	case bitswap_message_pb.Message_BlockRound:
+		var ok bool
+		if db, ok = h.inflight.finish(key); !ok {
+			if db, err = store.Snapshot(); err != nil {
+				break
+			}
+		}
+		if err = checkEpoch(req, db); err != nil {
+			break
+		}
+		resp.Epoch = db.Epoch
+		resp.Answer, err = h.processPIRRequestFromEncryptedIndexToBlock(ctx, store, db, req.Query)
+	default:
+		err = errors.New("unknown PIR round")
+	}
+	if err == nil {
+		resp.Receipt = h.receipt(store, db, req, resp.Answer)
+	}
+	return resp, err
+}
+
+// onHintRequest sends as much of the hint asked for by req as fits in a
+// message. Requests for the hints of other epochs than the current one are
+// answered with only the current epoch, so the client runs the handshake
+// again. Clients naming the epoch of a hint they hold are sent the changes to
+// it instead, if the store still keeps it.
+func (h *handler) onHintRequest(req bitswap_message_pb.Message_PIRHintRequest) (bitswap_message_pb.Message_PIRHint, error) {
+	resp := bitswap_message_pb.Message_PIRHint{Scheme: req.Scheme, Round: req.Round}
+	store, err := h.storeIn(req.Group, req.Scheme)
+	if err != nil {
+		return resp, err
+	}
+	db, err := store.Snapshot()
+	if err != nil {
+		return resp, err
+	}
+	resp.Epoch = db.Epoch
+	if req.Epoch != 0 && req.Epoch != db.Epoch {
+		return resp, nil
+	}
+	var hint []byte
+	switch req.Round {
+	case bitswap_message_pb.Message_IndexRound:
+		hint = db.Index.Params.Hint
+	case bitswap_message_pb.Message_BlockRound:
+		hint = db.Blocks.Params.Hint
+	default:
+		return resp, errors.New("unknown PIR round")
+	}
+	if req.Since != 0 && req.Since != db.Epoch {
+		// clients holding the hint of a snapshot still kept are sent
+		// only its changes.
+		if delta, ok := store.HintDelta(req.Since, db.Epoch, req.Round == bitswap_message_pb.Message_BlockRound); ok {
+			hint, resp.Since = delta, req.Since
+		}
+	}
+	if req.Offset > uint64(len(hint)) {
+		return resp, errors.New("hint request past the end of the hint")
+	}
+	end := req.Offset + uint64(h.cfg.maxMessageSize)
+	if end > uint64(len(hint)) {
+		end = uint64(len(hint))
+	}
+	resp.Offset, resp.Total, resp.Data = req.Offset, uint64(len(hint)), hint[req.Offset:end]
+	h.cfg.metrics.Add("pir_hint_bytes", float64(len(resp.Data)))
+	return resp, nil
+}
+
+// queuePIR queues run to answer requests of round from ss, of estimated
+// cost, unless too many requests are already waiting. Requests the server's
+// Settlement refuses are passed to refuse at once, with its error, and are
+// neither queued nor counted against the admission budget. Requests over
+// that budget wait, under ctx, up to its MaxDefer for room before they are
+// queued; those still over it then are passed to refuse instead, with a
+// BudgetError, as are those finding the queue full after waiting, with
+// ErrBusy. Servers without PIR stores have no workers for them, and start
+// run at once, as it fails without computing anything.
+func (h *handler) queuePIR(ctx context.Context, ss *streamSender, round bitswap_message_pb.Message_PIRRound, cost time.Duration, run func(), refuse func(error)) bool {
+	if err := h.admitQuery(ctx, ss.Conn().RemotePeer()); err != nil {
+		refuse(err)
+		return true
+	}
+	if h.pirTasks == nil {
+		go run()
+		return true
+	}
+	priority := int32(0)
+	if round == bitswap_message_pb.Message_BlockRound || round == bitswap_message_pb.Message_BatchBlockRound {
+		priority = 1
+	}
+	queued := time.Now()
+	push := func() bool {
+		ok := h.pirTasks.push(&Task{Peer: ss.Conn().RemotePeer(), Priority: priority, Cost: int(cost), run: func() {
+			defer h.admission.release(cost)
+			h.cfg.metrics.Observe("pir_queue_seconds", time.Since(queued).Seconds())
+			run()
+		}})
+		if !ok {
+			h.admission.release(cost)
+		}
+		return ok
+	}
+	err := h.admission.reserve(cost)
+	if err == nil {
+		return push()
+	}
+	if h.admission.get().MaxDefer <= 0 {
+		h.cfg.metrics.Add("pir_over_budget", 1)
+		refuse(err)
+		return true
+	}
+	h.cfg.metrics.Add("pir_deferred", 1)
+	go func() {
+		switch err := h.admission.wait(ctx, cost); {
+		case err == nil:
+			if !push() {
+				refuse(ErrBusy)
+			}
+		case ctx.Err() != nil:
+			// run answers nothing once its round is cancelled or timed
+			// out, but tells the client as it would have.
+			run()
+		default:
+			h.cfg.metrics.Add("pir_over_budget", 1)
+			refuse(err)
+		}
+	}()
+	return true
+}
+
+func isBatchRound(r bitswap_message_pb.Message_PIRRound) bool {
+	return r == bitswap_message_pb.Message_BatchIndexRound || r == bitswap_message_pb.Message_BatchBlockRound
+}
+
+// onPIRBatch answers the parts of one round of a batched retrieval from p,
+// one per bucket, passing each answer to send as it is computed, until ctx
+// is done. All parts are answered against the same snapshot, so the whole
+// batch costs about batch.NumHashes passes over the database.
+func (h *handler) onPIRBatch(ctx context.Context, p peer.ID, reqs []bitswap_message_pb.Message_PIRRequest, send func(bitswap_message_pb.Message_PIRResponse) error) error {
+	session, round := reqs[0].Session, reqs[0].Round
+	store, err := h.storeIn(reqs[0].Group, reqs[0].Scheme)
+	if err != nil {
+		return err
+	}
+	key := inflightKey{p, storeID(store), session}
+	var db *pirstore.Snapshot
+	switch round {
+	case bitswap_message_pb.Message_BatchIndexRound:
+		if db, err = store.Snapshot(); err != nil {
+			return err
+		}
+		if err := checkEpoch(reqs[0], db); err != nil {
+			return err
+		}
+		db = h.inflight.start(key, db)
+	case bitswap_message_pb.Message_BatchBlockRound:
+		var ok bool
+		if db, ok = h.inflight.finish(key); !ok {
+			if db, err = store.Snapshot(); err != nil {
+				return err
+			}
+		}
+		if err := checkEpoch(reqs[0], db); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unknown PIR round")
+	}
+	bdb, name := db.IndexBatch, "index"
+	if round == bitswap_message_pb.Message_BatchBlockRound {
+		bdb, name = db.BlocksBatch, "blocks"
+	}
+	if bdb == nil {
+		return ErrNoBatch
+	}
+	for _, r := range reqs {
+		r := r
+		answer, err := h.answer(ctx, store, db, name+"-batch", r.Part, r.Query, func() ([]byte, error) {
+			return batch.Answer(store.Scheme(), bdb, uint64(r.Part), r.Query)
+		})
+		if err != nil {
+			return err
+		}
+		resp := bitswap_message_pb.Message_PIRResponse{Session: session, Round: round, Part: r.Part, Answer: answer, Epoch: db.Epoch, Id: r.Id}
+		resp.Receipt = h.receipt(store, db, r, answer)
+		if err := send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
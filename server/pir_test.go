@@ -0,0 +1,573 @@
+package bitswapserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/multiformats/go-multihash"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/pirtest"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestEnumerate(t *testing.T) {
+	ctx := context.Background()
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	a := util.Add(bs, []byte("hello world"))
+	b := util.Add(bs, []byte("hello world 2"))
+	count, largest, err := Size(ctx, bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || largest != 13 {
+		t.Fatalf("sized %d blocks, the largest %d bytes", count, largest)
+	}
+	all, err := Enumerate(ctx, bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := all.GetAll(); len(got) != 2 || string(got[a]) != "hello world" || string(got[b]) != "hello world 2" {
+		t.Fatalf("enumerated %v", got)
+	}
+}
+
+func TestFollowMutations(t *testing.T) {
+	ctx := context.Background()
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	a := util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := h.stores[0]
+	b := util.Add(bs, []byte("hello world 2"))
+	if _, ok := db.Position(b); !ok {
+		t.Fatal("block put should be laid out for PIR")
+	}
+	if err := bs.DeleteBlock(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.Position(a); ok {
+		t.Fatal("block deleted should be dropped from PIR")
+	}
+	if db.Len() != 1 {
+		t.Fatalf("PIR store holds %d blocks", db.Len())
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	attach := func() *pirstore.Snapshot {
+		h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithSnapshots(dir, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap, err := h.stores[0].Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return snap
+	}
+	path := snapshotPath(dir, fastpir.New())
+	saved := attach()
+	first, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same blocks are loaded from the file, which is left as it is.
+	if snap := attach(); snap.Epoch != saved.Epoch {
+		t.Fatalf("restored epoch %d, saved %d", snap.Epoch, saved.Epoch)
+	}
+	if again, err := os.Stat(path); err != nil || !os.SameFile(first, again) {
+		t.Fatalf("snapshot of unchanged blocks saved again: %v", err)
+	}
+	util.Add(bs, []byte("hello world 2"))
+	attach()
+	if again, err := os.Stat(path); err != nil || os.SameFile(first, again) {
+		t.Fatalf("snapshot of changed blocks not saved again: %v", err)
+	}
+}
+
+// guarded is a blockstore recording its guard.
+type guarded struct {
+	MutableBlockstore
+	inUse func(c cid.Cid) bool
+}
+
+func (g *guarded) Guard(inUse func(c cid.Cid) bool) func() {
+	g.inUse = inUse
+	return func() {}
+}
+
+func TestGuardInflight(t *testing.T) {
+	bs := &guarded{MutableBlockstore: util.NewMemStore(make(map[cid.Cid][]byte))}
+	a := util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.inUse == nil {
+		t.Fatal("server should guard the blocks of in-flight sessions")
+	}
+	if bs.inUse(a) {
+		t.Fatal("block should not be in use before any session")
+	}
+	snap, err := h.stores[0].Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.inflight.start(inflightKey{session: 1}, snap)
+	b := util.Add(bs, []byte("hello world 2"))
+	if !bs.inUse(a) {
+		t.Fatal("block of an in-flight session's database should be in use")
+	}
+	if bs.inUse(b) {
+		t.Fatal("block added since should not be in use")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h := &handler{}
+	for _, scheme := range []pir.Scheme{fastpir.New(), spiral.New()} {
+		db, err := NewPIRStore(bs, scheme, pirstore.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.stores = append(h.stores, db)
+	}
+
+	for _, tc := range []struct {
+		offer  *bitswap_message_pb.Message_PIROffer
+		chosen string
+	}{
+		{nil, fastpir.ID},
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{spiral.ID, fastpir.ID}}, spiral.ID},
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{"fastpir-lwe1024/v0", fastpir.ID}}, fastpir.ID},
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{"fastpir-lwe1024/v0"}}, ""},
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}, MaxElements: 1}, ""},
+		{&bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}, MaxElementSize: 1 << 20}, fastpir.ID},
+	} {
+		hs, err := h.handshake(tc.offer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hs.Index.Scheme != tc.chosen || hs.Blocks.Scheme != tc.chosen {
+			t.Fatalf("offer %v: chose %q, expected %q", tc.offer, hs.Index.Scheme, tc.chosen)
+		}
+		if len(hs.Schemes) != 2 || hs.Schemes[0] != fastpir.ID || hs.Schemes[1] != spiral.ID {
+			t.Fatalf("advertised %v", hs.Schemes)
+		}
+	}
+
+	if _, err := h.storeFor("fastpir-lwe1024/v0"); err == nil {
+		t.Fatal("should not answer with an unsupported version")
+	}
+
+	hs, err := (&handler{}).handshake(nil)
+	if err != nil || len(hs.Schemes) != 0 || hs.Index.Scheme != "" {
+		t.Fatalf("server without PIR should advertise no schemes, got %v %v", hs, err)
+	}
+}
+
+func TestHintRequest(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	scheme := pirtest.Hinted(fastpir.New(), 1000)
+	metrics := countingSink{}
+	h, err := newHandler(bs, WithPIRScheme(scheme, pirstore.Options{}), WithMaxMessageSize(300), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := hs.Blocks.Params()
+
+	var hint []byte
+	for {
+		resp, err := h.onHintRequest(bitswap_message_pb.Message_PIRHintRequest{
+			Scheme: scheme.ID(),
+			Round:  bitswap_message_pb.Message_BlockRound,
+			Epoch:  hs.Epoch,
+			Offset: uint64(len(hint)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Offset != uint64(len(hint)) || resp.Total != 1000 || len(resp.Data) > 300 {
+			t.Fatalf("sent %d bytes at %d of %d", len(resp.Data), resp.Offset, resp.Total)
+		}
+		hint = append(hint, resp.Data...)
+		if len(hint) == 1000 {
+			break
+		}
+	}
+	if !pir.CheckHint(params, hint) {
+		t.Fatal("assembled hint does not match its digest")
+	}
+	if metrics["pir_hint_bytes"] != 1000 {
+		t.Fatalf("counted %v hint bytes", metrics["pir_hint_bytes"])
+	}
+
+	resp, err := h.onHintRequest(bitswap_message_pb.Message_PIRHintRequest{
+		Scheme: scheme.ID(),
+		Round:  bitswap_message_pb.Message_BlockRound,
+		Epoch:  hs.Epoch + 1,
+	})
+	if err != nil || len(resp.Data) != 0 || resp.Epoch != hs.Epoch {
+		t.Fatalf("request for another epoch answered with %d bytes of epoch %d: %v", len(resp.Data), resp.Epoch, err)
+	}
+	if _, err := h.onHintRequest(bitswap_message_pb.Message_PIRHintRequest{
+		Scheme: scheme.ID(),
+		Round:  bitswap_message_pb.Message_BlockRound,
+		Offset: 1001,
+	}); err == nil {
+		t.Fatal("request past the end of the hint should fail")
+	}
+
+	// once the database changes, clients holding the hint are sent its
+	// changes.
+	store, err := h.storeFor(scheme.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := util.Add(bs, []byte("hello again"))
+	if err := store.Add(c, []byte("hello again")); err != nil {
+		t.Fatal(err)
+	}
+	next, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = h.onHintRequest(bitswap_message_pb.Message_PIRHintRequest{
+		Scheme: scheme.ID(),
+		Round:  bitswap_message_pb.Message_BlockRound,
+		Epoch:  next.Epoch,
+		Since:  hs.Epoch,
+	})
+	if err != nil || resp.Since != hs.Epoch || resp.Offset != 0 || resp.Total >= 1000 || uint64(len(resp.Data)) != resp.Total {
+		t.Fatalf("sent %d bytes of %d since epoch %d: %v", len(resp.Data), resp.Total, resp.Since, err)
+	}
+	if updated, err := pir.PatchHint(hint, resp.Data); err != nil || !pir.CheckHint(next.Blocks.Params(), updated) {
+		t.Fatalf("changes do not patch the hint: %v", err)
+	}
+}
+
+func TestSplitAnswer(t *testing.T) {
+	pr := bitswap_message_pb.Message_PIRResponse{Session: 1, Part: 2, Epoch: 3, Answer: make([]byte, 1000)}
+	if parts := splitAnswer(pr, 1000); len(parts) != 1 || parts[0].Total != 0 {
+		t.Fatalf("answer within the message size split in %d", len(parts))
+	}
+	parts := splitAnswer(pr, 300)
+	if len(parts) != 4 {
+		t.Fatalf("split in %d parts", len(parts))
+	}
+	var answer []byte
+	for _, part := range parts {
+		if part.Offset != uint64(len(answer)) || part.Total != 1000 || len(part.Answer) > 300 {
+			t.Fatalf("part of %d bytes at %d of %d", len(part.Answer), part.Offset, part.Total)
+		}
+		if part.Session != 1 || part.Part != 2 || part.Epoch != 3 {
+			t.Fatalf("part names session %d part %d epoch %d", part.Session, part.Part, part.Epoch)
+		}
+		answer = append(answer, part.Answer...)
+	}
+	if len(answer) != 1000 {
+		t.Fatalf("parts hold %d bytes", len(answer))
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _, err := fastpir.New().Query(hs.Index.Params(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bitswap_message_pb.Message_PIRRequest{Session: 1, Round: bitswap_message_pb.Message_IndexRound, Query: q, Id: 5}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	ss.replyInline()
+
+	// a request resent while the first is being answered is not answered again.
+	if !ss.startRequest(r.Id) {
+		t.Fatal("new request taken for a resent one")
+	}
+	msg, err := (&bitswap_message_pb.Message{PirRequests: []bitswap_message_pb.Message_PIRRequest{r}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.onMessage(context.Background(), ss, msg); err != nil {
+		t.Fatal(err)
+	}
+	if ss.outstanding() != 0 {
+		t.Fatal("resent request answered again")
+	}
+
+	// answers name the request they answer, which may be sent again after.
+	ss.track(pirWork(r.Session))
+	h.answerPIR(context.Background(), ss, r, time.Now())
+	m := queued(t, ss)
+	if len(m.PirResponses) != 1 || m.PirResponses[0].Id != r.Id {
+		t.Fatalf("request %d answered with %v", r.Id, m)
+	}
+	if !ss.startRequest(r.Id) {
+		t.Fatal("answered request still outstanding")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	c := util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	onMessage := func(m *bitswap_message_pb.Message) error {
+		msg, err := m.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h.onMessage(context.Background(), ss, msg)
+	}
+
+	// clients offering zstd have their replies compressed.
+	offer := &bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}, Compression: []string{bitswap_message_pb.CompressionZstd}}
+	if err := onMessage(&bitswap_message_pb.Message{PirHandshake: &bitswap_message_pb.Message_PIRHandshake{Offer: offer}}); err != nil {
+		t.Fatal(err)
+	}
+	if m := queued(t, ss); m.PirHandshake.GetCompression() != bitswap_message_pb.CompressionZstd {
+		t.Fatalf("offer of zstd answered with %v", m.PirHandshake)
+	}
+
+	// and may send their wants compressed in turn.
+	want := bitswap_message_pb.Message{Wantlist: bitswap_message_pb.Message_Wantlist{Entries: []bitswap_message_pb.Message_Wantlist_Entry{{
+		Block: bitswap_message_pb.Cid{Cid: c}, WantType: bitswap_message_pb.Message_Wantlist_Have,
+	}}}, InlineReplies: true}
+	if err := want.Compress(); err != nil {
+		t.Fatal(err)
+	}
+	if err := onMessage(&want); err != nil {
+		t.Fatal(err)
+	}
+	m := queued(t, ss)
+	if len(m.Zstd) == 0 || len(m.BlockPresences) != 0 {
+		t.Fatalf("presences sent uncompressed: %v", m)
+	}
+	if err := m.Decompress(bitswap.MaxBlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.BlockPresences) != 1 || !m.BlockPresences[0].Cid.Cid.Equals(c) || m.BlockPresences[0].Type != bitswap_message_pb.Message_Have {
+		t.Fatalf("presences decompressed as %v", m.BlockPresences)
+	}
+
+	// messages which do not decompress are malformed.
+	if err := onMessage(&bitswap_message_pb.Message{Zstd: []byte("not zstd")}); !errors.Is(err, pir.ErrMalformed) {
+		t.Fatalf("bad compressed message failed with %v", err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// servers without a key sign nothing.
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hs.Manifest != nil {
+		t.Fatal("manifest sent without a key to sign it")
+	}
+
+	priv, pub, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.key = priv
+	if hs, err = h.handshake(nil); err != nil {
+		t.Fatal(err)
+	}
+	m := hs.Manifest
+	if m == nil || m.Scheme != fastpir.ID || m.Epoch != hs.Epoch || len(m.Digest) == 0 {
+		t.Fatalf("handshake sent manifest %v", m)
+	}
+	if ok, err := pub.Verify(bitswap_message_pb.ManifestPayload(m.Scheme, m.Group, m.Epoch, m.Digest), m.Signature); !ok || err != nil {
+		t.Fatalf("manifest signature did not verify: %v", err)
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithReceipts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, pub, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.key = priv
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _, err := fastpir.New().Query(hs.Index.Params(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bitswap_message_pb.Message_PIRRequest{Session: 1, Round: bitswap_message_pb.Message_IndexRound, Query: q}
+	// answers are signed only for clients which ask.
+	pr, err := h.onPIRRequest(context.Background(), "p", r)
+	if err != nil || pr.Receipt != nil {
+		t.Fatalf("receipt %v sent unasked, %v", pr.Receipt, err)
+	}
+	r.Session, r.Receipt = 2, true
+	if pr, err = h.onPIRRequest(context.Background(), "p", r); err != nil {
+		t.Fatal(err)
+	}
+	rc := pr.Receipt
+	query, answer := sha256.Sum256(q), sha256.Sum256(pr.Answer)
+	if rc == nil || rc.Scheme != fastpir.ID || rc.Epoch != hs.Epoch || !bytes.Equal(rc.Query, query[:]) || !bytes.Equal(rc.Answer, answer[:]) || rc.Timestamp == 0 {
+		t.Fatalf("answer sent with receipt %v", rc)
+	}
+	if ok, err := pub.Verify(rc.Payload(), rc.Signature); !ok || err != nil {
+		t.Fatalf("receipt signature did not verify: %v", err)
+	}
+
+	// answers split across messages carry their receipt with the last.
+	parts := splitAnswer(pr, len(pr.Answer)/2+1)
+	if len(parts) != 2 || parts[0].Receipt != nil || parts[1].Receipt != rc {
+		t.Fatalf("receipt split as %v, %v", parts[0].Receipt, parts[1].Receipt)
+	}
+}
+
+// dagNode stores a dag-cbor block linking to children.
+func dagNode(t *testing.T, bs MutableBlockstore, children ...cid.Cid) cid.Cid {
+	n, err := qp.BuildList(basicnode.Prototype.Any, int64(len(children)), func(la datamodel.ListAssembler) {
+		for _, c := range children {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: c}))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(n, &buf); err != nil {
+		t.Fatal(err)
+	}
+	h, err := multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid(buf.Bytes(), cid.NewCidV1(cid.DagCBOR, h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Put(context.Background(), blk); err != nil {
+		t.Fatal(err)
+	}
+	return blk.Cid()
+}
+
+func TestGroups(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte)).(MutableBlockstore)
+	a := util.Add(bs, []byte("a"))
+	root := dagNode(t, bs, a, dagNode(t, bs, util.Add(bs, []byte("b"))))
+	for i := 0; i < 20; i++ {
+		util.Add(bs, []byte(fmt.Sprintf("elsewhere %d", i)))
+	}
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithPIRGroups(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, pub, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.key = priv
+
+	// clients offering the group are described its smaller databases,
+	whole, err := h.handshake(&bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := h.handshake(&bitswap_message_pb.Message_PIROffer{Schemes: []string{fastpir.ID}, Group: root.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(whole.Group) != 0 || !bytes.Equal(hs.Group, root.Bytes()) || len(hs.Groups) != 1 || !bytes.Equal(hs.Groups[0], root.Bytes()) {
+		t.Fatalf("handshakes describe groups %x and %x of %x", whole.Group, hs.Group, hs.Groups)
+	}
+	if hs.Blocks.NumElements >= whole.Blocks.NumElements {
+		t.Fatalf("group laid out in %d elements, the whole store in %d", hs.Blocks.NumElements, whole.Blocks.NumElements)
+	}
+	m := hs.Manifest
+	if ok, err := pub.Verify(bitswap_message_pb.ManifestPayload(m.Scheme, m.Group, m.Epoch, m.Digest), m.Signature); !ok || err != nil {
+		t.Fatalf("group manifest signature did not verify: %v", err)
+	}
+	if bytes.Equal(m.Digest, whole.Manifest.Digest) {
+		t.Fatal("group manifest commits to the whole store")
+	}
+
+	// and answered from them, holding only the blocks of the DAG.
+	group, err := h.storeIn(root.Bytes(), fastpir.ID)
+	if err != nil || group.Group() != root {
+		t.Fatalf("requests for the group answered from %v: %v", group, err)
+	}
+	snap, err := group.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snap.Has(a) || !snap.Has(root) || snap.Has(util.Add(bs, []byte("elsewhere 0"))) {
+		t.Fatal("group lays out other blocks than those of its DAG")
+	}
+	other := dagNode(t, bs)
+	if st, err := h.storeIn(other.Bytes(), fastpir.ID); err != nil || st.Group().Defined() {
+		t.Fatalf("requests for a group not laid out answered from %v: %v", st, err)
+	}
+
+	// groups lose the blocks deleted from the blockstore.
+	if err := bs.DeleteBlock(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if snap, err = group.Snapshot(); err != nil || snap.Has(a) {
+		t.Fatalf("group kept a deleted block: %v", err)
+	}
+}
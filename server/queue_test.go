@@ -0,0 +1,55 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestSendBackpressure(t *testing.T) {
+	metrics := countingSink{}
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)),
+		WithSendQueueDepth(2), WithTimeouts(Timeouts{Send: 20 * time.Millisecond}), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	for i := 0; i < 2; i++ {
+		if err := ss.enqueue(make([]byte, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ss.waitForRoom(); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("full queue: waited with %v", err)
+	}
+	if err := ss.enqueue(make([]byte, 1)); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("full queue: queued with %v", err)
+	}
+	if metrics["read_backpressure"] != 1 || metrics["send_queue_overflows"] != 2 {
+		t.Fatalf("counted %v", metrics)
+	}
+
+	// reads resume, and responses are queued, once the client catches up.
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		<-ss.queue
+		ss.room <- struct{}{}
+	}()
+	if err := ss.waitForRoom(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		<-ss.queue
+	}()
+	if err := ss.enqueue(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.enqueue(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+package bitswapserver
+
+import (
+	"errors"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+)
+
+// ServiceName is the resource manager service private bitswap streams are
+// attached to, so the streams and the memory of the PIR answers queued on
+// them are limited, and reported, apart from the rest of the host's.
+const ServiceName = "bitswap-pir"
+
+// ErrMemoryLimit is returned for PIR answers the resource manager refused
+// memory for.
+var ErrMemoryLimit = errors.New("resource manager refused memory for PIR answer")
+
+var (
+	// ServiceLimit bounds the streams of the PIR service, and the memory of
+	// the answers queued on them, on hosts with little memory.
+	ServiceLimit = rcmgr.BaseLimit{
+		StreamsInbound:  256,
+		StreamsOutbound: 256,
+		Streams:         256,
+		Memory:          64 << 20,
+	}
+	// ServiceLimitIncrease raises ServiceLimit per GiB of memory the
+	// resource manager may use.
+	ServiceLimitIncrease = rcmgr.BaseLimitIncrease{
+		StreamsInbound:  128,
+		StreamsOutbound: 128,
+		Streams:         128,
+		Memory:          256 << 20,
+	}
+	// ServicePeerLimit bounds the share of the PIR service each peer may
+	// use, so one peer cannot queue answers enough to starve the others.
+	ServicePeerLimit = rcmgr.BaseLimit{
+		StreamsInbound:  16,
+		StreamsOutbound: 16,
+		Streams:         16,
+		Memory:          16 << 20,
+	}
+	// ServicePeerLimitIncrease raises ServicePeerLimit per GiB of memory.
+	ServicePeerLimitIncrease = rcmgr.BaseLimitIncrease{
+		StreamsInbound:  4,
+		StreamsOutbound: 4,
+		Streams:         4,
+		Memory:          32 << 20,
+	}
+)
+
+// SetServiceLimits adds the limits of the PIR service to cfg, before it is
+// scaled and given to rcmgr.NewResourceManager.
+//
+//	limits := rcmgr.DefaultLimits
+//	libp2p.SetDefaultServiceLimits(&limits)
+//	bitswapserver.SetServiceLimits(&limits)
+func SetServiceLimits(cfg *rcmgr.ScalingLimitConfig) {
+	cfg.AddServiceLimit(ServiceName, ServiceLimit, ServiceLimitIncrease)
+	cfg.AddServicePeerLimit(ServiceName, ServicePeerLimit, ServicePeerLimitIncrease)
+}
+
+// admit wraps msg, which completes the work tracked under keys, for the
+// queue. On streams with a resource scope its memory is reserved at prio
+// until it is sent or dropped. If the reservation is refused, or the stream
+// has ended, msg is handed back to the pool.
+func (ss *streamSender) admit(msg []byte, prio uint8, keys []string) (outgoing, error) {
+	out := outgoing{msg: msg, keys: keys}
+	if err := ss.ctx.Err(); err != nil {
+		ss.buffers.put(msg)
+		return out, err
+	}
+	if ss.scope == nil {
+		return out, nil
+	}
+	if err := ss.scope.ReserveMemory(len(msg), prio); err != nil {
+		ss.metrics.Add("pir_memory_refused", 1)
+		ss.buffers.put(msg)
+		return out, ErrMemoryLimit
+	}
+	out.reserved = len(msg)
+	return out, nil
+}
+
+// drop hands out's buffer back to the pool, frees its reservation, and
+// signals that it is done with, once written or not.
+func (ss *streamSender) drop(out outgoing) {
+	if out.written != nil {
+		close(out.written)
+	}
+	if out.reserved > 0 {
+		ss.scope.ReleaseMemory(out.reserved)
+	}
+	ss.buffers.put(out.msg)
+}
+
+// attachService attaches a private bitswap stream to the PIR service.
+func attachService(s network.Stream) error {
+	return s.Scope().SetService(ServiceName)
+}
@@ -0,0 +1,72 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// budgetScope grants reservations up to a number of bytes.
+type budgetScope struct {
+	network.NullScope
+	limit, used int
+}
+
+func (b *budgetScope) ReserveMemory(size int, _ uint8) error {
+	if b.used+size > b.limit {
+		return network.ErrResourceLimitExceeded
+	}
+	b.used += size
+	return nil
+}
+
+func (b *budgetScope) ReleaseMemory(size int) { b.used -= size }
+
+// privateStream is a private bitswap stream under a scope.
+type privateStream struct {
+	discardStream
+	scope *budgetScope
+}
+
+func (s privateStream) Protocol() protocol.ID      { return bitswap.ProtocolPrivate }
+func (s privateStream) Scope() network.StreamScope { return s.scope }
+
+func TestMemoryReservations(t *testing.T) {
+	metrics := countingSink{}
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := &budgetScope{limit: 6}
+	ss := h.newStreamSender(context.Background(), privateStream{scope: scope})
+	if err := ss.enqueue(make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.enqueue(make([]byte, 4)); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("answer over the scope's memory: queued with %v", err)
+	}
+	if ss.offer(make([]byte, 4)) {
+		t.Fatal("progress over the scope's memory should be dropped")
+	}
+	if scope.used != 4 || metrics["pir_memory_refused"] != 2 {
+		t.Fatalf("reserved %d bytes, counted %v", scope.used, metrics)
+	}
+
+	// memory is freed as messages are sent.
+	close(ss.queue)
+	ss.writeLoop()
+	if scope.used != 0 {
+		t.Fatalf("%d bytes still reserved once sent", scope.used)
+	}
+
+	// streams of other protocols are not accounted.
+	if plain := h.newStreamSender(context.Background(), discardStream{}); plain.scope != nil {
+		t.Fatal("plaintext stream given a scope")
+	}
+}
@@ -0,0 +1,31 @@
+package bitswapserver
+
+import (
+	"context"
+	"time"
+)
+
+// hold waits until the release of an answer to a request received at
+// received, when the server releases answers on a fixed schedule: the first
+// whole number of answer periods after the request was received. Answers
+// taking longer than a period to compute are counted as overruns. It returns
+// early with the error of ctx.
+func (h *handler) hold(ctx context.Context, received time.Time) error {
+	period := h.cfg.answerPeriod
+	if period <= 0 {
+		return nil
+	}
+	elapsed := time.Since(received)
+	if elapsed > period {
+		h.cfg.metrics.Add("pir_answer_overruns", 1)
+	}
+	wait := period - elapsed%period
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,53 @@
+package bitswapserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestAnswerPeriod(t *testing.T) {
+	metrics := countingSink{}
+	period := 100 * time.Millisecond
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)), WithAnswerPeriod(period), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// answers are released a period after their request, however quickly
+	// they were computed.
+	for _, computed := range []time.Duration{0, 60 * time.Millisecond} {
+		received := time.Now().Add(-computed)
+		if err := h.hold(ctx, received); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(received); d < period || d > period+50*time.Millisecond {
+			t.Fatalf("answer computed in %v released after %v", computed, d)
+		}
+	}
+	if metrics["pir_answer_overruns"] != 0 {
+		t.Fatal("answers within the period counted as overruns")
+	}
+
+	// answers taking longer wait for the next period.
+	received := time.Now().Add(-130 * time.Millisecond)
+	if err := h.hold(ctx, received); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(received); d < 2*period || d > 2*period+50*time.Millisecond {
+		t.Fatalf("overrunning answer released after %v", d)
+	}
+	if metrics["pir_answer_overruns"] != 1 {
+		t.Fatalf("%v overruns", metrics["pir_answer_overruns"])
+	}
+
+	cctx, cncl := context.WithCancel(ctx)
+	cncl()
+	if err := h.hold(cctx, time.Now()); err != context.Canceled {
+		t.Fatalf("cancelled hold returned %v", err)
+	}
+}
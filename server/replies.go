@@ -0,0 +1,77 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+var errRepliesClosed = errors.New("reply stream closed")
+
+// replyInline writes replies to the stream of the requests from now on, as
+// the peer reads them from there.
+func (ss *streamSender) replyInline() {
+	ss.replyMtx.Lock()
+	defer ss.replyMtx.Unlock()
+	ss.inline = true
+}
+
+// repliesInline reports whether replies are written to the stream of the
+// requests.
+func (ss *streamSender) repliesInline() bool {
+	ss.replyMtx.Lock()
+	defer ss.replyMtx.Unlock()
+	return ss.inline
+}
+
+// compressReplies compresses the metadata of replies from now on, as the
+// peer negotiated.
+func (ss *streamSender) compressReplies() {
+	ss.replyMtx.Lock()
+	defer ss.replyMtx.Unlock()
+	ss.compress = true
+}
+
+// compressesReplies reports whether the metadata of replies is compressed.
+func (ss *streamSender) compressesReplies() bool {
+	ss.replyMtx.Lock()
+	defer ss.replyMtx.Unlock()
+	return ss.compress
+}
+
+// replyStream returns the stream replies are written to. Stock bitswap peers
+// never read from the streams they send wants on, but from streams opened by
+// the server, so one is opened on the first reply to such a peer.
+func (ss *streamSender) replyStream() (network.Stream, error) {
+	ss.replyMtx.Lock()
+	defer ss.replyMtx.Unlock()
+	if ss.inline || ss.open == nil {
+		return ss.Stream, nil
+	}
+	if ss.replies != nil {
+		return ss.replies, nil
+	}
+	if ss.closed {
+		return nil, errRepliesClosed
+	}
+	ctx, cncl := context.WithTimeout(context.Background(), ss.sendTimeout)
+	defer cncl()
+	s, err := ss.open(ctx, ss.Conn().RemotePeer(), ss.Protocol())
+	if err != nil {
+		return nil, err
+	}
+	ss.metrics.Add("reply_streams_opened", 1)
+	ss.replies = s
+	return s, nil
+}
+
+// closeReplies closes the reply stream, once the peer's stream has ended.
+func (ss *streamSender) closeReplies() {
+	ss.replyMtx.Lock()
+	defer ss.replyMtx.Unlock()
+	ss.closed = true
+	if ss.replies != nil {
+		_ = ss.replies.Close()
+	}
+}
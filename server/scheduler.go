@@ -0,0 +1,335 @@
+package bitswapserver
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DefaultQuantum is the number of bytes each peer may be sent per round by
+// the deficit round robin scheduler.
+const DefaultQuantum = 256 * 1024
+
+// Task is a block request waiting to be served.
+type Task struct {
+	Peer peer.ID
+	// Priority is the priority of the wantlist entry. Higher runs first.
+	Priority int32
+	// Cost is the estimated cost of the task: the number of bytes it will
+	// send, for block requests, or the nanoseconds of work of its answer,
+	// for PIR requests.
+	Cost int
+
+	seq uint64
+	run func()
+}
+
+// Scheduler orders the tasks waiting for a worker. A scheduler is only used
+// by one server, which serializes calls to it.
+type Scheduler interface {
+	Push(t *Task)
+	// Pop removes and returns the next task to run, or nil if none are queued.
+	Pop() *Task
+	Len() int
+}
+
+// NewFIFOScheduler returns a scheduler which runs tasks in arrival order,
+// ignoring their priority.
+func NewFIFOScheduler() Scheduler {
+	return &fifoScheduler{}
+}
+
+type fifoScheduler struct {
+	tasks []*Task
+}
+
+func (s *fifoScheduler) Push(t *Task) {
+	s.tasks = append(s.tasks, t)
+}
+
+func (s *fifoScheduler) Pop() *Task {
+	if len(s.tasks) == 0 {
+		return nil
+	}
+	t := s.tasks[0]
+	s.tasks[0] = nil
+	s.tasks = s.tasks[1:]
+	return t
+}
+
+func (s *fifoScheduler) Len() int {
+	return len(s.tasks)
+}
+
+// NewPriorityScheduler returns a scheduler which runs the highest priority
+// task first, regardless of which peer asked for it. Tasks of equal priority
+// run in arrival order.
+func NewPriorityScheduler() Scheduler {
+	return &priorityScheduler{}
+}
+
+type priorityScheduler struct {
+	tasks taskHeap
+}
+
+func (s *priorityScheduler) Push(t *Task) {
+	heap.Push(&s.tasks, t)
+}
+
+func (s *priorityScheduler) Pop() *Task {
+	if len(s.tasks) == 0 {
+		return nil
+	}
+	return heap.Pop(&s.tasks).(*Task)
+}
+
+func (s *priorityScheduler) Len() int {
+	return len(s.tasks)
+}
+
+// NewDRRScheduler returns a scheduler which shares the server fairly between
+// peers using deficit round robin: each round, a peer may be sent quantum
+// bytes worth of tasks, taken from its own queue in priority order. A
+// non-positive quantum selects DefaultQuantum.
+func NewDRRScheduler(quantum int) Scheduler {
+	if quantum <= 0 {
+		quantum = DefaultQuantum
+	}
+	return &drrScheduler{quantum: quantum, queues: make(map[peer.ID]*drrQueue)}
+}
+
+type drrQueue struct {
+	peer    peer.ID
+	tasks   taskHeap
+	deficit int
+}
+
+type drrScheduler struct {
+	quantum int
+	queues  map[peer.ID]*drrQueue
+	// active holds the peers with queued tasks, in round robin order.
+	active []*drrQueue
+	n      int
+}
+
+func (s *drrScheduler) Push(t *Task) {
+	q, ok := s.queues[t.Peer]
+	if !ok {
+		q = &drrQueue{peer: t.Peer}
+		s.queues[t.Peer] = q
+		s.active = append(s.active, q)
+	}
+	heap.Push(&q.tasks, t)
+	s.n++
+}
+
+func (s *drrScheduler) Pop() *Task {
+	for len(s.active) > 0 {
+		q := s.active[0]
+		cost := q.tasks[0].Cost
+		if cost < 1 {
+			cost = 1
+		}
+		if cost > q.deficit {
+			// out of credit for this round: move to the back of the line.
+			q.deficit += s.quantum
+			s.active = append(s.active[1:], q)
+			continue
+		}
+		t := heap.Pop(&q.tasks).(*Task)
+		q.deficit -= cost
+		s.n--
+		if len(q.tasks) == 0 {
+			s.active = s.active[1:]
+			delete(s.queues, q.peer)
+		}
+		return t
+	}
+	return nil
+}
+
+func (s *drrScheduler) Len() int {
+	return s.n
+}
+
+// NewWFQScheduler returns a scheduler sharing the server between peers by
+// weighted fair queueing: each peer with tasks queued is served in
+// proportion to its weight, counted in the Cost of its tasks, however many
+// it queues, so that a peer queueing much cannot hold the others back, and
+// a peer which was idle is served as soon as its share allows. A peer's own
+// tasks run in priority order. weight returns the weight of a peer, read
+// when it queues a task while it has none queued; peers are weighed alike if
+// weight is nil, and non-positive weights count as 1.
+func NewWFQScheduler(weight func(peer.ID) float64) Scheduler {
+	return &wfqScheduler{weight: weight, queues: make(map[peer.ID]*wfqQueue)}
+}
+
+// wfqQueue is the queue of a peer, tagged with the virtual times at which
+// its next task starts and its last one finished, as in start-time fair
+// queueing.
+type wfqQueue struct {
+	peer   peer.ID
+	tasks  taskHeap
+	weight float64
+	start  float64
+	finish float64
+}
+
+type wfqScheduler struct {
+	weight func(peer.ID) float64
+	// queues holds the queues of peers with tasks queued, and of idle
+	// peers until the virtual time passes their last finish.
+	queues map[peer.ID]*wfqQueue
+	// active holds the queues with tasks, in the order they became active,
+	// which breaks ties between start tags.
+	active  []*wfqQueue
+	virtual float64
+	n       int
+}
+
+func (s *wfqScheduler) Push(t *Task) {
+	q, ok := s.queues[t.Peer]
+	if !ok {
+		q = &wfqQueue{peer: t.Peer}
+		s.queues[t.Peer] = q
+	}
+	if len(q.tasks) == 0 {
+		q.weight = 1
+		if s.weight != nil {
+			if w := s.weight(t.Peer); w > 0 {
+				q.weight = w
+			}
+		}
+		// a peer returning before its share of earlier work was used up
+		// waits out the rest of it.
+		q.start = math.Max(s.virtual, q.finish)
+		s.active = append(s.active, q)
+	}
+	heap.Push(&q.tasks, t)
+	s.n++
+}
+
+func (s *wfqScheduler) Pop() *Task {
+	if len(s.active) == 0 {
+		return nil
+	}
+	next := 0
+	for i, q := range s.active {
+		if q.start < s.active[next].start {
+			next = i
+		}
+	}
+	q := s.active[next]
+	t := heap.Pop(&q.tasks).(*Task)
+	s.n--
+	cost := t.Cost
+	if cost < 1 {
+		cost = 1
+	}
+	s.virtual = q.start
+	q.finish = q.start + float64(cost)/q.weight
+	q.start = q.finish
+	if len(q.tasks) == 0 {
+		s.active = append(s.active[:next], s.active[next+1:]...)
+	}
+	for p, idle := range s.queues {
+		if len(idle.tasks) == 0 && idle.finish <= s.virtual {
+			delete(s.queues, p)
+		}
+	}
+	return t
+}
+
+func (s *wfqScheduler) Len() int {
+	return s.n
+}
+
+// taskHeap orders tasks by descending priority, then by arrival.
+type taskHeap []*Task
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*Task)) }
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return t
+}
+
+// dispatcher runs the tasks of a Scheduler on a fixed number of workers,
+// queueing at most depth of them if depth is positive.
+type dispatcher struct {
+	mtx   sync.Mutex
+	cond  *sync.Cond
+	sched Scheduler
+	seq   uint64
+	depth int
+	// workers is the number of workers, and busy those running a task.
+	workers int
+	busy    int
+}
+
+func newDispatcher(sched Scheduler, workers, depth int) *dispatcher {
+	d := &dispatcher{sched: sched, depth: depth, workers: workers}
+	d.cond = sync.NewCond(&d.mtx)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// push queues t, unless the queue is full.
+func (d *dispatcher) push(t *Task) bool {
+	d.mtx.Lock()
+	if d.depth > 0 && d.sched.Len() >= d.depth {
+		d.mtx.Unlock()
+		return false
+	}
+	d.seq++
+	t.seq = d.seq
+	d.sched.Push(t)
+	d.mtx.Unlock()
+	d.cond.Signal()
+	return true
+}
+
+func (d *dispatcher) work() {
+	for {
+		d.mtx.Lock()
+		for d.sched.Len() == 0 {
+			d.cond.Wait()
+		}
+		t := d.sched.Pop()
+		if t == nil {
+			d.mtx.Unlock()
+			continue
+		}
+		d.busy++
+		d.mtx.Unlock()
+		t.run()
+		d.mtx.Lock()
+		d.busy--
+		d.mtx.Unlock()
+	}
+}
+
+// stats returns the number of tasks queued, and of workers busy.
+func (d *dispatcher) stats() (queued, busy int) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.sched.Len(), d.busy
+}
@@ -0,0 +1,119 @@
+package bitswapserver
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func pushAll(s Scheduler, tasks ...*Task) {
+	for i, t := range tasks {
+		t.seq = uint64(i)
+		s.Push(t)
+	}
+}
+
+func popAll(s Scheduler) []*Task {
+	var out []*Task
+	for s.Len() > 0 {
+		out = append(out, s.Pop())
+	}
+	if s.Pop() != nil {
+		panic("pop from empty scheduler returned a task")
+	}
+	return out
+}
+
+func expectOrder(t *testing.T, got []*Task, want ...*Task) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d tasks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("task %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFIFOScheduler(t *testing.T) {
+	a := &Task{Priority: 1}
+	b := &Task{Priority: 5}
+	c := &Task{Priority: 3}
+	s := NewFIFOScheduler()
+	pushAll(s, a, b, c)
+	expectOrder(t, popAll(s), a, b, c)
+}
+
+func TestPriorityScheduler(t *testing.T) {
+	a := &Task{Priority: 1}
+	b := &Task{Priority: 5}
+	c := &Task{Priority: 3}
+	d := &Task{Priority: 5}
+	s := NewPriorityScheduler()
+	pushAll(s, a, b, c, d)
+	expectOrder(t, popAll(s), b, d, c, a)
+}
+
+func TestDRRScheduler(t *testing.T) {
+	p1, p2 := peer.ID("one"), peer.ID("two")
+	// p1 asks for many large blocks before p2 asks for small ones.
+	a1 := &Task{Peer: p1, Cost: 100}
+	a2 := &Task{Peer: p1, Cost: 100}
+	a3 := &Task{Peer: p1, Cost: 100, Priority: 9}
+	b1 := &Task{Peer: p2, Cost: 50}
+	b2 := &Task{Peer: p2, Cost: 50}
+	s := NewDRRScheduler(100)
+	pushAll(s, a1, a2, a3, b1, b2)
+	// each round a peer is sent 100 bytes, highest priority first.
+	expectOrder(t, popAll(s), a3, b1, b2, a1, a2)
+}
+
+func TestWFQScheduler(t *testing.T) {
+	p1, p2, p3 := peer.ID("one"), peer.ID("two"), peer.ID("three")
+	s := NewWFQScheduler(func(p peer.ID) float64 {
+		if p == p1 {
+			return 2
+		}
+		return 1
+	})
+	// p1 queues many requests before p2 queues some: p1 is answered twice
+	// as much, its highest priority first.
+	a1 := &Task{Peer: p1, Cost: 100}
+	a2 := &Task{Peer: p1, Cost: 100}
+	a3 := &Task{Peer: p1, Cost: 100, Priority: 1}
+	a4 := &Task{Peer: p1, Cost: 100}
+	b1 := &Task{Peer: p2, Cost: 100}
+	b2 := &Task{Peer: p2, Cost: 100}
+	pushAll(s, a1, a2, a3, a4, b1, b2)
+	expectOrder(t, []*Task{s.Pop(), s.Pop(), s.Pop()}, a3, b1, a1)
+
+	// a peer arriving late is answered next, rather than after those
+	// already queued.
+	c1 := &Task{Peer: p3, Cost: 100}
+	s.Push(c1)
+	expectOrder(t, popAll(s), c1, a2, b2, a4)
+}
+
+func TestDispatcherDepth(t *testing.T) {
+	// without workers nothing leaves the queue.
+	d := newDispatcher(NewFIFOScheduler(), 0, 2)
+	for i := 0; i < 2; i++ {
+		if !d.push(&Task{}) {
+			t.Fatalf("task %d refused by a queue with room", i)
+		}
+	}
+	if d.push(&Task{}) {
+		t.Fatal("task accepted by a full queue")
+	}
+
+	done := make(chan struct{})
+	d = newDispatcher(NewFIFOScheduler(), 1, 0)
+	for i := 0; i < 100; i++ {
+		if !d.push(&Task{run: func() {}}) {
+			t.Fatal("unbounded queue refused a task")
+		}
+	}
+	d.push(&Task{run: func() { close(done) }})
+	<-done
+}
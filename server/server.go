@@ -1,162 +1,405 @@
 package bitswapserver
 
 import (
+	"bufio"
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"os"
+	"runtime"
+	"sync"
 	"time"
 
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
-	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
 	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/padding"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // accept bitswap streams. return requested blocks. simple
 
 const (
+	// MaxRequestTimeout is the default timeout of plaintext requests and
+	// PIR handshakes.
 	MaxRequestTimeout = 30 * time.Second
 	MaxSendMsgSize    = 3 * 1024 * 1024
 )
 
 var (
-	ErrNotHave  = errors.New("no requested blocks available")
+	ErrNotHave = errors.New("no requested blocks available")
+	// ErrOverflow is returned for responses which found a stream's send
+	// queue full for longer than the send timeout.
 	ErrOverflow = errors.New("send queue overflow")
+	ErrNoPIR    = errors.New("private retrieval not configured")
+	// ErrMessageTooLarge is returned for messages longer than MaxBlockSize.
+	ErrMessageTooLarge = errors.New("message too large")
+	// ErrTimeout is returned for requests which could not be answered
+	// within their phase's timeout.
+	ErrTimeout = errors.New("request timed out")
+	// ErrIdle closes streams which were idle for longer than their timeout.
+	ErrIdle = errors.New("stream idle")
 )
 
-var logger = log.Logger("bitswap-server")
+var (
+	logger        = logging.Logger("bitswap-server")
+	pirLogger     = logging.Logger(logging.PIR)
+	framingLogger = logging.Logger(logging.Framing)
+	schedLogger   = logging.Logger(logging.Scheduler)
+)
 
 type Blockstore interface {
 	Has(ctx context.Context, c cid.Cid) (bool, error)
 	Get(ctx context.Context, c cid.Cid) (blocks.Block, error)
 }
 
-func AttachBitswapServer(h host.Host, bs Blockstore) error {
-	bsh := handler{bs}
-	h.SetStreamHandler(bitswap.ProtocolBitswap, bsh.onStream)
+// EnumerableBlockstore is a Blockstore which can list its blocks, as PIR
+// databases are built from. Its methods are those of go-ipfs-blockstore.
+type EnumerableBlockstore interface {
+	Blockstore
+	// AllKeysChan lists the CIDs of the blocks held, closing the channel
+	// once all are listed or ctx is done.
+	AllKeysChan(ctx context.Context) (<-chan cid.Cid, error)
+	// GetSize returns the size of the block named by c.
+	GetSize(ctx context.Context, c cid.Cid) (int, error)
+}
+
+// MutableBlockstore is an EnumerableBlockstore which can be written to, and
+// which notifies subscribers of its mutations, so the PIR databases built
+// from it follow them.
+type MutableBlockstore interface {
+	EnumerableBlockstore
+	Put(ctx context.Context, blk blocks.Block) error
+	PutMany(ctx context.Context, blks []blocks.Block) error
+	DeleteBlock(ctx context.Context, c cid.Cid) error
+	// Subscribe calls f after each block put, with its data, and each block
+	// deleted, with nil data, until the returned function is called.
+	Subscribe(f func(c cid.Cid, data []byte)) (cancel func())
+}
+
+// Guarded is implemented by blockstores which garbage collect their blocks,
+// such as those of package pin. The server guards the blocks of the PIR
+// databases answering in-flight sessions, so a block is not deleted while a
+// client may still be retrieving it.
+type Guarded interface {
+	// Guard keeps the blocks inUse reports until the returned function is
+	// called.
+	Guard(inUse func(c cid.Cid) bool) (release func())
+}
+
+// AttachBitswapServer serves the blocks in bs to bitswap streams opened on h.
+func AttachBitswapServer(h host.Host, bs Blockstore, opts ...Option) error {
+	bsh, err := newHandler(bs, opts...)
+	if err != nil {
+		return err
+	}
+	bsh.open = h.NewStream
+	bsh.protect.cm = h.ConnManager()
+	bsh.key = h.Peerstore().PrivKey(h.ID())
+	// stock bitswap peers are served on every version of the protocol.
+	for _, id := range []protocol.ID{bitswap.ProtocolBitswap, bitswap.ProtocolBitswapOneOne, bitswap.ProtocolBitswapOneZero, bitswap.ProtocolBitswapNoVers} {
+		h.SetStreamHandler(id, bsh.onStream)
+	}
+	if len(bsh.stores) > 0 {
+		// PIR messages are still answered on ProtocolBitswap, for older
+		// clients.
+		h.SetStreamHandler(bitswap.ProtocolPrivate, bsh.onStream)
+	}
 	return nil
 }
 
+func newHandler(bs Blockstore, opts ...Option) (*handler, error) {
+	cfg := defaultConfig()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var stores, groups, built, builtGroups []*pirstore.Store
+	for _, src := range cfg.pir {
+		if src.store != nil && src.store.Group().Defined() {
+			groups = append(groups, src.store)
+			continue
+		}
+		if src.store == nil {
+			for _, root := range cfg.groups {
+				db, err := NewPIRGroupStore(context.Background(), bs, root, src.scheme, src.opts)
+				if err != nil {
+					return nil, err
+				}
+				builtGroups = append(builtGroups, db)
+			}
+			db, err := NewPIRStore(bs, src.scheme, src.opts)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.snapshotDir != "" {
+				if err := restore(db, cfg.snapshotDir, cfg.snapshotKey); err != nil {
+					return nil, err
+				}
+			}
+			src.store = db
+			built = append(built, db)
+		}
+		stores = append(stores, src.store)
+	}
+	groups = append(groups, builtGroups...)
+	// the databases built here follow the blockstore, groups only losing
+	// blocks; those given with WithPIRStore are kept up to date by their
+	// owner.
+	if mbs, ok := bs.(MutableBlockstore); ok && len(built) > 0 {
+		mbs.Subscribe(func(c cid.Cid, data []byte) {
+			follow(built, c, data)
+			if data == nil {
+				follow(builtGroups, c, nil)
+			}
+		})
+	}
+	if cfg.scheduler == nil {
+		cfg.scheduler = NewPriorityScheduler()
+	}
+	if cfg.workers <= 0 {
+		cfg.workers = DefaultWorkers
+	}
+	if cfg.pirScheduler == nil {
+		cfg.pirScheduler = NewWFQScheduler(cfg.pirWeights)
+	}
+	if cfg.pirWorkers <= 0 {
+		cfg.pirWorkers = runtime.GOMAXPROCS(0)
+	}
+	bsh := &handler{
+		bs:      bs,
+		cfg:     cfg,
+		buffers: newBufferPool(cfg.metrics),
+		stores:  stores,
+		groups:  groups,
+		tasks:   newDispatcher(cfg.scheduler, cfg.workers, 0),
+		limits:  newLimiter(cfg.limits),
+		answers: newAnswerCache(cfg.answerCacheSize),
+
+		admission: newAdmission(cfg.admission, cfg.pirWorkers),
+	}
+	if len(stores) > 0 {
+		bsh.pirTasks = newDispatcher(cfg.pirScheduler, cfg.pirWorkers, cfg.pirQueueDepth)
+		if g, ok := bs.(Guarded); ok {
+			g.Guard(bsh.inflight.inUse)
+		}
+	}
+	if cfg.monitor != nil {
+		cfg.monitor.attach(bsh)
+	}
+	return bsh, nil
+}
+
 type handler struct {
-	bs Blockstore
+	bs      Blockstore
+	cfg     config
+	tasks   *dispatcher
+	limits  *limiter
+	buffers *bufferPool
+	// open opens streams to peers, for replies to peers which do not read
+	// them from their own streams. Without it, all replies are written to
+	// the stream of the request.
+	open func(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error)
+
+	stores []*pirstore.Store
+	// groups are the stores of content groups, answered for clients
+	// offering their roots.
+	groups   []*pirstore.Store
+	pirTasks *dispatcher
+	// admission bounds the work of the PIR requests queued in pirTasks.
+	admission *admission
+	inflight  inflightTable
+	answers   *answerCache
+	protect   protector
+	// key, the host's peer key, signs the manifests of the PIR databases.
+	key crypto.PrivKey
 }
 
 func (h *handler) onStream(s network.Stream) {
-	if err := s.SetReadDeadline(time.Now().Add(MaxRequestTimeout)); err != nil {
-		_ = s.Close()
+	p := s.Conn().RemotePeer()
+	if err := h.authorize(context.Background(), s, s.Protocol()); err != nil {
+		logger.Debugw("refusing unauthorized stream", "peer", p, "protocol", s.Protocol(), "err", err)
+		if s.Protocol() == bitswap.ProtocolPrivate {
+			go h.refuse(s, err)
+		} else {
+			_ = s.Reset()
+		}
 		return
 	}
-	go h.readLoop(s)
+	if !h.limits.openStream(p) {
+		logger.Debugw("refusing stream over limit", "peer", p)
+		h.cfg.metrics.Add("streams_refused", 1)
+		_ = s.Reset()
+		return
+	}
+	if s.Protocol() == bitswap.ProtocolPrivate {
+		if err := attachService(s); err != nil {
+			logger.Debugw("refusing stream over PIR service limit", "peer", p, "err", err)
+			h.cfg.metrics.Add("streams_refused", 1)
+			h.limits.closeStream(p)
+			_ = s.Reset()
+			return
+		}
+	}
+	h.cfg.metrics.Add("streams_opened", 1)
+	if h.cfg.timeouts.Idle > 0 {
+		if err := s.SetReadDeadline(time.Now().Add(h.cfg.timeouts.Idle)); err != nil {
+			h.limits.closeStream(p)
+			_ = s.Close()
+			return
+		}
+	}
+	go func() {
+		defer h.limits.closeStream(p)
+		ctx, span := tracer.Start(context.Background(), "Stream", trace.WithAttributes(attribute.String("peer", p.String())))
+		defer span.End()
+		h.readLoop(ctx, s)
+	}()
 }
 
-func (h *handler) readLoop(stream network.Stream) {
-	responder := &streamSender{stream, make(chan []byte, 5)}
+// readLoop reads and handles the messages of stream until it ends, then
+// cancels the work still pending on it.
+func (h *handler) readLoop(ctx context.Context, stream network.Stream) {
+	responder := h.newStreamSender(ctx, stream)
+	defer responder.cancelAll()
 	go responder.writeLoop()
-	buf := make([]byte, 4*1024*1024)
-	pos := uint64(0)
-	prefixLen := 0
-	msgLen := uint64(0)
-	for {
-		readLen, err := stream.Read(buf[pos:])
+	r := bufio.NewReader(&streamReader{
+		Stream:  stream,
+		idle:    h.cfg.timeouts.Idle,
+		busy:    func() bool { return responder.outstanding() > 0 },
+		metrics: h.cfg.metrics,
+		ledger:  h.cfg.ledger,
+		last:    time.Now(),
+	})
+	err := h.buffers.readMessages(r, bitswap.MaxBlockSize, func(msg []byte) error {
+		if err := h.onMessage(ctx, responder, msg); err != nil {
+			// tell the client why the stream is closed.
+			h.sendError(responder, protocolError(0, 0, err), true)
+			return err
+		}
+		// read no more requests than the client takes responses.
+		return responder.waitForRoom()
+	})
+	if err != nil {
+		// the stream failed, the client sent an invalid message, or one
+		// which could not be served.
+		_ = stream.Close()
+	}
+	responder.closeReplies()
+}
 
-		if err != nil {
-			if os.IsTimeout(err) {
-				continue
-			}
-			if errors.Is(err, io.EOF) {
-				return
-			}
-			//otherwise assume real error / conn closed.
-			//s.connErr = err
-			stream.Close()
-			return
+// streamReader reads from a stream, counting the bytes received. Reads
+// which time out fail with ErrIdle if nothing was read for the idle timeout
+// and busy reports no outstanding work, and are otherwise retried with a new
+// deadline.
+type streamReader struct {
+	network.Stream
+	idle    time.Duration
+	busy    func() bool
+	metrics MetricsSink
+	ledger  *Ledger
+	// last is when data was last read.
+	last time.Time
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.Stream.Read(p)
+		if n > 0 {
+			r.last = time.Now()
+			r.metrics.Add("bytes_received", float64(n))
+			r.ledger.received(r.Conn().RemotePeer(), n)
 		}
-		if msgLen == 0 {
-			nextLen, intLen := binary.Uvarint(buf)
-			if intLen <= 0 {
-				//s.connErr = errors.New("invalid message")
-				stream.Close()
-				return
-			}
-			if nextLen > bitswap.MaxBlockSize {
-				//s.connErr = errors.New("too large message")
-				stream.Close()
-				return
-			}
-			if nextLen > uint64(len(buf)) {
-				nb := make([]byte, uint64(intLen)+nextLen)
-				copy(nb, buf[:])
-				buf = nb
+		if n == 0 && err != nil && os.IsTimeout(err) {
+			if time.Since(r.last) >= r.idle && !r.busy() {
+				r.metrics.Add("streams_idle_closed", 1)
+				return 0, ErrIdle
 			}
-			msgLen = nextLen + uint64(intLen)
-			pos = uint64(readLen)
-			prefixLen = intLen
-		} else {
-			pos += uint64(readLen)
-		}
-
-		if pos == msgLen {
-			if err := h.onMessage(responder, buf[prefixLen:msgLen]); err != nil {
-				//s.connErr = fmt.Errorf("invalid block read: %w", err)
-				stream.Close()
-				return
+			if err := r.SetReadDeadline(time.Now().Add(r.idle)); err != nil {
+				return 0, err
 			}
-			pos = 0
-			prefixLen = 0
-			msgLen = 0
+			continue
 		}
+		return n, err
 	}
 }
 
-func (h *handler) processPIRRequestFromEncryptedCIDToIndex(encryptedCID []byte) (encryptedIndex []byte, err error) {
-	encryptedIndex = make([]byte, 0)
-	return encryptedIndex, nil
+func (h *handler) processPIRRequestFromEncryptedCIDToIndex(ctx context.Context, store *pirstore.Store, db *pirstore.Snapshot, encryptedCID []byte) (encryptedIndex []byte, err error) {
+	return h.answer(ctx, store, db, "index", 0, encryptedCID, func() ([]byte, error) {
+		return store.Scheme().Answer(db.Index, encryptedCID)
+	})
 }
 
-func (h *handler) processPIRRequestFromEncryptedIndexToBlock(encryptedIndex []byte) (encryptedBlock []byte, err error) {
-	encryptedBlock = make([]byte, 0)
-	return encryptedBlock, nil
+func (h *handler) processPIRRequestFromEncryptedIndexToBlock(ctx context.Context, store *pirstore.Store, db *pirstore.Snapshot, encryptedIndex []byte) (encryptedBlock []byte, err error) {
+	return h.answer(ctx, store, db, "blocks", 0, encryptedIndex, func() ([]byte, error) {
+		return store.Scheme().Answer(db.Blocks, encryptedIndex)
+	})
 }
 
-func (h *handler) onMessage(ss *streamSender, buf []byte) error {
+func (h *handler) onMessage(ctx context.Context, ss *streamSender, buf []byte) (err error) {
+	ctx, span := tracer.Start(ctx, "Message")
+	defer func() { endSpan(span, err) }()
 	m := bitswap_message_pb.Message{}
 	if err := m.Unmarshal(buf); err != nil {
-		logger.Warnw("failed to parse message as bitswap", "err", err)
-		return fmt.Errorf("failed to parse message (len %d) as bitswap: %w", len(buf), err)
+		framingLogger.Warnw("failed to parse message as bitswap", "err", err)
+		return fmt.Errorf("%w: message of %d bytes: %v", pir.ErrMalformed, len(buf), err)
+	}
+	if len(m.Zstd) > 0 {
+		// clients compress only once they negotiated it, and so read
+		// replies compressed in turn.
+		if err := m.Decompress(bitswap.MaxBlockSize); err != nil {
+			return err
+		}
+		ss.compressReplies()
 	}
+	h.cfg.metrics.Add("messages_received", 1)
+	if m.InlineReplies || m.PirHandshake != nil || len(m.PirRequests) > 0 || len(m.PirHintRequests) > 0 || m.Ping != 0 {
+		// only clients of this package, which read replies inline, ask
+		// for private retrievals.
+		ss.replyInline()
+	}
+	if !ss.authorized && ss.Protocol() != bitswap.ProtocolPrivate && (m.PirHandshake != nil || len(m.PirRequests) > 0 || len(m.PirHintRequests) > 0) {
+		// private retrievals over plain bitswap streams are authorized as
+		// if the private protocol was opened.
+		if err := h.authorize(ctx, ss, bitswap.ProtocolPrivate); err != nil {
+			return err
+		}
+		ss.authorized = true
+	}
+	span.SetAttributes(
+		attribute.Int("wants", len(m.Wantlist.Entries)),
+		attribute.Int("pir_requests", len(m.PirRequests)),
+	)
 
 	resp := bitswap_message_pb.Message{}
 	resp.Wantlist = bitswap_message_pb.Message_Wantlist{}
-	filled := 0
-	timed, cncl := context.WithTimeout(context.Background(), time.Second)
+	timed, cncl := context.WithTimeout(ctx, h.cfg.blockstoreTimeout)
 	defer cncl()
+	if m.Wantlist.Full {
+		// a full wantlist replaces the peer's earlier wants.
+		ss.keepWants(m.Wantlist.Entries)
+	}
+	scheduled := false
 	for _, e := range m.Wantlist.Entries {
-		// Changes in function signatures: no block CIDs here
-		// TODO: We'd need to process the encrypted CID and return an encrypted Index
-		//  (instead of Message_Have) and then process the encrypted Block Request to return Block
+		if e.Cancel {
+			ss.cancelWork(cidWork(e.Block.Cid))
+			continue
+		}
 		wantType := e.GetWantType().String()
 		if wantType == "Block" {
-			if filled < MaxSendMsgSize {
-				data, err := h.bs.Get(timed, e.Block.Cid)
-				if err != nil {
-					return err
-				}
-				resp.Blocks = append(resp.Blocks, data.RawData())
-				filled += len(data.RawData())
-			} else { // either the wantType is "Have" or it is "Block" but we can't send the block in this message
-				// in both cases just say that we have it
-				resp.BlockPresences = append(resp.BlockPresences, bitswap_message_pb.Message_BlockPresence{
-					Cid:  e.Block, // this just returns the CID from the request, not to be confused with the block fetched above
-					Type: bitswap_message_pb.Message_Have,
-				})
-			}
-
+			// blocks are sent as workers get to them, in the order chosen by the scheduler.
+			h.schedule(timed, ss, e)
+			scheduled = true
 		} else { // wantType == "Have"
 			// just reply back whether we have the message or not
 			if has, err := h.bs.Has(timed, e.Block.Cid); err == nil && has {
@@ -173,52 +416,750 @@ func (h *handler) onMessage(ss *streamSender, buf []byte) error {
 		}
 	}
 
-	if filled > 0 {
-		rBytes, err := resp.Marshal()
+	if m.PirHandshake != nil {
+		hs, err := h.timedHandshake(m.PirHandshake.Offer)
+		if err != nil {
+			return err
+		}
+		resp.PirHandshake = hs
+		if err := h.refusal(m.PirHandshake.Offer, hs); err != nil {
+			resp.Errors = append(resp.Errors, protocolError(0, 0, err))
+		} else if m.PirHandshake.Offer.OffersZstd() {
+			hs.Compression = bitswap_message_pb.CompressionZstd
+			ss.compressReplies()
+		}
+	}
+	if m.Ping != 0 {
+		h.cfg.metrics.Add("pings_answered", 1)
+		resp.Pong = m.Ping
+	}
+	for _, req := range m.PirHintRequests {
+		hint, err := h.onHintRequest(req)
+		if err != nil {
+			return err
+		}
+		resp.PirHints = append(resp.PirHints, hint)
+	}
+
+	queries := 0
+	for _, r := range m.PirRequests {
+		if !r.Cancel {
+			queries++
+		}
+	}
+	if !h.limits.allowQueries(ss.Conn().RemotePeer(), queries) {
+		return ErrRateLimited
+	}
+	h.cfg.metrics.Add("pir_queries", float64(queries))
+	type batchRound struct {
+		scheme  string
+		group   string
+		session uint64
+		round   bitswap_message_pb.Message_PIRRound
+	}
+	var batches map[batchRound][]bitswap_message_pb.Message_PIRRequest
+	var batchCtx map[batchRound]context.Context
+	busy := false
+	received := time.Now()
+	for _, r := range m.PirRequests {
+		if r.Cancel {
+			ss.cancelWork(pirWork(r.Session))
+			continue
+		}
+		if r.Id != 0 && !ss.startRequest(r.Id) {
+			// resent while the first is being answered.
+			h.cfg.metrics.Add("pir_duplicate_requests", 1)
+			continue
+		}
+		// the answer is cancelled with the session, but traced as part of this message.
+		actx := trace.ContextWithSpanContext(ss.track(pirWork(r.Session)), span.SpanContext())
+		if isBatchRound(r.Round) {
+			// the parts of a batched round are answered together.
+			if batches == nil {
+				batches = make(map[batchRound][]bitswap_message_pb.Message_PIRRequest)
+				batchCtx = make(map[batchRound]context.Context)
+			}
+			k := batchRound{r.Scheme, string(r.Group), r.Session, r.Round}
+			batches[k] = append(batches[k], r)
+			batchCtx[k] = actx
+			continue
+		}
+		r := r
+		actx, cncl := context.WithTimeout(actx, h.cfg.timeouts.round(r.Round))
+		cost := h.pirCost([]bitswap_message_pb.Message_PIRRequest{r})
+		refuse := func(err error) {
+			cncl()
+			ss.finishRequests(r)
+			ss.release(pirWork(r.Session))
+			e := protocolError(r.Session, r.Round, err)
+			e.Id = r.Id
+			h.sendError(ss, e, false)
+		}
+		if !h.queuePIR(actx, ss, r.Round, cost, func() { defer cncl(); h.answerPIR(actx, ss, r, received) }, refuse) {
+			cncl()
+			ss.finishRequests(r)
+			ss.release(pirWork(r.Session))
+			busy = true
+		}
+	}
+	for k, reqs := range batches {
+		k, reqs := k, reqs
+		actx, cncl := context.WithTimeout(batchCtx[k], h.cfg.timeouts.round(k.round))
+		release := func() {
+			cncl()
+			ss.finishRequests(reqs...)
+			for range reqs {
+				ss.release(pirWork(k.session))
+			}
+		}
+		refuse := func(err error) {
+			release()
+			h.sendError(ss, protocolError(k.session, k.round, err), false)
+		}
+		if !h.queuePIR(actx, ss, k.round, h.pirCost(reqs), func() { defer cncl(); h.answerPIRBatch(actx, ss, reqs, received) }, refuse) {
+			release()
+			busy = true
+		}
+	}
+	if busy {
+		h.cfg.metrics.Add("pir_queue_overflows", 1)
+		schedLogger.Warnw("PIR queue full, closing stream", "peer", ss.Conn().RemotePeer())
+		return ErrBusy
+	}
+
+	if len(resp.BlockPresences) > 0 || resp.PirHandshake != nil || len(resp.PirHints) > 0 || resp.Pong != 0 || len(resp.Errors) > 0 {
+		resp.PendingBytes = ss.pendingBytes()
+		rBytes, err := ss.frame(&resp)
 		if err != nil {
-			return fmt.Errorf("marshal of response failed: %w", err)
+			return err
 		}
 		return ss.enqueue(rBytes)
+	} else if scheduled || len(m.PirRequests) > 0 || hasOnlyCancels(m.Wantlist) {
+		// blocks and PIR answers are sent as they are ready.
+		return nil
 	} else {
 		return ErrNotHave
 	}
 }
 
+// sendDontHave tells the peer that the block c it asked for isn't held.
+func (h *handler) sendDontHave(ctx context.Context, ss *streamSender, c bitswap_message_pb.Cid) error {
+	m := bitswap_message_pb.Message{BlockPresences: []bitswap_message_pb.Message_BlockPresence{{
+		Cid:  c,
+		Type: bitswap_message_pb.Message_DontHave,
+	}}, PendingBytes: ss.pendingBytes()}
+	rBytes, err := ss.frame(&m)
+	if err != nil {
+		return err
+	}
+	key := cidWork(c.Cid)
+	ss.track(key)
+	if err := ss.send(ctx, rBytes, key); err != nil {
+		ss.release(key)
+		return err
+	}
+	return nil
+}
+
+// sizer is implemented by blockstores which can report the size of a block
+// without reading it, letting the scheduler weigh requests by size.
+type sizer interface {
+	GetSize(ctx context.Context, c cid.Cid) (int, error)
+}
+
+// schedule queues the block wanted by e to be sent to ss. The work is traced
+// as a child of the span in ctx.
+func (h *handler) schedule(ctx context.Context, ss *streamSender, e bitswap_message_pb.Message_Wantlist_Entry) {
+	cost, size := 1, 0
+	if sz, ok := h.bs.(sizer); ok {
+		if n, err := sz.GetSize(ctx, e.Block.Cid); err == nil {
+			cost, size = n, n
+		}
+	}
+	key := cidWork(e.Block.Cid)
+	wanted := ss.track(key)
+	ss.queueBytes(size)
+	parent := trace.SpanContextFromContext(ctx)
+	h.tasks.push(&Task{
+		Peer:     ss.Conn().RemotePeer(),
+		Priority: e.Priority,
+		Cost:     cost,
+		run: func() {
+			defer ss.release(key)
+			ss.queueBytes(-size)
+			if wanted.Err() != nil {
+				return
+			}
+			ctx, cncl := context.WithTimeout(wanted, h.cfg.timeouts.Block)
+			defer cncl()
+			ctx, span := tracer.Start(trace.ContextWithSpanContext(ctx, parent), "ServeBlock", trace.WithAttributes(
+				attribute.String("cid", e.Block.Cid.String()),
+				attribute.Int("priority", int(e.Priority)),
+			))
+			var err error
+			if e.Depth > 0 {
+				err = h.serveSubtree(ctx, ss, e)
+			} else {
+				err = h.serveBlock(ctx, ss, e.Block)
+			}
+			if errors.Is(err, ErrNotHave) {
+				// keep serving the rest of the wantlist.
+				err = nil
+				if e.SendDontHave {
+					err = h.sendDontHave(ctx, ss, e.Block)
+				}
+			}
+			endSpan(span, err)
+			if err != nil && wanted.Err() == nil {
+				logger.Warnw("failed to serve block", "cid", e.Block.Cid, "err", err)
+				_ = ss.Close()
+			}
+		},
+	})
+}
+
+// serveBlock sends the block c, split across several messages if it is
+// larger than the maximum message size.
+func (h *handler) serveBlock(ctx context.Context, ss *streamSender, c bitswap_message_pb.Cid) error {
+	raw, err := h.getBlock(ctx, c.Cid)
+	if err != nil {
+		return err
+	}
+	return h.sendBlock(ctx, ss, c, raw, cidWork(c.Cid))
+}
+
+// getBlock reads the block c from the blockstore, failing with ErrNotHave
+// if it isn't held.
+func (h *handler) getBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	timed, cncl := context.WithTimeout(ctx, h.cfg.blockstoreTimeout)
+	defer cncl()
+	data, err := h.bs.Get(timed, c)
+	if err != nil {
+		if has, herr := h.bs.Has(timed, c); herr == nil && !has {
+			return nil, ErrNotHave
+		}
+		return nil, err
+	}
+	return data.RawData(), nil
+}
+
+// sendBlock sends raw, the data of the block c, as work under key.
+func (h *handler) sendBlock(ctx context.Context, ss *streamSender, c bitswap_message_pb.Cid, raw []byte, key string) error {
+	var msgs []bitswap_message_pb.Message
+	maxSize := h.cfg.maxMessageSize
+	if len(raw) <= maxSize {
+		msg := bitswap_message_pb.Message{PendingBytes: ss.pendingBytes()}
+		if ss.legacy {
+			msg.Blocks = [][]byte{raw}
+		} else {
+			// bitswap 1.1 names the block by its CID prefix.
+			msg.Payload = []bitswap_message_pb.Message_Block{{Prefix: c.Cid.Prefix().Bytes(), Data: raw}}
+		}
+		msgs = append(msgs, msg)
+	} else {
+		for off := 0; off < len(raw); off += maxSize {
+			end := off + maxSize
+			if end > len(raw) {
+				end = len(raw)
+			}
+			msgs = append(msgs, bitswap_message_pb.Message{Chunks: []bitswap_message_pb.Message_BlockChunk{{
+				Cid:    c,
+				Offset: uint64(off),
+				Total:  uint64(len(raw)),
+				Data:   raw[off:end],
+			}}, PendingBytes: ss.pendingBytes()})
+		}
+	}
+	for _, msg := range msgs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rBytes, err := ss.frame(&msg)
+		if err != nil {
+			return err
+		}
+		ss.track(key)
+		if err := ss.send(ctx, rBytes, key); err != nil {
+			ss.release(key)
+			return err
+		}
+	}
+	h.cfg.metrics.Add("blocks_served", 1)
+	h.cfg.ledger.served(ss.Conn().RemotePeer())
+	return nil
+}
+
+func hasOnlyCancels(wl bitswap_message_pb.Message_Wantlist) bool {
+	if len(wl.Entries) == 0 {
+		return false
+	}
+	for _, e := range wl.Entries {
+		if !e.Cancel {
+			return false
+		}
+	}
+	return true
+}
+
+// answerPIR computes the response to a PIR request off the read loop, so
+// that later messages can cancel it. The response is held until its release,
+// if the server releases answers on a schedule.
+func (h *handler) answerPIR(ctx context.Context, ss *streamSender, r bitswap_message_pb.Message_PIRRequest, received time.Time) {
+	key := pirWork(r.Session)
+	defer ss.finishRequests(r)
+	if ctx.Err() != nil {
+		h.expired(ctx, ss, r)
+		ss.release(key)
+		return
+	}
+	defer h.keepalive(ctx, ss, r)()
+	_, span := tracer.Start(ctx, "AnswerPIR", trace.WithAttributes(
+		attribute.Int64("session", int64(r.Session)),
+		attribute.String("round", r.Round.String()),
+		attribute.Int("part", int(r.Part)),
+	))
+	var err error
+	defer func() { endSpan(span, err) }()
+	start := time.Now()
+	pr, err := h.onPIRRequest(ctx, ss.Conn().RemotePeer(), r)
+	elapsed := time.Since(start)
+	h.cfg.metrics.Observe("pir_answer_seconds", elapsed.Seconds())
+	h.cfg.ledger.answered(ss.Conn().RemotePeer(), 1, elapsed)
+	if err != nil && ctx.Err() != nil {
+		// cancelled before the answer was computed.
+		h.expired(ctx, ss, r)
+		ss.release(key)
+		return
+	}
+	if err != nil {
+		pirLogger.Warnw("failed to answer PIR request", "session", r.Session, "round", r.Round, "err", err)
+		ss.release(key)
+		// fail the client's round rather than leave it waiting on an
+		// answer which will not come.
+		e := protocolError(r.Session, r.Round, err)
+		e.Id = r.Id
+		h.sendError(ss, e, false)
+		return
+	}
+	if err = h.hold(ctx, received); err != nil {
+		h.expired(ctx, ss, r)
+		ss.release(key)
+		return
+	}
+	err = h.sendAnswer(ss, key, pr, func(msg []byte) error {
+		return ss.enqueue(msg, key)
+	})
+	if err != nil {
+		framingLogger.Warnw("failed to send PIR response", "session", r.Session, "err", err)
+		if errors.Is(err, ErrMemoryLimit) {
+			// fail the client's request rather than leave it waiting on
+			// an answer which will not come.
+			_ = ss.Close()
+		}
+		return
+	}
+	h.settle(ss.Conn().RemotePeer(), []int{len(pr.Answer)}, elapsed)
+}
+
+// answerPIRBatch answers one round of a batched retrieval. Each part holds a
+// reference to the session's work, released as its answer is sent, and each
+// answer is held until its release as in answerPIR.
+func (h *handler) answerPIRBatch(ctx context.Context, ss *streamSender, reqs []bitswap_message_pb.Message_PIRRequest, received time.Time) {
+	r := reqs[0]
+	key := pirWork(r.Session)
+	defer ss.finishRequests(reqs...)
+	unsent := len(reqs)
+	defer func() {
+		for ; unsent > 0; unsent-- {
+			ss.release(key)
+		}
+	}()
+	if ctx.Err() != nil {
+		h.expired(ctx, ss, r)
+		return
+	}
+	defer h.keepalive(ctx, ss, r)()
+	_, span := tracer.Start(ctx, "AnswerPIRBatch", trace.WithAttributes(
+		attribute.Int64("session", int64(r.Session)),
+		attribute.String("round", r.Round.String()),
+		attribute.Int("parts", len(reqs)),
+	))
+	var err error
+	defer func() { endSpan(span, err) }()
+	start := time.Now()
+	var sent []int
+	err = h.onPIRBatch(ctx, ss.Conn().RemotePeer(), reqs, func(pr bitswap_message_pb.Message_PIRResponse) error {
+		if err := h.hold(ctx, received); err != nil {
+			return err
+		}
+		unsent--
+		// answers are large and a batch has many, so wait for room rather
+		// than overflowing the queue.
+		err := h.sendAnswer(ss, key, pr, func(msg []byte) error {
+			return ss.send(ctx, msg, key)
+		})
+		if err == nil {
+			sent = append(sent, len(pr.Answer))
+		}
+		return err
+	})
+	elapsed := time.Since(start)
+	h.cfg.metrics.Observe("pir_answer_seconds", elapsed.Seconds())
+	h.cfg.ledger.answered(ss.Conn().RemotePeer(), len(reqs), elapsed)
+	h.settle(ss.Conn().RemotePeer(), sent, elapsed/time.Duration(len(reqs)))
+	if err != nil && !h.expired(ctx, ss, r) && ctx.Err() == nil {
+		pirLogger.Warnw("failed to answer PIR batch", "session", r.Session, "round", r.Round, "err", err)
+		h.sendError(ss, protocolError(r.Session, r.Round, err), false)
+	}
+}
+
+// sendAnswer frames pr and sends it with send, split across messages if its
+// answer is larger than the maximum message size, so that clients may decode
+// it as it arrives. Each message holds a reference to the work of key: the
+// first takes the caller's, and any of them not sent are released.
+func (h *handler) sendAnswer(ss *streamSender, key string, pr bitswap_message_pb.Message_PIRResponse, send func(msg []byte) error) error {
+	parts := splitAnswer(pr, h.cfg.maxMessageSize)
+	for range parts[1:] {
+		ss.track(key)
+	}
+	for i, part := range parts {
+		resp := bitswap_message_pb.Message{PirResponses: []bitswap_message_pb.Message_PIRResponse{part}}
+		rBytes, err := ss.frame(&resp)
+		if err == nil {
+			err = send(rBytes)
+		}
+		if err != nil {
+			for ; i < len(parts); i++ {
+				ss.release(key)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// splitAnswer splits pr into responses carrying at most size bytes of its
+// answer each, at their offsets in it, and its receipt with the last.
+func splitAnswer(pr bitswap_message_pb.Message_PIRResponse, size int) []bitswap_message_pb.Message_PIRResponse {
+	if len(pr.Answer) <= size {
+		return []bitswap_message_pb.Message_PIRResponse{pr}
+	}
+	var parts []bitswap_message_pb.Message_PIRResponse
+	for off := 0; off < len(pr.Answer); off += size {
+		end := off + size
+		if end > len(pr.Answer) {
+			end = len(pr.Answer)
+		}
+		part := pr
+		part.Offset, part.Total, part.Answer = uint64(off), uint64(len(pr.Answer)), pr.Answer[off:end]
+		if end < len(pr.Answer) {
+			// the receipt goes with the last piece, once the client has
+			// the whole answer to check it against.
+			part.Receipt = nil
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// expired reports whether the round of r ran out of time under ctx, closing
+// the stream so the client fails the request rather than waiting on it.
+func (h *handler) expired(ctx context.Context, ss *streamSender, r bitswap_message_pb.Message_PIRRequest) bool {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	h.cfg.metrics.Add("pir_timeouts", 1)
+	pirLogger.Warnw("PIR request timed out", "session", r.Session, "round", r.Round)
+	_ = ss.Close()
+	return true
+}
+
+// keepalive sends progress on the round of r every keepalive interval until
+// the returned function is called, so the client keeps waiting on answers
+// which take long to compute.
+func (h *handler) keepalive(ctx context.Context, ss *streamSender, r bitswap_message_pb.Message_PIRRequest) func() {
+	if h.cfg.keepalive <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		t := time.NewTicker(h.cfg.keepalive)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+			m := bitswap_message_pb.Message{PirProgress: []bitswap_message_pb.Message_PIRProgress{{
+				Session:       r.Session,
+				Round:         r.Round,
+				ElapsedMillis: uint64(time.Since(start).Milliseconds()),
+			}}}
+			msg, err := ss.frame(&m)
+			if err != nil {
+				return
+			}
+			// a full queue is sending the client data already.
+			if ss.offer(msg) {
+				h.cfg.metrics.Add("pir_progress_sent", 1)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// newStreamSender returns the sender of replies to stream, whose work is
+// cancelled once ctx is done or cancelAll is called.
+func (h *handler) newStreamSender(ctx context.Context, stream network.Stream) *streamSender {
+	ss := &streamSender{
+		Stream:      stream,
+		queue:       make(chan outgoing, h.cfg.sendQueueDepth),
+		room:        make(chan struct{}, 1),
+		sendTimeout: h.cfg.timeouts.Send,
+		pending:     make(map[string]*pendingWork),
+		requests:    make(map[uint64]struct{}),
+		open:        h.open,
+		padding:     h.cfg.padding,
+		protect:     &h.protect,
+		legacy:      stream.Protocol() == bitswap.ProtocolBitswapOneZero || stream.Protocol() == bitswap.ProtocolBitswapNoVers,
+		bytes:       h.limits.bytesFor(stream.Conn().RemotePeer()),
+		buffers:     h.buffers,
+		metrics:     h.cfg.metrics,
+		ledger:      h.cfg.ledger,
+	}
+	ss.ctx, ss.cancel = context.WithCancel(ctx)
+	if stream.Protocol() == bitswap.ProtocolPrivate {
+		ss.scope = stream.Scope()
+	}
+	return ss
+}
+
 type streamSender struct {
 	network.Stream
-	queue chan []byte
+	queue chan outgoing
+	// room is signalled as messages leave the queue.
+	room        chan struct{}
+	sendTimeout time.Duration
+	// ctx is done once the stream has ended, cancelling all work pending
+	// on it.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	pendingMtx sync.Mutex
+	pending    map[string]*pendingWork
+	// requests holds the ids of the PIR requests being answered.
+	requests map[uint64]struct{}
+	// queued is the size of the blocks waiting to be sent.
+	queued int64
+
+	// open, if set, opens the stream replies are written to for peers
+	// which, like stock bitswap peers, do not read them from their own.
+	open     func(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error)
+	replyMtx sync.Mutex
+	inline   bool
+	// compress, set once the peer negotiates zstd, compresses the
+	// wantlists, presences and hints of replies.
+	compress bool
+	replies  network.Stream
+	closed   bool
+	// legacy streams speak bitswap 1.0.0, whose blocks carry no CID.
+	legacy bool
+	// authorized is set once the peer of a plain bitswap stream is allowed
+	// private retrievals over it.
+	authorized bool
+	// padding rounds the size of every message sent up to a bucket.
+	padding padding.Policy
+	// protect protects the connection while PIR work is pending on it.
+	protect *protector
+	// scope, set on private bitswap streams, accounts for the memory of
+	// the messages queued on them.
+	scope network.StreamScope
+
+	// bytes, if set, throttles writes to the peer.
+	bytes   *rate.Limiter
+	buffers *bufferPool
+	metrics MetricsSink
+	ledger  *Ledger
+}
+
+// outgoing is a queued message, framed by frame, along with the work it
+// completes and the memory reserved for it in the stream's scope.
+type outgoing struct {
+	msg      []byte
+	keys     []string
+	reserved int
+	// written, if set, is closed once the message is written or dropped.
+	written chan struct{}
+}
+
+// frame marshals m behind its length prefix into a pooled buffer, which is
+// handed back to the pool once the message is sent or dropped. m is padded
+// first, if the server pads messages.
+func (ss *streamSender) frame(m *bitswap_message_pb.Message) ([]byte, error) {
+	if ss.compressesReplies() {
+		if err := m.Compress(); err != nil {
+			return nil, fmt.Errorf("compression of response failed: %w", err)
+		}
+	}
+	if ss.padding.Enabled() {
+		ss.pad(m)
+	}
+	msg, err := ss.buffers.frame(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshal of response failed: %w", err)
+	}
+	return msg, nil
 }
 
-func (ss *streamSender) enqueue(msg []byte) error {
+// enqueue queues msg, which completes the work tracked under keys, waiting
+// up to the send timeout for room. The message is dropped if all of that work
+// is cancelled before it is sent.
+func (ss *streamSender) enqueue(msg []byte, keys ...string) error {
+	out, err := ss.admit(msg, network.ReservationPriorityHigh, keys)
+	if err != nil {
+		return err
+	}
 	select {
-	case ss.queue <- msg:
+	case ss.queue <- out:
 		return nil
 	default:
+	}
+	t := time.NewTimer(ss.sendTimeout)
+	defer t.Stop()
+	select {
+	case ss.queue <- out:
+		return nil
+	case <-t.C:
+		ss.metrics.Add("send_queue_overflows", 1)
+		ss.drop(out)
 		return ErrOverflow
+	case <-ss.ctx.Done():
+		ss.drop(out)
+		return ss.ctx.Err()
 	}
 }
 
+// offer queues msg if there is room, dropping it otherwise.
+func (ss *streamSender) offer(msg []byte) bool {
+	out, err := ss.admit(msg, network.ReservationPriorityLow, nil)
+	if err != nil {
+		return false
+	}
+	select {
+	case ss.queue <- out:
+		return true
+	default:
+		ss.drop(out)
+		return false
+	}
+}
+
+// waitForRoom waits up to the send timeout while the queue is full.
+func (ss *streamSender) waitForRoom() error {
+	if cap(ss.queue) == 0 || len(ss.queue) < cap(ss.queue) {
+		return nil
+	}
+	ss.metrics.Add("read_backpressure", 1)
+	t := time.NewTimer(ss.sendTimeout)
+	defer t.Stop()
+	for len(ss.queue) >= cap(ss.queue) {
+		select {
+		case <-ss.room:
+		case <-t.C:
+			ss.metrics.Add("send_queue_overflows", 1)
+			return ErrOverflow
+		}
+	}
+	return nil
+}
+
+// send queues msg like enqueue, but waits for room in the queue.
+func (ss *streamSender) send(ctx context.Context, msg []byte, keys ...string) error {
+	out, err := ss.admit(msg, network.ReservationPriorityHigh, keys)
+	if err != nil {
+		return err
+	}
+	select {
+	case ss.queue <- out:
+		return nil
+	case <-ctx.Done():
+		ss.drop(out)
+		return ctx.Err()
+	}
+}
+
+// writeLoop writes the messages queued on ss until its stream fails or
+// ends, dropping those still queued then.
 func (ss *streamSender) writeLoop() {
-	next := []byte{}
 	for {
-		if len(next) > 0 {
-			n, err := ss.Stream.Write(next)
-			if err != nil {
+		var out outgoing
+		var ok bool
+		select {
+		case out, ok = <-ss.queue:
+		case <-ss.ctx.Done():
+			ss.dropQueued()
+			return
+		}
+		if !ok {
+			return
+		}
+		select {
+		case ss.room <- struct{}{}:
+		default:
+		}
+		wanted := len(out.keys) == 0
+		for _, k := range out.keys {
+			if ss.release(k) {
+				wanted = true
+			}
+		}
+		if wanted {
+			if err := ss.write(out.msg); err != nil {
+				ss.drop(out)
 				return
 			}
-			next = next[n:]
-			continue
 		}
+		ss.drop(out)
+	}
+}
 
-		msg, ok := <-ss.queue
-		buf := make([]byte, binary.MaxVarintLen64)
-		ln := binary.PutUvarint(buf, uint64(len(msg)))
-		if _, err := ss.Stream.Write(buf[0:ln]); err != nil {
+// dropQueued drops the messages waiting in the queue.
+func (ss *streamSender) dropQueued() {
+	for {
+		select {
+		case out := <-ss.queue:
+			ss.drop(out)
+		default:
 			return
 		}
-		next = msg
-		if !ok {
-			return
+	}
+}
+
+// write sends msg, throttled to the peer's byte rate.
+func (ss *streamSender) write(msg []byte) error {
+	dst, err := ss.replyStream()
+	if err != nil {
+		return err
+	}
+	for len(msg) > 0 {
+		chunk := msg
+		if ss.bytes != nil && len(chunk) > ss.bytes.Burst() {
+			chunk = chunk[:ss.bytes.Burst()]
 		}
+		if err := waitBytes(ss.bytes, len(chunk)); err != nil {
+			return err
+		}
+		n, err := dst.Write(chunk)
+		ss.metrics.Add("bytes_sent", float64(n))
+		ss.ledger.sent(ss.Conn().RemotePeer(), n)
+		if err != nil {
+			return err
+		}
+		msg = msg[n:]
 	}
+	return nil
 }
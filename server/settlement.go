@@ -0,0 +1,175 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ErrQuotaExceeded refuses the PIR queries of peers which have spent their
+// quota.
+var ErrQuotaExceeded = errors.New("peer exceeded its PIR quota")
+
+// QuotaError is an ErrQuotaExceeded, telling when the peer's quota renews.
+// Clients are told it with a RateLimited error.
+type QuotaError struct {
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	if e.RetryAfter <= 0 {
+		return ErrQuotaExceeded.Error()
+	}
+	return fmt.Sprintf("%v, renewed in %v", ErrQuotaExceeded, e.RetryAfter)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// A Charge is what answering one PIR query cost the server.
+type Charge struct {
+	Peer peer.ID
+	// Bytes is the size of the answer sent.
+	Bytes int
+	// Compute is the time spent computing the answer. The parts of a
+	// batched round, answered together, are each charged an equal share
+	// of its time.
+	Compute time.Duration
+}
+
+// A Settlement charges peers for the PIR queries answered for them, for
+// operators to integrate payments or quotas. It must be safe for concurrent
+// use, and should return quickly, as queries wait on it.
+type Settlement interface {
+	// Admit is called as PIR queries of p are received, before they are
+	// queued, and returns an error to refuse them, such as a QuotaError
+	// once p has spent its quota, or ErrUnauthorized if it has not paid.
+	Admit(ctx context.Context, p peer.ID) error
+	// Settle is called once for each PIR query answered, as its answer is
+	// sent. Queries which fail are not charged.
+	Settle(c Charge)
+}
+
+// Quota bounds what each peer may use of a server's PIR answers over a
+// period. Zero fields are unlimited.
+type Quota struct {
+	// Bytes is the size of the answers a peer may be sent per period.
+	Bytes uint64
+	// Compute is the time a peer's answers may take to compute per period.
+	Compute time.Duration
+	// Period is how often quotas renew. Zero never renews them, so peers
+	// are only served again once Reset.
+	Period time.Duration
+}
+
+// QuotaSettlement is a Settlement refusing the queries of peers which spent
+// their Quota, until it renews. A query admitted is answered whatever its
+// cost, so a peer may overspend by the queries it has outstanding.
+type QuotaSettlement struct {
+	quota Quota
+	now   func() time.Time
+
+	mtx   sync.Mutex
+	peers map[peer.ID]*quotaUsage
+	// swept is when peers whose period ended were last forgotten.
+	swept time.Time
+}
+
+type quotaUsage struct {
+	// start is when the peer's current period began.
+	start   time.Time
+	bytes   uint64
+	compute time.Duration
+}
+
+// NewQuotaSettlement returns a QuotaSettlement allowing each peer q.
+func NewQuotaSettlement(q Quota) *QuotaSettlement {
+	return &QuotaSettlement{quota: q, now: time.Now, peers: make(map[peer.ID]*quotaUsage)}
+}
+
+func (q *QuotaSettlement) Admit(_ context.Context, p peer.ID) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	now := q.now()
+	u := q.usage(p, now)
+	if (q.quota.Bytes > 0 && u.bytes >= q.quota.Bytes) || (q.quota.Compute > 0 && u.compute >= q.quota.Compute) {
+		e := &QuotaError{}
+		if q.quota.Period > 0 {
+			e.RetryAfter = u.start.Add(q.quota.Period).Sub(now)
+		}
+		return e
+	}
+	return nil
+}
+
+func (q *QuotaSettlement) Settle(c Charge) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	u := q.usage(c.Peer, q.now())
+	u.bytes += uint64(c.Bytes)
+	u.compute += c.Compute
+}
+
+// Used returns what p has used of its quota in the current period.
+func (q *QuotaSettlement) Used(p peer.ID) (bytes uint64, compute time.Duration) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	u := q.usage(p, q.now())
+	return u.bytes, u.compute
+}
+
+// Reset renews the quota of p at once, as when it pays for more.
+func (q *QuotaSettlement) Reset(p peer.ID) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	delete(q.peers, p)
+}
+
+// usage returns the usage of p in the period under way at now, starting
+// one if its last has ended. Peers whose period ended are forgotten once a
+// period, so that peers which stop querying are not kept.
+func (q *QuotaSettlement) usage(p peer.ID, now time.Time) *quotaUsage {
+	if q.quota.Period > 0 && now.Sub(q.swept) >= q.quota.Period {
+		for id, u := range q.peers {
+			if now.Sub(u.start) >= q.quota.Period {
+				delete(q.peers, id)
+			}
+		}
+		q.swept = now
+	}
+	u, ok := q.peers[p]
+	if !ok || (q.quota.Period > 0 && now.Sub(u.start) >= q.quota.Period) {
+		u = &quotaUsage{start: now}
+		q.peers[p] = u
+	}
+	return u
+}
+
+// admitQuery asks the server's Settlement whether the PIR queries of p are
+// answered, counting refusals as "pir_unsettled".
+func (h *handler) admitQuery(ctx context.Context, p peer.ID) error {
+	if h.cfg.settlement == nil {
+		return nil
+	}
+	err := h.cfg.settlement.Admit(ctx, p)
+	if err != nil {
+		h.cfg.metrics.Add("pir_unsettled", 1)
+	}
+	return err
+}
+
+// settle charges p for the answers of bytes sent, each of which took
+// compute.
+func (h *handler) settle(p peer.ID, bytes []int, compute time.Duration) {
+	if h.cfg.settlement == nil {
+		return
+	}
+	for _, n := range bytes {
+		h.cfg.settlement.Settle(Charge{Peer: p, Bytes: n, Compute: compute})
+	}
+}
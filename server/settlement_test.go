@@ -0,0 +1,111 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestQuotaSettlement(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := NewQuotaSettlement(Quota{Bytes: 100, Compute: time.Second, Period: time.Minute})
+	q.now = func() time.Time { return now }
+	ctx := context.Background()
+	if err := q.Admit(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	q.Settle(Charge{Peer: "a", Bytes: 60, Compute: 10 * time.Millisecond})
+	q.Settle(Charge{Peer: "b", Bytes: 10, Compute: time.Second})
+	if err := q.Admit(ctx, "a"); err != nil {
+		t.Fatalf("peer within its quota refused with %v", err)
+	}
+
+	// peers over either quota are refused until their period ends.
+	q.Settle(Charge{Peer: "a", Bytes: 40})
+	now = now.Add(20 * time.Second)
+	var qe *QuotaError
+	if err := q.Admit(ctx, "a"); !errors.As(err, &qe) || !errors.Is(err, ErrQuotaExceeded) || qe.RetryAfter != 40*time.Second {
+		t.Fatalf("peer over its byte quota admitted with %v", err)
+	}
+	if err := q.Admit(ctx, "b"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("peer over its compute quota admitted with %v", err)
+	}
+	if e := protocolError(0, 0, qe); e.Code != bitswap_message_pb.Message_RateLimited || e.RetryAfter != 40000 {
+		t.Fatalf("quota told as %v", e)
+	}
+	q.Reset("b")
+	if err := q.Admit(ctx, "b"); err != nil {
+		t.Fatalf("reset peer refused with %v", err)
+	}
+	now = now.Add(40 * time.Second)
+	if err := q.Admit(ctx, "a"); err != nil {
+		t.Fatalf("peer refused once its quota renewed: %v", err)
+	}
+	if bytes, compute := q.Used("a"); bytes != 0 || compute != 0 {
+		t.Fatalf("renewed quota used %d bytes, %v", bytes, compute)
+	}
+}
+
+func TestSettlement(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	metrics := countingSink{}
+	quota := NewQuotaSettlement(Quota{Bytes: 1})
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithSettlement(quota), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := h.handshake(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _, err := fastpir.New().Query(hs.Index.Params(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	ss.replyInline()
+	query := func(id uint64) {
+		r := bitswap_message_pb.Message_PIRRequest{Session: id, Round: bitswap_message_pb.Message_IndexRound, Query: q, Id: id}
+		msg, err := (&bitswap_message_pb.Message{PirRequests: []bitswap_message_pb.Message_PIRRequest{r}}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.onMessage(context.Background(), ss, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// answers are charged to the peer as they are sent.
+	query(1)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if bytes, compute := quota.Used("remote"); bytes > 0 && compute > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("answer not charged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if m := queued(t, ss); len(m.PirResponses) != 1 {
+		t.Fatalf("answer not sent: %v", m)
+	}
+
+	// and the peer, once over its quota, is refused.
+	query(2)
+	m := queued(t, ss)
+	if len(m.Errors) != 1 || m.Errors[0].Code != bitswap_message_pb.Message_RateLimited || m.Errors[0].Id != 2 {
+		t.Fatalf("query over quota refused with %v", m.Errors)
+	}
+	if metrics["pir_unsettled"] != 1 || !ss.startRequest(2) {
+		t.Fatalf("refused query left outstanding, counted %v", metrics)
+	}
+}
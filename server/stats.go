@@ -0,0 +1,119 @@
+package bitswapserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+// Stats is the state of a server at one time, for operators diagnosing slow
+// answers.
+type Stats struct {
+	// Streams is the number of streams open.
+	Streams int
+	// Workers and PIRWorkers are the numbers of workers serving blocks
+	// and PIR answers, and BusyWorkers and BusyPIRWorkers those running a
+	// request.
+	Workers, BusyWorkers       int
+	PIRWorkers, BusyPIRWorkers int
+	// Queued and PIRQueued are the requests waiting for a worker.
+	Queued, PIRQueued int
+	// PIRAdmitted is the estimated work of the PIR requests admitted and
+	// not yet answered.
+	PIRAdmitted time.Duration
+	// Databases are the PIR databases served.
+	Databases []DatabaseStats
+}
+
+// DatabaseStats describes the PIR databases of one store.
+type DatabaseStats struct {
+	Scheme string
+	// Group is the content group the databases lay out, or cid.Undef for
+	// the whole store.
+	Group cid.Cid
+	// Epoch is the epoch of the databases served, zero until they are
+	// first encoded.
+	Epoch uint64
+}
+
+// Monitor reads the Stats of the server it is passed to with WithMonitor,
+// and adjusts its limits while it runs, without closing its streams. It is
+// safe for concurrent use.
+type Monitor struct {
+	mtx sync.Mutex
+	h   *handler
+}
+
+// NewMonitor returns a Monitor to pass to a server.
+func NewMonitor() *Monitor {
+	return &Monitor{}
+}
+
+func (m *Monitor) attach(h *handler) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.h = h
+}
+
+// Stats returns the state of the server, reporting false if the server was
+// not yet created.
+func (m *Monitor) Stats() (Stats, bool) {
+	h := m.handler()
+	if h == nil {
+		return Stats{}, false
+	}
+	return h.stats(), true
+}
+
+// SetLimits replaces the Limits given with WithLimits, reporting false if
+// the server was not yet created. Peers are held to them from now on, over
+// the streams they have open too, except that streams opened while writes
+// were unlimited stay so.
+func (m *Monitor) SetLimits(l Limits) bool {
+	h := m.handler()
+	if h == nil {
+		return false
+	}
+	h.limits.set(l)
+	return true
+}
+
+// SetAdmission replaces the Admission budget given with WithAdmission,
+// reporting false if the server was not yet created. Requests already
+// admitted keep counting against it.
+func (m *Monitor) SetAdmission(a Admission) bool {
+	h := m.handler()
+	if h == nil {
+		return false
+	}
+	h.admission.set(a)
+	return true
+}
+
+func (m *Monitor) handler() *handler {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.h
+}
+
+func (h *handler) stats() Stats {
+	s := Stats{
+		Streams:     h.limits.streams(),
+		Workers:     h.tasks.workers,
+		PIRAdmitted: h.admission.outstanding(),
+	}
+	s.Queued, s.BusyWorkers = h.tasks.stats()
+	if h.pirTasks != nil {
+		s.PIRWorkers = h.pirTasks.workers
+		s.PIRQueued, s.BusyPIRWorkers = h.pirTasks.stats()
+	}
+	for _, db := range append(h.stores[:len(h.stores):len(h.stores)], h.groups...) {
+		s.Databases = append(s.Databases, DatabaseStats{
+			Scheme: db.Scheme().ID(),
+			Group:  db.Group(),
+			Epoch:  db.Epoch(),
+		})
+	}
+	return s
+}
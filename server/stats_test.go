@@ -0,0 +1,57 @@
+package bitswapserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor()
+	if _, ok := m.Stats(); ok || m.SetLimits(Limits{}) {
+		t.Fatal("monitor reached a server before it was created")
+	}
+	bs := util.NewMemStore(make(map[cid.Cid][]byte))
+	util.Add(bs, []byte("hello world"))
+	h, err := newHandler(bs, WithPIRScheme(fastpir.New(), pirstore.Options{}), WithPIRWorkers(1), WithMonitor(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := m.Stats()
+	if !ok || s.PIRWorkers != 1 || s.BusyPIRWorkers != 0 || len(s.Databases) != 1 {
+		t.Fatalf("got %+v", s)
+	}
+	if db := s.Databases[0]; db.Scheme != fastpir.New().ID() || db.Group.Defined() || db.Epoch != 0 {
+		t.Fatalf("got database %+v before it was encoded", db)
+	}
+	if _, err := h.handshake(nil); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ = m.Stats(); s.Databases[0].Epoch == 0 {
+		t.Fatalf("got database %+v once encoded", s.Databases[0])
+	}
+
+	// one task runs, and the next waits for the only worker.
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		h.pirTasks.push(&Task{Peer: "a", run: func() { <-release }})
+	}
+	deadline := time.Now().Add(time.Second)
+	for s, _ = m.Stats(); s.BusyPIRWorkers != 1 || s.PIRQueued != 1; s, _ = m.Stats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %+v", s)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for s, _ = m.Stats(); s.BusyPIRWorkers != 0 || s.PIRQueued != 0; s, _ = m.Stats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %+v after the tasks ran", s)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
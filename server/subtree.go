@@ -0,0 +1,79 @@
+package bitswapserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+)
+
+// serveSubtree sends the block wanted by e and its descendants down to
+// e.Depth links, breadth first, until the bytes sent reach e.MaxBytes or
+// the server's limit. Descendants the server lacks, or cannot decode the
+// links of, are left out. The walk ends with a SubtreeEnd, so the client
+// knows not to wait for more. All blocks are sent as work under the root,
+// so cancelling the want of the root stops the walk.
+func (h *handler) serveSubtree(ctx context.Context, ss *streamSender, e bitswap_message_pb.Message_Wantlist_Entry) error {
+	budget := h.cfg.maxSubtreeBytes
+	if e.MaxBytes > 0 && e.MaxBytes < budget {
+		budget = e.MaxBytes
+	}
+	key := cidWork(e.Block.Cid)
+	type node struct {
+		c     cid.Cid
+		depth uint32
+	}
+	queue := []node{{e.Block.Cid, 0}}
+	seen := map[cid.Cid]struct{}{e.Block.Cid: {}}
+	end := bitswap_message_pb.Message_SubtreeEnd{Root: e.Block}
+	var sent uint64
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		raw, err := h.getBlock(ctx, n.c)
+		if errors.Is(err, ErrNotHave) && n.depth > 0 {
+			end.Truncated = true
+			continue
+		} else if err != nil {
+			return err
+		}
+		if sent+uint64(len(raw)) > budget && n.depth > 0 {
+			// the root is sent whatever its size, as for any want.
+			end.Truncated = true
+			break
+		}
+		if err := h.sendBlock(ctx, ss, bitswap_message_pb.Cid{Cid: n.c}, raw, key); err != nil {
+			return err
+		}
+		sent += uint64(len(raw))
+		end.Blocks++
+		if n.depth == e.Depth {
+			continue
+		}
+		links, err := dag.Links(n.c, raw)
+		if err != nil {
+			end.Truncated = true
+			continue
+		}
+		for _, l := range links {
+			if _, ok := seen[l]; !ok {
+				seen[l] = struct{}{}
+				queue = append(queue, node{l, n.depth + 1})
+			}
+		}
+	}
+	m := bitswap_message_pb.Message{SubtreeEnds: []bitswap_message_pb.Message_SubtreeEnd{end}, PendingBytes: ss.pendingBytes()}
+	rBytes, err := ss.frame(&m)
+	if err != nil {
+		return err
+	}
+	ss.track(key)
+	if err := ss.send(ctx, rBytes, key); err != nil {
+		ss.release(key)
+		return err
+	}
+	h.cfg.metrics.Add("subtrees_served", 1)
+	return nil
+}
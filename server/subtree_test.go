@@ -0,0 +1,87 @@
+package bitswapserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// wantSubtree sends a want of the subtree of root to a new stream of h, and
+// returns the CIDs of the blocks sent back and the end of the subtree.
+func wantSubtree(t *testing.T, h *handler, root cid.Cid, depth uint32, maxBytes uint64) ([]cid.Cid, bitswap_message_pb.Message_SubtreeEnd) {
+	t.Helper()
+	stream := newRecordStream(bitswap.ProtocolBitswap)
+	ss := h.newStreamSender(context.Background(), stream)
+	go ss.writeLoop()
+	m := bitswap_message_pb.Message{InlineReplies: true}
+	m.Wantlist.Entries = append(m.Wantlist.Entries, bitswap_message_pb.Message_Wantlist_Entry{
+		Block:    bitswap_message_pb.Cid{Cid: root},
+		Depth:    depth,
+		MaxBytes: maxBytes,
+	})
+	msg, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.onMessage(context.Background(), ss, msg); err != nil {
+		t.Fatal(err)
+	}
+	var sent []cid.Cid
+	for {
+		reply := stream.next(t)
+		for _, b := range reply.Payload {
+			prefix, err := cid.PrefixFromBytes(b.Prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := prefix.Sum(b.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sent = append(sent, c)
+		}
+		if len(reply.SubtreeEnds) > 0 {
+			return sent, reply.SubtreeEnds[0]
+		}
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	bs := util.NewMemStore(make(map[cid.Cid][]byte)).(MutableBlockstore)
+	a, b := util.Add(bs, []byte("a")), util.Add(bs, []byte("b"))
+	mid := dagNode(t, bs, b)
+	root := dagNode(t, bs, a, mid)
+	h, err := newHandler(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// blocks are sent breadth first, down to the depth wanted.
+	sent, end := wantSubtree(t, h, root, 1, 0)
+	if len(sent) != 3 || sent[0] != root || sent[1] != a || sent[2] != mid {
+		t.Fatalf("depth 1 sent %v", sent)
+	}
+	if !end.Root.Cid.Equals(root) || end.Blocks != 3 || end.Truncated {
+		t.Fatalf("depth 1 ended with %+v", end)
+	}
+	if sent, end = wantSubtree(t, h, root, 2, 0); len(sent) != 4 || sent[3] != b || end.Blocks != 4 {
+		t.Fatalf("depth 2 sent %v, ending with %+v", sent, end)
+	}
+
+	// the byte limit stops the walk, and the root is sent whatever its size.
+	if sent, end = wantSubtree(t, h, root, 2, 1); len(sent) != 1 || !end.Truncated {
+		t.Fatalf("walk limited to 1 byte sent %v, ending with %+v", sent, end)
+	}
+
+	// descendants the server lacks are left out.
+	if err := bs.DeleteBlock(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if sent, end = wantSubtree(t, h, root, 2, 0); len(sent) != 3 || end.Blocks != 3 || !end.Truncated {
+		t.Fatalf("walk missing a block sent %v, ending with %+v", sent, end)
+	}
+}
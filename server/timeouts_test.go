@@ -0,0 +1,195 @@
+package bitswapserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+func TestWithTimeouts(t *testing.T) {
+	cfg := defaultConfig()
+	WithTimeouts(Timeouts{Idle: time.Second, IndexRound: time.Minute})(&cfg)
+	want := DefaultTimeouts
+	want.Idle = time.Second
+	want.IndexRound = time.Minute
+	if cfg.timeouts != want {
+		t.Fatalf("got %+v, expected %+v", cfg.timeouts, want)
+	}
+	if d := cfg.timeouts.round(bitswap_message_pb.Message_BatchIndexRound); d != time.Minute {
+		t.Fatalf("batched index round has timeout %v", d)
+	}
+	if d := cfg.timeouts.round(bitswap_message_pb.Message_BlockRound); d != DefaultTimeouts.BlockRound {
+		t.Fatalf("block round has timeout %v", d)
+	}
+}
+
+// closeStream is a discardStream recording whether it was closed.
+type closeStream struct {
+	discardStream
+	closed *bool
+}
+
+func (s closeStream) Close() error {
+	*s.closed = true
+	return nil
+}
+
+func TestPIRRoundTimeout(t *testing.T) {
+	metrics := countingSink{}
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := false
+	ss := h.newStreamSender(context.Background(), closeStream{closed: &closed})
+	r := bitswap_message_pb.Message_PIRRequest{Session: 1, Round: bitswap_message_pb.Message_IndexRound}
+	ctx, cncl := context.WithTimeout(ss.track(pirWork(r.Session)), 0)
+	defer cncl()
+	<-ctx.Done()
+
+	h.answerPIR(ctx, ss, r, time.Now())
+	if !closed || metrics["pir_timeouts"] != 1 {
+		t.Fatalf("expired round left the stream open (closed %v, %v timeouts)", closed, metrics["pir_timeouts"])
+	}
+	if ss.outstanding() != 0 {
+		t.Fatal("expired round was not released")
+	}
+
+	// rounds cancelled by the client are not timeouts.
+	closed = false
+	ctx, cncl = context.WithCancel(ss.track(pirWork(r.Session)))
+	cncl()
+	h.answerPIR(ctx, ss, r, time.Now())
+	if closed || metrics["pir_timeouts"] != 1 {
+		t.Fatal("cancelled round counted as a timeout")
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o deadline reached" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// idleStream times out every read.
+type idleStream struct {
+	discardStream
+	renewed *int
+}
+
+func (idleStream) Read([]byte) (int, error) { return 0, timeoutError{} }
+
+func (s idleStream) SetReadDeadline(time.Time) error {
+	*s.renewed++
+	if *s.renewed > 1 {
+		return errors.New("stop")
+	}
+	return nil
+}
+
+func TestIdleStream(t *testing.T) {
+	renewed := 0
+	r := &streamReader{Stream: idleStream{renewed: &renewed}, idle: time.Millisecond, busy: func() bool { return false }, metrics: nopSink{}}
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, ErrIdle) {
+		t.Fatalf("idle stream read returned %v", err)
+	}
+
+	// streams with outstanding work, or read from recently, are kept open.
+	r.busy = func() bool { return true }
+	if _, err := r.Read(make([]byte, 1)); err == nil || errors.Is(err, ErrIdle) || renewed != 2 {
+		t.Fatalf("busy stream read returned %v after %d renewals", err, renewed)
+	}
+	renewed = 0
+	r.busy = func() bool { return false }
+	r.idle = time.Hour
+	r.last = time.Now()
+	if _, err := r.Read(make([]byte, 1)); err == nil || errors.Is(err, ErrIdle) {
+		t.Fatalf("recently read stream returned %v", err)
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)), WithKeepalive(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	r := bitswap_message_pb.Message_PIRRequest{Session: 3, Round: bitswap_message_pb.Message_BatchBlockRound}
+	stop := h.keepalive(context.Background(), ss, r)
+	time.Sleep(35 * time.Millisecond)
+	stop()
+
+	if n := len(ss.queue); n < 2 {
+		t.Fatalf("sent %d progress messages", n)
+	}
+	out := <-ss.queue
+	l, n := binary.Uvarint(out.msg)
+	m := bitswap_message_pb.Message{}
+	if err := m.Unmarshal(out.msg[n : n+int(l)]); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.PirProgress) != 1 || m.PirProgress[0].Session != 3 || m.PirProgress[0].Round != r.Round || m.PirProgress[0].ElapsedMillis < 10 {
+		t.Fatalf("sent %v", m.PirProgress)
+	}
+}
+
+func TestStreamEnd(t *testing.T) {
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	stopped := make(chan struct{})
+	go func() {
+		ss.writeLoop()
+		close(stopped)
+	}()
+	work := ss.track(pirWork(1))
+	ss.cancelAll()
+	if work.Err() == nil || ss.outstanding() != 0 {
+		t.Fatal("work outlived the stream")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("write loop outlived the stream")
+	}
+
+	// work tracked once the stream has ended is cancelled already.
+	if ss.track(pirWork(2)).Err() == nil || ss.outstanding() != 0 {
+		t.Fatal("work tracked on an ended stream")
+	}
+	if err := ss.enqueue([]byte{0}, pirWork(2)); err == nil {
+		t.Fatal("message queued on an ended stream")
+	}
+}
+
+func TestPing(t *testing.T) {
+	h, err := newHandler(util.NewMemStore(make(map[cid.Cid][]byte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := h.newStreamSender(context.Background(), discardStream{})
+	msg, err := (&bitswap_message_pb.Message{Ping: 7}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.onMessage(context.Background(), ss, msg); err != nil {
+		t.Fatal(err)
+	}
+	out := <-ss.queue
+	l, n := binary.Uvarint(out.msg)
+	m := bitswap_message_pb.Message{}
+	if err := m.Unmarshal(out.msg[n : n+int(l)]); err != nil {
+		t.Fatal(err)
+	}
+	if m.Pong != 7 {
+		t.Fatalf("ping answered with pong %d", m.Pong)
+	}
+}
@@ -0,0 +1,140 @@
+// Package carstore serves blocks directly out of a CAR file. Blocks are read
+// from the file as they are requested, through the CAR's index, so the
+// contents are never all held in memory just to be served.
+package carstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/ipld/go-car/v2/storage"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+var logger = logging.Logger("bitswap-carstore")
+
+// Store is a read-only blockstore backed by a CAR file.
+type Store struct {
+	file *os.File
+	car  storage.ReadableCar
+}
+
+var _ bitswapserver.EnumerableBlockstore = (*Store)(nil)
+
+// Open opens the CAR at path. CARv2 files are served through their index;
+// CARv1 files, and CARv2 files without one, are indexed in memory first.
+func Open(path string) (*Store, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	car, err := storage.OpenReadable(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Store{file: f, car: car}, nil
+}
+
+func (s *Store) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	return s.car.Has(ctx, c.KeyString())
+}
+
+func (s *Store) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	data, err := s.car.Get(ctx, c.KeyString())
+	if errors.As(err, &storage.ErrNotFound{}) {
+		return nil, util.ErrNotHave
+	}
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// GetSize returns the size of the block named by c. The CAR's index does
+// not record sizes, so the block is read.
+func (s *Store) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	blk, err := s.Get(ctx, c)
+	if err != nil {
+		return -1, err
+	}
+	return len(blk.RawData()), nil
+}
+
+// AllKeysChan lists the CIDs of the blocks of the CAR in one pass over the
+// file, skipping over their contents, and stopping at the first which fails
+// to read.
+func (s *Store) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	br, err := s.blockReader()
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		for {
+			meta, err := br.SkipNext()
+			if err != nil {
+				if err != io.EOF {
+					logger.Warnw("failed to enumerate car", "err", err)
+				}
+				return
+			}
+			select {
+			case out <- meta.Cid:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// blockReader reads the blocks of the CAR from the start.
+func (s *Store) blockReader() (*carv2.BlockReader, error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return carv2.NewBlockReader(io.NewSectionReader(s.file, 0, info.Size()))
+}
+
+// Roots returns the roots of the CAR.
+func (s *Store) Roots() []cid.Cid {
+	return s.car.Roots()
+}
+
+// GetAll reads every block of the CAR into memory, to lay them out for PIR
+// with pirstore. Blocks are read in one pass over the file, stopping at the
+// first which fails to read.
+func (s *Store) GetAll() map[cid.Cid][]byte {
+	all := make(map[cid.Cid][]byte)
+	br, err := s.blockReader()
+	if err != nil {
+		logger.Warnw("failed to enumerate car", "err", err)
+		return all
+	}
+	for {
+		blk, err := br.Next()
+		if err == io.EOF {
+			return all
+		}
+		if err != nil {
+			logger.Warnw("failed to enumerate car", "err", err)
+			return all
+		}
+		all[blk.Cid()] = blk.RawData()
+	}
+}
+
+// Close closes the CAR file.
+func (s *Store) Close() error {
+	return s.file.Close()
+}
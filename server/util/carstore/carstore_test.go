@@ -0,0 +1,108 @@
+package carstore_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/carwriter"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+	"github.com/willscott/go-selfish-bitswap-client/server/util/carstore"
+)
+
+func block(t *testing.T, data string) blocks.Block {
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid([]byte(data), cid.NewCidV1(cid.Raw, h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	blks := []blocks.Block{block(t, "hello world"), block(t, "hello world 2")}
+	missing := block(t, "not a number")
+
+	for _, v1 := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "store.car")
+		var w *carwriter.Writer
+		var err error
+		if v1 {
+			f, ferr := os.Create(path)
+			if ferr != nil {
+				t.Fatal(ferr)
+			}
+			defer f.Close()
+			w, err = carwriter.NewV1(f, []cid.Cid{blks[0].Cid()})
+		} else {
+			w, err = carwriter.Create(path, []cid.Cid{blks[0].Cid()})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, blk := range blks {
+			if err := w.Put(ctx, blk); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := carstore.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if roots := s.Roots(); len(roots) != 1 || !roots[0].Equals(blks[0].Cid()) {
+			t.Fatalf("unexpected roots %v", roots)
+		}
+		for _, blk := range blks {
+			if has, err := s.Has(ctx, blk.Cid()); err != nil || !has {
+				t.Fatalf("should have %s: %v", blk.Cid(), err)
+			}
+			got, err := s.Get(ctx, blk.Cid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got.RawData()) != string(blk.RawData()) {
+				t.Fatalf("got %q", got.RawData())
+			}
+		}
+		if has, _ := s.Has(ctx, missing.Cid()); has {
+			t.Fatal("should not have a block never written")
+		}
+		if _, err := s.Get(ctx, missing.Cid()); err != util.ErrNotHave {
+			t.Fatalf("expected not have, got %v", err)
+		}
+
+		if n := len(s.GetAll()); n != len(blks) {
+			t.Fatalf("enumerated %d blocks, expected %d", n, len(blks))
+		}
+		count, largest, err := bitswapserver.Size(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(blks) || largest != len(blks[1].RawData()) {
+			t.Fatalf("sized %d blocks, the largest %d bytes", count, largest)
+		}
+		db, err := bitswapserver.NewPIRStore(s, fastpir.New(), pirstore.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.Len() != len(blks) {
+			t.Fatalf("laid out %d blocks for PIR", db.Len())
+		}
+	}
+}
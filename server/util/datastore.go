@@ -0,0 +1,203 @@
+package util
+
+import (
+	"context"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	badger "github.com/ipfs/go-ds-badger"
+	flatfs "github.com/ipfs/go-ds-flatfs"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	"github.com/willscott/go-selfish-bitswap-client/logging"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+)
+
+var logger = logging.Logger("bitswap-datastore")
+
+// BlocksPrefix is the namespace of a repository's datastore holding its
+// blocks, as Kubo and go-ipfs-blockstore lay it out.
+var BlocksPrefix = datastore.NewKey("/blocks")
+
+// DatastoreStore serves blocks out of a datastore, keyed by multihash as
+// Kubo keys them, so the blocks of a Kubo repository can be served and
+// blocks stored here read back by Kubo.
+type DatastoreStore struct {
+	ds   datastore.Batching
+	subs subscribers
+}
+
+var _ bitswapserver.MutableBlockstore = (*DatastoreStore)(nil)
+
+// NewDatastoreStore wraps the datastore of a repository as a blockstore,
+// keeping blocks under its BlocksPrefix.
+func NewDatastoreStore(ds datastore.Batching) *DatastoreStore {
+	return &DatastoreStore{ds: namespace.Wrap(ds, BlocksPrefix)}
+}
+
+// NewFlatFSStore opens, creating if needed, a flatfs repository of blocks
+// at path, sharded as Kubo shards its blocks directory. Kubo mounts that
+// directory at BlocksPrefix, so blocks are kept at the root of it: path may
+// be the blocks directory of a Kubo repository.
+func NewFlatFSStore(path string) (*DatastoreStore, error) {
+	ds, err := flatfs.CreateOrOpen(path, flatfs.NextToLast(2), true)
+	if err != nil {
+		return nil, err
+	}
+	return &DatastoreStore{ds: ds}, nil
+}
+
+// NewBadgerStore opens, creating if needed, a badger repository at path,
+// keeping blocks under its BlocksPrefix as Kubo's badgerds profile does.
+func NewBadgerStore(path string) (*DatastoreStore, error) {
+	ds, err := badger.NewDatastore(path, &badger.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatastoreStore(ds), nil
+}
+
+func dsKey(c cid.Cid) datastore.Key {
+	return dshelp.MultihashToDsKey(c.Hash())
+}
+
+func (s *DatastoreStore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	return s.ds.Has(ctx, dsKey(c))
+}
+
+func (s *DatastoreStore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	data, err := s.ds.Get(ctx, dsKey(c))
+	if err == datastore.ErrNotFound {
+		return nil, ErrNotHave
+	}
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// GetSize returns the size of a block without reading it.
+func (s *DatastoreStore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	n, err := s.ds.GetSize(ctx, dsKey(c))
+	if err == datastore.ErrNotFound {
+		return -1, ErrNotHave
+	}
+	return n, err
+}
+
+// Put stores a block.
+func (s *DatastoreStore) Put(ctx context.Context, blk blocks.Block) error {
+	if err := s.ds.Put(ctx, dsKey(blk.Cid()), blk.RawData()); err != nil {
+		return err
+	}
+	s.subs.notify(blk.Cid(), blk.RawData())
+	return nil
+}
+
+// PutMany stores blocks in one batch.
+func (s *DatastoreStore) PutMany(ctx context.Context, blks []blocks.Block) error {
+	b, err := s.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	for _, blk := range blks {
+		if err := b.Put(ctx, dsKey(blk.Cid()), blk.RawData()); err != nil {
+			return err
+		}
+	}
+	if err := b.Commit(ctx); err != nil {
+		return err
+	}
+	for _, blk := range blks {
+		s.subs.notify(blk.Cid(), blk.RawData())
+	}
+	return nil
+}
+
+// DeleteBlock removes a block. Removing a block not held is not an error.
+func (s *DatastoreStore) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	if err := s.ds.Delete(ctx, dsKey(c)); err != nil {
+		return err
+	}
+	s.subs.notify(c, nil)
+	return nil
+}
+
+// Subscribe calls f after each block put or deleted through the store.
+// Writes made to the datastore directly go unnoticed.
+func (s *DatastoreStore) Subscribe(f func(c cid.Cid, data []byte)) func() {
+	return s.subs.subscribe(f)
+}
+
+// AllKeysChan lists the CIDs of the blocks held, without reading them. As
+// blocks are keyed by multihash, their CIDs are given as raw CIDv1s, which
+// bitswap and the PIR databases match to a CID of any codec naming the same
+// multihash. Keys which are not multihashes are skipped, and enumeration
+// stops, logged, at the first error of the datastore.
+func (s *DatastoreStore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	res, err := s.ds.Query(ctx, query.Query{KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		defer res.Close()
+		for r := range res.Next() {
+			if r.Error != nil {
+				logger.Warnw("failed to enumerate datastore", "err", r.Error)
+				return
+			}
+			c, err := keyCid(r.Key)
+			if err != nil {
+				continue
+			}
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func keyCid(key string) (cid.Cid, error) {
+	h, err := dshelp.DsKeyToMultihash(datastore.RawKey(key))
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.Raw, h), nil
+}
+
+// GetAll reads every block of the datastore into memory, named as by
+// AllKeysChan. Errors of the datastore are logged, and the blocks read
+// before them returned.
+func (s *DatastoreStore) GetAll() map[cid.Cid][]byte {
+	all := make(map[cid.Cid][]byte)
+	res, err := s.ds.Query(context.Background(), query.Query{})
+	if err != nil {
+		logger.Warnw("failed to enumerate datastore", "err", err)
+		return all
+	}
+	defer res.Close()
+	for r := range res.Next() {
+		if r.Error != nil {
+			logger.Warnw("failed to enumerate datastore", "err", r.Error)
+			return all
+		}
+		c, err := keyCid(r.Key)
+		if err != nil {
+			continue
+		}
+		all[c] = r.Value
+	}
+	return all
+}
+
+// Close releases the underlying datastore.
+func (s *DatastoreStore) Close() error {
+	return s.ds.Close()
+}
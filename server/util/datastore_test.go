@@ -0,0 +1,121 @@
+package util_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/mount"
+	dssync "github.com/ipfs/go-datastore/sync"
+	flatfs "github.com/ipfs/go-ds-flatfs"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// dagBlock returns a block named by a CIDv0, as Kubo names the blocks of
+// files it adds.
+func dagBlock(t *testing.T, data string) blocks.Block {
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid([]byte(data), cid.NewCidV0(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+// kubo opens the repository at repo as Kubo does with its default flatfs
+// profile: a flatfs datastore in the blocks directory, mounted at /blocks.
+func kubo(t *testing.T, repo string) (blockstore.Blockstore, func()) {
+	fs, err := flatfs.CreateOrOpen(filepath.Join(repo, "blocks"), flatfs.NextToLast(2), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := mount.New([]mount.Mount{{Prefix: datastore.NewKey("/blocks"), Datastore: fs}})
+	return blockstore.NewBlockstore(ds), func() { _ = ds.Close() }
+}
+
+func TestFlatFSKuboLayout(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	theirs, ours := dagBlock(t, "added by kubo"), dagBlock(t, "added here")
+
+	bs, closeKubo := kubo(t, repo)
+	if err := bs.Put(ctx, theirs); err != nil {
+		t.Fatal(err)
+	}
+	closeKubo()
+
+	store, err := util.NewFlatFSStore(filepath.Join(repo, "blocks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := store.Get(ctx, theirs.Cid())
+	if err != nil || string(blk.RawData()) != "added by kubo" {
+		t.Fatalf("kubo's block read as %v, %v", blk, err)
+	}
+	keys, err := store.AllKeysChan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []cid.Cid
+	for c := range keys {
+		listed = append(listed, c)
+	}
+	if len(listed) != 1 || listed[0].Prefix().Codec != cid.Raw || string(listed[0].Hash()) != string(theirs.Cid().Hash()) {
+		t.Fatalf("listed %v, not kubo's block by multihash", listed)
+	}
+	if err := store.Put(ctx, ours); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block is where kubo keeps it: named by its multihash, in the
+	// directory of the next to last two characters of the name.
+	name := dshelp.MultihashToDsKey(ours.Cid().Hash()).String()[1:]
+	if _, err := os.Stat(filepath.Join(repo, "blocks", name[len(name)-3:len(name)-1], name+".data")); err != nil {
+		t.Fatal(err)
+	}
+	bs, closeKubo = kubo(t, repo)
+	defer closeKubo()
+	if blk, err := bs.Get(ctx, ours.Cid()); err != nil || string(blk.RawData()) != "added here" {
+		t.Fatalf("block read by kubo as %v, %v", blk, err)
+	}
+}
+
+func TestDatastoreBlocksPrefix(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	blk := dagBlock(t, "hello world")
+	if err := blockstore.NewBlockstore(ds).Put(ctx, blk); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Put(ctx, datastore.NewKey("/pins/other"), []byte("not a block")); err != nil {
+		t.Fatal(err)
+	}
+
+	store := util.NewDatastoreStore(ds)
+	if got, err := store.Get(ctx, blk.Cid()); err != nil || string(got.RawData()) != "hello world" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	all := store.GetAll()
+	if len(all) != 1 {
+		t.Fatalf("listed %d blocks, want only the one under /blocks", len(all))
+	}
+	if err := store.DeleteBlock(ctx, blk.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if has, err := blockstore.NewBlockstore(ds).Has(ctx, blk.Cid()); err != nil || has {
+		t.Fatalf("block still held after delete: %v", err)
+	}
+}
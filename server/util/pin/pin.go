@@ -0,0 +1,208 @@
+// Package pin guards the blocks of a blockstore from garbage collection.
+// Roots are pinned recursively: the root and every block it links to, as
+// decoded by dag.Links, are kept until the root is unpinned. Blocks can
+// also be kept by guards, such as the one a server registers for the blocks
+// of the PIR databases still answering in-flight sessions.
+package pin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+)
+
+var (
+	// ErrPinned is returned when deleting a block which is pinned or guarded.
+	ErrPinned = errors.New("block is pinned")
+	// ErrNotPinned is returned when unpinning a root which is not pinned.
+	ErrNotPinned = errors.New("root is not pinned")
+)
+
+// Pinner is a MutableBlockstore whose blocks are only deleted, directly or
+// by GC, when neither pinned nor guarded.
+type Pinner struct {
+	bitswapserver.MutableBlockstore
+
+	mtx sync.Mutex
+	// roots counts the pins of each root, and refs the pinned roots each
+	// block is reachable from, by multihash, as stores may list a block
+	// under a CID of another codec than the links to it.
+	roots  map[cid.Cid]int
+	refs   map[string]int
+	guards map[int]func(c cid.Cid) bool
+	next   int
+}
+
+var (
+	_ bitswapserver.MutableBlockstore = (*Pinner)(nil)
+	_ bitswapserver.Guarded           = (*Pinner)(nil)
+)
+
+// New guards the blocks of bs.
+func New(bs bitswapserver.MutableBlockstore) *Pinner {
+	return &Pinner{
+		MutableBlockstore: bs,
+		roots:             make(map[cid.Cid]int),
+		refs:              make(map[string]int),
+		guards:            make(map[int]func(c cid.Cid) bool),
+	}
+}
+
+// walk returns root and every block reachable from it. All of them must be
+// held.
+func (p *Pinner) walk(ctx context.Context, root cid.Cid) ([]cid.Cid, error) {
+	seen := map[cid.Cid]bool{root: true}
+	queue := []cid.Cid{root}
+	for i := 0; i < len(queue); i++ {
+		c := queue[i]
+		blk, err := p.MutableBlockstore.Get(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("walking %s from %s: %w", c, root, err)
+		}
+		links, err := dag.Links(c, blk.RawData())
+		if err != nil {
+			return nil, fmt.Errorf("walking %s from %s: %w", c, root, err)
+		}
+		for _, l := range links {
+			if !seen[l] {
+				seen[l] = true
+				queue = append(queue, l)
+			}
+		}
+	}
+	return queue, nil
+}
+
+// Pin keeps root and every block reachable from it, all of which must be
+// held, until it is unpinned as many times as it was pinned.
+func (p *Pinner) Pin(ctx context.Context, root cid.Cid) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.roots[root] > 0 {
+		p.roots[root]++
+		return nil
+	}
+	dag, err := p.walk(ctx, root)
+	if err != nil {
+		return err
+	}
+	for _, c := range dag {
+		p.refs[string(c.Hash())]++
+	}
+	p.roots[root] = 1
+	return nil
+}
+
+// Unpin releases a pin of root, and once none are left, of the blocks
+// reachable from it.
+func (p *Pinner) Unpin(ctx context.Context, root cid.Cid) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	switch p.roots[root] {
+	case 0:
+		return ErrNotPinned
+	case 1:
+	default:
+		p.roots[root]--
+		return nil
+	}
+	// the blocks pinned cannot have been deleted since, so the walk finds
+	// the same ones.
+	dag, err := p.walk(ctx, root)
+	if err != nil {
+		return err
+	}
+	for _, c := range dag {
+		key := string(c.Hash())
+		if p.refs[key]--; p.refs[key] == 0 {
+			delete(p.refs, key)
+		}
+	}
+	delete(p.roots, root)
+	return nil
+}
+
+// Roots returns the pinned roots.
+func (p *Pinner) Roots() []cid.Cid {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	out := make([]cid.Cid, 0, len(p.roots))
+	for c := range p.roots {
+		out = append(out, c)
+	}
+	return out
+}
+
+// IsPinned reports whether c is kept, by a pin or a guard.
+func (p *Pinner) IsPinned(c cid.Cid) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.kept(c)
+}
+
+func (p *Pinner) kept(c cid.Cid) bool {
+	if p.refs[string(c.Hash())] > 0 {
+		return true
+	}
+	for _, inUse := range p.guards {
+		if inUse(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Guard keeps the blocks inUse reports, until the returned function is
+// called. inUse must not call back into the Pinner.
+func (p *Pinner) Guard(inUse func(c cid.Cid) bool) func() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	id := p.next
+	p.next++
+	p.guards[id] = inUse
+	return func() {
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+		delete(p.guards, id)
+	}
+}
+
+// DeleteBlock removes a block, unless it is kept.
+func (p *Pinner) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.kept(c) {
+		return ErrPinned
+	}
+	return p.MutableBlockstore.DeleteBlock(ctx, c)
+}
+
+// GC removes every block which is not kept, returning how many were.
+func (p *Pinner) GC(ctx context.Context) (int, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	keys, err := p.AllKeysChan(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var garbage []cid.Cid
+	for c := range keys {
+		if !p.kept(c) {
+			garbage = append(garbage, c)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	for i, c := range garbage {
+		if err := p.MutableBlockstore.DeleteBlock(ctx, c); err != nil {
+			return i, err
+		}
+	}
+	return len(garbage), nil
+}
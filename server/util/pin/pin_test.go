@@ -0,0 +1,106 @@
+package pin_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+	"github.com/willscott/go-selfish-bitswap-client/server/util/pin"
+)
+
+// node stores a dag-cbor block linking to children.
+func node(t *testing.T, p *pin.Pinner, children ...cid.Cid) cid.Cid {
+	n, err := qp.BuildList(basicnode.Prototype.Any, int64(len(children)), func(la datamodel.ListAssembler) {
+		for _, c := range children {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: c}))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := dagcbor.Encode(n, &buf); err != nil {
+		t.Fatal(err)
+	}
+	h, err := multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid(buf.Bytes(), cid.NewCidV1(cid.DagCBOR, h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(context.Background(), blk); err != nil {
+		t.Fatal(err)
+	}
+	return blk.Cid()
+}
+
+func TestPinner(t *testing.T) {
+	ctx := context.Background()
+	p := pin.New(util.NewMemStore(make(map[cid.Cid][]byte)))
+	a := util.Add(p, []byte("a"))
+	b := util.Add(p, []byte("b"))
+	loose := util.Add(p, []byte("loose"))
+	root := node(t, p, a, node(t, p, b))
+
+	if err := p.Pin(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Pin(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DeleteBlock(ctx, b); !errors.Is(err, pin.ErrPinned) {
+		t.Fatalf("deleting a pinned block: %v", err)
+	}
+	n, err := p.GC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("collected %d blocks, expected only the loose one", n)
+	}
+	if has, _ := p.Has(ctx, loose); has {
+		t.Fatal("loose block should be collected")
+	}
+
+	// the root stays pinned until unpinned as many times as pinned.
+	if err := p.Unpin(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsPinned(b) {
+		t.Fatal("block should stay pinned")
+	}
+	if err := p.Unpin(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Unpin(ctx, root); !errors.Is(err, pin.ErrNotPinned) {
+		t.Fatalf("unpinning an unpinned root: %v", err)
+	}
+
+	release := p.Guard(func(c cid.Cid) bool { return c.Equals(a) })
+	if n, err := p.GC(ctx); err != nil || n != 3 {
+		t.Fatalf("collected %d blocks: %v", n, err)
+	}
+	if has, _ := p.Has(ctx, a); !has {
+		t.Fatal("guarded block should be kept")
+	}
+	release()
+	if err := p.DeleteBlock(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Pin(ctx, root); err == nil {
+		t.Fatal("pinning a root not held should fail")
+	}
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"context"
 	"errors"
+	"sync"
 
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
@@ -12,15 +13,19 @@ import (
 
 var ErrNotHave = errors.New("not found")
 
-func NewMemStore(of map[cid.Cid][]byte) bitswapserver.Blockstore {
-	return &store{of}
+func NewMemStore(of map[cid.Cid][]byte) bitswapserver.MutableBlockstore {
+	return &store{db: of}
 }
 
 type store struct {
-	db map[cid.Cid][]byte
+	mu   sync.RWMutex
+	db   map[cid.Cid][]byte
+	subs subscribers
 }
 
 func (s *store) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	_, ok := s.db[c]
 	if ok {
 		return true, nil
@@ -29,28 +34,146 @@ func (s *store) Has(ctx context.Context, c cid.Cid) (bool, error) {
 }
 
 func (s *store) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	s.mu.RLock()
 	blk, ok := s.db[c]
+	s.mu.RUnlock()
 	if ok {
 		return blocks.NewBlockWithCid(blk, c)
 	}
 	return nil, ErrNotHave
 }
 
+func (s *store) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blk, ok := s.db[c]
+	if ok {
+		return len(blk), nil
+	}
+	return -1, ErrNotHave
+}
+
+// Put stores a block.
+func (s *store) Put(ctx context.Context, blk blocks.Block) error {
+	return s.PutMany(ctx, []blocks.Block{blk})
+}
+
+// PutMany stores blocks.
+func (s *store) PutMany(ctx context.Context, blks []blocks.Block) error {
+	s.mu.Lock()
+	for _, blk := range blks {
+		s.db[blk.Cid()] = blk.RawData()
+	}
+	s.mu.Unlock()
+	for _, blk := range blks {
+		s.subs.notify(blk.Cid(), blk.RawData())
+	}
+	return nil
+}
+
+// DeleteBlock removes a block. Removing a block not held is not an error.
+func (s *store) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	s.mu.Lock()
+	_, ok := s.db[c]
+	delete(s.db, c)
+	s.mu.Unlock()
+	if ok {
+		s.subs.notify(c, nil)
+	}
+	return nil
+}
+
+func (s *store) Subscribe(f func(c cid.Cid, data []byte)) func() {
+	return s.subs.subscribe(f)
+}
+
+// AllKeysChan lists the CIDs of the blocks held.
+func (s *store) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	s.mu.RLock()
+	keys := make([]cid.Cid, 0, len(s.db))
+	for c := range s.db {
+		keys = append(keys, c)
+	}
+	s.mu.RUnlock()
+	return sendKeys(ctx, keys), nil
+}
+
+// sendKeys lists keys on a channel, closed once all are sent or ctx is done.
+func sendKeys(ctx context.Context, keys []cid.Cid) <-chan cid.Cid {
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		for _, c := range keys {
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
 // TODO: To encode the blcoks here, take as input an encoder callback function to run on each array item.
 func (s *store) GetAll() map[cid.Cid][]byte {
-	return s.db
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make(map[cid.Cid][]byte, len(s.db))
+	for c, blk := range s.db {
+		all[c] = blk
+	}
+	return all
+}
+
+// subscribers are the functions notified of the mutations of a store.
+type subscribers struct {
+	mu   sync.Mutex
+	next int
+	fs   map[int]func(c cid.Cid, data []byte)
+}
+
+func (s *subscribers) subscribe(f func(c cid.Cid, data []byte)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fs == nil {
+		s.fs = make(map[int]func(c cid.Cid, data []byte))
+	}
+	id := s.next
+	s.next++
+	s.fs[id] = f
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.fs, id)
+	}
 }
 
+// notify calls every subscriber, in turn, with a mutation.
+func (s *subscribers) notify(c cid.Cid, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.fs {
+		f(c, data)
+	}
+}
+
+// Add stores blk in s, which must be a MutableBlockstore, under a raw CID,
+// returning cid.Undef if it could not be.
 func Add(s bitswapserver.Blockstore, blk []byte) cid.Cid {
-	st, ok := s.(*store)
+	ms, ok := s.(bitswapserver.MutableBlockstore)
 	if !ok {
 		return cid.Undef
 	}
-
 	name, err := cid.V1Builder{Codec: uint64(multicodec.Raw), MhType: uint64(multicodec.Sha2_256)}.Sum(blk)
 	if err != nil {
 		return cid.Undef
 	}
-	st.db[name] = blk
+	b, err := blocks.NewBlockWithCid(blk, name)
+	if err != nil {
+		return cid.Undef
+	}
+	if err := ms.Put(context.Background(), b); err != nil {
+		return cid.Undef
+	}
 	return name
 }
@@ -1,24 +1,29 @@
 package bitswap
 
 import (
+	"bufio"
 	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"golang.org/x/exp/slices"
-	"os"
+	"io"
 	"sync"
 	"time"
 
+	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
-	"github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
-	"github.com/multiformats/go-multihash"
+	"github.com/libp2p/go-msgio"
+	msmux "github.com/multiformats/go-multistream"
 
+	"github.com/willscott/go-selfish-bitswap-client/logging"
 	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Bitswap interface {
@@ -34,7 +39,13 @@ type Session struct {
 
 	close   context.CancelFunc
 	conn    network.Stream
-	connErr error
+	connErr error // guarded by interestMtx
+
+	// private is the stream of ProtocolPrivate carrying private retrievals,
+	// opened on first use.
+	privateOnce sync.Once
+	private     network.Stream
+	privateErr  error
 
 	// 1 message sent on the ready chan once the connection is established
 	ready chan struct{}
@@ -43,17 +54,200 @@ type Session struct {
 	privateWants chan string
 	lbuf         []byte
 
+	writeMtx sync.Mutex
+
 	interestMtx sync.Mutex
 	interests   map[string]func([]byte, error)
-	stimeout    time.Duration
-	ttimeout    time.Duration
+	// wanted holds the CIDs of outstanding Gets, by multihash.
+	wanted map[string]cid.Cid
+	// subtrees holds the walks of outstanding GetSubtrees, by the
+	// multihash of their root.
+	subtrees   map[string]*subtreeWalk
+	invalid    int
+	partialMtx sync.Mutex
+	partials   map[string]*partialBlock
+	stimeout   time.Duration
+	ttimeout   time.Duration
+
+	schemes []pir.Scheme
+	limits  bitswap_message_pb.Message_PIROffer
+	// compress offers zstd compression in the handshake.
+	compress bool
+	// onFallback is nil unless plaintext fallback is allowed.
+	onFallback   func(peer.ID, cid.Cid, error)
+	params       *ParamCache
+	book         *AddressBook
+	handshakeMtx sync.Mutex
+	pirSession   uint64
+	// pirRequest numbers the PIR requests sent, so that their answers are
+	// told apart from those of other requests with the same session.
+	pirRequest uint64
+	// pipelineDepth bounds the retrievals PrivateGetBatch runs at once.
+	pipelineDepth int
+	// filterAge is how long peers' presence filters are trusted for,
+	// negative for not at all.
+	filterAge time.Duration
+	// group is the content group offered in the handshake, and named in
+	// every PIR request, or cid.Undef.
+	group cid.Cid
+	// protections numbers the connection manager tags protecting the
+	// connection while PIR rounds await answers.
+	protections uint64
+	rtimeout    time.Duration
+	// progress signals the PIR rounds awaiting answers, by progressInterest,
+	// when the peer reports progress on them. Guarded by interestMtx.
+	progress map[string]chan struct{}
+	// answers receives the answers awaited by PIR rounds, by pirInterest.
+	// Guarded by interestMtx.
+	answers    map[string]*answerStream
+	onProgress func(peer.ID, Progress)
+	// onReceipt is nil unless the session asks for receipts.
+	onReceipt func(peer.ID, Receipt)
+	maxHint   uint64
+	// decoyCtx is done once the session is closed, ending its decoys. It is
+	// nil unless the session sends any.
+	decoyPolicy DecoyPolicy
+	decoyCtx    context.Context
+	stopDecoys  context.CancelFunc
+	// alive is signalled by every message received, for pingLoop. It is nil
+	// unless the session pings its peer.
+	alive        chan struct{}
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+
+	metrics MetricsSink
+	sink    BlockSink
+	cache   *BlockCache
 }
 
 type Options struct {
 	SessionTimeout          time.Duration
 	WriteAggregationQuantum time.Duration
+
+	// Scheme is the PIR scheme used by PrivateGet.
+	Scheme pir.Scheme
+	// Schemes are further PIR schemes PrivateGet may use, in order of
+	// preference after Scheme. Peers choose the first scheme offered which
+	// they also support.
+	Schemes []pir.Scheme
+	// MaxElements and MaxElementSize, if positive, bound the block database
+	// peers may choose in the handshake, and so the cost of each query and
+	// the size of each answer.
+	MaxElements    uint64
+	MaxElementSize uint64
+	// MaxHintSize bounds the hints downloaded for schemes with an offline
+	// phase. Zero selects DefaultMaxHintSize.
+	MaxHintSize uint64
+	// AllowPlaintextFallback lets PrivateGet and PrivateGetBatch fetch
+	// blocks with plain wants, revealing their CIDs, from peers which support
+	// none of the session's schemes. Every block fetched so is reported to
+	// OnPlaintextFallback first, and without it there is no fallback.
+	AllowPlaintextFallback bool
+	OnPlaintextFallback    func(p peer.ID, c cid.Cid, reason error)
+	// ResponseTimeout, if set, fails PIR handshakes and rounds with
+	// ErrResponseTimeout when the peer sends neither answers nor progress
+	// for this long. Peers send progress while computing answers, so a round
+	// may take longer in all.
+	ResponseTimeout time.Duration
+	// OnProgress, if set, is called with each report of a peer that it is
+	// still computing the answers to a PIR round.
+	OnProgress func(p peer.ID, pr Progress)
+	// Params caches the PIR parameters of peers. It may be shared between
+	// sessions; if nil the session keeps its own.
+	Params *ParamCache
+	// Metrics, if set, receives measurements of the session's activity.
+	Metrics MetricsSink
+	// Retry is the policy with which a Client retries failed fetches. The
+	// zero value selects DefaultRetryPolicy. Sessions do not retry.
+	Retry RetryPolicy
+	// Sink, if set, receives every block retrieved, once verified, such as
+	// a carwriter.Writer recording the retrieval.
+	Sink BlockSink
+	// Cache, if set, keeps the blocks retrieved, and Gets and PrivateGets of
+	// blocks it holds return them from it without asking the peer. It may
+	// be shared between sessions.
+	Cache *BlockCache
+	// Scores rates the peers a Client fetches from, and a MultiSession asks
+	// the best rated peers first. It may be shared between clients; if nil
+	// the client keeps its own, with DefaultScoreParams.
+	Scores *Scores
+	// Decoys, if set, has sessions send decoy PIR retrievals, hiding the
+	// timing of real ones among them.
+	Decoys DecoyPolicy
+	// PingInterval, if set, has sessions ping their peer on the private
+	// stream whenever nothing has been received from it for this long, and
+	// fail with ErrPingTimeout if the peer does not answer within
+	// PingTimeout, so sessions with unresponsive peers are closed rather
+	// than found out on their next retrieval. Zero PingTimeout selects
+	// PingInterval.
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	// Compress offers peers zstd compression in the handshake. Peers which
+	// accept it compress the block presences and hints they send, and are
+	// sent wantlists compressed in turn. PIR queries and answers are never
+	// compressed.
+	Compress bool
+	// Pipeline is how many retrievals PrivateGetBatch keeps outstanding on
+	// the private stream at once, their rounds interleaved, so that fetching
+	// many blocks from one peer is not paced by its round trips. Zero or one
+	// retrieves them in turn.
+	Pipeline int
+	// FilterMaxAge is how long after the handshake the presence filter a
+	// peer sent with it is trusted for. Retrievals of CIDs it lacks fail
+	// with ErrNotFound at once, without a PIR round; once it is older, they
+	// are run, and the peer's parameters fetched afresh for the next. Zero
+	// selects DefaultFilterMaxAge, and negative never trusts filters.
+	FilterMaxAge time.Duration
+	// Group is the root of the content the session retrieves from, if
+	// known. Peers which lay the DAG of Group out apart, as advertised in
+	// PeerParams.Groups, are queried in its far smaller databases, which
+	// hold only the blocks of that DAG; others are queried in those of
+	// their whole store. The peer learns the group, but not which of its
+	// blocks are retrieved.
+	Group cid.Cid
+	// AddressBook, if set, keeps the parameters sessions negotiate, which
+	// later sessions with the same peers, in this process or after a
+	// restart, restore rather than run a handshake. A Client also restores
+	// the addresses and scores of the peers it holds into its host and
+	// Scores, and saves their scores on Close.
+	AddressBook *AddressBook
+	// OnReceipt, if set, asks peers for a signed receipt with each PIR
+	// answer, and is passed those received once verified against the
+	// peer's key. Rounds whose receipt does not verify fail with
+	// ErrBadReceipt; peers which sign no receipts send none.
+	OnReceipt func(p peer.ID, r Receipt)
+}
+
+// fallbackHook returns the hook reporting plaintext fallbacks, or nil if
+// they aren't allowed.
+func (o Options) fallbackHook() func(peer.ID, cid.Cid, error) {
+	if !o.AllowPlaintextFallback {
+		return nil
+	}
+	return o.OnPlaintextFallback
+}
+
+// BlockSink receives blocks as they are retrieved. Blockstores are
+// BlockSinks. Implementations must be safe for concurrent use.
+type BlockSink interface {
+	Put(ctx context.Context, blk blocks.Block) error
+}
+
+// MetricsSink receives measurements of client or server activity. Names are
+// stable, snake_case identifiers such as "bytes_received".
+type MetricsSink interface {
+	// Add increases the counter name by v.
+	Add(name string, v float64)
+	// Observe records a sample of the distribution name, such as a latency
+	// in seconds.
+	Observe(name string, v float64)
 }
 
+type nopSink struct{}
+
+func (nopSink) Add(string, float64)     {}
+func (nopSink) Observe(string, float64) {}
+
 const (
 	defaultWriteAggregationQuantum = 50 * time.Millisecond
 )
@@ -63,16 +257,74 @@ func New(h host.Host, peer peer.ID, opts Options) *Session {
 	if opts.WriteAggregationQuantum == 0 {
 		opts.WriteAggregationQuantum = defaultWriteAggregationQuantum
 	}
-	return &Session{
+	if opts.Params == nil {
+		opts.Params = NewParamCache()
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = nopSink{}
+	}
+	if opts.MaxHintSize == 0 {
+		opts.MaxHintSize = DefaultMaxHintSize
+	}
+	if opts.PingTimeout == 0 {
+		opts.PingTimeout = opts.PingInterval
+	}
+	if opts.Pipeline < 1 {
+		opts.Pipeline = 1
+	}
+	if opts.FilterMaxAge == 0 {
+		opts.FilterMaxAge = DefaultFilterMaxAge
+	}
+	var schemes []pir.Scheme
+	for _, scheme := range append([]pir.Scheme{opts.Scheme}, opts.Schemes...) {
+		if scheme != nil {
+			schemes = append(schemes, scheme)
+		}
+	}
+	s := &Session{
 		Host:      h,
 		peer:      peer,
 		ready:     make(chan struct{}),
 		wants:     make(chan cid.Cid, 5),
 		lbuf:      make([]byte, binary.MaxVarintLen64),
 		interests: make(map[string]func([]byte, error)),
+		wanted:    make(map[string]cid.Cid),
+		subtrees:  make(map[string]*subtreeWalk),
+		partials:  make(map[string]*partialBlock),
+		progress:  make(map[string]chan struct{}),
+		answers:   make(map[string]*answerStream),
 		stimeout:  opts.SessionTimeout,
 		ttimeout:  opts.WriteAggregationQuantum,
+
+		schemes: schemes,
+		limits: bitswap_message_pb.Message_PIROffer{
+			MaxElements:    opts.MaxElements,
+			MaxElementSize: opts.MaxElementSize,
+		},
+		compress:   opts.Compress,
+		onFallback: opts.fallbackHook(),
+		params:     opts.Params,
+		book:       opts.AddressBook,
+		onReceipt:  opts.OnReceipt,
+		rtimeout:   opts.ResponseTimeout,
+		onProgress: opts.OnProgress,
+		maxHint:    opts.MaxHintSize,
+		metrics:    opts.Metrics,
+		sink:       opts.Sink,
+		cache:      opts.Cache,
+
+		pingInterval: opts.PingInterval,
+		pingTimeout:  opts.PingTimeout,
+
+		pipelineDepth: opts.Pipeline,
+		filterAge:     opts.FilterMaxAge,
+		group:         opts.Group,
 	}
+	if s.pingInterval > 0 {
+		s.alive = make(chan struct{}, 1)
+	}
+	s.startDecoys(opts.Decoys)
+	return s
 }
 
 var (
@@ -84,15 +336,29 @@ var (
 	ProtocolBitswapOneOne protocol.ID = "/ipfs/bitswap/1.1.0"
 	// ProtocolBitswap is the current version of the bitswap protocol: 1.2.0
 	ProtocolBitswap protocol.ID = "/ipfs/bitswap/1.2.0"
-
-	logger = log.Logger("bitswap-client")
+	// ProtocolPrivate carries private retrievals: the PIR handshake and
+	// queries. It is negotiated apart from bitswap so peers which don't
+	// support private retrieval are found out at once, and so the two
+	// protocols can evolve independently.
+	ProtocolPrivate protocol.ID = "/ipfs/bitswap-pir/1.0.0"
+
+	logger        = logging.Logger("bitswap-client")
+	pirLogger     = logging.Logger(logging.PIR)
+	framingLogger = logging.Logger(logging.Framing)
 )
 
 const (
 	// maximum block we'll read is 4mb
 	MaxBlockSize = 1024 * 1024 * 4
+	// MaxInvalidBlocks is the number of invalid or unrequested blocks a
+	// peer may send before its session is failed.
+	MaxInvalidBlocks = 8
 )
 
+// ErrCorruptPeer fails sessions with peers which sent too many invalid
+// blocks.
+var ErrCorruptPeer = errors.New("peer sent too many invalid blocks")
+
 func (s *Session) connect() {
 	sessionCtx, cncl := context.WithCancel(context.Background())
 	s.close = cncl
@@ -103,72 +369,80 @@ func (s *Session) connect() {
 		defer cncl()
 	}
 	stream, err := s.Host.NewStream(ctx, s.peer, ProtocolBitswap, ProtocolBitswapOneZero, ProtocolBitswapOneOne, ProtocolBitswapNoVers)
-	s.connErr = err
 	s.conn = stream
-	if s.connErr != nil {
-		logger.Warnw("could not connect", "peer", s.peer, "err", s.connErr)
+	if err != nil {
+		s.interestMtx.Lock()
+		s.connErr = err
+		s.interestMtx.Unlock()
+		logger.Warnw("could not connect", "peer", s.peer, "err", err)
 		return
 	}
+	s.metrics.Add("streams_opened", 1)
 	s.Host.SetStreamHandler(stream.Protocol(), s.onStream)
 
 	go s.onStream(s.conn)
 	s.ready <- struct{}{}
 }
 
+// connectPrivate opens the private stream. Peers which do not support
+// ProtocolPrivate fail it with ErrNoCommonScheme.
+func (s *Session) connectPrivate() {
+	ctx := context.Background()
+	if s.stimeout != 0 {
+		var cncl context.CancelFunc
+		ctx, cncl = context.WithDeadline(ctx, time.Now().Add(s.stimeout))
+		defer cncl()
+	}
+	stream, err := s.Host.NewStream(ctx, s.peer, ProtocolPrivate)
+	if errors.Is(err, msmux.ErrNotSupported[protocol.ID]{}) {
+		err = fmt.Errorf("%w: peer does not support %s", ErrNoCommonScheme, ProtocolPrivate)
+	}
+	s.private, s.privateErr = stream, err
+	if err != nil {
+		logger.Debugw("could not open private stream", "peer", s.peer, "err", err)
+		return
+	}
+	s.metrics.Add("streams_opened", 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.onStream(stream)
+	}()
+	if s.pingInterval > 0 {
+		go s.pingLoop(done)
+	}
+}
+
 func (s *Session) onStream(stream network.Stream) {
 	defer stream.Close()
-	buf := make([]byte, 4*1024*1024)
-	pos := uint64(0)
-	prefixLen := 0
-	msgLen := uint64(0)
+	r := msgio.NewVarintReaderSize(bufio.NewReader(countingReader{stream, s.metrics}), MaxBlockSize)
 	for {
-		readLen, err := stream.Read(buf[pos:])
-
+		msg, err := r.ReadMsg()
 		if err != nil {
-			if os.IsTimeout(err) {
-				continue
-			}
-			s.connErr = err
-			s.Close()
+			s.fail(err)
 			return
 		}
-		if msgLen == 0 {
-			nextLen, intLen := binary.Uvarint(buf)
-			if intLen <= 0 {
-				s.connErr = errors.New("invalid message")
-				s.Close()
-				return
-			}
-			if nextLen > MaxBlockSize {
-				s.connErr = errors.New("too large message")
-				s.Close()
-				return
-			}
-			if nextLen > uint64(len(buf)) {
-				nb := make([]byte, uint64(intLen)+nextLen)
-				copy(nb, buf[:])
-				buf = nb
-			}
-			msgLen = nextLen + uint64(intLen)
-			pos = uint64(readLen)
-			prefixLen = intLen
-		} else {
-			pos += uint64(readLen)
-		}
-
-		if pos == msgLen {
-			if err := s.handle(buf[prefixLen:msgLen]); err != nil {
-				s.connErr = fmt.Errorf("invalid block read: %w", err)
-				s.Close()
-				return
-			}
-			pos = 0
-			prefixLen = 0
-			msgLen = 0
+		err = s.handle(msg)
+		r.ReleaseMsg(msg)
+		if err != nil {
+			s.fail(fmt.Errorf("invalid block read: %w", err))
+			return
 		}
 	}
 }
 
+// countingReader counts the bytes read from a stream as received.
+type countingReader struct {
+	io.Reader
+	metrics MetricsSink
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.metrics.Add("bytes_received", float64(n))
+	return n, err
+}
+
 // writeLoop is the event loop handling outbound messages
 func (s *Session) writeLoop(ctx context.Context) {
 	cids := make([]cid.Cid, 0)
@@ -212,19 +486,42 @@ func (s *Session) send(cids []cid.Cid, wantType bitswap_message_pb.Message_Wantl
 			WantType:     wantType,
 		})
 	}
+	if pp, ok := s.params.Get(s.peer); s.compress && ok && pp.Compression == bitswap_message_pb.CompressionZstd {
+		if err := m.Compress(); err != nil {
+			return err
+		}
+	}
+	return s.write(&m)
+}
+
+// write sends a single length-prefixed message on the stream. Replies are
+// read from the same stream, which servers are told.
+func (s *Session) write(m *bitswap_message_pb.Message) error {
+	m.InlineReplies = true
+	return s.writeTo(s.conn, m)
+}
 
+// writePrivate sends m on the private stream.
+func (s *Session) writePrivate(m *bitswap_message_pb.Message) error {
+	return s.writeTo(s.private, m)
+}
+
+func (s *Session) writeTo(stream network.Stream, m *bitswap_message_pb.Message) error {
 	bytes, err := m.Marshal()
 	if err != nil {
 		return err
 	}
 
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
 	ln := binary.PutUvarint(s.lbuf, uint64(len(bytes)))
-	if _, err := s.conn.Write(s.lbuf[0:ln]); err != nil {
+	if _, err := stream.Write(s.lbuf[0:ln]); err != nil {
 		return err
 	}
-	if _, err := s.conn.Write(bytes); err != nil {
+	if _, err := stream.Write(bytes); err != nil {
 		return err
 	}
+	s.metrics.Add("bytes_sent", float64(ln+len(bytes)))
 	return nil
 }
 
@@ -232,70 +529,153 @@ func (s *Session) send(cids []cid.Cid, wantType bitswap_message_pb.Message_Wantl
 func (s *Session) handle(buf []byte) error {
 	m := bitswap_message_pb.Message{}
 	if err := m.Unmarshal(buf); err != nil {
-		logger.Warnw("failed to parse message as bitswap", "err", err)
+		framingLogger.Warnw("failed to parse message as bitswap", "err", err)
 		return err
 	}
+	if err := m.Decompress(MaxBlockSize); err != nil {
+		framingLogger.Warnw("failed to decompress message", "err", err)
+		return err
+	}
+	s.metrics.Add("messages_received", 1)
+	s.heard()
 
-	cidsIHave := make([]cid.Cid, 0)
-	for _, blockPresences := range m.BlockPresences {
-		givenCid, err := cid.Cast(blockPresences.Cid.Cid.Bytes())
+	refused := false
+	for _, e := range m.Errors {
+		refused = refused || (e.Session == 0 && m.PirHandshake != nil)
+		s.onError(e, m.PirHandshake != nil)
+	}
+	if m.PirHandshake != nil && !refused {
+		hs, err := m.PirHandshake.Marshal()
 		if err != nil {
-			logger.Warnw("error casting CID from BlockPresences", err)
 			return err
 		}
-		haveBlockOrNot := blockPresences.Type.String()
-		if haveBlockOrNot == "Have" {
-			cidsIHave = append(cidsIHave, givenCid)
+		if err := s.resolveKey(handshakeInterest, hs); err != nil {
+			pirLogger.Warnw("unexpected PIR handshake", "err", err)
+		}
+	}
+	for _, r := range m.PirResponses {
+		if err := s.onResponse(r); err != nil {
+			pirLogger.Warnw("unexpected PIR response", "session", r.Session, "err", err)
+		}
+	}
+	for _, h := range m.PirHints {
+		hint, err := h.Marshal()
+		if err != nil {
+			return err
+		}
+		if err := s.resolveKey(hintInterest(h.Round, h.Offset), hint); err != nil {
+			pirLogger.Warnw("unexpected PIR hint", "err", err)
+		}
+	}
+	for _, pr := range m.PirProgress {
+		s.progressed(pr)
+	}
+	for _, ch := range m.Chunks {
+		if err := s.onChunk(ch); err != nil {
+			return err
 		}
 	}
 
-	foundBlocks := 0
-	// bitswap 1.1
-	for _, e := range m.Wantlist.Entries {
-		if e.WantType.String() == "Block" {
-			for _, bp := range m.Payload {
-				prefix, err := cid.PrefixFromBytes(bp.Prefix)
-				if err != nil {
-					logger.Warnw("failed to parse payload cid", "err", err)
-					continue
-				}
-				c, err := prefix.Sum(bp.GetData())
-				if err != nil {
-					logger.Warnw("failed to hash payload", "err", err)
-					continue
-				}
-				if err := s.resolve(c, bp.GetData(), nil); err != nil {
-					continue
-				}
-				foundBlocks++
+	var haves []cid.Cid
+	for _, bp := range m.BlockPresences {
+		c := bp.Cid.Cid
+		if bp.Type == bitswap_message_pb.Message_Have {
+			if s.isWanted(c) {
+				haves = append(haves, c)
 			}
-			// bitswap 1.0
-			for _, b := range m.Blocks {
-				// CIDv0, sha256, protobuf only
-				mh, err := multihash.Sum(b, multihash.SHA2_256, -1)
-				if err != nil {
-					logger.Warnw("failed to hash block", "err", err)
-					continue
-				}
-				c := cid.NewCidV0(mh)
-				if err := s.resolve(c, b, nil); err != nil {
-					continue
-				}
-				foundBlocks++
+		} else {
+			// fail the Get now rather than leaving it to time out.
+			_ = s.resolve(c, nil, ErrNotFound)
+		}
+	}
+	if len(haves) > 0 {
+		if err := s.send(haves, bitswap_message_pb.Message_Wantlist_Block); err != nil {
+			return err
+		}
+	}
+
+	// bitswap 1.1: the payload names its cid, so hashing it proves the claim.
+	for _, bp := range m.Payload {
+		prefix, err := cid.PrefixFromBytes(bp.Prefix)
+		if err != nil {
+			if err := s.onInvalid("unparseable payload cid", err); err != nil {
+				return err
 			}
-			if foundBlocks == 0 {
-				return errors.New("no requested block read")
+			continue
+		}
+		c, err := prefix.Sum(bp.GetData())
+		if err != nil {
+			if err := s.onInvalid("unhashable payload", err); err != nil {
+				return err
 			}
-		} else {
-			c := e.Block.Cid
-			if slices.Contains(cidsIHave, c) {
-				err := s.send(cidsIHave, bitswap_message_pb.Message_Wantlist_Block)
-				if err != nil {
-					return err
-				}
+			continue
+		}
+		sub := s.onSubtreeBlock(c, bp.GetData())
+		if err := s.resolve(c, bp.GetData(), nil); err != nil && !sub {
+			framingLogger.Debugw("unrequested block", "cid", c)
+		}
+	}
+	// bitswap 1.0: bare blocks must hash to one of the outstanding wants.
+	for _, b := range m.Blocks {
+		c, ok := s.match(b)
+		if !ok {
+			if err := s.onInvalid("block matches no outstanding want", nil); err != nil {
+				return err
 			}
+			continue
+		}
+		_ = s.resolve(c, b, nil)
+	}
+	// the end of a subtree follows all its blocks.
+	for _, end := range m.SubtreeEnds {
+		s.onSubtreeEnd(end)
+	}
+	return nil
+}
+
+// isWanted reports whether there is an outstanding Get of c.
+func (s *Session) isWanted(c cid.Cid) bool {
+	s.interestMtx.Lock()
+	defer s.interestMtx.Unlock()
+	_, ok := s.wanted[c.Hash().HexString()]
+	return ok
+}
+
+// match finds the outstanding want that data is the block of, by hashing it
+// as each kind of cid wanted.
+func (s *Session) match(data []byte) (cid.Cid, bool) {
+	s.interestMtx.Lock()
+	prefixes := make(map[cid.Prefix]struct{})
+	for _, c := range s.wanted {
+		prefixes[c.Prefix()] = struct{}{}
+	}
+	s.interestMtx.Unlock()
+
+	for prefix := range prefixes {
+		c, err := prefix.Sum(data)
+		if err != nil {
+			continue
+		}
+		if s.isWanted(c) {
+			return c, true
 		}
 	}
+	return cid.Undef, false
+}
+
+// onInvalid records that the peer sent data which isn't what it claims to
+// be, or wasn't asked for. Too many of these, beyond what cancellations
+// racing with responses explain, and the session is failed with
+// ErrCorruptPeer.
+func (s *Session) onInvalid(reason string, err error) error {
+	s.metrics.Add("invalid_blocks", 1)
+	logger.Warnw("invalid block from peer", "peer", s.peer, "reason", reason, "err", err)
+	s.interestMtx.Lock()
+	defer s.interestMtx.Unlock()
+	s.invalid++
+	if s.invalid >= MaxInvalidBlocks {
+		return ErrCorruptPeer
+	}
 	return nil
 }
 
@@ -304,22 +684,48 @@ func (s *Session) Close() error {
 	if s.close != nil {
 		s.close()
 	}
+	if s.stopDecoys != nil {
+		s.stopDecoys()
+	}
+	if s.private != nil {
+		_ = s.private.Close()
+	}
+	s.interestMtx.Lock()
+	defer s.interestMtx.Unlock()
 	if s.connErr != nil {
-		s.interestMtx.Lock()
-		defer s.interestMtx.Unlock()
-		for _, i := range s.interests {
+		for key, i := range s.interests {
+			delete(s.interests, key)
 			i(nil, s.connErr)
 		}
 	}
 	return nil
 }
 
+// fail records err as the reason the session stopped, and stops it. The
+// first error recorded is kept.
+func (s *Session) fail(err error) {
+	s.interestMtx.Lock()
+	if s.connErr == nil {
+		s.connErr = err
+	}
+	s.interestMtx.Unlock()
+	s.Close()
+}
+
+// failure returns the reason the session stopped, if it has.
+func (s *Session) failure() error {
+	s.interestMtx.Lock()
+	defer s.interestMtx.Unlock()
+	return s.connErr
+}
+
 func (s *Session) on(c cid.Cid, cb func([]byte, error)) {
 	s.interestMtx.Lock()
 	defer s.interestMtx.Unlock()
 	// todo: support multiple
 	mh := c.Hash().HexString()
 	s.interests[mh] = cb
+	s.wanted[mh] = c
 }
 
 func (s *Session) resolve(c cid.Cid, data []byte, err error) error {
@@ -329,11 +735,15 @@ func (s *Session) resolve(c cid.Cid, data []byte, err error) error {
 	cb, ok := s.interests[mh]
 	if ok {
 		delete(s.interests, mh)
+		delete(s.wanted, mh)
 	}
 
 	s.interestMtx.Unlock()
 
 	if ok {
+		if err == nil {
+			s.metrics.Add("blocks_received", 1)
+		}
 		cb(data, err)
 		return nil
 	} else {
@@ -341,6 +751,62 @@ func (s *Session) resolve(c cid.Cid, data []byte, err error) error {
 	}
 }
 
+// partialBlock is a block being reassembled from chunks.
+type partialBlock struct {
+	data     []byte
+	received uint64
+	// offsets holds the offsets of the chunks received, so that a chunk
+	// sent again is not counted twice.
+	offsets map[uint64]bool
+}
+
+// onChunk adds a chunk to the block it is part of, resolving the block once
+// all of it has arrived.
+func (s *Session) onChunk(ch bitswap_message_pb.Message_BlockChunk) error {
+	if ch.Total > MaxBlockSize || ch.Offset+uint64(len(ch.Data)) > ch.Total {
+		return errors.New("invalid block chunk")
+	}
+	key := ch.Cid.Cid.KeyString()
+	if !s.isWanted(ch.Cid.Cid) && !s.inSubtree(ch.Cid.Cid) {
+		// likely cancelled since; don't reassemble it.
+		framingLogger.Debugw("unrequested block chunk", "cid", ch.Cid.Cid)
+		return nil
+	}
+
+	s.partialMtx.Lock()
+	p, ok := s.partials[key]
+	if !ok {
+		p = &partialBlock{data: make([]byte, ch.Total), offsets: make(map[uint64]bool)}
+		s.partials[key] = p
+	}
+	if uint64(len(p.data)) != ch.Total {
+		s.partialMtx.Unlock()
+		return errors.New("inconsistent block chunk size")
+	}
+	copy(p.data[ch.Offset:], ch.Data)
+	if !p.offsets[ch.Offset] {
+		p.offsets[ch.Offset] = true
+		p.received += uint64(len(ch.Data))
+	}
+	complete := p.received >= ch.Total
+	if complete {
+		delete(s.partials, key)
+	}
+	s.partialMtx.Unlock()
+
+	if !complete {
+		return nil
+	}
+	if err := verify(ch.Cid.Cid, p.data); err != nil {
+		return s.onInvalid("chunked block doesn't match its cid", err)
+	}
+	sub := s.onSubtreeBlock(ch.Cid.Cid, p.data)
+	if err := s.resolve(ch.Cid.Cid, p.data, nil); err != nil && !sub {
+		framingLogger.Debugw("unrequested block", "cid", ch.Cid.Cid)
+	}
+	return nil
+}
+
 func (s *Session) generatePIRRequestToGetIndexFromCID(c cid.Cid) ([]byte, error) {
 	return make([]byte, 0), nil
 }
@@ -359,33 +825,85 @@ func (s *Session) decodeBlock(encryptedBlock []byte) ([]byte, error) {
 
 // Get a specific block of data in this session.
 // ctx is used to wrap client in timeout logic across a session.
-func (s *Session) Get(ctx context.Context, c cid.Cid) ([]byte, error) {
+func (s *Session) Get(ctx context.Context, c cid.Cid) (_ []byte, err error) {
+	_, span := tracer.Start(ctx, "Get", trace.WithAttributes(
+		attribute.String("peer", s.peer.String()),
+		attribute.String("cid", c.String()),
+	))
+	defer func() { endSpan(span, err) }()
 	// confirm connected.
+	if data, ok, err := s.cached(ctx, c); ok || err != nil {
+		return data, err
+	}
 	s.initated.Do(s.connect)
-	if s.connErr != nil {
-		return nil, s.connErr
+	if err := s.failure(); err != nil {
+		return nil, err
 	}
-	s.wants <- c
 
 	// wait for want to be handled.
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	var data []byte
-	var err error
-
-	// TODO: run decodeIndex and trigger running the generatePIRRequestToGetBlockFromIndex
-	//  and then when the server responds with the block, again, trigger decodeBlock
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
 	s.on(c, func(rb []byte, re error) {
-		data = rb
-		err = re
-		wg.Done()
+		done <- result{rb, re}
 	})
-	wg.Wait()
+	s.wants <- c
 
 	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		if err := s.record(ctx, c, r.data); err != nil {
+			return nil, err
+		}
+		return r.data, nil
 	case <-ctx.Done():
-		return data, ctx.Err()
-	default:
-		return data, err
+		if err := s.Cancel(c); err != nil {
+			logger.Debugw("failed to cancel want", "cid", c, "err", err)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// record keeps a retrieved block in the session's cache and passes it to its
+// sink.
+func (s *Session) record(ctx context.Context, c cid.Cid, data []byte) error {
+	if s.cache != nil {
+		if evicted := s.cache.add(c, data); evicted > 0 {
+			s.metrics.Add("block_cache_evictions", float64(evicted))
+		}
+	}
+	if s.sink == nil {
+		return nil
+	}
+	blk, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		return err
 	}
+	if err := s.sink.Put(ctx, blk); err != nil {
+		return fmt.Errorf("record %s: %w", c, err)
+	}
+	return nil
+}
+
+// Cancel abandons an outstanding Get of c, asking the peer to drop any work
+// it has queued for it.
+func (s *Session) Cancel(c cid.Cid) error {
+	s.interestMtx.Lock()
+	delete(s.interests, c.Hash().HexString())
+	delete(s.wanted, c.Hash().HexString())
+	s.interestMtx.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	m := bitswap_message_pb.Message{}
+	m.Wantlist.Entries = append(m.Wantlist.Entries, bitswap_message_pb.Message_Wantlist_Entry{
+		Block:  bitswap_message_pb.Cid{Cid: c},
+		Cancel: true,
+	})
+	return s.write(&m)
 }
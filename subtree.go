@@ -0,0 +1,182 @@
+package bitswap
+
+import (
+	"context"
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/willscott/go-selfish-bitswap-client/dag"
+	bitswap_message_pb "github.com/willscott/go-selfish-bitswap-client/message"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Subtree is the part of a DAG sent for a GetSubtree.
+type Subtree struct {
+	// Blocks are the root and its descendants, in the order the peer sent
+	// them, which is breadth first.
+	Blocks []blocks.Block
+	// Truncated is set when the peer left out descendants within the depth
+	// asked for, because of the byte limit or because it lacks them.
+	Truncated bool
+}
+
+// subtreeWalk collects the blocks of a GetSubtree as they arrive.
+type subtreeWalk struct {
+	depth uint32
+	// expect holds the CIDs linked from the blocks received so far, and
+	// the depth they are at, by multihash.
+	expect map[string]subtreeLink
+	blocks []blocks.Block
+	end    chan bitswap_message_pb.Message_SubtreeEnd
+}
+
+type subtreeLink struct {
+	c     cid.Cid
+	depth uint32
+}
+
+// GetSubtree retrieves root and its descendants down to depth links, as far
+// as maxBytes of blocks, or the peer's own limit if maxBytes is 0, allow.
+// One want is sent for the whole subtree, and the peer walks it and streams
+// the blocks back, saving a round trip per level of the DAG.
+//
+// Blocks are only accepted if they are linked from blocks of the subtree
+// received before them, so the peer cannot pass off other blocks as part of
+// it. Like Get, the want names root in plaintext: the peer must learn the
+// CIDs of a DAG to walk it, so subtrees cannot be retrieved privately.
+func (s *Session) GetSubtree(ctx context.Context, root cid.Cid, depth uint32, maxBytes uint64) (_ *Subtree, err error) {
+	_, span := tracer.Start(ctx, "GetSubtree", trace.WithAttributes(
+		attribute.String("peer", s.peer.String()),
+		attribute.String("cid", root.String()),
+		attribute.Int("depth", int(depth)),
+	))
+	defer func() { endSpan(span, err) }()
+	s.initated.Do(s.connect)
+	if err := s.failure(); err != nil {
+		return nil, err
+	}
+
+	key := root.Hash().HexString()
+	w := &subtreeWalk{
+		depth:  depth,
+		expect: map[string]subtreeLink{key: {root, 0}},
+		end:    make(chan bitswap_message_pb.Message_SubtreeEnd, 1),
+	}
+	s.interestMtx.Lock()
+	if _, ok := s.subtrees[key]; ok {
+		s.interestMtx.Unlock()
+		return nil, fmt.Errorf("subtree of %s already wanted", root)
+	}
+	s.subtrees[key] = w
+	s.interestMtx.Unlock()
+	defer func() {
+		s.interestMtx.Lock()
+		delete(s.subtrees, key)
+		s.interestMtx.Unlock()
+	}()
+
+	// the root is also wanted as for Get, so that the peer not having it
+	// fails the walk at once.
+	missing := make(chan error, 1)
+	s.on(root, func(_ []byte, err error) {
+		if err != nil {
+			missing <- err
+		}
+	})
+	m := bitswap_message_pb.Message{}
+	m.Wantlist.Entries = []bitswap_message_pb.Message_Wantlist_Entry{{
+		Block:        bitswap_message_pb.Cid{Cid: root},
+		WantType:     bitswap_message_pb.Message_Wantlist_Block,
+		SendDontHave: true,
+		Depth:        depth,
+		MaxBytes:     maxBytes,
+	}}
+	if err := s.write(&m); err != nil {
+		return nil, err
+	}
+
+	select {
+	case end := <-w.end:
+		s.interestMtx.Lock()
+		st := &Subtree{Blocks: w.blocks, Truncated: end.Truncated || uint64(len(w.blocks)) < end.Blocks}
+		s.interestMtx.Unlock()
+		for _, blk := range st.Blocks {
+			if err := s.record(ctx, blk.Cid(), blk.RawData()); err != nil {
+				return nil, err
+			}
+		}
+		return st, nil
+	case err := <-missing:
+		return nil, err
+	case <-ctx.Done():
+		if err := s.Cancel(root); err != nil {
+			logger.Debugw("failed to cancel want", "cid", root, "err", err)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// inSubtree reports whether c is linked from a subtree being received.
+func (s *Session) inSubtree(c cid.Cid) bool {
+	s.interestMtx.Lock()
+	defer s.interestMtx.Unlock()
+	key := c.Hash().HexString()
+	for _, w := range s.subtrees {
+		if _, ok := w.expect[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// onSubtreeBlock adds the block c, whose data was checked against it, to
+// the subtrees it is linked from, and expects the blocks it links to in
+// turn. It reports whether any subtree took the block.
+func (s *Session) onSubtreeBlock(c cid.Cid, data []byte) bool {
+	key := c.Hash().HexString()
+	s.interestMtx.Lock()
+	defer s.interestMtx.Unlock()
+	took := false
+	for _, w := range s.subtrees {
+		l, ok := w.expect[key]
+		if !ok {
+			continue
+		}
+		delete(w.expect, key)
+		blk, err := blocks.NewBlockWithCid(data, l.c)
+		if err != nil {
+			continue
+		}
+		w.blocks = append(w.blocks, blk)
+		took = true
+		s.metrics.Add("subtree_blocks", 1)
+		if l.depth == w.depth {
+			continue
+		}
+		links, err := dag.Links(l.c, data)
+		if err != nil {
+			continue
+		}
+		for _, lc := range links {
+			w.expect[lc.Hash().HexString()] = subtreeLink{lc, l.depth + 1}
+		}
+	}
+	return took
+}
+
+// onSubtreeEnd ends the walk of the subtree of end.Root.
+func (s *Session) onSubtreeEnd(end bitswap_message_pb.Message_SubtreeEnd) {
+	s.interestMtx.Lock()
+	w, ok := s.subtrees[end.Root.Cid.Hash().HexString()]
+	s.interestMtx.Unlock()
+	if !ok {
+		logger.Debugw("unexpected subtree end", "cid", end.Root.Cid)
+		return
+	}
+	select {
+	case w.end <- end:
+	default:
+	}
+}
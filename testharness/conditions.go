@@ -0,0 +1,106 @@
+package testharness
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// Conditions simulate a wide area network between the client and the server.
+// The zero value is a perfect link.
+type Conditions struct {
+	// Latency delays every write, and Bandwidth, in bytes per second, caps
+	// the throughput of the link. Zero is unlimited.
+	Latency   time.Duration
+	Bandwidth float64
+	// Drop is the probability that a read or write on one of the client's
+	// streams fails, resetting the stream as if the connection was lost.
+	Drop float64
+	// MaxRead, if positive, bounds the bytes each read from one of the
+	// client's streams returns, so messages arrive in pieces.
+	MaxRead int
+}
+
+// SetConditions changes the conditions of the link between the client and
+// the server. Streams already open are affected too.
+func (h *Harness) SetConditions(c Conditions) {
+	for _, l := range h.Net.LinksBetweenPeers(h.ClientHost.ID(), h.ServerHost.ID()) {
+		l.SetOptions(mocknet.LinkOptions{Latency: c.Latency, Bandwidth: c.Bandwidth})
+	}
+	h.streams.set(c)
+}
+
+// streamConditions are the conditions applied to streams rather than by the
+// link.
+type streamConditions struct {
+	mtx     sync.Mutex
+	drop    float64
+	maxRead int
+}
+
+func (sc *streamConditions) set(c Conditions) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	sc.drop, sc.maxRead = c.Drop, c.MaxRead
+}
+
+// dropped reports whether the next read or write fails, and how many bytes
+// the next read may return.
+func (sc *streamConditions) dropped() (bool, int) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	return sc.drop > 0 && rand.Float64() < sc.drop, sc.maxRead
+}
+
+// conditionedHost applies stream conditions to every stream it opens or
+// accepts.
+type conditionedHost struct {
+	host.Host
+	conditions *streamConditions
+}
+
+func (ch conditionedHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error) {
+	s, err := ch.Host.NewStream(ctx, p, pids...)
+	if err != nil {
+		return nil, err
+	}
+	return conditionedStream{s, ch.conditions}, nil
+}
+
+func (ch conditionedHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	ch.Host.SetStreamHandler(pid, func(s network.Stream) {
+		handler(conditionedStream{s, ch.conditions})
+	})
+}
+
+type conditionedStream struct {
+	network.Stream
+	conditions *streamConditions
+}
+
+func (cs conditionedStream) Read(p []byte) (int, error) {
+	drop, maxRead := cs.conditions.dropped()
+	if drop {
+		_ = cs.Stream.Reset()
+		return 0, network.ErrReset
+	}
+	if maxRead > 0 && len(p) > maxRead {
+		p = p[:maxRead]
+	}
+	return cs.Stream.Read(p)
+}
+
+func (cs conditionedStream) Write(p []byte) (int, error) {
+	if drop, _ := cs.conditions.dropped(); drop {
+		_ = cs.Stream.Reset()
+		return 0, network.ErrReset
+	}
+	return cs.Stream.Write(p)
+}
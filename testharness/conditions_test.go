@@ -0,0 +1,124 @@
+package testharness_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+	"github.com/willscott/go-selfish-bitswap-client/testharness"
+)
+
+// wan is a long, narrow link.
+var wan = testharness.Conditions{Latency: 40 * time.Millisecond, Bandwidth: 1 << 20}
+
+func TestSlowLink(t *testing.T) {
+	h := testharness.New(t, testharness.Options{Blocks: smallBlocks(3), Schemes: []pir.Scheme{spiral.New()}, Conditions: wan})
+	s := h.Session()
+	for i, c := range h.CIDs {
+		data, err := s.PrivateGet(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Expect(i, data)
+	}
+}
+
+func TestPartialReads(t *testing.T) {
+	h := testharness.New(t, testharness.Options{Blocks: smallBlocks(3), Conditions: testharness.Conditions{MaxRead: 3}})
+	s := h.Session()
+	for i, c := range h.CIDs {
+		data, err := s.PrivateGet(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Expect(i, data)
+		if data, err = s.Get(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+		h.Expect(i, data)
+	}
+}
+
+func TestDrops(t *testing.T) {
+	rp := bitswap.DefaultRetryPolicy
+	rp.MaxAttempts = 10
+	rp.InitialBackoff = time.Millisecond
+	rp.AttemptTimeout = 5 * time.Second
+	h := testharness.New(t, testharness.Options{
+		Blocks:     smallBlocks(8),
+		Client:     bitswap.Options{Retry: rp},
+		Conditions: testharness.Conditions{Drop: 0.05},
+	})
+	cl := h.Client()
+	// retries over new streams recover from lost connections.
+	for i, c := range h.CIDs {
+		data, err := cl.PrivateGet(context.Background(), h.ServerHost.ID(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Expect(i, data)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	rp := bitswap.DefaultRetryPolicy
+	rp.MaxAttempts = 1
+	rp.AttemptTimeout = 50 * time.Millisecond
+	h := testharness.New(t, testharness.Options{
+		Blocks:     smallBlocks(1),
+		Client:     bitswap.Options{Retry: rp},
+		Conditions: testharness.Conditions{Latency: 100 * time.Millisecond},
+	})
+	if _, err := h.Client().PrivateGet(context.Background(), h.ServerHost.ID(), h.CIDs[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the attempt to time out, got %v", err)
+	}
+
+	h.SetConditions(testharness.Conditions{})
+	if _, err := h.Client().PrivateGet(context.Background(), h.ServerHost.ID(), h.CIDs[0]); err != nil {
+		t.Fatalf("once the link recovered: %v", err)
+	}
+}
+
+func TestResponseTimeout(t *testing.T) {
+	h := testharness.New(t, testharness.Options{
+		Blocks:     smallBlocks(1),
+		Client:     bitswap.Options{ResponseTimeout: 30 * time.Millisecond},
+		Conditions: testharness.Conditions{Latency: 60 * time.Millisecond},
+	})
+	if _, err := h.Session().PrivateGet(context.Background(), h.CIDs[0]); !errors.Is(err, bitswap.ErrResponseTimeout) {
+		t.Fatalf("expected the peer to be considered quiet, got %v", err)
+	}
+}
+
+func BenchmarkPrivateGet(b *testing.B) {
+	for _, bc := range []struct {
+		name       string
+		scheme     pir.Scheme
+		conditions testharness.Conditions
+	}{
+		{"fastpir/lan", fastpir.New(), testharness.Conditions{}},
+		{"spiral/lan", spiral.New(), testharness.Conditions{}},
+		{"spiral/wan", spiral.New(), wan},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			h := testharness.New(b, testharness.Options{Blocks: smallBlocks(64), Schemes: []pir.Scheme{bc.scheme}, Conditions: bc.conditions})
+			s := h.Session()
+			ctx := context.Background()
+			// the handshake is not measured.
+			if _, err := s.PrivateGet(ctx, h.CIDs[0]); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.PrivateGet(ctx, h.CIDs[i%len(h.CIDs)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package testharness runs bitswap clients against a server on an in-memory
+// libp2p network, for end-to-end tests of plaintext and private retrieval
+// here and in downstream projects.
+package testharness
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/host"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/server/util"
+)
+
+// Options configure a Harness.
+type Options struct {
+	// Blocks are held by the server. Their CIDs are listed, in order, in
+	// Harness.CIDs.
+	Blocks [][]byte
+	// Schemes are the PIR schemes the server answers with, in order of
+	// preference. Defaults to fastpir.
+	Schemes []pir.Scheme
+	// Store lays out the server's PIR databases.
+	Store pirstore.Options
+	// Server holds further options of the server.
+	Server []bitswapserver.Option
+	// Client configures the sessions and clients of the harness. Its Scheme
+	// defaults to the first of Schemes.
+	Client bitswap.Options
+	// Conditions simulate the network between the client and the server.
+	// They may be changed later with SetConditions.
+	Conditions Conditions
+}
+
+// Harness is a server holding a set of blocks and a client host connected to
+// it. Everything is torn down when the test ends.
+type Harness struct {
+	// Net is the in-memory network joining the hosts, for tests shaping or
+	// cutting the link between them.
+	Net mocknet.Mocknet
+	// ClientHost is subject to the harness's Conditions.
+	ClientHost host.Host
+	ServerHost host.Host
+	// CIDs name Options.Blocks, in order.
+	CIDs []cid.Cid
+
+	t       testing.TB
+	opts    Options
+	streams *streamConditions
+}
+
+// New starts a server holding opts.Blocks and connects a client host to it.
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	if len(opts.Schemes) == 0 {
+		opts.Schemes = []pir.Scheme{fastpir.New()}
+	}
+	if opts.Client.Scheme == nil {
+		opts.Client.Scheme = opts.Schemes[0]
+	}
+
+	mn := mocknet.New()
+	t.Cleanup(func() { _ = mn.Close() })
+	server, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mn.SetLinkDefaults(mocknet.LinkOptions{Latency: opts.Conditions.Latency, Bandwidth: opts.Conditions.Bandwidth})
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatal(err)
+	}
+
+	streams := &streamConditions{}
+	streams.set(opts.Conditions)
+	h := &Harness{
+		Net:        mn,
+		ClientHost: conditionedHost{client, streams},
+		ServerHost: server,
+		t:          t,
+		opts:       opts,
+		streams:    streams,
+	}
+	store := util.NewMemStore(make(map[cid.Cid][]byte))
+	for _, blk := range opts.Blocks {
+		h.CIDs = append(h.CIDs, util.Add(store, blk))
+	}
+	var sopts []bitswapserver.Option
+	for _, scheme := range opts.Schemes {
+		sopts = append(sopts, bitswapserver.WithPIRScheme(scheme, opts.Store))
+	}
+	if err := bitswapserver.AttachBitswapServer(server, store, append(sopts, opts.Server...)...); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// Session opens a session from the client host to the server.
+func (h *Harness) Session() *bitswap.Session {
+	s := bitswap.New(h.ClientHost, h.ServerHost.ID(), h.opts.Client)
+	h.t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// Client creates a client on the client host, which fetches from the server
+// given ServerHost.ID().
+func (h *Harness) Client() *bitswap.Client {
+	cl := bitswap.NewClient(h.ClientHost, h.opts.Client)
+	h.t.Cleanup(func() { _ = cl.Close() })
+	return cl
+}
+
+// Disconnect closes the connections between the client and the server,
+// failing every stream on them. The hosts stay linked, so the next retrieval
+// dials the server again.
+func (h *Harness) Disconnect() {
+	h.t.Helper()
+	if err := h.Net.DisconnectPeers(h.ClientHost.ID(), h.ServerHost.ID()); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// Expect fails the test unless data is the i'th of the server's blocks.
+func (h *Harness) Expect(i int, data []byte) {
+	h.t.Helper()
+	if !bytes.Equal(data, h.opts.Blocks[i]) {
+		h.t.Fatalf("block %d: retrieved %d bytes which differ from the %d stored", i, len(data), len(h.opts.Blocks[i]))
+	}
+}
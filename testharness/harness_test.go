@@ -0,0 +1,171 @@
+package testharness_test
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	bitswap "github.com/willscott/go-selfish-bitswap-client"
+	"github.com/willscott/go-selfish-bitswap-client/pir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/fastpir"
+	"github.com/willscott/go-selfish-bitswap-client/pir/keyword"
+	"github.com/willscott/go-selfish-bitswap-client/pir/spiral"
+	"github.com/willscott/go-selfish-bitswap-client/pirstore"
+	bitswapserver "github.com/willscott/go-selfish-bitswap-client/server"
+	"github.com/willscott/go-selfish-bitswap-client/testharness"
+)
+
+func smallBlocks(n int) [][]byte {
+	blocks := make([][]byte, n)
+	for i := range blocks {
+		blocks[i] = []byte(fmt.Sprintf("block %d", i))
+	}
+	return blocks
+}
+
+func TestPrivateGet(t *testing.T) {
+	for _, scheme := range []pir.Scheme{fastpir.New(), spiral.New()} {
+		t.Run(scheme.ID(), func(t *testing.T) {
+			h := testharness.New(t, testharness.Options{Blocks: smallBlocks(5), Schemes: []pir.Scheme{scheme}})
+			s := h.Session()
+			for i, c := range h.CIDs {
+				data, err := s.PrivateGet(context.Background(), c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h.Expect(i, data)
+			}
+		})
+	}
+}
+
+func TestNegotiatedScheme(t *testing.T) {
+	h := testharness.New(t, testharness.Options{
+		Blocks:  smallBlocks(3),
+		Schemes: []pir.Scheme{fastpir.New()},
+		Client:  bitswap.Options{Scheme: spiral.New(), Schemes: []pir.Scheme{fastpir.New()}},
+	})
+	data, err := h.Session().PrivateGet(context.Background(), h.CIDs[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(2, data)
+}
+
+func TestPrivateGetBatch(t *testing.T) {
+	h := testharness.New(t, testharness.Options{Blocks: smallBlocks(12), Store: pirstore.Options{BatchSize: 8}})
+	out, err := h.Client().PrivateGetBatch(context.Background(), h.ServerHost.ID(), h.CIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, data := range out {
+		h.Expect(i, data)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	h := testharness.New(t, testharness.Options{Blocks: smallBlocks(2)})
+	cl := h.Client()
+	ctx, cncl := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cncl()
+	data, err := cl.PrivateGet(ctx, h.ServerHost.ID(), h.CIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(0, data)
+
+	h.Disconnect()
+	data, err = cl.PrivateGet(ctx, h.ServerHost.ID(), h.CIDs[1])
+	if err != nil {
+		t.Fatalf("after reconnecting: %v", err)
+	}
+	h.Expect(1, data)
+	if data, err = cl.Get(ctx, h.ServerHost.ID(), h.CIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(0, data)
+}
+
+func TestLargeBlocks(t *testing.T) {
+	blocks := smallBlocks(3)
+	blocks[1] = make([]byte, 20<<10)
+	_, _ = rand.Read(blocks[1])
+	h := testharness.New(t, testharness.Options{
+		Blocks: blocks,
+		// spiral answers are near the block size, where fastpir's are
+		// thousands of times larger than a message may be.
+		Schemes: []pir.Scheme{spiral.New()},
+		Server:  []bitswapserver.Option{bitswapserver.WithMaxMessageSize(4 << 10)},
+	})
+	s := h.Session()
+	// plaintext blocks larger than a message arrive in chunks.
+	data, err := s.Get(context.Background(), h.CIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(1, data)
+	for _, i := range []int{0, 1} {
+		data, err := s.PrivateGet(context.Background(), h.CIDs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Expect(i, data)
+	}
+}
+
+// counters is a MetricsSink of client and server, counting.
+type counters struct {
+	mtx sync.Mutex
+	c   map[string]float64
+}
+
+func (c *counters) Add(name string, v float64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.c == nil {
+		c.c = make(map[string]float64)
+	}
+	c.c[name] += v
+}
+
+func (c *counters) Observe(string, float64) {}
+
+func (c *counters) get(name string) float64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.c[name]
+}
+
+func TestDecoys(t *testing.T) {
+	var client, server counters
+	h := testharness.New(t, testharness.Options{
+		Blocks: smallBlocks(4),
+		Server: []bitswapserver.Option{bitswapserver.WithMetrics(&server)},
+		Client: bitswap.Options{
+			Metrics: &client,
+			Decoys:  bitswap.DecoyPolicy{PerRetrieval: 2, Spread: 50 * time.Millisecond},
+		},
+	})
+	data, err := h.Session().PrivateGet(context.Background(), h.CIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(1, data)
+
+	// each decoy is a whole retrieval, with as many queries as the real
+	// one, and answered like it.
+	want := float64(3 * (keyword.NumHashes + 1))
+	deadline := time.Now().Add(10 * time.Second)
+	for server.get("pir_queries") < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("server answered %v of %v queries", server.get("pir_queries"), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.get("pir_decoys") != 2 || client.get("pir_queries") != want {
+		t.Fatalf("sent %v decoys of %v queries", client.get("pir_decoys"), client.get("pir_queries"))
+	}
+}
@@ -0,0 +1,431 @@
+// Package unixfs reads UnixFS files and directories retrieved through the
+// client, presenting a DAG as an fs.FS. Files read as io.ReadSeekers over
+// their chunks, directories list and look up their entries whether they are
+// basic or sharded into HAMTs, and both report the size, mode and
+// modification time UnixFS records for them.
+//
+// Blocks are retrieved on demand with a fetcher.Session, as they are read,
+// so only the parts of a DAG an application opens are retrieved, privately
+// if the fetcher is, with the links following each block retrieved ahead.
+package unixfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-unixfsnode"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/file"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/schema"
+	"github.com/willscott/go-selfish-bitswap-client/fetcher"
+)
+
+// ErrNotUnixFS is returned for blocks which are neither raw leaves nor
+// dag-pb nodes carrying UnixFS data.
+var ErrNotUnixFS = errors.New("not a unixfs node")
+
+// Getter retrieves blocks by CID. fetcher.Sessions and blockstores are
+// Getters.
+type Getter interface {
+	Get(ctx context.Context, c cid.Cid) (blocks.Block, error)
+}
+
+// FS is the UnixFS tree under a root CID. The root may be a directory, or a
+// single file, which is then opened as ".". An FS is an fs.ReadDirFS and
+// fs.StatFS too, and is safe for concurrent use.
+type FS struct {
+	ctx  context.Context
+	root cid.Cid
+	lsys ipld.LinkSystem
+	// close releases the getter blocks are read with.
+	close func() error
+}
+
+var (
+	_ fs.ReadDirFS = (*FS)(nil)
+	_ fs.StatFS    = (*FS)(nil)
+)
+
+// New opens the UnixFS tree under root, retrieving its blocks with f as they
+// are read. Reads fail once ctx is done, and the FS should be closed once
+// its files are no longer read.
+func New(ctx context.Context, f *fetcher.Fetcher, root cid.Cid) *FS {
+	s := f.NewSession(ctx)
+	fsys := NewFromGetter(ctx, s, root)
+	fsys.close = s.Close
+	return fsys
+}
+
+// NewFromGetter opens the UnixFS tree under root, retrieving its blocks with
+// g, for blocks retrieved other than from a single peer.
+func NewFromGetter(ctx context.Context, g Getter, root cid.Cid) *FS {
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageReadOpener = func(lctx ipld.LinkContext, l ipld.Link) (io.Reader, error) {
+		cl, ok := l.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("unsupported link %s", l)
+		}
+		blk, err := g.Get(lctx.Ctx, cl.Cid)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(blk.RawData()), nil
+	}
+	return &FS{ctx: ctx, root: root, lsys: lsys, close: func() error { return nil }}
+}
+
+// Close abandons the blocks retrieved ahead of reads.
+func (fsys *FS) Close() error {
+	return fsys.close()
+}
+
+// Open opens the file or directory at name. Files opened are *Files, and
+// directories fs.ReadDirFiles.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	n, err := fsys.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.IsDir() {
+		return &dir{fsys: fsys, node: n}, nil
+	}
+	r, err := fsys.reader(n)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &File{node: n, r: r}, nil
+}
+
+// OpenFile opens the file at name for reading and seeking.
+func (fsys *FS) OpenFile(name string) (*File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := f.(*File)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("is a directory")}
+	}
+	return file, nil
+}
+
+// Stat describes the file or directory at name, retrieving its root block
+// only.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	return fsys.resolve("stat", name)
+}
+
+// ReadDir lists the directory at name, sorted by file name. Each entry's
+// root block is retrieved to describe it.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := fsys.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	d := &dir{fsys: fsys, node: n}
+	entries, err := d.ReadDir(-1)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// resolve walks the directories from the root down to name, retrieving the
+// root block of each.
+func (fsys *FS) resolve(op, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	n, err := fsys.load(fsys.root, ".")
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if name == "." {
+		return n, nil
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if !n.IsDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		c, err := fsys.lookup(n, seg)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if n, err = fsys.load(c, seg); err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+	}
+	return n, nil
+}
+
+// lookup returns the CID of the entry name of the directory n.
+func (fsys *FS) lookup(n *node, name string) (cid.Cid, error) {
+	d, err := unixfsnode.Reify(ipld.LinkContext{Ctx: fsys.ctx}, n.pb, &fsys.lsys)
+	if err != nil {
+		return cid.Undef, err
+	}
+	v, err := d.LookupByString(name)
+	if errors.As(err, new(schema.ErrNoSuchField)) {
+		return cid.Undef, fs.ErrNotExist
+	} else if err != nil {
+		return cid.Undef, err
+	}
+	return linkCid(v)
+}
+
+// load retrieves and decodes the root block of the file or directory c.
+func (fsys *FS) load(c cid.Cid, name string) (*node, error) {
+	lctx := ipld.LinkContext{Ctx: fsys.ctx}
+	switch c.Prefix().Codec {
+	case cid.Raw:
+		raw, err := fsys.lsys.LoadRaw(lctx, cidlink.Link{Cid: c})
+		if err != nil {
+			return nil, err
+		}
+		return &node{c: c, name: name, raw: raw}, nil
+	case cid.DagProtobuf:
+		nd, err := fsys.lsys.Load(lctx, cidlink.Link{Cid: c}, dagpb.Type.PBNode)
+		if err != nil {
+			return nil, err
+		}
+		pb, ok := nd.(dagpb.PBNode)
+		if !ok || !pb.FieldData().Exists() {
+			return nil, fmt.Errorf("%s: %w", c, ErrNotUnixFS)
+		}
+		ufs, err := data.DecodeUnixFSData(pb.FieldData().Must().Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", c, ErrNotUnixFS, err)
+		}
+		return &node{c: c, name: name, pb: pb, ufs: ufs}, nil
+	default:
+		return nil, fmt.Errorf("%s: %w", c, ErrNotUnixFS)
+	}
+}
+
+// reader reads the content of the file or symlink n, retrieving its chunks
+// as they are read.
+func (fsys *FS) reader(n *node) (io.ReadSeeker, error) {
+	if n.pb == nil {
+		return bytes.NewReader(n.raw), nil
+	}
+	switch n.ufs.FieldDataType().Int() {
+	case data.Data_Symlink:
+		if !n.ufs.FieldData().Exists() {
+			return bytes.NewReader(nil), nil
+		}
+		return bytes.NewReader(n.ufs.FieldData().Must().Bytes()), nil
+	case data.Data_File, data.Data_Raw:
+		lbn, err := file.NewUnixFSFile(fsys.ctx, n.pb, &fsys.lsys)
+		if err != nil {
+			return nil, err
+		}
+		return lbn.AsLargeBytes()
+	default:
+		return nil, fmt.Errorf("%s: unreadable %s node", n.c, data.DataTypeNames[n.ufs.FieldDataType().Int()])
+	}
+}
+
+// linkCid returns the CID a directory entry links to.
+func linkCid(v ipld.Node) (cid.Cid, error) {
+	l, err := v.AsLink()
+	if err != nil {
+		return cid.Undef, err
+	}
+	cl, ok := l.(cidlink.Link)
+	if !ok {
+		return cid.Undef, fmt.Errorf("unsupported link %s", l)
+	}
+	return cl.Cid, nil
+}
+
+// node is the decoded root block of a file or directory, and describes it as
+// an fs.FileInfo.
+type node struct {
+	c    cid.Cid
+	name string
+	// pb and ufs are set for dag-pb nodes, and raw for raw leaves.
+	pb  dagpb.PBNode
+	ufs data.UnixFSData
+	raw []byte
+}
+
+func (n *node) Name() string { return path.Base(n.name) }
+
+// Size is the length of a file's content, or of a symlink's target.
+func (n *node) Size() int64 {
+	if n.pb == nil {
+		return int64(len(n.raw))
+	}
+	switch n.ufs.FieldDataType().Int() {
+	case data.Data_File, data.Data_Raw:
+		if n.ufs.FieldFileSize().Exists() {
+			return n.ufs.FieldFileSize().Must().Int()
+		}
+		fallthrough
+	case data.Data_Symlink:
+		if n.ufs.FieldData().Exists() {
+			return int64(len(n.ufs.FieldData().Must().Bytes()))
+		}
+	}
+	return 0
+}
+
+// Mode is the permissions UnixFS records, or the defaults for the node's
+// type when it records none.
+func (n *node) Mode() fs.FileMode {
+	if n.pb == nil {
+		return data.FilePermissionsDefault
+	}
+	mode := fs.FileMode(n.ufs.Permissions()) & fs.ModePerm
+	switch n.ufs.FieldDataType().Int() {
+	case data.Data_Directory, data.Data_HAMTShard:
+		mode |= fs.ModeDir
+	case data.Data_Symlink:
+		mode |= fs.ModeSymlink
+	}
+	return mode
+}
+
+// ModTime is the modification time UnixFS records, or the zero time.
+func (n *node) ModTime() time.Time {
+	if n.pb == nil || !n.ufs.FieldMtime().Exists() {
+		return time.Time{}
+	}
+	mtime := n.ufs.FieldMtime().Must()
+	var nsec int64
+	if mtime.FieldFractionalNanoseconds().Exists() {
+		nsec = mtime.FieldFractionalNanoseconds().Must().Int()
+	}
+	return time.Unix(mtime.FieldSeconds().Int(), nsec)
+}
+
+func (n *node) IsDir() bool { return n.Mode().IsDir() }
+
+// Sys returns the CID of the node.
+func (n *node) Sys() interface{} { return n.c }
+
+// File is a file or symlink of an FS. Reading a symlink reads its target.
+type File struct {
+	node *node
+	r    io.ReadSeeker
+	// off is the offset of the next read. It is kept here, as the readers
+	// of chunked files lose it once they reach the end.
+	off int64
+}
+
+var _ io.ReadSeeker = (*File)(nil)
+
+func (f *File) Stat() (fs.FileInfo, error) { return f.node, nil }
+
+func (f *File) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.off += int64(n)
+	return n, err
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.node.Size()
+	default:
+		return f.off, fmt.Errorf("seek: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return f.off, fmt.Errorf("seek: negative offset %d", offset)
+	}
+	if _, err := f.r.Seek(offset, io.SeekStart); err != nil {
+		return f.off, err
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *File) Close() error { return nil }
+
+// dir is a directory of an FS, listing its entries on the first ReadDir.
+type dir struct {
+	fsys    *FS
+	node    *node
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.node, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error { return nil }
+
+// ReadDir lists the directory's entries, retrieving each one's root block to
+// describe it, and sharded directories' shards as they are walked.
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		if err := d.list(); err != nil {
+			return nil, err
+		}
+		d.listed = true
+	}
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+func (d *dir) list() error {
+	nd, err := unixfsnode.Reify(ipld.LinkContext{Ctx: d.fsys.ctx}, d.node.pb, &d.fsys.lsys)
+	if err != nil {
+		return err
+	}
+	it := nd.MapIterator()
+	for !it.Done() {
+		k, v, err := it.Next()
+		if err != nil {
+			return err
+		}
+		name, err := k.AsString()
+		if err != nil {
+			return err
+		}
+		c, err := linkCid(v)
+		if err != nil {
+			return err
+		}
+		n, err := d.fsys.load(c, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		d.entries = append(d.entries, fs.FileInfoToDirEntry(n))
+	}
+	sort.Slice(d.entries, func(i, j int) bool {
+		return d.entries[i].Name() < d.entries[j].Name()
+	})
+	return nil
+}
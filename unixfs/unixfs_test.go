@@ -0,0 +1,170 @@
+package unixfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-unixfsnode/data"
+	"github.com/ipfs/go-unixfsnode/data/builder"
+	"github.com/ipfs/go-unixfsnode/hamt"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/willscott/go-selfish-bitswap-client/fetcher"
+)
+
+// newStore returns a blockstore and a link system writing to it.
+func newStore() (blockstore.Blockstore, *ipld.LinkSystem) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageWriteOpener = func(lctx ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+		var buf bytes.Buffer
+		return &buf, func(l ipld.Link) error {
+			blk, err := blocks.NewBlockWithCid(buf.Bytes(), l.(cidlink.Link).Cid)
+			if err != nil {
+				return err
+			}
+			return bs.Put(lctx.Ctx, blk)
+		}, nil
+	}
+	return bs, &lsys
+}
+
+// entry builds the directory entry name for the file holding content.
+func entry(t *testing.T, lsys *ipld.LinkSystem, name string, content []byte) dagpb.PBLink {
+	t.Helper()
+	l, size, err := builder.BuildUnixFSFile(bytes.NewReader(content), "size-100", lsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link(t, name, size, l)
+}
+
+func link(t *testing.T, name string, size uint64, l ipld.Link) dagpb.PBLink {
+	t.Helper()
+	e, err := builder.BuildUnixFSDirectoryEntry(name, int64(size), l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// metaFile builds a single block file recording a mode and mtime.
+func metaFile(t *testing.T, lsys *ipld.LinkSystem, content []byte, mode int, mtime time.Time) ipld.Link {
+	t.Helper()
+	ufs, err := builder.BuildUnixFS(func(b *builder.Builder) {
+		builder.DataType(b, data.Data_File)
+		builder.Data(b, content)
+		builder.FileSize(b, uint64(len(content)))
+		builder.Permissions(b, mode)
+		builder.Mtime(b, func(tb builder.TimeBuilder) { builder.Time(tb, mtime) })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbn, err := qp.BuildMap(dagpb.Type.PBNode, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "Links", qp.List(0, func(datamodel.ListAssembler) {}))
+		qp.MapEntry(ma, "Data", qp.Bytes(data.EncodeUnixFSData(ufs)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := lsys.Store(ipld.LinkContext{}, cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagProtobuf,
+		MhType:   multihash.SHA2_256,
+		MhLength: 32,
+	}}, pbn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestFS(t *testing.T) {
+	bs, lsys := newStore()
+	big := bytes.Repeat([]byte("0123456789"), 100)
+	mtime := time.Unix(1700000000, 5)
+
+	var shardEntries []dagpb.PBLink
+	for i := 0; i < 40; i++ {
+		shardEntries = append(shardEntries, entry(t, lsys, fmt.Sprintf("f%d", i), []byte(fmt.Sprintf("sharded %d", i))))
+	}
+	shard, shardSize, err := builder.BuildUnixFSShardedDirectory(16, hamt.HashMurmur3, shardEntries, lsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symlink, symlinkSize, err := builder.BuildUnixFSSymlink("big.txt", lsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _, err := builder.BuildUnixFSDirectory([]dagpb.PBLink{
+		entry(t, lsys, "big.txt", big),
+		link(t, "link", symlinkSize, symlink),
+		link(t, "meta.txt", 4, metaFile(t, lsys, []byte("meta"), 0o600, mtime)),
+		link(t, "shard", shardSize, shard),
+	}, lsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every block is in the fetcher's store, so none is retrieved.
+	f := fetcher.New(nil, "p", fetcher.Options{Store: bs})
+	fsys := New(context.Background(), f, root.(cidlink.Link).Cid)
+	defer fsys.Close()
+
+	if err := fstest.TestFS(fsys, "big.txt", "link", "meta.txt", "shard/f0", "shard/f39"); err != nil {
+		t.Fatal(err)
+	}
+
+	// files chunked over many blocks read and seek as a whole.
+	file, err := fsys.OpenFile("big.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Seek(995, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if tail, err := io.ReadAll(file); err != nil || string(tail) != "56789" {
+		t.Fatalf("read %q after seeking, err %v", tail, err)
+	}
+
+	// metadata is reported as UnixFS records it.
+	info, err := fsys.Stat("meta.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != 0o600 || !info.ModTime().Equal(mtime) || info.Size() != 4 {
+		t.Fatalf("meta.txt has mode %v, mtime %v and size %d", info.Mode(), info.ModTime(), info.Size())
+	}
+	if info, err = fsys.Stat("link"); err != nil || info.Mode().Type() != fs.ModeSymlink {
+		t.Fatalf("link stat as %v, err %v", info, err)
+	}
+
+	// sharded directories are looked up and listed as basic ones are.
+	entries, err := fsys.ReadDir("shard")
+	if err != nil || len(entries) != 40 {
+		t.Fatalf("listed %d entries of the shard, err %v", len(entries), err)
+	}
+	if content, err := fs.ReadFile(fsys, "shard/f17"); err != nil || string(content) != "sharded 17" {
+		t.Fatalf("read %q from the shard, err %v", content, err)
+	}
+	if _, err := fsys.Open("shard/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a missing entry not to exist, got %v", err)
+	}
+}
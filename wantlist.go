@@ -0,0 +1,135 @@
+package bitswap
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// wantlist tracks the blocks a Client's callers are waiting for, by peer.
+// A caller asking a peer for a block which is already being fetched from it,
+// in the same way, waits for that fetch rather than starting another, so the
+// peer is asked once however many callers want the block. The fetch is
+// abandoned once every caller waiting on it has given up.
+type wantlist struct {
+	mtx   sync.Mutex
+	wants map[wantKey]*want
+	// reconnects holds, by peer, a channel closed when a new session to the
+	// peer replaces a failed one.
+	reconnects map[peer.ID]chan struct{}
+	metrics    MetricsSink
+}
+
+type wantKey struct {
+	peer    peer.ID
+	mh      string
+	private bool
+}
+
+// want is an outstanding fetch, shared by its callers.
+type want struct {
+	c       cid.Cid
+	callers int
+	cancel  context.CancelFunc
+	// done is closed once data and err are set.
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func newWantlist(metrics MetricsSink) *wantlist {
+	return &wantlist{
+		wants:      make(map[wantKey]*want),
+		reconnects: make(map[peer.ID]chan struct{}),
+		metrics:    metrics,
+	}
+}
+
+// get returns the block named by c from p, as fetched by fetch. Callers share
+// the fetch of an outstanding want for c from p, and its result, which must
+// not be modified. The fetch runs until it completes or all of its callers'
+// contexts are done; it is traced as part of the first caller's span.
+func (wl *wantlist) get(ctx context.Context, p peer.ID, c cid.Cid, private bool, fetch func(context.Context) ([]byte, error)) ([]byte, error) {
+	k := wantKey{p, string(c.Hash()), private}
+	wl.mtx.Lock()
+	w, ok := wl.wants[k]
+	if ok {
+		wl.metrics.Add("wants_coalesced", 1)
+	} else {
+		fctx, cncl := context.WithCancel(trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx)))
+		w = &want{c: c, cancel: cncl, done: make(chan struct{})}
+		wl.wants[k] = w
+		go func() {
+			data, err := fetch(fctx)
+			wl.mtx.Lock()
+			wl.forget(k, w)
+			w.data, w.err = data, err
+			wl.mtx.Unlock()
+			close(w.done)
+		}()
+	}
+	w.callers++
+	wl.mtx.Unlock()
+
+	select {
+	case <-w.done:
+		return w.data, w.err
+	case <-ctx.Done():
+		wl.mtx.Lock()
+		w.callers--
+		if w.callers == 0 {
+			wl.forget(k, w)
+		}
+		wl.mtx.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes w, cancelling its fetch. The caller holds mtx.
+func (wl *wantlist) forget(k wantKey, w *want) {
+	w.cancel()
+	if wl.wants[k] == w {
+		delete(wl.wants, k)
+	}
+}
+
+// peerWants returns the CIDs of the blocks outstanding from p.
+func (wl *wantlist) peerWants(p peer.ID) []cid.Cid {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	var out []cid.Cid
+	seen := make(map[string]bool)
+	for k, w := range wl.wants {
+		if k.peer == p && !seen[k.mh] {
+			seen[k.mh] = true
+			out = append(out, w.c)
+		}
+	}
+	return out
+}
+
+// reconnected returns a channel closed the next time a failed session to p
+// is replaced.
+func (wl *wantlist) reconnected(p peer.ID) <-chan struct{} {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	ch, ok := wl.reconnects[p]
+	if !ok {
+		ch = make(chan struct{})
+		wl.reconnects[p] = ch
+	}
+	return ch
+}
+
+// reconnect wakes the wants waiting to be resent to p.
+func (wl *wantlist) reconnect(p peer.ID) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	if ch, ok := wl.reconnects[p]; ok {
+		close(ch)
+		delete(wl.reconnects, p)
+	}
+}
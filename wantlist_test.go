@@ -0,0 +1,138 @@
+package bitswap
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+func testCid(t *testing.T, data string) cid.Cid {
+	mh, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, mh)
+}
+
+// callers returns the number of callers waiting on wl.
+func callers(wl *wantlist) int {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	n := 0
+	for _, w := range wl.wants {
+		n += w.callers
+	}
+	return n
+}
+
+func TestWantlistCoalesces(t *testing.T) {
+	wl := newWantlist(nopSink{})
+	c := testCid(t, "block")
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return []byte("block"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := wl.get(context.Background(), "p", c, true, fetch)
+			if err != nil || string(data) != "block" {
+				t.Errorf("got %q %v", data, err)
+			}
+		}()
+	}
+	for callers(wl) < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := wl.peerWants("p"); len(w) != 1 || !w[0].Equals(c) {
+		t.Fatalf("expected %s outstanding, got %v", c, w)
+	}
+	if w := wl.peerWants("q"); len(w) != 0 {
+		t.Fatalf("expected nothing outstanding from another peer, got %v", w)
+	}
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&fetches); n != 1 || len(wl.peerWants("p")) != 0 {
+		t.Fatalf("%d fetches, %d wants left", n, len(wl.peerWants("p")))
+	}
+}
+
+func TestWantlistAbandon(t *testing.T) {
+	wl := newWantlist(nopSink{})
+	c := testCid(t, "block")
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fetch := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	ctx1, cncl1 := context.WithCancel(context.Background())
+	ctx2, cncl2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := wl.get(ctx1, "p", c, false, fetch)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := wl.get(ctx2, "p", c, false, func(context.Context) ([]byte, error) {
+			t.Error("outstanding want fetched again")
+			return nil, nil
+		})
+		errs <- err
+	}()
+	for callers(wl) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the fetch continues while any caller waits for it.
+	cncl1()
+	<-errs
+	select {
+	case <-cancelled:
+		t.Fatal("fetch cancelled while a caller still waits")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cncl2()
+	<-errs
+	<-cancelled
+	if w := wl.peerWants("p"); len(w) != 0 {
+		t.Fatalf("abandoned want still outstanding: %v", w)
+	}
+}
+
+func TestWantlistReconnect(t *testing.T) {
+	wl := newWantlist(nopSink{})
+	ch := wl.reconnected("p")
+	other := wl.reconnected("q")
+	wl.reconnect("p")
+	select {
+	case <-ch:
+	default:
+		t.Fatal("reconnect did not wake waiting wants")
+	}
+	select {
+	case <-other:
+		t.Fatal("reconnect woke wants of another peer")
+	default:
+	}
+	select {
+	case <-wl.reconnected("p"):
+		t.Fatal("reconnect woke wants waiting after it")
+	default:
+	}
+}